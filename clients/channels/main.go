@@ -0,0 +1,120 @@
+// Package channels manages a pool of channel accounts to submit transactions
+// concurrently on behalf of a single account.
+//
+// Each transaction uses one of the channel accounts of the pool as its source
+// account, so that concurrent submissions do not compete for the sequence
+// number of the account performing the operations. Channel accounts are
+// leased to one goroutine at a time, their sequence numbers are tracked
+// locally and reloaded from Horizon whenever a submission may not have
+// consumed them.
+//
+// Example:
+//
+//	pool := channels.New(channels.Config{
+//		Horizon:           horizonclient.DefaultTestNetClient,
+//		NetworkPassphrase: network.TestNetworkPassphrase,
+//		Funder:            funder,
+//		BaseFee:           txnbuild.MinBaseFee,
+//	})
+//	if err := pool.CreateChannels(ctx, 10); err != nil {
+//		return err
+//	}
+//	defer pool.MergeChannels(context.Background())
+//
+//	tx, err := pool.Submit(ctx, channels.TransactionParams{
+//		Operations: []txnbuild.Operation{&txnbuild.Payment{
+//			Destination:   destination,
+//			Amount:        "10",
+//			Asset:         txnbuild.NativeAsset{},
+//			SourceAccount: funder.Address(),
+//		}},
+//		Signers: []*keypair.Full{funder},
+//	})
+package channels
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pownieh/stellar_go/clients/horizonclient"
+	"github.com/pownieh/stellar_go/keypair"
+	hProtocol "github.com/pownieh/stellar_go/protocols/horizon"
+	"github.com/pownieh/stellar_go/support/errors"
+	"github.com/pownieh/stellar_go/txnbuild"
+)
+
+// DefaultStartingBalance is the balance of the channel accounts created by
+// CreateChannels when Config.StartingBalance is empty.
+const DefaultStartingBalance = "2"
+
+// DefaultMaxAttempts is the number of times a transaction is submitted when
+// Config.MaxAttempts is zero.
+const DefaultMaxAttempts = 3
+
+// maxOperationsPerTransaction is the maximum number of operations in a
+// transaction.
+const maxOperationsPerTransaction = 100
+
+var (
+	// ErrNoChannels is returned when a channel account is requested from a
+	// pool which has none.
+	ErrNoChannels = errors.New("no channel accounts in the pool")
+)
+
+// Horizon represents the subset of the horizon client used by a Pool.
+type Horizon interface {
+	AccountDetailWithContext(ctx context.Context, request horizonclient.AccountRequest) (hProtocol.Account, error)
+	SubmitTransactionWithContext(ctx context.Context, transaction *txnbuild.Transaction) (hProtocol.Transaction, error)
+	SubmitFeeBumpTransactionWithContext(ctx context.Context, transaction *txnbuild.FeeBumpTransaction) (hProtocol.Transaction, error)
+}
+
+// Config configures a Pool.
+type Config struct {
+	Horizon           Horizon
+	NetworkPassphrase string
+	// Funder is the account which creates and funds the channel accounts,
+	// receives their balances when they are merged and pays for fee bump
+	// transactions.
+	Funder *keypair.Full
+	// StartingBalance is the balance of the channel accounts created by
+	// CreateChannels. It defaults to DefaultStartingBalance.
+	StartingBalance string
+	// BaseFee is the base fee of the transactions built by the pool. It
+	// defaults to txnbuild.MinBaseFee.
+	BaseFee int64
+	// FeeBumpBaseFee is the base fee of the fee bump transaction, paid by
+	// Funder, wrapping a transaction rejected with tx_insufficient_fee. Zero
+	// disables fee bumps.
+	FeeBumpBaseFee int64
+	// MaxAttempts is the maximum number of times Submit sends a transaction,
+	// rebuilding it with a fresh sequence number after a tx_bad_seq error. It
+	// defaults to DefaultMaxAttempts.
+	MaxAttempts int
+	// TimeoutSeconds is the validity of the transactions built by the pool,
+	// when TransactionParams.Preconditions is not set. Zero means
+	// transactions never expire.
+	TimeoutSeconds int64
+}
+
+// Pool is a pool of channel accounts. It is safe for concurrent use.
+type Pool struct {
+	config Config
+
+	mu       sync.Mutex
+	channels []*channel
+	idle     []*channel
+	// released is closed, and replaced, whenever a channel account is
+	// released, to wake up goroutines waiting in Acquire.
+	released chan struct{}
+}
+
+// channel is a channel account of a pool.
+type channel struct {
+	keypair *keypair.Full
+	account txnbuild.SimpleAccount
+	// synced is false when the sequence number of account must be reloaded
+	// from Horizon before the account is used.
+	synced bool
+}
+
+var _ Horizon = &horizonclient.Client{}
@@ -0,0 +1,166 @@
+package channels
+
+import (
+	"context"
+
+	"github.com/pownieh/stellar_go/clients/horizonclient"
+	"github.com/pownieh/stellar_go/keypair"
+	"github.com/pownieh/stellar_go/support/errors"
+	"github.com/pownieh/stellar_go/txnbuild"
+)
+
+// New returns a Pool using the given existing channel accounts. More channel
+// accounts can be added with Add or CreateChannels.
+func New(config Config, channels ...*keypair.Full) *Pool {
+	if config.StartingBalance == "" {
+		config.StartingBalance = DefaultStartingBalance
+	}
+	if config.BaseFee <= 0 {
+		config.BaseFee = txnbuild.MinBaseFee
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = DefaultMaxAttempts
+	}
+
+	p := &Pool{
+		config:   config,
+		released: make(chan struct{}),
+	}
+	p.Add(channels...)
+	return p
+}
+
+// Add adds existing channel accounts to the pool. Their sequence numbers are
+// loaded from Horizon when they are first leased.
+func (p *Pool) Add(channels ...*keypair.Full) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, kp := range channels {
+		c := &channel{
+			keypair: kp,
+			account: txnbuild.SimpleAccount{AccountID: kp.Address()},
+		}
+		p.channels = append(p.channels, c)
+		p.idle = append(p.idle, c)
+	}
+	p.notifyReleased()
+}
+
+// Len returns the number of channel accounts in the pool, leased or not.
+func (p *Pool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.channels)
+}
+
+// Lease is a channel account leased to a single goroutine.
+type Lease struct {
+	pool    *Pool
+	channel *channel
+}
+
+// Account returns the channel account, to be used as the source account of
+// a transaction built with IncrementSequenceNum set. Its sequence number is
+// tracked by the pool.
+func (l *Lease) Account() txnbuild.Account {
+	return &l.channel.account
+}
+
+// Keypair returns the keypair of the channel account, which must sign the
+// transactions using it as source account.
+func (l *Lease) Keypair() *keypair.Full {
+	return l.channel.keypair
+}
+
+// Release returns the channel account to the pool. err must be the error
+// returned by the submission of the last transaction built with the lease,
+// nil if it succeeded. The sequence number of the channel account is reloaded
+// from Horizon before it is used again when the transaction may not have
+// consumed it.
+func (l *Lease) Release(err error) {
+	if l.channel == nil {
+		return
+	}
+	if err != nil && !sequenceConsumed(err) {
+		l.channel.synced = false
+	}
+	l.pool.release(l.channel)
+	l.channel = nil
+}
+
+// Acquire leases a channel account, waiting for one to be released if they
+// are all leased. The lease must be released with Release.
+func (p *Pool) Acquire(ctx context.Context) (*Lease, error) {
+	for {
+		p.mu.Lock()
+		if n := len(p.idle); n > 0 {
+			c := p.idle[n-1]
+			p.idle = p.idle[:n-1]
+			p.mu.Unlock()
+
+			if err := p.sync(ctx, c); err != nil {
+				p.release(c)
+				return nil, err
+			}
+			return &Lease{pool: p, channel: c}, nil
+		}
+		if len(p.channels) == 0 {
+			p.mu.Unlock()
+			return nil, ErrNoChannels
+		}
+		released := p.released
+		p.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-released:
+		}
+	}
+}
+
+// sync loads the sequence number of a channel account from Horizon if
+// needed.
+func (p *Pool) sync(ctx context.Context, c *channel) error {
+	if c.synced {
+		return nil
+	}
+	account, err := p.config.Horizon.AccountDetailWithContext(ctx, horizonclient.AccountRequest{
+		AccountID: c.account.AccountID,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "loading channel account %s", c.account.AccountID)
+	}
+	c.account.Sequence = account.Sequence
+	c.synced = true
+	return nil
+}
+
+func (p *Pool) release(c *channel) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.idle = append(p.idle, c)
+	p.notifyReleased()
+}
+
+// remove removes a leased channel account from the pool.
+func (p *Pool) remove(c *channel) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, other := range p.channels {
+		if other == c {
+			p.channels = append(p.channels[:i], p.channels[i+1:]...)
+			break
+		}
+	}
+	// wake up goroutines waiting for a channel account, in case the pool is
+	// now empty
+	p.notifyReleased()
+}
+
+// notifyReleased must be called with mu held.
+func (p *Pool) notifyReleased() {
+	close(p.released)
+	p.released = make(chan struct{})
+}
@@ -0,0 +1,273 @@
+package channels
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pownieh/stellar_go/clients/horizonclient"
+	"github.com/pownieh/stellar_go/keypair"
+	"github.com/pownieh/stellar_go/network"
+	hProtocol "github.com/pownieh/stellar_go/protocols/horizon"
+	"github.com/pownieh/stellar_go/support/render/problem"
+	"github.com/pownieh/stellar_go/txnbuild"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeHorizon simulates the sequence numbers of the accounts of a network.
+type fakeHorizon struct {
+	mu        sync.Mutex
+	sequences map[string]int64
+	loads     int
+	submitted []*txnbuild.Transaction
+	// reject, if set, returns the result code rejecting a transaction.
+	reject   func(tx *txnbuild.Transaction) string
+	feeBumps []*txnbuild.FeeBumpTransaction
+}
+
+func newFakeHorizon(accounts ...string) *fakeHorizon {
+	h := &fakeHorizon{sequences: map[string]int64{}}
+	for _, account := range accounts {
+		h.sequences[account] = 100
+	}
+	return h
+}
+
+func (h *fakeHorizon) sequence(account string) int64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.sequences[account]
+}
+
+func (h *fakeHorizon) AccountDetailWithContext(ctx context.Context, request horizonclient.AccountRequest) (hProtocol.Account, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.loads++
+	sequence, ok := h.sequences[request.AccountID]
+	if !ok {
+		return hProtocol.Account{}, &horizonclient.Error{Problem: problem.NotFound}
+	}
+	return hProtocol.Account{AccountID: request.AccountID, Sequence: sequence}, nil
+}
+
+func (h *fakeHorizon) SubmitTransactionWithContext(ctx context.Context, tx *txnbuild.Transaction) (hProtocol.Transaction, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.reject != nil {
+		if code := h.reject(tx); code != "" {
+			return hProtocol.Transaction{}, txError(code)
+		}
+	}
+	return h.apply(tx)
+}
+
+func (h *fakeHorizon) SubmitFeeBumpTransactionWithContext(ctx context.Context, tx *txnbuild.FeeBumpTransaction) (hProtocol.Transaction, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.feeBumps = append(h.feeBumps, tx)
+	return h.apply(tx.InnerTransaction())
+}
+
+// apply must be called with mu held.
+func (h *fakeHorizon) apply(tx *txnbuild.Transaction) (hProtocol.Transaction, error) {
+	source := tx.SourceAccount()
+	if tx.SequenceNumber() != h.sequences[source.AccountID]+1 {
+		return hProtocol.Transaction{}, txError("tx_bad_seq")
+	}
+	h.sequences[source.AccountID]++
+	h.submitted = append(h.submitted, tx)
+
+	for _, op := range tx.Operations() {
+		switch op := op.(type) {
+		case *txnbuild.CreateAccount:
+			h.sequences[op.Destination] = 1000
+		case *txnbuild.AccountMerge:
+			delete(h.sequences, source.AccountID)
+		}
+	}
+	return hProtocol.Transaction{Account: source.AccountID, AccountSequence: tx.SequenceNumber(), Successful: true}, nil
+}
+
+func txError(code string) error {
+	return &horizonclient.Error{Problem: problem.P{
+		Type:   "transaction_failed",
+		Status: 400,
+		Extras: map[string]interface{}{
+			"result_codes": map[string]interface{}{"transaction": code},
+		},
+	}}
+}
+
+func newTestPool(t *testing.T, n int, config Config) (*Pool, *fakeHorizon, *keypair.Full) {
+	funder := keypair.MustRandom()
+	channels := make([]*keypair.Full, n)
+	addresses := []string{funder.Address()}
+	for i := range channels {
+		channels[i] = keypair.MustRandom()
+		addresses = append(addresses, channels[i].Address())
+	}
+	horizon := newFakeHorizon(addresses...)
+
+	config.Horizon = horizon
+	config.NetworkPassphrase = network.TestNetworkPassphrase
+	config.Funder = funder
+	return New(config, channels...), horizon, funder
+}
+
+func payment(funder *keypair.Full) TransactionParams {
+	return TransactionParams{
+		Operations: []txnbuild.Operation{&txnbuild.Payment{
+			Destination:   keypair.MustRandom().Address(),
+			Amount:        "10",
+			Asset:         txnbuild.NativeAsset{},
+			SourceAccount: funder.Address(),
+		}},
+		Signers: []*keypair.Full{funder},
+	}
+}
+
+func TestSubmitConcurrently(t *testing.T) {
+	pool, horizon, funder := newTestPool(t, 3, Config{})
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 30)
+	for i := 0; i < 30; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := pool.Submit(context.Background(), payment(funder))
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	assert.Len(t, horizon.submitted, 30)
+	// the sequence numbers are loaded at most once per channel account
+	assert.LessOrEqual(t, horizon.loads, 3)
+	assert.Equal(t, int64(100), horizon.sequence(funder.Address()))
+	for _, tx := range horizon.submitted {
+		assert.NotEqual(t, funder.Address(), tx.SourceAccount().AccountID)
+		assert.Len(t, tx.Signatures(), 2)
+	}
+}
+
+func TestSubmitResyncsAfterBadSequence(t *testing.T) {
+	pool, horizon, funder := newTestPool(t, 1, Config{})
+
+	_, err := pool.Submit(context.Background(), payment(funder))
+	require.NoError(t, err)
+	assert.Equal(t, 1, horizon.loads)
+
+	// the channel account is used by another submitter
+	channel := pool.channels[0].account.AccountID
+	horizon.mu.Lock()
+	horizon.sequences[channel] += 5
+	horizon.mu.Unlock()
+
+	tx, err := pool.Submit(context.Background(), payment(funder))
+	require.NoError(t, err)
+	assert.Equal(t, int64(107), tx.AccountSequence)
+	assert.Equal(t, 2, horizon.loads)
+
+	// a rejected transaction does not consume the sequence number
+	horizon.reject = func(*txnbuild.Transaction) string { return "tx_bad_auth" }
+	_, err = pool.Submit(context.Background(), payment(funder))
+	assert.Equal(t, "tx_bad_auth", transactionCode(err))
+	horizon.reject = nil
+	tx, err = pool.Submit(context.Background(), payment(funder))
+	require.NoError(t, err)
+	assert.Equal(t, int64(108), tx.AccountSequence)
+	assert.Equal(t, 3, horizon.loads)
+
+	// attempts are limited
+	horizon.reject = func(*txnbuild.Transaction) string { return "tx_bad_seq" }
+	_, err = pool.Submit(context.Background(), payment(funder))
+	assert.Equal(t, "tx_bad_seq", transactionCode(err))
+	assert.Equal(t, 3+DefaultMaxAttempts-1, horizon.loads)
+}
+
+func TestSubmitFeeBump(t *testing.T) {
+	pool, horizon, funder := newTestPool(t, 1, Config{FeeBumpBaseFee: 1000})
+	horizon.reject = func(*txnbuild.Transaction) string { return "tx_insufficient_fee" }
+
+	_, err := pool.Submit(context.Background(), payment(funder))
+	require.NoError(t, err)
+	require.Len(t, horizon.feeBumps, 1)
+	assert.Equal(t, funder.Address(), horizon.feeBumps[0].FeeAccount())
+	assert.Equal(t, int64(1000), horizon.feeBumps[0].BaseFee())
+
+	// fee bumps are disabled by default
+	pool.config.FeeBumpBaseFee = 0
+	_, err = pool.Submit(context.Background(), payment(funder))
+	assert.Equal(t, "tx_insufficient_fee", transactionCode(err))
+	assert.Len(t, horizon.feeBumps, 1)
+}
+
+func TestAcquire(t *testing.T) {
+	pool, _, _ := newTestPool(t, 1, Config{})
+
+	lease, err := pool.Acquire(context.Background())
+	require.NoError(t, err)
+	kp := lease.Keypair()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = pool.Acquire(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		lease.Release(nil)
+	}()
+	other, err := pool.Acquire(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, kp, other.Keypair())
+	other.Release(nil)
+
+	_, err = New(Config{}).Acquire(context.Background())
+	assert.Equal(t, ErrNoChannels, err)
+}
+
+func TestCreateAndMergeChannels(t *testing.T) {
+	pool, horizon, funder := newTestPool(t, 0, Config{})
+
+	require.NoError(t, pool.CreateChannels(context.Background(), 150))
+	assert.Equal(t, 150, pool.Len())
+	// channel accounts are created in batches of 100
+	assert.Equal(t, int64(102), horizon.sequence(funder.Address()))
+	assert.Len(t, horizon.sequences, 151)
+
+	_, err := pool.Submit(context.Background(), payment(funder))
+	require.NoError(t, err)
+
+	require.NoError(t, pool.MergeChannels(context.Background()))
+	assert.Equal(t, 0, pool.Len())
+	assert.Len(t, horizon.sequences, 1)
+}
+
+func TestCreateChannelsFailedBatch(t *testing.T) {
+	pool, horizon, funder := newTestPool(t, 0, Config{})
+	batches := 0
+	horizon.reject = func(tx *txnbuild.Transaction) string {
+		batches++
+		if batches == 2 {
+			return "tx_insufficient_balance"
+		}
+		return ""
+	}
+
+	err := pool.CreateChannels(context.Background(), 250)
+	assert.Equal(t, "tx_insufficient_balance", transactionCode(err))
+	assert.Equal(t, 3, batches)
+	// the funder account is reloaded after the failed batch, so the last
+	// batch is submitted with the right sequence number
+	assert.Equal(t, 150, pool.Len())
+	assert.Equal(t, int64(102), horizon.sequence(funder.Address()))
+	assert.Equal(t, 2, horizon.loads)
+}
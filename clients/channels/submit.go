@@ -0,0 +1,233 @@
+package channels
+
+import (
+	"context"
+
+	"github.com/pownieh/stellar_go/clients/horizonclient"
+	"github.com/pownieh/stellar_go/keypair"
+	hProtocol "github.com/pownieh/stellar_go/protocols/horizon"
+	"github.com/pownieh/stellar_go/support/errors"
+	"github.com/pownieh/stellar_go/txnbuild"
+)
+
+// TransactionParams are the parameters of a transaction submitted with a
+// channel account as source account.
+type TransactionParams struct {
+	// Operations must have their SourceAccount set to the account on whose
+	// behalf they are performed, unless they are meant to apply to the
+	// channel account itself.
+	Operations []txnbuild.Operation
+	Memo       txnbuild.Memo
+	// Preconditions defaults to a time bound of Config.TimeoutSeconds.
+	Preconditions *txnbuild.Preconditions
+	// Signers are the keypairs signing the transaction in addition to the
+	// channel account, usually the source accounts of the operations.
+	Signers []*keypair.Full
+}
+
+// Submit builds a transaction using a leased channel account as source
+// account, signs it and submits it to Horizon. The transaction is rebuilt and
+// resubmitted with a fresh sequence number when it is rejected with
+// tx_bad_seq, up to Config.MaxAttempts times. When it is rejected with
+// tx_insufficient_fee and Config.FeeBumpBaseFee is set, it is resubmitted
+// wrapped in a fee bump transaction paid by Config.Funder.
+func (p *Pool) Submit(ctx context.Context, params TransactionParams) (hProtocol.Transaction, error) {
+	for attempt := 1; ; attempt++ {
+		lease, err := p.Acquire(ctx)
+		if err != nil {
+			return hProtocol.Transaction{}, err
+		}
+
+		resp, err := p.submit(ctx, lease, params)
+		lease.Release(err)
+		if err == nil || attempt >= p.config.MaxAttempts || transactionCode(err) != "tx_bad_seq" {
+			return resp, err
+		}
+	}
+}
+
+func (p *Pool) submit(ctx context.Context, lease *Lease, params TransactionParams) (hProtocol.Transaction, error) {
+	preconditions := txnbuild.Preconditions{TimeBounds: txnbuild.NewInfiniteTimeout()}
+	if params.Preconditions != nil {
+		preconditions = *params.Preconditions
+	} else if p.config.TimeoutSeconds > 0 {
+		preconditions.TimeBounds = txnbuild.NewTimeout(p.config.TimeoutSeconds)
+	}
+
+	tx, err := txnbuild.NewTransaction(txnbuild.TransactionParams{
+		SourceAccount:        lease.Account(),
+		IncrementSequenceNum: true,
+		Operations:           params.Operations,
+		Memo:                 params.Memo,
+		BaseFee:              p.config.BaseFee,
+		Preconditions:        preconditions,
+	})
+	if err != nil {
+		return hProtocol.Transaction{}, errors.Wrap(err, "unable to build tx")
+	}
+
+	signers := append([]*keypair.Full{lease.Keypair()}, params.Signers...)
+	tx, err = tx.Sign(p.config.NetworkPassphrase, signers...)
+	if err != nil {
+		return hProtocol.Transaction{}, errors.Wrap(err, "unable to sign tx")
+	}
+
+	resp, err := p.config.Horizon.SubmitTransactionWithContext(ctx, tx)
+	if err != nil && p.config.FeeBumpBaseFee > 0 && transactionCode(err) == "tx_insufficient_fee" {
+		return p.submitFeeBump(ctx, tx)
+	}
+	return resp, err
+}
+
+// submitFeeBump submits tx, which was rejected because its fee was too low,
+// wrapped in a fee bump transaction paid by the funder.
+func (p *Pool) submitFeeBump(ctx context.Context, tx *txnbuild.Transaction) (hProtocol.Transaction, error) {
+	if p.config.Funder == nil {
+		return hProtocol.Transaction{}, errors.New("fee bump requires a funder")
+	}
+
+	feeBump, err := txnbuild.NewFeeBumpTransaction(txnbuild.FeeBumpTransactionParams{
+		Inner:      tx,
+		FeeAccount: p.config.Funder.Address(),
+		BaseFee:    p.config.FeeBumpBaseFee,
+	})
+	if err != nil {
+		return hProtocol.Transaction{}, errors.Wrap(err, "unable to build fee bump tx")
+	}
+	feeBump, err = feeBump.Sign(p.config.NetworkPassphrase, p.config.Funder)
+	if err != nil {
+		return hProtocol.Transaction{}, errors.Wrap(err, "unable to sign fee bump tx")
+	}
+	return p.config.Horizon.SubmitFeeBumpTransactionWithContext(ctx, feeBump)
+}
+
+// CreateChannels creates n channel accounts funded by Config.Funder with
+// Config.StartingBalance and adds them to the pool. Channel accounts are
+// added as soon as the transaction creating them succeeded. When the
+// transaction of a batch fails, the next batches are still submitted and the
+// first error is returned once all of them were, so some channel accounts may
+// have been added when an error is returned.
+func (p *Pool) CreateChannels(ctx context.Context, n int) error {
+	if p.config.Funder == nil {
+		return errors.New("creating channel accounts requires a funder")
+	}
+
+	funder := txnbuild.SimpleAccount{AccountID: p.config.Funder.Address()}
+	if err := p.loadSequence(ctx, &funder); err != nil {
+		return errors.Wrap(err, "loading funder account")
+	}
+
+	var firstErr error
+
+	for n > 0 {
+		batch := make([]*keypair.Full, 0, maxOperationsPerTransaction)
+		ops := make([]txnbuild.Operation, 0, maxOperationsPerTransaction)
+		for ; n > 0 && len(batch) < maxOperationsPerTransaction; n-- {
+			kp, err := keypair.Random()
+			if err != nil {
+				return errors.Wrap(err, "generating channel account keypair")
+			}
+			batch = append(batch, kp)
+			ops = append(ops, &txnbuild.CreateAccount{
+				Destination: kp.Address(),
+				Amount:      p.config.StartingBalance,
+			})
+		}
+
+		tx, err := txnbuild.NewTransaction(txnbuild.TransactionParams{
+			SourceAccount:        &funder,
+			IncrementSequenceNum: true,
+			Operations:           ops,
+			BaseFee:              p.config.BaseFee,
+			Preconditions:        txnbuild.Preconditions{TimeBounds: txnbuild.NewInfiniteTimeout()},
+		})
+		if err != nil {
+			return errors.Wrap(err, "unable to build tx")
+		}
+		tx, err = tx.Sign(p.config.NetworkPassphrase, p.config.Funder)
+		if err != nil {
+			return errors.Wrap(err, "unable to sign tx")
+		}
+		if _, err := p.config.Horizon.SubmitTransactionWithContext(ctx, tx); err != nil {
+			if firstErr == nil {
+				firstErr = errors.Wrap(err, "submitting tx creating channel accounts")
+			}
+			// the sequence number was incremented when the transaction was
+			// built but it may not have been consumed
+			if err := p.loadSequence(ctx, &funder); err != nil {
+				return errors.Wrap(err, "reloading funder account")
+			}
+			continue
+		}
+		p.Add(batch...)
+	}
+	return firstErr
+}
+
+// loadSequence loads the sequence number of account from Horizon.
+func (p *Pool) loadSequence(ctx context.Context, account *txnbuild.SimpleAccount) error {
+	details, err := p.config.Horizon.AccountDetailWithContext(ctx, horizonclient.AccountRequest{
+		AccountID: account.AccountID,
+	})
+	if err != nil {
+		return err
+	}
+	account.Sequence = details.Sequence
+	return nil
+}
+
+// MergeChannels merges all the channel accounts of the pool into
+// Config.Funder and removes them from the pool, waiting for leased channel
+// accounts to be released.
+func (p *Pool) MergeChannels(ctx context.Context) error {
+	if p.config.Funder == nil {
+		return errors.New("merging channel accounts requires a funder")
+	}
+
+	for p.Len() > 0 {
+		lease, err := p.Acquire(ctx)
+		if err == ErrNoChannels {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = p.submit(ctx, lease, TransactionParams{
+			Operations: []txnbuild.Operation{&txnbuild.AccountMerge{
+				Destination: p.config.Funder.Address(),
+			}},
+		})
+		if err != nil {
+			lease.Release(err)
+			return errors.Wrapf(err, "merging channel account %s", lease.Keypair().Address())
+		}
+		p.remove(lease.channel)
+	}
+	return nil
+}
+
+// transactionCode returns the transaction result code of a submission error
+// returned by Horizon, or an empty string.
+func transactionCode(err error) string {
+	herr := horizonclient.GetError(err)
+	if herr == nil {
+		return ""
+	}
+	codes, err := herr.ResultCodes()
+	if err != nil {
+		return ""
+	}
+	return codes.TransactionCode
+}
+
+// sequenceConsumed returns true if the transaction whose submission failed
+// with err was included in a ledger, and therefore consumed its sequence
+// number.
+func sequenceConsumed(err error) bool {
+	switch transactionCode(err) {
+	case "tx_failed", "tx_fee_bump_inner_failed":
+		return true
+	}
+	return false
+}
@@ -1,12 +1,9 @@
 package horizonclient
 
 import (
-	"bufio"
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -16,8 +13,6 @@ import (
 	"github.com/pownieh/stellar_go/txnbuild"
 	"github.com/pownieh/stellar_go/xdr"
 
-	"github.com/manucorporat/sse"
-
 	hProtocol "github.com/pownieh/stellar_go/protocols/horizon"
 	"github.com/pownieh/stellar_go/protocols/horizon/effects"
 	"github.com/pownieh/stellar_go/protocols/horizon/operations"
@@ -25,18 +20,19 @@ import (
 )
 
 // sendRequest builds the URL for the given horizon request and sends the url to a horizon server
-func (c *Client) sendRequest(hr HorizonRequest, resp interface{}) (err error) {
-	req, err := hr.HTTPRequest(c.fixHorizonURL())
-	if err != nil {
-		return err
-	}
-
-	return c.sendHTTPRequest(req, resp)
+func (c *Client) sendRequest(ctx context.Context, hr HorizonRequest, resp interface{}) (err error) {
+	return c.retry(ctx, func(horizonURL string) error {
+		req, err := hr.HTTPRequest(horizonURL)
+		if err != nil {
+			return err
+		}
+		return c.sendHTTPRequest(ctx, req, horizonURL, resp)
+	})
 }
 
 // checkMemoRequired implements a memo required check as defined in
 // https://github.com/stellar/stellar-protocol/blob/master/ecosystem/sep-0029.md
-func (c *Client) checkMemoRequired(transaction *txnbuild.Transaction) error {
+func (c *Client) checkMemoRequired(ctx context.Context, transaction *txnbuild.Transaction) error {
 	destinations := map[string]bool{}
 
 	for i, op := range transaction.Operations() {
@@ -77,7 +73,7 @@ func (c *Client) checkMemoRequired(transaction *txnbuild.Transaction) error {
 			DataKey:   "config.memo_required",
 		}
 
-		data, err := c.AccountData(request)
+		data, err := c.AccountDataWithContext(ctx, request)
 		if err != nil {
 			horizonError := GetError(err)
 
@@ -101,156 +97,47 @@ func (c *Client) checkMemoRequired(transaction *txnbuild.Transaction) error {
 
 // sendGetRequest sends a HTTP GET request to a horizon server.
 // It can be used for requests that do not implement the HorizonRequest interface.
-func (c *Client) sendGetRequest(requestURL string, a interface{}) error {
-	req, err := http.NewRequest("GET", requestURL, nil)
-	if err != nil {
-		return errors.Wrap(err, "error creating HTTP request")
-	}
-	return c.sendHTTPRequest(req, a)
+// URLs pointing to one of the client's Horizon servers, like paging links, are
+// rewritten to point to the server the request is sent to.
+func (c *Client) sendGetRequest(ctx context.Context, requestURL string, a interface{}) error {
+	endpoints := c.endpoints()
+	return c.retry(ctx, func(horizonURL string) error {
+		req, err := http.NewRequest("GET", endpoints.rebase(requestURL, horizonURL), nil)
+		if err != nil {
+			return errors.Wrap(err, "error creating HTTP request")
+		}
+		return c.sendHTTPRequest(ctx, req, horizonURL, a)
+	})
 }
 
-func (c *Client) sendHTTPRequest(req *http.Request, a interface{}) error {
+// sendHTTPRequest sends req to horizonURL and decodes the response into a.
+// Connection errors and responses with a retryable status code are returned
+// as *retryableError.
+func (c *Client) sendHTTPRequest(ctx context.Context, req *http.Request, horizonURL string, a interface{}) error {
 	c.setClientAppHeaders(req)
 	c.setDefaultClient()
 
 	if c.horizonTimeout == 0 {
 		c.horizonTimeout = HorizonTimeout
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), c.horizonTimeout)
+	reqCtx, cancel := context.WithTimeout(ctx, c.horizonTimeout)
 	defer cancel()
 
-	if resp, err := c.HTTP.Do(req.WithContext(ctx)); err != nil {
-		return err
-	} else {
-		return decodeResponse(resp, a, c.HorizonURL, c.clock)
-	}
-}
-
-// stream handles connections to endpoints that support streaming on a horizon server
-func (c *Client) stream(
-	ctx context.Context,
-	streamURL string,
-	handler func(data []byte) error,
-) error {
-	su, err := url.Parse(streamURL)
+	resp, err := c.HTTP.Do(req.WithContext(reqCtx))
 	if err != nil {
-		return errors.Wrap(err, "error parsing stream url")
-	}
-
-	query := su.Query()
-	if query.Get("cursor") == "" {
-		query.Set("cursor", "now")
-	}
-
-	for {
-		// updates the url with new cursor
-		su.RawQuery = query.Encode()
-		req, err := http.NewRequest("GET", su.String(), nil)
-		if err != nil {
-			return errors.Wrap(err, "error creating HTTP request")
-		}
-		req.Header.Set("Accept", "text/event-stream")
-		c.setDefaultClient()
-		c.setClientAppHeaders(req)
-
-		// We can use c.HTTP here because we set Timeout per request not on the client. See sendRequest()
-		resp, err := c.HTTP.Do(req)
-		if err != nil {
-			return errors.Wrap(err, "error sending HTTP request")
-		}
-
-		// Expected statusCode are 200-299
-		if !(resp.StatusCode >= 200 && resp.StatusCode < 300) {
-			return fmt.Errorf("got bad HTTP status code %d", resp.StatusCode)
+		if ctx.Err() != nil {
+			return err
 		}
-		defer resp.Body.Close()
-
-		reader := bufio.NewReader(resp.Body)
-
-		// Read events one by one. Break this loop when there is no more data to be
-		// read from resp.Body (io.EOF).
-	Events:
-		for {
-			// Read until empty line = event delimiter. The perfect solution would be to read
-			// as many bytes as possible and forward them to sse.Decode. However this
-			// requires much more complicated code.
-			// We could also write our own `sse` package that works fine with streams directly
-			// (github.com/manucorporat/sse is just using io/ioutils.ReadAll).
-			var buffer bytes.Buffer
-			nonEmptylinesRead := 0
-			for {
-				// Check if ctx is not canceled
-				select {
-				case <-ctx.Done():
-					return nil
-				default:
-					// Continue
-				}
-
-				line, err := reader.ReadString('\n')
-				if err != nil {
-					if err == io.EOF || err == io.ErrUnexpectedEOF {
-						// We catch EOF errors to handle two possible situations:
-						// - The last line before closing the stream was not empty. This should never
-						//   happen in Horizon as it always sends an empty line after each event.
-						// - The stream was closed by the server/proxy because the connection was idle.
-						//
-						// In the former case, that (again) should never happen in Horizon, we need to
-						// check if there are any events we need to decode. We do this in the `if`
-						// statement below just in case if Horizon behavior changes in a future.
-						//
-						// From spec:
-						// > Once the end of the file is reached, the user agent must dispatch the
-						// > event one final time, as defined below.
-						if nonEmptylinesRead == 0 {
-							break Events
-						}
-					} else {
-						return errors.Wrap(err, "error reading line")
-					}
-				}
-				buffer.WriteString(line)
-
-				if strings.TrimRight(line, "\n\r") == "" {
-					break
-				}
-
-				nonEmptylinesRead++
-			}
-
-			events, err := sse.Decode(strings.NewReader(buffer.String()))
-			if err != nil {
-				return errors.Wrap(err, "error decoding event")
-			}
+		return &retryableError{err: err}
+	}
 
-			// Right now len(events) should always be 1. This loop will be helpful after writing
-			// new SSE decoder that can handle io.Reader without using ioutils.ReadAll().
-			for _, event := range events {
-				if event.Event != "message" {
-					continue
-				}
-
-				// Update cursor with event ID
-				if event.Id != "" {
-					query.Set("cursor", event.Id)
-				}
-
-				switch data := event.Data.(type) {
-				case string:
-					err = handler([]byte(data))
-					err = errors.Wrap(err, "handler error")
-				case []byte:
-					err = handler(data)
-					err = errors.Wrap(err, "handler error")
-				default:
-					err = errors.New("invalid event.Data type")
-				}
-				if err != nil {
-					return err
-				}
-			}
+	if isRetryableStatus(resp.StatusCode) {
+		return &retryableError{
+			err:        decodeResponse(resp, a, horizonURL, c.clock),
+			retryAfter: parseRetryAfter(resp.Header, c.clock),
 		}
 	}
+	return decodeResponse(resp, a, horizonURL, c.clock)
 }
 
 func (c *Client) setClientAppHeaders(req *http.Request) {
@@ -294,13 +181,25 @@ func (c *Client) HorizonTimeout() time.Duration {
 // have a trustline to an asset.
 // See https://developers.stellar.org/api/resources/accounts/
 func (c *Client) Accounts(request AccountsRequest) (accounts hProtocol.AccountsPage, err error) {
-	err = c.sendRequest(request, &accounts)
+	return c.AccountsWithContext(context.Background(), request)
+}
+
+// AccountsWithContext is like Accounts but accepts a context.Context controlling the
+// request and its retries.
+func (c *Client) AccountsWithContext(ctx context.Context, request AccountsRequest) (accounts hProtocol.AccountsPage, err error) {
+	err = c.sendRequest(ctx, request, &accounts)
 	return
 }
 
 // AccountDetail returns information for a single account.
 // See https://developers.stellar.org/api/resources/accounts/single/
 func (c *Client) AccountDetail(request AccountRequest) (account hProtocol.Account, err error) {
+	return c.AccountDetailWithContext(context.Background(), request)
+}
+
+// AccountDetailWithContext is like AccountDetail but accepts a context.Context controlling the
+// request and its retries.
+func (c *Client) AccountDetailWithContext(ctx context.Context, request AccountRequest) (account hProtocol.Account, err error) {
 	if request.AccountID == "" {
 		err = errors.New("no account ID provided")
 	}
@@ -309,13 +208,19 @@ func (c *Client) AccountDetail(request AccountRequest) (account hProtocol.Accoun
 		return
 	}
 
-	err = c.sendRequest(request, &account)
+	err = c.sendRequest(ctx, request, &account)
 	return
 }
 
 // AccountData returns a single data associated with a given account
 // See https://developers.stellar.org/api/resources/accounts/data/
 func (c *Client) AccountData(request AccountRequest) (accountData hProtocol.AccountData, err error) {
+	return c.AccountDataWithContext(context.Background(), request)
+}
+
+// AccountDataWithContext is like AccountData but accepts a context.Context controlling the
+// request and its retries.
+func (c *Client) AccountDataWithContext(ctx context.Context, request AccountRequest) (accountData hProtocol.AccountData, err error) {
 	if request.AccountID == "" || request.DataKey == "" {
 		err = errors.New("too few parameters")
 	}
@@ -324,34 +229,58 @@ func (c *Client) AccountData(request AccountRequest) (accountData hProtocol.Acco
 		return
 	}
 
-	err = c.sendRequest(request, &accountData)
+	err = c.sendRequest(ctx, request, &accountData)
 	return
 }
 
 // Effects returns effects (https://developers.stellar.org/api/resources/effects/)
 // It can be used to return effects for an account, a ledger, an operation, a transaction and all effects on the network.
 func (c *Client) Effects(request EffectRequest) (effects effects.EffectsPage, err error) {
-	err = c.sendRequest(request, &effects)
+	return c.EffectsWithContext(context.Background(), request)
+}
+
+// EffectsWithContext is like Effects but accepts a context.Context controlling the
+// request and its retries.
+func (c *Client) EffectsWithContext(ctx context.Context, request EffectRequest) (effects effects.EffectsPage, err error) {
+	err = c.sendRequest(ctx, request, &effects)
 	return
 }
 
 // Assets returns asset information.
 // See https://developers.stellar.org/api/resources/assets/list/
 func (c *Client) Assets(request AssetRequest) (assets hProtocol.AssetsPage, err error) {
-	err = c.sendRequest(request, &assets)
+	return c.AssetsWithContext(context.Background(), request)
+}
+
+// AssetsWithContext is like Assets but accepts a context.Context controlling the
+// request and its retries.
+func (c *Client) AssetsWithContext(ctx context.Context, request AssetRequest) (assets hProtocol.AssetsPage, err error) {
+	err = c.sendRequest(ctx, request, &assets)
 	return
 }
 
 // Ledgers returns information about all ledgers.
 // See https://developers.stellar.org/api/resources/ledgers/list/
 func (c *Client) Ledgers(request LedgerRequest) (ledgers hProtocol.LedgersPage, err error) {
-	err = c.sendRequest(request, &ledgers)
+	return c.LedgersWithContext(context.Background(), request)
+}
+
+// LedgersWithContext is like Ledgers but accepts a context.Context controlling the
+// request and its retries.
+func (c *Client) LedgersWithContext(ctx context.Context, request LedgerRequest) (ledgers hProtocol.LedgersPage, err error) {
+	err = c.sendRequest(ctx, request, &ledgers)
 	return
 }
 
 // LedgerDetail returns information about a particular ledger for a given sequence number
 // See https://developers.stellar.org/api/resources/ledgers/single/
 func (c *Client) LedgerDetail(sequence uint32) (ledger hProtocol.Ledger, err error) {
+	return c.LedgerDetailWithContext(context.Background(), sequence)
+}
+
+// LedgerDetailWithContext is like LedgerDetail but accepts a context.Context controlling the
+// request and its retries.
+func (c *Client) LedgerDetailWithContext(ctx context.Context, sequence uint32) (ledger hProtocol.Ledger, err error) {
 	if sequence == 0 {
 		err = errors.New("invalid sequence number provided")
 	}
@@ -361,28 +290,73 @@ func (c *Client) LedgerDetail(sequence uint32) (ledger hProtocol.Ledger, err err
 	}
 
 	request := LedgerRequest{forSequence: sequence}
-	err = c.sendRequest(request, &ledger)
+	err = c.sendRequest(ctx, request, &ledger)
 	return
 }
 
 // FeeStats returns information about fees in the last 5 ledgers.
 // See https://developers.stellar.org/api/aggregations/fee-stats/
 func (c *Client) FeeStats() (feestats hProtocol.FeeStats, err error) {
+	return c.FeeStatsWithContext(context.Background())
+}
+
+// FeeStatsWithContext is like FeeStats but accepts a context.Context controlling the
+// request and its retries.
+func (c *Client) FeeStatsWithContext(ctx context.Context) (feestats hProtocol.FeeStats, err error) {
 	request := feeStatsRequest{endpoint: "fee_stats"}
-	err = c.sendRequest(request, &feestats)
+	err = c.sendRequest(ctx, request, &feestats)
 	return
 }
 
+// FeeEstimate returns the fee to bid for a transaction to be included with a given probability
+// within a given number of ledgers, estimated from the fee stats of the recent ledgers.
+func (c *Client) FeeEstimate(request FeeEstimateRequest) (estimate hProtocol.FeeEstimate, err error) {
+	return c.FeeEstimateWithContext(context.Background(), request)
+}
+
+// FeeEstimateWithContext is like FeeEstimate but accepts a context.Context controlling the
+// request and its retries.
+func (c *Client) FeeEstimateWithContext(ctx context.Context, request FeeEstimateRequest) (estimate hProtocol.FeeEstimate, err error) {
+	err = c.sendRequest(ctx, request, &estimate)
+	return
+}
+
+// EstimateBaseFee returns the base fee, the fee per operation, to bid for a transaction to be
+// included with the given probability within the given number of ledgers. It implements
+// txnbuild.BaseFeeEstimator.
+func (c *Client) EstimateBaseFee(probability float64, withinLedgers uint32) (int64, error) {
+	estimate, err := c.FeeEstimate(FeeEstimateRequest{
+		Probability:   probability,
+		WithinLedgers: withinLedgers,
+	})
+	if err != nil {
+		return 0, err
+	}
+	return estimate.InclusionFee, nil
+}
+
 // Offers returns information about offers made on the SDEX.
 // See https://developers.stellar.org/api/resources/offers/list/
 func (c *Client) Offers(request OfferRequest) (offers hProtocol.OffersPage, err error) {
-	err = c.sendRequest(request, &offers)
+	return c.OffersWithContext(context.Background(), request)
+}
+
+// OffersWithContext is like Offers but accepts a context.Context controlling the
+// request and its retries.
+func (c *Client) OffersWithContext(ctx context.Context, request OfferRequest) (offers hProtocol.OffersPage, err error) {
+	err = c.sendRequest(ctx, request, &offers)
 	return
 }
 
 // OfferDetails returns information for a single offer.
 // See https://developers.stellar.org/api/resources/offers/single/
 func (c *Client) OfferDetails(offerID string) (offer hProtocol.Offer, err error) {
+	return c.OfferDetailsWithContext(context.Background(), offerID)
+}
+
+// OfferDetailsWithContext is like OfferDetails but accepts a context.Context controlling the
+// request and its retries.
+func (c *Client) OfferDetailsWithContext(ctx context.Context, offerID string) (offer hProtocol.Offer, err error) {
 	if len(offerID) == 0 {
 		err = errors.New("no offer ID provided")
 		return
@@ -393,20 +367,32 @@ func (c *Client) OfferDetails(offerID string) (offer hProtocol.Offer, err error)
 		return
 	}
 
-	err = c.sendRequest(OfferRequest{OfferID: offerID}, &offer)
+	err = c.sendRequest(ctx, OfferRequest{OfferID: offerID}, &offer)
 	return
 }
 
 // Operations returns stellar operations (https://developers.stellar.org/api/resources/operations/list/)
 // It can be used to return operations for an account, a ledger, a transaction and all operations on the network.
 func (c *Client) Operations(request OperationRequest) (ops operations.OperationsPage, err error) {
-	err = c.sendRequest(request.SetOperationsEndpoint(), &ops)
+	return c.OperationsWithContext(context.Background(), request)
+}
+
+// OperationsWithContext is like Operations but accepts a context.Context controlling the
+// request and its retries.
+func (c *Client) OperationsWithContext(ctx context.Context, request OperationRequest) (ops operations.OperationsPage, err error) {
+	err = c.sendRequest(ctx, request.SetOperationsEndpoint(), &ops)
 	return
 }
 
 // OperationDetail returns a single stellar operation for a given operation id
 // See https://developers.stellar.org/api/resources/operations/single/
 func (c *Client) OperationDetail(id string) (ops operations.Operation, err error) {
+	return c.OperationDetailWithContext(context.Background(), id)
+}
+
+// OperationDetailWithContext is like OperationDetail but accepts a context.Context controlling the
+// request and its retries.
+func (c *Client) OperationDetailWithContext(ctx context.Context, id string) (ops operations.Operation, err error) {
 	if id == "" {
 		return ops, errors.New("invalid operation id provided")
 	}
@@ -415,7 +401,7 @@ func (c *Client) OperationDetail(id string) (ops operations.Operation, err error
 
 	var record interface{}
 
-	err = c.sendRequest(request, &record)
+	err = c.sendRequest(ctx, request, &record)
 	if err != nil {
 		return ops, errors.Wrap(err, "sending request to horizon")
 	}
@@ -439,9 +425,45 @@ func (c *Client) OperationDetail(id string) (ops operations.Operation, err error
 // SubmitTransactionXDR submits a transaction represented as a base64 XDR string to the network. err can be either error object or horizon.Error object.
 // See https://developers.stellar.org/api/resources/transactions/post/
 func (c *Client) SubmitTransactionXDR(transactionXdr string) (tx hProtocol.Transaction,
+	err error) {
+	return c.SubmitTransactionXDRWithContext(context.Background(), transactionXdr)
+}
+
+// SubmitTransactionXDRWithContext is like SubmitTransactionXDR but accepts a context.Context controlling the
+// request and its retries.
+//
+// When a RetryPolicy is set, a submission that may have reached the network before failing is not
+// blindly resubmitted: the transaction is first looked up by hash and returned if it was already
+// included in a ledger.
+func (c *Client) SubmitTransactionXDRWithContext(ctx context.Context, transactionXdr string) (tx hProtocol.Transaction,
 	err error) {
 	request := submitRequest{endpoint: "transactions", transactionXdr: transactionXdr}
-	err = c.sendRequest(request, &tx)
+	if c.RetryPolicy == nil {
+		err = c.sendRequest(ctx, request, &tx)
+		return
+	}
+
+	hash := c.transactionHash(ctx, transactionXdr)
+	submitted := false
+	err = c.retry(ctx, func(horizonURL string) error {
+		if submitted && hash != "" {
+			lookup, lookupErr := TransactionRequest{forTransactionHash: hash}.HTTPRequest(horizonURL)
+			if lookupErr != nil {
+				return lookupErr
+			}
+			lookupErr = c.sendHTTPRequest(ctx, lookup, horizonURL, &tx)
+			if !IsNotFoundError(lookupErr) {
+				return lookupErr
+			}
+		}
+
+		submitted = true
+		req, reqErr := request.HTTPRequest(horizonURL)
+		if reqErr != nil {
+			return reqErr
+		}
+		return c.sendHTTPRequest(ctx, req, horizonURL, &tx)
+	})
 	return
 }
 
@@ -455,7 +477,13 @@ func (c *Client) SubmitTransactionXDR(transactionXdr string) (tx hProtocol.Trans
 //
 // See https://developers.stellar.org/api/resources/transactions/post/
 func (c *Client) SubmitFeeBumpTransaction(transaction *txnbuild.FeeBumpTransaction) (tx hProtocol.Transaction, err error) {
-	return c.SubmitFeeBumpTransactionWithOptions(transaction, SubmitTxOpts{})
+	return c.SubmitFeeBumpTransactionWithContext(context.Background(), transaction)
+}
+
+// SubmitFeeBumpTransactionWithContext is like SubmitFeeBumpTransaction but accepts a context.Context controlling the
+// request and its retries.
+func (c *Client) SubmitFeeBumpTransactionWithContext(ctx context.Context, transaction *txnbuild.FeeBumpTransaction) (tx hProtocol.Transaction, err error) {
+	return c.SubmitFeeBumpTransactionWithOptionsWithContext(ctx, transaction, SubmitTxOpts{})
 }
 
 // SubmitFeeBumpTransactionWithOptions submits a fee bump transaction to the network, allowing
@@ -463,10 +491,16 @@ func (c *Client) SubmitFeeBumpTransaction(transaction *txnbuild.FeeBumpTransacti
 //
 // See https://developers.stellar.org/api/resources/transactions/post/
 func (c *Client) SubmitFeeBumpTransactionWithOptions(transaction *txnbuild.FeeBumpTransaction, opts SubmitTxOpts) (tx hProtocol.Transaction, err error) {
+	return c.SubmitFeeBumpTransactionWithOptionsWithContext(context.Background(), transaction, opts)
+}
+
+// SubmitFeeBumpTransactionWithOptionsWithContext is like SubmitFeeBumpTransactionWithOptions but accepts a context.Context controlling the
+// request and its retries.
+func (c *Client) SubmitFeeBumpTransactionWithOptionsWithContext(ctx context.Context, transaction *txnbuild.FeeBumpTransaction, opts SubmitTxOpts) (tx hProtocol.Transaction, err error) {
 	// only check if memo is required if skip is false and the inner transaction
 	// doesn't have a memo.
 	if inner := transaction.InnerTransaction(); !opts.SkipMemoRequiredCheck && inner.Memo() == nil {
-		err = c.checkMemoRequired(inner)
+		err = c.checkMemoRequired(ctx, inner)
 		if err != nil {
 			return
 		}
@@ -478,7 +512,7 @@ func (c *Client) SubmitFeeBumpTransactionWithOptions(transaction *txnbuild.FeeBu
 		return
 	}
 
-	return c.SubmitTransactionXDR(txeBase64)
+	return c.SubmitTransactionXDRWithContext(ctx, txeBase64)
 }
 
 // SubmitTransaction submits a transaction to the network. err can be either an
@@ -491,7 +525,13 @@ func (c *Client) SubmitFeeBumpTransactionWithOptions(transaction *txnbuild.FeeBu
 //
 // See https://developers.stellar.org/api/resources/transactions/post/
 func (c *Client) SubmitTransaction(transaction *txnbuild.Transaction) (tx hProtocol.Transaction, err error) {
-	return c.SubmitTransactionWithOptions(transaction, SubmitTxOpts{})
+	return c.SubmitTransactionWithContext(context.Background(), transaction)
+}
+
+// SubmitTransactionWithContext is like SubmitTransaction but accepts a context.Context controlling the
+// request and its retries.
+func (c *Client) SubmitTransactionWithContext(ctx context.Context, transaction *txnbuild.Transaction) (tx hProtocol.Transaction, err error) {
+	return c.SubmitTransactionWithOptionsWithContext(ctx, transaction, SubmitTxOpts{})
 }
 
 // SubmitTransactionWithOptions submits a transaction to the network, allowing
@@ -499,10 +539,16 @@ func (c *Client) SubmitTransaction(transaction *txnbuild.Transaction) (tx hProto
 //
 // See https://developers.stellar.org/api/resources/transactions/post/
 func (c *Client) SubmitTransactionWithOptions(transaction *txnbuild.Transaction, opts SubmitTxOpts) (tx hProtocol.Transaction, err error) {
+	return c.SubmitTransactionWithOptionsWithContext(context.Background(), transaction, opts)
+}
+
+// SubmitTransactionWithOptionsWithContext is like SubmitTransactionWithOptions but accepts a context.Context controlling the
+// request and its retries.
+func (c *Client) SubmitTransactionWithOptionsWithContext(ctx context.Context, transaction *txnbuild.Transaction, opts SubmitTxOpts) (tx hProtocol.Transaction, err error) {
 	// only check if memo is required if skip is false and the transaction
 	// doesn't have a memo.
 	if !opts.SkipMemoRequiredCheck && transaction.Memo() == nil {
-		err = c.checkMemoRequired(transaction)
+		err = c.checkMemoRequired(ctx, transaction)
 		if err != nil {
 			return
 		}
@@ -514,72 +560,126 @@ func (c *Client) SubmitTransactionWithOptions(transaction *txnbuild.Transaction,
 		return
 	}
 
-	return c.SubmitTransactionXDR(txeBase64)
+	return c.SubmitTransactionXDRWithContext(ctx, txeBase64)
 }
 
 // Transactions returns stellar transactions (https://developers.stellar.org/api/resources/transactions/list/)
 // It can be used to return transactions for an account, a ledger,and all transactions on the network.
 func (c *Client) Transactions(request TransactionRequest) (txs hProtocol.TransactionsPage, err error) {
-	err = c.sendRequest(request, &txs)
+	return c.TransactionsWithContext(context.Background(), request)
+}
+
+// TransactionsWithContext is like Transactions but accepts a context.Context controlling the
+// request and its retries.
+func (c *Client) TransactionsWithContext(ctx context.Context, request TransactionRequest) (txs hProtocol.TransactionsPage, err error) {
+	err = c.sendRequest(ctx, request, &txs)
 	return
 }
 
 // TransactionDetail returns information about a particular transaction for a given transaction hash
 // See https://developers.stellar.org/api/resources/transactions/single/
 func (c *Client) TransactionDetail(txHash string) (tx hProtocol.Transaction, err error) {
+	return c.TransactionDetailWithContext(context.Background(), txHash)
+}
+
+// TransactionDetailWithContext is like TransactionDetail but accepts a context.Context controlling the
+// request and its retries.
+func (c *Client) TransactionDetailWithContext(ctx context.Context, txHash string) (tx hProtocol.Transaction, err error) {
 	if txHash == "" {
 		return tx, errors.New("no transaction hash provided")
 	}
 
 	request := TransactionRequest{forTransactionHash: txHash}
-	err = c.sendRequest(request, &tx)
+	err = c.sendRequest(ctx, request, &tx)
 	return
 }
 
 // OrderBook returns the orderbook for an asset pair (https://developers.stellar.org/api/aggregations/order-books/single/)
 func (c *Client) OrderBook(request OrderBookRequest) (obs hProtocol.OrderBookSummary, err error) {
-	err = c.sendRequest(request, &obs)
+	return c.OrderBookWithContext(context.Background(), request)
+}
+
+// OrderBookWithContext is like OrderBook but accepts a context.Context controlling the
+// request and its retries.
+func (c *Client) OrderBookWithContext(ctx context.Context, request OrderBookRequest) (obs hProtocol.OrderBookSummary, err error) {
+	err = c.sendRequest(ctx, request, &obs)
 	return
 }
 
 // Paths returns the available paths to make a strict receive path payment. See https://developers.stellar.org/api/aggregations/paths/strict-receive/
 // This function is an alias for `client.StrictReceivePaths` and will be deprecated, use `client.StrictReceivePaths` instead.
 func (c *Client) Paths(request PathsRequest) (paths hProtocol.PathsPage, err error) {
-	paths, err = c.StrictReceivePaths(request)
+	return c.PathsWithContext(context.Background(), request)
+}
+
+// PathsWithContext is like Paths but accepts a context.Context controlling the
+// request and its retries.
+func (c *Client) PathsWithContext(ctx context.Context, request PathsRequest) (paths hProtocol.PathsPage, err error) {
+	paths, err = c.StrictReceivePathsWithContext(ctx, request)
 	return
 }
 
 // StrictReceivePaths returns the available paths to make a strict receive path payment. See https://developers.stellar.org/api/aggregations/paths/strict-receive/
 func (c *Client) StrictReceivePaths(request PathsRequest) (paths hProtocol.PathsPage, err error) {
-	err = c.sendRequest(request, &paths)
+	return c.StrictReceivePathsWithContext(context.Background(), request)
+}
+
+// StrictReceivePathsWithContext is like StrictReceivePaths but accepts a context.Context controlling the
+// request and its retries.
+func (c *Client) StrictReceivePathsWithContext(ctx context.Context, request PathsRequest) (paths hProtocol.PathsPage, err error) {
+	err = c.sendRequest(ctx, request, &paths)
 	return
 }
 
 // StrictSendPaths returns the available paths to make a strict send path payment. See https://developers.stellar.org/api/aggregations/paths/strict-send/
 func (c *Client) StrictSendPaths(request StrictSendPathsRequest) (paths hProtocol.PathsPage, err error) {
-	err = c.sendRequest(request, &paths)
+	return c.StrictSendPathsWithContext(context.Background(), request)
+}
+
+// StrictSendPathsWithContext is like StrictSendPaths but accepts a context.Context controlling the
+// request and its retries.
+func (c *Client) StrictSendPathsWithContext(ctx context.Context, request StrictSendPathsRequest) (paths hProtocol.PathsPage, err error) {
+	err = c.sendRequest(ctx, request, &paths)
 	return
 }
 
 // Payments returns stellar account_merge, create_account, path payment and payment operations.
 // It can be used to return payments for an account, a ledger, a transaction and all payments on the network.
 func (c *Client) Payments(request OperationRequest) (ops operations.OperationsPage, err error) {
-	err = c.sendRequest(request.SetPaymentsEndpoint(), &ops)
+	return c.PaymentsWithContext(context.Background(), request)
+}
+
+// PaymentsWithContext is like Payments but accepts a context.Context controlling the
+// request and its retries.
+func (c *Client) PaymentsWithContext(ctx context.Context, request OperationRequest) (ops operations.OperationsPage, err error) {
+	err = c.sendRequest(ctx, request.SetPaymentsEndpoint(), &ops)
 	return
 }
 
 // Trades returns stellar trades (https://developers.stellar.org/api/resources/trades/list/)
 // It can be used to return trades for an account, an offer and all trades on the network.
 func (c *Client) Trades(request TradeRequest) (tds hProtocol.TradesPage, err error) {
-	err = c.sendRequest(request, &tds)
+	return c.TradesWithContext(context.Background(), request)
+}
+
+// TradesWithContext is like Trades but accepts a context.Context controlling the
+// request and its retries.
+func (c *Client) TradesWithContext(ctx context.Context, request TradeRequest) (tds hProtocol.TradesPage, err error) {
+	err = c.sendRequest(ctx, request, &tds)
 	return
 }
 
 // Fund creates a new account funded from friendbot. It only works on test networks. See
 // https://developers.stellar.org/docs/tutorials/create-account/ for more information.
 func (c *Client) Fund(addr string) (tx hProtocol.Transaction, err error) {
+	return c.FundWithContext(context.Background(), addr)
+}
+
+// FundWithContext is like Fund but accepts a context.Context controlling the
+// request and its retries.
+func (c *Client) FundWithContext(ctx context.Context, addr string) (tx hProtocol.Transaction, err error) {
 	friendbotURL := fmt.Sprintf("%sfriendbot?addr=%s", c.fixHorizonURL(), addr)
-	err = c.sendGetRequest(friendbotURL, &tx)
+	err = c.sendGetRequest(ctx, friendbotURL, &tx)
 	if IsNotFoundError(err) {
 		return tx, errors.Wrap(err, "funding is only available on test networks and may not be supported by "+c.fixHorizonURL())
 	}
@@ -596,7 +696,13 @@ func (c *Client) StreamTrades(ctx context.Context, request TradeRequest, handler
 
 // TradeAggregations returns stellar trade aggregations (https://developers.stellar.org/api/aggregations/trade-aggregations/list/)
 func (c *Client) TradeAggregations(request TradeAggregationRequest) (tds hProtocol.TradeAggregationsPage, err error) {
-	err = c.sendRequest(request, &tds)
+	return c.TradeAggregationsWithContext(context.Background(), request)
+}
+
+// TradeAggregationsWithContext is like TradeAggregations but accepts a context.Context controlling the
+// request and its retries.
+func (c *Client) TradeAggregationsWithContext(ctx context.Context, request TradeAggregationRequest) (tds hProtocol.TradeAggregationsPage, err error) {
+	err = c.sendRequest(ctx, request, &tds)
 	return
 }
 
@@ -673,7 +779,13 @@ func (c *Client) FetchTimebounds(seconds int64) (txnbuild.TimeBounds, error) {
 
 // Root loads the root endpoint of horizon
 func (c *Client) Root() (root hProtocol.Root, err error) {
-	err = c.sendGetRequest(c.fixHorizonURL(), &root)
+	return c.RootWithContext(context.Background())
+}
+
+// RootWithContext is like Root but accepts a context.Context controlling the
+// request and its retries.
+func (c *Client) RootWithContext(ctx context.Context) (root hProtocol.Root, err error) {
+	err = c.sendGetRequest(ctx, c.fixHorizonURL(), &root)
 	return
 }
 
@@ -684,111 +796,219 @@ func (c *Client) Version() string {
 
 // NextAccountsPage returns the next page of accounts.
 func (c *Client) NextAccountsPage(page hProtocol.AccountsPage) (accounts hProtocol.AccountsPage, err error) {
-	err = c.sendGetRequest(page.Links.Next.Href, &accounts)
+	return c.NextAccountsPageWithContext(context.Background(), page)
+}
+
+// NextAccountsPageWithContext is like NextAccountsPage but accepts a context.Context controlling the
+// request and its retries.
+func (c *Client) NextAccountsPageWithContext(ctx context.Context, page hProtocol.AccountsPage) (accounts hProtocol.AccountsPage, err error) {
+	err = c.sendGetRequest(ctx, page.Links.Next.Href, &accounts)
 	return
 }
 
 // NextAssetsPage returns the next page of assets.
 func (c *Client) NextAssetsPage(page hProtocol.AssetsPage) (assets hProtocol.AssetsPage, err error) {
-	err = c.sendGetRequest(page.Links.Next.Href, &assets)
+	return c.NextAssetsPageWithContext(context.Background(), page)
+}
+
+// NextAssetsPageWithContext is like NextAssetsPage but accepts a context.Context controlling the
+// request and its retries.
+func (c *Client) NextAssetsPageWithContext(ctx context.Context, page hProtocol.AssetsPage) (assets hProtocol.AssetsPage, err error) {
+	err = c.sendGetRequest(ctx, page.Links.Next.Href, &assets)
 	return
 }
 
 // PrevAssetsPage returns the previous page of assets.
 func (c *Client) PrevAssetsPage(page hProtocol.AssetsPage) (assets hProtocol.AssetsPage, err error) {
-	err = c.sendGetRequest(page.Links.Prev.Href, &assets)
+	return c.PrevAssetsPageWithContext(context.Background(), page)
+}
+
+// PrevAssetsPageWithContext is like PrevAssetsPage but accepts a context.Context controlling the
+// request and its retries.
+func (c *Client) PrevAssetsPageWithContext(ctx context.Context, page hProtocol.AssetsPage) (assets hProtocol.AssetsPage, err error) {
+	err = c.sendGetRequest(ctx, page.Links.Prev.Href, &assets)
 	return
 }
 
 // NextLedgersPage returns the next page of ledgers.
 func (c *Client) NextLedgersPage(page hProtocol.LedgersPage) (ledgers hProtocol.LedgersPage, err error) {
-	err = c.sendGetRequest(page.Links.Next.Href, &ledgers)
+	return c.NextLedgersPageWithContext(context.Background(), page)
+}
+
+// NextLedgersPageWithContext is like NextLedgersPage but accepts a context.Context controlling the
+// request and its retries.
+func (c *Client) NextLedgersPageWithContext(ctx context.Context, page hProtocol.LedgersPage) (ledgers hProtocol.LedgersPage, err error) {
+	err = c.sendGetRequest(ctx, page.Links.Next.Href, &ledgers)
 	return
 }
 
 // PrevLedgersPage returns the previous page of ledgers.
 func (c *Client) PrevLedgersPage(page hProtocol.LedgersPage) (ledgers hProtocol.LedgersPage, err error) {
-	err = c.sendGetRequest(page.Links.Prev.Href, &ledgers)
+	return c.PrevLedgersPageWithContext(context.Background(), page)
+}
+
+// PrevLedgersPageWithContext is like PrevLedgersPage but accepts a context.Context controlling the
+// request and its retries.
+func (c *Client) PrevLedgersPageWithContext(ctx context.Context, page hProtocol.LedgersPage) (ledgers hProtocol.LedgersPage, err error) {
+	err = c.sendGetRequest(ctx, page.Links.Prev.Href, &ledgers)
 	return
 }
 
 // NextEffectsPage returns the next page of effects.
 func (c *Client) NextEffectsPage(page effects.EffectsPage) (efp effects.EffectsPage, err error) {
-	err = c.sendGetRequest(page.Links.Next.Href, &efp)
+	return c.NextEffectsPageWithContext(context.Background(), page)
+}
+
+// NextEffectsPageWithContext is like NextEffectsPage but accepts a context.Context controlling the
+// request and its retries.
+func (c *Client) NextEffectsPageWithContext(ctx context.Context, page effects.EffectsPage) (efp effects.EffectsPage, err error) {
+	err = c.sendGetRequest(ctx, page.Links.Next.Href, &efp)
 	return
 }
 
 // PrevEffectsPage returns the previous page of effects.
 func (c *Client) PrevEffectsPage(page effects.EffectsPage) (efp effects.EffectsPage, err error) {
-	err = c.sendGetRequest(page.Links.Prev.Href, &efp)
+	return c.PrevEffectsPageWithContext(context.Background(), page)
+}
+
+// PrevEffectsPageWithContext is like PrevEffectsPage but accepts a context.Context controlling the
+// request and its retries.
+func (c *Client) PrevEffectsPageWithContext(ctx context.Context, page effects.EffectsPage) (efp effects.EffectsPage, err error) {
+	err = c.sendGetRequest(ctx, page.Links.Prev.Href, &efp)
 	return
 }
 
 // NextTransactionsPage returns the next page of transactions.
 func (c *Client) NextTransactionsPage(page hProtocol.TransactionsPage) (transactions hProtocol.TransactionsPage, err error) {
-	err = c.sendGetRequest(page.Links.Next.Href, &transactions)
+	return c.NextTransactionsPageWithContext(context.Background(), page)
+}
+
+// NextTransactionsPageWithContext is like NextTransactionsPage but accepts a context.Context controlling the
+// request and its retries.
+func (c *Client) NextTransactionsPageWithContext(ctx context.Context, page hProtocol.TransactionsPage) (transactions hProtocol.TransactionsPage, err error) {
+	err = c.sendGetRequest(ctx, page.Links.Next.Href, &transactions)
 	return
 }
 
 // PrevTransactionsPage returns the previous page of transactions.
 func (c *Client) PrevTransactionsPage(page hProtocol.TransactionsPage) (transactions hProtocol.TransactionsPage, err error) {
-	err = c.sendGetRequest(page.Links.Prev.Href, &transactions)
+	return c.PrevTransactionsPageWithContext(context.Background(), page)
+}
+
+// PrevTransactionsPageWithContext is like PrevTransactionsPage but accepts a context.Context controlling the
+// request and its retries.
+func (c *Client) PrevTransactionsPageWithContext(ctx context.Context, page hProtocol.TransactionsPage) (transactions hProtocol.TransactionsPage, err error) {
+	err = c.sendGetRequest(ctx, page.Links.Prev.Href, &transactions)
 	return
 }
 
 // NextOperationsPage returns the next page of operations.
 func (c *Client) NextOperationsPage(page operations.OperationsPage) (operations operations.OperationsPage, err error) {
-	err = c.sendGetRequest(page.Links.Next.Href, &operations)
+	return c.NextOperationsPageWithContext(context.Background(), page)
+}
+
+// NextOperationsPageWithContext is like NextOperationsPage but accepts a context.Context controlling the
+// request and its retries.
+func (c *Client) NextOperationsPageWithContext(ctx context.Context, page operations.OperationsPage) (operations operations.OperationsPage, err error) {
+	err = c.sendGetRequest(ctx, page.Links.Next.Href, &operations)
 	return
 }
 
 // PrevOperationsPage returns the previous page of operations.
 func (c *Client) PrevOperationsPage(page operations.OperationsPage) (operations operations.OperationsPage, err error) {
-	err = c.sendGetRequest(page.Links.Prev.Href, &operations)
+	return c.PrevOperationsPageWithContext(context.Background(), page)
+}
+
+// PrevOperationsPageWithContext is like PrevOperationsPage but accepts a context.Context controlling the
+// request and its retries.
+func (c *Client) PrevOperationsPageWithContext(ctx context.Context, page operations.OperationsPage) (operations operations.OperationsPage, err error) {
+	err = c.sendGetRequest(ctx, page.Links.Prev.Href, &operations)
 	return
 }
 
 // NextPaymentsPage returns the next page of payments.
 func (c *Client) NextPaymentsPage(page operations.OperationsPage) (operations.OperationsPage, error) {
-	return c.NextOperationsPage(page)
+	return c.NextPaymentsPageWithContext(context.Background(), page)
+}
+
+// NextPaymentsPageWithContext is like NextPaymentsPage but accepts a context.Context controlling the
+// request and its retries.
+func (c *Client) NextPaymentsPageWithContext(ctx context.Context, page operations.OperationsPage) (operations.OperationsPage, error) {
+	return c.NextOperationsPageWithContext(ctx, page)
 }
 
 // PrevPaymentsPage returns the previous page of payments.
 func (c *Client) PrevPaymentsPage(page operations.OperationsPage) (operations.OperationsPage, error) {
-	return c.PrevOperationsPage(page)
+	return c.PrevPaymentsPageWithContext(context.Background(), page)
+}
+
+// PrevPaymentsPageWithContext is like PrevPaymentsPage but accepts a context.Context controlling the
+// request and its retries.
+func (c *Client) PrevPaymentsPageWithContext(ctx context.Context, page operations.OperationsPage) (operations.OperationsPage, error) {
+	return c.PrevOperationsPageWithContext(ctx, page)
 }
 
 // NextOffersPage returns the next page of offers.
 func (c *Client) NextOffersPage(page hProtocol.OffersPage) (offers hProtocol.OffersPage, err error) {
-	err = c.sendGetRequest(page.Links.Next.Href, &offers)
+	return c.NextOffersPageWithContext(context.Background(), page)
+}
+
+// NextOffersPageWithContext is like NextOffersPage but accepts a context.Context controlling the
+// request and its retries.
+func (c *Client) NextOffersPageWithContext(ctx context.Context, page hProtocol.OffersPage) (offers hProtocol.OffersPage, err error) {
+	err = c.sendGetRequest(ctx, page.Links.Next.Href, &offers)
 	return
 }
 
 // PrevOffersPage returns the previous page of offers.
 func (c *Client) PrevOffersPage(page hProtocol.OffersPage) (offers hProtocol.OffersPage, err error) {
-	err = c.sendGetRequest(page.Links.Prev.Href, &offers)
+	return c.PrevOffersPageWithContext(context.Background(), page)
+}
+
+// PrevOffersPageWithContext is like PrevOffersPage but accepts a context.Context controlling the
+// request and its retries.
+func (c *Client) PrevOffersPageWithContext(ctx context.Context, page hProtocol.OffersPage) (offers hProtocol.OffersPage, err error) {
+	err = c.sendGetRequest(ctx, page.Links.Prev.Href, &offers)
 	return
 }
 
 // NextTradesPage returns the next page of trades.
 func (c *Client) NextTradesPage(page hProtocol.TradesPage) (trades hProtocol.TradesPage, err error) {
-	err = c.sendGetRequest(page.Links.Next.Href, &trades)
+	return c.NextTradesPageWithContext(context.Background(), page)
+}
+
+// NextTradesPageWithContext is like NextTradesPage but accepts a context.Context controlling the
+// request and its retries.
+func (c *Client) NextTradesPageWithContext(ctx context.Context, page hProtocol.TradesPage) (trades hProtocol.TradesPage, err error) {
+	err = c.sendGetRequest(ctx, page.Links.Next.Href, &trades)
 	return
 }
 
 // PrevTradesPage returns the previous page of trades.
 func (c *Client) PrevTradesPage(page hProtocol.TradesPage) (trades hProtocol.TradesPage, err error) {
-	err = c.sendGetRequest(page.Links.Prev.Href, &trades)
+	return c.PrevTradesPageWithContext(context.Background(), page)
+}
+
+// PrevTradesPageWithContext is like PrevTradesPage but accepts a context.Context controlling the
+// request and its retries.
+func (c *Client) PrevTradesPageWithContext(ctx context.Context, page hProtocol.TradesPage) (trades hProtocol.TradesPage, err error) {
+	err = c.sendGetRequest(ctx, page.Links.Prev.Href, &trades)
 	return
 }
 
 // HomeDomainForAccount returns the home domain for a single account.
 func (c *Client) HomeDomainForAccount(aid string) (string, error) {
+	return c.HomeDomainForAccountWithContext(context.Background(), aid)
+}
+
+// HomeDomainForAccountWithContext is like HomeDomainForAccount but accepts a context.Context controlling the
+// request and its retries.
+func (c *Client) HomeDomainForAccountWithContext(ctx context.Context, aid string) (string, error) {
 	if aid == "" {
 		return "", errors.New("no account ID provided")
 	}
 
-	accountDetail, err := c.AccountDetail(AccountRequest{AccountID: aid})
+	accountDetail, err := c.AccountDetailWithContext(ctx, AccountRequest{AccountID: aid})
 	if err != nil {
 		return "", errors.Wrap(err, "get account detail failed")
 	}
@@ -799,50 +1019,100 @@ func (c *Client) HomeDomainForAccount(aid string) (string, error) {
 // NextTradeAggregationsPage returns the next page of trade aggregations from the current
 // trade aggregations response.
 func (c *Client) NextTradeAggregationsPage(page hProtocol.TradeAggregationsPage) (ta hProtocol.TradeAggregationsPage, err error) {
-	err = c.sendGetRequest(page.Links.Next.Href, &ta)
+	return c.NextTradeAggregationsPageWithContext(context.Background(), page)
+}
+
+// NextTradeAggregationsPageWithContext is like NextTradeAggregationsPage but accepts a context.Context controlling the
+// request and its retries.
+func (c *Client) NextTradeAggregationsPageWithContext(ctx context.Context, page hProtocol.TradeAggregationsPage) (ta hProtocol.TradeAggregationsPage, err error) {
+	err = c.sendGetRequest(ctx, page.Links.Next.Href, &ta)
 	return
 }
 
 // PrevTradeAggregationsPage returns the previous page of trade aggregations from the current
 // trade aggregations response.
 func (c *Client) PrevTradeAggregationsPage(page hProtocol.TradeAggregationsPage) (ta hProtocol.TradeAggregationsPage, err error) {
-	err = c.sendGetRequest(page.Links.Prev.Href, &ta)
+	return c.PrevTradeAggregationsPageWithContext(context.Background(), page)
+}
+
+// PrevTradeAggregationsPageWithContext is like PrevTradeAggregationsPage but accepts a context.Context controlling the
+// request and its retries.
+func (c *Client) PrevTradeAggregationsPageWithContext(ctx context.Context, page hProtocol.TradeAggregationsPage) (ta hProtocol.TradeAggregationsPage, err error) {
+	err = c.sendGetRequest(ctx, page.Links.Prev.Href, &ta)
 	return
 }
 
 // ClaimableBalances returns details about available claimable balances,
 // possibly filtered to a specific sponsor or other parameters.
 func (c *Client) ClaimableBalances(cbr ClaimableBalanceRequest) (cb hProtocol.ClaimableBalances, err error) {
-	err = c.sendRequest(cbr, &cb)
+	return c.ClaimableBalancesWithContext(context.Background(), cbr)
+}
+
+// ClaimableBalancesWithContext is like ClaimableBalances but accepts a context.Context controlling the
+// request and its retries.
+func (c *Client) ClaimableBalancesWithContext(ctx context.Context, cbr ClaimableBalanceRequest) (cb hProtocol.ClaimableBalances, err error) {
+	err = c.sendRequest(ctx, cbr, &cb)
 	return
 }
 
 // ClaimableBalance returns details about a *specific*, unique claimable balance.
 func (c *Client) ClaimableBalance(id string) (cb hProtocol.ClaimableBalance, err error) {
+	return c.ClaimableBalanceWithContext(context.Background(), id)
+}
+
+// ClaimableBalanceWithContext is like ClaimableBalance but accepts a context.Context controlling the
+// request and its retries.
+func (c *Client) ClaimableBalanceWithContext(ctx context.Context, id string) (cb hProtocol.ClaimableBalance, err error) {
 	cbr := ClaimableBalanceRequest{ID: id}
-	err = c.sendRequest(cbr, &cb)
+	err = c.sendRequest(ctx, cbr, &cb)
 	return
 }
 
 func (c *Client) LiquidityPoolDetail(request LiquidityPoolRequest) (lp hProtocol.LiquidityPool, err error) {
-	err = c.sendRequest(request, &lp)
+	return c.LiquidityPoolDetailWithContext(context.Background(), request)
+}
+
+// LiquidityPoolDetailWithContext is like LiquidityPoolDetail but accepts a context.Context controlling the
+// request and its retries.
+func (c *Client) LiquidityPoolDetailWithContext(ctx context.Context, request LiquidityPoolRequest) (lp hProtocol.LiquidityPool, err error) {
+	err = c.sendRequest(ctx, request, &lp)
 	return
 }
 
 func (c *Client) LiquidityPools(request LiquidityPoolsRequest) (lp hProtocol.LiquidityPoolsPage, err error) {
-	err = c.sendRequest(request, &lp)
+	return c.LiquidityPoolsWithContext(context.Background(), request)
+}
+
+// LiquidityPoolsWithContext is like LiquidityPools but accepts a context.Context controlling the
+// request and its retries.
+func (c *Client) LiquidityPoolsWithContext(ctx context.Context, request LiquidityPoolsRequest) (lp hProtocol.LiquidityPoolsPage, err error) {
+	err = c.sendRequest(ctx, request, &lp)
 	return
 }
 
 func (c *Client) NextLiquidityPoolsPage(page hProtocol.LiquidityPoolsPage) (lp hProtocol.LiquidityPoolsPage, err error) {
-	err = c.sendGetRequest(page.Links.Next.Href, &lp)
+	return c.NextLiquidityPoolsPageWithContext(context.Background(), page)
+}
+
+// NextLiquidityPoolsPageWithContext is like NextLiquidityPoolsPage but accepts a context.Context controlling the
+// request and its retries.
+func (c *Client) NextLiquidityPoolsPageWithContext(ctx context.Context, page hProtocol.LiquidityPoolsPage) (lp hProtocol.LiquidityPoolsPage, err error) {
+	err = c.sendGetRequest(ctx, page.Links.Next.Href, &lp)
 	return
 }
 
 func (c *Client) PrevLiquidityPoolsPage(page hProtocol.LiquidityPoolsPage) (lp hProtocol.LiquidityPoolsPage, err error) {
-	err = c.sendGetRequest(page.Links.Prev.Href, &lp)
+	return c.PrevLiquidityPoolsPageWithContext(context.Background(), page)
+}
+
+// PrevLiquidityPoolsPageWithContext is like PrevLiquidityPoolsPage but accepts a context.Context controlling the
+// request and its retries.
+func (c *Client) PrevLiquidityPoolsPageWithContext(ctx context.Context, page hProtocol.LiquidityPoolsPage) (lp hProtocol.LiquidityPoolsPage, err error) {
+	err = c.sendGetRequest(ctx, page.Links.Prev.Href, &lp)
 	return
 }
 
-// ensure that the horizon client implements ClientInterface
+// ensure that the horizon client implements ClientInterface and ContextClientInterface
 var _ ClientInterface = &Client{}
+var _ ContextClientInterface = &Client{}
+var _ txnbuild.BaseFeeEstimator = &Client{}
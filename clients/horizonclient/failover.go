@@ -0,0 +1,136 @@
+package horizonclient
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pownieh/stellar_go/support/clock"
+	"github.com/pownieh/stellar_go/support/errors"
+)
+
+// FailoverCooldown is how long a Horizon URL is avoided after a request to it
+// failed with a transient error, when other URLs are available.
+var FailoverCooldown = 30 * time.Second
+
+// endpointPool tracks the health of the Horizon URLs a Client can use.
+type endpointPool struct {
+	mu             sync.Mutex
+	urls           []string
+	unhealthyUntil []time.Time
+	clock          *clock.Clock
+}
+
+// endpoints returns the pool of Horizon URLs of the client, HorizonURL
+// followed by FailoverURLs.
+func (c *Client) endpoints() *endpointPool {
+	c.endpointsOnce.Do(func() {
+		urls := []string{c.fixHorizonURL()}
+		for _, u := range c.FailoverURLs {
+			urls = append(urls, strings.TrimRight(u, "/")+"/")
+		}
+		c.endpointPool = &endpointPool{
+			urls:           urls,
+			unhealthyUntil: make([]time.Time, len(urls)),
+			clock:          c.clock,
+		}
+	})
+	return c.endpointPool
+}
+
+// pick returns the first healthy URL in order of preference or, if all of
+// them failed recently, the one whose cooldown expires first.
+func (p *endpointPool) pick() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.clock.Now()
+	best := 0
+	for i, until := range p.unhealthyUntil {
+		if !now.Before(until) {
+			return p.urls[i]
+		}
+		if until.Before(p.unhealthyUntil[best]) {
+			best = i
+		}
+	}
+	return p.urls[best]
+}
+
+func (p *endpointPool) markUnhealthy(horizonURL string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, u := range p.urls {
+		if u == horizonURL {
+			p.unhealthyUntil[i] = p.clock.Now().Add(FailoverCooldown)
+		}
+	}
+}
+
+func (p *endpointPool) markHealthy(horizonURL string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, u := range p.urls {
+		if u == horizonURL {
+			p.unhealthyUntil[i] = time.Time{}
+		}
+	}
+}
+
+// rebase rewrites a link returned by one of the Horizon servers of the pool,
+// like a paging link, so that it points to horizonURL instead. Links to other
+// hosts are returned unchanged.
+func (p *endpointPool) rebase(link, horizonURL string) string {
+	for _, u := range p.urls {
+		if strings.HasPrefix(link, u) {
+			return horizonURL + link[len(u):]
+		}
+	}
+	return link
+}
+
+// CheckHealth queries the /health endpoint of HorizonURL and of every
+// FailoverURLs entry, so that subsequent requests are sent to an instance
+// reporting itself healthy. It returns an error if none of them is healthy.
+func (c *Client) CheckHealth(ctx context.Context) error {
+	c.setDefaultClient()
+	endpoints := c.endpoints()
+
+	healthy := 0
+	for _, horizonURL := range endpoints.urls {
+		if c.checkHealth(ctx, horizonURL) {
+			endpoints.markHealthy(horizonURL)
+			healthy++
+		} else {
+			endpoints.markUnhealthy(horizonURL)
+		}
+	}
+
+	if healthy == 0 {
+		return errors.New("no healthy horizon instance")
+	}
+	return nil
+}
+
+func (c *Client) checkHealth(ctx context.Context, horizonURL string) bool {
+	timeout := c.horizonTimeout
+	if timeout == 0 {
+		timeout = HorizonTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, horizonURL+"health", nil)
+	if err != nil {
+		return false
+	}
+	c.setClientAppHeaders(req)
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode >= 200 && resp.StatusCode < 300
+}
@@ -0,0 +1,54 @@
+package horizonclient
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/pownieh/stellar_go/support/errors"
+)
+
+// BuildURL creates the endpoint to be queried based on the data in the FeeEstimateRequest struct.
+func (fr FeeEstimateRequest) BuildURL() (endpoint string, err error) {
+	if fr.Probability < 0 || fr.Probability > 1 {
+		return endpoint, errors.New("invalid request: probability must be between 0 and 1")
+	}
+
+	endpoint = "fee_stats/estimate"
+	paramMap := make(map[string]string)
+	if fr.Probability != 0 {
+		paramMap["probability"] = strconv.FormatFloat(fr.Probability, 'f', -1, 64)
+	}
+	if fr.WithinLedgers != 0 {
+		paramMap["within_ledgers"] = strconv.FormatUint(uint64(fr.WithinLedgers), 10)
+	}
+	if fr.Operations != 0 {
+		paramMap["operations"] = strconv.FormatUint(uint64(fr.Operations), 10)
+	}
+	if fr.Window != 0 {
+		paramMap["window"] = strconv.FormatUint(uint64(fr.Window), 10)
+	}
+
+	queryParams := addQueryParams(paramMap)
+	if queryParams != "" {
+		endpoint = fmt.Sprintf("%s?%s", endpoint, queryParams)
+	}
+
+	_, err = url.Parse(endpoint)
+	if err != nil {
+		err = errors.Wrap(err, "failed to parse endpoint")
+	}
+
+	return endpoint, err
+}
+
+// HTTPRequest returns the http request for the fee estimate endpoint
+func (fr FeeEstimateRequest) HTTPRequest(horizonURL string) (*http.Request, error) {
+	endpoint, err := fr.BuildURL()
+	if err != nil {
+		return nil, err
+	}
+
+	return http.NewRequest("GET", horizonURL+endpoint, nil)
+}
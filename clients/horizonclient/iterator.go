@@ -0,0 +1,253 @@
+package horizonclient
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/pownieh/stellar_go/protocols/horizon/effects"
+	"github.com/pownieh/stellar_go/protocols/horizon/operations"
+	"github.com/pownieh/stellar_go/support/errors"
+	"github.com/pownieh/stellar_go/support/render/hal"
+)
+
+// IteratorOptions configures an Iterator. The page size and the order of the
+// records are the ones of the HorizonRequest, e.g. LedgerRequest.Limit and
+// LedgerRequest.Order.
+type IteratorOptions[T any] struct {
+	// MaxRecords is the maximum number of records returned by the iterator.
+	// Zero means no limit.
+	MaxRecords int
+	// Until, if set, ends the iteration before the first record for which it
+	// returns true.
+	Until func(record T) bool
+	// Prefetch fetches the next page in the background while the records of
+	// the current page are consumed.
+	Prefetch bool
+}
+
+// Iterator walks the records returned by a HorizonRequest across pages,
+// following the next links returned by Horizon until an empty page is
+// returned. T is the type of the records, e.g. hProtocol.Ledger for a
+// LedgerRequest, operations.Operation for an OperationRequest, whose endpoint
+// must be set with SetOperationsEndpoint or SetPaymentsEndpoint, or
+// effects.Effect for an EffectRequest.
+//
+// Example:
+//
+//	it := horizonclient.NewIterator[hProtocol.Ledger](ctx, client, horizonclient.LedgerRequest{Limit: 200}, horizonclient.IteratorOptions[hProtocol.Ledger]{})
+//	defer it.Close()
+//	for it.Next() {
+//		fmt.Println(it.Record().Sequence)
+//	}
+//	if err := it.Err(); err != nil {
+//		return err
+//	}
+type Iterator[T any] struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	client  *Client
+	request HorizonRequest
+	options IteratorOptions[T]
+
+	records  []T
+	record   T
+	next     string
+	returned int
+	done     bool
+	err      error
+	// prefetched receives the page following records when Prefetch is set.
+	prefetched chan pageResult[T]
+}
+
+type pageResult[T any] struct {
+	records []T
+	next    string
+	err     error
+}
+
+// recordsPage is the representation of any page of records returned by
+// Horizon.
+type recordsPage struct {
+	Links    hal.Links `json:"_links"`
+	Embedded struct {
+		Records []json.RawMessage `json:"records"`
+	} `json:"_embedded"`
+}
+
+// NewIterator returns an Iterator over the records returned by request. The
+// first page is fetched by the first call to Next. ctx controls all the
+// requests sent by the iterator.
+func NewIterator[T any](ctx context.Context, client *Client, request HorizonRequest, options IteratorOptions[T]) *Iterator[T] {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Iterator[T]{
+		ctx:     ctx,
+		cancel:  cancel,
+		client:  client,
+		request: request,
+		options: options,
+	}
+}
+
+// Next advances the iterator to the next record, which is then available
+// through Record. It returns false when there are no more records, when a
+// stop condition is met or when an error occurred, see Err.
+func (it *Iterator[T]) Next() bool {
+	if it.done {
+		return false
+	}
+	if it.options.MaxRecords > 0 && it.returned >= it.options.MaxRecords {
+		return it.stop(nil)
+	}
+
+	for len(it.records) == 0 {
+		if err := it.ctx.Err(); err != nil {
+			return it.stop(err)
+		}
+		result := it.fetch()
+		if result.err != nil {
+			return it.stop(result.err)
+		}
+		if len(result.records) == 0 {
+			return it.stop(nil)
+		}
+		it.records, it.next = result.records, result.next
+		if it.options.Prefetch && it.next != "" {
+			it.prefetch(it.next)
+		}
+	}
+
+	record := it.records[0]
+	if it.options.Until != nil && it.options.Until(record) {
+		return it.stop(nil)
+	}
+	it.records = it.records[1:]
+	it.record = record
+	it.returned++
+	return true
+}
+
+// Record returns the current record.
+func (it *Iterator[T]) Record() T {
+	return it.record
+}
+
+// Err returns the error which ended the iteration, if any.
+func (it *Iterator[T]) Err() error {
+	return it.err
+}
+
+// Close stops the iterator, canceling any request in progress. It should be
+// called when the iteration is abandoned before Next returns false, so that a
+// prefetch in progress is canceled.
+func (it *Iterator[T]) Close() {
+	it.done = true
+	it.cancel()
+}
+
+func (it *Iterator[T]) stop(err error) bool {
+	it.err = err
+	it.Close()
+	return false
+}
+
+// fetch returns the next page, waiting for the prefetched one if any.
+func (it *Iterator[T]) fetch() pageResult[T] {
+	if it.prefetched != nil {
+		prefetched := it.prefetched
+		it.prefetched = nil
+		select {
+		case result := <-prefetched:
+			return result
+		case <-it.ctx.Done():
+			return pageResult[T]{err: it.ctx.Err()}
+		}
+	}
+
+	if it.request != nil {
+		request := it.request
+		it.request = nil
+		return it.fetchPage(func(p *recordsPage) error {
+			return it.client.sendRequest(it.ctx, request, p)
+		})
+	}
+	if it.next == "" {
+		return pageResult[T]{}
+	}
+	next := it.next
+	return it.fetchPage(func(p *recordsPage) error {
+		return it.client.sendGetRequest(it.ctx, next, p)
+	})
+}
+
+func (it *Iterator[T]) prefetch(next string) {
+	// The channel is buffered so the goroutine never blocks, even if the
+	// iterator is not consumed anymore.
+	it.prefetched = make(chan pageResult[T], 1)
+	go func(result chan<- pageResult[T]) {
+		result <- it.fetchPage(func(p *recordsPage) error {
+			return it.client.sendGetRequest(it.ctx, next, p)
+		})
+	}(it.prefetched)
+}
+
+func (it *Iterator[T]) fetchPage(send func(*recordsPage) error) pageResult[T] {
+	var p recordsPage
+	if err := send(&p); err != nil {
+		return pageResult[T]{err: err}
+	}
+
+	records := make([]T, 0, len(p.Embedded.Records))
+	for _, raw := range p.Embedded.Records {
+		record, err := decodeRecord[T](raw)
+		if err != nil {
+			return pageResult[T]{err: errors.Wrap(err, "error decoding record")}
+		}
+		records = append(records, record)
+	}
+	return pageResult[T]{records: records, next: p.Links.Next.Href}
+}
+
+// decodeRecord decodes a record of a page. Operations and effects are decoded
+// to the concrete type matching their type field.
+func decodeRecord[T any](raw json.RawMessage) (T, error) {
+	var record T
+	switch r := any(&record).(type) {
+	case *operations.Operation:
+		var base operations.Base
+		if err := json.Unmarshal(raw, &base); err != nil {
+			return record, err
+		}
+		op, err := operations.UnmarshalOperation(base.TypeI, raw)
+		if err != nil {
+			return record, err
+		}
+		*r = op
+	case *effects.Effect:
+		var base effects.Base
+		if err := json.Unmarshal(raw, &base); err != nil {
+			return record, err
+		}
+		effect, err := effects.UnmarshalEffect(base.Type, raw)
+		if err != nil {
+			return record, err
+		}
+		*r = effect
+	default:
+		if err := json.Unmarshal(raw, &record); err != nil {
+			return record, err
+		}
+	}
+	return record, nil
+}
+
+// Collect returns all the records an Iterator over request would return.
+func Collect[T any](ctx context.Context, client *Client, request HorizonRequest, options IteratorOptions[T]) ([]T, error) {
+	it := NewIterator[T](ctx, client, request, options)
+	defer it.Close()
+
+	var records []T
+	for it.Next() {
+		records = append(records, it.Record())
+	}
+	return records, it.Err()
+}
@@ -0,0 +1,115 @@
+package horizonclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	hProtocol "github.com/pownieh/stellar_go/protocols/horizon"
+	"github.com/pownieh/stellar_go/protocols/horizon/operations"
+	"github.com/pownieh/stellar_go/support/http/httptest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ledgersPage returns a page of ledgers with the given sequences, linking to
+// the page after the last one.
+func ledgersPage(sequences ...int) string {
+	records := make([]string, 0, len(sequences))
+	for _, sequence := range sequences {
+		records = append(records, fmt.Sprintf(`{"sequence": %d, "paging_token": "%d"}`, sequence, sequence))
+	}
+	cursor := 0
+	if len(sequences) > 0 {
+		cursor = sequences[len(sequences)-1]
+	}
+	return fmt.Sprintf(`{
+		"_links": {"next": {"href": "https://localhost/ledgers?cursor=%d&limit=2"}},
+		"_embedded": {"records": [%s]}
+	}`, cursor, strings.Join(records, ","))
+}
+
+func mockLedgerPages(hmock *httptest.Client) {
+	hmock.On("GET", "https://localhost/ledgers?limit=2").ReturnString(http.StatusOK, ledgersPage(1, 2))
+	hmock.On("GET", "https://localhost/ledgers?cursor=2&limit=2").ReturnString(http.StatusOK, ledgersPage(3, 4))
+	hmock.On("GET", "https://localhost/ledgers?cursor=4&limit=2").ReturnString(http.StatusOK, ledgersPage(5))
+	hmock.On("GET", "https://localhost/ledgers?cursor=5&limit=2").ReturnString(http.StatusOK, ledgersPage())
+}
+
+func sequences(ledgers []hProtocol.Ledger) []int32 {
+	result := make([]int32, 0, len(ledgers))
+	for _, ledger := range ledgers {
+		result = append(result, ledger.Sequence)
+	}
+	return result
+}
+
+func TestIterator(t *testing.T) {
+	hmock := httptest.NewClient()
+	client := &Client{HorizonURL: "https://localhost/", HTTP: hmock}
+	mockLedgerPages(hmock)
+	request := LedgerRequest{Limit: 2}
+
+	for _, prefetch := range []bool{false, true} {
+		ledgers, err := Collect(context.Background(), client, request, IteratorOptions[hProtocol.Ledger]{Prefetch: prefetch})
+		require.NoError(t, err)
+		assert.Equal(t, []int32{1, 2, 3, 4, 5}, sequences(ledgers))
+	}
+
+	ledgers, err := Collect(context.Background(), client, request, IteratorOptions[hProtocol.Ledger]{MaxRecords: 3})
+	require.NoError(t, err)
+	assert.Equal(t, []int32{1, 2, 3}, sequences(ledgers))
+
+	ledgers, err = Collect(context.Background(), client, request, IteratorOptions[hProtocol.Ledger]{
+		Until: func(ledger hProtocol.Ledger) bool { return ledger.Sequence == 4 },
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int32{1, 2, 3}, sequences(ledgers))
+}
+
+func TestIteratorErrors(t *testing.T) {
+	hmock := httptest.NewClient()
+	client := &Client{HorizonURL: "https://localhost/", HTTP: hmock}
+	mockLedgerPages(hmock)
+	hmock.On("GET", "https://localhost/ledgers?cursor=2&limit=2").ReturnString(http.StatusNotFound, notFoundResponse)
+
+	ledgers, err := Collect(context.Background(), client, LedgerRequest{Limit: 2}, IteratorOptions[hProtocol.Ledger]{})
+	assert.True(t, IsNotFoundError(err))
+	assert.Equal(t, []int32{1, 2}, sequences(ledgers))
+
+	// the iteration stops when the context is canceled
+	ctx, cancel := context.WithCancel(context.Background())
+	it := NewIterator(ctx, client, LedgerRequest{Limit: 2}, IteratorOptions[hProtocol.Ledger]{Prefetch: true})
+	defer it.Close()
+	require.True(t, it.Next())
+	assert.Equal(t, int32(1), it.Record().Sequence)
+	cancel()
+	require.True(t, it.Next())
+	assert.False(t, it.Next())
+	assert.Equal(t, context.Canceled, it.Err())
+}
+
+func TestIteratorDecodesOperations(t *testing.T) {
+	hmock := httptest.NewClient()
+	client := &Client{HorizonURL: "https://localhost/", HTTP: hmock}
+
+	calls := 0
+	hmock.On("GET", "https://localhost/operations?limit=1").Return(sequenceResponder(&calls,
+		httpmock.NewStringResponder(http.StatusOK, `{
+			"_links": {"next": {"href": "https://localhost/operations?cursor=1&limit=1"}},
+			"_embedded": {"records": [{"id": "1", "type": "bump_sequence", "type_i": 11, "bump_to": "100"}]}
+		}`),
+	))
+	hmock.On("GET", "https://localhost/operations?cursor=1&limit=1").
+		ReturnString(http.StatusOK, `{"_embedded": {"records": []}}`)
+
+	ops, err := Collect(context.Background(), client, (&OperationRequest{Limit: 1}).SetOperationsEndpoint(), IteratorOptions[operations.Operation]{})
+	require.NoError(t, err)
+	require.Len(t, ops, 1)
+	bump, ok := ops[0].(operations.BumpSequence)
+	require.True(t, ok)
+	assert.Equal(t, "100", bump.BumpTo)
+}
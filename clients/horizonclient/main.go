@@ -95,6 +95,11 @@ var (
 	// when any of the destination accounts required a memo in the transaction.
 	ErrAccountRequiresMemo = errors.New("destination account requires a memo in the transaction")
 
+	// ErrStreamStalled is the error reported when a stream receives nothing,
+	// not even a keep-alive comment, for longer than
+	// StreamOptions.HeartbeatTimeout.
+	ErrStreamStalled = errors.New("stream stalled")
+
 	// HorizonTimeout is the default number of nanoseconds before a request to horizon times out.
 	HorizonTimeout = 60 * time.Second
 
@@ -135,6 +140,27 @@ type Client struct {
 	HorizonURL        string
 	fixHorizonURLOnce sync.Once
 
+	// FailoverURLs lists other Horizon servers, in order of preference, that
+	// requests are sent to while HorizonURL is failing. Failover only
+	// happens when requests are retried, see RetryPolicy.
+	FailoverURLs  []string
+	endpointsOnce sync.Once
+	endpointPool  *endpointPool
+
+	// RetryPolicy configures how requests failing with a transient error are
+	// retried. Requests are not retried when it is nil.
+	RetryPolicy *RetryPolicy
+
+	// StreamOptions configures how streams reconnect after failures. Streams
+	// stop on the first connection error when it is nil.
+	StreamOptions *StreamOptions
+
+	// NetworkPassphrase is used to compute the hash of submitted transactions
+	// when retrying submissions. It is loaded from the root endpoint when
+	// empty.
+	NetworkPassphrase      string
+	networkPassphraseMutex sync.Mutex
+
 	// HTTP client to make requests with
 	HTTP HTTP
 
@@ -182,6 +208,8 @@ type ClientInterface interface {
 	Ledgers(request LedgerRequest) (hProtocol.LedgersPage, error)
 	LedgerDetail(sequence uint32) (hProtocol.Ledger, error)
 	FeeStats() (hProtocol.FeeStats, error)
+	FeeEstimate(request FeeEstimateRequest) (hProtocol.FeeEstimate, error)
+	EstimateBaseFee(probability float64, withinLedgers uint32) (int64, error)
 	Offers(request OfferRequest) (hProtocol.OffersPage, error)
 	OfferDetails(offerID string) (offer hProtocol.Offer, err error)
 	Operations(request OperationRequest) (operations.OperationsPage, error)
@@ -234,6 +262,67 @@ type ClientInterface interface {
 	PrevLiquidityPoolsPage(hProtocol.LiquidityPoolsPage) (hProtocol.LiquidityPoolsPage, error)
 }
 
+// ContextClientInterface contains the context.Context accepting methods
+// implemented by the horizon client. It is kept separate from ClientInterface
+// so that existing implementations of ClientInterface keep compiling.
+type ContextClientInterface interface {
+	AccountsWithContext(context.Context, AccountsRequest) (hProtocol.AccountsPage, error)
+	AccountDetailWithContext(context.Context, AccountRequest) (hProtocol.Account, error)
+	AccountDataWithContext(context.Context, AccountRequest) (hProtocol.AccountData, error)
+	EffectsWithContext(context.Context, EffectRequest) (effects.EffectsPage, error)
+	AssetsWithContext(context.Context, AssetRequest) (hProtocol.AssetsPage, error)
+	LedgersWithContext(context.Context, LedgerRequest) (hProtocol.LedgersPage, error)
+	LedgerDetailWithContext(context.Context, uint32) (hProtocol.Ledger, error)
+	FeeStatsWithContext(context.Context) (hProtocol.FeeStats, error)
+	FeeEstimateWithContext(context.Context, FeeEstimateRequest) (hProtocol.FeeEstimate, error)
+	OffersWithContext(context.Context, OfferRequest) (hProtocol.OffersPage, error)
+	OfferDetailsWithContext(context.Context, string) (hProtocol.Offer, error)
+	OperationsWithContext(context.Context, OperationRequest) (operations.OperationsPage, error)
+	OperationDetailWithContext(context.Context, string) (operations.Operation, error)
+	SubmitTransactionXDRWithContext(context.Context, string) (hProtocol.Transaction, error)
+	SubmitFeeBumpTransactionWithContext(context.Context, *txnbuild.FeeBumpTransaction) (hProtocol.Transaction, error)
+	SubmitFeeBumpTransactionWithOptionsWithContext(context.Context, *txnbuild.FeeBumpTransaction, SubmitTxOpts) (hProtocol.Transaction, error)
+	SubmitTransactionWithContext(context.Context, *txnbuild.Transaction) (hProtocol.Transaction, error)
+	SubmitTransactionWithOptionsWithContext(context.Context, *txnbuild.Transaction, SubmitTxOpts) (hProtocol.Transaction, error)
+	TransactionsWithContext(context.Context, TransactionRequest) (hProtocol.TransactionsPage, error)
+	TransactionDetailWithContext(context.Context, string) (hProtocol.Transaction, error)
+	OrderBookWithContext(context.Context, OrderBookRequest) (hProtocol.OrderBookSummary, error)
+	PathsWithContext(context.Context, PathsRequest) (hProtocol.PathsPage, error)
+	StrictReceivePathsWithContext(context.Context, PathsRequest) (hProtocol.PathsPage, error)
+	StrictSendPathsWithContext(context.Context, StrictSendPathsRequest) (hProtocol.PathsPage, error)
+	PaymentsWithContext(context.Context, OperationRequest) (operations.OperationsPage, error)
+	TradesWithContext(context.Context, TradeRequest) (hProtocol.TradesPage, error)
+	FundWithContext(context.Context, string) (hProtocol.Transaction, error)
+	TradeAggregationsWithContext(context.Context, TradeAggregationRequest) (hProtocol.TradeAggregationsPage, error)
+	RootWithContext(context.Context) (hProtocol.Root, error)
+	NextAccountsPageWithContext(context.Context, hProtocol.AccountsPage) (hProtocol.AccountsPage, error)
+	NextAssetsPageWithContext(context.Context, hProtocol.AssetsPage) (hProtocol.AssetsPage, error)
+	PrevAssetsPageWithContext(context.Context, hProtocol.AssetsPage) (hProtocol.AssetsPage, error)
+	NextLedgersPageWithContext(context.Context, hProtocol.LedgersPage) (hProtocol.LedgersPage, error)
+	PrevLedgersPageWithContext(context.Context, hProtocol.LedgersPage) (hProtocol.LedgersPage, error)
+	NextEffectsPageWithContext(context.Context, effects.EffectsPage) (effects.EffectsPage, error)
+	PrevEffectsPageWithContext(context.Context, effects.EffectsPage) (effects.EffectsPage, error)
+	NextTransactionsPageWithContext(context.Context, hProtocol.TransactionsPage) (hProtocol.TransactionsPage, error)
+	PrevTransactionsPageWithContext(context.Context, hProtocol.TransactionsPage) (hProtocol.TransactionsPage, error)
+	NextOperationsPageWithContext(context.Context, operations.OperationsPage) (operations.OperationsPage, error)
+	PrevOperationsPageWithContext(context.Context, operations.OperationsPage) (operations.OperationsPage, error)
+	NextPaymentsPageWithContext(context.Context, operations.OperationsPage) (operations.OperationsPage, error)
+	PrevPaymentsPageWithContext(context.Context, operations.OperationsPage) (operations.OperationsPage, error)
+	NextOffersPageWithContext(context.Context, hProtocol.OffersPage) (hProtocol.OffersPage, error)
+	PrevOffersPageWithContext(context.Context, hProtocol.OffersPage) (hProtocol.OffersPage, error)
+	NextTradesPageWithContext(context.Context, hProtocol.TradesPage) (hProtocol.TradesPage, error)
+	PrevTradesPageWithContext(context.Context, hProtocol.TradesPage) (hProtocol.TradesPage, error)
+	HomeDomainForAccountWithContext(context.Context, string) (string, error)
+	NextTradeAggregationsPageWithContext(context.Context, hProtocol.TradeAggregationsPage) (hProtocol.TradeAggregationsPage, error)
+	PrevTradeAggregationsPageWithContext(context.Context, hProtocol.TradeAggregationsPage) (hProtocol.TradeAggregationsPage, error)
+	ClaimableBalancesWithContext(context.Context, ClaimableBalanceRequest) (hProtocol.ClaimableBalances, error)
+	ClaimableBalanceWithContext(context.Context, string) (hProtocol.ClaimableBalance, error)
+	LiquidityPoolDetailWithContext(context.Context, LiquidityPoolRequest) (hProtocol.LiquidityPool, error)
+	LiquidityPoolsWithContext(context.Context, LiquidityPoolsRequest) (hProtocol.LiquidityPoolsPage, error)
+	NextLiquidityPoolsPageWithContext(context.Context, hProtocol.LiquidityPoolsPage) (hProtocol.LiquidityPoolsPage, error)
+	PrevLiquidityPoolsPageWithContext(context.Context, hProtocol.LiquidityPoolsPage) (hProtocol.LiquidityPoolsPage, error)
+}
+
 // DefaultTestNetClient is a default client to connect to test network.
 var DefaultTestNetClient = &Client{
 	HorizonURL:     "https://horizon-testnet.stellar.org/",
@@ -315,6 +404,20 @@ type feeStatsRequest struct {
 	endpoint string
 }
 
+// FeeEstimateRequest struct contains data for estimating the fee of a transaction from a horizon server.
+// All the fields are optional, Horizon estimates the fee of a single operation transaction to be included
+// with a 90% probability in the next ledger, given the fees of the last 100 ledgers, by default.
+type FeeEstimateRequest struct {
+	// Probability is the target probability, greater than 0 and at most 1, of the transaction being included.
+	Probability float64
+	// WithinLedgers is the number of ledgers within which the transaction should be included.
+	WithinLedgers uint32
+	// Operations is the number of operations of the transaction.
+	Operations uint32
+	// Window is the number of recent ledgers the estimate is based on.
+	Window uint32
+}
+
 // OfferRequest struct contains data for getting offers made by an account from a horizon server.
 // The query parameters (Order, Cursor and Limit) are optional. All or none can be set.
 type OfferRequest struct {
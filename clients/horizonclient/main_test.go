@@ -1,6 +1,7 @@
 package horizonclient
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"testing"
@@ -165,7 +166,7 @@ func TestCheckMemoRequired(t *testing.T) {
 				).ReturnString(404, notFoundResponse)
 			}
 
-			err = client.checkMemoRequired(tx)
+			err = client.checkMemoRequired(context.Background(), tx)
 
 			if len(tc.expected) > 0 {
 				tt.Error(err)
@@ -488,6 +489,46 @@ func TestAssetsRequest(t *testing.T) {
 
 }
 
+func TestFeeEstimate(t *testing.T) {
+	hmock := httptest.NewClient()
+	client := &Client{
+		HorizonURL: "https://localhost/",
+		HTTP:       hmock,
+	}
+
+	hmock.On(
+		"GET",
+		"https://localhost/fee_stats/estimate?operations=2&probability=0.95&within_ledgers=3",
+	).ReturnString(200, feeEstimateResponse)
+
+	estimate, err := client.FeeEstimate(FeeEstimateRequest{Probability: 0.95, WithinLedgers: 3, Operations: 2})
+	if assert.NoError(t, err) {
+		assert.Equal(t, uint32(22606298), estimate.LastLedger)
+		assert.Equal(t, int64(100), estimate.LastLedgerBaseFee)
+		assert.Equal(t, uint32(100), estimate.Ledgers)
+		assert.Equal(t, 0.95, estimate.Probability)
+		assert.Equal(t, uint32(3), estimate.WithinLedgers)
+		assert.Equal(t, int64(250), estimate.InclusionFee)
+		assert.Equal(t, int64(500), estimate.MaxFee)
+		assert.Equal(t, int64(12), estimate.Soroban.TransactionCount)
+		assert.Equal(t, int64(20000), estimate.Soroban.FeeCharged.P50)
+		assert.Equal(t, int64(2500), estimate.Soroban.RefundableFee.P90)
+	}
+
+	hmock.On(
+		"GET",
+		"https://localhost/fee_stats/estimate?probability=0.95&within_ledgers=3",
+	).ReturnString(200, feeEstimateResponse)
+
+	baseFee, err := client.EstimateBaseFee(0.95, 3)
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(250), baseFee)
+	}
+
+	_, err = client.FeeEstimate(FeeEstimateRequest{Probability: 2})
+	assert.EqualError(t, err, "invalid request: probability must be between 0 and 1")
+}
+
 func TestFeeStats(t *testing.T) {
 	hmock := httptest.NewClient()
 	client := &Client{
@@ -2047,6 +2088,35 @@ var feesResponse = `{
   }
 }`
 
+var feeEstimateResponse = `{
+  "last_ledger": "22606298",
+  "last_ledger_base_fee": "100",
+  "ledgers": "100",
+  "probability": "0.95",
+  "within_ledgers": "3",
+  "inclusion_fee": "250",
+  "max_fee": "500",
+  "soroban": {
+    "transaction_count": "12",
+    "fee_charged": {
+      "max": "90000",
+      "min": "5000",
+      "p10": "6000",
+      "p50": "20000",
+      "p90": "70000",
+      "p99": "90000"
+    },
+    "refundable_fee": {
+      "max": "3000",
+      "min": "100",
+      "p10": "200",
+      "p50": "1000",
+      "p90": "2500",
+      "p99": "3000"
+    }
+  }
+}`
+
 var offersResponse = `{
   "_links": {
     "self": {
@@ -67,6 +67,18 @@ func (m *MockClient) FeeStats() (hProtocol.FeeStats, error) {
 	return a.Get(0).(hProtocol.FeeStats), a.Error(1)
 }
 
+// FeeEstimate is a mocking method
+func (m *MockClient) FeeEstimate(request FeeEstimateRequest) (hProtocol.FeeEstimate, error) {
+	a := m.Called(request)
+	return a.Get(0).(hProtocol.FeeEstimate), a.Error(1)
+}
+
+// EstimateBaseFee is a mocking method
+func (m *MockClient) EstimateBaseFee(probability float64, withinLedgers uint32) (int64, error) {
+	a := m.Called(probability, withinLedgers)
+	return a.Get(0).(int64), a.Error(1)
+}
+
 // Offers is a mocking method
 func (m *MockClient) Offers(request OfferRequest) (hProtocol.OffersPage, error) {
 	a := m.Called(request)
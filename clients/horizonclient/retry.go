@@ -0,0 +1,137 @@
+package horizonclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pownieh/stellar_go/support/clock"
+)
+
+// DefaultRetryPolicy is a reasonable retry policy for clients talking to
+// public Horizon instances.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    4,
+	InitialBackoff: 500 * time.Millisecond,
+	MaxBackoff:     30 * time.Second,
+}
+
+// RetryPolicy configures how a Client retries requests failing with a
+// connection error, a timeout or a 429, 502, 503 or 504 status code. Other
+// errors, including all other Horizon problems, are returned immediately.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of times a request is sent,
+	// including the first attempt. Values lower than 2 disable retries.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry. The delay doubles
+	// on each subsequent retry.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between two attempts, including delays
+	// requested by Horizon in a Retry-After header.
+	MaxBackoff time.Duration
+}
+
+// backoff returns the delay to wait before the given retry, starting at 1.
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	delay := p.InitialBackoff
+	for i := 1; i < retry && (p.MaxBackoff <= 0 || delay < p.MaxBackoff); i++ {
+		delay *= 2
+	}
+	return p.capBackoff(delay)
+}
+
+func (p RetryPolicy) capBackoff(delay time.Duration) time.Duration {
+	if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+		return p.MaxBackoff
+	}
+	return delay
+}
+
+// retryableError marks an error as transient. retryAfter is the delay
+// requested by Horizon, if any.
+type retryableError struct {
+	err        error
+	retryAfter time.Duration
+}
+
+func (e *retryableError) Error() string {
+	return e.err.Error()
+}
+
+func (e *retryableError) Unwrap() error {
+	return e.err
+}
+
+// isRetryableStatus returns true for status codes signaling that Horizon, or
+// a proxy in front of it, is temporarily unable to serve the request.
+func isRetryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// parseRetryAfter parses a Retry-After header, which is either a number of
+// seconds or an HTTP date. It returns 0 when the header is missing or
+// invalid.
+func parseRetryAfter(header http.Header, c *clock.Clock) time.Duration {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := date.Sub(c.Now()); delay > 0 {
+			return delay
+		}
+	}
+	return 0
+}
+
+// retry calls attempt, passing it the Horizon URL to use, until it succeeds,
+// fails with an error which is not a *retryableError or the retry policy is
+// exhausted. Failed Horizon URLs are reported to the endpoint pool so the
+// next attempt can fail over to another instance.
+func (c *Client) retry(ctx context.Context, attempt func(horizonURL string) error) error {
+	policy := RetryPolicy{MaxAttempts: 1}
+	if c.RetryPolicy != nil {
+		policy = *c.RetryPolicy
+	}
+	endpoints := c.endpoints()
+
+	for i := 1; ; i++ {
+		horizonURL := endpoints.pick()
+		err := attempt(horizonURL)
+
+		var retryable *retryableError
+		if !errors.As(err, &retryable) {
+			endpoints.markHealthy(horizonURL)
+			return err
+		}
+		endpoints.markUnhealthy(horizonURL)
+		if i >= policy.MaxAttempts || ctx.Err() != nil {
+			return retryable.err
+		}
+
+		delay := policy.backoff(i)
+		if retryable.retryAfter > 0 {
+			delay = policy.capBackoff(retryable.retryAfter)
+		}
+		select {
+		case <-ctx.Done():
+			return retryable.err
+		case <-time.After(delay):
+		}
+	}
+}
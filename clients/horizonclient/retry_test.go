@@ -0,0 +1,238 @@
+package horizonclient
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/pownieh/stellar_go/network"
+	hProtocol "github.com/pownieh/stellar_go/protocols/horizon"
+	"github.com/pownieh/stellar_go/support/clock"
+	"github.com/pownieh/stellar_go/support/clock/clocktest"
+	"github.com/pownieh/stellar_go/support/http/httptest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: time.Millisecond,
+	MaxBackoff:     10 * time.Millisecond,
+}
+
+// sequenceResponder returns the given responders in order, repeating the last
+// one, and counts the calls.
+func sequenceResponder(calls *int, responders ...httpmock.Responder) httpmock.Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		i := *calls
+		if i >= len(responders) {
+			i = len(responders) - 1
+		}
+		*calls++
+		return responders[i](req)
+	}
+}
+
+func TestRetryOnServiceUnavailable(t *testing.T) {
+	hmock := httptest.NewClient()
+	client := &Client{
+		HorizonURL:  "https://localhost/",
+		HTTP:        hmock,
+		RetryPolicy: &testRetryPolicy,
+	}
+
+	calls := 0
+	hmock.On("GET", "https://localhost/").Return(sequenceResponder(&calls,
+		httpmock.NewStringResponder(http.StatusServiceUnavailable, `{"status": 503, "title": "Service Unavailable"}`),
+		httpmock.NewStringResponder(http.StatusTooManyRequests, `{"status": 429, "title": "Rate Limit Exceeded"}`),
+		httpmock.NewStringResponder(http.StatusOK, rootResponse),
+	))
+
+	root, err := client.Root()
+	require.NoError(t, err)
+	assert.Equal(t, "Test SDF Network ; September 2015", root.NetworkPassphrase)
+	assert.Equal(t, 3, calls)
+
+	// the last error is returned once attempts are exhausted
+	calls = 0
+	hmock.On("GET", "https://localhost/").Return(sequenceResponder(&calls,
+		httpmock.NewStringResponder(http.StatusServiceUnavailable, `{"status": 503, "title": "Service Unavailable"}`),
+	))
+	_, err = client.Root()
+	require.Error(t, err)
+	assert.Equal(t, "Service Unavailable", GetError(err).Problem.Title)
+	assert.Equal(t, 3, calls)
+}
+
+func TestNoRetryWithoutPolicyOrOnPermanentErrors(t *testing.T) {
+	hmock := httptest.NewClient()
+	client := &Client{
+		HorizonURL: "https://localhost/",
+		HTTP:       hmock,
+	}
+
+	calls := 0
+	hmock.On("GET", "https://localhost/").Return(sequenceResponder(&calls,
+		httpmock.NewStringResponder(http.StatusServiceUnavailable, `{"status": 503, "title": "Service Unavailable"}`),
+	))
+	_, err := client.Root()
+	assert.Error(t, err)
+	assert.Equal(t, 1, calls)
+
+	client.RetryPolicy = &testRetryPolicy
+	calls = 0
+	hmock.On("GET", "https://localhost/").Return(sequenceResponder(&calls,
+		httpmock.NewStringResponder(http.StatusNotFound, notFoundResponse),
+	))
+	_, err = client.Root()
+	assert.True(t, IsNotFoundError(err))
+	assert.Equal(t, 1, calls)
+}
+
+func TestRetryStopsWhenContextIsDone(t *testing.T) {
+	hmock := httptest.NewClient()
+	client := &Client{
+		HorizonURL: "https://localhost/",
+		HTTP:       hmock,
+		RetryPolicy: &RetryPolicy{
+			MaxAttempts:    10,
+			InitialBackoff: time.Hour,
+		},
+	}
+
+	calls := 0
+	hmock.On("GET", "https://localhost/").Return(sequenceResponder(&calls,
+		httpmock.NewStringResponder(http.StatusServiceUnavailable, `{"status": 503, "title": "Service Unavailable"}`),
+	))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := client.RootWithContext(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestFailover(t *testing.T) {
+	hmock := httptest.NewClient()
+	client := &Client{
+		HorizonURL:   "https://primary/",
+		FailoverURLs: []string{"https://secondary"},
+		HTTP:         hmock,
+		RetryPolicy:  &testRetryPolicy,
+	}
+
+	primaryCalls, secondaryCalls := 0, 0
+	hmock.On("GET", "https://primary/").Return(sequenceResponder(&primaryCalls,
+		httpmock.NewErrorResponder(assert.AnError),
+	))
+	hmock.On("GET", "https://secondary/").Return(sequenceResponder(&secondaryCalls,
+		httpmock.NewStringResponder(http.StatusOK, rootResponse),
+	))
+
+	_, err := client.Root()
+	require.NoError(t, err)
+	assert.Equal(t, 1, primaryCalls)
+	assert.Equal(t, 1, secondaryCalls)
+
+	// the primary is avoided during the cooldown
+	_, err = client.Root()
+	require.NoError(t, err)
+	assert.Equal(t, 1, primaryCalls)
+	assert.Equal(t, 2, secondaryCalls)
+
+	// paging links returned by the primary are rebased on the secondary
+	hmock.On("GET", "https://secondary/ledgers?cursor=1").
+		ReturnString(http.StatusOK, `{"_embedded": {"records": []}}`)
+	page, err := client.NextLedgersPageWithContext(context.Background(), ledgersPageWithNext("https://primary/ledgers?cursor=1"))
+	require.NoError(t, err)
+	assert.Empty(t, page.Embedded.Records)
+}
+
+func ledgersPageWithNext(next string) hProtocol.LedgersPage {
+	var page hProtocol.LedgersPage
+	page.Links.Next.Href = next
+	return page
+}
+
+func TestCheckHealth(t *testing.T) {
+	hmock := httptest.NewClient()
+	client := &Client{
+		HorizonURL:   "https://primary/",
+		FailoverURLs: []string{"https://secondary/"},
+		HTTP:         hmock,
+	}
+
+	hmock.On("GET", "https://primary/health").ReturnString(http.StatusServiceUnavailable, "{}")
+	hmock.On("GET", "https://secondary/health").ReturnString(http.StatusOK, "{}")
+	require.NoError(t, client.CheckHealth(context.Background()))
+	assert.Equal(t, "https://secondary/", client.endpoints().pick())
+
+	hmock.On("GET", "https://secondary/health").ReturnError("connection refused")
+	assert.EqualError(t, client.CheckHealth(context.Background()), "no healthy horizon instance")
+}
+
+func TestSubmitRetryLooksUpTransactionByHash(t *testing.T) {
+	hmock := httptest.NewClient()
+	client := &Client{
+		HorizonURL:        "https://localhost/",
+		HTTP:              hmock,
+		RetryPolicy:       &testRetryPolicy,
+		NetworkPassphrase: network.TestNetworkPassphrase,
+	}
+
+	txXdr := `AAAAABB90WssODNIgi6BHveqzxTRmIpvAFRyVNM+Hm2GVuCcAAAAZAAABD0AAuV/AAAAAAAAAAAAAAABAAAAAAAAAAAAAAAAyTBGxOgfSApppsTnb/YRr6gOR8WT0LZNrhLh4y3FCgoAAAAXSHboAAAAAAAAAAABhlbgnAAAAEAivKe977CQCxMOKTuj+cWTFqc2OOJU8qGr9afrgu2zDmQaX5Q0cNshc3PiBwe0qw/+D/qJk5QqM5dYeSUGeDQP`
+	hash := client.transactionHash(context.Background(), txXdr)
+	require.NotEmpty(t, hash)
+
+	submissions, lookups := 0, 0
+	hmock.On("POST", "https://localhost/transactions").Return(sequenceResponder(&submissions,
+		httpmock.NewStringResponder(http.StatusGatewayTimeout, `{"status": 504, "title": "Timeout"}`),
+	))
+	hmock.On("GET", "https://localhost/transactions/"+hash).Return(sequenceResponder(&lookups,
+		httpmock.NewStringResponder(http.StatusOK, txSuccess),
+	))
+
+	tx, err := client.SubmitTransactionXDR(txXdr)
+	require.NoError(t, err)
+	assert.Equal(t, int32(354811), tx.Ledger)
+	assert.Equal(t, 1, submissions)
+	assert.Equal(t, 1, lookups)
+
+	// the transaction is resubmitted when it is not found
+	submissions, lookups = 0, 0
+	hmock.On("POST", "https://localhost/transactions").Return(sequenceResponder(&submissions,
+		httpmock.NewStringResponder(http.StatusGatewayTimeout, `{"status": 504, "title": "Timeout"}`),
+		httpmock.NewStringResponder(http.StatusOK, txSuccess),
+	))
+	hmock.On("GET", "https://localhost/transactions/"+hash).Return(sequenceResponder(&lookups,
+		httpmock.NewStringResponder(http.StatusNotFound, notFoundResponse),
+	))
+	_, err = client.SubmitTransactionXDR(txXdr)
+	require.NoError(t, err)
+	assert.Equal(t, 2, submissions)
+	assert.Equal(t, 1, lookups)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := &clock.Clock{Source: clocktest.FixedSource(now)}
+
+	assert.Equal(t, time.Duration(0), parseRetryAfter(http.Header{}, c))
+	assert.Equal(t, 5*time.Second, parseRetryAfter(http.Header{"Retry-After": []string{"5"}}, c))
+	assert.Equal(t, time.Duration(0), parseRetryAfter(http.Header{"Retry-After": []string{"-5"}}, c))
+	assert.Equal(t, 2*time.Minute, parseRetryAfter(http.Header{
+		"Retry-After": []string{now.Add(2 * time.Minute).Format(http.TimeFormat)},
+	}, c))
+	assert.Equal(t, time.Duration(0), parseRetryAfter(http.Header{"Retry-After": []string{"soon"}}, c))
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}
+	assert.Equal(t, time.Second, p.backoff(1))
+	assert.Equal(t, 2*time.Second, p.backoff(2))
+	assert.Equal(t, 4*time.Second, p.backoff(3))
+	assert.Equal(t, 5*time.Second, p.backoff(4))
+	assert.Equal(t, 5*time.Second, p.backoff(100))
+}
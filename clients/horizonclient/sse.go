@@ -0,0 +1,126 @@
+package horizonclient
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sseEvent is an event received from a Server-Sent Events stream.
+type sseEvent struct {
+	// Event is the event type, "message" when the server did not set one.
+	Event string
+	// ID is the last event ID, which Horizon sets to the paging token of the
+	// streamed record.
+	ID   string
+	Data []byte
+}
+
+// sseDecoder reads events from a Server-Sent Events stream, as specified in
+// https://html.spec.whatwg.org/multipage/server-sent-events.html, without
+// buffering more than one line at a time.
+type sseDecoder struct {
+	reader *bufio.Reader
+
+	// lastEventID is the value of the last id field, which persists across
+	// events.
+	lastEventID string
+	// retry is the last reconnection time sent by the server with the retry
+	// field, zero if none was sent.
+	retry time.Duration
+	// onLine is called whenever a line, including a comment, is read. It is
+	// used to detect stalled streams.
+	onLine func()
+}
+
+func newSSEDecoder(r io.Reader, onLine func()) *sseDecoder {
+	return &sseDecoder{reader: bufio.NewReader(r), onLine: onLine}
+}
+
+// next returns the next event of the stream. It returns io.EOF when the
+// stream ends without a pending event.
+func (d *sseDecoder) next() (sseEvent, error) {
+	var (
+		data      bytes.Buffer
+		eventType string
+		hasData   bool
+	)
+
+	for {
+		line, err := d.reader.ReadString('\n')
+		if err == io.ErrUnexpectedEOF {
+			err = io.EOF
+		}
+		if err != nil && err != io.EOF {
+			return sseEvent{}, err
+		}
+		if line != "" && d.onLine != nil {
+			d.onLine()
+		}
+		eof := err == io.EOF
+		line = strings.TrimRight(line, "\r\n")
+
+		if line == "" {
+			if hasData {
+				return d.dispatch(eventType, data.Bytes()), nil
+			}
+			if eof {
+				return sseEvent{}, io.EOF
+			}
+			// An empty event, like one only setting id or retry, is not
+			// dispatched.
+			eventType = ""
+			continue
+		}
+
+		field, value := line, ""
+		if i := strings.IndexByte(line, ':'); i >= 0 {
+			field, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+		}
+
+		switch field {
+		case "":
+			// comment, used by servers as a keep-alive
+		case "event":
+			eventType = value
+		case "data":
+			if hasData {
+				data.WriteByte('\n')
+			}
+			data.WriteString(value)
+			hasData = true
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				d.lastEventID = value
+			}
+		case "retry":
+			if ms, err := strconv.ParseUint(value, 10, 63); err == nil {
+				d.retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+
+		if eof {
+			// Horizon always terminates events with an empty line, but a
+			// pending event is still dispatched if the stream ends without
+			// one.
+			if hasData {
+				return d.dispatch(eventType, data.Bytes()), nil
+			}
+			return sseEvent{}, io.EOF
+		}
+	}
+}
+
+func (d *sseDecoder) dispatch(eventType string, data []byte) sseEvent {
+	if eventType == "" {
+		eventType = "message"
+	}
+	return sseEvent{
+		Event: eventType,
+		ID:    d.lastEventID,
+		Data:  append([]byte(nil), data...),
+	}
+}
@@ -0,0 +1,299 @@
+package horizonclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	supportErrors "github.com/pownieh/stellar_go/support/errors"
+)
+
+// StreamStatus is the state of a stream reported to a StreamStatusHandler.
+type StreamStatus int
+
+const (
+	// StreamConnecting is reported before each connection attempt.
+	StreamConnecting StreamStatus = iota
+	// StreamConnected is reported when Horizon accepted the connection.
+	StreamConnected
+	// StreamReconnecting is reported when the connection was closed or
+	// failed and a new connection is about to be attempted.
+	StreamReconnecting
+	// StreamClosed is reported when streaming stops, either because the
+	// context was canceled or because of an error which is not retried.
+	StreamClosed
+)
+
+// String returns the name of the status.
+func (s StreamStatus) String() string {
+	switch s {
+	case StreamConnecting:
+		return "connecting"
+	case StreamConnected:
+		return "connected"
+	case StreamReconnecting:
+		return "reconnecting"
+	case StreamClosed:
+		return "closed"
+	}
+	return fmt.Sprintf("StreamStatus(%d)", int(s))
+}
+
+// StreamStatusEvent describes a change in the state of a stream.
+type StreamStatusEvent struct {
+	Status StreamStatus
+	// URL is the URL of the current connection.
+	URL string
+	// Cursor is the paging token the stream resumes from.
+	Cursor string
+	// Err is the error which closed the connection, if any.
+	Err error
+	// RetryIn is the delay before the next connection attempt, set when
+	// Status is StreamReconnecting.
+	RetryIn time.Duration
+	// Failures is the number of consecutive failed connection attempts.
+	Failures int
+}
+
+// StreamStatusHandler is a function that is called whenever the state of a
+// stream changes.
+type StreamStatusHandler func(StreamStatusEvent)
+
+// StreamOptions configures how the Stream* methods of a Client recover from
+// failures.
+//
+// Streams always resume from the paging token of the last received record when
+// Horizon closes the connection. When a Client has StreamOptions, streams also
+// reconnect with a backoff after connection errors, non-2xx responses and
+// stalls, failing over to other Horizon instances when FailoverURLs is set.
+// Without StreamOptions these failures end streaming with an error.
+type StreamOptions struct {
+	// MaxReconnects is the maximum number of consecutive failed connection
+	// attempts before streaming stops with an error. Zero means no limit.
+	MaxReconnects int
+	// InitialBackoff is the delay before the first reconnection after a
+	// failure. It defaults to the reconnection time Horizon sends in the
+	// stream, or to one second. The delay doubles on each consecutive
+	// failure.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between two connection attempts.
+	MaxBackoff time.Duration
+	// HeartbeatTimeout is how long a connection may stay silent before it is
+	// considered stalled and reconnected. Zero disables stall detection.
+	HeartbeatTimeout time.Duration
+	// OnStatus, if set, is called whenever the state of a stream changes.
+	OnStatus StreamStatusHandler
+}
+
+func (o *StreamOptions) notify(event StreamStatusEvent) {
+	if o != nil && o.OnStatus != nil {
+		o.OnStatus(event)
+	}
+}
+
+// backoff returns the delay before reconnecting after the given number of
+// consecutive failures.
+func (o *StreamOptions) backoff(failures int, serverRetry, retryAfter time.Duration) time.Duration {
+	initial := o.InitialBackoff
+	if initial <= 0 {
+		initial = serverRetry
+	}
+	if initial <= 0 {
+		initial = time.Second
+	}
+	policy := RetryPolicy{InitialBackoff: initial, MaxBackoff: o.MaxBackoff}
+	delay := policy.backoff(failures)
+	if retryAfter > delay {
+		delay = policy.capBackoff(retryAfter)
+	}
+	return delay
+}
+
+// stream handles connections to endpoints that support streaming on a horizon server
+func (c *Client) stream(
+	ctx context.Context,
+	streamURL string,
+	handler func(data []byte) error,
+) error {
+	su, err := url.Parse(streamURL)
+	if err != nil {
+		return supportErrors.Wrap(err, "error parsing stream url")
+	}
+
+	query := su.Query()
+	if query.Get("cursor") == "" {
+		query.Set("cursor", "now")
+	}
+
+	opts := c.StreamOptions
+	endpoints := c.endpoints()
+	// serverRetry is the reconnection time sent by Horizon with the retry
+	// field, which persists across connections.
+	var serverRetry time.Duration
+	failures := 0
+
+	for {
+		horizonURL := endpoints.pick()
+		// updates the url with new cursor
+		su.RawQuery = query.Encode()
+		requestURL := endpoints.rebase(su.String(), horizonURL)
+		status := StreamStatusEvent{URL: requestURL, Cursor: query.Get("cursor"), Failures: failures}
+
+		status.Status = StreamConnecting
+		opts.notify(status)
+
+		err := c.readStream(ctx, requestURL, &serverRetry, func(event sseEvent) error {
+			failures = 0
+			if event.Event != "message" {
+				return nil
+			}
+
+			// Update cursor with event ID
+			if event.ID != "" {
+				query.Set("cursor", event.ID)
+			}
+			return supportErrors.Wrap(handler(event.Data), "handler error")
+		})
+
+		status.Cursor = query.Get("cursor")
+		if ctx.Err() != nil {
+			status.Status = StreamClosed
+			opts.notify(status)
+			return nil
+		}
+
+		var delay time.Duration
+		var retryable *retryableError
+		switch {
+		case err == nil:
+			// Horizon closed the stream. Clients with StreamOptions wait as
+			// instructed by the last retry field before reconnecting.
+			if opts != nil {
+				delay = serverRetry
+			}
+		case errors.As(err, &retryable):
+			endpoints.markUnhealthy(horizonURL)
+			failures++
+			if opts == nil || (opts.MaxReconnects > 0 && failures > opts.MaxReconnects) {
+				status.Status, status.Err, status.Failures = StreamClosed, retryable.err, failures
+				opts.notify(status)
+				return retryable.err
+			}
+			delay = opts.backoff(failures, serverRetry, retryable.retryAfter)
+		default:
+			status.Status, status.Err = StreamClosed, err
+			opts.notify(status)
+			return err
+		}
+
+		status.Status, status.Err, status.RetryIn, status.Failures = StreamReconnecting, retryable.unwrap(), delay, failures
+		opts.notify(status)
+
+		if delay > 0 {
+			select {
+			case <-ctx.Done():
+				status.Status, status.Err, status.RetryIn = StreamClosed, nil, 0
+				opts.notify(status)
+				return nil
+			case <-time.After(delay):
+			}
+		}
+	}
+}
+
+// readStream reads the events of a single connection to requestURL, calling
+// onEvent for each of them. It returns nil when Horizon closed the stream or
+// ctx is done, a *retryableError when the connection failed or stalled and
+// the error returned by onEvent, if any.
+func (c *Client) readStream(
+	ctx context.Context,
+	requestURL string,
+	serverRetry *time.Duration,
+	onEvent func(sseEvent) error,
+) error {
+	connCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(connCtx, "GET", requestURL, nil)
+	if err != nil {
+		return supportErrors.Wrap(err, "error creating HTTP request")
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	c.setDefaultClient()
+	c.setClientAppHeaders(req)
+
+	// We can use c.HTTP here because we set Timeout per request not on the client. See sendRequest()
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return &retryableError{err: supportErrors.Wrap(err, "error sending HTTP request")}
+	}
+	defer resp.Body.Close()
+
+	// Expected statusCode are 200-299
+	if !(resp.StatusCode >= 200 && resp.StatusCode < 300) {
+		return &retryableError{
+			err:        fmt.Errorf("got bad HTTP status code %d", resp.StatusCode),
+			retryAfter: parseRetryAfter(resp.Header, c.clock),
+		}
+	}
+	c.StreamOptions.notify(StreamStatusEvent{Status: StreamConnected, URL: requestURL})
+
+	var stalled atomic.Bool
+	onLine := func() {}
+	if opts := c.StreamOptions; opts != nil && opts.HeartbeatTimeout > 0 {
+		timer := time.AfterFunc(opts.HeartbeatTimeout, func() {
+			stalled.Store(true)
+			cancel()
+			// Closing the body unblocks readers which ignore the request
+			// context.
+			resp.Body.Close()
+		})
+		defer timer.Stop()
+		onLine = func() { timer.Reset(opts.HeartbeatTimeout) }
+	}
+
+	decoder := newSSEDecoder(resp.Body, onLine)
+	for {
+		event, err := decoder.next()
+		if decoder.retry > 0 {
+			*serverRetry = decoder.retry
+		}
+		if err == io.EOF {
+			// The stream was closed by Horizon, or by a proxy because the
+			// connection was idle.
+			return nil
+		}
+		if err != nil {
+			if stalled.Load() {
+				return &retryableError{err: ErrStreamStalled}
+			}
+			if ctx.Err() != nil {
+				return nil
+			}
+			return &retryableError{err: supportErrors.Wrap(err, "error reading line")}
+		}
+
+		if err := onEvent(event); err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// unwrap returns the wrapped error, or nil for a nil *retryableError.
+func (e *retryableError) unwrap() error {
+	if e == nil {
+		return nil
+	}
+	return e.err
+}
@@ -0,0 +1,206 @@
+package horizonclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	hProtocol "github.com/pownieh/stellar_go/protocols/horizon"
+	"github.com/pownieh/stellar_go/support/http/httptest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSSEDecoder(t *testing.T) {
+	lines := 0
+	d := newSSEDecoder(strings.NewReader(
+		"retry: 1000\n"+
+			"event: open\n"+
+			"data: \"hello\"\n"+
+			"\n"+
+			": keep-alive\n"+
+			"\n"+
+			"id: 1-2\n"+
+			"data: {\"a\":\n"+
+			"data: 1}\r\n"+
+			"\r\n"+
+			"data: no id\n"+
+			"\n"+
+			"id: 3\n"+
+			"\n"+
+			"data: trailing",
+	), func() { lines++ })
+
+	event, err := d.next()
+	require.NoError(t, err)
+	assert.Equal(t, sseEvent{Event: "open", Data: []byte(`"hello"`)}, event)
+	assert.Equal(t, time.Second, d.retry)
+
+	event, err = d.next()
+	require.NoError(t, err)
+	assert.Equal(t, sseEvent{Event: "message", ID: "1-2", Data: []byte("{\"a\":\n1}")}, event)
+
+	// the last event ID persists across events
+	event, err = d.next()
+	require.NoError(t, err)
+	assert.Equal(t, sseEvent{Event: "message", ID: "1-2", Data: []byte("no id")}, event)
+
+	// a pending event is dispatched at the end of the stream
+	event, err = d.next()
+	require.NoError(t, err)
+	assert.Equal(t, sseEvent{Event: "message", ID: "3", Data: []byte("trailing")}, event)
+
+	_, err = d.next()
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, 15, lines)
+}
+
+func TestStreamResumesFromLastCursor(t *testing.T) {
+	hmock := httptest.NewClient()
+	client := &Client{
+		HorizonURL: "https://localhost/",
+		HTTP:       hmock,
+	}
+
+	hmock.On("GET", "https://localhost/ledgers?cursor=now").
+		ReturnString(http.StatusOK, "id: 1\ndata: {\"sequence\": 1}\n\n")
+	hmock.On("GET", "https://localhost/ledgers?cursor=1").
+		ReturnString(http.StatusOK, "id: 2\ndata: {\"sequence\": 2}\n\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var sequences []int32
+	err := client.StreamLedgers(ctx, LedgerRequest{}, func(ledger hProtocol.Ledger) {
+		sequences = append(sequences, ledger.Sequence)
+		if len(sequences) == 2 {
+			cancel()
+		}
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int32{1, 2}, sequences)
+}
+
+func TestStreamReconnectsAfterFailure(t *testing.T) {
+	hmock := httptest.NewClient()
+	var statuses []StreamStatusEvent
+	client := &Client{
+		HorizonURL:   "https://primary/",
+		FailoverURLs: []string{"https://secondary/"},
+		HTTP:         hmock,
+		StreamOptions: &StreamOptions{
+			InitialBackoff: time.Millisecond,
+			OnStatus: func(event StreamStatusEvent) {
+				statuses = append(statuses, event)
+			},
+		},
+	}
+
+	primaryCalls := 0
+	hmock.On("GET", "https://primary/ledgers?cursor=1").Return(sequenceResponder(&primaryCalls,
+		httpmock.NewStringResponder(http.StatusServiceUnavailable, "{}"),
+	))
+	hmock.On("GET", "https://secondary/ledgers?cursor=1").
+		ReturnString(http.StatusOK, "retry: 10\nid: 2\ndata: {\"sequence\": 2}\n\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var sequences []int32
+	err := client.StreamLedgers(ctx, LedgerRequest{Cursor: "1"}, func(ledger hProtocol.Ledger) {
+		sequences = append(sequences, ledger.Sequence)
+		cancel()
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int32{2}, sequences)
+	assert.Equal(t, 1, primaryCalls)
+
+	var got []StreamStatus
+	for _, event := range statuses {
+		got = append(got, event.Status)
+	}
+	assert.Equal(t, []StreamStatus{
+		StreamConnecting,
+		StreamReconnecting,
+		StreamConnecting,
+		StreamConnected,
+		StreamClosed,
+	}, got)
+	assert.EqualError(t, statuses[1].Err, "got bad HTTP status code 503")
+	assert.Equal(t, time.Millisecond, statuses[1].RetryIn)
+	assert.Equal(t, 1, statuses[1].Failures)
+	assert.Equal(t, "https://secondary/ledgers?cursor=1", statuses[2].URL)
+	assert.Equal(t, "2", statuses[4].Cursor)
+}
+
+func TestStreamMaxReconnects(t *testing.T) {
+	hmock := httptest.NewClient()
+	client := &Client{
+		HorizonURL: "https://localhost/",
+		HTTP:       hmock,
+		StreamOptions: &StreamOptions{
+			MaxReconnects:  2,
+			InitialBackoff: time.Millisecond,
+		},
+	}
+
+	calls := 0
+	hmock.On("GET", "https://localhost/ledgers?cursor=now").Return(sequenceResponder(&calls,
+		httpmock.NewErrorResponder(assert.AnError),
+	))
+
+	err := client.StreamLedgers(context.Background(), LedgerRequest{}, func(hProtocol.Ledger) {})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "error sending HTTP request")
+	assert.Equal(t, 3, calls)
+}
+
+func TestStreamReconnectsWhenStalled(t *testing.T) {
+	hmock := httptest.NewClient()
+	var statuses []StreamStatusEvent
+	client := &Client{
+		HorizonURL: "https://localhost/",
+		HTTP:       hmock,
+		StreamOptions: &StreamOptions{
+			InitialBackoff:   time.Millisecond,
+			HeartbeatTimeout: 10 * time.Millisecond,
+			OnStatus: func(event StreamStatusEvent) {
+				statuses = append(statuses, event)
+			},
+		},
+	}
+
+	// the first connection sends a keep-alive comment and then nothing
+	stalledBody, w := io.Pipe()
+	defer w.Close()
+	go w.Write([]byte(": keep-alive\n"))
+
+	calls := 0
+	hmock.On("GET", "https://localhost/ledgers?cursor=now").Return(sequenceResponder(&calls,
+		func(*http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK, Body: stalledBody}, nil
+		},
+		httpmock.NewStringResponder(http.StatusOK, "id: 2\ndata: {\"sequence\": 2}\n\n"),
+	))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err := client.StreamLedgers(ctx, LedgerRequest{}, func(hProtocol.Ledger) {
+		cancel()
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls)
+	require.True(t, len(statuses) > 2)
+	assert.Equal(t, StreamReconnecting, statuses[2].Status)
+	assert.Equal(t, ErrStreamStalled, statuses[2].Err)
+}
+
+func TestStreamOptionsBackoff(t *testing.T) {
+	opts := StreamOptions{MaxBackoff: 5 * time.Second}
+	assert.Equal(t, time.Second, opts.backoff(1, 0, 0))
+	assert.Equal(t, 2*time.Second, opts.backoff(1, 2*time.Second, 0))
+	assert.Equal(t, 3*time.Second, opts.backoff(1, 0, 3*time.Second))
+	assert.Equal(t, 5*time.Second, opts.backoff(1, 0, time.Minute))
+
+	opts.InitialBackoff = 100 * time.Millisecond
+	assert.Equal(t, 400*time.Millisecond, opts.backoff(3, 2*time.Second, 0))
+}
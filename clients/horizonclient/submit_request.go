@@ -1,12 +1,16 @@
 package horizonclient
 
 import (
+	"context"
+	"encoding/hex"
 	"fmt"
 	"net/http"
 	"net/url"
 	"strings"
 
+	"github.com/pownieh/stellar_go/network"
 	"github.com/pownieh/stellar_go/support/errors"
+	"github.com/pownieh/stellar_go/xdr"
 )
 
 // BuildURL returns the url for submitting transactions to a running horizon instance
@@ -33,3 +37,40 @@ func (sr submitRequest) HTTPRequest(horizonURL string) (*http.Request, error) {
 	request.Header.Add("Content-Type", "application/x-www-form-urlencoded")
 	return request, nil
 }
+
+// transactionHash returns the hex encoded hash of the transaction envelope, or
+// an empty string if it can't be computed because the envelope is invalid or
+// the network passphrase is unknown.
+func (c *Client) transactionHash(ctx context.Context, transactionXdr string) string {
+	var envelope xdr.TransactionEnvelope
+	if err := xdr.SafeUnmarshalBase64(transactionXdr, &envelope); err != nil {
+		return ""
+	}
+
+	passphrase, err := c.networkPassphrase(ctx)
+	if err != nil {
+		return ""
+	}
+
+	hash, err := network.HashTransactionInEnvelope(envelope, passphrase)
+	if err != nil {
+		return ""
+	}
+	return hex.EncodeToString(hash[:])
+}
+
+// networkPassphrase returns NetworkPassphrase, loading it from the root
+// endpoint the first time if it is not set.
+func (c *Client) networkPassphrase(ctx context.Context) (string, error) {
+	c.networkPassphraseMutex.Lock()
+	defer c.networkPassphraseMutex.Unlock()
+
+	if c.NetworkPassphrase == "" {
+		root, err := c.RootWithContext(ctx)
+		if err != nil {
+			return "", errors.Wrap(err, "loading network passphrase")
+		}
+		c.NetworkPassphrase = root.NetworkPassphrase
+	}
+	return c.NetworkPassphrase, nil
+}
@@ -0,0 +1,346 @@
+package orderbook
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/pownieh/stellar_go/price"
+	"github.com/pownieh/stellar_go/support/errors"
+	"github.com/pownieh/stellar_go/xdr"
+)
+
+// ErrQuoteNotFound indicates that the order book does not hold enough
+// liquidity to trade the amount of a quote.
+var ErrQuoteNotFound = errors.New("not enough liquidity to trade the amount")
+
+// ErrOfferCrossSelf indicates that an offer would cross an offer of its own
+// seller, which the protocol rejects.
+var ErrOfferCrossSelf = errors.New("offer crosses an offer of its seller")
+
+// Quote is the result of trading an amount against the offers or the liquidity
+// pool of a trading pair, in a single path payment hop. Like the protocol,
+// the trade is executed against the offers or the pool, whichever gives the
+// better result.
+type Quote struct {
+	SourceAsset       string
+	SourceAmount      xdr.Int64
+	DestinationAsset  string
+	DestinationAmount xdr.Int64
+
+	// MidPrice is the price of the destination asset in terms of the source
+	// asset halfway between the best bid and the best ask of the pair, the
+	// spot price of the liquidity pool counting as both. It is nil when the
+	// pair has no liquidity.
+	MidPrice *big.Rat
+
+	// Offers are the offers consumed by the trade, in the order in which they
+	// are crossed.
+	Offers []QuoteOffer
+	// Pool is the liquidity pool the trade is executed against, it is nil
+	// when the trade is executed against the offers.
+	Pool *QuotePool
+}
+
+// QuoteOffer is an offer consumed by a quote.
+type QuoteOffer struct {
+	Offer xdr.OfferEntry
+	// Amount is the amount of the selling asset of the offer bought.
+	Amount xdr.Int64
+}
+
+// QuotePool is the liquidity pool a quote is executed against.
+type QuotePool struct {
+	// Pool is the liquidity pool before the trade.
+	Pool xdr.LiquidityPoolEntry
+	// ReserveA and ReserveB are the reserves of the pool after the trade.
+	ReserveA xdr.Int64
+	ReserveB xdr.Int64
+}
+
+// QuoteStrictSend returns the quote for selling `amountToSpend` of
+// `sourceAsset` for `destinationAsset`.
+//
+// `sourceAccountID` is optional, but if it's provided, then no offers created
+// by `sourceAccountID` will be considered.
+//
+// ErrQuoteNotFound is returned when the order book does not hold enough
+// liquidity to trade the whole amount.
+func (graph *OrderBookGraph) QuoteStrictSend(
+	ctx context.Context,
+	sourceAsset xdr.Asset,
+	amountToSpend xdr.Int64,
+	destinationAsset xdr.Asset,
+	sourceAccountID *xdr.AccountId,
+	includePools bool,
+) (Quote, uint32, error) {
+	graph.lock.RLock()
+	defer graph.lock.RUnlock()
+
+	quote, err := graph.quote(sourceAsset, destinationAsset, amountToSpend, true, sourceAccountID, includePools)
+	return quote, graph.lastLedger, err
+}
+
+// QuoteStrictReceive returns the quote for buying `destinationAmount` of
+// `destinationAsset` with `sourceAsset`.
+//
+// `sourceAccountID` is optional, but if it's provided, then no offers created
+// by `sourceAccountID` will be considered.
+func (graph *OrderBookGraph) QuoteStrictReceive(
+	ctx context.Context,
+	sourceAsset xdr.Asset,
+	destinationAsset xdr.Asset,
+	destinationAmount xdr.Int64,
+	sourceAccountID *xdr.AccountId,
+	includePools bool,
+) (Quote, uint32, error) {
+	graph.lock.RLock()
+	defer graph.lock.RUnlock()
+
+	quote, err := graph.quote(sourceAsset, destinationAsset, destinationAmount, false, sourceAccountID, includePools)
+	return quote, graph.lastLedger, err
+}
+
+// CrossOffer returns the quote for the part of a new offer selling
+// `sellingAsset` for `buyingAsset` which is immediately executed against the
+// offers of the order book. Unlike path payments, offers never trade with
+// liquidity pools.
+//
+// When `buy` is false `amount` is the amount of `sellingAsset` to sell and
+// `offerPrice` the price of `sellingAsset` in terms of `buyingAsset`, like in
+// ManageSellOffer operations. Otherwise `amount` is the amount of
+// `buyingAsset` to buy and `offerPrice` the price of `buyingAsset` in terms of
+// `sellingAsset`, like in ManageBuyOffer operations. Passive offers don't
+// cross the offers at the same price.
+//
+// The quote trades less than `amount` when the order book doesn't hold enough
+// offers at the price of the offer. ErrOfferCrossSelf is returned if the offer
+// would cross an offer of `sellerID`.
+func (graph *OrderBookGraph) CrossOffer(
+	ctx context.Context,
+	sellingAsset, buyingAsset xdr.Asset,
+	amount xdr.Int64,
+	buy bool,
+	offerPrice xdr.Price,
+	passive bool,
+	sellerID xdr.AccountId,
+) (Quote, uint32, error) {
+	graph.lock.RLock()
+	defer graph.lock.RUnlock()
+
+	if amount <= 0 || offerPrice.N <= 0 || offerPrice.D <= 0 {
+		return Quote{}, graph.lastLedger, errBadAmount
+	}
+	quote := Quote{
+		SourceAsset:      sellingAsset.String(),
+		DestinationAsset: buyingAsset.String(),
+	}
+	sellingID, ok := graph.assetStringToID[quote.SourceAsset]
+	if !ok {
+		return quote, graph.lastLedger, nil
+	}
+	buyingID, ok := graph.assetStringToID[quote.DestinationAsset]
+	if !ok {
+		return quote, graph.lastLedger, nil
+	}
+	asks := pairVenues(graph.venuesForSellingAsset[buyingID], sellingID)
+	bids := pairVenues(graph.venuesForSellingAsset[sellingID], buyingID)
+	quote.MidPrice = midPrice(Venues{offers: asks.offers}, Venues{offers: bids.offers}, sellingID)
+
+	// maxN/maxD is the highest price, in units of sellingAsset per unit of
+	// buyingAsset, of the asks crossed by the offer
+	maxN, maxD := int64(offerPrice.D), int64(offerPrice.N)
+	if buy {
+		maxN, maxD = maxD, maxN
+	}
+	remaining := amount
+	for _, offer := range asks.offers {
+		if remaining <= 0 {
+			break
+		}
+		n, d := int64(offer.Price.N), int64(offer.Price.D)
+		if cmp := n*maxD - maxN*d; cmp > 0 || (passive && cmp == 0) {
+			break
+		}
+		if offer.SellerId.Equals(sellerID) {
+			return Quote{}, graph.lastLedger, ErrOfferCrossSelf
+		}
+
+		wanted := remaining
+		if !buy {
+			// the amount of the selling asset of the ask bought with the
+			// remaining amount to sell
+			bought, err := price.MulFractionRoundDown(int64(remaining), d, n)
+			if err == price.ErrOverflow || (err == nil && xdr.Int64(bought) > offer.Amount) {
+				bought = int64(offer.Amount)
+			} else if err != nil {
+				return Quote{}, graph.lastLedger, err
+			}
+			wanted = xdr.Int64(bought)
+		}
+		if wanted > offer.Amount {
+			wanted = offer.Amount
+		}
+		if wanted <= 0 {
+			break
+		}
+
+		spent, bought, err := price.ConvertToBuyingUnits(int64(offer.Amount), int64(wanted), n, d)
+		if err != nil {
+			return Quote{}, graph.lastLedger, err
+		}
+		if bought <= 0 || (!buy && xdr.Int64(spent) > remaining) {
+			break
+		}
+		quote.Offers = append(quote.Offers, QuoteOffer{Offer: offer, Amount: xdr.Int64(bought)})
+		quote.SourceAmount += xdr.Int64(spent)
+		quote.DestinationAmount += xdr.Int64(bought)
+		if buy {
+			remaining -= xdr.Int64(bought)
+		} else {
+			remaining -= xdr.Int64(spent)
+		}
+	}
+	return quote, graph.lastLedger, nil
+}
+
+func (graph *OrderBookGraph) quote(
+	sourceAsset, destinationAsset xdr.Asset,
+	amount xdr.Int64,
+	strictSend bool,
+	ignoreOffersFrom *xdr.AccountId,
+	includePools bool,
+) (Quote, error) {
+	if amount <= 0 {
+		return Quote{}, errBadAmount
+	}
+	quote := Quote{
+		SourceAsset:      sourceAsset.String(),
+		DestinationAsset: destinationAsset.String(),
+	}
+	sourceID, ok := graph.assetStringToID[quote.SourceAsset]
+	if !ok {
+		return Quote{}, ErrQuoteNotFound
+	}
+	destinationID, ok := graph.assetStringToID[quote.DestinationAsset]
+	if !ok {
+		return Quote{}, ErrQuoteNotFound
+	}
+
+	// asks are the offers selling the destination asset, which the trade
+	// consumes, and bids the offers buying it
+	asks := pairVenues(graph.venuesForSellingAsset[destinationID], sourceID)
+	bids := pairVenues(graph.venuesForSellingAsset[sourceID], destinationID)
+	if !includePools {
+		asks.pool, bids.pool = liquidityPool{}, liquidityPool{}
+	}
+	quote.MidPrice = midPrice(asks, bids, sourceID)
+
+	book := newResidualBook()
+	var err error
+	if strictSend {
+		quote.SourceAmount = amount
+		quote.DestinationAmount, err = book.sell(withoutOffersOf(asks, ignoreOffersFrom), sourceID, amount, includePools)
+	} else {
+		quote.DestinationAmount = amount
+		quote.SourceAmount, err = book.buy(asks, destinationID, sourceID, amount, includePools, ignoreOffersFrom)
+	}
+	if err == errNoLiquidity {
+		return Quote{}, ErrQuoteNotFound
+	} else if err != nil {
+		return Quote{}, err
+	}
+	quote.Offers, quote.Pool = book.consumed(asks)
+	return quote, nil
+}
+
+// withoutOffersOf returns the venues without the offers of account, if any.
+func withoutOffersOf(venues Venues, account *xdr.AccountId) Venues {
+	if account == nil {
+		return venues
+	}
+	result := venues
+	result.offers = nil
+	for _, offer := range venues.offers {
+		if !offer.SellerId.Equals(*account) {
+			result.offers = append(result.offers, offer)
+		}
+	}
+	return result
+}
+
+// pairVenues returns the venues of the edges trading with asset.
+func pairVenues(edges edgeSet, asset int32) Venues {
+	if i := edges.find(asset); i >= 0 {
+		return edges[i].value
+	}
+	return Venues{}
+}
+
+// midPrice returns the price of the asset sold by the asks in terms of
+// sourceAsset halfway between the best bid and the best ask, or nil if there
+// are neither bids nor asks.
+func midPrice(asks, bids Venues, sourceAsset int32) *big.Rat {
+	var bestAsk, bestBid *big.Rat
+	if len(asks.offers) > 0 {
+		bestAsk = big.NewRat(int64(asks.offers[0].Price.N), int64(asks.offers[0].Price.D))
+	}
+	if len(bids.offers) > 0 {
+		// the bids sell sourceAsset so their price is inverted
+		bestBid = big.NewRat(int64(bids.offers[0].Price.D), int64(bids.offers[0].Price.N))
+	}
+	if pool := asks.pool; pool.Body.ConstantProduct != nil {
+		if spot := poolSpotPrice(pool, sourceAsset); spot != nil {
+			if bestAsk == nil || spot.Cmp(bestAsk) < 0 {
+				bestAsk = spot
+			}
+			if bestBid == nil || spot.Cmp(bestBid) > 0 {
+				bestBid = spot
+			}
+		}
+	}
+
+	switch {
+	case bestAsk == nil:
+		return bestBid
+	case bestBid == nil:
+		return bestAsk
+	}
+	mid := new(big.Rat).Add(bestAsk, bestBid)
+	return mid.Quo(mid, big.NewRat(2, 1))
+}
+
+// poolSpotPrice returns the price of the other asset of the pool in terms of
+// asset, or nil if the pool is empty.
+func poolSpotPrice(pool liquidityPool, asset int32) *big.Rat {
+	details := pool.Body.MustConstantProduct()
+	reserve, otherReserve := details.ReserveA, details.ReserveB
+	if pool.assetA != asset {
+		reserve, otherReserve = otherReserve, reserve
+	}
+	if reserve <= 0 || otherReserve <= 0 {
+		return nil
+	}
+	return big.NewRat(int64(reserve), int64(otherReserve))
+}
+
+// consumed returns the offers of venues consumed by the trades of the book
+// and the pool traded with, if any.
+func (b *residualBook) consumed(venues Venues) ([]QuoteOffer, *QuotePool) {
+	var offers []QuoteOffer
+	for _, offer := range venues.offers {
+		if remaining, ok := b.offers[offer.OfferId]; ok && remaining < offer.Amount {
+			offers = append(offers, QuoteOffer{Offer: offer, Amount: offer.Amount - remaining})
+		}
+	}
+
+	if pool := venues.pool; pool.Body.ConstantProduct != nil {
+		if reserves, ok := b.pools[pool.LiquidityPoolId]; ok {
+			return offers, &QuotePool{
+				Pool:     pool.LiquidityPoolEntry,
+				ReserveA: reserves[0],
+				ReserveB: reserves[1],
+			}
+		}
+	}
+	return offers, nil
+}
@@ -0,0 +1,197 @@
+package orderbook
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pownieh/stellar_go/xdr"
+)
+
+func TestQuoteAgainstOffers(t *testing.T) {
+	graph := NewOrderBookGraph()
+	// asks: 500 XLM for USD at 0.5 and 500 XLM for USD at 1
+	cheapOffer := xdr.OfferEntry{
+		SellerId: issuer,
+		OfferId:  1,
+		Buying:   usdAsset,
+		Selling:  nativeAsset,
+		Price:    xdr.Price{N: 1, D: 2},
+		Amount:   500,
+	}
+	expensiveOffer := xdr.OfferEntry{
+		SellerId: issuer,
+		OfferId:  2,
+		Buying:   usdAsset,
+		Selling:  nativeAsset,
+		Price:    xdr.Price{N: 1, D: 1},
+		Amount:   500,
+	}
+	// bid: 100 USD for XLM at 0.25 USD per XLM
+	bid := xdr.OfferEntry{
+		SellerId: issuer,
+		OfferId:  3,
+		Buying:   nativeAsset,
+		Selling:  usdAsset,
+		Price:    xdr.Price{N: 4, D: 1},
+		Amount:   100,
+	}
+	graph.AddOffers(cheapOffer, expensiveOffer, bid)
+	require.NoError(t, graph.Apply(1))
+
+	quote, lastLedger, err := graph.QuoteStrictReceive(context.TODO(), usdAsset, nativeAsset, 600, nil, true)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1), lastLedger)
+	assert.Equal(t, usdAsset.String(), quote.SourceAsset)
+	assert.Equal(t, nativeAsset.String(), quote.DestinationAsset)
+	// 500 XLM cost 250 USD and the 100 XLM left cost 100 USD
+	assert.Equal(t, xdr.Int64(350), quote.SourceAmount)
+	assert.Equal(t, xdr.Int64(600), quote.DestinationAmount)
+	assert.Equal(t, big.NewRat(3, 8), quote.MidPrice)
+	assert.Equal(t, []QuoteOffer{
+		{Offer: cheapOffer, Amount: 500},
+		{Offer: expensiveOffer, Amount: 100},
+	}, quote.Offers)
+	assert.Nil(t, quote.Pool)
+
+	quote, _, err = graph.QuoteStrictSend(context.TODO(), usdAsset, 100, nativeAsset, nil, true)
+	require.NoError(t, err)
+	assert.Equal(t, xdr.Int64(100), quote.SourceAmount)
+	assert.Equal(t, xdr.Int64(200), quote.DestinationAmount)
+	assert.Equal(t, []QuoteOffer{{Offer: cheapOffer, Amount: 200}}, quote.Offers)
+
+	_, _, err = graph.QuoteStrictReceive(context.TODO(), usdAsset, nativeAsset, 1001, nil, true)
+	assert.Equal(t, ErrQuoteNotFound, err)
+
+	// offers of the source account are ignored
+	_, _, err = graph.QuoteStrictReceive(context.TODO(), usdAsset, nativeAsset, 100, &issuer, true)
+	assert.Equal(t, ErrQuoteNotFound, err)
+	_, _, err = graph.QuoteStrictSend(context.TODO(), usdAsset, 100, nativeAsset, &issuer, true)
+	assert.Equal(t, ErrQuoteNotFound, err)
+
+	_, _, err = graph.QuoteStrictSend(context.TODO(), usdAsset, 100, eurAsset, nil, true)
+	assert.Equal(t, ErrQuoteNotFound, err)
+
+	_, _, err = graph.QuoteStrictSend(context.TODO(), usdAsset, 0, nativeAsset, nil, true)
+	assert.Equal(t, errBadAmount, err)
+}
+
+func TestQuoteAgainstPool(t *testing.T) {
+	graph := NewOrderBookGraph()
+	offer := xdr.OfferEntry{
+		SellerId: issuer,
+		OfferId:  1,
+		Buying:   usdAsset,
+		Selling:  nativeAsset,
+		Price:    xdr.Price{N: 2, D: 1},
+		Amount:   500,
+	}
+	pool := makePool(nativeAsset, usdAsset, 1000, 1000)
+	graph.AddOffers(offer)
+	graph.AddLiquidityPools(pool)
+	require.NoError(t, graph.Apply(1))
+
+	// the pool pays out more than the offer
+	quote, _, err := graph.QuoteStrictSend(context.TODO(), usdAsset, 100, nativeAsset, nil, true)
+	require.NoError(t, err)
+	payout, _, ok := CalculatePoolPayout(1000, 1000, 100, pool.Body.ConstantProduct.Params.Fee, false)
+	require.True(t, ok)
+	assert.Equal(t, payout, quote.DestinationAmount)
+	assert.Equal(t, big.NewRat(1, 1), quote.MidPrice)
+	assert.Empty(t, quote.Offers)
+	require.NotNil(t, quote.Pool)
+	assert.Equal(t, pool, quote.Pool.Pool)
+	reserveXLM, reserveUSD := quote.Pool.ReserveA, quote.Pool.ReserveB
+	if pool.Body.ConstantProduct.Params.AssetA.Equals(usdAsset) {
+		reserveXLM, reserveUSD = reserveUSD, reserveXLM
+	}
+	assert.Equal(t, 1000-payout, reserveXLM)
+	assert.Equal(t, xdr.Int64(1100), reserveUSD)
+
+	// without pools the offer is used
+	quote, _, err = graph.QuoteStrictSend(context.TODO(), usdAsset, 100, nativeAsset, nil, false)
+	require.NoError(t, err)
+	assert.Equal(t, xdr.Int64(50), quote.DestinationAmount)
+	assert.Equal(t, big.NewRat(2, 1), quote.MidPrice)
+	assert.Equal(t, []QuoteOffer{{Offer: offer, Amount: 50}}, quote.Offers)
+	assert.Nil(t, quote.Pool)
+}
+
+func TestCrossOffer(t *testing.T) {
+	graph := NewOrderBookGraph()
+	// asks: 500 XLM for USD at 0.5 and 500 XLM for USD at 1
+	cheapOffer := xdr.OfferEntry{
+		SellerId: issuer,
+		OfferId:  1,
+		Buying:   usdAsset,
+		Selling:  nativeAsset,
+		Price:    xdr.Price{N: 1, D: 2},
+		Amount:   500,
+	}
+	expensiveOffer := xdr.OfferEntry{
+		SellerId: issuer,
+		OfferId:  2,
+		Buying:   usdAsset,
+		Selling:  nativeAsset,
+		Price:    xdr.Price{N: 1, D: 1},
+		Amount:   500,
+	}
+	graph.AddOffers(cheapOffer, expensiveOffer)
+	// offers never trade with pools
+	graph.AddLiquidityPools(makePool(nativeAsset, usdAsset, 100000, 100000))
+	require.NoError(t, graph.Apply(1))
+	seller, err := xdr.NewAccountId(xdr.PublicKeyTypePublicKeyTypeEd25519, xdr.Uint256{1})
+	require.NoError(t, err)
+
+	// selling USD for 2 XLM only crosses the cheap offer
+	quote, lastLedger, err := graph.CrossOffer(context.TODO(), usdAsset, nativeAsset, 100, false, xdr.Price{N: 2, D: 1}, false, seller)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1), lastLedger)
+	assert.Equal(t, usdAsset.String(), quote.SourceAsset)
+	assert.Equal(t, nativeAsset.String(), quote.DestinationAsset)
+	assert.Equal(t, xdr.Int64(100), quote.SourceAmount)
+	assert.Equal(t, xdr.Int64(200), quote.DestinationAmount)
+	assert.Equal(t, []QuoteOffer{{Offer: cheapOffer, Amount: 200}}, quote.Offers)
+	assert.Nil(t, quote.Pool)
+
+	// selling USD for 1 XLM crosses both offers
+	quote, _, err = graph.CrossOffer(context.TODO(), usdAsset, nativeAsset, 400, false, xdr.Price{N: 1, D: 1}, false, seller)
+	require.NoError(t, err)
+	assert.Equal(t, xdr.Int64(400), quote.SourceAmount)
+	assert.Equal(t, xdr.Int64(650), quote.DestinationAmount)
+	assert.Equal(t, []QuoteOffer{
+		{Offer: cheapOffer, Amount: 500},
+		{Offer: expensiveOffer, Amount: 150},
+	}, quote.Offers)
+
+	// passive offers don't cross the offers at the same price
+	quote, _, err = graph.CrossOffer(context.TODO(), usdAsset, nativeAsset, 400, false, xdr.Price{N: 1, D: 1}, true, seller)
+	require.NoError(t, err)
+	assert.Equal(t, xdr.Int64(250), quote.SourceAmount)
+	assert.Equal(t, xdr.Int64(500), quote.DestinationAmount)
+
+	// the offer doesn't cross any offer
+	quote, _, err = graph.CrossOffer(context.TODO(), usdAsset, nativeAsset, 1000, false, xdr.Price{N: 3, D: 1}, false, seller)
+	require.NoError(t, err)
+	assert.Equal(t, xdr.Int64(0), quote.SourceAmount)
+	assert.Empty(t, quote.Offers)
+
+	// buying 600 XLM for 1 USD each
+	quote, _, err = graph.CrossOffer(context.TODO(), usdAsset, nativeAsset, 600, true, xdr.Price{N: 1, D: 1}, false, seller)
+	require.NoError(t, err)
+	assert.Equal(t, xdr.Int64(350), quote.SourceAmount)
+	assert.Equal(t, xdr.Int64(600), quote.DestinationAmount)
+	assert.Equal(t, []QuoteOffer{
+		{Offer: cheapOffer, Amount: 500},
+		{Offer: expensiveOffer, Amount: 100},
+	}, quote.Offers)
+
+	_, _, err = graph.CrossOffer(context.TODO(), usdAsset, nativeAsset, 100, false, xdr.Price{N: 2, D: 1}, false, issuer)
+	assert.Equal(t, ErrOfferCrossSelf, err)
+
+	_, _, err = graph.CrossOffer(context.TODO(), usdAsset, nativeAsset, 0, false, xdr.Price{N: 2, D: 1}, false, seller)
+	assert.Equal(t, errBadAmount, err)
+}
@@ -0,0 +1,539 @@
+package orderbook
+
+import (
+	"context"
+
+	"github.com/pownieh/stellar_go/price"
+	"github.com/pownieh/stellar_go/support/errors"
+	"github.com/pownieh/stellar_go/xdr"
+)
+
+var (
+	// ErrSplitPathNotFound indicates that the order book does not hold enough
+	// liquidity to route the whole amount of a split path.
+	ErrSplitPathNotFound = errors.New("not enough liquidity to route the amount")
+	errNoLiquidity       = errors.New("no liquidity left in the venues")
+	errInvalidParts      = errors.New("parts must be positive")
+)
+
+// SplitPath is a payment split across several paths. Each leg is a payment
+// path which is submitted as its own path payment operation. The legs are
+// priced in order, each one against the order book left by the previous
+// legs.
+type SplitPath struct {
+	SourceAsset       string
+	SourceAmount      xdr.Int64
+	DestinationAsset  string
+	DestinationAmount xdr.Int64
+
+	Legs []Path
+}
+
+// FindSplitPathsStrictSend returns the split path which delivers the most of
+// `destinationAsset` by spending `amountToSpend` of `sourceAsset`.
+//
+// The amount is split in `parts` equal parts which are routed one after the
+// other through the best path of the order book left by the previous parts, so
+// the depth consumed by a part is accounted for when routing the next ones.
+// The parts routed through the same path are merged into a single leg.
+//
+// ErrSplitPathNotFound is returned when the order book does not hold enough
+// liquidity to route the whole amount.
+func (graph *OrderBookGraph) FindSplitPathsStrictSend(
+	ctx context.Context,
+	maxPathLength int,
+	sourceAsset xdr.Asset,
+	amountToSpend xdr.Int64,
+	destinationAsset xdr.Asset,
+	parts int,
+	includePools bool,
+) (SplitPath, uint32, error) {
+	graph.lock.RLock()
+	defer graph.lock.RUnlock()
+
+	router := &splitRouter{
+		graph:         graph,
+		strictSend:    true,
+		maxPathLength: maxPathLength,
+		includePools:  includePools,
+	}
+	split, err := router.route(ctx, sourceAsset, destinationAsset, amountToSpend, parts)
+	return split, graph.lastLedger, err
+}
+
+// FindSplitPathsStrictReceive returns the split path which delivers
+// `destinationAmount` of `destinationAsset` by spending the least of
+// `sourceAsset`.
+//
+// The amount is split in parts the same way as FindSplitPathsStrictSend does.
+// `sourceAccountID` is optional, but if it's provided, then no offers created
+// by `sourceAccountID` will be considered.
+func (graph *OrderBookGraph) FindSplitPathsStrictReceive(
+	ctx context.Context,
+	maxPathLength int,
+	sourceAsset xdr.Asset,
+	destinationAsset xdr.Asset,
+	destinationAmount xdr.Int64,
+	sourceAccountID *xdr.AccountId,
+	parts int,
+	includePools bool,
+) (SplitPath, uint32, error) {
+	graph.lock.RLock()
+	defer graph.lock.RUnlock()
+
+	router := &splitRouter{
+		graph:            graph,
+		strictSend:       false,
+		maxPathLength:    maxPathLength,
+		includePools:     includePools,
+		ignoreOffersFrom: sourceAccountID,
+	}
+	split, err := router.route(ctx, sourceAsset, destinationAsset, destinationAmount, parts)
+	return split, graph.lastLedger, err
+}
+
+// splitRouter routes the parts of a split path. Strict send paths are
+// searched and executed from the source asset, strict receive paths from the
+// destination asset, which is the order in which the path payment operations
+// consume the order book.
+type splitRouter struct {
+	graph            *OrderBookGraph
+	strictSend       bool
+	maxPathLength    int
+	includePools     bool
+	ignoreOffersFrom *xdr.AccountId
+}
+
+type splitLeg struct {
+	path []int32
+	// amount is the amount of the first asset of path routed through the leg.
+	amount xdr.Int64
+}
+
+func (r *splitRouter) route(
+	ctx context.Context,
+	sourceAsset, destinationAsset xdr.Asset,
+	amount xdr.Int64,
+	parts int,
+) (SplitPath, error) {
+	if parts <= 0 {
+		return SplitPath{}, errInvalidParts
+	}
+	if amount <= 0 {
+		return SplitPath{}, errBadAmount
+	}
+	if xdr.Int64(parts) > amount {
+		parts = int(amount)
+	}
+
+	sourceID, ok := r.graph.assetStringToID[sourceAsset.String()]
+	if !ok {
+		return SplitPath{}, ErrSplitPathNotFound
+	}
+	destinationID, ok := r.graph.assetStringToID[destinationAsset.String()]
+	if !ok {
+		return SplitPath{}, ErrSplitPathNotFound
+	}
+	start, end := sourceID, destinationID
+	if !r.strictSend {
+		start, end = destinationID, sourceID
+	}
+
+	book := newResidualBook()
+	var legs []splitLeg
+	legForPath := map[string]int{}
+	for i := 0; i < parts; i++ {
+		part := amount / xdr.Int64(parts)
+		if xdr.Int64(i) < amount%xdr.Int64(parts) {
+			part++
+		}
+
+		path, err := r.bestPath(ctx, book, start, end, part)
+		if err != nil {
+			return SplitPath{}, err
+		}
+		if path == nil {
+			return SplitPath{}, ErrSplitPathNotFound
+		}
+		if _, err = r.execute(book, path, part); err == errNoLiquidity {
+			return SplitPath{}, ErrSplitPathNotFound
+		} else if err != nil {
+			return SplitPath{}, err
+		}
+
+		key := pathKey(path)
+		if j, ok := legForPath[key]; ok {
+			legs[j].amount += part
+		} else {
+			legForPath[key] = len(legs)
+			legs = append(legs, splitLeg{path: path, amount: part})
+		}
+	}
+
+	// The merged legs are priced again because trading an amount at once
+	// through a pool does not give exactly the same result as trading it in
+	// several parts. The rounding of the offers can also leave a merged leg
+	// without enough liquidity even though each of its parts had some.
+	split := SplitPath{
+		SourceAsset:      sourceAsset.String(),
+		DestinationAsset: destinationAsset.String(),
+	}
+	book = newResidualBook()
+	for _, leg := range legs {
+		result, err := r.execute(book, leg.path, leg.amount)
+		if err == errNoLiquidity {
+			return SplitPath{}, ErrSplitPathNotFound
+		} else if err != nil {
+			return SplitPath{}, err
+		}
+		split.Legs = append(split.Legs, r.toPath(leg.path, leg.amount, result))
+	}
+	for _, leg := range split.Legs {
+		split.SourceAmount += leg.SourceAmount
+		split.DestinationAmount += leg.DestinationAmount
+	}
+	return split, nil
+}
+
+// bestPath returns the best path from start to end for the given amount in
+// the order book left by the previous parts, or nil if there is none.
+func (r *splitRouter) bestPath(
+	ctx context.Context,
+	book *residualBook,
+	start, end int32,
+	amount xdr.Int64,
+) ([]int32, error) {
+	var base searchState
+	if r.strictSend {
+		base = &buyingGraphSearchState{
+			graph:        r.graph,
+			targetAssets: map[int32]bool{end: true},
+			includePools: r.includePools,
+		}
+	} else {
+		base = &sellingGraphSearchState{
+			graph:            r.graph,
+			ignoreOffersFrom: r.ignoreOffersFrom,
+			targetAssets:     map[int32]xdr.Int64{end: 0},
+			includePools:     r.includePools,
+		}
+	}
+
+	state := &splitSearchState{searchState: base, book: book}
+	if err := search(ctx, state, r.maxPathLength, start, amount); err != nil {
+		return nil, err
+	}
+	return state.bestPath, nil
+}
+
+// execute trades amount of the first asset of the path along the path,
+// consuming the venues of book, and returns the resulting amount of the last
+// asset of the path.
+func (r *splitRouter) execute(book *residualBook, path []int32, amount xdr.Int64) (xdr.Int64, error) {
+	for i := 0; i+1 < len(path); i++ {
+		current, next := path[i], path[i+1]
+
+		var edges edgeSet
+		if r.strictSend {
+			edges = r.graph.venuesForBuyingAsset[current]
+		} else {
+			edges = r.graph.venuesForSellingAsset[current]
+		}
+		j := edges.find(next)
+		if j < 0 {
+			return 0, errNoLiquidity
+		}
+		venues := book.venues(edges[j].value)
+
+		var err error
+		if r.strictSend {
+			amount, err = book.sell(venues, current, amount, r.includePools)
+		} else {
+			amount, err = book.buy(venues, current, next, amount, r.includePools, r.ignoreOffersFrom)
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	return amount, nil
+}
+
+// toPath converts a leg, which turned amount of the first asset of path into
+// result of the last one, to a payment path.
+func (r *splitRouter) toPath(path []int32, amount, result xdr.Int64) Path {
+	interior := []int32{}
+	if len(path) > 2 {
+		interior = append(interior, path[1:len(path)-1]...)
+	}
+
+	if r.strictSend {
+		return Path{
+			SourceAsset:       r.graph.idToAssetString[path[0]],
+			SourceAmount:      amount,
+			DestinationAsset:  r.graph.idToAssetString[path[len(path)-1]],
+			DestinationAmount: result,
+			InteriorNodes:     assetIDsToAssetStrings(r.graph, interior),
+		}
+	}
+	reversePath(interior)
+	return Path{
+		SourceAsset:       r.graph.idToAssetString[path[len(path)-1]],
+		SourceAmount:      result,
+		DestinationAsset:  r.graph.idToAssetString[path[0]],
+		DestinationAmount: amount,
+		InteriorNodes:     assetIDsToAssetStrings(r.graph, interior),
+	}
+}
+
+func pathKey(path []int32) string {
+	key := make([]byte, 0, 4*len(path))
+	for _, asset := range path {
+		key = append(key, byte(asset>>24), byte(asset>>16), byte(asset>>8), byte(asset))
+	}
+	return string(key)
+}
+
+// splitSearchState searches the order book left by the previous parts of a
+// split path and only keeps the best path found.
+type splitSearchState struct {
+	searchState
+	book       *residualBook
+	bestPath   []int32
+	bestAmount xdr.Int64
+}
+
+func (state *splitSearchState) venues(currentAsset int32) edgeSet {
+	return state.book.edges(state.searchState.venues(currentAsset))
+}
+
+func (state *splitSearchState) appendToPaths(
+	path []int32,
+	currentAsset int32,
+	currentAssetAmount xdr.Int64,
+) {
+	// paths are found by increasing length, so the shortest path is kept when
+	// several paths result in the same amount
+	if state.bestPath == nil || state.betterPathAmount(state.bestAmount, currentAssetAmount) {
+		state.bestPath = append([]int32{}, path...)
+		state.bestAmount = currentAssetAmount
+	}
+}
+
+// residualBook holds the offers and liquidity pools of the order book
+// consumed by the previous parts of a split path.
+type residualBook struct {
+	// offers maps the consumed offers to their remaining amount.
+	offers map[xdr.Int64]xdr.Int64
+	// pools maps the consumed pools to their reserves.
+	pools map[xdr.PoolId][2]xdr.Int64
+}
+
+func newResidualBook() *residualBook {
+	return &residualBook{
+		offers: map[xdr.Int64]xdr.Int64{},
+		pools:  map[xdr.PoolId][2]xdr.Int64{},
+	}
+}
+
+func (b *residualBook) edges(edges edgeSet) edgeSet {
+	if len(b.offers) == 0 && len(b.pools) == 0 {
+		return edges
+	}
+	result := make(edgeSet, len(edges))
+	for i, e := range edges {
+		result[i] = edge{key: e.key, value: b.venues(e.value)}
+	}
+	return result
+}
+
+// venues returns the venues left once the consumed offers and pools are
+// accounted for.
+func (b *residualBook) venues(venues Venues) Venues {
+	result := venues
+	copied := false
+	for i, offer := range venues.offers {
+		remaining, ok := b.offers[offer.OfferId]
+		if !ok {
+			if copied {
+				result.offers = append(result.offers, offer)
+			}
+			continue
+		}
+		if !copied {
+			result.offers = append(make([]xdr.OfferEntry, 0, len(venues.offers)), venues.offers[:i]...)
+			copied = true
+		}
+		if remaining > 0 {
+			offer.Amount = remaining
+			result.offers = append(result.offers, offer)
+		}
+	}
+
+	if details := venues.pool.Body.ConstantProduct; details != nil {
+		if reserves, ok := b.pools[venues.pool.LiquidityPoolId]; ok {
+			updated := *details
+			updated.ReserveA, updated.ReserveB = reserves[0], reserves[1]
+			result.pool.Body.ConstantProduct = &updated
+		}
+	}
+	return result
+}
+
+type offerFill struct {
+	offerID   xdr.Int64
+	remaining xdr.Int64
+}
+
+func (b *residualBook) fill(fills []offerFill) {
+	for _, f := range fills {
+		b.offers[f.offerID] = f.remaining
+	}
+}
+
+// tradeWithPool records the deposit of an asset into the pool and the payout
+// of the other asset.
+func (b *residualBook) tradeWithPool(pool liquidityPool, depositAsset int32, deposit, payout xdr.Int64) {
+	details := pool.Body.MustConstantProduct()
+	reserveA, reserveB := details.ReserveA+deposit, details.ReserveB-payout
+	if pool.assetA != depositAsset {
+		reserveA, reserveB = details.ReserveA-payout, details.ReserveB+deposit
+	}
+	b.pools[pool.LiquidityPoolId] = [2]xdr.Int64{reserveA, reserveB}
+}
+
+// sell trades amount of asset against the venues buying it, picking the venue
+// the same way the path search does, and returns the amount of the other
+// asset received.
+func (b *residualBook) sell(venues Venues, asset int32, amount xdr.Int64, includePools bool) (xdr.Int64, error) {
+	poolAmount := xdr.Int64(0)
+	if pool := venues.pool; includePools && pool.Body.ConstantProduct != nil {
+		if received, err := makeTrade(pool, asset, tradeTypeDeposit, amount); err == nil {
+			poolAmount = received
+		}
+	}
+
+	offersAmount, fills, err := fillOffersForBuyingAsset(venues.offers, amount)
+	if err != nil {
+		return 0, err
+	}
+
+	if poolAmount > 0 && poolAmount > offersAmount {
+		b.tradeWithPool(venues.pool, asset, amount, poolAmount)
+		return poolAmount, nil
+	}
+	if offersAmount <= 0 {
+		return 0, errNoLiquidity
+	}
+	b.fill(fills)
+	return offersAmount, nil
+}
+
+// buy trades against the venues selling asset to receive amount of it,
+// picking the venue the same way the path search does, and returns the amount
+// of the next asset spent.
+func (b *residualBook) buy(
+	venues Venues,
+	asset, nextAsset int32,
+	amount xdr.Int64,
+	includePools bool,
+	ignoreOffersFrom *xdr.AccountId,
+) (xdr.Int64, error) {
+	poolAmount := xdr.Int64(0)
+	if pool := venues.pool; includePools && pool.Body.ConstantProduct != nil {
+		if deposit, err := makeTrade(pool, nextAsset, tradeTypeExpectation, amount); err == nil {
+			poolAmount = deposit
+		}
+	}
+
+	offersAmount, fills, err := fillOffersForSellingAsset(venues.offers, ignoreOffersFrom, amount)
+	if err != nil {
+		return 0, err
+	}
+
+	if poolAmount > 0 && (offersAmount <= 0 || poolAmount < offersAmount) {
+		b.tradeWithPool(venues.pool, nextAsset, poolAmount, amount)
+		return poolAmount, nil
+	}
+	if offersAmount <= 0 {
+		return 0, errNoLiquidity
+	}
+	b.fill(fills)
+	return offersAmount, nil
+}
+
+// fillOffersForBuyingAsset sells amount to the offers, like
+// consumeOffersForBuyingAsset, and also returns the remaining amount of the
+// offers crossed. It returns -1 if the offers can't absorb the amount.
+func fillOffersForBuyingAsset(offers []xdr.OfferEntry, amount xdr.Int64) (xdr.Int64, []offerFill, error) {
+	var fills []offerFill
+	received := xdr.Int64(0)
+	for i := 0; i < len(offers) && amount > 0; i++ {
+		n, d := int64(offers[i].Price.N), int64(offers[i].Price.D)
+
+		// check if we can spend all of amount on the current offer
+		amountSold, err := price.MulFractionRoundDown(int64(amount), d, n)
+		if err == nil {
+			if amountSold <= 0 {
+				return -1, nil, nil
+			}
+			if xdr.Int64(amountSold) <= offers[i].Amount {
+				fills = append(fills, offerFill{offers[i].OfferId, offers[i].Amount - xdr.Int64(amountSold)})
+				return received + xdr.Int64(amountSold), fills, nil
+			}
+		} else if err != price.ErrOverflow {
+			return -1, nil, err
+		}
+
+		buyingUnits, sellingUnits, err := price.ConvertToBuyingUnits(
+			int64(offers[i].Amount), int64(offers[i].Amount), n, d,
+		)
+		if err == price.ErrOverflow {
+			return -1, nil, nil
+		} else if err != nil {
+			return -1, nil, err
+		}
+		fills = append(fills, offerFill{offers[i].OfferId, offers[i].Amount - xdr.Int64(sellingUnits)})
+		received += xdr.Int64(sellingUnits)
+		amount -= xdr.Int64(buyingUnits)
+	}
+	if amount != 0 {
+		return -1, nil, nil
+	}
+	return received, fills, nil
+}
+
+// fillOffersForSellingAsset buys amount from the offers, like
+// consumeOffersForSellingAsset, and also returns the remaining amount of the
+// offers crossed. It returns -1 if the offers can't provide the amount.
+func fillOffersForSellingAsset(
+	offers []xdr.OfferEntry,
+	ignoreOffersFrom *xdr.AccountId,
+	amount xdr.Int64,
+) (xdr.Int64, []offerFill, error) {
+	var fills []offerFill
+	spent := xdr.Int64(0)
+	for i := 0; i < len(offers) && amount > 0; i++ {
+		if ignoreOffersFrom != nil && ignoreOffersFrom.Equals(offers[i].SellerId) {
+			continue
+		}
+
+		buyingUnits, sellingUnits, err := price.ConvertToBuyingUnits(
+			int64(offers[i].Amount),
+			int64(amount),
+			int64(offers[i].Price.N),
+			int64(offers[i].Price.D),
+		)
+		if err == price.ErrOverflow {
+			return -1, nil, nil
+		} else if err != nil {
+			return -1, nil, err
+		}
+		fills = append(fills, offerFill{offers[i].OfferId, offers[i].Amount - xdr.Int64(sellingUnits)})
+		spent += xdr.Int64(buyingUnits)
+		amount -= xdr.Int64(sellingUnits)
+	}
+	if amount != 0 {
+		return -1, nil, nil
+	}
+	return spent, fills, nil
+}
@@ -0,0 +1,153 @@
+package orderbook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pownieh/stellar_go/xdr"
+)
+
+func setupSplitPathsGraph(t *testing.T) *OrderBookGraph {
+	graph := NewOrderBookGraph()
+	// USD can be exchanged for EUR directly or through CHF
+	graph.AddLiquidityPools(
+		makePool(eurAsset, usdAsset, 1000, 1000),
+		makePool(chfAsset, usdAsset, 1000, 1000),
+		makePool(chfAsset, eurAsset, 1000, 1000),
+	)
+	require.NoError(t, graph.Apply(1))
+	return graph
+}
+
+func TestFindSplitPathsStrictSend(t *testing.T) {
+	graph := setupSplitPathsGraph(t)
+
+	single, _, err := graph.FindFixedPaths(context.TODO(), 3, usdAsset, 400, []xdr.Asset{eurAsset}, 5, true)
+	require.NoError(t, err)
+	require.NotEmpty(t, single)
+
+	split, lastLedger, err := graph.FindSplitPathsStrictSend(context.TODO(), 3, usdAsset, 400, eurAsset, 10, true)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1), lastLedger)
+
+	assert.Equal(t, usdAsset.String(), split.SourceAsset)
+	assert.Equal(t, xdr.Int64(400), split.SourceAmount)
+	assert.Equal(t, eurAsset.String(), split.DestinationAsset)
+	assert.Greater(t, split.DestinationAmount, single[0].DestinationAmount)
+
+	require.Len(t, split.Legs, 2)
+	assert.Empty(t, split.Legs[0].InteriorNodes)
+	assert.Equal(t, []string{chfAsset.String()}, split.Legs[1].InteriorNodes)
+	assert.Equal(t, split.SourceAmount, split.Legs[0].SourceAmount+split.Legs[1].SourceAmount)
+	assert.Equal(t, split.DestinationAmount, split.Legs[0].DestinationAmount+split.Legs[1].DestinationAmount)
+
+	// the first leg is priced against the whole order book
+	direct, err := makeTrade(graph.poolFromEntry(makePool(eurAsset, usdAsset, 1000, 1000)),
+		graph.assetStringToID[usdAsset.String()], tradeTypeDeposit, split.Legs[0].SourceAmount)
+	require.NoError(t, err)
+	assert.Equal(t, direct, split.Legs[0].DestinationAmount)
+}
+
+func TestFindSplitPathsStrictReceive(t *testing.T) {
+	graph := setupSplitPathsGraph(t)
+
+	single, _, err := graph.FindPaths(context.TODO(), 3, eurAsset, 300, nil,
+		[]xdr.Asset{usdAsset}, []xdr.Int64{0}, false, 5, true)
+	require.NoError(t, err)
+	require.NotEmpty(t, single)
+
+	split, _, err := graph.FindSplitPathsStrictReceive(context.TODO(), 3, usdAsset, eurAsset, 300, nil, 10, true)
+	require.NoError(t, err)
+
+	assert.Equal(t, xdr.Int64(300), split.DestinationAmount)
+	assert.Less(t, split.SourceAmount, single[0].SourceAmount)
+	require.Len(t, split.Legs, 2)
+	for _, leg := range split.Legs {
+		assert.Equal(t, usdAsset.String(), leg.SourceAsset)
+		assert.Equal(t, eurAsset.String(), leg.DestinationAsset)
+	}
+	assert.Equal(t, []string{chfAsset.String()}, split.Legs[1].InteriorNodes)
+	assert.Equal(t, split.SourceAmount, split.Legs[0].SourceAmount+split.Legs[1].SourceAmount)
+}
+
+func TestFindSplitPathsAccountsForConsumedOffers(t *testing.T) {
+	graph := NewOrderBookGraph()
+	// 500 XLM for USD at 0.5 and 500 XLM for USD at 1
+	graph.AddOffers(
+		xdr.OfferEntry{
+			SellerId: issuer,
+			OfferId:  1,
+			Buying:   usdAsset,
+			Selling:  nativeAsset,
+			Price:    xdr.Price{N: 1, D: 2},
+			Amount:   500,
+		},
+		xdr.OfferEntry{
+			SellerId: issuer,
+			OfferId:  2,
+			Buying:   usdAsset,
+			Selling:  nativeAsset,
+			Price:    xdr.Price{N: 1, D: 1},
+			Amount:   500,
+		},
+	)
+	require.NoError(t, graph.Apply(1))
+
+	split, _, err := graph.FindSplitPathsStrictReceive(context.TODO(), 3, usdAsset, nativeAsset, 600, nil, 4, false)
+	require.NoError(t, err)
+	// 500 XLM cost 250 USD and the 100 XLM left cost 100 USD
+	assert.Equal(t, xdr.Int64(350), split.SourceAmount)
+	require.Len(t, split.Legs, 1)
+	assert.Equal(t, xdr.Int64(600), split.Legs[0].DestinationAmount)
+
+	_, _, err = graph.FindSplitPathsStrictReceive(context.TODO(), 3, usdAsset, nativeAsset, 1001, nil, 4, false)
+	assert.Equal(t, ErrSplitPathNotFound, err)
+
+	// offers of the source account are ignored
+	_, _, err = graph.FindSplitPathsStrictReceive(context.TODO(), 3, usdAsset, nativeAsset, 100, &issuer, 4, false)
+	assert.Equal(t, ErrSplitPathNotFound, err)
+
+	split, _, err = graph.FindSplitPathsStrictSend(context.TODO(), 3, usdAsset, 350, nativeAsset, 7, false)
+	require.NoError(t, err)
+	assert.Equal(t, xdr.Int64(600), split.DestinationAmount)
+
+	// the parts can't be smaller than one stroop
+	split, _, err = graph.FindSplitPathsStrictSend(context.TODO(), 3, usdAsset, 3, nativeAsset, 10, false)
+	require.NoError(t, err)
+	assert.Equal(t, xdr.Int64(6), split.DestinationAmount)
+}
+
+func TestFindSplitPathsMergedLegExhausted(t *testing.T) {
+	graph := NewOrderBookGraph()
+	// 2 XLM for USD at 2
+	graph.AddOffers(xdr.OfferEntry{
+		SellerId: issuer,
+		OfferId:  1,
+		Buying:   usdAsset,
+		Selling:  nativeAsset,
+		Price:    xdr.Price{N: 2, D: 1},
+		Amount:   2,
+	})
+	require.NoError(t, graph.Apply(1))
+
+	// each part of 3 USD buys 1 XLM once rounded down, but the merged leg of
+	// 6 USD needs 3 XLM
+	_, _, err := graph.FindSplitPathsStrictSend(context.TODO(), 3, usdAsset, 6, nativeAsset, 2, false)
+	assert.Equal(t, ErrSplitPathNotFound, err)
+}
+
+func TestFindSplitPathsInvalidParams(t *testing.T) {
+	graph := setupSplitPathsGraph(t)
+
+	_, _, err := graph.FindSplitPathsStrictSend(context.TODO(), 3, usdAsset, 400, eurAsset, 0, true)
+	assert.Equal(t, errInvalidParts, err)
+
+	_, _, err = graph.FindSplitPathsStrictSend(context.TODO(), 3, usdAsset, 0, eurAsset, 10, true)
+	assert.Equal(t, errBadAmount, err)
+
+	_, _, err = graph.FindSplitPathsStrictSend(context.TODO(), 3, usdAsset, 400, yenAsset, 10, true)
+	assert.Equal(t, ErrSplitPathNotFound, err)
+}
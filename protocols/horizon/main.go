@@ -282,6 +282,24 @@ type OrderBookSummary struct {
 	Buying  Asset        `json:"counter"`
 }
 
+// OrderBookSnapshot is the order book of a trading pair stored by ingestion at
+// the close of a ledger, along with its depth and spread. The depth of the
+// asks is denominated in the base asset and the depth of the bids in the
+// counter asset.
+type OrderBookSnapshot struct {
+	Ledger   int32        `json:"ledger"`
+	ClosedAt time.Time    `json:"closed_at"`
+	Selling  Asset        `json:"base"`
+	Buying   Asset        `json:"counter"`
+	BestBid  string       `json:"best_bid,omitempty"`
+	BestAsk  string       `json:"best_ask,omitempty"`
+	Spread   string       `json:"spread,omitempty"`
+	BidDepth string       `json:"bid_depth"`
+	AskDepth string       `json:"ask_depth"`
+	Bids     []PriceLevel `json:"bids"`
+	Asks     []PriceLevel `json:"asks"`
+}
+
 // Path represents a single payment path.
 type Path struct {
 	SourceAssetType        string  `json:"source_asset_type"`
@@ -300,6 +318,64 @@ func (p Path) PagingToken() string {
 	return ""
 }
 
+// SplitPath represents a payment split across several payment paths. Each leg
+// is submitted as its own operation of type OperationType, in order.
+type SplitPath struct {
+	OperationType          string `json:"operation_type"`
+	SourceAssetType        string `json:"source_asset_type"`
+	SourceAssetCode        string `json:"source_asset_code,omitempty"`
+	SourceAssetIssuer      string `json:"source_asset_issuer,omitempty"`
+	SourceAmount           string `json:"source_amount"`
+	DestinationAssetType   string `json:"destination_asset_type"`
+	DestinationAssetCode   string `json:"destination_asset_code,omitempty"`
+	DestinationAssetIssuer string `json:"destination_asset_issuer,omitempty"`
+	DestinationAmount      string `json:"destination_amount"`
+	Legs                   []Path `json:"legs"`
+}
+
+// Quote is the result of trading an amount directly between two assets,
+// against the offers or the liquidity pool of the pair, whichever gives the
+// better result. Prices are expressed in units of the source asset per unit
+// of the destination asset and PriceImpact is the relative difference
+// between the average price and the mid price.
+type Quote struct {
+	OperationType          string              `json:"operation_type"`
+	SourceAssetType        string              `json:"source_asset_type"`
+	SourceAssetCode        string              `json:"source_asset_code,omitempty"`
+	SourceAssetIssuer      string              `json:"source_asset_issuer,omitempty"`
+	SourceAmount           string              `json:"source_amount"`
+	DestinationAssetType   string              `json:"destination_asset_type"`
+	DestinationAssetCode   string              `json:"destination_asset_code,omitempty"`
+	DestinationAssetIssuer string              `json:"destination_asset_issuer,omitempty"`
+	DestinationAmount      string              `json:"destination_amount"`
+	Venue                  string              `json:"venue"`
+	AveragePrice           string              `json:"average_price"`
+	ExecutionPrice         string              `json:"execution_price"`
+	MidPrice               string              `json:"mid_price,omitempty"`
+	PriceImpact            string              `json:"price_impact,omitempty"`
+	Offers                 []QuoteOffer        `json:"offers"`
+	LiquidityPool          *QuoteLiquidityPool `json:"liquidity_pool,omitempty"`
+}
+
+// QuoteOffer is an offer consumed by a quote, Amount is the amount of the
+// destination asset bought from it.
+type QuoteOffer struct {
+	ID     int64  `json:"id,string"`
+	Seller string `json:"seller"`
+	PriceR Price  `json:"price_r"`
+	Price  string `json:"price"`
+	Amount string `json:"amount"`
+}
+
+// QuoteLiquidityPool is the liquidity pool a quote is executed against, with
+// its reserves before and after the trade.
+type QuoteLiquidityPool struct {
+	ID            string                 `json:"id"`
+	FeeBP         uint32                 `json:"fee_bp"`
+	Reserves      []LiquidityPoolReserve `json:"reserves"`
+	ReservesAfter []LiquidityPoolReserve `json:"reserves_after"`
+}
+
 // Price represents a price for an offer
 type Price base.Price
 
@@ -350,6 +426,10 @@ type Root struct {
 	CurrentProtocolVersion       int32     `json:"current_protocol_version"`
 	SupportedProtocolVersion     uint32    `json:"supported_protocol_version"`
 	CoreSupportedProtocolVersion int32     `json:"core_supported_protocol_version"`
+
+	// HistoryElderSequenceByResource is the oldest ledger of the history
+	// resources which are retained for fewer ledgers than the ledgers.
+	HistoryElderSequenceByResource map[string]int32 `json:"history_elder_ledger_by_resource,omitempty"`
 }
 
 // Signer represents one of an account's signers.
@@ -476,6 +556,16 @@ type TradeAggregation struct {
 	OpenR         TradePrice `json:"open_r"`
 	Close         string     `json:"close"`
 	CloseR        TradePrice `json:"close_r"`
+	// VWAP is the volume weighted average price of the trades.
+	VWAP string `json:"vwap"`
+	// The trades are split between the ones with liquidity pools and the ones
+	// which exercised offers on the orderbook.
+	LiquidityPoolTradeCount    int64  `json:"liquidity_pool_trade_count,string"`
+	LiquidityPoolBaseVolume    string `json:"liquidity_pool_base_volume"`
+	LiquidityPoolCounterVolume string `json:"liquidity_pool_counter_volume"`
+	OrderbookTradeCount        int64  `json:"orderbook_trade_count,string"`
+	OrderbookBaseVolume        string `json:"orderbook_base_volume"`
+	OrderbookCounterVolume     string `json:"orderbook_counter_volume"`
 }
 
 // PagingToken implementation for hal.Pageable. Not actually used
@@ -483,6 +573,27 @@ func (res TradeAggregation) PagingToken() string {
 	return strconv.FormatInt(res.Timestamp, 10)
 }
 
+// LiquidityPoolStats represents the activity of a liquidity pool over a time
+// window. Volume and Fees are the amounts of each asset traded with the pool
+// and earned by it, Reserves, TotalShares and SharePrice are taken at the end
+// of the window. FeeAPR is the annualized yield of the fees relative to the
+// reserves.
+type LiquidityPoolStats struct {
+	Timestamp   int64                  `json:"timestamp,string"`
+	TradeCount  int64                  `json:"trade_count,string"`
+	Volume      []LiquidityPoolReserve `json:"volume"`
+	Fees        []LiquidityPoolReserve `json:"fees"`
+	Reserves    []LiquidityPoolReserve `json:"reserves"`
+	TotalShares string                 `json:"total_shares"`
+	SharePrice  []LiquidityPoolReserve `json:"share_price"`
+	FeeAPR      string                 `json:"fee_apr"`
+}
+
+// PagingToken implementation for hal.Pageable. Not actually used
+func (res LiquidityPoolStats) PagingToken() string {
+	return strconv.FormatInt(res.Timestamp, 10)
+}
+
 // Transaction represents a single, successful transaction
 type Transaction struct {
 	Links struct {
@@ -665,6 +776,58 @@ type AccountData struct {
 	Sponsor string `json:"sponsor,omitempty"`
 }
 
+// AccountSponsorships are the entries an account sponsors, grouped by entry
+// type. Reserves are counted in base reserves, the amounts are in lumens.
+type AccountSponsorships struct {
+	AccountID     string            `json:"account_id"`
+	BaseReserve   string            `json:"base_reserve"`
+	NumSponsoring uint32            `json:"num_sponsoring"`
+	Reserves      int64             `json:"reserves"`
+	ReserveAmount string            `json:"reserve_amount"`
+	EntryTypes    []SponsorshipType `json:"entry_types"`
+}
+
+// SponsorshipType sums up the entries of a given type sponsored by an
+// account. Entries is limited by the `limit` request parameter, Count is the
+// number of all of them.
+type SponsorshipType struct {
+	Type          string           `json:"type"`
+	Count         int64            `json:"count"`
+	Reserves      int64            `json:"reserves"`
+	ReserveAmount string           `json:"reserve_amount"`
+	Entries       []SponsoredEntry `json:"entries"`
+}
+
+// AccountSponsors are the entries of an account which are sponsored by other
+// accounts, grouped by sponsor.
+type AccountSponsors struct {
+	AccountID     string    `json:"account_id"`
+	BaseReserve   string    `json:"base_reserve"`
+	NumSponsored  uint32    `json:"num_sponsored"`
+	Reserves      int64     `json:"reserves"`
+	ReserveAmount string    `json:"reserve_amount"`
+	Sponsors      []Sponsor `json:"sponsors"`
+}
+
+// Sponsor sums up the entries of an account sponsored by a given account.
+type Sponsor struct {
+	Sponsor       string           `json:"sponsor"`
+	Reserves      int64            `json:"reserves"`
+	ReserveAmount string           `json:"reserve_amount"`
+	Entries       []SponsoredEntry `json:"entries"`
+}
+
+// SponsoredEntry is a sponsored ledger entry or signer. Key identifies the
+// entry among the entries of its type and account: the account id, the
+// signer key, the trust line asset or liquidity pool id, the data name, the
+// offer id or the claimable balance id.
+type SponsoredEntry struct {
+	Type      string `json:"type"`
+	AccountID string `json:"account_id,omitempty"`
+	Key       string `json:"key"`
+	Reserves  int64  `json:"reserves"`
+}
+
 // AccountsPage returns a list of account records
 type AccountsPage struct {
 	Links    hal.Links `json:"_links"`
@@ -741,6 +904,187 @@ type FeeStats struct {
 	MaxFee     FeeDistribution `json:"max_fee"`
 }
 
+// LedgerFeeDistribution represents a summary of the fees of the transactions
+// of a ledger
+type LedgerFeeDistribution struct {
+	Max int64 `json:"max,string"`
+	Min int64 `json:"min,string"`
+	P10 int64 `json:"p10,string"`
+	P50 int64 `json:"p50,string"`
+	P90 int64 `json:"p90,string"`
+	P99 int64 `json:"p99,string"`
+}
+
+// LedgerFeeStats represents the fees of the transactions included in a
+// ledger. The classic transaction fees are per operation, when the ledger has
+// no classic transactions they are the base fee. The Soroban transaction fees
+// are per transaction and are zero when the ledger has no Soroban
+// transactions.
+type LedgerFeeStats struct {
+	PT                      string                `json:"paging_token"`
+	Ledger                  uint32                `json:"ledger,string"`
+	ClosedAt                time.Time             `json:"closed_at"`
+	BaseFee                 int64                 `json:"base_fee,string"`
+	LedgerCapacityUsage     float64               `json:"ledger_capacity_usage,string"`
+	TransactionCount        int32                 `json:"transaction_count"`
+	FeeCharged              LedgerFeeDistribution `json:"fee_charged"`
+	MaxFee                  LedgerFeeDistribution `json:"max_fee"`
+	SorobanTransactionCount int32                 `json:"soroban_transaction_count"`
+	SorobanFeeCharged       LedgerFeeDistribution `json:"soroban_fee_charged"`
+	SorobanRefundableFee    LedgerFeeDistribution `json:"soroban_refundable_fee"`
+}
+
+// PagingToken implementation for hal.Pageable
+func (res LedgerFeeStats) PagingToken() string {
+	return res.PT
+}
+
+// LedgerFeeStatsPage contains records of ledger fee stats returned by Horizon
+type LedgerFeeStatsPage struct {
+	Links    hal.Links `json:"_links"`
+	Embedded struct {
+		Records []LedgerFeeStats
+	} `json:"_embedded"`
+}
+
+// FeeEstimate represents the fee recommended by horizon for a transaction to
+// be included with a given probability within a given number of ledgers.
+type FeeEstimate struct {
+	LastLedger        uint32  `json:"last_ledger,string"`
+	LastLedgerBaseFee int64   `json:"last_ledger_base_fee,string"`
+	Ledgers           uint32  `json:"ledgers,string"`
+	Probability       float64 `json:"probability,string"`
+	WithinLedgers     uint32  `json:"within_ledgers,string"`
+	// InclusionFee is the fee to bid per operation.
+	InclusionFee int64 `json:"inclusion_fee,string"`
+	// MaxFee is the inclusion fee multiplied by the number of operations.
+	MaxFee  int64              `json:"max_fee,string"`
+	Soroban SorobanFeeEstimate `json:"soroban"`
+}
+
+// SorobanFeeEstimate represents the fees of the recent Soroban transactions.
+// The resource fee of a Soroban transaction depends on the resources it uses,
+// it must be added to the inclusion fee.
+type SorobanFeeEstimate struct {
+	TransactionCount int64                 `json:"transaction_count,string"`
+	FeeCharged       LedgerFeeDistribution `json:"fee_charged"`
+	RefundableFee    LedgerFeeDistribution `json:"refundable_fee"`
+}
+
+// QueuedTransaction represents a transaction of the transaction submission
+// queue, which Horizon rebroadcasts until it is included in a ledger or
+// cannot be included anymore.
+type QueuedTransaction struct {
+	Links struct {
+		Self        hal.Link `json:"self"`
+		Transaction hal.Link `json:"transaction"`
+	} `json:"_links"`
+	Hash            string `json:"hash"`
+	InnerHash       string `json:"inner_hash,omitempty"`
+	Status          string `json:"status"`
+	Account         string `json:"source_account"`
+	AccountSequence int64  `json:"source_account_sequence,string"`
+	// SubmissionCount is the number of times the transaction was submitted to
+	// stellar-core.
+	SubmissionCount int32      `json:"submission_count"`
+	CreatedAt       time.Time  `json:"created_at"`
+	LastSubmittedAt time.Time  `json:"last_submitted_at"`
+	FinishedAt      *time.Time `json:"finished_at,omitempty"`
+	MaxTime         string     `json:"max_time,omitempty"`
+	MaxLedger       uint32     `json:"max_ledger,omitempty"`
+	// Ledger is the ledger the transaction was included in.
+	Ledger      int32  `json:"ledger,omitempty"`
+	EnvelopeXdr string `json:"envelope_xdr"`
+	ResultXdr   string `json:"result_xdr,omitempty"`
+}
+
+// TransactionDryRun is the expected outcome of a transaction given the latest
+// ingested ledger and the order book. The transaction isn't submitted.
+type TransactionDryRun struct {
+	Hash   string `json:"hash"`
+	Ledger uint32 `json:"ledger"`
+	// OrderBookLedger is the ledger of the order book the trades of path
+	// payments and offers are priced against, it may differ from Ledger.
+	OrderBookLedger uint32 `json:"order_book_ledger,omitempty"`
+	// Signed is false when the transaction has no signatures, they aren't
+	// checked then.
+	Signed bool `json:"signed"`
+	// Complete is false when the transaction has operations which the dry run
+	// doesn't support, the result and the effects only cover the operations
+	// before the first of them.
+	Complete    bool                   `json:"complete"`
+	Successful  bool                   `json:"successful"`
+	ResultCodes TransactionResultCodes `json:"result_codes"`
+	// Reason explains why the transaction is expected to fail.
+	Reason                   string                    `json:"reason,omitempty"`
+	FeeAccount               string                    `json:"fee_account"`
+	FeeCharged               int64                     `json:"fee_charged,string"`
+	Operations               []DryRunOperation         `json:"operations"`
+	BalanceChanges           []DryRunBalanceChange     `json:"balance_changes"`
+	TrustLinesCreated        []DryRunTrustLine         `json:"trustlines_created"`
+	ClaimableBalancesCreated []DryRunClaimableBalance  `json:"claimable_balances_created"`
+	SponsorshipChanges       []DryRunSponsorshipChange `json:"sponsorship_changes"`
+	ReserveChanges           []DryRunReserveChange     `json:"reserve_changes"`
+}
+
+// DryRunOperation is the expected outcome of an operation of a
+// TransactionDryRun. The amounts are set for path payments and offers,
+// MinimumReceived and MaximumSent are the bounds of path payments.
+type DryRunOperation struct {
+	Type              string               `json:"type"`
+	SourceAccount     string               `json:"source_account"`
+	Supported         bool                 `json:"supported"`
+	ResultCode        string               `json:"result_code,omitempty"`
+	SourceAmount      string               `json:"source_amount,omitempty"`
+	DestinationAmount string               `json:"destination_amount,omitempty"`
+	MinimumReceived   string               `json:"minimum_received,omitempty"`
+	MaximumSent       string               `json:"maximum_sent,omitempty"`
+	OfferAmount       string               `json:"offer_amount,omitempty"`
+	OffersCrossed     []QuoteOffer         `json:"offers_crossed,omitempty"`
+	LiquidityPools    []QuoteLiquidityPool `json:"liquidity_pools,omitempty"`
+}
+
+// DryRunBalanceChange is the change of the balance of an account.
+type DryRunBalanceChange struct {
+	Account string `json:"account"`
+	base.Asset
+	Amount string `json:"amount"`
+}
+
+// DryRunTrustLine is a trust line created by a transaction.
+type DryRunTrustLine struct {
+	Account string `json:"account"`
+	base.Asset
+	Limit      string `json:"limit"`
+	Authorized bool   `json:"authorized"`
+	Sponsor    string `json:"sponsor,omitempty"`
+}
+
+// DryRunClaimableBalance is a claimable balance created by a transaction.
+type DryRunClaimableBalance struct {
+	Sponsor string `json:"sponsor"`
+	base.Asset
+	Amount    string   `json:"amount"`
+	Claimants []string `json:"claimants"`
+}
+
+// DryRunSponsorshipChange is a reserve sponsored, or no longer sponsored when
+// Removed is true, by a transaction.
+type DryRunSponsorshipChange struct {
+	OperationIndex int    `json:"operation_index"`
+	Sponsor        string `json:"sponsor"`
+	Account        string `json:"account,omitempty"`
+	EntryType      string `json:"entry_type"`
+	Removed        bool   `json:"removed"`
+}
+
+// DryRunReserveChange is the change of the minimum balance of an account.
+type DryRunReserveChange struct {
+	Account string `json:"account"`
+	Before  string `json:"minimum_balance_before"`
+	After   string `json:"minimum_balance_after"`
+}
+
 // TransactionsPage contains records of transaction information returned by Horizon
 type TransactionsPage struct {
 	Links    hal.Links `json:"_links"`
@@ -852,6 +1196,25 @@ type AccountFilterConfig struct {
 	LastModified int64    `json:"last_modified,omitempty"`
 }
 
+type RulesFilterConfig struct {
+	Allow        []FilterRule `json:"allow"`
+	Deny         []FilterRule `json:"deny"`
+	Enabled      *bool        `json:"enabled"`
+	LastModified int64        `json:"last_modified,omitempty"`
+}
+
+// FilterRule matches transactions in a RulesFilterConfig. All the conditions
+// set in a rule must match for the rule to match.
+type FilterRule struct {
+	And            []FilterRule `json:"and,omitempty"`
+	Or             []FilterRule `json:"or,omitempty"`
+	Not            *FilterRule  `json:"not,omitempty"`
+	OperationTypes []string     `json:"operation_types,omitempty"`
+	ContractIDs    []string     `json:"contract_ids,omitempty"`
+	SourceAccounts []string     `json:"source_accounts,omitempty"`
+	MemoPattern    string       `json:"memo_pattern,omitempty"`
+}
+
 func (f *AccountFilterConfig) UnmarshalJSON(data []byte) error {
 	type accountFilterConfig AccountFilterConfig
 	var config = accountFilterConfig{}
@@ -891,3 +1254,27 @@ func (f *AssetFilterConfig) UnmarshalJSON(data []byte) error {
 	*f = AssetFilterConfig(config)
 	return nil
 }
+
+func (f *RulesFilterConfig) UnmarshalJSON(data []byte) error {
+	type rulesFilterConfig RulesFilterConfig
+	var config = rulesFilterConfig{}
+
+	if err := json.Unmarshal(data, &config); err != nil {
+		return err
+	}
+
+	if config.Enabled == nil {
+		return errors.New("missing required enabled")
+	}
+
+	if config.Allow == nil {
+		config.Allow = []FilterRule{}
+	}
+
+	if config.Deny == nil {
+		config.Deny = []FilterRule{}
+	}
+
+	*f = RulesFilterConfig(config)
+	return nil
+}
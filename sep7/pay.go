@@ -0,0 +1,193 @@
+package sep7
+
+import (
+	"encoding/base64"
+	"strconv"
+
+	"github.com/pownieh/stellar_go/amount"
+	"github.com/pownieh/stellar_go/strkey"
+	"github.com/pownieh/stellar_go/support/errors"
+	"github.com/pownieh/stellar_go/txnbuild"
+)
+
+// Memo types accepted by the memo_type parameter of pay URIs.
+const (
+	MemoTypeText   = "MEMO_TEXT"
+	MemoTypeID     = "MEMO_ID"
+	MemoTypeHash   = "MEMO_HASH"
+	MemoTypeReturn = "MEMO_RETURN"
+)
+
+// PayURI is a SEP-7 request asking a wallet to pay a destination, letting the
+// wallet choose the source asset and path.
+type PayURI struct {
+	Params
+	// Destination is the account (G...) or muxed account (M...) to pay.
+	Destination string
+	// Amount is the amount to pay, leaving it empty lets the user choose.
+	Amount string
+	// AssetCode is the code of the asset to pay, leaving it empty means
+	// XLM.
+	AssetCode string
+	// AssetIssuer is the issuer of AssetCode.
+	AssetIssuer string
+	// Memo is the memo to attach to the payment. Hash and return memos are
+	// base64 encoded.
+	Memo string
+	// MemoType is one of the MemoType* constants, MemoTypeText by default.
+	MemoType string
+}
+
+var _ URI = (*PayURI)(nil)
+
+// NewPayURI returns a PayURI requesting the given payment with an optional
+// memo. The payment source account is not part of the request, the wallet
+// picks it.
+func NewPayURI(payment txnbuild.Payment, memo txnbuild.Memo) (*PayURI, error) {
+	u := &PayURI{
+		Destination: payment.Destination,
+		Amount:      payment.Amount,
+	}
+	if payment.Asset != nil && !payment.Asset.IsNative() {
+		u.AssetCode = payment.Asset.GetCode()
+		u.AssetIssuer = payment.Asset.GetIssuer()
+	}
+	if err := u.SetMemo(memo); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// Operation returns OperationPay.
+func (u *PayURI) Operation() string {
+	return OperationPay
+}
+
+// String returns the encoded URI.
+func (u *PayURI) String() string {
+	return encode(u)
+}
+
+// Asset returns the requested asset.
+func (u *PayURI) Asset() txnbuild.Asset {
+	if u.AssetCode == "" {
+		return txnbuild.NativeAsset{}
+	}
+	return txnbuild.CreditAsset{Code: u.AssetCode, Issuer: u.AssetIssuer}
+}
+
+// SetMemo sets the Memo and MemoType parameters from memo. A nil memo clears
+// them.
+func (u *PayURI) SetMemo(memo txnbuild.Memo) error {
+	switch m := memo.(type) {
+	case nil:
+		u.Memo, u.MemoType = "", ""
+	case txnbuild.MemoText:
+		u.Memo, u.MemoType = string(m), MemoTypeText
+	case txnbuild.MemoID:
+		u.Memo, u.MemoType = strconv.FormatUint(uint64(m), 10), MemoTypeID
+	case txnbuild.MemoHash:
+		u.Memo, u.MemoType = base64.StdEncoding.EncodeToString(m[:]), MemoTypeHash
+	case txnbuild.MemoReturn:
+		u.Memo, u.MemoType = base64.StdEncoding.EncodeToString(m[:]), MemoTypeReturn
+	default:
+		return errors.Errorf("unsupported memo type %T", memo)
+	}
+	return nil
+}
+
+// MemoValue decodes the Memo and MemoType parameters. It returns nil when no
+// memo is requested.
+func (u *PayURI) MemoValue() (txnbuild.Memo, error) {
+	if u.Memo == "" {
+		return nil, nil
+	}
+	switch u.MemoType {
+	case "", MemoTypeText:
+		return txnbuild.MemoText(u.Memo), nil
+	case MemoTypeID:
+		id, err := strconv.ParseUint(u.Memo, 10, 64)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid id memo")
+		}
+		return txnbuild.MemoID(id), nil
+	case MemoTypeHash, MemoTypeReturn:
+		raw, err := base64.StdEncoding.DecodeString(u.Memo)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid hash memo")
+		}
+		var hash [32]byte
+		if len(raw) != len(hash) {
+			return nil, errors.Errorf("hash memo must be %d bytes long", len(hash))
+		}
+		copy(hash[:], raw)
+		if u.MemoType == MemoTypeHash {
+			return txnbuild.MemoHash(hash), nil
+		}
+		return txnbuild.MemoReturn(hash), nil
+	default:
+		return nil, errors.Errorf("unknown memo_type %q", u.MemoType)
+	}
+}
+
+// Validate checks that the URI parameters conform to SEP-7.
+func (u *PayURI) Validate() error {
+	if !strkey.IsValidEd25519PublicKey(u.Destination) &&
+		!strkey.IsValidMuxedAccountEd25519PublicKey(u.Destination) {
+		return errors.New("destination is not a valid account id")
+	}
+	if u.Amount != "" {
+		if _, err := amount.Parse(u.Amount); err != nil {
+			return errors.Wrap(err, "invalid amount")
+		}
+	}
+	if u.AssetCode != "" {
+		if _, err := u.Asset().ToXDR(); err != nil {
+			return errors.Wrap(err, "invalid asset")
+		}
+	} else if u.AssetIssuer != "" {
+		return errors.New("asset_issuer requires asset_code")
+	}
+	memo, err := u.MemoValue()
+	if err != nil {
+		return err
+	}
+	if memo != nil {
+		if _, err := memo.ToXDR(); err != nil {
+			return errors.Wrap(err, "invalid memo")
+		}
+	}
+	return u.Params.validate()
+}
+
+func (u *PayURI) query() query {
+	var q query
+	q.add("destination", u.Destination)
+	q.add("amount", u.Amount)
+	q.add("asset_code", u.AssetCode)
+	q.add("asset_issuer", u.AssetIssuer)
+	q.add("memo", u.Memo)
+	q.add("memo_type", u.MemoType)
+	u.Params.addTo(&q)
+	return q
+}
+
+func (u *PayURI) set(key, value string) error {
+	switch key {
+	case "destination":
+		u.Destination = value
+	case "amount":
+		u.Amount = value
+	case "asset_code":
+		u.AssetCode = value
+	case "asset_issuer":
+		u.AssetIssuer = value
+	case "memo":
+		u.Memo = value
+	case "memo_type":
+		u.MemoType = value
+	default:
+		return u.Params.set(key, value)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package sep7
+
+import (
+	"testing"
+
+	"github.com/pownieh/stellar_go/txnbuild"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPayURI(t *testing.T) {
+	usdc := txnbuild.CreditAsset{Code: "USDC", Issuer: "GA5ZSEJYB37JRC5AVCIA5MOP4RHTM335X2KGX3IHOJAPP5RE34K4KZVN"}
+	u, err := NewPayURI(txnbuild.Payment{
+		Destination: destination,
+		Amount:      "25",
+		Asset:       usdc,
+	}, txnbuild.MemoID(7))
+	require.NoError(t, err)
+	assert.Equal(t,
+		"web+stellar:pay?destination="+destination+"&amount=25&asset_code=USDC&asset_issuer="+usdc.Issuer+"&memo=7&memo_type=MEMO_ID",
+		u.String(),
+	)
+	assert.Equal(t, usdc, u.Asset())
+
+	parsed, err := Parse(u.String())
+	require.NoError(t, err)
+	assert.Equal(t, u, parsed)
+
+	u, err = NewPayURI(txnbuild.Payment{Destination: destination, Asset: txnbuild.NativeAsset{}}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "web+stellar:pay?destination="+destination, u.String())
+	assert.Equal(t, txnbuild.NativeAsset{}, u.Asset())
+}
+
+func TestPayURIMemo(t *testing.T) {
+	hash := txnbuild.MemoHash{1, 2, 3}
+	for _, memo := range []txnbuild.Memo{
+		nil,
+		txnbuild.MemoText("hello"),
+		txnbuild.MemoID(123),
+		hash,
+		txnbuild.MemoReturn(hash),
+	} {
+		u := &PayURI{Destination: destination}
+		require.NoError(t, u.SetMemo(memo))
+		got, err := u.MemoValue()
+		require.NoError(t, err)
+		assert.Equal(t, memo, got)
+	}
+
+	u := &PayURI{Destination: destination, Memo: "AQID", MemoType: MemoTypeHash}
+	assert.EqualError(t, u.Validate(), "hash memo must be 32 bytes long")
+
+	u = &PayURI{Destination: destination, Memo: "x", MemoType: "MEMO_FOO"}
+	assert.EqualError(t, u.Validate(), `unknown memo_type "MEMO_FOO"`)
+}
+
+func TestPayURIValidate(t *testing.T) {
+	assert.EqualError(t, (&PayURI{}).Validate(), "destination is not a valid account id")
+	assert.EqualError(t,
+		(&PayURI{Destination: destination, AssetIssuer: destination}).Validate(),
+		"asset_issuer requires asset_code",
+	)
+	assert.Error(t, (&PayURI{Destination: destination, Amount: "abc"}).Validate())
+	assert.Error(t, (&PayURI{Destination: destination, AssetCode: "USD"}).Validate())
+}
@@ -0,0 +1,98 @@
+package sep7
+
+import (
+	"strings"
+
+	"github.com/pownieh/stellar_go/support/errors"
+)
+
+// Replacement asks the wallet to replace a transaction field before signing.
+// Several fields may share the same Reference, in which case they take the
+// same value.
+type Replacement struct {
+	// Path is the SEP-11 Txrep path of the field, for example
+	// "sourceAccount" or "operations[0].sourceAccount".
+	Path string
+	// Reference is an identifier linking the field to its Hint.
+	Reference string
+	// Hint describes the value the wallet should use.
+	Hint string
+}
+
+// encodeReplacements encodes replacements as
+// "path1:ref1,path2:ref2;ref1:hint1,ref2:hint2".
+func encodeReplacements(replacements []Replacement) string {
+	if len(replacements) == 0 {
+		return ""
+	}
+
+	fields := make([]string, 0, len(replacements))
+	hints := make([]string, 0, len(replacements))
+	seen := map[string]bool{}
+	for _, r := range replacements {
+		fields = append(fields, r.Path+":"+r.Reference)
+		if !seen[r.Reference] {
+			seen[r.Reference] = true
+			hints = append(hints, r.Reference+":"+r.Hint)
+		}
+	}
+	return strings.Join(fields, ",") + ";" + strings.Join(hints, ",")
+}
+
+func parseReplacements(value string) ([]Replacement, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	rawFields, rawHints, ok := strings.Cut(value, ";")
+	if !ok {
+		return nil, errors.New("missing ';' separating fields from hints")
+	}
+
+	hints := map[string]string{}
+	for _, rawHint := range strings.Split(rawHints, ",") {
+		reference, hint, ok := strings.Cut(rawHint, ":")
+		if !ok {
+			return nil, errors.Errorf("malformed hint %q", rawHint)
+		}
+		hints[reference] = hint
+	}
+
+	var replacements []Replacement
+	for _, rawField := range strings.Split(rawFields, ",") {
+		path, reference, ok := strings.Cut(rawField, ":")
+		if !ok {
+			return nil, errors.Errorf("malformed field %q", rawField)
+		}
+		hint, ok := hints[reference]
+		if !ok {
+			return nil, errors.Errorf("no hint for reference %q", reference)
+		}
+		replacements = append(replacements, Replacement{
+			Path:      path,
+			Reference: reference,
+			Hint:      hint,
+		})
+	}
+	return replacements, nil
+}
+
+func validateReplacements(replacements []Replacement) error {
+	hints := map[string]string{}
+	for _, r := range replacements {
+		if r.Path == "" || r.Reference == "" {
+			return errors.New("path and reference are required")
+		}
+		if strings.ContainsAny(r.Path, ":,;") || strings.ContainsAny(r.Reference, ":,;") {
+			return errors.Errorf("replacement %q contains a reserved character", r.Path)
+		}
+		if strings.ContainsAny(r.Hint, ",;") {
+			return errors.Errorf("hint for %q contains a reserved character", r.Reference)
+		}
+		if hint, ok := hints[r.Reference]; ok && hint != r.Hint {
+			return errors.Errorf("reference %q has conflicting hints", r.Reference)
+		}
+		hints[r.Reference] = r.Hint
+	}
+	return nil
+}
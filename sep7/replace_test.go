@@ -0,0 +1,49 @@
+package sep7
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplacementsRoundTrip(t *testing.T) {
+	replacements := []Replacement{
+		{Path: "sourceAccount", Reference: "X", Hint: "account from where you want to pay fees"},
+		{Path: "operations[0].sourceAccount", Reference: "Y", Hint: "account that needs the trustline and which will receive the new tokens"},
+		{Path: "operations[1].destination", Reference: "Y", Hint: "account that needs the trustline and which will receive the new tokens"},
+	}
+	encoded := encodeReplacements(replacements)
+	assert.Equal(t,
+		"sourceAccount:X,operations[0].sourceAccount:Y,operations[1].destination:Y;"+
+			"X:account from where you want to pay fees,Y:account that needs the trustline and which will receive the new tokens",
+		encoded,
+	)
+
+	parsed, err := parseReplacements(encoded)
+	require.NoError(t, err)
+	assert.Equal(t, replacements, parsed)
+}
+
+func TestParseReplacementsErrors(t *testing.T) {
+	_, err := parseReplacements("sourceAccount:X")
+	assert.EqualError(t, err, "missing ';' separating fields from hints")
+
+	_, err = parseReplacements("sourceAccount:X;Y:hint")
+	assert.EqualError(t, err, `no hint for reference "X"`)
+
+	_, err = parseReplacements("sourceAccount;X:hint")
+	assert.EqualError(t, err, `malformed field "sourceAccount"`)
+}
+
+func TestValidateReplacements(t *testing.T) {
+	assert.NoError(t, validateReplacements(nil))
+	assert.EqualError(t,
+		validateReplacements([]Replacement{{Path: "sourceAccount", Reference: "X", Hint: "a"}, {Path: "seqNum", Reference: "X", Hint: "b"}}),
+		`reference "X" has conflicting hints`,
+	)
+	assert.EqualError(t,
+		validateReplacements([]Replacement{{Path: "sourceAccount", Reference: "X", Hint: "a;b"}}),
+		`hint for "X" contains a reserved character`,
+	)
+}
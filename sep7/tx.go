@@ -0,0 +1,105 @@
+package sep7
+
+import (
+	"github.com/pownieh/stellar_go/strkey"
+	"github.com/pownieh/stellar_go/support/errors"
+	"github.com/pownieh/stellar_go/txnbuild"
+)
+
+// TxURI is a SEP-7 request asking a wallet to sign, and optionally submit, a
+// transaction.
+type TxURI struct {
+	Params
+	// XDR is the base64 encoded TransactionEnvelope to sign.
+	XDR string
+	// Replace lists the transaction fields the wallet should fill in before
+	// signing, following the SEP-11 Txrep field paths.
+	Replace []Replacement
+	// Pubkey is the public key the wallet should sign with, if the
+	// requester needs a specific signer.
+	Pubkey string
+	// Chain is a nested SEP-7 URI that triggered this one.
+	Chain string
+}
+
+var _ URI = (*TxURI)(nil)
+
+// NewTxURI returns a TxURI requesting the signing of tx.
+func NewTxURI(tx *txnbuild.Transaction) (*TxURI, error) {
+	xdr, err := tx.Base64()
+	if err != nil {
+		return nil, errors.Wrap(err, "encoding transaction")
+	}
+	return &TxURI{XDR: xdr}, nil
+}
+
+// Operation returns OperationTx.
+func (u *TxURI) Operation() string {
+	return OperationTx
+}
+
+// String returns the encoded URI.
+func (u *TxURI) String() string {
+	return encode(u)
+}
+
+// Transaction decodes the transaction envelope of the request.
+func (u *TxURI) Transaction() (*txnbuild.GenericTransaction, error) {
+	tx, err := txnbuild.TransactionFromXDR(u.XDR)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid xdr")
+	}
+	return tx, nil
+}
+
+// Validate checks that the URI parameters conform to SEP-7.
+func (u *TxURI) Validate() error {
+	if u.XDR == "" {
+		return errors.New("xdr is required")
+	}
+	if _, err := u.Transaction(); err != nil {
+		return err
+	}
+	if u.Pubkey != "" && !strkey.IsValidEd25519PublicKey(u.Pubkey) {
+		return errors.New("pubkey is not a valid account id")
+	}
+	if err := validateReplacements(u.Replace); err != nil {
+		return errors.Wrap(err, "invalid replace")
+	}
+	return u.Params.validate()
+}
+
+func (u *TxURI) query() query {
+	var q query
+	q.add("xdr", u.XDR)
+	q.add("replace", encodeReplacements(u.Replace))
+	if u.Callback != "" {
+		q.add("callback", callbackPrefix+u.Callback)
+	}
+	q.add("pubkey", u.Pubkey)
+	q.add("chain", u.Chain)
+	q.add("msg", u.Msg)
+	q.add("network_passphrase", u.NetworkPassphrase)
+	q.add("origin_domain", u.OriginDomain)
+	return q
+}
+
+func (u *TxURI) set(key, value string) error {
+	switch key {
+	case "xdr":
+		u.XDR = value
+	case "replace":
+		replace, err := parseReplacements(value)
+		if err != nil {
+			return errors.Wrap(err, "invalid replace")
+		}
+		u.Replace = replace
+	case "pubkey":
+		u.Pubkey = value
+	case "chain":
+		u.Chain = value
+	default:
+		return u.Params.set(key, value)
+	}
+	return nil
+}
@@ -0,0 +1,352 @@
+// Package sep7 builds, parses, signs and verifies SEP-7 URIs (the
+// `web+stellar:` URI scheme) used to delegate the signing of transactions and
+// payments to a wallet. See
+// https://github.com/stellar/stellar-protocol/blob/master/ecosystem/sep-0007.md
+package sep7
+
+import (
+	"bytes"
+	"encoding/base64"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/pownieh/stellar_go/clients/stellartoml"
+	"github.com/pownieh/stellar_go/keypair"
+	"github.com/pownieh/stellar_go/support/errors"
+)
+
+const (
+	// Scheme is the URI scheme used by SEP-7 requests.
+	Scheme = "web+stellar"
+
+	// OperationTx is the operation of URIs requesting the signing of a
+	// transaction.
+	OperationTx = "tx"
+
+	// OperationPay is the operation of URIs requesting a payment.
+	OperationPay = "pay"
+
+	// MsgMaxLength is the maximum number of characters allowed in the msg
+	// parameter.
+	MsgMaxLength = 300
+
+	// callbackPrefix is the prefix that the callback parameter value must
+	// have, reserving room for other callback types in the future.
+	callbackPrefix = "url:"
+
+	// signaturePrefix is the separator preceding the signature parameter,
+	// which must always be the last parameter of a URI.
+	signaturePrefix = "&signature="
+
+	// signaturePayloadPrefix is prepended to the URI when building the
+	// payload that is signed by the origin domain.
+	signaturePayloadPrefix = "stellar.sep.7 - URI Scheme"
+)
+
+var (
+	// ErrInvalidScheme is returned when parsing a URI that does not use the
+	// web+stellar scheme.
+	ErrInvalidScheme = errors.New("uri does not use the web+stellar scheme")
+
+	// ErrUnsupportedOperation is returned when parsing a URI whose operation
+	// is neither tx nor pay.
+	ErrUnsupportedOperation = errors.New("unsupported uri operation")
+
+	// ErrNotSigned is returned when verifying a URI that has no signature.
+	ErrNotSigned = errors.New("uri is not signed")
+
+	// ErrMissingOriginDomain is returned when verifying a URI that has no
+	// origin_domain parameter.
+	ErrMissingOriginDomain = errors.New("uri has no origin_domain")
+
+	// ErrInvalidSignature is returned when the signature of a URI does not
+	// match the signing key of its origin domain.
+	ErrInvalidSignature = errors.New("uri signature is invalid")
+)
+
+// URI is a SEP-7 request, either a *TxURI or a *PayURI.
+type URI interface {
+	// Operation returns the URI operation, OperationTx or OperationPay.
+	Operation() string
+	// String returns the encoded URI, including the signature if present.
+	String() string
+	// Validate checks that the URI parameters conform to SEP-7.
+	Validate() error
+
+	commonParams() *Params
+	query() query
+}
+
+// Params holds the parameters shared by the tx and pay operations.
+type Params struct {
+	// Callback is the URL the signed transaction should be POSTed to instead
+	// of being submitted to the network. It is encoded with the "url:"
+	// prefix required by SEP-7.
+	Callback string
+	// Msg is an optional message, up to MsgMaxLength characters, shown to
+	// the user by the wallet.
+	Msg string
+	// NetworkPassphrase identifies the network the request is intended for.
+	// An empty value means the public network.
+	NetworkPassphrase string
+	// OriginDomain is the fully qualified domain name of the service that
+	// created the URI. Its stellar.toml URI_REQUEST_SIGNING_KEY signs the
+	// URI.
+	OriginDomain string
+	// Signature is the base64 encoded signature of the URI made with the
+	// origin domain's signing key.
+	Signature string
+}
+
+func (p *Params) commonParams() *Params {
+	return p
+}
+
+func (p *Params) validate() error {
+	if utf8.RuneCountInString(p.Msg) > MsgMaxLength {
+		return errors.Errorf("msg can't be longer than %d characters", MsgMaxLength)
+	}
+	if p.Callback != "" {
+		u, err := url.Parse(p.Callback)
+		if err != nil {
+			return errors.Wrap(err, "invalid callback")
+		}
+		if !u.IsAbs() {
+			return errors.New("callback must be an absolute url")
+		}
+	}
+	if p.OriginDomain != "" && !govalidator.IsDNSName(p.OriginDomain) {
+		return errors.New("origin_domain must be a fully qualified domain name")
+	}
+	if p.Signature != "" {
+		if _, err := base64.StdEncoding.DecodeString(p.Signature); err != nil {
+			return errors.Wrap(err, "signature is not valid base64")
+		}
+	}
+	return nil
+}
+
+func (p *Params) addTo(q *query) {
+	if p.Callback != "" {
+		q.add("callback", callbackPrefix+p.Callback)
+	}
+	q.add("msg", p.Msg)
+	q.add("network_passphrase", p.NetworkPassphrase)
+	q.add("origin_domain", p.OriginDomain)
+}
+
+// set assigns a shared parameter, ignoring unknown keys as required by SEP-7.
+func (p *Params) set(key, value string) error {
+	switch key {
+	case "callback":
+		if !strings.HasPrefix(value, callbackPrefix) {
+			return errors.Errorf("callback must start with %q", callbackPrefix)
+		}
+		p.Callback = strings.TrimPrefix(value, callbackPrefix)
+	case "msg":
+		p.Msg = value
+	case "network_passphrase":
+		p.NetworkPassphrase = value
+	case "origin_domain":
+		p.OriginDomain = value
+	case "signature":
+		p.Signature = value
+	}
+	return nil
+}
+
+// Parse decodes a SEP-7 URI, returning a *TxURI or a *PayURI depending on the
+// operation. Unknown parameters are ignored as required by SEP-7.
+func Parse(uri string) (URI, error) {
+	rest, ok := cutPrefixFold(uri, Scheme+":")
+	if !ok {
+		return nil, ErrInvalidScheme
+	}
+	operation, rawQuery, _ := strings.Cut(rest, "?")
+
+	var u URI
+	var set func(key, value string) error
+	switch operation {
+	case OperationTx:
+		tx := &TxURI{}
+		u, set = tx, tx.set
+	case OperationPay:
+		pay := &PayURI{}
+		u, set = pay, pay.set
+	default:
+		return nil, errors.Wrap(ErrUnsupportedOperation, operation)
+	}
+
+	pairs := strings.Split(rawQuery, "&")
+	for i, pair := range pairs {
+		if pair == "" {
+			continue
+		}
+		rawKey, rawValue, _ := strings.Cut(pair, "=")
+		key, err := url.QueryUnescape(rawKey)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid parameter %q", rawKey)
+		}
+		value, err := url.QueryUnescape(rawValue)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid value for parameter %q", key)
+		}
+		if key == "signature" && i != len(pairs)-1 {
+			return nil, errors.New("signature must be the last parameter")
+		}
+		if err := set(key, value); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := u.Validate(); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// Sign signs u with the origin domain's signing key, replacing any existing
+// signature. The key must be the URI_REQUEST_SIGNING_KEY published in the
+// stellar.toml of u's origin domain.
+func Sign(u URI, kp *keypair.Full) error {
+	p := u.commonParams()
+	if p.OriginDomain == "" {
+		return ErrMissingOriginDomain
+	}
+	p.Signature = ""
+	if err := u.Validate(); err != nil {
+		return err
+	}
+
+	signature, err := kp.Sign(signaturePayload(u.String()))
+	if err != nil {
+		return errors.Wrap(err, "signing uri")
+	}
+	p.Signature = base64.StdEncoding.EncodeToString(signature)
+	return nil
+}
+
+// VerifySignature checks that the signature of the encoded uri was made by
+// signingKey.
+func VerifySignature(uri, signingKey string) error {
+	i := strings.LastIndex(uri, signaturePrefix)
+	if i < 0 {
+		return ErrNotSigned
+	}
+	unsigned, rawSignature := uri[:i], uri[i+len(signaturePrefix):]
+
+	encoded, err := url.QueryUnescape(rawSignature)
+	if err != nil {
+		return errors.Wrap(err, "invalid signature encoding")
+	}
+	signature, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return errors.Wrap(err, "signature is not valid base64")
+	}
+
+	kp, err := keypair.ParseAddress(signingKey)
+	if err != nil {
+		return errors.Wrap(err, "invalid signing key")
+	}
+	if err := kp.Verify(signaturePayload(unsigned), signature); err != nil {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// Verify parses uri and checks its signature against the
+// URI_REQUEST_SIGNING_KEY published in the stellar.toml of its origin domain,
+// fetched using client. It returns the parsed URI when the signature is valid.
+func Verify(uri string, client stellartoml.ClientInterface) (URI, error) {
+	u, err := Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	p := u.commonParams()
+	if p.OriginDomain == "" {
+		return nil, ErrMissingOriginDomain
+	}
+	if p.Signature == "" {
+		return nil, ErrNotSigned
+	}
+
+	toml, err := client.GetStellarToml(p.OriginDomain)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fetching stellar.toml of %s", p.OriginDomain)
+	}
+	if toml.UriRequestSigningKey == "" {
+		return nil, errors.Errorf("stellar.toml of %s has no URI_REQUEST_SIGNING_KEY", p.OriginDomain)
+	}
+
+	if err := VerifySignature(uri, toml.UriRequestSigningKey); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// encode returns the string form of a URI, appending the signature last.
+func encode(u URI) string {
+	var b strings.Builder
+	b.WriteString(Scheme)
+	b.WriteString(":")
+	b.WriteString(u.Operation())
+	b.WriteString("?")
+	b.WriteString(u.query().encode())
+	if signature := u.commonParams().Signature; signature != "" {
+		b.WriteString(signaturePrefix)
+		b.WriteString(escape(signature))
+	}
+	return b.String()
+}
+
+// signaturePayload returns the bytes signed by the origin domain: 35 zero
+// bytes, a byte with value 4, the SEP-7 prefix and the unsigned URI.
+func signaturePayload(unsigned string) []byte {
+	var payload bytes.Buffer
+	payload.Write(make([]byte, 35))
+	payload.WriteByte(4)
+	payload.WriteString(signaturePayloadPrefix)
+	payload.WriteString(unsigned)
+	return payload.Bytes()
+}
+
+// escape percent-encodes s the same way as encodeURIComponent does in
+// browsers, which is what most wallets use to decode SEP-7 URIs.
+func escape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+func cutPrefixFold(s, prefix string) (string, bool) {
+	if len(s) < len(prefix) || !strings.EqualFold(s[:len(prefix)], prefix) {
+		return s, false
+	}
+	return s[len(prefix):], true
+}
+
+// query is an ordered list of URI parameters. Order matters because the
+// signature covers the URI exactly as encoded.
+type query []queryParam
+
+type queryParam struct {
+	key   string
+	value string
+}
+
+// add appends a parameter, skipping empty values.
+func (q *query) add(key, value string) {
+	if value == "" {
+		return
+	}
+	*q = append(*q, queryParam{key: key, value: value})
+}
+
+func (q query) encode() string {
+	parts := make([]string, 0, len(q))
+	for _, p := range q {
+		parts = append(parts, p.key+"="+escape(p.value))
+	}
+	return strings.Join(parts, "&")
+}
@@ -0,0 +1,138 @@
+package sep7
+
+import (
+	"testing"
+
+	"github.com/pownieh/stellar_go/clients/stellartoml"
+	"github.com/pownieh/stellar_go/keypair"
+	"github.com/pownieh/stellar_go/network"
+	"github.com/pownieh/stellar_go/support/errors"
+	"github.com/pownieh/stellar_go/txnbuild"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const destination = "GCALNQQBXAPZ2WIRSDDBMSTAKCUH5SG6U76YBFLQLIXJTF7FE5AX7AOO"
+
+func buildTx(t *testing.T) *txnbuild.Transaction {
+	source := txnbuild.NewSimpleAccount(destination, 1)
+	tx, err := txnbuild.NewTransaction(txnbuild.TransactionParams{
+		SourceAccount:        &source,
+		IncrementSequenceNum: true,
+		Operations: []txnbuild.Operation{&txnbuild.Payment{
+			Destination: destination,
+			Amount:      "10",
+			Asset:       txnbuild.NativeAsset{},
+		}},
+		BaseFee:       txnbuild.MinBaseFee,
+		Preconditions: txnbuild.Preconditions{TimeBounds: txnbuild.NewInfiniteTimeout()},
+	})
+	require.NoError(t, err)
+	return tx
+}
+
+func TestTxURIRoundTrip(t *testing.T) {
+	tx := buildTx(t)
+	u, err := NewTxURI(tx)
+	require.NoError(t, err)
+	u.Callback = "https://example.com/callback?x=1"
+	u.Msg = "order #42 & more"
+	u.NetworkPassphrase = network.TestNetworkPassphrase
+	u.Replace = []Replacement{
+		{Path: "sourceAccount", Reference: "X", Hint: "account paying fees"},
+		{Path: "operations[0].sourceAccount", Reference: "X", Hint: "account paying fees"},
+	}
+
+	encoded := u.String()
+	assert.Contains(t, encoded, "web+stellar:tx?xdr=")
+	assert.Contains(t, encoded, "&callback=url%3Ahttps%3A%2F%2Fexample.com%2Fcallback%3Fx%3D1")
+	assert.Contains(t, encoded, "&msg=order%20%2342%20%26%20more")
+
+	parsed, err := Parse(encoded)
+	require.NoError(t, err)
+	assert.Equal(t, u, parsed)
+
+	gtx, err := parsed.(*TxURI).Transaction()
+	require.NoError(t, err)
+	parsedTx, ok := gtx.Transaction()
+	require.True(t, ok)
+	assert.Equal(t, tx.SequenceNumber(), parsedTx.SequenceNumber())
+}
+
+func TestParseErrors(t *testing.T) {
+	_, err := Parse("https://example.com")
+	assert.Equal(t, ErrInvalidScheme, err)
+
+	_, err = Parse("web+stellar:sign?xdr=AAAA")
+	assert.Equal(t, ErrUnsupportedOperation, errors.Cause(err))
+
+	_, err = Parse("web+stellar:tx?xdr=notxdr")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid xdr")
+
+	_, err = Parse("web+stellar:pay?destination=" + destination + "&callback=https://example.com")
+	assert.EqualError(t, err, `callback must start with "url:"`)
+
+	_, err = Parse("web+stellar:pay?destination=" + destination + "&signature=AAAA&msg=hi")
+	assert.EqualError(t, err, "signature must be the last parameter")
+}
+
+func TestParseIgnoresUnknownParameters(t *testing.T) {
+	u, err := Parse("WEB+STELLAR:pay?destination=" + destination + "&foo=bar")
+	require.NoError(t, err)
+	assert.Equal(t, &PayURI{Destination: destination}, u)
+}
+
+func TestValidateMsgLength(t *testing.T) {
+	u := &PayURI{Destination: destination}
+	u.Msg = string(make([]rune, MsgMaxLength))
+	assert.NoError(t, u.Validate())
+	u.Msg += "a"
+	assert.EqualError(t, u.Validate(), "msg can't be longer than 300 characters")
+}
+
+func TestSignAndVerify(t *testing.T) {
+	signer := keypair.MustRandom()
+	u := &PayURI{Destination: destination, Amount: "12.5"}
+
+	assert.Equal(t, ErrMissingOriginDomain, Sign(u, signer))
+
+	u.OriginDomain = "example.com"
+	require.NoError(t, Sign(u, signer))
+	require.NotEmpty(t, u.Signature)
+	encoded := u.String()
+	assert.Contains(t, encoded, "&origin_domain=example.com&signature=")
+
+	assert.NoError(t, VerifySignature(encoded, signer.Address()))
+	assert.Equal(t, ErrInvalidSignature, VerifySignature(encoded, keypair.MustRandom().Address()))
+
+	tampered := &PayURI{Destination: destination, Amount: "1000", Params: u.Params}
+	assert.Equal(t, ErrInvalidSignature, VerifySignature(tampered.String(), signer.Address()))
+
+	client := &stellartoml.MockClient{}
+	client.On("GetStellarToml", "example.com").
+		Return(&stellartoml.Response{UriRequestSigningKey: signer.Address()}, nil).Once()
+	verified, err := Verify(encoded, client)
+	require.NoError(t, err)
+	assert.Equal(t, u, verified)
+
+	client.On("GetStellarToml", "example.com").
+		Return(&stellartoml.Response{}, nil).Once()
+	_, err = Verify(encoded, client)
+	assert.EqualError(t, err, "stellar.toml of example.com has no URI_REQUEST_SIGNING_KEY")
+
+	unsigned := &PayURI{Destination: destination, Params: Params{OriginDomain: "example.com"}}
+	_, err = Verify(unsigned.String(), client)
+	assert.Equal(t, ErrNotSigned, err)
+	client.AssertExpectations(t)
+}
+
+func TestSignaturePayload(t *testing.T) {
+	payload := signaturePayload("web+stellar:pay?destination=" + destination)
+	assert.Equal(t, make([]byte, 35), payload[:35])
+	assert.Equal(t, byte(4), payload[35])
+	assert.Equal(t,
+		"stellar.sep.7 - URI Schemeweb+stellar:pay?destination="+destination,
+		string(payload[36:]),
+	)
+}
@@ -3,18 +3,23 @@ package cmd
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"go/types"
+	"io"
 	"log"
+	"math"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/pownieh/stellar_go/services/horizon/internal/db2/history"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
 	horizon "github.com/pownieh/stellar_go/services/horizon/internal"
+	"github.com/pownieh/stellar_go/services/horizon/internal/coldstorage"
 	"github.com/pownieh/stellar_go/services/horizon/internal/db2/schema"
 	"github.com/pownieh/stellar_go/services/horizon/internal/ingest"
 	support "github.com/pownieh/stellar_go/support/config"
@@ -75,6 +80,11 @@ var dbInitCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
+		numPluginMigrationsRun, err := ingest.MigrateProcessorPlugins(db, schema.MigrateUp, 0)
+		if err != nil {
+			return err
+		}
+		numMigrationsRun += numPluginMigrationsRun
 
 		if numMigrationsRun == 0 {
 			log.Println("No migrations applied.")
@@ -100,6 +110,15 @@ func migrate(dir schema.MigrateDir, count int) error {
 	if err != nil {
 		return err
 	}
+	// The migrations of the processor plugins are only applied upwards, their
+	// tables are independent of the Horizon schema version.
+	if dir == schema.MigrateUp {
+		numPluginMigrationsRun, err := ingest.MigrateProcessorPlugins(dbConn.DB.DB, dir, 0)
+		if err != nil {
+			return err
+		}
+		numMigrationsRun += numPluginMigrationsRun
+	}
 
 	if numMigrationsRun == 0 {
 		log.Println("No migrations applied.")
@@ -246,6 +265,9 @@ var (
 	parallelJobSize     uint32
 	retries             uint
 	retryBackoffSeconds uint
+	distributed         bool
+	leaseSeconds        uint
+	maxAttempts         uint
 )
 
 func ingestRangeCmdOpts() support.ConfigOptions {
@@ -291,6 +313,31 @@ func ingestRangeCmdOpts() support.ConfigOptions {
 			FlagDefault: uint(5),
 			Usage:       "[optional] backoff seconds between reingest retries",
 		},
+		{
+			Name:        "distributed",
+			ConfigKey:   &distributed,
+			OptType:     types.Bool,
+			Required:    false,
+			FlagDefault: false,
+			Usage: "[optional] if this flag is set, horizon will enqueue the ranges as jobs in the database, which are " +
+				"processed by this command and by any `db reingest worker` command sharing the database (incompatible with --force)",
+		},
+		{
+			Name:        "lease-seconds",
+			ConfigKey:   &leaseSeconds,
+			OptType:     types.Uint,
+			Required:    false,
+			FlagDefault: uint(300),
+			Usage:       "[optional] distributed reingest jobs claimed by a worker which stops renewing its lease for the supplied number of seconds are claimed by other workers",
+		},
+		{
+			Name:        "max-attempts",
+			ConfigKey:   &maxAttempts,
+			OptType:     types.Uint,
+			Required:    false,
+			FlagDefault: uint(3),
+			Usage:       "[optional] distributed reingest jobs are marked as failed after the supplied number of attempts",
+		},
 	}
 }
 
@@ -398,6 +445,9 @@ func runDBReingestRange(ledgerRanges []history.LedgerRange, reingestForce bool,
 	if reingestForce && parallelWorkers > 1 {
 		return errors.New("--force is incompatible with --parallel-workers > 1")
 	}
+	if reingestForce && distributed {
+		return errors.New("--force is incompatible with --distributed")
+	}
 
 	maxLedgersPerFlush := ingest.MaxLedgersPerFlush
 	if parallelJobSize < maxLedgersPerFlush {
@@ -427,6 +477,30 @@ func runDBReingestRange(ledgerRanges []history.LedgerRange, reingestForce bool,
 		return fmt.Errorf("cannot open Horizon DB: %v", err)
 	}
 
+	if distributed {
+		system, systemErr := ingest.NewParallelSystems(ingestConfig, parallelWorkers)
+		if systemErr != nil {
+			return systemErr
+		}
+
+		if len(ledgerRanges) > 0 {
+			added, enqueueErr := system.EnqueueReingestJobs(ledgerRanges, parallelJobSize)
+			if enqueueErr != nil {
+				return enqueueErr
+			}
+			hlog.Infof("Enqueued %d reingest jobs", added)
+		}
+
+		if err = system.ReingestJobs(ingest.ReingestJobsOptions{
+			LeaseDuration: time.Duration(leaseSeconds) * time.Second,
+			MaxAttempts:   int(maxAttempts),
+		}); err != nil {
+			return err
+		}
+		hlog.Info("All reingest jobs run successfully!")
+		return nil
+	}
+
 	if parallelWorkers > 1 {
 		system, systemErr := ingest.NewParallelSystems(ingestConfig, parallelWorkers)
 		if systemErr != nil {
@@ -462,6 +536,126 @@ the reingest command completes.`)
 	return nil
 }
 
+var dbReingestWorkerCmdOpts = ingestRangeCmdOpts()
+var dbReingestWorkerCmd = &cobra.Command{
+	Use:   "worker",
+	Short: "reingests the ranges enqueued by distributed reingest commands",
+	Long: "claims and reingests the jobs enqueued in the database by `db reingest range --distributed` and " +
+		"`db fill-gaps --distributed`, until all of them are done or failed. Any number of workers, running on " +
+		"different machines, can share the jobs.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := dbReingestWorkerCmdOpts.RequireE(); err != nil {
+			return err
+		}
+		if err := dbReingestWorkerCmdOpts.SetValues(); err != nil {
+			return err
+		}
+
+		if len(args) != 0 {
+			return ErrUsage{cmd}
+		}
+
+		err := horizon.ApplyFlags(globalConfig, globalFlags, horizon.ApplyOptions{RequireCaptiveCoreFullConfig: false, AlwaysIngest: true})
+		if err != nil {
+			return err
+		}
+		distributed = true
+		return runDBReingestRange(nil, reingestForce, parallelWorkers, *globalConfig)
+	},
+}
+
+var dbReingestStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "shows the status of distributed reingest jobs",
+	Long:  "shows the ranges of the distributed reingest jobs which are done, pending, running and failed",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := requireAndSetFlags(horizon.DatabaseURLFlagName); err != nil {
+			return err
+		}
+
+		if len(args) != 0 {
+			return ErrUsage{cmd}
+		}
+
+		horizonSession, err := db.Open("postgres", globalConfig.DatabaseURL)
+		if err != nil {
+			return err
+		}
+		defer horizonSession.Close()
+		q := &history.Q{horizonSession}
+		jobs, err := q.GetReingestJobs(context.Background())
+		if err != nil {
+			return err
+		}
+		printReingestJobsStatus(os.Stdout, jobs)
+		return nil
+	},
+}
+
+// printReingestJobsStatus prints a summary of the jobs per status followed by
+// their ranges, merging the contiguous ranges of done jobs.
+func printReingestJobsStatus(w io.Writer, jobs []history.ReingestJob) {
+	if len(jobs) == 0 {
+		fmt.Fprintln(w, "No reingest jobs found")
+		return
+	}
+
+	statuses := []history.ReingestJobStatus{
+		history.ReingestJobDone,
+		history.ReingestJobRunning,
+		history.ReingestJobPending,
+		history.ReingestJobFailed,
+	}
+	jobCounts := map[history.ReingestJobStatus]int{}
+	ledgerCounts := map[history.ReingestJobStatus]uint64{}
+	var totalLedgers uint64
+	for _, job := range jobs {
+		ledgers := uint64(job.ToLedger-job.FromLedger) + 1
+		jobCounts[job.Status]++
+		ledgerCounts[job.Status] += ledgers
+		totalLedgers += ledgers
+	}
+	for _, status := range statuses {
+		fmt.Fprintf(w, "%-8s %6d jobs %10d ledgers (%.1f%%)\n",
+			status, jobCounts[status], ledgerCounts[status], 100*float64(ledgerCounts[status])/float64(totalLedgers))
+	}
+
+	fmt.Fprintln(w)
+	var done *history.LedgerRange
+	for _, job := range jobs {
+		if job.Status == history.ReingestJobDone {
+			if done != nil && done.EndSequence+1 == job.FromLedger {
+				done.EndSequence = job.ToLedger
+				continue
+			}
+			if done != nil {
+				fmt.Fprintf(w, "[%d, %d] %s\n", done.StartSequence, done.EndSequence, history.ReingestJobDone)
+			}
+			jobRange := job.LedgerRange()
+			done = &jobRange
+			continue
+		}
+
+		if done != nil {
+			fmt.Fprintf(w, "[%d, %d] %s\n", done.StartSequence, done.EndSequence, history.ReingestJobDone)
+			done = nil
+		}
+		switch job.Status {
+		case history.ReingestJobRunning:
+			fmt.Fprintf(w, "[%d, %d] %s by %s (attempt %d, lease expires at %s)\n",
+				job.FromLedger, job.ToLedger, job.Status, job.LeaseOwner.String, job.Attempts, job.LeaseExpiresAt.Time.Format(time.RFC3339))
+		case history.ReingestJobFailed:
+			fmt.Fprintf(w, "[%d, %d] %s after %d attempts: %s\n",
+				job.FromLedger, job.ToLedger, job.Status, job.Attempts, job.LastError.String)
+		default:
+			fmt.Fprintf(w, "[%d, %d] %s\n", job.FromLedger, job.ToLedger, job.Status)
+		}
+	}
+	if done != nil {
+		fmt.Fprintf(w, "[%d, %d] %s\n", done.StartSequence, done.EndSequence, history.ReingestJobDone)
+	}
+}
+
 var dbDetectGapsCmd = &cobra.Command{
 	Use:   "detect-gaps",
 	Short: "detects ingestion gaps in Horizon's database",
@@ -491,6 +685,201 @@ var dbDetectGapsCmd = &cobra.Command{
 	},
 }
 
+var partitionSize uint32
+
+var dbPartitionCmdOpts = support.ConfigOptions{
+	{
+		Name:        "partition-size",
+		ConfigKey:   &partitionSize,
+		OptType:     types.Uint32,
+		Required:    false,
+		FlagDefault: uint32(100000),
+		Usage:       "[optional] number of ledgers of each partition of the history tables",
+	},
+}
+
+var dbPartitionCmd = &cobra.Command{
+	Use:   "partition",
+	Short: "partitions the history tables by ledger",
+	Long: "converts the largest history tables into tables partitioned by ledger so that the reaper and " +
+		"reingestion can drop and truncate whole partitions instead of deleting rows. The conversion " +
+		"rewrites the tables and blocks ingestion until it completes, it cannot be undone.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := requireAndSetFlags(horizon.DatabaseURLFlagName); err != nil {
+			return err
+		}
+		if err := dbPartitionCmdOpts.RequireE(); err != nil {
+			return err
+		}
+		if err := dbPartitionCmdOpts.SetValues(); err != nil {
+			return err
+		}
+
+		if len(args) != 0 {
+			return ErrUsage{cmd}
+		}
+		if partitionSize < history.MinHistoryPartitionSize {
+			return fmt.Errorf("--partition-size must be at least %d", history.MinHistoryPartitionSize)
+		}
+
+		horizonSession, err := db.Open("postgres", globalConfig.DatabaseURL)
+		if err != nil {
+			return err
+		}
+		defer horizonSession.Close()
+		return runDBPartition(context.Background(), &history.Q{horizonSession}, partitionSize)
+	},
+}
+
+func runDBPartition(ctx context.Context, q *history.Q, partitionSize uint32) error {
+	if err := q.Begin(ctx); err != nil {
+		return err
+	}
+	defer q.Rollback()
+
+	// Blocks ingestion until the tables are partitioned
+	latest, err := q.GetLastLedgerIngest(ctx)
+	if err != nil {
+		return err
+	}
+	// Creates partitions ahead of the latest ledger, the reaper creates
+	// the following ones once Horizon is ingesting again.
+	if err = q.PartitionHistoryTables(ctx, partitionSize, latest+2*partitionSize); err != nil {
+		return err
+	}
+	if err = q.Commit(); err != nil {
+		return err
+	}
+
+	partitions, err := q.GetHistoryPartitions(ctx)
+	if err != nil {
+		return err
+	}
+	hlog.Infof("Partitioned history tables into %d partitions of %d ledgers", len(partitions), partitionSize)
+	return nil
+}
+
+var dbArchiveCmd = &cobra.Command{
+	Use:   "archive [command]",
+	Short: "commands to manage the history cold storage archive",
+	Long: "exports history to the Parquet files of the archive configured by --" + horizon.HistoryColdStorageURLFlagName +
+		" and reads them back. The reaper exports the history to the archive before deleting it.",
+}
+
+var dbArchiveExportCmd = &cobra.Command{
+	Use:   "export [Start sequence number] [End sequence number]",
+	Short: "exports the history of a ledger range to the archive",
+	Long:  "exports the rows of all the history tables for the ledgers of the range, inclusive, to the archive",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := requireAndSetFlags(horizon.DatabaseURLFlagName, horizon.HistoryColdStorageURLFlagName); err != nil {
+			return err
+		}
+
+		if len(args) != 2 {
+			return ErrUsage{cmd}
+		}
+		argsUInt32 := make([]uint32, 2)
+		for i, arg := range args {
+			seq, err := strconv.ParseUint(arg, 10, 32)
+			if err != nil {
+				cmd.Usage()
+				return fmt.Errorf(`invalid sequence number "%s"`, arg)
+			}
+			argsUInt32[i] = uint32(seq)
+		}
+
+		archive, err := coldstorage.Connect(globalConfig.HistoryColdStorageURL)
+		if err != nil {
+			return err
+		}
+		horizonSession, err := db.Open("postgres", globalConfig.DatabaseURL)
+		if err != nil {
+			return err
+		}
+		defer horizonSession.Close()
+
+		manifest, err := archive.ExportRange(context.Background(), &history.Q{horizonSession}, argsUInt32[0], argsUInt32[1], history.HistoryTables())
+		if err != nil {
+			return err
+		}
+		for _, file := range manifest.Tables {
+			hlog.Infof("Exported %d rows of %s to %s", file.Rows, file.Table, file.Path)
+		}
+		return nil
+	},
+}
+
+var dbArchiveListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "lists the ledger ranges exported to the archive",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := requireAndSetFlags(horizon.HistoryColdStorageURLFlagName); err != nil {
+			return err
+		}
+
+		if len(args) != 0 {
+			return ErrUsage{cmd}
+		}
+		archive, err := coldstorage.Connect(globalConfig.HistoryColdStorageURL)
+		if err != nil {
+			return err
+		}
+		manifests, err := archive.ListManifests()
+		if err != nil {
+			return err
+		}
+		if len(manifests) == 0 {
+			fmt.Println("No ledger ranges exported")
+			return nil
+		}
+		for _, manifest := range manifests {
+			fmt.Printf("[%d, %d] exported at %s\n", manifest.StartLedger, manifest.EndLedger, manifest.ExportedAt.Format(time.RFC3339))
+			for _, file := range manifest.Tables {
+				fmt.Printf("  %-40s %10d rows %12d bytes\n", file.Table, file.Rows, file.Size)
+			}
+		}
+		return nil
+	},
+}
+
+var dbArchiveReadCmd = &cobra.Command{
+	Use:   "read [table] [Start sequence number] [End sequence number]",
+	Short: "prints the archived rows of a history table",
+	Long: "prints the archived rows of a history table as JSON objects, one per line. When a ledger range is " +
+		"given, only the rows of the ledgers of the range, inclusive, are printed.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := requireAndSetFlags(horizon.HistoryColdStorageURLFlagName); err != nil {
+			return err
+		}
+
+		if len(args) != 1 && len(args) != 3 {
+			return ErrUsage{cmd}
+		}
+		from, to := uint32(0), uint32(math.MaxUint32)
+		if len(args) == 3 {
+			argsUInt32 := make([]uint32, 2)
+			for i, arg := range args[1:] {
+				seq, err := strconv.ParseUint(arg, 10, 32)
+				if err != nil {
+					cmd.Usage()
+					return fmt.Errorf(`invalid sequence number "%s"`, arg)
+				}
+				argsUInt32[i] = uint32(seq)
+			}
+			from, to = argsUInt32[0], argsUInt32[1]
+		}
+
+		archive, err := coldstorage.Connect(globalConfig.HistoryColdStorageURL)
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		return archive.ReadTable(args[0], from, to, func(row coldstorage.Row) error {
+			return encoder.Encode(row)
+		})
+	},
+}
+
 func runDBDetectGaps(config horizon.Config) ([]history.LedgerRange, error) {
 	horizonSession, err := db.Open("postgres", config.DatabaseURL)
 	if err != nil {
@@ -518,9 +907,17 @@ func init() {
 	if err := dbFillGapsCmdOpts.Init(dbFillGapsCmd); err != nil {
 		log.Fatal(err.Error())
 	}
+	if err := dbReingestWorkerCmdOpts.Init(dbReingestWorkerCmd); err != nil {
+		log.Fatal(err.Error())
+	}
+	if err := dbPartitionCmdOpts.Init(dbPartitionCmd); err != nil {
+		log.Fatal(err.Error())
+	}
 
 	viper.BindPFlags(dbReingestRangeCmd.PersistentFlags())
 	viper.BindPFlags(dbFillGapsCmd.PersistentFlags())
+	viper.BindPFlags(dbReingestWorkerCmd.PersistentFlags())
+	viper.BindPFlags(dbPartitionCmd.PersistentFlags())
 
 	RootCmd.AddCommand(dbCmd)
 	dbCmd.AddCommand(
@@ -530,6 +927,8 @@ func init() {
 		dbReingestCmd,
 		dbDetectGapsCmd,
 		dbFillGapsCmd,
+		dbPartitionCmd,
+		dbArchiveCmd,
 	)
 	dbMigrateCmd.AddCommand(
 		dbMigrateDownCmd,
@@ -537,5 +936,14 @@ func init() {
 		dbMigrateStatusCmd,
 		dbMigrateUpCmd,
 	)
-	dbReingestCmd.AddCommand(dbReingestRangeCmd)
+	dbReingestCmd.AddCommand(
+		dbReingestRangeCmd,
+		dbReingestWorkerCmd,
+		dbReingestStatusCmd,
+	)
+	dbArchiveCmd.AddCommand(
+		dbArchiveExportCmd,
+		dbArchiveListCmd,
+		dbArchiveReadCmd,
+	)
 }
@@ -6,6 +6,7 @@ import (
 	"go/types"
 	"net/http"
 	_ "net/http/pprof"
+	"time"
 
 	"github.com/pownieh/stellar_go/historyarchive"
 	horizon "github.com/pownieh/stellar_go/services/horizon/internal"
@@ -27,6 +28,9 @@ var ingestBuildStateSequence uint32
 var ingestBuildStateSkipChecks bool
 var ingestVerifyFrom, ingestVerifyTo, ingestVerifyDebugServerPort uint32
 var ingestVerifyState bool
+var ingestTriggerStateRebuildZeroDowntime bool
+var ingestReplayLedger uint32
+var ingestReplayDiff bool
 
 var ingestBuildStateCmdOpts = []*support.ConfigOption{
 	{
@@ -47,6 +51,36 @@ var ingestBuildStateCmdOpts = []*support.ConfigOption{
 	},
 }
 
+var ingestTriggerStateRebuildCmdOpts = []*support.ConfigOption{
+	{
+		Name:        "zero-downtime",
+		ConfigKey:   &ingestTriggerStateRebuildZeroDowntime,
+		OptType:     types.Bool,
+		Required:    false,
+		FlagDefault: false,
+		Usage:       "[optional] set to rebuild the state in shadow state tables which replace the state tables once they have caught up, the state endpoints remain available during the rebuild",
+	},
+}
+
+var ingestReplayCmdOpts = []*support.ConfigOption{
+	{
+		Name:        "ledger",
+		ConfigKey:   &ingestReplayLedger,
+		OptType:     types.Uint32,
+		Required:    true,
+		FlagDefault: uint32(0),
+		Usage:       "sequence of the ledger to replay",
+	},
+	{
+		Name:        "diff",
+		ConfigKey:   &ingestReplayDiff,
+		OptType:     types.Bool,
+		Required:    false,
+		FlagDefault: false,
+		Usage:       "[optional] set to only print the rows which differ from the rows of the ledger held by the DB",
+	},
+}
+
 var ingestVerifyRangeCmdOpts = []*support.ConfigOption{
 	{
 		Name:        "from",
@@ -238,7 +272,12 @@ var ingestStressTestCmd = &cobra.Command{
 var ingestTriggerStateRebuildCmd = &cobra.Command{
 	Use:   "trigger-state-rebuild",
 	Short: "updates a database to trigger state rebuild, state will be rebuilt by a running Horizon instance, DO NOT RUN production DB, some endpoints will be unavailable until state is rebuilt",
+	Long:  "with --zero-downtime the state is rebuilt in shadow state tables while the state endpoints remain available, the progress of the rebuild is served by the /ingestion/state_rebuild admin endpoint.",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		for _, co := range ingestTriggerStateRebuildCmdOpts {
+			co.SetValue()
+		}
+
 		ctx := context.Background()
 		if err := horizon.ApplyFlags(globalConfig, globalFlags, horizon.ApplyOptions{RequireCaptiveCoreFullConfig: false, AlwaysIngest: true}); err != nil {
 			return err
@@ -250,6 +289,17 @@ var ingestTriggerStateRebuildCmd = &cobra.Command{
 		}
 
 		historyQ := &history.Q{SessionInterface: horizonSession}
+		if ingestTriggerStateRebuildZeroDowntime {
+			now := time.Now().UTC()
+			rebuild := history.StateRebuild{Status: history.StateRebuildRequested, RequestedAt: &now}
+			if err := historyQ.UpdateStateRebuild(ctx, rebuild); err != nil {
+				return fmt.Errorf("cannot trigger state rebuild: %v", err)
+			}
+
+			log.Info("Triggered zero-downtime state rebuild, it will start at the next checkpoint ledger")
+			return nil
+		}
+
 		if err := historyQ.UpdateIngestVersion(ctx, 0); err != nil {
 			return fmt.Errorf("cannot trigger state rebuild: %v", err)
 		}
@@ -379,6 +429,60 @@ var ingestBuildStateCmd = &cobra.Command{
 	},
 }
 
+var ingestReplayCmd = &cobra.Command{
+	Use:   "replay",
+	Short: "prints the DB writes each processor would make when ingesting a ledger, without making them",
+	Long: "runs the processors on a ledger and prints the rows each processor would insert and the other " +
+		"statements it would execute. The writes are recorded instead of being executed and the DB is only " +
+		"read, in a read-only transaction, so the DB can be a read-only replica. `--diff` prints the rows " +
+		"which differ from the history rows of the ledger held by the DB. The state processors are only run " +
+		"for the ledger following the last ingested ledger.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		for _, co := range ingestReplayCmdOpts {
+			if err := co.RequireE(); err != nil {
+				return err
+			}
+			co.SetValue()
+		}
+
+		if err := horizon.ApplyFlags(globalConfig, globalFlags, horizon.ApplyOptions{RequireCaptiveCoreFullConfig: false, AlwaysIngest: true}); err != nil {
+			return err
+		}
+
+		horizonSession, err := db.Open("postgres", globalConfig.DatabaseURL)
+		if err != nil {
+			return fmt.Errorf("cannot open Horizon DB: %v", err)
+		}
+
+		ingestConfig := ingest.Config{
+			NetworkPassphrase:        globalConfig.NetworkPassphrase,
+			HistorySession:           horizonSession,
+			HistoryArchiveURLs:       globalConfig.HistoryArchiveURLs,
+			CaptiveCoreBinaryPath:    globalConfig.CaptiveCoreBinaryPath,
+			CaptiveCoreConfigUseDB:   globalConfig.CaptiveCoreConfigUseDB,
+			RemoteCaptiveCoreURL:     globalConfig.RemoteCaptiveCoreURL,
+			CheckpointFrequency:      globalConfig.CheckpointFrequency,
+			CaptiveCoreToml:          globalConfig.CaptiveCoreToml,
+			CaptiveCoreStoragePath:   globalConfig.CaptiveCoreStoragePath,
+			RoundingSlippageFilter:   globalConfig.RoundingSlippageFilter,
+			EnableIngestionFiltering: globalConfig.EnableIngestionFiltering,
+		}
+
+		system, err := ingest.NewSystem(ingestConfig)
+		if err != nil {
+			return err
+		}
+		defer system.Shutdown()
+
+		replay, err := system.ReplayLedger(ingestReplayLedger)
+		if err != nil {
+			return err
+		}
+
+		return ingest.WriteLedgerReplay(cmd.OutOrStdout(), replay, ingestReplayDiff)
+	},
+}
+
 func init() {
 	for _, co := range ingestVerifyRangeCmdOpts {
 		err := co.Init(ingestVerifyRangeCmd)
@@ -401,6 +505,20 @@ func init() {
 		}
 	}
 
+	for _, co := range ingestTriggerStateRebuildCmdOpts {
+		err := co.Init(ingestTriggerStateRebuildCmd)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+	}
+
+	for _, co := range ingestReplayCmdOpts {
+		err := co.Init(ingestReplayCmd)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+	}
+
 	viper.BindPFlags(ingestVerifyRangeCmd.PersistentFlags())
 
 	RootCmd.AddCommand(ingestCmd)
@@ -410,5 +528,6 @@ func init() {
 		ingestTriggerStateRebuildCmd,
 		ingestInitGenesisStateCmd,
 		ingestBuildStateCmd,
+		ingestReplayCmd,
 	)
 }
@@ -46,8 +46,10 @@ func (qp EffectsQuery) Validate() error {
 	return nil
 }
 
+// GetEffectsHandler is the action handler for all end-points returning a list of effects.
 type GetEffectsHandler struct {
-	LedgerState *ledger.State
+	LedgerState      *ledger.State
+	OnlySponsorships bool
 }
 
 func (handler GetEffectsHandler) GetResourcePage(w HeaderWriter, r *http.Request) ([]hal.Pageable, error) {
@@ -72,7 +74,7 @@ func (handler GetEffectsHandler) GetResourcePage(w HeaderWriter, r *http.Request
 		return nil, err
 	}
 
-	records, err := loadEffectRecords(r.Context(), historyQ, qp, pq)
+	records, err := loadEffectRecords(r.Context(), historyQ, qp, pq, handler.OnlySponsorships)
 	if err != nil {
 		return nil, errors.Wrap(err, "loading transaction records")
 	}
@@ -94,10 +96,12 @@ func (handler GetEffectsHandler) GetResourcePage(w HeaderWriter, r *http.Request
 	return result, nil
 }
 
-func loadEffectRecords(ctx context.Context, hq *history.Q, qp EffectsQuery, pq db2.PageQuery) ([]history.Effect, error) {
+func loadEffectRecords(ctx context.Context, hq *history.Q, qp EffectsQuery, pq db2.PageQuery, onlySponsorships bool) ([]history.Effect, error) {
 	effects := hq.Effects()
 
 	switch {
+	case onlySponsorships:
+		effects.ForSponsorships(ctx, qp.AccountID)
 	case qp.AccountID != "":
 		effects.ForAccount(ctx, qp.AccountID)
 	case qp.LiquidityPoolID != "":
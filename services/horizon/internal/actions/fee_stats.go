@@ -5,7 +5,13 @@ import (
 	"strconv"
 
 	"github.com/pownieh/stellar_go/protocols/horizon"
+	horizonContext "github.com/pownieh/stellar_go/services/horizon/internal/context"
+	"github.com/pownieh/stellar_go/services/horizon/internal/ledger"
 	"github.com/pownieh/stellar_go/services/horizon/internal/operationfeestats"
+	"github.com/pownieh/stellar_go/services/horizon/internal/resourceadapter"
+	"github.com/pownieh/stellar_go/support/errors"
+	"github.com/pownieh/stellar_go/support/render/hal"
+	"github.com/pownieh/stellar_go/support/render/problem"
 )
 
 // FeeStatsHandler is the action handler for the /fee_stats endpoint
@@ -66,3 +72,128 @@ func (handler FeeStatsHandler) GetResource(w HeaderWriter, r *http.Request) (int
 
 	return feeStats, nil
 }
+
+// FeeStatsHistoryHandler is the action handler for the /fee_stats/history
+// endpoint
+type FeeStatsHistoryHandler struct {
+	LedgerState *ledger.State
+}
+
+// GetResourcePage returns a page of ledgers with their fee stats
+func (handler FeeStatsHistoryHandler) GetResourcePage(w HeaderWriter, r *http.Request) ([]hal.Pageable, error) {
+	pq, err := GetPageQuery(handler.LedgerState, r)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validateCursorWithinHistory(handler.LedgerState, pq)
+	if err != nil {
+		return nil, err
+	}
+
+	historyQ, err := horizonContext.HistoryQFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := historyQ.LedgerFeeStats(r.Context(), pq)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []hal.Pageable
+	for _, record := range records {
+		var res horizon.LedgerFeeStats
+		resourceadapter.PopulateLedgerFeeStats(r.Context(), &res, record)
+		result = append(result, res)
+	}
+
+	return result, nil
+}
+
+const (
+	defaultFeeEstimateProbability = 0.9
+	defaultFeeEstimateWindow      = 100
+	maxFeeEstimateWindow          = 1000
+)
+
+// FeeEstimateQuery query struct for the /fee_stats/estimate endpoint
+type FeeEstimateQuery struct {
+	Probability   float64 `schema:"probability" valid:"-"`
+	WithinLedgers uint32  `schema:"within_ledgers" valid:"-"`
+	Operations    uint32  `schema:"operations" valid:"-"`
+	Window        uint32  `schema:"window" valid:"-"`
+}
+
+// Validate runs validations on FeeEstimateQuery
+func (q FeeEstimateQuery) Validate() error {
+	if q.Probability < 0 || q.Probability > 1 {
+		return problem.MakeInvalidFieldProblem(
+			"probability",
+			errors.New("the probability must be greater than 0 and at most 1"),
+		)
+	}
+	if q.Window > maxFeeEstimateWindow {
+		return problem.MakeInvalidFieldProblem(
+			"window",
+			errors.Errorf("the window must be at most %d ledgers", maxFeeEstimateWindow),
+		)
+	}
+	return nil
+}
+
+// FeeEstimateHandler is the action handler for the /fee_stats/estimate
+// endpoint
+type FeeEstimateHandler struct {
+	LedgerState *ledger.State
+}
+
+// GetResource returns the fee to bid for a transaction to be included with a
+// given probability within a given number of ledgers, estimated from the fee
+// stats of the recent ledgers
+func (handler FeeEstimateHandler) GetResource(w HeaderWriter, r *http.Request) (interface{}, error) {
+	qp := FeeEstimateQuery{}
+	if err := getParams(&qp, r); err != nil {
+		return nil, err
+	}
+	if qp.Probability == 0 {
+		qp.Probability = defaultFeeEstimateProbability
+	}
+	if qp.WithinLedgers == 0 {
+		qp.WithinLedgers = 1
+	}
+	if qp.Operations == 0 {
+		qp.Operations = 1
+	}
+	if qp.Window == 0 {
+		qp.Window = defaultFeeEstimateWindow
+	}
+
+	historyQ, err := horizonContext.HistoryQFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := historyQ.LatestLedgerFeeStats(
+		r.Context(),
+		handler.LedgerState.CurrentStatus().HistoryLatest,
+		int32(qp.Window),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, problem.NotFound
+	}
+
+	var estimate horizon.FeeEstimate
+	resourceadapter.PopulateFeeEstimate(
+		r.Context(),
+		&estimate,
+		records,
+		qp.Probability,
+		qp.WithinLedgers,
+		int64(qp.Operations),
+	)
+	return estimate, nil
+}
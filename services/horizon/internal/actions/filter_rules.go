@@ -8,6 +8,7 @@ import (
 	hProtocol "github.com/pownieh/stellar_go/protocols/horizon"
 	horizonContext "github.com/pownieh/stellar_go/services/horizon/internal/context"
 	"github.com/pownieh/stellar_go/services/horizon/internal/db2/history"
+	"github.com/pownieh/stellar_go/services/horizon/internal/ingest/filters"
 	"github.com/pownieh/stellar_go/support/render/problem"
 )
 
@@ -114,6 +115,66 @@ func (handler FilterConfigHandler) UpdateAssetConfig(w http.ResponseWriter, r *h
 	}
 }
 
+func (handler FilterConfigHandler) GetRulesConfig(w http.ResponseWriter, r *http.Request) {
+	historyQ, err := horizonContext.HistoryQFromRequest(r)
+	if err != nil {
+		problem.Render(r.Context(), w, err)
+		return
+	}
+
+	config, err := historyQ.GetRulesFilterConfig(r.Context())
+
+	if err != nil {
+		problem.Render(r.Context(), w, err)
+		return
+	}
+
+	responsePayload := handler.rulesConfigResource(config)
+	enc := json.NewEncoder(w)
+	if err = enc.Encode(responsePayload); err != nil {
+		problem.Render(r.Context(), w, err)
+	}
+}
+
+func (handler FilterConfigHandler) UpdateRulesConfig(w http.ResponseWriter, r *http.Request) {
+	historyQ, err := horizonContext.HistoryQFromRequest(r)
+	if err != nil {
+		problem.Render(r.Context(), w, err)
+		return
+	}
+
+	filterRequest, err := handler.rulesFilterResource(r)
+	if err != nil {
+		problem.Render(r.Context(), w, err)
+		return
+	}
+
+	filterConfig := history.RulesFilterConfig{}
+	filterConfig.Enabled = *filterRequest.Enabled
+	filterConfig.Rules = history.FilterRules{
+		Allow: toHistoryFilterRules(filterRequest.Allow),
+		Deny:  toHistoryFilterRules(filterRequest.Deny),
+	}
+
+	if err = filters.ValidateRules(filterConfig.Rules); err != nil {
+		p := problem.NewProblemWithInvalidField(problem.BadRequest, "reason", fmt.Errorf("invalid rules filter config %v", err.Error()))
+		problem.Render(r.Context(), w, p)
+		return
+	}
+
+	config, err := historyQ.UpdateRulesFilterConfig(r.Context(), filterConfig)
+	if err != nil {
+		problem.Render(r.Context(), w, err)
+		return
+	}
+
+	responsePayload := handler.rulesConfigResource(config)
+	enc := json.NewEncoder(w)
+	if err = enc.Encode(responsePayload); err != nil {
+		problem.Render(r.Context(), w, err)
+	}
+}
+
 func (handler FilterConfigHandler) assetFilterResource(r *http.Request) (hProtocol.AssetFilterConfig, error) {
 	var filterRequest hProtocol.AssetFilterConfig
 	dec := json.NewDecoder(r.Body)
@@ -149,3 +210,72 @@ func (handler FilterConfigHandler) accountConfigResource(config history.AccountF
 		LastModified: config.LastModified,
 	}
 }
+
+func (handler FilterConfigHandler) rulesFilterResource(r *http.Request) (hProtocol.RulesFilterConfig, error) {
+	var filterRequest hProtocol.RulesFilterConfig
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(&filterRequest); err != nil {
+		p := problem.NewProblemWithInvalidField(problem.BadRequest, "reason", fmt.Errorf("invalid json for rules filter config %v", err.Error()))
+		return hProtocol.RulesFilterConfig{}, p
+	}
+	return filterRequest, nil
+}
+
+func (handler FilterConfigHandler) rulesConfigResource(config history.RulesFilterConfig) hProtocol.RulesFilterConfig {
+	allow := toProtocolFilterRules(config.Rules.Allow)
+	if allow == nil {
+		allow = []hProtocol.FilterRule{}
+	}
+	deny := toProtocolFilterRules(config.Rules.Deny)
+	if deny == nil {
+		deny = []hProtocol.FilterRule{}
+	}
+	return hProtocol.RulesFilterConfig{
+		Allow:        allow,
+		Deny:         deny,
+		Enabled:      &config.Enabled,
+		LastModified: config.LastModified,
+	}
+}
+
+func toHistoryFilterRules(rules []hProtocol.FilterRule) []history.FilterRule {
+	if rules == nil {
+		return nil
+	}
+	result := make([]history.FilterRule, len(rules))
+	for i, rule := range rules {
+		result[i] = history.FilterRule{
+			And:            toHistoryFilterRules(rule.And),
+			Or:             toHistoryFilterRules(rule.Or),
+			OperationTypes: rule.OperationTypes,
+			ContractIDs:    rule.ContractIDs,
+			SourceAccounts: rule.SourceAccounts,
+			MemoPattern:    rule.MemoPattern,
+		}
+		if rule.Not != nil {
+			result[i].Not = &toHistoryFilterRules([]hProtocol.FilterRule{*rule.Not})[0]
+		}
+	}
+	return result
+}
+
+func toProtocolFilterRules(rules []history.FilterRule) []hProtocol.FilterRule {
+	if rules == nil {
+		return nil
+	}
+	result := make([]hProtocol.FilterRule, len(rules))
+	for i, rule := range rules {
+		result[i] = hProtocol.FilterRule{
+			And:            toProtocolFilterRules(rule.And),
+			Or:             toProtocolFilterRules(rule.Or),
+			OperationTypes: rule.OperationTypes,
+			ContractIDs:    rule.ContractIDs,
+			SourceAccounts: rule.SourceAccounts,
+			MemoPattern:    rule.MemoPattern,
+		}
+		if rule.Not != nil {
+			result[i].Not = &toProtocolFilterRules([]history.FilterRule{*rule.Not})[0]
+		}
+	}
+	return result
+}
@@ -244,3 +244,95 @@ func TestUpdateAccountFilterConfig(t *testing.T) {
 	tt.Assert.True(filterCfgResource.LastModified > 0)
 	tt.Assert.ElementsMatch(filterCfgResource.Whitelist, []string{"4", "5", "6"})
 }
+
+func TestUpdateRulesFilterConfig(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetHorizonDB(t, tt.HorizonDB)
+
+	q := &history.Q{SessionInterface: tt.HorizonSession()}
+
+	handler := &FilterConfigHandler{}
+	recorder := httptest.NewRecorder()
+	request := makeRequest(
+		t,
+		map[string]string{},
+		map[string]string{},
+		q,
+	)
+
+	request.Body = ioutil.NopCloser(strings.NewReader(`
+	    {
+			"allow": [{"operation_types": ["invoke_host_function"]}],
+			"deny": [{"or": [{"memo_pattern": "^spam"}, {"source_accounts": ["GD6WNNTW664WH7FXC5RUMUTF7P5QSURC2IT36VOQEEGFZ4UWUEQGECAL"]}]}],
+			"enabled": true
+		}`))
+
+	handler.UpdateRulesConfig(
+		recorder,
+		request,
+	)
+
+	resp := recorder.Result()
+	tt.Assert.Equal(http.StatusOK, resp.StatusCode)
+
+	raw, err := ioutil.ReadAll(resp.Body)
+	tt.Assert.NoError(err)
+
+	var filterCfgResource hProtocol.RulesFilterConfig
+	err = json.Unmarshal(raw, &filterCfgResource)
+	tt.Assert.NoError(err)
+
+	tt.Assert.Equal(*filterCfgResource.Enabled, true)
+	tt.Assert.True(filterCfgResource.LastModified > 0)
+	tt.Assert.Equal([]hProtocol.FilterRule{{OperationTypes: []string{"invoke_host_function"}}}, filterCfgResource.Allow)
+	tt.Assert.Equal([]hProtocol.FilterRule{{Or: []hProtocol.FilterRule{
+		{MemoPattern: "^spam"},
+		{SourceAccounts: []string{"GD6WNNTW664WH7FXC5RUMUTF7P5QSURC2IT36VOQEEGFZ4UWUEQGECAL"}},
+	}}}, filterCfgResource.Deny)
+
+	recorder = httptest.NewRecorder()
+	handler.GetRulesConfig(
+		recorder,
+		makeRequest(
+			t,
+			map[string]string{},
+			map[string]string{},
+			q,
+		),
+	)
+	tt.Assert.Equal(http.StatusOK, recorder.Result().StatusCode)
+}
+
+func TestInvalidUpdateRulesFilterConfig(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetHorizonDB(t, tt.HorizonDB)
+
+	q := &history.Q{SessionInterface: tt.HorizonSession()}
+
+	for _, body := range []string{
+		// missing required enabled
+		`{"allow": []}`,
+		`{"allow": [{"operation_types": ["unknown"]}], "enabled": true}`,
+		`{"deny": [{"not": {"memo_pattern": "("}}], "enabled": true}`,
+		`{"deny": [{"contract_ids": ["GD6WNNTW664WH7FXC5RUMUTF7P5QSURC2IT36VOQEEGFZ4UWUEQGECAL"]}], "enabled": true}`,
+	} {
+		handler := &FilterConfigHandler{}
+		recorder := httptest.NewRecorder()
+		request := makeRequest(
+			t,
+			map[string]string{},
+			map[string]string{},
+			q,
+		)
+		request.Body = ioutil.NopCloser(strings.NewReader(body))
+
+		handler.UpdateRulesConfig(
+			recorder,
+			request,
+		)
+
+		tt.Assert.Equal(http.StatusBadRequest, recorder.Result().StatusCode, body)
+	}
+}
@@ -0,0 +1,126 @@
+package actions
+
+import (
+	"net/http"
+	"strconv"
+	gTime "time"
+
+	"github.com/pownieh/stellar_go/protocols/horizon"
+	horizonContext "github.com/pownieh/stellar_go/services/horizon/internal/context"
+	"github.com/pownieh/stellar_go/services/horizon/internal/db2/history"
+	"github.com/pownieh/stellar_go/services/horizon/internal/ledger"
+	"github.com/pownieh/stellar_go/services/horizon/internal/resourceadapter"
+	"github.com/pownieh/stellar_go/support/errors"
+	"github.com/pownieh/stellar_go/support/render/hal"
+	"github.com/pownieh/stellar_go/support/render/problem"
+	"github.com/pownieh/stellar_go/support/time"
+)
+
+// LiquidityPoolStatsQuery query struct for the liquidity_pools/{id}/stats end-point
+type LiquidityPoolStatsQuery struct {
+	ID               string      `schema:"liquidity_pool_id" valid:"sha256"`
+	StartTimeFilter  time.Millis `schema:"start_time" valid:"-"`
+	EndTimeFilter    time.Millis `schema:"end_time" valid:"-"`
+	ResolutionFilter uint64      `schema:"resolution" valid:"-"`
+}
+
+// Validate runs validations on LiquidityPoolStatsQuery
+func (q LiquidityPoolStatsQuery) Validate() error {
+	resolution := gTime.Duration(q.ResolutionFilter) * gTime.Millisecond
+	if _, ok := history.AllowedLiquidityPoolStatsResolutions[resolution]; !ok {
+		return problem.MakeInvalidFieldProblem(
+			"resolution",
+			errors.New("illegal or missing resolution. "+
+				"allowed resolutions are: 1 hour (3600000), 1 day (86400000) and 1 week (604800000)"),
+		)
+	}
+	if !q.StartTimeFilter.IsNil() && !q.EndTimeFilter.IsNil() && q.StartTimeFilter > q.EndTimeFilter {
+		return problem.MakeInvalidFieldProblem(
+			"end_time",
+			errors.New("the end time must be greater than the start time"),
+		)
+	}
+	return nil
+}
+
+// GetLiquidityPoolStatsHandler is the action handler for the
+// liquidity_pools/{id}/stats end-point
+type GetLiquidityPoolStatsHandler struct {
+	LedgerState *ledger.State
+}
+
+// GetResource returns a page of liquidity pool stats
+func (handler GetLiquidityPoolStatsHandler) GetResource(w HeaderWriter, r *http.Request) (interface{}, error) {
+	ctx := r.Context()
+	pq, err := GetPageQuery(handler.LedgerState, r, DisableCursorValidation)
+	if err != nil {
+		return nil, err
+	}
+	qp := LiquidityPoolStatsQuery{}
+	if err = getParams(&qp, r); err != nil {
+		return nil, err
+	}
+
+	historyQ, err := horizonContext.HistoryQFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// the stats of the pools which were removed are still served
+	if _, err = historyQ.LiquidityPoolByID(ctx, qp.ID); err != nil {
+		return nil, err
+	}
+	var pool *history.LiquidityPool
+	livePool, err := historyQ.FindLiquidityPoolByID(ctx, qp.ID)
+	switch {
+	case err == nil:
+		pool = &livePool
+	case !historyQ.NoRows(err):
+		return nil, err
+	}
+
+	records, err := historyQ.GetLiquidityPoolStats(ctx, history.LiquidityPoolStatsQuery{
+		LiquidityPoolID: qp.ID,
+		Resolution:      int64(qp.ResolutionFilter),
+		StartTime:       qp.StartTimeFilter,
+		EndTime:         qp.EndTimeFilter,
+		PageQuery:       pq,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	page := hal.Page{
+		Cursor: pq.Cursor,
+		Order:  pq.Order,
+		Limit:  pq.Limit,
+	}
+	page.Init()
+	for _, record := range records {
+		var res horizon.LiquidityPoolStats
+		err = resourceadapter.PopulateLiquidityPoolStats(ctx, &res, pool, record, int64(qp.ResolutionFilter))
+		if err != nil {
+			return nil, err
+		}
+		page.Add(res)
+	}
+
+	newURL := FullURL(ctx)
+	page.Links.Self = hal.NewLink(newURL.String())
+	if len(records) == 0 {
+		page.Links.Next = page.Links.Self
+		return page, nil
+	}
+
+	// adjust the time range for the next page
+	q := newURL.Query()
+	timestamp := records[len(records)-1].Timestamp
+	if page.Order == "asc" {
+		q.Set("start_time", strconv.FormatInt(timestamp+int64(qp.ResolutionFilter), 10))
+	} else {
+		q.Set("end_time", strconv.FormatInt(timestamp, 10))
+	}
+	newURL.RawQuery = q.Encode()
+	page.Links.Next = hal.NewLink(newURL.String())
+	return page, nil
+}
@@ -0,0 +1,150 @@
+package actions
+
+import (
+	"fmt"
+	"math/big"
+	"net/http"
+	gTime "time"
+
+	protocol "github.com/pownieh/stellar_go/protocols/horizon"
+	horizonContext "github.com/pownieh/stellar_go/services/horizon/internal/context"
+	"github.com/pownieh/stellar_go/services/horizon/internal/db2/history"
+	"github.com/pownieh/stellar_go/services/horizon/internal/resourceadapter"
+	"github.com/pownieh/stellar_go/support/errors"
+	"github.com/pownieh/stellar_go/support/render/problem"
+	"github.com/pownieh/stellar_go/support/time"
+	"github.com/pownieh/stellar_go/xdr"
+)
+
+// OrderBookSnapshotQuery query struct for the /order_book/history end-point
+type OrderBookSnapshotQuery struct {
+	SellingAssetType   string      `schema:"selling_asset_type" valid:"assetType"`
+	SellingAssetIssuer string      `schema:"selling_asset_issuer" valid:"accountID,optional"`
+	SellingAssetCode   string      `schema:"selling_asset_code" valid:"-"`
+	BuyingAssetType    string      `schema:"buying_asset_type" valid:"assetType"`
+	BuyingAssetIssuer  string      `schema:"buying_asset_issuer" valid:"accountID,optional"`
+	BuyingAssetCode    string      `schema:"buying_asset_code" valid:"-"`
+	Ledger             uint32      `schema:"ledger" valid:"-"`
+	Timestamp          time.Millis `schema:"timestamp" valid:"-"`
+	Limit              uint        `schema:"limit" valid:"-"`
+}
+
+// Validate runs custom validations.
+func (q OrderBookSnapshotQuery) Validate() error {
+	err := validateAssetParams(q.SellingAssetType, q.SellingAssetCode, q.SellingAssetIssuer, "selling_")
+	if err != nil {
+		return err
+	}
+	err = validateAssetParams(q.BuyingAssetType, q.BuyingAssetCode, q.BuyingAssetIssuer, "buying_")
+	if err != nil {
+		return err
+	}
+	if q.Limit > 200 {
+		return problem.MakeInvalidFieldProblem(
+			"limit",
+			errors.New("limit must be at most 200"),
+		)
+	}
+	return nil
+}
+
+// Pair returns the trading pair of the order book.
+func (q OrderBookSnapshotQuery) Pair() history.OrderBookPair {
+	selling, err := xdr.BuildAsset(q.SellingAssetType, q.SellingAssetIssuer, q.SellingAssetCode)
+	if err != nil {
+		panic(err)
+	}
+	buying, err := xdr.BuildAsset(q.BuyingAssetType, q.BuyingAssetIssuer, q.BuyingAssetCode)
+	if err != nil {
+		panic(err)
+	}
+	return history.OrderBookPair{Selling: selling, Buying: buying}
+}
+
+// GetOrderBookSnapshotHandler is the action handler for the
+// /order_book/history endpoint
+type GetOrderBookSnapshotHandler struct{}
+
+// GetResource returns the latest order book snapshot taken at or before the
+// requested ledger and timestamp.
+func (handler GetOrderBookSnapshotHandler) GetResource(w HeaderWriter, r *http.Request) (interface{}, error) {
+	ctx := r.Context()
+	qp := OrderBookSnapshotQuery{}
+	if err := getParams(&qp, r); err != nil {
+		return nil, err
+	}
+	limit := int(qp.Limit)
+	if limit == 0 {
+		limit = 20
+	}
+
+	historyQ, err := horizonContext.HistoryQFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	pair := qp.Pair()
+	var closedAt gTime.Time
+	if !qp.Timestamp.IsNil() {
+		closedAt = qp.Timestamp.ToTime()
+	}
+	snapshot, err := historyQ.GetOrderBookSnapshot(ctx, pair, qp.Ledger, closedAt)
+	if historyQ.NoRows(err) {
+		return nil, problem.NotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	response := protocol.OrderBookSnapshot{
+		Ledger:   int32(snapshot.LedgerSequence),
+		ClosedAt: snapshot.ClosedAt,
+	}
+	if err = resourceadapter.PopulateAsset(ctx, &response.Selling, pair.Selling); err != nil {
+		return nil, err
+	}
+	if err = resourceadapter.PopulateAsset(ctx, &response.Buying, pair.Buying); err != nil {
+		return nil, err
+	}
+
+	if response.BidDepth, err = priceLevelsDepth(snapshot.Bids); err != nil {
+		return nil, err
+	}
+	if response.AskDepth, err = priceLevelsDepth(snapshot.Asks); err != nil {
+		return nil, err
+	}
+	if len(snapshot.Bids) > 0 {
+		response.BestBid = snapshot.Bids[0].Pricef
+	}
+	if len(snapshot.Asks) > 0 {
+		response.BestAsk = snapshot.Asks[0].Pricef
+	}
+	if len(snapshot.Bids) > 0 && len(snapshot.Asks) > 0 {
+		bestBid := big.NewRat(int64(snapshot.Bids[0].Pricen), int64(snapshot.Bids[0].Priced))
+		bestAsk := big.NewRat(int64(snapshot.Asks[0].Pricen), int64(snapshot.Asks[0].Priced))
+		response.Spread = new(big.Rat).Sub(bestAsk, bestBid).FloatString(7)
+	}
+
+	if len(snapshot.Bids) > limit {
+		snapshot.Bids = snapshot.Bids[:limit]
+	}
+	if len(snapshot.Asks) > limit {
+		snapshot.Asks = snapshot.Asks[:limit]
+	}
+	response.Bids = convertPriceLevels(snapshot.Bids)
+	response.Asks = convertPriceLevels(snapshot.Asks)
+	return response, nil
+}
+
+// priceLevelsDepth returns the total amount of the price levels. The sum
+// doesn't fit an int64 for some order books so it is computed with big.Rat.
+func priceLevelsDepth(levels history.PriceLevels) (string, error) {
+	depth := new(big.Rat)
+	for _, level := range levels {
+		amount, ok := new(big.Rat).SetString(level.Amount)
+		if !ok {
+			return "", fmt.Errorf("invalid price level amount %s", level.Amount)
+		}
+		depth.Add(depth, amount)
+	}
+	return depth.FloatString(7), nil
+}
@@ -0,0 +1,147 @@
+package actions
+
+import (
+	"net/http"
+
+	"github.com/pownieh/stellar_go/protocols/horizon"
+	horizonContext "github.com/pownieh/stellar_go/services/horizon/internal/context"
+	"github.com/pownieh/stellar_go/services/horizon/internal/paths"
+	horizonProblem "github.com/pownieh/stellar_go/services/horizon/internal/render/problem"
+	"github.com/pownieh/stellar_go/services/horizon/internal/resourceadapter"
+	"github.com/pownieh/stellar_go/services/horizon/internal/simplepath"
+	"github.com/pownieh/stellar_go/support/errors"
+	"github.com/pownieh/stellar_go/support/render/problem"
+	"github.com/pownieh/stellar_go/xdr"
+)
+
+// QuoteHandler is the http handler for the quote endpoints. A quote is the
+// result of trading an amount directly between two assets, against the
+// offers or the liquidity pool of the pair.
+type QuoteHandler struct {
+	// StrictSend is true for the strict send endpoint, where the source
+	// amount is fixed, and false for the strict receive endpoint, where the
+	// destination amount is fixed.
+	StrictSend          bool
+	SetLastLedgerHeader bool
+	PathFinder          paths.Finder
+}
+
+// QuoteQuery query struct for the quote end-points
+type QuoteQuery struct {
+	SourceAssetType        string `schema:"source_asset_type" valid:"assetType"`
+	SourceAssetIssuer      string `schema:"source_asset_issuer" valid:"accountID,optional"`
+	SourceAssetCode        string `schema:"source_asset_code" valid:"-"`
+	SourceAmount           string `schema:"source_amount" valid:"amount,optional"`
+	SourceAccount          string `schema:"source_account" valid:"accountID,optional"`
+	DestinationAssetType   string `schema:"destination_asset_type" valid:"assetType"`
+	DestinationAssetIssuer string `schema:"destination_asset_issuer" valid:"accountID,optional"`
+	DestinationAssetCode   string `schema:"destination_asset_code" valid:"-"`
+	DestinationAmount      string `schema:"destination_amount" valid:"amount,optional"`
+}
+
+// Validate runs custom validations.
+func (q QuoteQuery) Validate() error {
+	err := validateAssetParams(
+		q.SourceAssetType,
+		q.SourceAssetCode,
+		q.SourceAssetIssuer,
+		"source_",
+	)
+	if err != nil {
+		return err
+	}
+
+	err = validateAssetParams(
+		q.DestinationAssetType,
+		q.DestinationAssetCode,
+		q.DestinationAssetIssuer,
+		"destination_",
+	)
+	if err != nil {
+		return err
+	}
+
+	if q.SourceAssetType == q.DestinationAssetType &&
+		q.SourceAssetCode == q.DestinationAssetCode &&
+		q.SourceAssetIssuer == q.DestinationAssetIssuer {
+		return problem.MakeInvalidFieldProblem(
+			"destination_asset_type",
+			errors.New("the destination asset must be different from the source asset"),
+		)
+	}
+	return nil
+}
+
+func (q QuoteQuery) asset(assetType, issuer, code string) xdr.Asset {
+	asset, err := xdr.BuildAsset(assetType, issuer, code)
+	if err != nil {
+		panic(err)
+	}
+	return asset
+}
+
+// GetResource returns the quote of the requested trade
+func (handler QuoteHandler) GetResource(w HeaderWriter, r *http.Request) (interface{}, error) {
+	ctx := r.Context()
+	qp := QuoteQuery{}
+
+	if err := getParams(&qp, r); err != nil {
+		return nil, err
+	}
+
+	query := paths.QuoteQuery{
+		StrictSend:       handler.StrictSend,
+		SourceAsset:      qp.asset(qp.SourceAssetType, qp.SourceAssetIssuer, qp.SourceAssetCode),
+		DestinationAsset: qp.asset(qp.DestinationAssetType, qp.DestinationAssetIssuer, qp.DestinationAssetCode),
+	}
+
+	var err error
+	query.Amount, err = strictAmount(handler.StrictSend, qp.SourceAmount, qp.DestinationAmount)
+	if err != nil {
+		return nil, err
+	}
+
+	if qp.SourceAccount != "" {
+		sourceAccount := xdr.MustAddress(qp.SourceAccount)
+		query.SourceAccount = &sourceAccount
+	}
+
+	// Rollback REPEATABLE READ transaction so that a DB connection is released
+	// to be used by other http requests.
+	historyQ, err := horizonContext.HistoryQFromRequest(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not obtain historyQ from request")
+	}
+
+	err = historyQ.Rollback()
+	if err != nil {
+		return nil, errors.Wrap(err, "error in rollback")
+	}
+
+	quote, lastIngestedLedger, err := handler.PathFinder.Quote(ctx, query)
+	switch err {
+	case simplepath.ErrEmptyInMemoryOrderBook:
+		return nil, horizonProblem.StillIngesting
+	case simplepath.ErrQuoteNotFound:
+		return nil, problem.NotFound
+	case paths.ErrRateLimitExceeded:
+		return nil, horizonProblem.ServerOverCapacity
+	default:
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if handler.SetLastLedgerHeader {
+		// To make the Last-Ledger header consistent with the response content,
+		// we need to extract it from the ledger and not the DB.
+		// Thus, we overwrite the header if it was previously set.
+		SetLastLedgerHeader(w, lastIngestedLedger)
+	}
+
+	var resource horizon.Quote
+	if err = resourceadapter.PopulateQuote(ctx, &resource, quote, handler.StrictSend); err != nil {
+		return nil, err
+	}
+	return resource, nil
+}
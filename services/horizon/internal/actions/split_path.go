@@ -0,0 +1,187 @@
+package actions
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/pownieh/stellar_go/amount"
+	"github.com/pownieh/stellar_go/protocols/horizon"
+	horizonContext "github.com/pownieh/stellar_go/services/horizon/internal/context"
+	"github.com/pownieh/stellar_go/services/horizon/internal/paths"
+	horizonProblem "github.com/pownieh/stellar_go/services/horizon/internal/render/problem"
+	"github.com/pownieh/stellar_go/services/horizon/internal/resourceadapter"
+	"github.com/pownieh/stellar_go/services/horizon/internal/simplepath"
+	"github.com/pownieh/stellar_go/support/errors"
+	"github.com/pownieh/stellar_go/support/render/problem"
+	"github.com/pownieh/stellar_go/xdr"
+)
+
+const (
+	// DefaultSplitPathParts is the default number of parts the amount of a
+	// split path is routed in.
+	DefaultSplitPathParts = 10
+	// MaxSplitPathParts is the maximum number of parts the amount of a split
+	// path can be routed in.
+	MaxSplitPathParts = 20
+)
+
+// FindSplitPathsHandler is the http handler for the split payment paths
+// endpoints. A split payment path routes an amount across several payment
+// paths, each one submitted as its own path payment operation.
+type FindSplitPathsHandler struct {
+	// StrictSend is true for the strict send endpoint, where the source
+	// amount is fixed, and false for the strict receive endpoint, where the
+	// destination amount is fixed.
+	StrictSend          bool
+	MaxPathLength       uint
+	SetLastLedgerHeader bool
+	PathFinder          paths.Finder
+}
+
+// SplitPathsQuery query struct for the paths/split end-points
+type SplitPathsQuery struct {
+	SourceAssetType        string `schema:"source_asset_type" valid:"assetType"`
+	SourceAssetIssuer      string `schema:"source_asset_issuer" valid:"accountID,optional"`
+	SourceAssetCode        string `schema:"source_asset_code" valid:"-"`
+	SourceAmount           string `schema:"source_amount" valid:"amount,optional"`
+	SourceAccount          string `schema:"source_account" valid:"accountID,optional"`
+	DestinationAssetType   string `schema:"destination_asset_type" valid:"assetType"`
+	DestinationAssetIssuer string `schema:"destination_asset_issuer" valid:"accountID,optional"`
+	DestinationAssetCode   string `schema:"destination_asset_code" valid:"-"`
+	DestinationAmount      string `schema:"destination_amount" valid:"amount,optional"`
+	Parts                  uint   `schema:"parts" valid:"-"`
+}
+
+// Validate runs custom validations.
+func (q SplitPathsQuery) Validate() error {
+	err := validateAssetParams(
+		q.SourceAssetType,
+		q.SourceAssetCode,
+		q.SourceAssetIssuer,
+		"source_",
+	)
+	if err != nil {
+		return err
+	}
+
+	err = validateAssetParams(
+		q.DestinationAssetType,
+		q.DestinationAssetCode,
+		q.DestinationAssetIssuer,
+		"destination_",
+	)
+	if err != nil {
+		return err
+	}
+
+	if q.Parts > MaxSplitPathParts {
+		return problem.MakeInvalidFieldProblem(
+			"parts",
+			fmt.Errorf("parts must be at most %d", MaxSplitPathParts),
+		)
+	}
+	return nil
+}
+
+func (q SplitPathsQuery) asset(assetType, issuer, code string) xdr.Asset {
+	asset, err := xdr.BuildAsset(assetType, issuer, code)
+	if err != nil {
+		panic(err)
+	}
+	return asset
+}
+
+// strictAmount returns the source amount of a strict send request or the
+// destination amount of a strict receive request, the other amount must not
+// be set.
+func strictAmount(strictSend bool, sourceAmount, destinationAmount string) (xdr.Int64, error) {
+	amountParam, amountValue := "destination_amount", destinationAmount
+	if strictSend {
+		amountParam, amountValue = "source_amount", sourceAmount
+		if destinationAmount != "" {
+			return 0, problem.MakeInvalidFieldProblem(
+				"destination_amount",
+				errors.New("the destination amount can't be set for a strict send request"),
+			)
+		}
+	} else if sourceAmount != "" {
+		return 0, problem.MakeInvalidFieldProblem(
+			"source_amount",
+			errors.New("the source amount can't be set for a strict receive request"),
+		)
+	}
+	if amountValue == "" {
+		return 0, problem.MakeInvalidFieldProblem(amountParam, errors.New("Missing parameter"))
+	}
+	return amount.MustParse(amountValue), nil
+}
+
+// GetResource returns the split payment path routing the requested amount
+func (handler FindSplitPathsHandler) GetResource(w HeaderWriter, r *http.Request) (interface{}, error) {
+	ctx := r.Context()
+	qp := SplitPathsQuery{}
+
+	if err := getParams(&qp, r); err != nil {
+		return nil, err
+	}
+
+	query := paths.SplitQuery{
+		StrictSend:       handler.StrictSend,
+		SourceAsset:      qp.asset(qp.SourceAssetType, qp.SourceAssetIssuer, qp.SourceAssetCode),
+		DestinationAsset: qp.asset(qp.DestinationAssetType, qp.DestinationAssetIssuer, qp.DestinationAssetCode),
+		Parts:            int(qp.Parts),
+	}
+	if query.Parts == 0 {
+		query.Parts = DefaultSplitPathParts
+	}
+
+	var err error
+	query.Amount, err = strictAmount(handler.StrictSend, qp.SourceAmount, qp.DestinationAmount)
+	if err != nil {
+		return nil, err
+	}
+
+	if qp.SourceAccount != "" {
+		sourceAccount := xdr.MustAddress(qp.SourceAccount)
+		query.SourceAccount = &sourceAccount
+	}
+
+	// Rollback REPEATABLE READ transaction so that a DB connection is released
+	// to be used by other http requests.
+	historyQ, err := horizonContext.HistoryQFromRequest(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not obtain historyQ from request")
+	}
+
+	err = historyQ.Rollback()
+	if err != nil {
+		return nil, errors.Wrap(err, "error in rollback")
+	}
+
+	split, lastIngestedLedger, err := handler.PathFinder.FindSplitPaths(ctx, query, handler.MaxPathLength)
+	switch err {
+	case simplepath.ErrEmptyInMemoryOrderBook:
+		return nil, horizonProblem.StillIngesting
+	case simplepath.ErrSplitPathNotFound:
+		return nil, problem.NotFound
+	case paths.ErrRateLimitExceeded:
+		return nil, horizonProblem.ServerOverCapacity
+	default:
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if handler.SetLastLedgerHeader {
+		// To make the Last-Ledger header consistent with the response content,
+		// we need to extract it from the ledger and not the DB.
+		// Thus, we overwrite the header if it was previously set.
+		SetLastLedgerHeader(w, lastIngestedLedger)
+	}
+
+	var resource horizon.SplitPath
+	if err = resourceadapter.PopulateSplitPath(ctx, &resource, split, handler.StrictSend); err != nil {
+		return nil, err
+	}
+	return resource, nil
+}
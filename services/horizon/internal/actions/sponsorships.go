@@ -0,0 +1,130 @@
+package actions
+
+import (
+	"context"
+	"net/http"
+
+	protocol "github.com/pownieh/stellar_go/protocols/horizon"
+	horizonContext "github.com/pownieh/stellar_go/services/horizon/internal/context"
+	"github.com/pownieh/stellar_go/services/horizon/internal/db2"
+	"github.com/pownieh/stellar_go/services/horizon/internal/db2/history"
+	"github.com/pownieh/stellar_go/services/horizon/internal/resourceadapter"
+	"github.com/pownieh/stellar_go/support/errors"
+	"github.com/pownieh/stellar_go/support/render/problem"
+)
+
+// AccountSponsorshipsQuery query struct for the accounts/{account_id}/sponsorships end-point
+type AccountSponsorshipsQuery struct {
+	AccountID string `schema:"account_id" valid:"accountID"`
+	Limit     uint64 `schema:"limit" valid:"-"`
+}
+
+// Validate runs extra validations on query parameters
+func (q AccountSponsorshipsQuery) Validate() error {
+	if q.Limit > db2.MaxPageSize {
+		return problem.MakeInvalidFieldProblem(
+			"limit",
+			errors.Errorf("limit must not exceed %d", db2.MaxPageSize),
+		)
+	}
+	return nil
+}
+
+// GetAccountSponsorshipsHandler is the action handler for the
+// accounts/{account_id}/sponsorships end-point
+type GetAccountSponsorshipsHandler struct{}
+
+// GetResource returns the entries sponsored by an account grouped by entry
+// type. At most `limit` entries of every type are listed.
+func (handler GetAccountSponsorshipsHandler) GetResource(w HeaderWriter, r *http.Request) (interface{}, error) {
+	ctx := r.Context()
+	qp := AccountSponsorshipsQuery{}
+	if err := getParams(&qp, r); err != nil {
+		return nil, err
+	}
+	if qp.Limit == 0 {
+		qp.Limit = db2.DefaultPageSize
+	}
+
+	historyQ, err := horizonContext.HistoryQFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	account, err := historyQ.GetAccountByID(ctx, qp.AccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	baseReserve, err := latestBaseReserve(ctx, historyQ)
+	if err != nil {
+		return nil, err
+	}
+
+	totals, err := historyQ.SponsorshipTotalsForSponsor(ctx, qp.AccountID)
+	if err != nil {
+		return nil, errors.Wrap(err, "loading sponsorship totals")
+	}
+
+	entries, err := historyQ.SponsoredEntriesForSponsor(ctx, qp.AccountID, qp.Limit)
+	if err != nil {
+		return nil, errors.Wrap(err, "loading sponsored entries")
+	}
+
+	var resource protocol.AccountSponsorships
+	resourceadapter.PopulateAccountSponsorships(ctx, &resource, account, baseReserve, totals, entries)
+	return resource, nil
+}
+
+// GetAccountSponsorsHandler is the action handler for the
+// accounts/{account_id}/sponsors end-point
+type GetAccountSponsorsHandler struct{}
+
+// GetResource returns the entries of an account sponsored by other accounts
+// grouped by sponsor.
+func (handler GetAccountSponsorsHandler) GetResource(w HeaderWriter, r *http.Request) (interface{}, error) {
+	ctx := r.Context()
+	qp := AccountByIDQuery{}
+	if err := getParams(&qp, r); err != nil {
+		return nil, err
+	}
+
+	historyQ, err := horizonContext.HistoryQFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	account, err := historyQ.GetAccountByID(ctx, qp.AccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	baseReserve, err := latestBaseReserve(ctx, historyQ)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := historyQ.SponsoredEntriesForAccount(ctx, qp.AccountID)
+	if err != nil {
+		return nil, errors.Wrap(err, "loading sponsored entries")
+	}
+
+	var resource protocol.AccountSponsors
+	resourceadapter.PopulateAccountSponsors(ctx, &resource, account, baseReserve, entries)
+	return resource, nil
+}
+
+// latestBaseReserve returns the base reserve of the last ledger ingested in
+// the state tables.
+func latestBaseReserve(ctx context.Context, hq *history.Q) (int32, error) {
+	sequence, err := hq.GetLastLedgerIngestNonBlocking(ctx)
+	if err != nil {
+		return 0, errors.Wrap(err, "loading last ingested ledger")
+	}
+
+	var ledger history.Ledger
+	if err = hq.LedgerBySequence(ctx, &ledger, int32(sequence)); err != nil {
+		return 0, errors.Wrap(err, "loading last ingested ledger")
+	}
+	return ledger.BaseReserve, nil
+}
@@ -0,0 +1,137 @@
+package actions
+
+import (
+	"database/sql"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/guregu/null"
+
+	protocol "github.com/pownieh/stellar_go/protocols/horizon"
+	"github.com/pownieh/stellar_go/services/horizon/internal/db2/history"
+	"github.com/pownieh/stellar_go/services/horizon/internal/test"
+	"github.com/pownieh/stellar_go/support/render/problem"
+	"github.com/pownieh/stellar_go/xdr"
+)
+
+func TestAccountSponsorshipsHandlers(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetHorizonDB(t, tt.HorizonDB)
+	q := &history.Q{tt.HorizonSession()}
+
+	tt.Assert.NoError(q.UpsertAccounts(tt.Ctx, []history.AccountEntry{
+		{AccountID: sponsor, Balance: 100000000, NumSponsoring: 4, LastModifiedLedger: 4},
+		{
+			AccountID:          accountOne,
+			Balance:            0,
+			NumSubEntries:      2,
+			NumSponsored:       4,
+			LastModifiedLedger: 4,
+			Sponsor:            null.StringFrom(sponsor),
+		},
+	}))
+	tt.Assert.NoError(q.UpsertTrustLines(tt.Ctx, []history.TrustLine{
+		{
+			AccountID:          accountOne,
+			AssetType:          xdr.AssetTypeAssetTypeCreditAlphanum4,
+			AssetIssuer:        trustLineIssuer,
+			AssetCode:          "EUR",
+			LedgerKey:          "eur-tl",
+			Limit:              100,
+			LastModifiedLedger: 4,
+			Sponsor:            null.StringFrom(sponsor),
+		},
+		{
+			AccountID:          accountOne,
+			AssetType:          xdr.AssetTypeAssetTypeCreditAlphanum4,
+			AssetIssuer:        trustLineIssuer,
+			AssetCode:          "USD",
+			LedgerKey:          "usd-tl",
+			Limit:              100,
+			LastModifiedLedger: 4,
+			Sponsor:            null.StringFrom(sponsor),
+		},
+	}))
+
+	tt.Assert.NoError(q.Begin(tt.Ctx))
+	ledgerBatch := q.NewLedgerBatchInsertBuilder()
+	tt.Assert.NoError(ledgerBatch.Add(xdr.LedgerHeaderHistoryEntry{
+		Header: xdr.LedgerHeader{LedgerSeq: 4, BaseReserve: 5000000},
+	}, 0, 0, 0, 0, 0))
+	tt.Assert.NoError(ledgerBatch.Exec(tt.Ctx, q))
+	tt.Assert.NoError(q.UpdateLastLedgerIngest(tt.Ctx, 4))
+	tt.Assert.NoError(q.Commit())
+
+	resource, err := GetAccountSponsorshipsHandler{}.GetResource(
+		httptest.NewRecorder(),
+		makeRequest(t, map[string]string{"limit": "1"}, map[string]string{"account_id": sponsor}, q),
+	)
+	tt.Assert.NoError(err)
+	tt.Assert.Equal(protocol.AccountSponsorships{
+		AccountID:     sponsor,
+		BaseReserve:   "0.5000000",
+		NumSponsoring: 4,
+		Reserves:      4,
+		ReserveAmount: "2.0000000",
+		EntryTypes: []protocol.SponsorshipType{
+			{
+				Type:          history.SponsoredAccount,
+				Count:         1,
+				Reserves:      2,
+				ReserveAmount: "1.0000000",
+				Entries: []protocol.SponsoredEntry{
+					{Type: history.SponsoredAccount, AccountID: accountOne, Key: accountOne, Reserves: 2},
+				},
+			},
+			{
+				Type:          history.SponsoredTrustLine,
+				Count:         2,
+				Reserves:      2,
+				ReserveAmount: "1.0000000",
+				Entries: []protocol.SponsoredEntry{
+					{Type: history.SponsoredTrustLine, AccountID: accountOne, Key: "EUR:" + trustLineIssuer, Reserves: 1},
+				},
+			},
+		},
+	}, resource)
+
+	resource, err = GetAccountSponsorsHandler{}.GetResource(
+		httptest.NewRecorder(),
+		makeRequest(t, map[string]string{}, map[string]string{"account_id": accountOne}, q),
+	)
+	tt.Assert.NoError(err)
+	tt.Assert.Equal(protocol.AccountSponsors{
+		AccountID:     accountOne,
+		BaseReserve:   "0.5000000",
+		NumSponsored:  4,
+		Reserves:      4,
+		ReserveAmount: "2.0000000",
+		Sponsors: []protocol.Sponsor{
+			{
+				Sponsor:       sponsor,
+				Reserves:      4,
+				ReserveAmount: "2.0000000",
+				Entries: []protocol.SponsoredEntry{
+					{Type: history.SponsoredAccount, Key: accountOne, Reserves: 2},
+					{Type: history.SponsoredTrustLine, Key: "EUR:" + trustLineIssuer, Reserves: 1},
+					{Type: history.SponsoredTrustLine, Key: "USD:" + trustLineIssuer, Reserves: 1},
+				},
+			},
+		},
+	}, resource)
+
+	_, err = GetAccountSponsorsHandler{}.GetResource(
+		httptest.NewRecorder(),
+		makeRequest(t, map[string]string{}, map[string]string{"account_id": accountTwo}, q),
+	)
+	tt.Assert.Equal(sql.ErrNoRows, err)
+
+	_, err = GetAccountSponsorshipsHandler{}.GetResource(
+		httptest.NewRecorder(),
+		makeRequest(t, map[string]string{"limit": "201"}, map[string]string{"account_id": sponsor}, q),
+	)
+	if tt.Assert.IsType(&problem.P{}, err) {
+		tt.Assert.Equal("limit", err.(*problem.P).Extras["invalid_field"])
+	}
+}
@@ -0,0 +1,37 @@
+package actions
+
+import (
+	"encoding/json"
+	"net/http"
+
+	horizonContext "github.com/pownieh/stellar_go/services/horizon/internal/context"
+	"github.com/pownieh/stellar_go/support/render/problem"
+)
+
+// StateRebuildHandler serves the progress of the last zero-downtime state
+// rebuild.
+// This admin HTTP endpoint is documented in services/horizon/internal/httpx/static/admin_oapi.yml
+type StateRebuildHandler struct{}
+
+func (handler StateRebuildHandler) GetStatus(w http.ResponseWriter, r *http.Request) {
+	historyQ, err := horizonContext.HistoryQFromRequest(r)
+	if err != nil {
+		problem.Render(r.Context(), w, err)
+		return
+	}
+
+	rebuild, err := historyQ.GetStateRebuild(r.Context())
+	if err != nil {
+		problem.Render(r.Context(), w, err)
+		return
+	}
+	if rebuild.Status == "" {
+		problem.Render(r.Context(), w, problem.NotFound)
+		return
+	}
+
+	enc := json.NewEncoder(w)
+	if err = enc.Encode(rebuild); err != nil {
+		problem.Render(r.Context(), w, err)
+	}
+}
@@ -0,0 +1,36 @@
+package actions
+
+import (
+	"encoding/json"
+	"net/http"
+
+	horizonContext "github.com/pownieh/stellar_go/services/horizon/internal/context"
+	"github.com/pownieh/stellar_go/support/render/problem"
+)
+
+// StateVerificationHandler serves the result of the last state verification.
+// This admin HTTP endpoint is documented in services/horizon/internal/httpx/static/admin_oapi.yml
+type StateVerificationHandler struct{}
+
+func (handler StateVerificationHandler) GetResult(w http.ResponseWriter, r *http.Request) {
+	historyQ, err := horizonContext.HistoryQFromRequest(r)
+	if err != nil {
+		problem.Render(r.Context(), w, err)
+		return
+	}
+
+	result, err := historyQ.GetStateVerificationResult(r.Context())
+	if err != nil {
+		problem.Render(r.Context(), w, err)
+		return
+	}
+	if result == nil {
+		problem.Render(r.Context(), w, problem.NotFound)
+		return
+	}
+
+	enc := json.NewEncoder(w)
+	if err = enc.Encode(result); err != nil {
+		problem.Render(r.Context(), w, err)
+	}
+}
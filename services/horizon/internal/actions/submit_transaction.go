@@ -9,6 +9,7 @@ import (
 	"github.com/pownieh/stellar_go/gxdr"
 	"github.com/pownieh/stellar_go/network"
 	"github.com/pownieh/stellar_go/protocols/horizon"
+	"github.com/pownieh/stellar_go/services/horizon/internal/codes"
 	hProblem "github.com/pownieh/stellar_go/services/horizon/internal/render/problem"
 	"github.com/pownieh/stellar_go/services/horizon/internal/resourceadapter"
 	"github.com/pownieh/stellar_go/services/horizon/internal/txsub"
@@ -22,10 +23,18 @@ type NetworkSubmitter interface {
 	Submit(ctx context.Context, rawTx string, envelope xdr.TransactionEnvelope, hash string) <-chan txsub.Result
 }
 
+// TransactionValidator checks transactions before they are submitted.
+type TransactionValidator interface {
+	Validate(ctx context.Context, envelope xdr.TransactionEnvelope, hash string) error
+}
+
 type SubmitTransactionHandler struct {
 	Submitter         NetworkSubmitter
 	NetworkPassphrase string
 	DisableTxSub      bool
+	// Validator, when set, rejects the transactions which would fail
+	// instead of submitting them.
+	Validator TransactionValidator
 	CoreStateGetter
 }
 
@@ -62,7 +71,9 @@ func extractEnvelopeInfo(raw string, passphrase string) (envelopeInfo, error) {
 	return result, nil
 }
 
-func (handler SubmitTransactionHandler) validateBodyType(r *http.Request) error {
+// validateTransactionBodyType checks that the transaction is submitted in a
+// form.
+func validateTransactionBodyType(r *http.Request) error {
 	c := r.Header.Get("Content-Type")
 	if c == "" {
 		return nil
@@ -79,6 +90,22 @@ func (handler SubmitTransactionHandler) validateBodyType(r *http.Request) error
 	return nil
 }
 
+func transactionMalformedProblem(raw string) *problem.P {
+	return &problem.P{
+		Type:   "transaction_malformed",
+		Title:  "Transaction Malformed",
+		Status: http.StatusBadRequest,
+		Detail: "Horizon could not decode the transaction envelope in this " +
+			"request. A transaction should be an XDR TransactionEnvelope struct " +
+			"encoded using base64.  The envelope read from this request is " +
+			"echoed in the `extras.envelope_xdr` field of this response for your " +
+			"convenience.",
+		Extras: map[string]interface{}{
+			"envelope_xdr": raw,
+		},
+	}
+}
+
 func (handler SubmitTransactionHandler) response(r *http.Request, info envelopeInfo, result txsub.Result) (hal.Pageable, error) {
 	if result.Err == nil {
 		var resource horizon.Transaction
@@ -128,8 +155,55 @@ func (handler SubmitTransactionHandler) response(r *http.Request, info envelopeI
 	return nil, result.Err
 }
 
+func (handler SubmitTransactionHandler) validationProblem(info envelopeInfo, err error) error {
+	verr, ok := err.(*txsub.ValidationError)
+	if !ok {
+		return err
+	}
+
+	rcr := horizon.TransactionResultCodes{}
+	var codeErr error
+	rcr.TransactionCode, codeErr = codes.String(verr.Code)
+	if codeErr != nil {
+		return codeErr
+	}
+	if verr.Code == xdr.TransactionResultCodeTxFeeBumpInnerFailed {
+		rcr.InnerTransactionCode, codeErr = codes.String(verr.InnerCode)
+		if codeErr != nil {
+			return codeErr
+		}
+	}
+	extras := map[string]interface{}{
+		"envelope_xdr": info.raw,
+		"result_codes": rcr,
+		"reason":       verr.Reason,
+	}
+	if verr.OperationCode != nil {
+		opCode, codeErr := codes.String(verr.OperationCode)
+		if codeErr != nil {
+			return codeErr
+		}
+		extras["operation_index"] = verr.OperationIndex
+		extras["operation_code"] = opCode
+	}
+
+	return &problem.P{
+		Type:   "transaction_validation_failed",
+		Title:  "Transaction Validation Failed",
+		Status: http.StatusBadRequest,
+		Detail: "The transaction was not submitted to the stellar network because " +
+			"it would fail given the latest ledger ingested by Horizon. The " +
+			"`extras.result_codes` field on this response contains the expected " +
+			"result codes and the `extras.reason` field explains why the " +
+			"transaction is invalid. When an operation would fail, its index and " +
+			"result code are in the `extras.operation_index` and " +
+			"`extras.operation_code` fields.",
+		Extras: extras,
+	}
+}
+
 func (handler SubmitTransactionHandler) GetResource(w HeaderWriter, r *http.Request) (interface{}, error) {
-	if err := handler.validateBodyType(r); err != nil {
+	if err := validateTransactionBodyType(r); err != nil {
 		return nil, err
 	}
 
@@ -151,19 +225,7 @@ func (handler SubmitTransactionHandler) GetResource(w HeaderWriter, r *http.Requ
 
 	info, err := extractEnvelopeInfo(raw, handler.NetworkPassphrase)
 	if err != nil {
-		return nil, &problem.P{
-			Type:   "transaction_malformed",
-			Title:  "Transaction Malformed",
-			Status: http.StatusBadRequest,
-			Detail: "Horizon could not decode the transaction envelope in this " +
-				"request. A transaction should be an XDR TransactionEnvelope struct " +
-				"encoded using base64.  The envelope read from this request is " +
-				"echoed in the `extras.envelope_xdr` field of this response for your " +
-				"convenience.",
-			Extras: map[string]interface{}{
-				"envelope_xdr": raw,
-			},
-		}
+		return nil, transactionMalformedProblem(raw)
 	}
 
 	coreState := handler.GetCoreState()
@@ -171,6 +233,12 @@ func (handler SubmitTransactionHandler) GetResource(w HeaderWriter, r *http.Requ
 		return nil, hProblem.StaleHistory
 	}
 
+	if handler.Validator != nil {
+		if err = handler.Validator.Validate(r.Context(), info.parsed, info.hash); err != nil {
+			return nil, handler.validationProblem(info, err)
+		}
+	}
+
 	submission := handler.Submitter.Submit(r.Context(), info.raw, info.parsed, info.hash)
 
 	select {
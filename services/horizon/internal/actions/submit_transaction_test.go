@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/pownieh/stellar_go/network"
+	"github.com/pownieh/stellar_go/protocols/horizon"
 	"github.com/pownieh/stellar_go/services/horizon/internal/corestate"
 	hProblem "github.com/pownieh/stellar_go/services/horizon/internal/render/problem"
 	"github.com/pownieh/stellar_go/services/horizon/internal/txsub"
@@ -199,3 +200,76 @@ func TestDisableTxSubFlagSubmission(t *testing.T) {
 	_, err = handler.GetResource(w, request)
 	assert.Equal(t, p, err)
 }
+
+type transactionValidatorMock struct {
+	mock.Mock
+}
+
+func (m *transactionValidatorMock) Validate(ctx context.Context, envelope xdr.TransactionEnvelope, hash string) error {
+	a := m.Called(hash)
+	return a.Error(0)
+}
+
+func TestValidationFailedSubmission(t *testing.T) {
+	mock := &coreStateGetterMock{}
+	mock.On("GetCoreState").Return(corestate.State{
+		Synced: true,
+	})
+
+	mockSubmitter := &networkSubmitterMock{}
+	mockValidator := &transactionValidatorMock{}
+
+	handler := SubmitTransactionHandler{
+		Submitter:         mockSubmitter,
+		NetworkPassphrase: network.PublicNetworkPassphrase,
+		Validator:         mockValidator,
+		CoreStateGetter:   mock,
+	}
+
+	raw := "AAAAAAGUcmKO5465JxTSLQOQljwk2SfqAJmZSG6JH6wtqpwhAAABLAAAAAAAAAABAAAAAAAAAAEAAAALaGVsbG8gd29ybGQAAAAAAwAAAAAAAAAAAAAAABbxCy3mLg3hiTqX4VUEEp60pFOrJNxYM1JtxXTwXhY2AAAAAAvrwgAAAAAAAAAAAQAAAAAW8Qst5i4N4Yk6l+FVBBKetKRTqyTcWDNSbcV08F4WNgAAAAAN4Lazj4x61AAAAAAAAAAFAAAAAAAAAAAAAAAAAAAAAQAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAABLaqcIQAAAEBKwqWy3TaOxoGnfm9eUjfTRBvPf34dvDA0Nf+B8z4zBob90UXtuCqmQqwMCyH+okOI3c05br3khkH0yP4kCwcE"
+	info, err := extractEnvelopeInfo(raw, network.PublicNetworkPassphrase)
+	require.NoError(t, err)
+	mockValidator.On("Validate", info.hash).Return(&txsub.ValidationError{
+		Code:           xdr.TransactionResultCodeTxFailed,
+		OperationIndex: 1,
+		OperationCode:  xdr.PaymentResultCodePaymentNoTrust,
+		Reason:         "the account GAAA does not trust USD",
+	}).Once()
+
+	form := url.Values{}
+	form.Set("tx", raw)
+	request, err := http.NewRequest(
+		"POST",
+		"https://horizon.stellar.org/transactions",
+		strings.NewReader(form.Encode()),
+	)
+	require.NoError(t, err)
+	request.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+
+	w := httptest.NewRecorder()
+	_, err = handler.GetResource(w, request)
+	require.IsType(t, &problem.P{}, err)
+	p := err.(*problem.P)
+	assert.Equal(t, "transaction_validation_failed", p.Type)
+	assert.Equal(t, http.StatusBadRequest, p.Status)
+	assert.Equal(t, raw, p.Extras["envelope_xdr"])
+	assert.Equal(t, "tx_failed", p.Extras["result_codes"].(horizon.TransactionResultCodes).TransactionCode)
+	assert.Equal(t, 1, p.Extras["operation_index"])
+	assert.Equal(t, "op_no_trust", p.Extras["operation_code"])
+	assert.Equal(t, "the account GAAA does not trust USD", p.Extras["reason"])
+	mockValidator.AssertExpectations(t)
+	// the transaction is not submitted
+	mockSubmitter.AssertNotCalled(t, "Submit")
+
+	// other validation errors are returned as is
+	mockValidator.On("Validate", info.hash).Return(context.DeadlineExceeded).Once()
+	request, err = http.NewRequest(
+		"POST",
+		"https://horizon.stellar.org/transactions",
+		strings.NewReader(form.Encode()),
+	)
+	require.NoError(t, err)
+	request.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	_, err = handler.GetResource(w, request)
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
@@ -251,8 +251,8 @@ func (q TradeAggregationsQuery) Validate() error {
 
 	//check if resolution is legal
 	resolutionDuration := gTime.Duration(q.ResolutionFilter) * gTime.Millisecond
-	if history.StrictResolutionFiltering {
-		if _, ok := history.AllowedResolutions[resolutionDuration]; !ok {
+	if !history.ValidTradeAggregationResolution(int64(q.ResolutionFilter)) {
+		if history.StrictResolutionFiltering {
 			return problem.MakeInvalidFieldProblem(
 				"resolution",
 				errors.New("illegal or missing resolution. "+
@@ -260,6 +260,11 @@ func (q TradeAggregationsQuery) Validate() error {
 					"1 day (86400000) and 1 week (604800000)"),
 			)
 		}
+		return problem.MakeInvalidFieldProblem(
+			"resolution",
+			errors.New("illegal or missing resolution. "+
+				"the resolution must be a multiple of 1 minute (60000)"),
+		)
 	}
 	// check if offset is legal
 	offsetDuration := gTime.Duration(q.OffsetFilter) * gTime.Millisecond
@@ -446,7 +451,7 @@ func (handler GetTradeAggregationsHandler) buildPage(r *http.Request, records []
 
 		if page.Order == "asc" {
 			newStartTime := timestamp + int64(qp.ResolutionFilter)
-			if newStartTime >= qp.EndTimeFilter.ToInt64() {
+			if !qp.EndTimeFilter.IsNil() && newStartTime >= qp.EndTimeFilter.ToInt64() {
 				newStartTime = qp.EndTimeFilter.ToInt64()
 			}
 			q.Set("start_time", strconv.FormatInt(newStartTime, 10))
@@ -0,0 +1,66 @@
+package actions
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/pownieh/stellar_go/protocols/horizon"
+	horizonContext "github.com/pownieh/stellar_go/services/horizon/internal/context"
+	"github.com/pownieh/stellar_go/services/horizon/internal/paths"
+	hProblem "github.com/pownieh/stellar_go/services/horizon/internal/render/problem"
+	"github.com/pownieh/stellar_go/services/horizon/internal/resourceadapter"
+	"github.com/pownieh/stellar_go/services/horizon/internal/simplepath"
+	"github.com/pownieh/stellar_go/services/horizon/internal/txsub"
+	"github.com/pownieh/stellar_go/xdr"
+)
+
+// TransactionDryRunner estimates the outcome of transactions without
+// submitting them.
+type TransactionDryRunner interface {
+	DryRun(ctx context.Context, db txsub.ValidatorDB, envelope xdr.TransactionEnvelope) (txsub.DryRunResult, error)
+}
+
+// DryRunTransactionHandler is the action handler for the endpoint returning
+// the expected outcome of a signed or unsigned transaction.
+type DryRunTransactionHandler struct {
+	DryRunner         TransactionDryRunner
+	NetworkPassphrase string
+}
+
+// GetResource returns the expected outcome of the transaction.
+func (handler DryRunTransactionHandler) GetResource(w HeaderWriter, r *http.Request) (interface{}, error) {
+	if err := validateTransactionBodyType(r); err != nil {
+		return nil, err
+	}
+
+	raw, err := getString(r, "tx")
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := extractEnvelopeInfo(raw, handler.NetworkPassphrase)
+	if err != nil {
+		return nil, transactionMalformedProblem(raw)
+	}
+
+	historyQ, err := horizonContext.HistoryQFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := handler.DryRunner.DryRun(r.Context(), historyQ, info.parsed)
+	switch err {
+	case txsub.ErrNoIngestedLedger, txsub.ErrOrderBookChanged, simplepath.ErrEmptyInMemoryOrderBook:
+		return nil, hProblem.StillIngesting
+	case paths.ErrRateLimitExceeded:
+		return nil, hProblem.ServerOverCapacity
+	default:
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var resource horizon.TransactionDryRun
+	err = resourceadapter.PopulateTransactionDryRun(r.Context(), &resource, info.hash, info.parsed.IsFeeBump(), result)
+	return resource, err
+}
@@ -0,0 +1,99 @@
+package actions
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pownieh/stellar_go/network"
+	"github.com/pownieh/stellar_go/protocols/horizon"
+	horizonContext "github.com/pownieh/stellar_go/services/horizon/internal/context"
+	hProblem "github.com/pownieh/stellar_go/services/horizon/internal/render/problem"
+	"github.com/pownieh/stellar_go/services/horizon/internal/txsub"
+	"github.com/pownieh/stellar_go/support/db"
+	"github.com/pownieh/stellar_go/support/render/problem"
+	"github.com/pownieh/stellar_go/xdr"
+)
+
+type transactionDryRunnerMock struct {
+	mock.Mock
+}
+
+func (m *transactionDryRunnerMock) DryRun(ctx context.Context, db txsub.ValidatorDB, envelope xdr.TransactionEnvelope) (txsub.DryRunResult, error) {
+	a := m.Called(envelope)
+	return a.Get(0).(txsub.DryRunResult), a.Error(1)
+}
+
+func dryRunRequest(t *testing.T, raw string) *http.Request {
+	form := url.Values{}
+	form.Set("tx", raw)
+	request, err := http.NewRequest(
+		"POST",
+		"https://horizon.stellar.org/transactions/dry_run",
+		strings.NewReader(form.Encode()),
+	)
+	require.NoError(t, err)
+	request.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	ctx := context.WithValue(request.Context(), &horizonContext.SessionContextKey, &db.MockSession{})
+	return request.WithContext(ctx)
+}
+
+func TestDryRunTransaction(t *testing.T) {
+	runner := &transactionDryRunnerMock{}
+	handler := DryRunTransactionHandler{
+		DryRunner:         runner,
+		NetworkPassphrase: network.PublicNetworkPassphrase,
+	}
+
+	raw := "AAAAAAGUcmKO5465JxTSLQOQljwk2SfqAJmZSG6JH6wtqpwhAAABLAAAAAAAAAABAAAAAAAAAAEAAAALaGVsbG8gd29ybGQAAAAAAwAAAAAAAAAAAAAAABbxCy3mLg3hiTqX4VUEEp60pFOrJNxYM1JtxXTwXhY2AAAAAAvrwgAAAAAAAAAAAQAAAAAW8Qst5i4N4Yk6l+FVBBKetKRTqyTcWDNSbcV08F4WNgAAAAAN4Lazj4x61AAAAAAAAAAFAAAAAAAAAAAAAAAAAAAAAQAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAABLaqcIQAAAEBKwqWy3TaOxoGnfm9eUjfTRBvPf34dvDA0Nf+B8z4zBob90UXtuCqmQqwMCyH+okOI3c05br3khkH0yP4kCwcE"
+	info, err := extractEnvelopeInfo(raw, network.PublicNetworkPassphrase)
+	require.NoError(t, err)
+	runner.On("DryRun", info.parsed).Return(txsub.DryRunResult{
+		Ledger:   100,
+		Signed:   true,
+		Complete: true,
+		Error: &txsub.ValidationError{
+			Code:           xdr.TransactionResultCodeTxFailed,
+			OperationIndex: 0,
+			OperationCode:  xdr.PaymentResultCodePaymentUnderfunded,
+			Reason:         "underfunded",
+		},
+		Operations: []txsub.DryRunOperation{{
+			Type:      xdr.OperationTypePayment,
+			Supported: true,
+			Code:      xdr.PaymentResultCodePaymentUnderfunded,
+		}},
+	}, nil).Once()
+
+	w := httptest.NewRecorder()
+	resource, err := handler.GetResource(w, dryRunRequest(t, raw))
+	require.NoError(t, err)
+	dryRun := resource.(horizon.TransactionDryRun)
+	assert.Equal(t, info.hash, dryRun.Hash)
+	assert.Equal(t, uint32(100), dryRun.Ledger)
+	assert.False(t, dryRun.Successful)
+	assert.Equal(t, "underfunded", dryRun.Reason)
+	assert.Equal(t, horizon.TransactionResultCodes{
+		TransactionCode: "tx_failed",
+		OperationCodes:  []string{"op_underfunded"},
+	}, dryRun.ResultCodes)
+
+	runner.On("DryRun", info.parsed).Return(txsub.DryRunResult{}, txsub.ErrNoIngestedLedger).Once()
+	_, err = handler.GetResource(w, dryRunRequest(t, raw))
+	assert.Equal(t, hProblem.StillIngesting, err)
+	runner.On("DryRun", info.parsed).Return(txsub.DryRunResult{}, txsub.ErrOrderBookChanged).Once()
+	_, err = handler.GetResource(w, dryRunRequest(t, raw))
+	assert.Equal(t, hProblem.StillIngesting, err)
+	runner.AssertExpectations(t)
+
+	_, err = handler.GetResource(w, dryRunRequest(t, "AAAA"))
+	require.IsType(t, &problem.P{}, err)
+	assert.Equal(t, "transaction_malformed", err.(*problem.P).Type)
+}
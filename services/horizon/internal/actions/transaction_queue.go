@@ -0,0 +1,39 @@
+package actions
+
+import (
+	"net/http"
+
+	"github.com/pownieh/stellar_go/protocols/horizon"
+	horizonContext "github.com/pownieh/stellar_go/services/horizon/internal/context"
+	"github.com/pownieh/stellar_go/services/horizon/internal/resourceadapter"
+	"github.com/pownieh/stellar_go/support/errors"
+)
+
+// GetQueuedTransactionHandler is the action handler for the end-point
+// returning a transaction of the transaction submission queue.
+type GetQueuedTransactionHandler struct {
+}
+
+// GetResource returns a queued transaction.
+func (handler GetQueuedTransactionHandler) GetResource(w HeaderWriter, r *http.Request) (interface{}, error) {
+	ctx := r.Context()
+	qp := TransactionQuery{}
+	err := getParams(&qp, r)
+	if err != nil {
+		return nil, err
+	}
+
+	historyQ, err := horizonContext.HistoryQFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	record, err := historyQ.QueuedTransactionByHash(ctx, qp.TransactionHash)
+	if err != nil {
+		return nil, errors.Wrap(err, "loading queued transaction record")
+	}
+
+	var resource horizon.QueuedTransaction
+	resourceadapter.PopulateQueuedTransaction(ctx, &resource, record)
+	return resource, nil
+}
@@ -3,7 +3,9 @@ package horizon
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"math/big"
 	"net/http"
 	"net/url"
 	"strings"
@@ -71,6 +73,26 @@ func mockPathFindingClient(
 		router.Method("GET", "/paths", findPaths)
 		router.Method("GET", "/paths/strict-receive", findPaths)
 		router.Method("GET", "/paths/strict-send", findFixedPaths)
+		router.Method("GET", "/paths/split/strict-receive", httpx.ObjectActionHandler{actions.FindSplitPathsHandler{
+			PathFinder:          finder,
+			MaxPathLength:       3,
+			SetLastLedgerHeader: true,
+		}})
+		router.Method("GET", "/paths/split/strict-send", httpx.ObjectActionHandler{actions.FindSplitPathsHandler{
+			StrictSend:          true,
+			PathFinder:          finder,
+			MaxPathLength:       3,
+			SetLastLedgerHeader: true,
+		}})
+		router.Method("GET", "/quote/strict-receive", httpx.ObjectActionHandler{actions.QuoteHandler{
+			PathFinder:          finder,
+			SetLastLedgerHeader: true,
+		}})
+		router.Method("GET", "/quote/strict-send", httpx.ObjectActionHandler{actions.QuoteHandler{
+			StrictSend:          true,
+			PathFinder:          finder,
+			SetLastLedgerHeader: true,
+		}})
 	})
 
 	return test.NewRequestHelper(router)
@@ -729,3 +751,203 @@ func TestStrictReceivePathsQueryURLTemplate(t *testing.T) {
 	qp := actions.StrictReceivePathsQuery{}
 	tt.Equal(expected, qp.URITemplate())
 }
+
+func TestPathActionsSplitPaths(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetHorizonDB(t, tt.HorizonDB)
+	assertions := &test.Assertions{tt.Assert}
+
+	issuer := "GDSBCQO34HWPGUGQSP3QBFEXVTSR2PW46UIGTHVWGWJGQKH3AFNHXHXN"
+	eur := xdr.MustNewCreditAsset("EUR", issuer)
+	usd := xdr.MustNewCreditAsset("USD", issuer)
+	chf := xdr.MustNewCreditAsset("CHF", issuer)
+	split := paths.SplitPath{
+		Source:            usd.String(),
+		SourceAmount:      4000000000,
+		Destination:       eur.String(),
+		DestinationAmount: 3000000000,
+		Legs: []paths.Path{
+			{
+				Path:              []string{},
+				Source:            usd.String(),
+				SourceAmount:      2000000000,
+				Destination:       eur.String(),
+				DestinationAmount: 1600000000,
+			},
+			{
+				Path:              []string{chf.String()},
+				Source:            usd.String(),
+				SourceAmount:      2000000000,
+				Destination:       eur.String(),
+				DestinationAmount: 1400000000,
+			},
+		},
+	}
+
+	finder := paths.MockFinder{}
+	finder.On("FindSplitPaths", mock.Anything, paths.SplitQuery{
+		StrictSend:       true,
+		SourceAsset:      usd,
+		DestinationAsset: eur,
+		Amount:           4000000000,
+		Parts:            actions.DefaultSplitPathParts,
+	}, uint(3)).Return(split, uint32(1234), nil).Once()
+	finder.On("FindSplitPaths", mock.Anything, paths.SplitQuery{
+		StrictSend:       false,
+		SourceAsset:      usd,
+		DestinationAsset: eur,
+		Amount:           3000000000,
+		Parts:            5,
+	}, uint(3)).Return(paths.SplitPath{}, uint32(0), simplepath.ErrSplitPathNotFound).Once()
+	defer finder.AssertExpectations(t)
+
+	rh := mockPathFindingClient(tt, &finder, 2, tt.HorizonSession())
+
+	q := make(url.Values)
+	q.Add("source_asset_type", "credit_alphanum4")
+	q.Add("source_asset_code", "USD")
+	q.Add("source_asset_issuer", issuer)
+	q.Add("destination_asset_type", "credit_alphanum4")
+	q.Add("destination_asset_code", "EUR")
+	q.Add("destination_asset_issuer", issuer)
+
+	sendQuery := url.Values{"source_amount": []string{"400"}}
+	for k, v := range q {
+		sendQuery[k] = v
+	}
+	w := rh.Get("/paths/split/strict-send?" + sendQuery.Encode())
+	assertions.Equal(http.StatusOK, w.Code)
+	assertions.Equal("1234", w.Header().Get(actions.LastLedgerHeaderName))
+
+	var resource horizon.SplitPath
+	tt.Assert.NoError(json.Unmarshal(w.Body.Bytes(), &resource))
+	tt.Assert.Equal("path_payment_strict_send", resource.OperationType)
+	tt.Assert.Equal("400.0000000", resource.SourceAmount)
+	tt.Assert.Equal("300.0000000", resource.DestinationAmount)
+	tt.Assert.Len(resource.Legs, 2)
+	tt.Assert.Equal("160.0000000", resource.Legs[0].DestinationAmount)
+	tt.Assert.Equal([]horizon.Asset{{Type: "credit_alphanum4", Code: "CHF", Issuer: issuer}}, resource.Legs[1].Path)
+
+	receiveQuery := url.Values{"destination_amount": []string{"300"}, "parts": []string{"5"}}
+	for k, v := range q {
+		receiveQuery[k] = v
+	}
+	w = rh.Get("/paths/split/strict-receive?" + receiveQuery.Encode())
+	assertions.Equal(http.StatusNotFound, w.Code)
+
+	// the amount must match the endpoint
+	w = rh.Get("/paths/split/strict-receive?" + sendQuery.Encode())
+	assertions.Equal(http.StatusBadRequest, w.Code)
+
+	receiveQuery.Set("parts", "21")
+	w = rh.Get("/paths/split/strict-receive?" + receiveQuery.Encode())
+	assertions.Equal(http.StatusBadRequest, w.Code)
+}
+
+func TestPathActionsQuote(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetHorizonDB(t, tt.HorizonDB)
+	assertions := &test.Assertions{tt.Assert}
+
+	issuer := "GDSBCQO34HWPGUGQSP3QBFEXVTSR2PW46UIGTHVWGWJGQKH3AFNHXHXN"
+	seller := xdr.MustAddress("GA2NC4ZOXMXLVQAQQ5IQKJX47M3PKBQV2N5UV5Z4OXLQJ3CKMBA2O2YL")
+	eur := xdr.MustNewCreditAsset("EUR", issuer)
+	usd := xdr.MustNewCreditAsset("USD", issuer)
+	quote := paths.Quote{
+		Source:            usd.String(),
+		SourceAmount:      3000000000,
+		Destination:       eur.String(),
+		DestinationAmount: 2000000000,
+		MidPrice:          big.NewRat(5, 4),
+		Offers: []paths.QuoteOffer{
+			{
+				Offer: xdr.OfferEntry{
+					SellerId: seller,
+					OfferId:  1,
+					Selling:  eur,
+					Buying:   usd,
+					Amount:   1000000000,
+					Price:    xdr.Price{N: 5, D: 4},
+				},
+				Amount: 1000000000,
+			},
+			{
+				Offer: xdr.OfferEntry{
+					SellerId: seller,
+					OfferId:  2,
+					Selling:  eur,
+					Buying:   usd,
+					Amount:   2000000000,
+					Price:    xdr.Price{N: 7, D: 4},
+				},
+				Amount: 1000000000,
+			},
+		},
+	}
+
+	finder := paths.MockFinder{}
+	finder.On("Quote", mock.Anything, paths.QuoteQuery{
+		StrictSend:       true,
+		SourceAsset:      usd,
+		DestinationAsset: eur,
+		Amount:           3000000000,
+	}).Return(quote, uint32(1234), nil).Once()
+	finder.On("Quote", mock.Anything, paths.QuoteQuery{
+		StrictSend:       false,
+		SourceAsset:      usd,
+		DestinationAsset: eur,
+		Amount:           2000000000,
+	}).Return(paths.Quote{}, uint32(0), simplepath.ErrQuoteNotFound).Once()
+	defer finder.AssertExpectations(t)
+
+	rh := mockPathFindingClient(tt, &finder, 2, tt.HorizonSession())
+
+	q := make(url.Values)
+	q.Add("source_asset_type", "credit_alphanum4")
+	q.Add("source_asset_code", "USD")
+	q.Add("source_asset_issuer", issuer)
+	q.Add("destination_asset_type", "credit_alphanum4")
+	q.Add("destination_asset_code", "EUR")
+	q.Add("destination_asset_issuer", issuer)
+
+	sendQuery := url.Values{"source_amount": []string{"300"}}
+	for k, v := range q {
+		sendQuery[k] = v
+	}
+	w := rh.Get("/quote/strict-send?" + sendQuery.Encode())
+	assertions.Equal(http.StatusOK, w.Code)
+	assertions.Equal("1234", w.Header().Get(actions.LastLedgerHeaderName))
+
+	var resource horizon.Quote
+	tt.Assert.NoError(json.Unmarshal(w.Body.Bytes(), &resource))
+	tt.Assert.Equal("path_payment_strict_send", resource.OperationType)
+	tt.Assert.Equal("300.0000000", resource.SourceAmount)
+	tt.Assert.Equal("200.0000000", resource.DestinationAmount)
+	tt.Assert.Equal("order_book", resource.Venue)
+	tt.Assert.Equal("1.5000000", resource.AveragePrice)
+	tt.Assert.Equal("1.7500000", resource.ExecutionPrice)
+	tt.Assert.Equal("1.2500000", resource.MidPrice)
+	tt.Assert.Equal("0.2000000", resource.PriceImpact)
+	tt.Assert.Len(resource.Offers, 2)
+	tt.Assert.Equal(int64(2), resource.Offers[1].ID)
+	tt.Assert.Equal("100.0000000", resource.Offers[1].Amount)
+	tt.Assert.Nil(resource.LiquidityPool)
+
+	receiveQuery := url.Values{"destination_amount": []string{"200"}}
+	for k, v := range q {
+		receiveQuery[k] = v
+	}
+	w = rh.Get("/quote/strict-receive?" + receiveQuery.Encode())
+	assertions.Equal(http.StatusNotFound, w.Code)
+
+	// the amount must match the endpoint
+	w = rh.Get("/quote/strict-receive?" + sendQuery.Encode())
+	assertions.Equal(http.StatusBadRequest, w.Code)
+
+	// the assets must be different
+	sendQuery.Set("destination_asset_code", "USD")
+	w = rh.Get("/quote/strict-send?" + sendQuery.Encode())
+	assertions.Equal(http.StatusBadRequest, w.Code)
+}
@@ -437,6 +437,27 @@ func TestTradeActions_Aggregation(t *testing.T) {
 		}
 	}
 
+	//test an arbitrary multiple of a minute, a bucket per two trades
+	history.StrictResolutionFiltering = false
+	q.Set("resolution", strconv.FormatInt(2*minute, 10))
+	w = ht.GetWithParams(aggregationPath, q)
+	if ht.Assert.Equal(200, w.Code) {
+		if ht.Assert.PageOf(numOfTrades/2, w.Body) {
+			ht.UnmarshalPage(w.Body, &records)
+			ht.Assert.Equal(int64(2), records[0].TradeCount)
+			ht.Assert.Equal(int64(2), records[0].OrderbookTradeCount)
+			ht.Assert.Equal(int64(0), records[0].LiquidityPoolTradeCount)
+			ht.Assert.Equal(start+2*minute, records[1].Timestamp)
+		}
+	}
+
+	//test illegal resolution, which isn't a multiple of a minute
+	q.Set("resolution", strconv.FormatInt(minute+1, 10))
+	w = ht.GetWithParams(aggregationPath, q)
+	ht.Assert.Equal(400, w.Code)
+	history.StrictResolutionFiltering = true
+	q.Set("resolution", strconv.FormatInt(minute, 10))
+
 	//test partial range by modifying endTime to be one minute above half range.
 	//half of the results are expected
 	endTime := start + (numOfTrades/2)*minute
@@ -15,6 +15,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/pownieh/stellar_go/clients/stellarcore"
+	"github.com/pownieh/stellar_go/services/horizon/internal/coldstorage"
 	"github.com/pownieh/stellar_go/services/horizon/internal/corestate"
 	"github.com/pownieh/stellar_go/services/horizon/internal/db2/history"
 	"github.com/pownieh/stellar_go/services/horizon/internal/httpx"
@@ -39,6 +40,7 @@ type App struct {
 	webServer       *httpx.Server
 	historyQ        *history.Q
 	primaryHistoryQ *history.Q
+	replicaPool     *db.ReplicaPool
 	ctx             context.Context
 	cancel          func()
 	horizonVersion  string
@@ -63,6 +65,7 @@ func (a *App) GetCoreState() corestate.State {
 
 const tickerMaxFrequency = 1 * time.Second
 const tickerMaxDuration = 5 * time.Second
+const replicaCheckInterval = 1 * time.Second
 
 // NewApp constructs an new App instance from the provided config.
 func NewApp(config Config) (*App, error) {
@@ -166,6 +169,9 @@ func (a *App) waitForDone() {
 	if a.reaper != nil {
 		a.reaper.Shutdown()
 	}
+	if a.replicaPool != nil {
+		a.replicaPool.Close()
+	}
 	a.ticks.Stop()
 }
 
@@ -263,6 +269,16 @@ func (a *App) UpdateHorizonLedgerState(ctx context.Context) {
 		return
 	}
 
+	next.HistoryElderByResource = make(map[ledger.HistoryResource]int32, len(ledger.HistoryResources))
+	for _, resource := range ledger.HistoryResources {
+		elder, err := a.HistoryQ().GetHistoryResourceElder(ctx, string(resource))
+		if err != nil {
+			logErr(err, "failed to load the oldest retained ledger of the history resources from history DB")
+			return
+		}
+		next.HistoryElderByResource[resource] = int32(elder)
+	}
+
 	next.ExpHistoryLatest, err = a.HistoryQ().GetLastLedgerIngestNonBlocking(ctx)
 	if err != nil {
 		logErr(err, "failed to load the oldest known exp ledger state from history DB")
@@ -502,11 +518,23 @@ func (a *App) init() error {
 	}
 	initPathFinder(a)
 
+	// trade aggregations
+	history.StrictResolutionFiltering = !a.config.TradeAggregationsAnyResolution
+
 	// txsub
 	initSubmissionSystem(a)
 
 	// reaper
 	a.reaper = reap.New(a.config.HistoryRetentionCount, a.HorizonSession(), a.ledgerState)
+	a.reaper.RetentionCountByResource = a.config.HistoryRetentionCountByResource
+	a.reaper.MaintainPartitions = a.config.Ingest
+	if a.config.HistoryColdStorageURL != "" {
+		archive, err := coldstorage.Connect(a.config.HistoryColdStorageURL)
+		if err != nil {
+			return errors.Wrap(err, "error connecting to the history cold storage")
+		}
+		a.reaper.ColdStorage = archive
+	}
 
 	// go metrics
 	initGoMetrics(a)
@@ -525,6 +553,7 @@ func (a *App) init() error {
 
 	routerConfig := httpx.RouterConfig{
 		DBSession:                a.historyQ.SessionInterface,
+		ReplicaPool:              a.replicaPool,
 		TxSubmitter:              a.submitter,
 		RateQuota:                a.config.RateQuota,
 		BehindCloudflare:         a.config.BehindCloudflare,
@@ -558,6 +587,21 @@ func (a *App) init() error {
 		routerConfig.PrimaryDBSession = a.primaryHistoryQ.SessionInterface
 	}
 
+	if a.config.TxSubValidation {
+		routerConfig.TxValidator = &txsub.Validator{
+			DB: func(ctx context.Context) txsub.ValidatorDB {
+				return &history.Q{SessionInterface: a.HorizonSession()}
+			},
+			NetworkPassphrase: a.config.NetworkPassphrase,
+		}
+	}
+	if a.config.TxDryRun {
+		routerConfig.TxDryRunner = &txsub.DryRunner{
+			NetworkPassphrase: a.config.NetworkPassphrase,
+			PathFinder:        a.paths,
+		}
+	}
+
 	var err error
 	config := httpx.ServerConfig{
 		Port:      uint16(a.config.Port),
@@ -0,0 +1,137 @@
+package coldstorage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/pownieh/stellar_go/services/horizon/internal/db2/history"
+	"github.com/pownieh/stellar_go/support/errors"
+	"github.com/pownieh/stellar_go/toid"
+)
+
+// ExportRange exports the rows of the given history tables for the ledgers of
+// [startLedger, endLedger] to one Parquet file per table, and records them in
+// the manifest of the range. Exporting a range again overwrites the files of
+// the exported tables, so an interrupted export can be retried.
+func (a *Archive) ExportRange(ctx context.Context, q *history.Q, startLedger, endLedger uint32, tables []string) (Manifest, error) {
+	if startLedger == 0 || startLedger > endLedger {
+		return Manifest{}, errors.Errorf("invalid range [%d, %d]", startLedger, endLedger)
+	}
+	start, end, err := toid.LedgerRangeInclusive(int32(startLedger), int32(endLedger))
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	manifest, _, err := a.GetManifest(startLedger, endLedger)
+	if err != nil {
+		return Manifest{}, err
+	}
+	manifest.StartLedger = startLedger
+	manifest.EndLedger = endLedger
+	manifest.ExportedAt = time.Now().UTC()
+
+	for _, table := range tables {
+		file, err := a.exportTable(ctx, q, table, startLedger, endLedger, start, end)
+		if err != nil {
+			return Manifest{}, errors.Wrapf(err, "could not export %s", table)
+		}
+		manifest.setTable(file)
+	}
+
+	// the manifest is written last so that it only references complete files
+	if err = a.putManifest(manifest); err != nil {
+		return Manifest{}, errors.Wrap(err, "could not write manifest")
+	}
+	return manifest, nil
+}
+
+func (a *Archive) exportTable(ctx context.Context, q *history.Q, table string, startLedger, endLedger uint32, start, end int64) (TableFile, error) {
+	toidColumn, ok := history.HistoryTableToidColumn(table)
+	if !ok {
+		return TableFile{}, errors.Errorf("unknown history table %s", table)
+	}
+
+	// the file is written to a temporary file first because object stores
+	// need the full content to upload it
+	tmp, err := os.CreateTemp("", "horizon-cold-storage-*.parquet")
+	if err != nil {
+		return TableFile{}, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	rows, err := q.QueryHistoryTableRange(ctx, table, start, end)
+	if err != nil {
+		return TableFile{}, err
+	}
+	defer rows.Close()
+
+	hash := sha256.New()
+	file := TableFile{
+		Table:      table,
+		Path:       path.Join(rangeDir(startLedger, endLedger), table+".parquet"),
+		ToidColumn: toidColumn,
+	}
+	metadata := map[string]string{
+		"horizon.table":        table,
+		"horizon.start_ledger": strconv.FormatUint(uint64(startLedger), 10),
+		"horizon.end_ledger":   strconv.FormatUint(uint64(endLedger), 10),
+	}
+	file.Columns, file.Rows, err = writeRows(io.MultiWriter(tmp, hash), table, rows, metadata)
+	if err != nil {
+		return TableFile{}, err
+	}
+
+	if file.Size, err = tmp.Seek(0, io.SeekCurrent); err != nil {
+		return TableFile{}, err
+	}
+	if _, err = tmp.Seek(0, io.SeekStart); err != nil {
+		return TableFile{}, err
+	}
+	file.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	if err = a.backend.PutFile(file.Path, io.NopCloser(tmp)); err != nil {
+		return TableFile{}, err
+	}
+	return file, nil
+}
+
+// writeRows writes the rows as a Parquet file to w and returns the names of
+// the columns and the number of rows written.
+func writeRows(w io.Writer, table string, rows *sqlx.Rows, metadata map[string]string) ([]string, int64, error) {
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, 0, err
+	}
+	columns := make([]column, len(columnTypes))
+	names := make([]string, len(columnTypes))
+	for i, columnType := range columnTypes {
+		columns[i] = column{name: columnType.Name(), kind: columnKindOf(columnType.DatabaseTypeName())}
+		names[i] = columnType.Name()
+	}
+
+	writer := newTableWriter(w, table, columns, metadata)
+	for rows.Next() {
+		values, err := rows.SliceScan()
+		if err != nil {
+			return nil, 0, err
+		}
+		if err = writer.write(values); err != nil {
+			return nil, 0, err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	if err = writer.close(); err != nil {
+		return nil, 0, err
+	}
+	return names, writer.rows, nil
+}
@@ -0,0 +1,188 @@
+// Package coldstorage exports the history reaped from the Horizon database to
+// Parquet files in a local directory or an object store, and reads them back
+// for offline analysis. The files of each exported ledger range are described
+// by a manifest stored next to them.
+package coldstorage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/pownieh/stellar_go/historyarchive"
+	"github.com/pownieh/stellar_go/support/errors"
+)
+
+const manifestFileName = "manifest.json"
+
+// TableFile describes the Parquet file of a history table in a ledger range.
+type TableFile struct {
+	Table string `json:"table"`
+	Path  string `json:"path"`
+	// ToidColumn is the column of the table containing the toid of the rows,
+	// which contains their ledger.
+	ToidColumn string   `json:"toid_column"`
+	Columns    []string `json:"columns"`
+	Rows       int64    `json:"rows"`
+	Size       int64    `json:"size"`
+	SHA256     string   `json:"sha256"`
+}
+
+// Manifest describes the files exported for a ledger range.
+type Manifest struct {
+	StartLedger uint32      `json:"start_ledger"`
+	EndLedger   uint32      `json:"end_ledger"`
+	ExportedAt  time.Time   `json:"exported_at"`
+	Tables      []TableFile `json:"tables"`
+}
+
+// Table returns the file of table in the range, if it was exported.
+func (m Manifest) Table(table string) (TableFile, bool) {
+	for _, file := range m.Tables {
+		if file.Table == table {
+			return file, true
+		}
+	}
+	return TableFile{}, false
+}
+
+func (m *Manifest) setTable(file TableFile) {
+	for i := range m.Tables {
+		if m.Tables[i].Table == file.Table {
+			m.Tables[i] = file
+			return
+		}
+	}
+	m.Tables = append(m.Tables, file)
+	sort.Slice(m.Tables, func(i, j int) bool {
+		return m.Tables[i].Table < m.Tables[j].Table
+	})
+}
+
+// Archive is a cold storage archive of history.
+type Archive struct {
+	backend historyarchive.ArchiveBackend
+}
+
+// Connect connects to the archive at the given URL, e.g.
+// file:///var/lib/horizon/cold or s3://bucket/prefix.
+func Connect(url string) (*Archive, error) {
+	backend, err := historyarchive.ConnectBackend(url, historyarchive.ConnectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return NewArchive(backend), nil
+}
+
+// NewArchive returns an archive storing its files in backend.
+func NewArchive(backend historyarchive.ArchiveBackend) *Archive {
+	return &Archive{backend: backend}
+}
+
+func rangeDir(startLedger, endLedger uint32) string {
+	return fmt.Sprintf("%010d-%010d", startLedger, endLedger)
+}
+
+// GetManifest returns the manifest of the ledger range, if it was exported.
+func (a *Archive) GetManifest(startLedger, endLedger uint32) (Manifest, bool, error) {
+	return a.getManifest(path.Join(rangeDir(startLedger, endLedger), manifestFileName))
+}
+
+func (a *Archive) getManifest(pth string) (Manifest, bool, error) {
+	exists, err := a.backend.Exists(pth)
+	if err != nil || !exists {
+		return Manifest{}, false, err
+	}
+	r, err := a.backend.GetFile(pth)
+	if err != nil {
+		return Manifest{}, false, err
+	}
+	defer r.Close()
+
+	var manifest Manifest
+	if err = json.NewDecoder(r).Decode(&manifest); err != nil {
+		return Manifest{}, false, errors.Wrapf(err, "invalid manifest %s", pth)
+	}
+	return manifest, true, nil
+}
+
+func (a *Archive) putManifest(manifest Manifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	pth := path.Join(rangeDir(manifest.StartLedger, manifest.EndLedger), manifestFileName)
+	return a.backend.PutFile(pth, io.NopCloser(bytes.NewReader(data)))
+}
+
+// ListManifests returns the manifests of all the exported ledger ranges,
+// ordered by ledger.
+func (a *Archive) ListManifests() ([]Manifest, error) {
+	if !a.backend.CanListFiles() {
+		return nil, errors.New("the archive backend cannot list files")
+	}
+
+	files, errs := a.backend.ListFiles("")
+	var manifests []Manifest
+	for files != nil || errs != nil {
+		select {
+		case pth, ok := <-files:
+			if !ok {
+				files = nil
+				continue
+			}
+			// the listed paths include the prefix of the backend
+			if path.Base(pth) != manifestFileName {
+				continue
+			}
+			dir := path.Base(path.Dir(pth))
+			manifest, exists, err := a.getManifest(path.Join(dir, manifestFileName))
+			if err != nil {
+				return nil, err
+			}
+			if exists {
+				manifests = append(manifests, manifest)
+			}
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	sort.Slice(manifests, func(i, j int) bool {
+		return manifests[i].StartLedger < manifests[j].StartLedger
+	})
+	return manifests, nil
+}
+
+// ExportedLedger returns the last ledger up to which all the given tables were
+// exported, or 0 if any of them was never exported.
+func (a *Archive) ExportedLedger(tables []string) (uint32, error) {
+	manifests, err := a.ListManifests()
+	if err != nil {
+		return 0, err
+	}
+
+	var exported uint32
+	for i, table := range tables {
+		var tableExported uint32
+		for _, manifest := range manifests {
+			if _, ok := manifest.Table(table); ok && manifest.EndLedger > tableExported {
+				tableExported = manifest.EndLedger
+			}
+		}
+		if i == 0 || tableExported < exported {
+			exported = tableExported
+		}
+	}
+	return exported, nil
+}
@@ -0,0 +1,119 @@
+package coldstorage
+
+import (
+	"bytes"
+	"io"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pownieh/stellar_go/historyarchive"
+	"github.com/pownieh/stellar_go/toid"
+)
+
+var testColumns = []column{
+	{name: "id", kind: int64Column},
+	{name: "successful", kind: boolColumn},
+	{name: "details", kind: stringColumn},
+	{name: "created_at", kind: timestampColumn},
+	{name: "amount", kind: doubleColumn},
+}
+
+func writeTestFile(t *testing.T, archive *Archive, startLedger, endLedger uint32, rows [][]interface{}) {
+	var buf bytes.Buffer
+	writer := newTableWriter(&buf, "history_operations", testColumns, map[string]string{"horizon.table": "history_operations"})
+	for _, row := range rows {
+		require.NoError(t, writer.write(row))
+	}
+	require.NoError(t, writer.close())
+
+	file := TableFile{
+		Table:      "history_operations",
+		Path:       path.Join(rangeDir(startLedger, endLedger), "history_operations.parquet"),
+		ToidColumn: "id",
+		Rows:       writer.rows,
+	}
+	require.NoError(t, archive.backend.PutFile(file.Path, io.NopCloser(bytes.NewReader(buf.Bytes()))))
+
+	manifest, _, err := archive.GetManifest(startLedger, endLedger)
+	require.NoError(t, err)
+	manifest.StartLedger = startLedger
+	manifest.EndLedger = endLedger
+	manifest.setTable(file)
+	require.NoError(t, archive.putManifest(manifest))
+}
+
+func TestParquetRoundTrip(t *testing.T) {
+	createdAt := time.Date(2023, 11, 2, 10, 30, 0, 123000, time.UTC)
+	var buf bytes.Buffer
+	writer := newTableWriter(&buf, "history_operations", testColumns, nil)
+	require.NoError(t, writer.write([]interface{}{int64(12), true, []byte(`{"a":1}`), createdAt, 1.5}))
+	require.NoError(t, writer.write([]interface{}{int64(13), nil, "text", nil, nil}))
+	assert.EqualError(t, writer.write([]interface{}{"14", nil, nil, nil, nil}), "unexpected value string for column id")
+	assert.EqualError(t, writer.write([]interface{}{int64(14)}), "expected 5 values, got 1")
+	require.NoError(t, writer.close())
+
+	var rows []Row
+	err := readRows(bytes.NewReader(buf.Bytes()), int64(buf.Len()), func(row Row) error {
+		rows = append(rows, row)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []Row{
+		{"id": int64(12), "successful": true, "details": `{"a":1}`, "created_at": createdAt, "amount": 1.5},
+		{"id": int64(13), "successful": nil, "details": "text", "created_at": nil, "amount": nil},
+	}, rows)
+}
+
+func TestColumnKindOf(t *testing.T) {
+	assert.Equal(t, int64Column, columnKindOf("INT8"))
+	assert.Equal(t, int64Column, columnKindOf("int4"))
+	assert.Equal(t, boolColumn, columnKindOf("BOOL"))
+	assert.Equal(t, timestampColumn, columnKindOf("TIMESTAMP"))
+	assert.Equal(t, stringColumn, columnKindOf("JSONB"))
+	assert.Equal(t, stringColumn, columnKindOf("_TEXT"))
+}
+
+func TestReadTable(t *testing.T) {
+	backend, err := historyarchive.ConnectBackend("file://"+t.TempDir(), historyarchive.ConnectOptions{})
+	require.NoError(t, err)
+	archive := NewArchive(backend)
+
+	op := func(ledger int32) []interface{} {
+		return []interface{}{toid.New(ledger, 1, 1).ToInt64(), true, nil, nil, nil}
+	}
+	writeTestFile(t, archive, 200, 299, [][]interface{}{op(200), op(250), op(299)})
+	writeTestFile(t, archive, 100, 199, [][]interface{}{op(100), op(150)})
+
+	manifests, err := archive.ListManifests()
+	require.NoError(t, err)
+	require.Len(t, manifests, 2)
+	assert.Equal(t, uint32(100), manifests[0].StartLedger)
+	assert.Equal(t, uint32(200), manifests[1].StartLedger)
+
+	exported, err := archive.ExportedLedger([]string{"history_operations"})
+	require.NoError(t, err)
+	assert.Equal(t, uint32(299), exported)
+	exported, err = archive.ExportedLedger([]string{"history_operations", "history_effects"})
+	require.NoError(t, err)
+	assert.Equal(t, uint32(0), exported)
+
+	var ledgers []int32
+	err = archive.ReadTable("history_operations", 150, 250, func(row Row) error {
+		ledgers = append(ledgers, toid.Parse(row["id"].(int64)).LedgerSequence)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int32{150, 200, 250}, ledgers)
+
+	ledgers = nil
+	err = archive.ReadTable("history_effects", 0, 1000, func(row Row) error {
+		ledgers = append(ledgers, 0)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Empty(t, ledgers)
+}
@@ -0,0 +1,222 @@
+package coldstorage
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+
+	"github.com/pownieh/stellar_go/support/errors"
+)
+
+// columnKind is the Parquet representation of a Postgres column. Types
+// without a natural Parquet representation, e.g. jsonb, numeric or arrays,
+// are stored as their text representation.
+type columnKind int
+
+const (
+	stringColumn columnKind = iota
+	int64Column
+	boolColumn
+	doubleColumn
+	timestampColumn
+)
+
+// columnKindOf returns the kind of a column given its Postgres type name, as
+// reported by the database driver.
+func columnKindOf(databaseTypeName string) columnKind {
+	switch strings.ToUpper(databaseTypeName) {
+	case "INT2", "INT4", "INT8":
+		return int64Column
+	case "BOOL":
+		return boolColumn
+	case "FLOAT4", "FLOAT8":
+		return doubleColumn
+	case "TIMESTAMP", "TIMESTAMPTZ":
+		return timestampColumn
+	default:
+		return stringColumn
+	}
+}
+
+type column struct {
+	name string
+	kind columnKind
+}
+
+func (c column) node() parquet.Node {
+	var node parquet.Node
+	switch c.kind {
+	case int64Column:
+		node = parquet.Int(64)
+	case boolColumn:
+		node = parquet.Leaf(parquet.BooleanType)
+	case doubleColumn:
+		node = parquet.Leaf(parquet.DoubleType)
+	case timestampColumn:
+		node = parquet.Timestamp(parquet.Microsecond)
+	default:
+		node = parquet.String()
+	}
+	// all the columns are optional, the Postgres schema may change
+	return parquet.Optional(node)
+}
+
+// value converts a value scanned from Postgres to a Parquet value.
+func (c column) value(v interface{}) (parquet.Value, error) {
+	if v == nil {
+		return parquet.Value{}, nil
+	}
+	switch c.kind {
+	case int64Column:
+		if i, ok := v.(int64); ok {
+			return parquet.ValueOf(i), nil
+		}
+	case boolColumn:
+		if b, ok := v.(bool); ok {
+			return parquet.ValueOf(b), nil
+		}
+	case doubleColumn:
+		if f, ok := v.(float64); ok {
+			return parquet.ValueOf(f), nil
+		}
+	case timestampColumn:
+		if t, ok := v.(time.Time); ok {
+			return parquet.ValueOf(t.UnixMicro()), nil
+		}
+	default:
+		switch s := v.(type) {
+		case []byte:
+			return parquet.ValueOf(string(s)), nil
+		case string:
+			return parquet.ValueOf(s), nil
+		default:
+			return parquet.ValueOf(fmt.Sprint(s)), nil
+		}
+	}
+	return parquet.Value{}, errors.Errorf("unexpected value %T for column %s", v, c.name)
+}
+
+// tableWriter writes the rows of a history table to a Parquet file.
+type tableWriter struct {
+	columns []column
+	// leafIndexes are the indexes of the columns in the Parquet schema, whose
+	// columns are ordered by name.
+	leafIndexes []int
+	writer      *parquet.Writer
+	rows        int64
+}
+
+func newTableWriter(w io.Writer, table string, columns []column, metadata map[string]string) *tableWriter {
+	group := parquet.Group{}
+	for _, c := range columns {
+		group[c.name] = c.node()
+	}
+	schema := parquet.NewSchema(table, group)
+
+	leafIndex := map[string]int{}
+	for i, pth := range schema.Columns() {
+		leafIndex[pth[0]] = i
+	}
+	leafIndexes := make([]int, len(columns))
+	for i, c := range columns {
+		leafIndexes[i] = leafIndex[c.name]
+	}
+
+	options := []parquet.WriterOption{schema, parquet.Compression(&parquet.Zstd)}
+	for key, value := range metadata {
+		options = append(options, parquet.KeyValueMetadata(key, value))
+	}
+
+	return &tableWriter{
+		columns:     columns,
+		leafIndexes: leafIndexes,
+		writer:      parquet.NewWriter(w, options...),
+	}
+}
+
+func (t *tableWriter) write(values []interface{}) error {
+	if len(values) != len(t.columns) {
+		return errors.Errorf("expected %d values, got %d", len(t.columns), len(values))
+	}
+	row := make(parquet.Row, len(values))
+	for i, v := range values {
+		value, err := t.columns[i].value(v)
+		if err != nil {
+			return err
+		}
+		definitionLevel := 1
+		if value.IsNull() {
+			definitionLevel = 0
+		}
+		row[t.leafIndexes[i]] = value.Level(0, definitionLevel, t.leafIndexes[i])
+	}
+	if _, err := t.writer.WriteRows([]parquet.Row{row}); err != nil {
+		return err
+	}
+	t.rows++
+	return nil
+}
+
+func (t *tableWriter) close() error {
+	return t.writer.Close()
+}
+
+// Row is a row of a history table read from the archive, indexed by column.
+// Integers are int64, timestamps are time.Time in UTC and the columns stored
+// as text are strings.
+type Row map[string]interface{}
+
+// readRows calls fn with the rows of the Parquet file.
+func readRows(r io.ReaderAt, size int64, fn func(Row) error) error {
+	file, err := parquet.OpenFile(r, size)
+	if err != nil {
+		return errors.Wrap(err, "could not open parquet file")
+	}
+	fields := file.Schema().Fields()
+
+	reader := parquet.NewReader(file)
+	defer reader.Close()
+
+	rows := make([]parquet.Row, 128)
+	for {
+		n, err := reader.ReadRows(rows)
+		for _, row := range rows[:n] {
+			decoded := make(Row, len(fields))
+			for _, value := range row {
+				field := fields[value.Column()]
+				decoded[field.Name()] = decodeValue(field, value)
+			}
+			if err := fn(decoded); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "could not read parquet file")
+		}
+	}
+}
+
+func decodeValue(field parquet.Field, value parquet.Value) interface{} {
+	if value.IsNull() {
+		return nil
+	}
+	switch value.Kind() {
+	case parquet.Boolean:
+		return value.Boolean()
+	case parquet.Int64:
+		if logical := field.Type().LogicalType(); logical != nil && logical.Timestamp != nil {
+			return time.UnixMicro(value.Int64()).UTC()
+		}
+		return value.Int64()
+	case parquet.Double:
+		return value.Double()
+	default:
+		return string(value.ByteArray())
+	}
+}
@@ -0,0 +1,56 @@
+package coldstorage
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/pownieh/stellar_go/support/errors"
+	"github.com/pownieh/stellar_go/toid"
+)
+
+// ReadTable calls fn with the archived rows of a history table for the
+// ledgers of [fromLedger, toLedger], ordered by ledger.
+func (a *Archive) ReadTable(table string, fromLedger, toLedger uint32, fn func(Row) error) error {
+	manifests, err := a.ListManifests()
+	if err != nil {
+		return err
+	}
+
+	for _, manifest := range manifests {
+		if manifest.EndLedger < fromLedger || manifest.StartLedger > toLedger {
+			continue
+		}
+		file, ok := manifest.Table(table)
+		if !ok {
+			continue
+		}
+		err = a.readFile(file, func(row Row) error {
+			if id, ok := row[file.ToidColumn].(int64); ok {
+				ledger := uint32(toid.Parse(id).LedgerSequence)
+				if ledger < fromLedger || ledger > toLedger {
+					return nil
+				}
+			}
+			return fn(row)
+		})
+		if err != nil {
+			return errors.Wrapf(err, "could not read %s", file.Path)
+		}
+	}
+	return nil
+}
+
+func (a *Archive) readFile(file TableFile, fn func(Row) error) error {
+	r, err := a.backend.GetFile(file.Path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	// Parquet files are read from their footer so they need random access
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return readRows(bytes.NewReader(data), int64(len(data)), fn)
+}
@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"github.com/pownieh/stellar_go/ingest/ledgerbackend"
+	"github.com/pownieh/stellar_go/services/horizon/internal/db2/history"
+	"github.com/pownieh/stellar_go/services/horizon/internal/ledger"
 
 	"github.com/sirupsen/logrus"
 	"github.com/stellar/throttled"
@@ -13,11 +15,12 @@ import (
 // Config is the configuration for horizon.  It gets populated by the
 // app's main function and is provided to NewApp.
 type Config struct {
-	DatabaseURL        string
-	RoDatabaseURL      string
-	HistoryArchiveURLs []string
-	Port               uint
-	AdminPort          uint
+	DatabaseURL         string
+	RoDatabaseURL       string
+	ReplicaDatabaseURLs []string
+	HistoryArchiveURLs  []string
+	Port                uint
+	AdminPort           uint
 
 	EnableIngestionFiltering    bool
 	CaptiveCoreBinaryPath       string
@@ -78,6 +81,12 @@ type Config struct {
 	// determining a "retention duration", each ledger roughly corresponds to 10
 	// seconds of real time.
 	HistoryRetentionCount uint
+	// HistoryRetentionCountByResource overrides HistoryRetentionCount for
+	// some history resources, e.g. to retain trades longer than effects.
+	HistoryRetentionCountByResource map[ledger.HistoryResource]uint
+	// HistoryColdStorageURL is the URL of the archive the history is exported
+	// to, as Parquet files, before being reaped.
+	HistoryColdStorageURL string
 	// StaleThreshold represents the number of ledgers a history database may be
 	// out-of-date by before horizon begins to respond with an error to history
 	// requests.
@@ -93,12 +102,19 @@ type Config struct {
 	// If IngestStateVerificationCheckpointFrequency is set to 2 state verification is run on every second checkpoint,
 	// etc...
 	IngestStateVerificationCheckpointFrequency uint
+	// IngestStateVerificationFullFrequency configures how often state verification reads all the
+	// state tables even when the state checksums match the checkpoint, in checkpoints.
+	IngestStateVerificationFullFrequency uint
 	// IngestStateVerificationTimeout configures a timeout on the state verification routine.
 	// If IngestStateVerificationTimeout is set to 0 the timeout is disabled.
 	IngestStateVerificationTimeout time.Duration
 	// IngestEnableExtendedLogLedgerStats enables extended ledger stats in
 	// logging.
 	IngestEnableExtendedLogLedgerStats bool
+	// OrderBookSnapshotPairs are the trading pairs whose order book depth is
+	// stored by ingestion every OrderBookSnapshotInterval ledgers.
+	OrderBookSnapshotPairs    []history.OrderBookPair
+	OrderBookSnapshotInterval uint
 	// ApplyMigrations will apply pending migrations to the horizon database
 	// before starting the horizon service
 	ApplyMigrations bool
@@ -113,8 +129,30 @@ type Config struct {
 	BehindAWSLoadBalancer bool
 	// RoundingSlippageFilter excludes trades from /trade_aggregations with rounding slippage >x bps
 	RoundingSlippageFilter int
+	// TradeAggregationsAnyResolution allows /trade_aggregations with any
+	// multiple of 1 minute as resolution, instead of the AllowedResolutions.
+	TradeAggregationsAnyResolution bool
 	// Stellar network: 'testnet' or 'pubnet'
 	Network string
 	// DisableTxSub disables transaction submission functionality for Horizon.
 	DisableTxSub bool
+	// TxSubQueue enables the persistent queue of submitted transactions which
+	// are rebroadcast to stellar-core every TxSubQueueRebroadcastInterval
+	// until they are included in a ledger or expire.
+	TxSubQueue                    bool
+	TxSubQueueRebroadcastInterval time.Duration
+	// TxSubValidation validates the submitted transactions against the latest
+	// ingested ledger before submitting them to stellar-core.
+	TxSubValidation bool
+	// TxDryRun enables the /transactions/dry_run endpoint.
+	TxDryRun bool
+}
+
+// HistoryRetention returns the number of ledgers retained for each history
+// resource.
+func (c Config) HistoryRetention() ledger.HistoryRetention {
+	return ledger.HistoryRetention{
+		Count:      c.HistoryRetentionCount,
+		ByResource: c.HistoryRetentionCountByResource,
+	}
 }
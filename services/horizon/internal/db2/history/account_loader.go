@@ -150,7 +150,11 @@ func (a *AccountLoader) Exec(ctx context.Context, session db.SessionInterface) e
 		return err
 	}
 
-	return a.lookupKeys(ctx, q, addresses)
+	if err = a.lookupKeys(ctx, q, addresses); err != nil {
+		return err
+	}
+	assignRecordedIDs(session, addresses, a.ids)
+	return nil
 }
 
 type bulkInsertField struct {
@@ -195,6 +199,21 @@ func bulkInsert(ctx context.Context, q *Q, table string, conflictFields []string
 	return err
 }
 
+// assignRecordedIDs assigns ids to the keys which were not inserted because
+// session is a db.Recorder, so that the rows referring to them can be
+// recorded too.
+func assignRecordedIDs[K comparable](session db.SessionInterface, keys []K, ids map[K]int64) {
+	recorder, ok := session.(db.Recorder)
+	if !ok {
+		return
+	}
+	for _, key := range keys {
+		if _, ok := ids[key]; !ok {
+			ids[key] = recorder.NextID()
+		}
+	}
+}
+
 // AccountLoaderStub is a stub wrapper around AccountLoader which allows
 // you to manually configure the mapping of addresses to history account ids
 type AccountLoaderStub struct {
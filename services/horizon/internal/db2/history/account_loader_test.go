@@ -8,6 +8,7 @@ import (
 
 	"github.com/pownieh/stellar_go/keypair"
 	"github.com/pownieh/stellar_go/services/horizon/internal/test"
+	"github.com/pownieh/stellar_go/support/db"
 )
 
 func TestAccountLoader(t *testing.T) {
@@ -50,3 +51,44 @@ func TestAccountLoader(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), `was not found`)
 }
+
+func TestAccountLoaderRecordingSession(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetHorizonDB(t, tt.HorizonDB)
+	ctx := context.Background()
+
+	existing := keypair.MustRandom().Address()
+	loader := NewAccountLoader()
+	loader.GetFuture(existing)
+	assert.NoError(t, loader.Exec(ctx, tt.HorizonSession()))
+	existingID, err := loader.GetNow(existing)
+	assert.NoError(t, err)
+
+	session := db.NewRecordingSession(tt.HorizonSession())
+	assert.NoError(t, session.Begin(ctx))
+	defer session.Rollback()
+
+	recorded := keypair.MustRandom().Address()
+	loader = NewAccountLoader()
+	loader.GetFuture(existing)
+	loader.GetFuture(recorded)
+	assert.NoError(t, loader.Exec(ctx, session))
+
+	id, err := loader.GetNow(existing)
+	assert.NoError(t, err)
+	assert.Equal(t, existingID, id)
+	id, err = loader.GetNow(recorded)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-1), id)
+
+	writes := session.Writes()
+	if assert.Len(t, writes, 2) {
+		assert.Equal(t, "history_accounts", writes[0].Table)
+		assert.Equal(t, db.RecordedInsert, writes[0].Operation)
+	}
+
+	var account Account
+	err = (&Q{tt.HorizonSession()}).AccountByAddress(ctx, &account, recorded)
+	assert.True(t, tt.HorizonSession().NoRows(err))
+}
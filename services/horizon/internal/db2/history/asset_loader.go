@@ -197,7 +197,11 @@ func (a *AssetLoader) Exec(ctx context.Context, session db.SessionInterface) err
 		return err
 	}
 
-	return a.lookupKeys(ctx, q, keys)
+	if err = a.lookupKeys(ctx, q, keys); err != nil {
+		return err
+	}
+	assignRecordedIDs(session, keys, a.ids)
+	return nil
 }
 
 // AssetLoaderStub is a stub wrapper around AssetLoader which allows
@@ -143,5 +143,9 @@ func (a *ClaimableBalanceLoader) Exec(ctx context.Context, session db.SessionInt
 		return err
 	}
 
-	return a.lookupKeys(ctx, q, ids)
+	if err = a.lookupKeys(ctx, q, ids); err != nil {
+		return err
+	}
+	assignRecordedIDs(session, ids, a.ids)
+	return nil
 }
@@ -92,6 +92,34 @@ func (q *EffectsQ) ForAccount(ctx context.Context, aid string) *EffectsQ {
 	return q
 }
 
+// ForSponsorships filters the query to the sponsorship effects involving a
+// specific account, either as the account of the sponsored entry or as its
+// sponsor, former sponsor or new sponsor.
+func (q *EffectsQ) ForSponsorships(ctx context.Context, aid string) *EffectsQ {
+	var account Account
+	q.Err = q.parent.AccountByAddress(ctx, &account, aid)
+	if q.Err != nil {
+		return q
+	}
+
+	// The type range must match the predicate of the sponsorship_effects_by_*
+	// partial indexes, otherwise the sponsor conditions can't use them.
+	q.sql = q.sql.
+		Where(fmt.Sprintf(
+			"heff.type BETWEEN %d AND %d",
+			EffectAccountSponsorshipCreated,
+			EffectSignerSponsorshipRemoved,
+		)).
+		Where(`(
+			heff.history_account_id = ? OR
+			heff.details->>'sponsor' = ? OR
+			heff.details->>'former_sponsor' = ? OR
+			heff.details->>'new_sponsor' = ?
+		)`, account.ID, aid, aid, aid)
+
+	return q
+}
+
 // ForLedger filters the query to only effects in a specific ledger,
 // specified by its sequence.
 func (q *EffectsQ) ForLedger(ctx context.Context, seq int32) *EffectsQ {
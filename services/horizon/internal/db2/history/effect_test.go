@@ -182,3 +182,74 @@ func TestEffectsForTrustlinesSponsorshipEmptyAssetType(t *testing.T) {
 		}
 	}
 }
+
+func TestEffectsForSponsorships(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetHorizonDB(t, tt.HorizonDB)
+	q := &Q{tt.HorizonSession()}
+	tt.Assert.NoError(q.Begin(tt.Ctx))
+
+	sponsor := "GDMQUXK7ZUCWM5472ZU3YLDP4BMJLQQ76DEMNYDEY2ODEEGGRKLEWGW2"
+	newSponsor := "GAUJETIZVEP2NRYLUESJ3LS66NVCEGMON4UDCBCSBEVPIID773P2W6AY"
+	sponsored := "GAQAA5L65LSYH7CQ3VTJ7F3HHLGCL3DSLAR2Y47263D56MNNGHSQSTVY"
+	accountLoader := NewAccountLoader()
+
+	builder := q.NewEffectBatchInsertBuilder()
+	sequence := int32(56)
+	rows := []struct {
+		account    string
+		effectType EffectType
+		details    map[string]string
+	}{
+		{sponsor, EffectAccountDebited, map[string]string{"amount": "1.0000000", "asset_type": "native"}},
+		{sponsored, EffectAccountSponsorshipCreated, map[string]string{"sponsor": sponsor}},
+		{sponsored, EffectAccountCredited, map[string]string{"amount": "1.0000000", "asset_type": "native"}},
+		{sponsored, EffectTrustlineSponsorshipUpdated, map[string]string{
+			"asset":          "USD:GAUJETIZVEP2NRYLUESJ3LS66NVCEGMON4UDCBCSBEVPIID773P2W6AY",
+			"former_sponsor": sponsor,
+			"new_sponsor":    newSponsor,
+		}},
+		{newSponsor, EffectSignerSponsorshipCreated, map[string]string{"signer": sponsored, "sponsor": sponsored}},
+	}
+	for i, row := range rows {
+		details, err := json.Marshal(row.details)
+		tt.Assert.NoError(err)
+		tt.Assert.NoError(builder.Add(
+			accountLoader.GetFuture(row.account),
+			null.String{},
+			toid.New(sequence, 1, int32(i+1)).ToInt64(),
+			1,
+			row.effectType,
+			details,
+		))
+	}
+
+	tt.Assert.NoError(accountLoader.Exec(tt.Ctx, q))
+	tt.Assert.NoError(builder.Exec(tt.Ctx, q))
+	tt.Assert.NoError(q.Commit())
+
+	page := db2.PageQuery{Cursor: "0-0", Order: "asc", Limit: 10}
+	for _, testCase := range []struct {
+		account string
+		types   []EffectType
+	}{
+		{sponsor, []EffectType{EffectAccountSponsorshipCreated, EffectTrustlineSponsorshipUpdated}},
+		{newSponsor, []EffectType{EffectTrustlineSponsorshipUpdated, EffectSignerSponsorshipCreated}},
+		{sponsored, []EffectType{
+			EffectAccountSponsorshipCreated,
+			EffectTrustlineSponsorshipUpdated,
+			EffectSignerSponsorshipCreated,
+		}},
+	} {
+		var result []Effect
+		err := q.Effects().ForSponsorships(tt.Ctx, testCase.account).Page(page).Select(tt.Ctx, &result)
+		tt.Assert.NoError(err)
+
+		var types []EffectType
+		for _, effect := range result {
+			types = append(types, effect.Type)
+		}
+		tt.Assert.Equal(testCase.types, types)
+	}
+}
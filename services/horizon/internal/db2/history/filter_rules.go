@@ -3,17 +3,23 @@ package history
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/lib/pq"
+
+	"github.com/pownieh/stellar_go/support/errors"
 )
 
 const (
-	assetFilterRulesTableName   = "asset_filter_rules"
-	accountFilterRulesTableName = "account_filter_rules"
-	whitelistColumnName         = "whitelist"
-	enabledColumnName           = "enabled"
-	lastModifiedColumnName      = "last_modified"
+	assetFilterRulesTableName     = "asset_filter_rules"
+	accountFilterRulesTableName   = "account_filter_rules"
+	ingestionFilterRulesTableName = "ingestion_filter_rules"
+	whitelistColumnName           = "whitelist"
+	rulesColumnName               = "rules"
+	enabledColumnName             = "enabled"
+	lastModifiedColumnName        = "last_modified"
 )
 
 type AssetFilterConfig struct {
@@ -28,11 +34,74 @@ type AccountFilterConfig struct {
 	LastModified int64          `db:"last_modified"`
 }
 
+// RulesFilterConfig is the configuration of the ingestion filter rule engine.
+type RulesFilterConfig struct {
+	Enabled      bool        `db:"enabled"`
+	Rules        FilterRules `db:"rules"`
+	LastModified int64       `db:"last_modified"`
+}
+
+// FilterRules lists the rules of the ingestion filter rule engine. A
+// transaction is ingested if it matches any of the Allow rules, or if there
+// are none, and it matches none of the Deny rules.
+type FilterRules struct {
+	Allow []FilterRule `json:"allow"`
+	Deny  []FilterRule `json:"deny"`
+}
+
+// FilterRule matches transactions. All the conditions set in a rule must
+// match for the rule to match, conditions which are not set are ignored.
+type FilterRule struct {
+	// And matches if all of its rules match.
+	And []FilterRule `json:"and,omitempty"`
+	// Or matches if any of its rules matches.
+	Or []FilterRule `json:"or,omitempty"`
+	// Not matches if its rule does not match.
+	Not *FilterRule `json:"not,omitempty"`
+	// OperationTypes matches transactions with an operation of one of the
+	// given types, named like the type field of operation resources, e.g.
+	// "create_claimable_balance".
+	OperationTypes []string `json:"operation_types,omitempty"`
+	// ContractIDs matches transactions invoking one of the given contracts.
+	ContractIDs []string `json:"contract_ids,omitempty"`
+	// SourceAccounts matches transactions whose source account, or the
+	// source account of one of its operations, is one of the given accounts.
+	SourceAccounts []string `json:"source_accounts,omitempty"`
+	// MemoPattern matches transactions with a memo matching the given
+	// regular expression. Id memos are matched in decimal, hash and return
+	// memos in hex.
+	MemoPattern string `json:"memo_pattern,omitempty"`
+}
+
+func (r FilterRules) Value() (driver.Value, error) {
+	if r.Allow == nil {
+		r.Allow = []FilterRule{}
+	}
+	if r.Deny == nil {
+		r.Deny = []FilterRule{}
+	}
+	// Convert the byte array into a string, like Claimants.Value does, to
+	// bypass buggy encoding in the pq driver.
+	val, err := json.Marshal(r)
+	return string(val), err
+}
+
+func (r *FilterRules) Scan(value interface{}) error {
+	b, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+
+	return json.Unmarshal(b, r)
+}
+
 type QFilter interface {
 	GetAccountFilterConfig(ctx context.Context) (AccountFilterConfig, error)
 	GetAssetFilterConfig(ctx context.Context) (AssetFilterConfig, error)
+	GetRulesFilterConfig(ctx context.Context) (RulesFilterConfig, error)
 	UpdateAssetFilterConfig(ctx context.Context, config AssetFilterConfig) (AssetFilterConfig, error)
 	UpdateAccountFilterConfig(ctx context.Context, config AccountFilterConfig) (AccountFilterConfig, error)
+	UpdateRulesFilterConfig(ctx context.Context, config RulesFilterConfig) (RulesFilterConfig, error)
 }
 
 func (q *Q) GetAccountFilterConfig(ctx context.Context) (AccountFilterConfig, error) {
@@ -51,6 +120,14 @@ func (q *Q) GetAssetFilterConfig(ctx context.Context) (AssetFilterConfig, error)
 	return filterConfig, err
 }
 
+func (q *Q) GetRulesFilterConfig(ctx context.Context) (RulesFilterConfig, error) {
+	filterConfig := RulesFilterConfig{}
+	sql := sq.Select("*").From(ingestionFilterRulesTableName)
+	err := q.Get(ctx, &filterConfig, sql)
+
+	return filterConfig, err
+}
+
 func (q *Q) UpdateAssetFilterConfig(ctx context.Context, config AssetFilterConfig) (AssetFilterConfig, error) {
 	updateCols := map[string]interface{}{
 		lastModifiedColumnName: sq.Expr(`extract(epoch from now() at time zone 'utc')`),
@@ -91,6 +168,26 @@ func (q *Q) UpdateAccountFilterConfig(ctx context.Context, config AccountFilterC
 	return q.GetAccountFilterConfig(ctx)
 }
 
+func (q *Q) UpdateRulesFilterConfig(ctx context.Context, config RulesFilterConfig) (RulesFilterConfig, error) {
+	updateCols := map[string]interface{}{
+		lastModifiedColumnName: sq.Expr(`extract(epoch from now() at time zone 'utc')`),
+		enabledColumnName:      config.Enabled,
+		rulesColumnName:        config.Rules,
+	}
+
+	sqlUpdate := sq.Update(ingestionFilterRulesTableName).SetMap(updateCols)
+
+	rowCnt, err := q.checkForError(sqlUpdate, ctx)
+	if err != nil {
+		return RulesFilterConfig{}, err
+	}
+
+	if rowCnt < 1 {
+		return RulesFilterConfig{}, sql.ErrNoRows
+	}
+	return q.GetRulesFilterConfig(ctx)
+}
+
 func (q *Q) checkForError(builder sq.Sqlizer, ctx context.Context) (int64, error) {
 	result, err := q.Exec(ctx, builder)
 	if err != nil {
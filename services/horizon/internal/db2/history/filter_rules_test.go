@@ -43,3 +43,31 @@ func TestAccountFilterConfig(t *testing.T) {
 	tt.Assert.Equal(fc1Result.Enabled, true)
 	tt.Assert.ElementsMatch(fc1Result.Whitelist, []string{"1", "2"})
 }
+
+func TestRulesFilterConfig(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetHorizonDB(t, tt.HorizonDB)
+	q := &Q{tt.HorizonSession()}
+
+	fc1Result, err := q.GetRulesFilterConfig(tt.Ctx)
+	assert.NoError(t, err)
+	tt.Assert.Equal(fc1Result.Enabled, false)
+	tt.Assert.Len(fc1Result.Rules.Allow, 0)
+	tt.Assert.Len(fc1Result.Rules.Deny, 0)
+
+	fc1Result.Enabled = true
+	fc1Result.Rules.Allow = []FilterRule{{OperationTypes: []string{"payment"}}}
+	fc1Result.Rules.Deny = []FilterRule{{Not: &FilterRule{MemoPattern: "^id-"}}}
+	fc1Result, err = q.UpdateRulesFilterConfig(tt.Ctx, fc1Result)
+	assert.NoError(t, err)
+
+	fc1Result, err = q.GetRulesFilterConfig(tt.Ctx)
+	assert.NoError(t, err)
+	tt.Assert.Equal(fc1Result.Enabled, true)
+	tt.Assert.True(fc1Result.LastModified > 0)
+	tt.Assert.Equal(FilterRules{
+		Allow: []FilterRule{{OperationTypes: []string{"payment"}}},
+		Deny:  []FilterRule{{Not: &FilterRule{MemoPattern: "^id-"}}},
+	}, fc1Result.Rules)
+}
@@ -2,6 +2,8 @@ package history
 
 import (
 	"context"
+
+	"github.com/pownieh/stellar_go/support/errors"
 )
 
 // TruncateIngestStateTables clears out ingestion state tables.
@@ -10,15 +12,29 @@ import (
 // Any horizon database tables which cannot be populated using
 // history archive snapshots will not be truncated.
 func (q *Q) TruncateIngestStateTables(ctx context.Context) error {
-	return q.TruncateTables(ctx, []string{
-		"accounts",
-		"accounts_data",
-		"accounts_signers",
-		"claimable_balances",
-		"claimable_balance_claimants",
-		"exp_asset_stats",
-		"liquidity_pools",
-		"offers",
-		"trust_lines",
-	})
+	return q.TruncateTables(ctx, StateTables)
+}
+
+// Savepoint creates a savepoint in the current transaction. The statements
+// executed after it can be rolled back with RollbackToSavepoint without
+// aborting the transaction.
+func (q *Q) Savepoint(ctx context.Context, name string) error {
+	if tx := q.GetTx(); tx == nil {
+		return errors.New("cannot be called outside of a transaction")
+	}
+	_, err := q.ExecRaw(ctx, "SAVEPOINT "+name)
+	return err
+}
+
+// RollbackToSavepoint rolls back the statements executed after the savepoint.
+func (q *Q) RollbackToSavepoint(ctx context.Context, name string) error {
+	_, err := q.ExecRaw(ctx, "ROLLBACK TO SAVEPOINT "+name)
+	return err
+}
+
+// ReleaseSavepoint destroys the savepoint, keeping the effects of the
+// statements executed after it.
+func (q *Q) ReleaseSavepoint(ctx context.Context, name string) error {
+	_, err := q.ExecRaw(ctx, "RELEASE SAVEPOINT "+name)
+	return err
 }
@@ -18,6 +18,9 @@ const (
 	stateInvalid                    = "exp_state_invalid"
 	offerCompactionSequence         = "offer_compaction_sequence"
 	liquidityPoolCompactionSequence = "liquidity_pool_compaction_sequence"
+	// historyResourceElderPrefix is followed by the name of a history
+	// resource.
+	historyResourceElderPrefix = "history_resource_elder_"
 )
 
 // GetLastLedgerIngestNonBlocking works like GetLastLedgerIngest but
@@ -181,6 +184,26 @@ func (q *Q) UpdateLiquidityPoolCompactionSequence(ctx context.Context, sequence
 	)
 }
 
+// GetHistoryResourceElder returns the oldest ledger retained for a history
+// resource as recorded by the reaper, or 0 if the resource was never reaped.
+func (q *Q) GetHistoryResourceElder(ctx context.Context, resource string) (uint32, error) {
+	parsed, err := q.getIntValueFromStore(ctx, historyResourceElderPrefix+resource, 32)
+	if err != nil {
+		return 0, errors.Wrap(err, "Error converting sequence value")
+	}
+	return uint32(parsed), nil
+}
+
+// UpdateHistoryResourceElder records the oldest ledger retained for a history
+// resource once the ledgers before it were reaped.
+func (q *Q) UpdateHistoryResourceElder(ctx context.Context, resource string, elder uint32) error {
+	return q.updateValueInStore(
+		ctx,
+		historyResourceElderPrefix+resource,
+		strconv.FormatUint(uint64(elder), 10),
+	)
+}
+
 // getValueFromStore returns a value for a given key from KV store. If value
 // is not present in the key value store "" will be returned.
 func (q *Q) getValueFromStore(ctx context.Context, key string, forUpdate bool) (string, error) {
@@ -0,0 +1,204 @@
+package history
+
+import (
+	"context"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/guregu/null"
+
+	"github.com/pownieh/stellar_go/services/horizon/internal/db2"
+	"github.com/pownieh/stellar_go/support/db"
+	"github.com/pownieh/stellar_go/toid"
+)
+
+const ledgerFeeStatsTable = "history_ledger_fee_stats"
+
+// QLedgerFeeStats defines the ledger fee stats related queries used by
+// ingestion.
+type QLedgerFeeStats interface {
+	NewLedgerFeeStatsBatchInsertBuilder() LedgerFeeStatsBatchInsertBuilder
+}
+
+// FeeDistribution summarizes the fees per operation of the transactions of a
+// ledger.
+type FeeDistribution struct {
+	Min int64
+	P10 int64
+	P50 int64
+	P90 int64
+	P99 int64
+	Max int64
+}
+
+// LedgerFeeStats are the fee distributions of the transactions included in a
+// ledger. The classic and Soroban transactions are accounted separately since
+// they are surge priced in different lanes.
+type LedgerFeeStats struct {
+	LedgerSequence uint32
+	// TransactionCount is the number of classic transactions, FeeCharged and
+	// MaxFee are only set when it isn't 0.
+	TransactionCount int32
+	FeeCharged       FeeDistribution
+	MaxFee           FeeDistribution
+	// SorobanTransactionCount is the number of Soroban transactions,
+	// SorobanFeeCharged and SorobanRefundableFee are only set when it isn't
+	// 0. The Soroban fees are per transaction since they mostly pay for the
+	// resources used by the transaction.
+	SorobanTransactionCount int32
+	SorobanFeeCharged       FeeDistribution
+	SorobanRefundableFee    FeeDistribution
+}
+
+// LedgerFeeStatsBatchInsertBuilder is used to insert ledger fee stats into the
+// history_ledger_fee_stats table
+type LedgerFeeStatsBatchInsertBuilder interface {
+	Add(stats LedgerFeeStats) error
+	Exec(ctx context.Context, session db.SessionInterface) error
+}
+
+type ledgerFeeStatsBatchInsertBuilder struct {
+	table   string
+	builder db.FastBatchInsertBuilder
+}
+
+// NewLedgerFeeStatsBatchInsertBuilder constructs a new
+// LedgerFeeStatsBatchInsertBuilder instance
+func (q *Q) NewLedgerFeeStatsBatchInsertBuilder() LedgerFeeStatsBatchInsertBuilder {
+	return &ledgerFeeStatsBatchInsertBuilder{
+		table:   ledgerFeeStatsTable,
+		builder: db.FastBatchInsertBuilder{},
+	}
+}
+
+// Add adds the fee stats of a ledger to the batch
+func (i *ledgerFeeStatsBatchInsertBuilder) Add(stats LedgerFeeStats) error {
+	row := map[string]interface{}{
+		"ledger_toid":               toid.New(int32(stats.LedgerSequence), 0, 0).ToInt64(),
+		"transaction_count":         stats.TransactionCount,
+		"soroban_transaction_count": stats.SorobanTransactionCount,
+	}
+	addFeeDistribution(row, "fee_charged", stats.FeeCharged, stats.TransactionCount > 0)
+	addFeeDistribution(row, "max_fee", stats.MaxFee, stats.TransactionCount > 0)
+	addFeeDistribution(row, "soroban_fee_charged", stats.SorobanFeeCharged, stats.SorobanTransactionCount > 0)
+	addFeeDistribution(row, "soroban_refundable_fee", stats.SorobanRefundableFee, stats.SorobanTransactionCount > 0)
+	return i.builder.Row(row)
+}
+
+func addFeeDistribution(row map[string]interface{}, prefix string, distribution FeeDistribution, valid bool) {
+	values := map[string]int64{
+		"min": distribution.Min,
+		"p10": distribution.P10,
+		"p50": distribution.P50,
+		"p90": distribution.P90,
+		"p99": distribution.P99,
+		"max": distribution.Max,
+	}
+	for name, value := range values {
+		row[prefix+"_"+name] = null.NewInt(value, valid)
+	}
+}
+
+// Exec flushes all pending ledger fee stats to the db
+func (i *ledgerFeeStatsBatchInsertBuilder) Exec(ctx context.Context, session db.SessionInterface) error {
+	return i.builder.Exec(ctx, session, i.table)
+}
+
+// LedgerFeeStatsRow is a ledger joined with its fee stats. The fee columns
+// are null when the ledger has no transaction of the kind.
+type LedgerFeeStatsRow struct {
+	TotalOrderID
+	Sequence                int32     `db:"sequence"`
+	ClosedAt                time.Time `db:"closed_at"`
+	BaseFee                 int32     `db:"base_fee"`
+	MaxTxSetSize            int32     `db:"max_tx_set_size"`
+	TxSetOperationCount     *int32    `db:"tx_set_operation_count"`
+	TransactionCount        int32     `db:"transaction_count"`
+	FeeChargedMin           null.Int  `db:"fee_charged_min"`
+	FeeChargedP10           null.Int  `db:"fee_charged_p10"`
+	FeeChargedP50           null.Int  `db:"fee_charged_p50"`
+	FeeChargedP90           null.Int  `db:"fee_charged_p90"`
+	FeeChargedP99           null.Int  `db:"fee_charged_p99"`
+	FeeChargedMax           null.Int  `db:"fee_charged_max"`
+	MaxFeeMin               null.Int  `db:"max_fee_min"`
+	MaxFeeP10               null.Int  `db:"max_fee_p10"`
+	MaxFeeP50               null.Int  `db:"max_fee_p50"`
+	MaxFeeP90               null.Int  `db:"max_fee_p90"`
+	MaxFeeP99               null.Int  `db:"max_fee_p99"`
+	MaxFeeMax               null.Int  `db:"max_fee_max"`
+	SorobanTransactionCount int32     `db:"soroban_transaction_count"`
+	SorobanFeeChargedMin    null.Int  `db:"soroban_fee_charged_min"`
+	SorobanFeeChargedP10    null.Int  `db:"soroban_fee_charged_p10"`
+	SorobanFeeChargedP50    null.Int  `db:"soroban_fee_charged_p50"`
+	SorobanFeeChargedP90    null.Int  `db:"soroban_fee_charged_p90"`
+	SorobanFeeChargedP99    null.Int  `db:"soroban_fee_charged_p99"`
+	SorobanFeeChargedMax    null.Int  `db:"soroban_fee_charged_max"`
+	SorobanRefundableFeeMin null.Int  `db:"soroban_refundable_fee_min"`
+	SorobanRefundableFeeP10 null.Int  `db:"soroban_refundable_fee_p10"`
+	SorobanRefundableFeeP50 null.Int  `db:"soroban_refundable_fee_p50"`
+	SorobanRefundableFeeP90 null.Int  `db:"soroban_refundable_fee_p90"`
+	SorobanRefundableFeeP99 null.Int  `db:"soroban_refundable_fee_p99"`
+	SorobanRefundableFeeMax null.Int  `db:"soroban_refundable_fee_max"`
+}
+
+var selectLedgerFeeStats = sq.Select(
+	"hl.id",
+	"hl.sequence",
+	"hl.closed_at",
+	"hl.base_fee",
+	"hl.max_tx_set_size",
+	"hl.tx_set_operation_count",
+	"COALESCE(fs.transaction_count, 0) AS transaction_count",
+	"fs.fee_charged_min",
+	"fs.fee_charged_p10",
+	"fs.fee_charged_p50",
+	"fs.fee_charged_p90",
+	"fs.fee_charged_p99",
+	"fs.fee_charged_max",
+	"fs.max_fee_min",
+	"fs.max_fee_p10",
+	"fs.max_fee_p50",
+	"fs.max_fee_p90",
+	"fs.max_fee_p99",
+	"fs.max_fee_max",
+	"COALESCE(fs.soroban_transaction_count, 0) AS soroban_transaction_count",
+	"fs.soroban_fee_charged_min",
+	"fs.soroban_fee_charged_p10",
+	"fs.soroban_fee_charged_p50",
+	"fs.soroban_fee_charged_p90",
+	"fs.soroban_fee_charged_p99",
+	"fs.soroban_fee_charged_max",
+	"fs.soroban_refundable_fee_min",
+	"fs.soroban_refundable_fee_p10",
+	"fs.soroban_refundable_fee_p50",
+	"fs.soroban_refundable_fee_p90",
+	"fs.soroban_refundable_fee_p99",
+	"fs.soroban_refundable_fee_max",
+).From("history_ledgers hl").
+	LeftJoin(ledgerFeeStatsTable + " fs ON fs.ledger_toid = hl.id")
+
+// LedgerFeeStats returns a page of ledgers with their fee stats, the ledgers
+// without transactions are included.
+func (q *Q) LedgerFeeStats(ctx context.Context, page db2.PageQuery) ([]LedgerFeeStatsRow, error) {
+	sql, err := page.ApplyTo(selectLedgerFeeStats, "hl.id")
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []LedgerFeeStatsRow
+	err = q.Select(ctx, &rows, sql)
+	return rows, err
+}
+
+// LatestLedgerFeeStats returns the fee stats of the given number of ledgers
+// up to currentSeq, the latest ledger first.
+func (q *Q) LatestLedgerFeeStats(ctx context.Context, currentSeq int32, ledgers int32) ([]LedgerFeeStatsRow, error) {
+	sql := selectLedgerFeeStats.
+		Where(sq.LtOrEq{"hl.sequence": currentSeq}).
+		Where(sq.Gt{"hl.sequence": currentSeq - ledgers}).
+		OrderBy("hl.sequence DESC")
+
+	var rows []LedgerFeeStatsRow
+	err := q.Select(ctx, &rows, sql)
+	return rows, err
+}
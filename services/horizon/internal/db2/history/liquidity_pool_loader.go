@@ -143,7 +143,11 @@ func (a *LiquidityPoolLoader) Exec(ctx context.Context, session db.SessionInterf
 		return err
 	}
 
-	return a.lookupKeys(ctx, q, ids)
+	if err = a.lookupKeys(ctx, q, ids); err != nil {
+		return err
+	}
+	assignRecordedIDs(session, ids, a.ids)
+	return nil
 }
 
 // LiquidityPoolLoaderStub is a stub wrapper around LiquidityPoolLoader which allows
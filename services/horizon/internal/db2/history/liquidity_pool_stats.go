@@ -0,0 +1,265 @@
+package history
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/pownieh/stellar_go/services/horizon/internal/db2"
+	"github.com/pownieh/stellar_go/support/db"
+	"github.com/pownieh/stellar_go/support/errors"
+	strtime "github.com/pownieh/stellar_go/support/time"
+	"github.com/pownieh/stellar_go/toid"
+)
+
+// LiquidityPoolStatsTableName is the table of the hourly liquidity pool stats
+// buckets, the stats of lower resolutions are aggregated from it.
+const LiquidityPoolStatsTableName = "history_liquidity_pool_stats_3600000"
+
+const liquidityPoolStatsBucketResolution = int64(time.Hour / time.Millisecond)
+
+// AllowedLiquidityPoolStatsResolutions is the set of time windows allowed to
+// be used as the `resolution` of liquidity pool stats.
+var AllowedLiquidityPoolStatsResolutions = map[time.Duration]struct{}{
+	time.Hour:          {}, //1 hour
+	time.Hour * 24:     {}, //day
+	time.Hour * 24 * 7: {}, //week
+}
+
+// QLiquidityPoolStats defines liquidity pool stats related queries.
+type QLiquidityPoolStats interface {
+	NewLiquidityPoolLedgerStatsBatchInsertBuilder() LiquidityPoolLedgerStatsBatchInsertBuilder
+	RebuildLiquidityPoolStatsBuckets(ctx context.Context, fromSeq, toSeq uint32) error
+}
+
+// LiquidityPoolLedgerStats are the trades of a liquidity pool in a ledger and
+// the state of the pool at the end of the ledger. The A and B amounts are in
+// the order of the assets of the pool.
+type LiquidityPoolLedgerStats struct {
+	LiquidityPoolID FutureLiquidityPoolID
+	LedgerSequence  uint32
+	LedgerCloseTime time.Time
+	TradeCount      int32
+	// VolumeA and VolumeB are the amounts of each asset traded with the pool,
+	// they can overflow an int64 so they are numeric strings.
+	VolumeA string
+	VolumeB string
+	// FeesA and FeesB are the fees earned by the pool in each asset.
+	FeesA       string
+	FeesB       string
+	ReserveA    int64
+	ReserveB    int64
+	TotalShares int64
+}
+
+// LiquidityPoolLedgerStatsBatchInsertBuilder is used to insert liquidity pool
+// ledger stats into the history_liquidity_pool_ledger_stats table
+type LiquidityPoolLedgerStatsBatchInsertBuilder interface {
+	Add(stats LiquidityPoolLedgerStats) error
+	Exec(ctx context.Context, session db.SessionInterface) error
+}
+
+type liquidityPoolLedgerStatsBatchInsertBuilder struct {
+	table   string
+	builder db.FastBatchInsertBuilder
+}
+
+// NewLiquidityPoolLedgerStatsBatchInsertBuilder constructs a new
+// LiquidityPoolLedgerStatsBatchInsertBuilder instance
+func (q *Q) NewLiquidityPoolLedgerStatsBatchInsertBuilder() LiquidityPoolLedgerStatsBatchInsertBuilder {
+	return &liquidityPoolLedgerStatsBatchInsertBuilder{
+		table:   "history_liquidity_pool_ledger_stats",
+		builder: db.FastBatchInsertBuilder{},
+	}
+}
+
+// Add adds the stats of a liquidity pool to the batch
+func (i *liquidityPoolLedgerStatsBatchInsertBuilder) Add(stats LiquidityPoolLedgerStats) error {
+	return i.builder.Row(map[string]interface{}{
+		"history_liquidity_pool_id": stats.LiquidityPoolID,
+		"ledger_toid":               toid.New(int32(stats.LedgerSequence), 0, 0).ToInt64(),
+		"ledger_closed_at":          stats.LedgerCloseTime,
+		"trade_count":               stats.TradeCount,
+		"volume_a":                  stats.VolumeA,
+		"volume_b":                  stats.VolumeB,
+		"fees_a":                    stats.FeesA,
+		"fees_b":                    stats.FeesB,
+		"reserve_a":                 stats.ReserveA,
+		"reserve_b":                 stats.ReserveB,
+		"total_shares":              stats.TotalShares,
+	})
+}
+
+// Exec flushes all pending liquidity pool stats to the db
+func (i *liquidityPoolLedgerStatsBatchInsertBuilder) Exec(ctx context.Context, session db.SessionInterface) error {
+	return i.builder.Exec(ctx, session, i.table)
+}
+
+// LiquidityPoolStats are the stats of a liquidity pool over a time window.
+// The reserves and total shares are the ones at the end of the window.
+type LiquidityPoolStats struct {
+	Timestamp   int64  `db:"timestamp"`
+	TradeCount  int64  `db:"trade_count"`
+	VolumeA     string `db:"volume_a"`
+	VolumeB     string `db:"volume_b"`
+	FeesA       string `db:"fees_a"`
+	FeesB       string `db:"fees_b"`
+	ReserveA    int64  `db:"reserve_a"`
+	ReserveB    int64  `db:"reserve_b"`
+	TotalShares int64  `db:"total_shares"`
+}
+
+// LiquidityPoolStatsQuery is the query of the stats of a liquidity pool.
+type LiquidityPoolStatsQuery struct {
+	LiquidityPoolID string
+	// Resolution is the time window of the stats in milliseconds, it must be
+	// one of AllowedLiquidityPoolStatsResolutions.
+	Resolution int64
+	// StartTime is rounded up and EndTime rounded down to the resolution so
+	// only complete windows are returned. EndTime is exclusive.
+	StartTime strtime.Millis
+	EndTime   strtime.Millis
+	PageQuery db2.PageQuery
+}
+
+// GetLiquidityPoolStats returns the stats of a liquidity pool aggregated in
+// windows of the requested resolution. Windows without activity are omitted.
+func (q *Q) GetLiquidityPoolStats(ctx context.Context, query LiquidityPoolStatsQuery) ([]LiquidityPoolStats, error) {
+	resolution := time.Duration(query.Resolution) * time.Millisecond
+	if _, ok := AllowedLiquidityPoolStatsResolutions[resolution]; !ok {
+		return nil, errors.New("resolution is not allowed")
+	}
+
+	buckets := sq.Select(
+		fmt.Sprintf("(s.timestamp / %d) * %d AS timestamp", query.Resolution, query.Resolution),
+		"s.trade_count",
+		"s.volume_a",
+		"s.volume_b",
+		"s.fees_a",
+		"s.fees_b",
+		"s.reserve_a",
+		"s.reserve_b",
+		"s.total_shares",
+	).From(LiquidityPoolStatsTableName + " s").
+		Join("history_liquidity_pools hlp ON hlp.id = s.history_liquidity_pool_id").
+		Where(sq.Eq{"hlp.liquidity_pool_id": query.LiquidityPoolID}).
+		// ensure the reserves of the last bucket of each window are kept
+		OrderBy("s.timestamp ASC")
+	if !query.StartTime.IsNil() {
+		buckets = buckets.Where(sq.GtOrEq{"s.timestamp": query.StartTime.RoundUp(query.Resolution)})
+	}
+	if !query.EndTime.IsNil() {
+		buckets = buckets.Where(sq.Lt{"s.timestamp": query.EndTime.RoundDown(query.Resolution)})
+	}
+
+	sql := sq.Select(
+		"timestamp",
+		"sum(trade_count) as trade_count",
+		"sum(volume_a) as volume_a",
+		"sum(volume_b) as volume_b",
+		"sum(fees_a) as fees_a",
+		"sum(fees_b) as fees_b",
+		"last(reserve_a) as reserve_a",
+		"last(reserve_b) as reserve_b",
+		"last(total_shares) as total_shares",
+	).FromSelect(buckets, "buckets").
+		GroupBy("timestamp").
+		OrderBy("timestamp " + query.PageQuery.Order).
+		Limit(query.PageQuery.Limit)
+
+	var stats []LiquidityPoolStats
+	err := q.Select(ctx, &stats, sql)
+	return stats, err
+}
+
+// RebuildLiquidityPoolStatsBuckets rebuilds the hourly liquidity pool stats
+// buckets of the ledgers between fromSeq and toSeq (inclusive) from the
+// liquidity pool ledger stats.
+func (q *Q) RebuildLiquidityPoolStatsBuckets(ctx context.Context, fromSeq, toSeq uint32) error {
+	fromLedgerToid := toid.New(int32(fromSeq), 0, 0).ToInt64()
+	// toLedger should be inclusive here.
+	toLedgerToid := toid.New(int32(toSeq+1), 0, 0).ToInt64()
+
+	timestamps := sq.Select(
+		fmt.Sprintf("to_millis(closed_at, %d)", liquidityPoolStatsBucketResolution),
+	).From("history_ledgers").Where(
+		sq.GtOrEq{"id": fromLedgerToid},
+	).Where(
+		sq.Lt{"id": toLedgerToid},
+	)
+
+	var from strtime.Millis
+	err := q.Get(ctx, &from, timestamps.OrderBy("id").Limit(1))
+	if err != nil {
+		return errors.Wrap(err, "could not rebuild liquidity pool stats buckets")
+	}
+	var to strtime.Millis
+	err = q.Get(ctx, &to, timestamps.OrderBy("id DESC").Limit(1))
+	if err != nil {
+		return errors.Wrap(err, "could not rebuild liquidity pool stats buckets")
+	}
+
+	_, err = q.Exec(ctx, sq.Delete(LiquidityPoolStatsTableName).Where(
+		sq.GtOrEq{"timestamp": from},
+	).Where(
+		sq.LtOrEq{"timestamp": to},
+	))
+	if err != nil {
+		return errors.Wrap(err, "could not rebuild liquidity pool stats buckets")
+	}
+
+	bucketTimestamp := fmt.Sprintf("to_millis(ledger_closed_at, %d)", liquidityPoolStatsBucketResolution)
+	ledgerStats := sq.Select(
+		bucketTimestamp+" as timestamp",
+		"history_liquidity_pool_id",
+		"ledger_toid",
+		"trade_count",
+		"volume_a",
+		"volume_b",
+		"fees_a",
+		"fees_b",
+		"reserve_a",
+		"reserve_b",
+		"total_shares",
+	).From("history_liquidity_pool_ledger_stats").Where(
+		sq.GtOrEq{bucketTimestamp: from},
+	).Where(
+		sq.LtOrEq{bucketTimestamp: to},
+	).OrderBy("history_liquidity_pool_id", "ledger_toid")
+
+	rebuilt := sq.Select(
+		"timestamp",
+		"history_liquidity_pool_id",
+		"sum(trade_count) as trade_count",
+		"sum(volume_a) as volume_a",
+		"sum(volume_b) as volume_b",
+		"sum(fees_a) as fees_a",
+		"sum(fees_b) as fees_b",
+		"last(reserve_a) as reserve_a",
+		"last(reserve_b) as reserve_b",
+		"last(total_shares) as total_shares",
+		"first(ledger_toid) as open_ledger_toid",
+		"last(ledger_toid) as close_ledger_toid",
+	).FromSelect(ledgerStats, "ledger_stats").GroupBy("history_liquidity_pool_id", "timestamp")
+
+	_, err = q.Exec(ctx, sq.Insert(LiquidityPoolStatsTableName).Columns(
+		"timestamp",
+		"history_liquidity_pool_id",
+		"trade_count",
+		"volume_a",
+		"volume_b",
+		"fees_a",
+		"fees_b",
+		"reserve_a",
+		"reserve_b",
+		"total_shares",
+		"open_ledger_toid",
+		"close_ledger_toid",
+	).Select(rebuilt))
+	if err != nil {
+		return errors.Wrap(err, "could not rebuild liquidity pool stats buckets")
+	}
+	return nil
+}
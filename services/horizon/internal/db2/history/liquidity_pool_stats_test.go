@@ -0,0 +1,127 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pownieh/stellar_go/services/horizon/internal/db2"
+	"github.com/pownieh/stellar_go/services/horizon/internal/test"
+	strtime "github.com/pownieh/stellar_go/support/time"
+	"github.com/pownieh/stellar_go/xdr"
+)
+
+func TestLiquidityPoolStats(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetHorizonDB(t, tt.HorizonDB)
+	q := &Q{tt.HorizonSession()}
+
+	tt.Assert.NoError(q.Begin(tt.Ctx))
+	defer q.Rollback()
+
+	poolID := "cafebabedeadbeef000000000000000000000000000000000000000000000000"
+	loader := NewLiquidityPoolLoader()
+	future := loader.GetFuture(poolID)
+	tt.Assert.NoError(loader.Exec(tt.Ctx, q))
+
+	hour := time.Date(2023, 11, 14, 22, 0, 0, 0, time.UTC)
+	closeTimes := map[uint32]time.Time{
+		10: hour.Add(10 * time.Second),
+		11: hour.Add(30 * time.Minute),
+		12: hour.Add(65 * time.Minute),
+	}
+	ledgerBatch := q.NewLedgerBatchInsertBuilder()
+	statsBatch := q.NewLiquidityPoolLedgerStatsBatchInsertBuilder()
+	for sequence := uint32(10); sequence <= 12; sequence++ {
+		tt.Assert.NoError(ledgerBatch.Add(xdr.LedgerHeaderHistoryEntry{
+			Hash: xdr.Hash{byte(sequence)},
+			Header: xdr.LedgerHeader{
+				LedgerSeq: xdr.Uint32(sequence),
+				ScpValue: xdr.StellarValue{
+					CloseTime: xdr.TimePoint(closeTimes[sequence].Unix()),
+				},
+			},
+		}, 1, 0, 1, 1, 1))
+		tt.Assert.NoError(statsBatch.Add(LiquidityPoolLedgerStats{
+			LiquidityPoolID: future,
+			LedgerSequence:  sequence,
+			LedgerCloseTime: closeTimes[sequence],
+			TradeCount:      int32(sequence - 9),
+			VolumeA:         "100",
+			VolumeB:         "200",
+			FeesA:           "1",
+			FeesB:           "0",
+			ReserveA:        int64(1000 * sequence),
+			ReserveB:        int64(2000 * sequence),
+			TotalShares:     int64(500 * sequence),
+		}))
+	}
+	tt.Assert.NoError(ledgerBatch.Exec(tt.Ctx, q))
+	tt.Assert.NoError(statsBatch.Exec(tt.Ctx, q))
+
+	tt.Assert.NoError(q.RebuildLiquidityPoolStatsBuckets(tt.Ctx, 10, 12))
+	// rebuilding the buckets again doesn't change them
+	tt.Assert.NoError(q.RebuildLiquidityPoolStatsBuckets(tt.Ctx, 11, 11))
+
+	query := LiquidityPoolStatsQuery{
+		LiquidityPoolID: poolID,
+		Resolution:      3600000,
+		PageQuery:       db2.PageQuery{Order: "asc", Limit: 10},
+	}
+	hourly, err := q.GetLiquidityPoolStats(tt.Ctx, query)
+	tt.Assert.NoError(err)
+	assert.Equal(t, []LiquidityPoolStats{
+		{
+			Timestamp:   hour.UnixMilli(),
+			TradeCount:  3,
+			VolumeA:     "200",
+			VolumeB:     "400",
+			FeesA:       "2",
+			FeesB:       "0",
+			ReserveA:    11000,
+			ReserveB:    22000,
+			TotalShares: 5500,
+		},
+		{
+			Timestamp:   hour.Add(time.Hour).UnixMilli(),
+			TradeCount:  3,
+			VolumeA:     "100",
+			VolumeB:     "200",
+			FeesA:       "1",
+			FeesB:       "0",
+			ReserveA:    12000,
+			ReserveB:    24000,
+			TotalShares: 6000,
+		},
+	}, hourly)
+
+	query.Resolution = 86400000
+	daily, err := q.GetLiquidityPoolStats(tt.Ctx, query)
+	tt.Assert.NoError(err)
+	assert.Equal(t, []LiquidityPoolStats{
+		{
+			Timestamp:   time.Date(2023, 11, 14, 0, 0, 0, 0, time.UTC).UnixMilli(),
+			TradeCount:  6,
+			VolumeA:     "300",
+			VolumeB:     "600",
+			FeesA:       "3",
+			FeesB:       "0",
+			ReserveA:    12000,
+			ReserveB:    24000,
+			TotalShares: 6000,
+		},
+	}, daily)
+
+	query.Resolution = 3600000
+	query.StartTime = strtime.MillisFromTime(hour.Add(time.Minute))
+	hourly, err = q.GetLiquidityPoolStats(tt.Ctx, query)
+	tt.Assert.NoError(err)
+	assert.Len(t, hourly, 1)
+	assert.Equal(t, hour.Add(time.Hour).UnixMilli(), hourly[0].Timestamp)
+
+	query.Resolution = 60000
+	_, err = q.GetLiquidityPoolStats(tt.Ctx, query)
+	assert.EqualError(t, err, "resolution is not allowed")
+}
@@ -9,6 +9,7 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -24,6 +25,7 @@ import (
 	"github.com/pownieh/stellar_go/support/db"
 	"github.com/pownieh/stellar_go/support/errors"
 	strtime "github.com/pownieh/stellar_go/support/time"
+	"github.com/pownieh/stellar_go/toid"
 	"github.com/pownieh/stellar_go/xdr"
 )
 
@@ -278,6 +280,11 @@ type IngestionQ interface {
 	NewTransactionParticipantsBatchInsertBuilder() TransactionParticipantsBatchInsertBuilder
 	NewOperationParticipantBatchInsertBuilder() OperationParticipantBatchInsertBuilder
 	QSigners
+	QStateChecksums
+	QStateRebuild
+	QOrderBookSnapshots
+	QLiquidityPoolStats
+	QLedgerFeeStats
 	//QTrades
 	NewTradeBatchInsertBuilder() TradeBatchInsertBuilder
 	RebuildTradeAggregationTimes(ctx context.Context, from, to strtime.Millis, roundingSlippageFilter int) error
@@ -302,9 +309,16 @@ type IngestionQ interface {
 	GetOfferCompactionSequence(context.Context) (uint32, error)
 	GetLiquidityPoolCompactionSequence(context.Context) (uint32, error)
 	TruncateIngestStateTables(context.Context) error
+	TruncateTables(ctx context.Context, tables []string) error
 	DeleteRangeAll(ctx context.Context, start, end int64) error
+	DeleteRange(ctx context.Context, start, end int64, table string, idCol string) error
+	CreateHistoryPartitions(ctx context.Context, fromLedger, toLedger uint32) error
+	UpdateStateVerificationResult(ctx context.Context, result StateVerificationResult) error
 	DeleteTransactionsFilteredTmpOlderThan(ctx context.Context, howOldInSeconds uint64) (int64, error)
 	TryStateVerificationLock(ctx context.Context) (bool, error)
+	Savepoint(ctx context.Context, name string) error
+	RollbackToSavepoint(ctx context.Context, name string) error
+	ReleaseSavepoint(ctx context.Context, name string) error
 }
 
 // QAccounts defines account related queries.
@@ -872,9 +886,13 @@ func (q *Q) GetLatestHistoryLedger(ctx context.Context) (uint32, error) {
 	return value, err
 }
 
+// LatestLedgerQuery returns the latest known ledger, it is also used to
+// measure the replication lag of read replicas.
+const LatestLedgerQuery = `SELECT COALESCE(MAX(sequence), 0) FROM history_ledgers`
+
 // LatestLedger loads the latest known ledger
 func (q *Q) LatestLedger(ctx context.Context, dest interface{}) error {
-	return q.GetRaw(ctx, dest, `SELECT COALESCE(MAX(sequence), 0) FROM history_ledgers`)
+	return q.GetRaw(ctx, dest, LatestLedgerQuery)
 }
 
 // LatestLedgerSequenceClosedAt loads the latest known ledger sequence and close time,
@@ -996,6 +1014,14 @@ func (q Q) ReapLookupTables(ctx context.Context, offsets map[string]int64) (
 				name:        "history_transaction_liquidity_pools",
 				objectField: "history_liquidity_pool_id",
 			},
+			{
+				name:        "history_liquidity_pool_ledger_stats",
+				objectField: "history_liquidity_pool_id",
+			},
+			{
+				name:        "history_liquidity_pool_stats_3600000",
+				objectField: "history_liquidity_pool_id",
+			},
 		},
 	} {
 		query, err := constructReapLookupTablesQuery(table, historyTables, batchSize, offsets[table])
@@ -1099,24 +1125,150 @@ func constructReapLookupTablesQuery(table string, historyTables []tableObjectFie
 	return sb.String(), nil
 }
 
+// historyTableColumns are the history tables deleted by DeleteRangeAll and
+// their toid column.
+var historyTableColumns = map[string]string{
+	"history_effects":                        "history_operation_id",
+	"history_ledger_fee_stats":               "ledger_toid",
+	"history_ledgers":                        "id",
+	"history_liquidity_pool_ledger_stats":    "ledger_toid",
+	"history_liquidity_pool_stats_3600000":   "open_ledger_toid",
+	"history_operation_claimable_balances":   "history_operation_id",
+	"history_operation_participants":         "history_operation_id",
+	"history_operation_liquidity_pools":      "history_operation_id",
+	"history_operations":                     "id",
+	"history_trades":                         "history_operation_id",
+	"history_trades_60000":                   "open_ledger_toid",
+	"history_transaction_claimable_balances": "history_transaction_id",
+	"history_transaction_participants":       "history_transaction_id",
+	"history_transaction_liquidity_pools":    "history_transaction_id",
+	"history_transactions":                   "id",
+}
+
+var (
+	registeredHistoryTablesLock sync.RWMutex
+	registeredHistoryTables     = map[string]string{}
+)
+
+// RegisterHistoryTables registers extra history tables, mapped to their toid
+// column, which are reaped with the ledgers and exported to cold storage like
+// the built-in history tables. None of the tables is registered if one of
+// them is already known.
+func RegisterHistoryTables(tables map[string]string) error {
+	registeredHistoryTablesLock.Lock()
+	defer registeredHistoryTablesLock.Unlock()
+	for table := range tables {
+		if _, ok := historyTableColumns[table]; ok {
+			return errors.Errorf("history table %s is already known", table)
+		}
+		if _, ok := registeredHistoryTables[table]; ok {
+			return errors.Errorf("history table %s is already known", table)
+		}
+	}
+	for table, column := range tables {
+		registeredHistoryTables[table] = column
+	}
+	return nil
+}
+
+// RegisteredHistoryTables returns the names of the history tables registered
+// with RegisterHistoryTables, sorted by name.
+func RegisteredHistoryTables() []string {
+	registeredHistoryTablesLock.RLock()
+	defer registeredHistoryTablesLock.RUnlock()
+	tables := make([]string, 0, len(registeredHistoryTables))
+	for table := range registeredHistoryTables {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	return tables
+}
+
+// historyTableColumn returns the toid column of a built-in or registered
+// history table.
+func historyTableColumn(table string) (string, bool) {
+	if column, ok := historyTableColumns[table]; ok {
+		return column, true
+	}
+	registeredHistoryTablesLock.RLock()
+	defer registeredHistoryTablesLock.RUnlock()
+	column, ok := registeredHistoryTables[table]
+	return column, ok
+}
+
 // DeleteRangeAll deletes a range of rows from all history tables between
-// `start` and `end` (exclusive).
+// `start` and `end` (exclusive). When the history tables are partitioned, the
+// partitions entirely contained in the range are truncated.
 func (q *Q) DeleteRangeAll(ctx context.Context, start, end int64) error {
-	for table, column := range map[string]string{
-		"history_effects":                        "history_operation_id",
-		"history_ledgers":                        "id",
-		"history_operation_claimable_balances":   "history_operation_id",
-		"history_operation_participants":         "history_operation_id",
-		"history_operation_liquidity_pools":      "history_operation_id",
-		"history_operations":                     "id",
-		"history_trades":                         "history_operation_id",
-		"history_trades_60000":                   "open_ledger_toid",
-		"history_transaction_claimable_balances": "history_transaction_id",
-		"history_transaction_participants":       "history_transaction_id",
-		"history_transaction_liquidity_pools":    "history_transaction_id",
-		"history_transactions":                   "id",
-	} {
-		err := q.DeleteRange(ctx, start, end, table, column)
+	return q.DeleteRangeTables(ctx, start, end, HistoryTables())
+}
+
+// HistoryTables returns the names of the history tables deleted by
+// DeleteRangeAll, sorted by name.
+func HistoryTables() []string {
+	tables := make([]string, 0, len(historyTableColumns))
+	for table := range historyTableColumns {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	return tables
+}
+
+// GetHistoryRowsInRange returns the rows of the history tables deleted by
+// DeleteRangeAll, and of the given extra history tables mapped to their toid
+// column, which are between `start` and `end` (exclusive), by table.
+func (q *Q) GetHistoryRowsInRange(ctx context.Context, start, end int64, extraTables map[string]string) (map[string][]map[string]interface{}, error) {
+	tables := make(map[string]string, len(historyTableColumns)+len(extraTables))
+	for table, column := range historyTableColumns {
+		tables[table] = column
+	}
+	for table, column := range extraTables {
+		tables[table] = column
+	}
+
+	result := map[string][]map[string]interface{}{}
+	for table, column := range tables {
+		rows, err := q.QueryRaw(ctx, fmt.Sprintf(
+			"SELECT * FROM %s WHERE %s >= ? AND %s < ?", table, column, column,
+		), start, end)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not select rows of %s", table)
+		}
+		for rows.Next() {
+			row := map[string]interface{}{}
+			if err = rows.MapScan(row); err != nil {
+				rows.Close()
+				return nil, errors.Wrapf(err, "could not scan rows of %s", table)
+			}
+			result[table] = append(result[table], row)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not select rows of %s", table)
+		}
+	}
+	return result, nil
+}
+
+// DeleteRangeTables is like DeleteRangeAll but only deletes the rows of the
+// given history tables.
+func (q *Q) DeleteRangeTables(ctx context.Context, start, end int64, tables []string) error {
+	partitionSize, err := q.GetHistoryPartitionSize(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, table := range tables {
+		column, ok := historyTableColumn(table)
+		if !ok {
+			return errors.Errorf("unknown history table %s", table)
+		}
+		if partitionSize > 0 && isPartitionedHistoryTable(table) {
+			err = q.deleteRangePartitioned(ctx, start, end, table)
+		} else {
+			err = q.DeleteRange(ctx, start, end, table, column)
+		}
 		if err != nil {
 			return errors.Wrapf(err, "Error clearing %s", table)
 		}
@@ -1124,6 +1276,45 @@ func (q *Q) DeleteRangeAll(ctx context.Context, start, end int64) error {
 	return nil
 }
 
+// HistoryTableElderLedger returns the oldest ledger with rows in the given
+// history table, or 0 if the table is empty.
+func (q *Q) HistoryTableElderLedger(ctx context.Context, table string) (int32, error) {
+	column, ok := historyTableColumn(table)
+	if !ok {
+		return 0, errors.Errorf("unknown history table %s", table)
+	}
+
+	var oldest sql.NullInt64
+	err := q.GetRaw(ctx, &oldest, fmt.Sprintf("SELECT MIN(%s) FROM %s", column, table))
+	if err != nil {
+		return 0, errors.Wrapf(err, "could not get the oldest row of %s", table)
+	}
+	if !oldest.Valid {
+		return 0, nil
+	}
+	return toid.Parse(oldest.Int64).LedgerSequence, nil
+}
+
+// HistoryTableToidColumn returns the toid column of a history table deleted
+// by DeleteRangeAll or registered with RegisterHistoryTables.
+func HistoryTableToidColumn(table string) (string, bool) {
+	column, ok := historyTableColumn(table)
+	return column, ok
+}
+
+// QueryHistoryTableRange returns the rows of a history table between `start`
+// and `end` (exclusive) toids, ordered by toid.
+func (q *Q) QueryHistoryTableRange(ctx context.Context, table string, start, end int64) (*sqlx.Rows, error) {
+	column, ok := historyTableColumn(table)
+	if !ok {
+		return nil, errors.Errorf("unknown history table %s", table)
+	}
+	return q.QueryRaw(ctx, fmt.Sprintf(
+		"SELECT * FROM %s WHERE %s >= ? AND %s < ? ORDER BY %s",
+		table, column, column, column,
+	), start, end)
+}
+
 // upsertRows builds and executes an upsert query that allows very fast upserts
 // to a given table. The final query is of form:
 //
@@ -21,6 +21,11 @@ func (m *MockQFilter) GetAssetFilterConfig(ctx context.Context) (AssetFilterConf
 	return a.Get(0).(AssetFilterConfig), a.Error(1)
 }
 
+func (m *MockQFilter) GetRulesFilterConfig(ctx context.Context) (RulesFilterConfig, error) {
+	a := m.Called(ctx)
+	return a.Get(0).(RulesFilterConfig), a.Error(1)
+}
+
 func (m *MockQFilter) UpdateAccountFilterConfig(ctx context.Context, config AccountFilterConfig) (AccountFilterConfig, error) {
 	a := m.Called(ctx, config)
 	return a.Get(0).(AccountFilterConfig), a.Error(0)
@@ -30,3 +35,8 @@ func (m *MockQFilter) UpdateAssetFilterConfig(ctx context.Context, config AssetF
 	a := m.Called(ctx, config)
 	return a.Get(0).(AssetFilterConfig), a.Error(0)
 }
+
+func (m *MockQFilter) UpdateRulesFilterConfig(ctx context.Context, config RulesFilterConfig) (RulesFilterConfig, error) {
+	a := m.Called(ctx, config)
+	return a.Get(0).(RulesFilterConfig), a.Error(1)
+}
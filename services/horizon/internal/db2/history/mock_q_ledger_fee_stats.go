@@ -0,0 +1,33 @@
+package history
+
+import (
+	"context"
+
+	"github.com/pownieh/stellar_go/support/db"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockQLedgerFeeStats is a mock implementation of the QLedgerFeeStats interface
+type MockQLedgerFeeStats struct {
+	mock.Mock
+}
+
+func (m *MockQLedgerFeeStats) NewLedgerFeeStatsBatchInsertBuilder() LedgerFeeStatsBatchInsertBuilder {
+	a := m.Called()
+	return a.Get(0).(LedgerFeeStatsBatchInsertBuilder)
+}
+
+type MockLedgerFeeStatsBatchInsertBuilder struct {
+	mock.Mock
+}
+
+func (m *MockLedgerFeeStatsBatchInsertBuilder) Add(stats LedgerFeeStats) error {
+	a := m.Called(stats)
+	return a.Error(0)
+}
+
+func (m *MockLedgerFeeStatsBatchInsertBuilder) Exec(ctx context.Context, session db.SessionInterface) error {
+	a := m.Called(ctx, session)
+	return a.Error(0)
+}
@@ -0,0 +1,38 @@
+package history
+
+import (
+	"context"
+
+	"github.com/pownieh/stellar_go/support/db"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockQLiquidityPoolStats is a mock implementation of the QLiquidityPoolStats interface
+type MockQLiquidityPoolStats struct {
+	mock.Mock
+}
+
+func (m *MockQLiquidityPoolStats) NewLiquidityPoolLedgerStatsBatchInsertBuilder() LiquidityPoolLedgerStatsBatchInsertBuilder {
+	a := m.Called()
+	return a.Get(0).(LiquidityPoolLedgerStatsBatchInsertBuilder)
+}
+
+func (m *MockQLiquidityPoolStats) RebuildLiquidityPoolStatsBuckets(ctx context.Context, fromSeq, toSeq uint32) error {
+	a := m.Called(ctx, fromSeq, toSeq)
+	return a.Error(0)
+}
+
+type MockLiquidityPoolLedgerStatsBatchInsertBuilder struct {
+	mock.Mock
+}
+
+func (m *MockLiquidityPoolLedgerStatsBatchInsertBuilder) Add(stats LiquidityPoolLedgerStats) error {
+	a := m.Called(stats)
+	return a.Error(0)
+}
+
+func (m *MockLiquidityPoolLedgerStatsBatchInsertBuilder) Exec(ctx context.Context, session db.SessionInterface) error {
+	a := m.Called(ctx, session)
+	return a.Error(0)
+}
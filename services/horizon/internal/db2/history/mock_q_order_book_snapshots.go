@@ -0,0 +1,24 @@
+package history
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockQOrderBookSnapshots is a mock implementation of the QOrderBookSnapshots interface
+type MockQOrderBookSnapshots struct {
+	mock.Mock
+}
+
+func (m *MockQOrderBookSnapshots) InsertOrderBookSnapshot(
+	ctx context.Context,
+	sequence uint32,
+	closedAt time.Time,
+	pair OrderBookPair,
+	maxPriceLevels int,
+) error {
+	a := m.Called(ctx, sequence, closedAt, pair, maxPriceLevels)
+	return a.Error(0)
+}
@@ -0,0 +1,32 @@
+package history
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockQStateChecksums is a mock implementation of the QStateChecksums interface
+type MockQStateChecksums struct {
+	mock.Mock
+}
+
+func (m *MockQStateChecksums) GetStateChecksums(ctx context.Context) (StateChecksums, error) {
+	a := m.Called(ctx)
+	return a.Get(0).(StateChecksums), a.Error(1)
+}
+
+func (m *MockQStateChecksums) UpdateStateChecksums(ctx context.Context, checksums StateChecksums) error {
+	a := m.Called(ctx, checksums)
+	return a.Error(0)
+}
+
+func (m *MockQStateChecksums) GetStateChecksumOffsets(ctx context.Context) (StateChecksums, error) {
+	a := m.Called(ctx)
+	return a.Get(0).(StateChecksums), a.Error(1)
+}
+
+func (m *MockQStateChecksums) UpdateStateChecksumOffsets(ctx context.Context, offsets StateChecksums) error {
+	a := m.Called(ctx, offsets)
+	return a.Error(0)
+}
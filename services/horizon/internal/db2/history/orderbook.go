@@ -52,6 +52,14 @@ func (q *Q) GetOrderBookSummary(ctx context.Context, sellingAsset, buyingAsset x
 		return result, errors.New("should only be called in a repeatable read transaction")
 	}
 
+	return q.getOrderBookSummary(ctx, sellingAsset, buyingAsset, maxPriceLevels)
+}
+
+// getOrderBookSummary returns the OrderBookSummary of a trading pair without
+// checking the transaction it is called in.
+func (q *Q) getOrderBookSummary(ctx context.Context, sellingAsset, buyingAsset xdr.Asset, maxPriceLevels int) (OrderBookSummary, error) {
+	var result OrderBookSummary
+
 	selling, err := xdr.MarshalBase64(sellingAsset)
 	if err != nil {
 		return result, errors.Wrap(err, "cannot marshal selling asset")
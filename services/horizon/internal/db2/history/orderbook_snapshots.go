@@ -0,0 +1,145 @@
+package history
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/pownieh/stellar_go/support/errors"
+	"github.com/pownieh/stellar_go/xdr"
+)
+
+const orderBookSnapshotsTable = "history_order_book_snapshots"
+
+// OrderBookPair is the trading pair of an order book, the asks of the order
+// book sell the selling asset and its bids buy it.
+type OrderBookPair struct {
+	Selling xdr.Asset
+	Buying  xdr.Asset
+}
+
+// String returns the pair as "selling/buying" where the assets use their
+// canonical representation.
+func (p OrderBookPair) String() string {
+	return p.Selling.StringCanonical() + "/" + p.Buying.StringCanonical()
+}
+
+// PriceLevels are the price levels of one side of an order book.
+type PriceLevels []PriceLevel
+
+func (l PriceLevels) Value() (driver.Value, error) {
+	// Convert the byte array into a string as a workaround to bypass buggy encoding in the pq driver
+	// (More info about this bug here https://github.com/pownieh/stellar_go/issues/5086#issuecomment-1773215436).
+	// By doing so, the data will be written as a string rather than hex encoded bytes.
+	if l == nil {
+		l = PriceLevels{}
+	}
+	val, err := json.Marshal(l)
+	return string(val), err
+}
+
+func (l *PriceLevels) Scan(value interface{}) error {
+	b, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+
+	return json.Unmarshal(b, &l)
+}
+
+// OrderBookSnapshot is the order book of a trading pair at the close of a
+// ledger.
+type OrderBookSnapshot struct {
+	LedgerSequence uint32      `db:"ledger_sequence"`
+	ClosedAt       time.Time   `db:"closed_at"`
+	SellingAsset   string      `db:"selling_asset"`
+	BuyingAsset    string      `db:"buying_asset"`
+	Bids           PriceLevels `db:"bids"`
+	Asks           PriceLevels `db:"asks"`
+}
+
+// QOrderBookSnapshots defines the order book snapshots related queries used
+// by ingestion.
+type QOrderBookSnapshots interface {
+	InsertOrderBookSnapshot(
+		ctx context.Context,
+		sequence uint32,
+		closedAt time.Time,
+		pair OrderBookPair,
+		maxPriceLevels int,
+	) error
+}
+
+// InsertOrderBookSnapshot stores the current order book of the pair, up to
+// maxPriceLevels on each side, as its snapshot at the given ledger. It must be
+// called in the ingestion transaction, once the offers of the ledger are
+// stored.
+func (q *Q) InsertOrderBookSnapshot(
+	ctx context.Context,
+	sequence uint32,
+	closedAt time.Time,
+	pair OrderBookPair,
+	maxPriceLevels int,
+) error {
+	if tx := q.GetTx(); tx == nil {
+		return errors.New("cannot be called outside of a transaction")
+	}
+
+	summary, err := q.getOrderBookSummary(ctx, pair.Selling, pair.Buying, maxPriceLevels)
+	if err != nil {
+		return errors.Wrapf(err, "could not get order book summary of %s", pair)
+	}
+
+	sql := sq.Insert(orderBookSnapshotsTable).SetMap(map[string]interface{}{
+		"ledger_sequence": sequence,
+		"closed_at":       closedAt.UTC(),
+		"selling_asset":   pair.Selling.StringCanonical(),
+		"buying_asset":    pair.Buying.StringCanonical(),
+		"bids":            PriceLevels(summary.Bids),
+		"asks":            PriceLevels(summary.Asks),
+	})
+	_, err = q.Exec(ctx, sql)
+	return err
+}
+
+// GetOrderBookSnapshot returns the latest snapshot of the pair taken at or
+// before the given ledger and close time. A zero ledger or close time doesn't
+// bound the snapshot.
+func (q *Q) GetOrderBookSnapshot(
+	ctx context.Context,
+	pair OrderBookPair,
+	sequence uint32,
+	closedAt time.Time,
+) (OrderBookSnapshot, error) {
+	var snapshot OrderBookSnapshot
+	sql := sq.Select(
+		"ledger_sequence", "closed_at", "selling_asset", "buying_asset", "bids", "asks",
+	).From(orderBookSnapshotsTable).Where(sq.Eq{
+		"selling_asset": pair.Selling.StringCanonical(),
+		"buying_asset":  pair.Buying.StringCanonical(),
+	})
+	if sequence > 0 {
+		sql = sql.Where(sq.LtOrEq{"ledger_sequence": sequence})
+	}
+	if !closedAt.IsZero() {
+		sql = sql.Where(sq.LtOrEq{"closed_at": closedAt.UTC()})
+	}
+	sql = sql.OrderBy("ledger_sequence DESC").Limit(1)
+
+	err := q.Get(ctx, &snapshot, sql)
+	return snapshot, err
+}
+
+// DeleteOrderBookSnapshotsBefore removes the snapshots taken before the
+// given ledger.
+func (q *Q) DeleteOrderBookSnapshotsBefore(ctx context.Context, sequence uint32) (int64, error) {
+	sql := sq.Delete(orderBookSnapshotsTable).Where(sq.Lt{"ledger_sequence": sequence})
+	result, err := q.Exec(ctx, sql)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,63 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pownieh/stellar_go/services/horizon/internal/test"
+)
+
+func TestOrderBookSnapshots(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetHorizonDB(t, tt.HorizonDB)
+	q := &Q{tt.HorizonSession()}
+
+	pair := OrderBookPair{Selling: nativeAsset, Buying: eurAsset}
+	closedAt := time.Date(2023, 11, 14, 22, 0, 0, 0, time.UTC)
+
+	err := q.InsertOrderBookSnapshot(tt.Ctx, 10, closedAt, pair, 10)
+	assert.EqualError(t, err, "cannot be called outside of a transaction")
+
+	assert.NoError(t, q.Begin(tt.Ctx))
+	defer q.Rollback()
+
+	assert.NoError(t, q.UpsertOffers(tt.Ctx, []Offer{eurOffer}))
+	assert.NoError(t, q.InsertOrderBookSnapshot(tt.Ctx, 10, closedAt, pair, 10))
+	assert.NoError(t, q.UpsertOffers(tt.Ctx, []Offer{twoEurOffer}))
+	assert.NoError(t, q.InsertOrderBookSnapshot(tt.Ctx, 20, closedAt.Add(time.Minute), pair, 10))
+	// the pair in the other direction has no offers
+	assert.NoError(t, q.InsertOrderBookSnapshot(tt.Ctx, 20, closedAt.Add(time.Minute), OrderBookPair{Selling: eurAsset, Buying: nativeAsset}, 10))
+
+	latest, err := q.GetOrderBookSnapshot(tt.Ctx, pair, 0, time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(20), latest.LedgerSequence)
+	assert.Equal(t, closedAt.Add(time.Minute), latest.ClosedAt.UTC())
+	assert.Equal(t, "native", latest.SellingAsset)
+	assert.Equal(t, eurAsset.StringCanonical(), latest.BuyingAsset)
+	assert.Empty(t, latest.Bids)
+	assert.Equal(t, PriceLevels{
+		{Pricen: 1, Priced: 1, Pricef: "1.0000000", Amount: "0.0000500"},
+		{Pricen: 2, Priced: 1, Pricef: "2.0000000", Amount: "0.0000500"},
+	}, latest.Asks)
+
+	byLedger, err := q.GetOrderBookSnapshot(tt.Ctx, pair, 19, time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(10), byLedger.LedgerSequence)
+	assert.Len(t, byLedger.Asks, 1)
+
+	byTime, err := q.GetOrderBookSnapshot(tt.Ctx, pair, 0, closedAt.Add(time.Second))
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(10), byTime.LedgerSequence)
+
+	_, err = q.GetOrderBookSnapshot(tt.Ctx, pair, 9, time.Time{})
+	assert.True(t, q.NoRows(err))
+
+	deleted, err := q.DeleteOrderBookSnapshotsBefore(tt.Ctx, 20)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+	_, err = q.GetOrderBookSnapshot(tt.Ctx, pair, 19, time.Time{})
+	assert.True(t, q.NoRows(err))
+}
@@ -0,0 +1,433 @@
+package history
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"github.com/pownieh/stellar_go/support/db"
+	"github.com/pownieh/stellar_go/support/errors"
+	"github.com/pownieh/stellar_go/toid"
+)
+
+const (
+	// historyPartitionSizeKey stores the number of ledgers of each partition
+	// of the partitioned history tables. It is not set when the history
+	// tables are not partitioned.
+	historyPartitionSizeKey = "history_partition_size"
+
+	// historyPartitionsLockId is the objid for the advisory lock acquired
+	// when creating or dropping partitions. The value is arbitrary.
+	historyPartitionsLockId = 73897214
+
+	// MinHistoryPartitionSize is the minimum number of ledgers per
+	// partition, roughly a day of ledgers.
+	MinHistoryPartitionSize = 17280
+)
+
+// partitionedHistoryTable is a history table which can be range partitioned
+// by the toid of its rows.
+type partitionedHistoryTable struct {
+	name   string
+	column string
+}
+
+// partitionedHistoryTables are the tables which are partitioned by ledger
+// when history partitioning is enabled. The other history tables, e.g.
+// history_ledgers, are small enough to be reaped with deletes.
+var partitionedHistoryTables = []partitionedHistoryTable{
+	{"history_effects", "history_operation_id"},
+	{"history_operation_claimable_balances", "history_operation_id"},
+	{"history_operation_liquidity_pools", "history_operation_id"},
+	{"history_operation_participants", "history_operation_id"},
+	{"history_operations", "id"},
+	{"history_trades", "history_operation_id"},
+	{"history_transaction_claimable_balances", "history_transaction_id"},
+	{"history_transaction_liquidity_pools", "history_transaction_id"},
+	{"history_transaction_participants", "history_transaction_id"},
+	{"history_transactions", "id"},
+}
+
+func isPartitionedHistoryTable(name string) bool {
+	return partitionedHistoryTableColumn(name) != ""
+}
+
+func partitionedHistoryTableColumn(name string) string {
+	for _, table := range partitionedHistoryTables {
+		if table.name == name {
+			return table.column
+		}
+	}
+	return ""
+}
+
+// HistoryPartition is the range of ledgers of a partition of the partitioned
+// history tables.
+type HistoryPartition struct {
+	// StartLedger is the first ledger of the partition.
+	StartLedger uint32
+	// EndLedger is the first ledger after the partition.
+	EndLedger uint32
+}
+
+func (p HistoryPartition) name(table string) string {
+	return fmt.Sprintf("%s_p%d", table, p.StartLedger)
+}
+
+// toidRange returns the range of toids, [from, to), of the partition.
+func (p HistoryPartition) toidRange() (int64, int64) {
+	to := int64(math.MaxInt64)
+	if p.EndLedger <= math.MaxInt32 {
+		to = toid.New(int32(p.EndLedger), 0, 0).ToInt64()
+	}
+	return toid.New(int32(p.StartLedger), 0, 0).ToInt64(), to
+}
+
+func (p HistoryPartition) bounds() string {
+	from, to := p.toidRange()
+	if to == math.MaxInt64 {
+		return fmt.Sprintf("FROM (%d) TO (MAXVALUE)", from)
+	}
+	return fmt.Sprintf("FROM (%d) TO (%d)", from, to)
+}
+
+// historyPartitionsInRange returns the partitions containing the ledgers of
+// [fromLedger, toLedger].
+func historyPartitionsInRange(partitionSize, fromLedger, toLedger uint32) []HistoryPartition {
+	var partitions []HistoryPartition
+	for start := fromLedger - fromLedger%partitionSize; start <= toLedger; start += partitionSize {
+		end := uint64(start) + uint64(partitionSize)
+		if end > math.MaxInt32 {
+			end = math.MaxInt32 + 1
+		}
+		partitions = append(partitions, HistoryPartition{StartLedger: start, EndLedger: uint32(end)})
+		if end > math.MaxInt32 {
+			break
+		}
+	}
+	return partitions
+}
+
+// QHistoryPartitions defines the queries managing the partitions of the
+// history tables.
+type QHistoryPartitions interface {
+	GetHistoryPartitionSize(ctx context.Context) (uint32, error)
+	GetHistoryPartitions(ctx context.Context) ([]HistoryPartition, error)
+	PartitionHistoryTables(ctx context.Context, partitionSize uint32, latestLedger uint32) error
+	CreateHistoryPartitions(ctx context.Context, fromLedger, toLedger uint32) error
+	DropHistoryPartitionsBefore(ctx context.Context, ledger uint32, tables []string) (int, error)
+}
+
+// GetHistoryPartitionSize returns the number of ledgers of each partition of
+// the history tables, or 0 if the history tables are not partitioned.
+func (q *Q) GetHistoryPartitionSize(ctx context.Context) (uint32, error) {
+	value, err := q.getValueFromStore(ctx, historyPartitionSizeKey, false)
+	if err != nil {
+		return 0, err
+	}
+	if value == "" {
+		return 0, nil
+	}
+	size, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return 0, errors.Wrap(err, "invalid history partition size")
+	}
+	return uint32(size), nil
+}
+
+// GetHistoryPartitions returns the partitions of the history tables ordered
+// by ledger. Partitions which were dropped from some of the tables, because
+// they are retained for fewer ledgers, are included as long as one of the
+// tables has them.
+func (q *Q) GetHistoryPartitions(ctx context.Context) ([]HistoryPartition, error) {
+	tables := make([]string, 0, len(partitionedHistoryTables))
+	for _, table := range partitionedHistoryTables {
+		tables = append(tables, table.name)
+	}
+	return q.getHistoryPartitions(ctx, tables)
+}
+
+// getHistoryPartitions returns the partitions of any of the given tables
+// ordered by ledger.
+func (q *Q) getHistoryPartitions(ctx context.Context, tables []string) ([]HistoryPartition, error) {
+	partitionSize, err := q.GetHistoryPartitionSize(ctx)
+	if err != nil || partitionSize == 0 {
+		return nil, err
+	}
+
+	var names []string
+	err = q.SelectRaw(ctx, &names, `
+		SELECT c.relname FROM pg_inherits i
+		JOIN pg_class c ON c.oid = i.inhrelid
+		WHERE i.inhparent = ANY(?::regclass[])`,
+		pq.Array(tables),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not list history partitions")
+	}
+
+	seen := map[uint32]bool{}
+	partitions := make([]HistoryPartition, 0, len(names))
+	for _, name := range names {
+		i := strings.LastIndex(name, "_p")
+		if i < 0 {
+			return nil, errors.Errorf("unexpected history partition %s", name)
+		}
+		start, err := strconv.ParseUint(name[i+2:], 10, 32)
+		if err != nil {
+			return nil, errors.Errorf("unexpected history partition %s", name)
+		}
+		if seen[uint32(start)] {
+			continue
+		}
+		seen[uint32(start)] = true
+		partitions = append(partitions, historyPartitionsInRange(partitionSize, uint32(start), uint32(start))...)
+	}
+	sort.Slice(partitions, func(i, j int) bool {
+		return partitions[i].StartLedger < partitions[j].StartLedger
+	})
+	return partitions, nil
+}
+
+// lockHistoryPartitions serializes the creation and removal of partitions
+// across Horizon instances until the end of the transaction.
+func (q *Q) lockHistoryPartitions(ctx context.Context) error {
+	if tx := q.GetTx(); tx == nil {
+		return errors.New("cannot be called outside of a transaction")
+	}
+	_, err := q.ExecRaw(
+		context.WithValue(ctx, &db.QueryTypeContextKey, db.AdvisoryLockQueryType),
+		"SELECT pg_advisory_xact_lock(?)",
+		historyPartitionsLockId,
+	)
+	return errors.Wrap(err, "error acquiring advisory lock for history partitions")
+}
+
+// PartitionHistoryTables converts the history tables to tables range
+// partitioned by ledger, with partitions of partitionSize ledgers created from
+// the oldest ingested ledger up to latestLedger, or the latest ingested ledger
+// if it is greater. The rows of the tables are copied to the partitions.
+//
+// Primary keys and unique indexes which do not include the partitioning
+// column, e.g. the unique index of transaction hashes, are recreated as non
+// unique indexes because Postgres cannot enforce them across partitions.
+//
+// It must be called in a transaction, which should also block ingestion.
+func (q *Q) PartitionHistoryTables(ctx context.Context, partitionSize uint32, latestLedger uint32) error {
+	if partitionSize < MinHistoryPartitionSize {
+		return errors.Errorf("partition size must be at least %d ledgers", MinHistoryPartitionSize)
+	}
+	if err := q.lockHistoryPartitions(ctx); err != nil {
+		return err
+	}
+
+	current, err := q.GetHistoryPartitionSize(ctx)
+	if err != nil {
+		return err
+	}
+	if current != 0 {
+		return errors.Errorf("history tables are already partitioned in partitions of %d ledgers", current)
+	}
+
+	var oldestLedger, latestIngestedLedger uint32
+	if err = q.ElderLedger(ctx, &oldestLedger); err != nil {
+		return errors.Wrap(err, "could not get oldest ledger")
+	}
+	if err = q.LatestLedger(ctx, &latestIngestedLedger); err != nil {
+		return errors.Wrap(err, "could not get latest ledger")
+	}
+	if oldestLedger == 0 {
+		oldestLedger = 1
+	}
+	if latestLedger < latestIngestedLedger {
+		latestLedger = latestIngestedLedger
+	}
+	if latestLedger < oldestLedger {
+		latestLedger = oldestLedger
+	}
+	partitions := historyPartitionsInRange(partitionSize, oldestLedger, latestLedger)
+
+	for _, table := range partitionedHistoryTables {
+		if err = q.partitionHistoryTable(ctx, table, partitions); err != nil {
+			return errors.Wrapf(err, "could not partition %s", table.name)
+		}
+	}
+
+	return q.updateValueInStore(ctx, historyPartitionSizeKey, strconv.FormatUint(uint64(partitionSize), 10))
+}
+
+type tableIndex struct {
+	Definition         string `db:"definition"`
+	IsUnique           bool   `db:"is_unique"`
+	HasPartitionColumn bool   `db:"has_partition_column"`
+}
+
+type serialColumn struct {
+	Column   string `db:"column_name"`
+	Sequence string `db:"sequence_name"`
+}
+
+func (q *Q) partitionHistoryTable(ctx context.Context, table partitionedHistoryTable, partitions []HistoryPartition) error {
+	var indexes []tableIndex
+	err := q.SelectRaw(ctx, &indexes, `
+		SELECT
+			pg_get_indexdef(ix.indexrelid) AS definition,
+			ix.indisunique AS is_unique,
+			EXISTS (
+				SELECT 1 FROM pg_attribute a
+				WHERE a.attrelid = ix.indrelid AND a.attnum = ANY(ix.indkey) AND a.attname = ?
+			) AS has_partition_column
+		FROM pg_index ix
+		WHERE ix.indrelid = ?::regclass`,
+		table.column, table.name,
+	)
+	if err != nil {
+		return errors.Wrap(err, "could not get indexes")
+	}
+
+	var serialColumns []serialColumn
+	err = q.SelectRaw(ctx, &serialColumns, `
+		SELECT a.attname AS column_name, pg_get_serial_sequence(?, a.attname) AS sequence_name
+		FROM pg_attribute a
+		WHERE a.attrelid = ?::regclass AND a.attnum > 0 AND NOT a.attisdropped
+		AND pg_get_serial_sequence(?, a.attname) IS NOT NULL`,
+		table.name, table.name, table.name,
+	)
+	if err != nil {
+		return errors.Wrap(err, "could not get sequences")
+	}
+
+	partitioned := table.name + "_partitioned"
+	statements := []string{
+		fmt.Sprintf(
+			"CREATE TABLE %s (LIKE %s INCLUDING DEFAULTS INCLUDING CONSTRAINTS INCLUDING STORAGE) PARTITION BY RANGE (%s)",
+			partitioned, table.name, table.column,
+		),
+	}
+	for _, partition := range partitions {
+		statements = append(statements, fmt.Sprintf(
+			"CREATE TABLE %s PARTITION OF %s FOR VALUES %s",
+			partition.name(table.name), partitioned, partition.bounds(),
+		))
+	}
+	statements = append(statements, fmt.Sprintf("INSERT INTO %s SELECT * FROM %s", partitioned, table.name))
+	for _, serial := range serialColumns {
+		// the sequences of serial columns would be dropped with the table
+		// owning them
+		statements = append(statements, fmt.Sprintf("ALTER SEQUENCE %s OWNED BY %s.%s", serial.Sequence, partitioned, serial.Column))
+	}
+	statements = append(statements,
+		fmt.Sprintf("DROP TABLE %s", table.name),
+		fmt.Sprintf("ALTER TABLE %s RENAME TO %s", partitioned, table.name),
+	)
+	for _, index := range indexes {
+		definition := index.Definition
+		if index.IsUnique && !index.HasPartitionColumn {
+			definition = strings.Replace(definition, "CREATE UNIQUE INDEX", "CREATE INDEX", 1)
+		}
+		statements = append(statements, definition)
+	}
+
+	for _, statement := range statements {
+		if _, err := q.ExecRaw(ctx, statement); err != nil {
+			return errors.Wrapf(err, "error executing %q", statement)
+		}
+	}
+	return nil
+}
+
+// CreateHistoryPartitions creates the missing partitions of the history
+// tables containing the ledgers of [fromLedger, toLedger]. It does nothing if
+// the history tables are not partitioned. It must be called in a
+// transaction.
+func (q *Q) CreateHistoryPartitions(ctx context.Context, fromLedger, toLedger uint32) error {
+	partitionSize, err := q.GetHistoryPartitionSize(ctx)
+	if err != nil || partitionSize == 0 {
+		return err
+	}
+	if err = q.lockHistoryPartitions(ctx); err != nil {
+		return err
+	}
+
+	for _, partition := range historyPartitionsInRange(partitionSize, fromLedger, toLedger) {
+		for _, table := range partitionedHistoryTables {
+			_, err = q.ExecRaw(ctx, fmt.Sprintf(
+				"CREATE TABLE IF NOT EXISTS %s PARTITION OF %s FOR VALUES %s",
+				partition.name(table.name), table.name, partition.bounds(),
+			))
+			if err != nil {
+				return errors.Wrapf(err, "could not create partition %s", partition.name(table.name))
+			}
+		}
+	}
+	return nil
+}
+
+// DropHistoryPartitionsBefore drops the partitions of the given history
+// tables which only contain ledgers before the given ledger, and returns the
+// number of partitions dropped. Tables which are not partitioned are ignored.
+// It does nothing if the history tables are not partitioned. It must be
+// called in a transaction.
+func (q *Q) DropHistoryPartitionsBefore(ctx context.Context, ledger uint32, tables []string) (int, error) {
+	partitionSize, err := q.GetHistoryPartitionSize(ctx)
+	if err != nil || partitionSize == 0 {
+		return 0, err
+	}
+	if err = q.lockHistoryPartitions(ctx); err != nil {
+		return 0, err
+	}
+
+	dropped := 0
+	for _, table := range tables {
+		if !isPartitionedHistoryTable(table) {
+			continue
+		}
+		partitions, err := q.getHistoryPartitions(ctx, []string{table})
+		if err != nil {
+			return dropped, err
+		}
+		for _, partition := range partitions {
+			if partition.EndLedger > ledger {
+				break
+			}
+			if _, err = q.ExecRaw(ctx, fmt.Sprintf("DROP TABLE IF EXISTS %s", partition.name(table))); err != nil {
+				return dropped, errors.Wrapf(err, "could not drop partition %s", partition.name(table))
+			}
+			dropped++
+		}
+	}
+	return dropped, nil
+}
+
+// deleteRangePartitioned deletes the rows of the given partitioned history
+// table in [start, end) toids, truncating the partitions entirely contained
+// in the range instead of deleting their rows.
+func (q *Q) deleteRangePartitioned(ctx context.Context, start, end int64, table string) error {
+	partitions, err := q.getHistoryPartitions(ctx, []string{table})
+	if err != nil {
+		return err
+	}
+
+	column := partitionedHistoryTableColumn(table)
+	for _, partition := range partitions {
+		partitionStart, partitionEnd := partition.toidRange()
+		if partitionEnd <= start || partitionStart >= end {
+			continue
+		}
+		name := partition.name(table)
+		if partitionStart >= start && partitionEnd <= end {
+			_, err = q.ExecRaw(ctx, fmt.Sprintf("TRUNCATE %s", name))
+		} else {
+			err = q.DeleteRange(ctx, start, end, name, column)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "Error clearing %s", name)
+		}
+	}
+	return nil
+}
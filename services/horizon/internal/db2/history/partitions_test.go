@@ -0,0 +1,135 @@
+package history
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pownieh/stellar_go/services/horizon/internal/test"
+	"github.com/pownieh/stellar_go/toid"
+)
+
+func TestHistoryPartitionsInRange(t *testing.T) {
+	assert.Equal(t, []HistoryPartition{
+		{StartLedger: 0, EndLedger: 100},
+	}, historyPartitionsInRange(100, 1, 99))
+	assert.Equal(t, []HistoryPartition{
+		{StartLedger: 100, EndLedger: 200},
+		{StartLedger: 200, EndLedger: 300},
+		{StartLedger: 300, EndLedger: 400},
+	}, historyPartitionsInRange(100, 150, 300))
+	assert.Equal(t, []HistoryPartition{
+		{StartLedger: math.MaxInt32 - 47, EndLedger: math.MaxInt32 + 1},
+	}, historyPartitionsInRange(100, math.MaxInt32-10, math.MaxInt32))
+}
+
+func TestHistoryPartitionBounds(t *testing.T) {
+	partition := HistoryPartition{StartLedger: 100, EndLedger: 200}
+	assert.Equal(t, "history_operations_p100", partition.name("history_operations"))
+	from, to := partition.toidRange()
+	assert.Equal(t, toid.New(100, 0, 0).ToInt64(), from)
+	assert.Equal(t, toid.New(200, 0, 0).ToInt64(), to)
+	assert.Equal(t, "FROM (429496729600) TO (858993459200)", partition.bounds())
+
+	last := HistoryPartition{StartLedger: math.MaxInt32 - 47, EndLedger: math.MaxInt32 + 1}
+	_, to = last.toidRange()
+	assert.Equal(t, int64(math.MaxInt64), to)
+	assert.Contains(t, last.bounds(), "TO (MAXVALUE)")
+}
+
+func TestPartitionHistoryTables(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetHorizonDB(t, tt.HorizonDB)
+	q := &Q{tt.HorizonSession()}
+
+	size, err := q.GetHistoryPartitionSize(tt.Ctx)
+	tt.Assert.NoError(err)
+	tt.Assert.Equal(uint32(0), size)
+
+	// partitions are not created when the tables are not partitioned
+	tt.Assert.NoError(q.Begin(tt.Ctx))
+	tt.Assert.NoError(q.CreateHistoryPartitions(tt.Ctx, 1, 100000))
+	tt.Assert.NoError(q.Commit())
+	partitions, err := q.GetHistoryPartitions(tt.Ctx)
+	tt.Assert.NoError(err)
+	tt.Assert.Empty(partitions)
+
+	tt.Assert.EqualError(
+		q.PartitionHistoryTables(tt.Ctx, MinHistoryPartitionSize, 100),
+		"cannot be called outside of a transaction",
+	)
+
+	tt.Assert.NoError(q.Begin(tt.Ctx))
+	tt.Assert.EqualError(
+		q.PartitionHistoryTables(tt.Ctx, 100, 100),
+		"partition size must be at least 17280 ledgers",
+	)
+	tt.Assert.NoError(q.PartitionHistoryTables(tt.Ctx, MinHistoryPartitionSize, 2*MinHistoryPartitionSize))
+	tt.Assert.NoError(q.Commit())
+
+	size, err = q.GetHistoryPartitionSize(tt.Ctx)
+	tt.Assert.NoError(err)
+	tt.Assert.Equal(uint32(MinHistoryPartitionSize), size)
+	partitions, err = q.GetHistoryPartitions(tt.Ctx)
+	tt.Assert.NoError(err)
+	tt.Assert.Equal(historyPartitionsInRange(MinHistoryPartitionSize, 1, 2*MinHistoryPartitionSize), partitions)
+
+	tt.Assert.NoError(q.Begin(tt.Ctx))
+	tt.Assert.EqualError(
+		q.PartitionHistoryTables(tt.Ctx, MinHistoryPartitionSize, 100),
+		"history tables are already partitioned in partitions of 17280 ledgers",
+	)
+	tt.Assert.NoError(q.Rollback())
+
+	// rows are routed to the partitions
+	tt.Assert.NoError(q.Begin(tt.Ctx))
+	tt.Assert.NoError(q.CreateHistoryPartitions(tt.Ctx, 3*MinHistoryPartitionSize, 3*MinHistoryPartitionSize))
+	tt.Assert.NoError(q.Commit())
+	ledger := int32(3*MinHistoryPartitionSize + 1)
+	_, err = q.ExecRaw(tt.Ctx, `INSERT INTO history_operations
+		(id, transaction_id, application_order, type, details, source_account)
+		VALUES (?, ?, 1, 0, '{}', 'GAQAA5L65LSYH7CQ3VTJ7F3HHLGCL3DSLAR2Y47263D56MNNGHSQSTVY')`,
+		toid.New(ledger, 1, 1).ToInt64(), toid.New(ledger, 1, 0).ToInt64(),
+	)
+	tt.Assert.NoError(err)
+	var count int
+	tt.Assert.NoError(q.GetRaw(tt.Ctx, &count, `SELECT COUNT(*) FROM history_operations_p51840`))
+	tt.Assert.Equal(1, count)
+
+	// deleting a range truncates the partitions it contains
+	start, end, err := toid.LedgerRangeInclusive(1, ledger)
+	tt.Assert.NoError(err)
+	tt.Assert.NoError(q.Begin(tt.Ctx))
+	tt.Assert.NoError(q.DeleteRangeAll(tt.Ctx, start, end))
+	tt.Assert.NoError(q.Commit())
+	tt.Assert.NoError(q.GetRaw(tt.Ctx, &count, `SELECT COUNT(*) FROM history_operations`))
+	tt.Assert.Equal(0, count)
+
+	// partitions can be dropped from some of the tables only
+	tt.Assert.NoError(q.Begin(tt.Ctx))
+	dropped, err := q.DropHistoryPartitionsBefore(tt.Ctx, 2*MinHistoryPartitionSize+1, []string{"history_effects", "history_ledgers"})
+	tt.Assert.NoError(err)
+	tt.Assert.NoError(q.Commit())
+	tt.Assert.Equal(2, dropped)
+	partitions, err = q.GetHistoryPartitions(tt.Ctx)
+	tt.Assert.NoError(err)
+	tt.Assert.Len(partitions, 4)
+
+	var tables []string
+	for _, table := range partitionedHistoryTables {
+		tables = append(tables, table.name)
+	}
+	tt.Assert.NoError(q.Begin(tt.Ctx))
+	dropped, err = q.DropHistoryPartitionsBefore(tt.Ctx, 2*MinHistoryPartitionSize+1, tables)
+	tt.Assert.NoError(err)
+	tt.Assert.NoError(q.Commit())
+	tt.Assert.Equal(2*(len(tables)-1), dropped)
+	partitions, err = q.GetHistoryPartitions(tt.Ctx)
+	tt.Assert.NoError(err)
+	tt.Assert.Equal([]HistoryPartition{
+		{StartLedger: 2 * MinHistoryPartitionSize, EndLedger: 3 * MinHistoryPartitionSize},
+		{StartLedger: 3 * MinHistoryPartitionSize, EndLedger: 4 * MinHistoryPartitionSize},
+	}, partitions)
+}
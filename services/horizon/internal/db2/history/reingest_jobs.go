@@ -0,0 +1,204 @@
+package history
+
+import (
+	"context"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/guregu/null"
+
+	"github.com/pownieh/stellar_go/support/errors"
+)
+
+// ReingestJobStatus is the status of a reingestion job.
+type ReingestJobStatus string
+
+const (
+	// ReingestJobPending jobs are waiting to be claimed by a worker.
+	ReingestJobPending ReingestJobStatus = "pending"
+	// ReingestJobRunning jobs are leased by a worker. They can be claimed by
+	// another worker once the lease expires.
+	ReingestJobRunning ReingestJobStatus = "running"
+	// ReingestJobDone jobs have been reingested successfully.
+	ReingestJobDone ReingestJobStatus = "done"
+	// ReingestJobFailed jobs failed in all of their attempts. They are not
+	// claimed anymore unless they are enqueued again.
+	ReingestJobFailed ReingestJobStatus = "failed"
+)
+
+const reingestJobsTableName = "reingest_jobs"
+
+// ReingestJob is a ledger range to reingest, claimed by reingestion workers
+// which may run on different machines.
+type ReingestJob struct {
+	ID             int64             `db:"id"`
+	FromLedger     uint32            `db:"from_ledger"`
+	ToLedger       uint32            `db:"to_ledger"`
+	Status         ReingestJobStatus `db:"status"`
+	Attempts       int               `db:"attempts"`
+	LeaseOwner     null.String       `db:"lease_owner"`
+	LeaseExpiresAt null.Time         `db:"lease_expires_at"`
+	LastError      null.String       `db:"last_error"`
+	CreatedAt      time.Time         `db:"created_at"`
+	UpdatedAt      time.Time         `db:"updated_at"`
+}
+
+// LedgerRange returns the range of ledgers of the job.
+func (j ReingestJob) LedgerRange() LedgerRange {
+	return LedgerRange{StartSequence: j.FromLedger, EndSequence: j.ToLedger}
+}
+
+// QReingestJobs defines the reingestion job queue related queries.
+type QReingestJobs interface {
+	EnqueueReingestJobs(ctx context.Context, ledgerRanges []LedgerRange) (int64, error)
+	ClaimReingestJob(ctx context.Context, owner string, lease time.Duration, maxAttempts int) (ReingestJob, bool, error)
+	RenewReingestJobLease(ctx context.Context, id int64, owner string, lease time.Duration) (bool, error)
+	CompleteReingestJob(ctx context.Context, id int64, owner string) (bool, error)
+	FailReingestJob(ctx context.Context, id int64, owner string, reason string, maxAttempts int) (bool, error)
+	GetReingestJobs(ctx context.Context) ([]ReingestJob, error)
+}
+
+// EnqueueReingestJobs adds a pending job for each of the given ranges.
+// Ranges which were already enqueued are left untouched, unless their job
+// failed, in which case it is reset so that it is retried. Returns the number
+// of jobs added or reset.
+func (q *Q) EnqueueReingestJobs(ctx context.Context, ledgerRanges []LedgerRange) (int64, error) {
+	if len(ledgerRanges) == 0 {
+		return 0, nil
+	}
+
+	sql := sq.Insert(reingestJobsTableName).Columns("from_ledger", "to_ledger")
+	for _, ledgerRange := range ledgerRanges {
+		sql = sql.Values(ledgerRange.StartSequence, ledgerRange.EndSequence)
+	}
+	sql = sql.Suffix(`ON CONFLICT (from_ledger, to_ledger) DO UPDATE SET
+		status = 'pending', attempts = 0, last_error = NULL, updated_at = (now() at time zone 'utc')
+		WHERE reingest_jobs.status = 'failed'`)
+
+	result, err := q.Exec(ctx, sql)
+	if err != nil {
+		return 0, errors.Wrap(err, "could not enqueue reingest jobs")
+	}
+	return result.RowsAffected()
+}
+
+// ClaimReingestJob leases the pending job with the lowest ledgers, or a
+// running job whose lease expired, to owner for the given duration. Returns
+// false if there is no job to claim. Concurrent workers never claim the same
+// job.
+//
+// Running jobs whose lease expired after maxAttempts attempts, e.g. because
+// their range keeps crashing the workers, are marked as failed instead of
+// being claimed again.
+func (q *Q) ClaimReingestJob(ctx context.Context, owner string, lease time.Duration, maxAttempts int) (ReingestJob, bool, error) {
+	_, err := q.ExecRaw(ctx, `
+		UPDATE reingest_jobs SET
+			status = 'failed',
+			lease_owner = NULL,
+			lease_expires_at = NULL,
+			last_error = 'lease expired after ' || attempts || ' attempts',
+			updated_at = (now() at time zone 'utc')
+		WHERE status = 'running'
+		AND lease_expires_at < (now() at time zone 'utc')
+		AND attempts >= ?`,
+		maxAttempts,
+	)
+	if err != nil {
+		return ReingestJob{}, false, errors.Wrap(err, "could not fail expired reingest jobs")
+	}
+
+	var job ReingestJob
+	err = q.GetRaw(ctx, &job, `
+		UPDATE reingest_jobs SET
+			status = 'running',
+			attempts = attempts + 1,
+			lease_owner = ?,
+			lease_expires_at = (now() at time zone 'utc') + ? * interval '1 second',
+			updated_at = (now() at time zone 'utc')
+		WHERE id = (
+			SELECT id FROM reingest_jobs
+			WHERE status = 'pending'
+			OR (
+				status = 'running'
+				AND lease_expires_at < (now() at time zone 'utc')
+				AND attempts < ?
+			)
+			ORDER BY from_ledger
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`,
+		owner, lease.Seconds(), maxAttempts,
+	)
+	if q.NoRows(err) {
+		return ReingestJob{}, false, nil
+	}
+	if err != nil {
+		return ReingestJob{}, false, errors.Wrap(err, "could not claim reingest job")
+	}
+	return job, true, nil
+}
+
+// RenewReingestJobLease extends the lease of a running job held by owner.
+// Returns false if the lease was lost, i.e. the job was claimed by another
+// worker after the lease expired.
+func (q *Q) RenewReingestJobLease(ctx context.Context, id int64, owner string, lease time.Duration) (bool, error) {
+	sql := sq.Update(reingestJobsTableName).
+		Set("lease_expires_at", sq.Expr("(now() at time zone 'utc') + ? * interval '1 second'", lease.Seconds())).
+		Set("updated_at", sq.Expr("(now() at time zone 'utc')")).
+		Where(sq.Eq{"id": id, "lease_owner": owner, "status": ReingestJobRunning})
+
+	rowCnt, err := q.checkForError(sql, ctx)
+	if err != nil {
+		return false, errors.Wrap(err, "could not renew reingest job lease")
+	}
+	return rowCnt > 0, nil
+}
+
+// CompleteReingestJob marks a job leased by owner as done. Returns false if
+// the lease was lost, in which case the job is left untouched.
+func (q *Q) CompleteReingestJob(ctx context.Context, id int64, owner string) (bool, error) {
+	sql := sq.Update(reingestJobsTableName).SetMap(map[string]interface{}{
+		"status":           ReingestJobDone,
+		"lease_owner":      nil,
+		"lease_expires_at": nil,
+		"last_error":       nil,
+		"updated_at":       sq.Expr("(now() at time zone 'utc')"),
+	}).Where(sq.Eq{"id": id, "lease_owner": owner})
+
+	rowCnt, err := q.checkForError(sql, ctx)
+	if err != nil {
+		return false, errors.Wrap(err, "could not complete reingest job")
+	}
+	return rowCnt > 0, nil
+}
+
+// FailReingestJob releases a job leased by owner after a failed attempt. The
+// job becomes pending again, to be retried, unless it reached maxAttempts in
+// which case it is marked as failed. Returns false if the lease was lost, in
+// which case the job is left untouched.
+func (q *Q) FailReingestJob(ctx context.Context, id int64, owner string, reason string, maxAttempts int) (bool, error) {
+	sql := sq.Update(reingestJobsTableName).SetMap(map[string]interface{}{
+		"status":           sq.Expr("CASE WHEN attempts >= ? THEN 'failed' ELSE 'pending' END", maxAttempts),
+		"lease_owner":      nil,
+		"lease_expires_at": nil,
+		"last_error":       reason,
+		"updated_at":       sq.Expr("(now() at time zone 'utc')"),
+	}).Where(sq.Eq{"id": id, "lease_owner": owner})
+
+	rowCnt, err := q.checkForError(sql, ctx)
+	if err != nil {
+		return false, errors.Wrap(err, "could not fail reingest job")
+	}
+	return rowCnt > 0, nil
+}
+
+// GetReingestJobs returns all the reingestion jobs ordered by ledger.
+func (q *Q) GetReingestJobs(ctx context.Context) ([]ReingestJob, error) {
+	var jobs []ReingestJob
+	sql := sq.Select("*").From(reingestJobsTableName).OrderBy("from_ledger ASC")
+	if err := q.Select(ctx, &jobs, sql); err != nil {
+		return nil, errors.Wrap(err, "could not get reingest jobs")
+	}
+	return jobs, nil
+}
@@ -0,0 +1,98 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pownieh/stellar_go/services/horizon/internal/test"
+)
+
+func TestReingestJobs(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetHorizonDB(t, tt.HorizonDB)
+	q := &Q{tt.HorizonSession()}
+
+	added, err := q.EnqueueReingestJobs(tt.Ctx, []LedgerRange{{1, 64}, {65, 128}})
+	tt.Assert.NoError(err)
+	tt.Assert.Equal(int64(2), added)
+
+	// ranges are enqueued once
+	added, err = q.EnqueueReingestJobs(tt.Ctx, []LedgerRange{{1, 64}})
+	tt.Assert.NoError(err)
+	tt.Assert.Equal(int64(0), added)
+
+	first, ok, err := q.ClaimReingestJob(tt.Ctx, "worker1", time.Minute, 3)
+	tt.Assert.NoError(err)
+	tt.Assert.True(ok)
+	tt.Assert.Equal(LedgerRange{1, 64}, first.LedgerRange())
+	tt.Assert.Equal(ReingestJobRunning, first.Status)
+	tt.Assert.Equal(1, first.Attempts)
+	tt.Assert.Equal("worker1", first.LeaseOwner.String)
+
+	second, ok, err := q.ClaimReingestJob(tt.Ctx, "worker2", time.Minute, 3)
+	tt.Assert.NoError(err)
+	tt.Assert.True(ok)
+	tt.Assert.Equal(LedgerRange{65, 128}, second.LedgerRange())
+
+	_, ok, err = q.ClaimReingestJob(tt.Ctx, "worker3", time.Minute, 3)
+	tt.Assert.NoError(err)
+	tt.Assert.False(ok)
+
+	renewed, err := q.RenewReingestJobLease(tt.Ctx, first.ID, "worker1", time.Minute)
+	tt.Assert.NoError(err)
+	tt.Assert.True(renewed)
+	renewed, err = q.RenewReingestJobLease(tt.Ctx, first.ID, "worker2", time.Minute)
+	tt.Assert.NoError(err)
+	tt.Assert.False(renewed)
+
+	completed, err := q.CompleteReingestJob(tt.Ctx, first.ID, "worker1")
+	tt.Assert.NoError(err)
+	tt.Assert.True(completed)
+	completed, err = q.CompleteReingestJob(tt.Ctx, second.ID, "worker1")
+	tt.Assert.NoError(err)
+	tt.Assert.False(completed)
+
+	// the failed job is retried until it reaches the maximum attempts
+	released, err := q.FailReingestJob(tt.Ctx, second.ID, "worker2", "failed because of foo", 2)
+	tt.Assert.NoError(err)
+	tt.Assert.True(released)
+	second, ok, err = q.ClaimReingestJob(tt.Ctx, "worker2", time.Minute, 3)
+	tt.Assert.NoError(err)
+	tt.Assert.True(ok)
+	tt.Assert.Equal(2, second.Attempts)
+	released, err = q.FailReingestJob(tt.Ctx, second.ID, "worker2", "failed because of bar", 2)
+	tt.Assert.NoError(err)
+	tt.Assert.True(released)
+
+	jobs, err := q.GetReingestJobs(tt.Ctx)
+	tt.Assert.NoError(err)
+	tt.Assert.Len(jobs, 2)
+	tt.Assert.Equal(ReingestJobDone, jobs[0].Status)
+	tt.Assert.Equal(ReingestJobFailed, jobs[1].Status)
+	tt.Assert.Equal("failed because of bar", jobs[1].LastError.String)
+
+	// enqueuing a failed range again resets it
+	added, err = q.EnqueueReingestJobs(tt.Ctx, []LedgerRange{{1, 64}, {65, 128}})
+	tt.Assert.NoError(err)
+	tt.Assert.Equal(int64(1), added)
+
+	// expired leases can be claimed by other workers
+	second, ok, err = q.ClaimReingestJob(tt.Ctx, "worker2", -time.Minute, 3)
+	tt.Assert.NoError(err)
+	tt.Assert.True(ok)
+	second, ok, err = q.ClaimReingestJob(tt.Ctx, "worker3", -time.Minute, 3)
+	tt.Assert.NoError(err)
+	tt.Assert.True(ok)
+	tt.Assert.Equal("worker3", second.LeaseOwner.String)
+	tt.Assert.Equal(2, second.Attempts)
+
+	// expired leases are not claimed once the job reached the maximum attempts
+	_, ok, err = q.ClaimReingestJob(tt.Ctx, "worker4", time.Minute, 2)
+	tt.Assert.NoError(err)
+	tt.Assert.False(ok)
+	jobs, err = q.GetReingestJobs(tt.Ctx)
+	tt.Assert.NoError(err)
+	tt.Assert.Equal(ReingestJobFailed, jobs[1].Status)
+	tt.Assert.Equal("lease expired after 2 attempts", jobs[1].LastError.String)
+}
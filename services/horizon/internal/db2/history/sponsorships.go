@@ -0,0 +1,220 @@
+package history
+
+import (
+	"context"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/pownieh/stellar_go/support/errors"
+	"github.com/pownieh/stellar_go/xdr"
+)
+
+// Sponsored entry types
+const (
+	SponsoredAccount          = "account"
+	SponsoredSigner           = "signer"
+	SponsoredTrustLine        = "trustline"
+	SponsoredData             = "data"
+	SponsoredOffer            = "offer"
+	SponsoredClaimableBalance = "claimable_balance"
+)
+
+// SponsoredEntry is a ledger entry, or a signer, in the state tables which is
+// sponsored by another account.
+type SponsoredEntry struct {
+	Type string `db:"entry_type"`
+	// AccountID is the account owning the entry. It's empty for claimable
+	// balances.
+	AccountID string `db:"account_id"`
+	Sponsor   string `db:"sponsor"`
+	// Key identifies the entry within its type: the account id, the signer
+	// key, the trust line asset (or liquidity pool id), the data name, the
+	// offer id or the claimable balance id.
+	Key string `db:"entry_key"`
+	// Reserves is the number of base reserves the sponsor pays for the entry.
+	Reserves int64 `db:"reserves"`
+}
+
+// SponsorshipTotal sums up the entries of a given type sponsored by an account.
+type SponsorshipTotal struct {
+	Type     string `db:"entry_type"`
+	Count    int64  `db:"count"`
+	Reserves int64  `db:"reserves"`
+}
+
+// sponsoredEntryTable describes how to read sponsored entries from one of the
+// state tables.
+type sponsoredEntryTable struct {
+	entryType string
+	table     string
+	// account is the column holding the owner of the entry, empty if the
+	// entry has no owner.
+	account  string
+	key      string
+	reserves string
+	filter   sq.Sqlizer
+}
+
+var sponsoredEntryTables = []sponsoredEntryTable{
+	{
+		entryType: SponsoredAccount,
+		table:     "accounts",
+		account:   "account_id",
+		key:       "account_id",
+		reserves:  "2",
+	},
+	{
+		entryType: SponsoredSigner,
+		table:     "accounts_signers",
+		account:   "account_id",
+		key:       "signer",
+		reserves:  "1",
+	},
+	{
+		entryType: SponsoredTrustLine,
+		table:     "trust_lines",
+		account:   "account_id",
+		key: fmt.Sprintf(
+			"CASE WHEN asset_type = %d THEN liquidity_pool_id ELSE asset_code || ':' || asset_issuer END",
+			xdr.AssetTypeAssetTypePoolShare,
+		),
+		// pool share trust lines count as two subentries
+		reserves: fmt.Sprintf("CASE WHEN asset_type = %d THEN 2 ELSE 1 END", xdr.AssetTypeAssetTypePoolShare),
+	},
+	{
+		entryType: SponsoredData,
+		table:     "accounts_data",
+		account:   "account_id",
+		key:       "name",
+		reserves:  "1",
+	},
+	{
+		entryType: SponsoredOffer,
+		table:     "offers",
+		account:   "seller_id",
+		key:       "offer_id::text",
+		reserves:  "1",
+		filter:    sq.Eq{"deleted": false},
+	},
+	{
+		entryType: SponsoredClaimableBalance,
+		table:     "claimable_balances",
+		key:       "id",
+		// the sponsor pays one base reserve per claimant
+		reserves: "jsonb_array_length(claimants)",
+	},
+}
+
+func (t sponsoredEntryTable) selectEntries() sq.SelectBuilder {
+	account := "''"
+	if t.account != "" {
+		account = t.account
+	}
+
+	sql := sq.Select(
+		fmt.Sprintf("'%s' AS entry_type", t.entryType),
+		account+" AS account_id",
+		"sponsor",
+		t.key+" AS entry_key",
+		t.reserves+" AS reserves",
+	).From(t.table)
+	if t.filter != nil {
+		sql = sql.Where(t.filter)
+	}
+	return sql
+}
+
+// unionAll combines the given queries into a single `UNION ALL` query.
+func unionAll(queries []sq.SelectBuilder) (sq.SelectBuilder, error) {
+	var union sq.SelectBuilder
+	for i, sql := range queries {
+		sql = sql.Prefix("(").Suffix(")")
+		if i == 0 {
+			union = sql
+			continue
+		}
+
+		sqlStr, args, err := sql.ToSql()
+		if err != nil {
+			return union, errors.Wrap(err, "could not construct sponsored entries query")
+		}
+		union = union.Suffix("UNION ALL "+sqlStr, args...)
+	}
+	return union, nil
+}
+
+// SponsorshipTotalsForSponsor returns, for every type of entry, the number of
+// entries and reserves sponsored by `sponsor`.
+func (q *Q) SponsorshipTotalsForSponsor(ctx context.Context, sponsor string) ([]SponsorshipTotal, error) {
+	queries := make([]sq.SelectBuilder, 0, len(sponsoredEntryTables))
+	for _, t := range sponsoredEntryTables {
+		queries = append(queries, t.selectEntries().Where(sq.Eq{"sponsor": sponsor}))
+	}
+	union, err := unionAll(queries)
+	if err != nil {
+		return nil, err
+	}
+
+	sql := sq.Select("entry_type", "COUNT(*) AS count", "SUM(reserves) AS reserves").
+		FromSelect(union, "entries").
+		GroupBy("entry_type")
+
+	var results []SponsorshipTotal
+	if err := q.Select(ctx, &results, sql); err != nil {
+		return nil, errors.Wrap(err, "could not run select query")
+	}
+	return results, nil
+}
+
+// SponsoredEntriesForSponsor returns the entries sponsored by `sponsor`. At
+// most `limit` entries of every type are returned, ordered by their key.
+func (q *Q) SponsoredEntriesForSponsor(ctx context.Context, sponsor string, limit uint64) ([]SponsoredEntry, error) {
+	queries := make([]sq.SelectBuilder, 0, len(sponsoredEntryTables))
+	for _, t := range sponsoredEntryTables {
+		queries = append(queries, t.selectEntries().
+			Where(sq.Eq{"sponsor": sponsor}).
+			OrderBy("entry_key").
+			Limit(limit),
+		)
+	}
+	union, err := unionAll(queries)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []SponsoredEntry
+	if err := q.Select(ctx, &results, union); err != nil {
+		return nil, errors.Wrap(err, "could not run select query")
+	}
+	return results, nil
+}
+
+// SponsoredEntriesForAccount returns the entries of `account` which are
+// sponsored by another account, ordered by sponsor.
+func (q *Q) SponsoredEntriesForAccount(ctx context.Context, account string) ([]SponsoredEntry, error) {
+	queries := make([]sq.SelectBuilder, 0, len(sponsoredEntryTables))
+	for _, t := range sponsoredEntryTables {
+		if t.account == "" {
+			continue
+		}
+		queries = append(queries, t.selectEntries().
+			Where(sq.Eq{t.account: account}).
+			Where("sponsor IS NOT NULL"),
+		)
+	}
+	union, err := unionAll(queries)
+	if err != nil {
+		return nil, err
+	}
+
+	sql := sq.Select("*").
+		FromSelect(union, "entries").
+		OrderBy("sponsor", "entry_type", "entry_key")
+
+	var results []SponsoredEntry
+	if err := q.Select(ctx, &results, sql); err != nil {
+		return nil, errors.Wrap(err, "could not run select query")
+	}
+	return results, nil
+}
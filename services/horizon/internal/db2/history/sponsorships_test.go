@@ -0,0 +1,54 @@
+package history
+
+import (
+	"testing"
+
+	"github.com/pownieh/stellar_go/services/horizon/internal/test"
+)
+
+func TestSponsorships(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetHorizonDB(t, tt.HorizonDB)
+	q := &Q{tt.HorizonSession()}
+
+	tt.Assert.NoError(q.UpsertAccounts(tt.Ctx, []AccountEntry{account1, account2, account3}))
+	tt.Assert.NoError(q.UpsertTrustLines(tt.Ctx, []TrustLine{eurTrustLine, usdTrustLine}))
+	tt.Assert.NoError(q.UpsertAccountData(tt.Ctx, []Data{data1, data2}))
+	tt.Assert.NoError(q.UpsertOffers(tt.Ctx, []Offer{xlmOffer, eurOffer}))
+	signerSponsor := sponsor
+	_, err := q.CreateAccountSigner(tt.Ctx, account1.AccountID, account3.AccountID, 1, &signerSponsor)
+	tt.Assert.NoError(err)
+	_, err = q.CreateAccountSigner(tt.Ctx, account1.AccountID, account2.AccountID, 1, nil)
+	tt.Assert.NoError(err)
+
+	totals, err := q.SponsorshipTotalsForSponsor(tt.Ctx, sponsor)
+	tt.Assert.NoError(err)
+	tt.Assert.ElementsMatch([]SponsorshipTotal{
+		{Type: SponsoredAccount, Count: 1, Reserves: 2},
+		{Type: SponsoredSigner, Count: 1, Reserves: 1},
+		{Type: SponsoredTrustLine, Count: 1, Reserves: 1},
+		{Type: SponsoredOffer, Count: 1, Reserves: 1},
+	}, totals)
+
+	entries, err := q.SponsoredEntriesForSponsor(tt.Ctx, sponsor, 10)
+	tt.Assert.NoError(err)
+	tt.Assert.ElementsMatch([]SponsoredEntry{
+		{Type: SponsoredAccount, AccountID: account2.AccountID, Sponsor: sponsor, Key: account2.AccountID, Reserves: 2},
+		{Type: SponsoredSigner, AccountID: account1.AccountID, Sponsor: sponsor, Key: account3.AccountID, Reserves: 1},
+		{Type: SponsoredTrustLine, AccountID: account1.AccountID, Sponsor: sponsor, Key: "EUR:" + trustLineIssuer, Reserves: 1},
+		{Type: SponsoredOffer, AccountID: eurOffer.SellerID, Sponsor: sponsor, Key: "4", Reserves: 1},
+	}, entries)
+
+	entries, err = q.SponsoredEntriesForAccount(tt.Ctx, account1.AccountID)
+	tt.Assert.NoError(err)
+	tt.Assert.Equal([]SponsoredEntry{
+		{Type: SponsoredData, AccountID: account1.AccountID, Sponsor: data2.Sponsor.String, Key: data2.Name, Reserves: 1},
+		{Type: SponsoredSigner, AccountID: account1.AccountID, Sponsor: sponsor, Key: account3.AccountID, Reserves: 1},
+		{Type: SponsoredTrustLine, AccountID: account1.AccountID, Sponsor: sponsor, Key: "EUR:" + trustLineIssuer, Reserves: 1},
+	}, entries)
+
+	entries, err = q.SponsoredEntriesForAccount(tt.Ctx, account3.AccountID)
+	tt.Assert.NoError(err)
+	tt.Assert.Empty(entries)
+}
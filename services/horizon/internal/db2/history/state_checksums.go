@@ -0,0 +1,214 @@
+package history
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/pownieh/stellar_go/support/errors"
+	"github.com/pownieh/stellar_go/xdr"
+)
+
+const (
+	stateChecksumsKey          = "state_checksums"
+	stateChecksumOffsetsKey    = "state_checksum_offsets"
+	stateVerificationResultKey = "state_verification_result"
+)
+
+// StateChecksumEntryTypes are the types of the ledger entries stored in the
+// state tables, whose checksums are maintained by ingestion.
+var StateChecksumEntryTypes = []xdr.LedgerEntryType{
+	xdr.LedgerEntryTypeAccount,
+	xdr.LedgerEntryTypeTrustline,
+	xdr.LedgerEntryTypeOffer,
+	xdr.LedgerEntryTypeData,
+	xdr.LedgerEntryTypeClaimableBalance,
+	xdr.LedgerEntryTypeLiquidityPool,
+}
+
+// StateChecksum is an order independent checksum of a set of ledger entries:
+// the sum, lane by lane, of the SHA-256 hashes of the entries. Entries can be
+// added and removed from the set by adding and subtracting their checksum.
+type StateChecksum [4]uint64
+
+// NewStateChecksum returns the checksum of the set containing only entry. The
+// entry is normalized first, so that equivalent entries have the same checksum.
+func NewStateChecksum(entry xdr.LedgerEntry) (StateChecksum, error) {
+	// entry is copied before normalizing it because Normalize modifies the
+	// entries it points to
+	raw, err := entry.MarshalBinary()
+	if err != nil {
+		return StateChecksum{}, errors.Wrap(err, "could not marshal ledger entry")
+	}
+	var normalized xdr.LedgerEntry
+	if err = xdr.SafeUnmarshal(raw, &normalized); err != nil {
+		return StateChecksum{}, errors.Wrap(err, "could not unmarshal ledger entry")
+	}
+	if raw, err = normalized.Normalize().MarshalBinary(); err != nil {
+		return StateChecksum{}, errors.Wrap(err, "could not marshal normalized ledger entry")
+	}
+
+	hash := sha256.Sum256(raw)
+	var checksum StateChecksum
+	for i := range checksum {
+		checksum[i] = binary.BigEndian.Uint64(hash[i*8:])
+	}
+	return checksum, nil
+}
+
+// Add returns the checksum of the union of the sets.
+func (c StateChecksum) Add(other StateChecksum) StateChecksum {
+	for i := range c {
+		c[i] += other[i]
+	}
+	return c
+}
+
+// Sub returns the checksum of the set without the entries of other.
+func (c StateChecksum) Sub(other StateChecksum) StateChecksum {
+	for i := range c {
+		c[i] -= other[i]
+	}
+	return c
+}
+
+func (c StateChecksum) String() string {
+	var raw [32]byte
+	for i := range c {
+		binary.BigEndian.PutUint64(raw[i*8:], c[i])
+	}
+	return hex.EncodeToString(raw[:])
+}
+
+func (c StateChecksum) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+func (c *StateChecksum) UnmarshalText(text []byte) error {
+	raw, err := hex.DecodeString(string(text))
+	if err != nil {
+		return err
+	}
+	if len(raw) != 32 {
+		return errors.Errorf("invalid state checksum length %d", len(raw))
+	}
+	for i := range c {
+		c[i] = binary.BigEndian.Uint64(raw[i*8:])
+	}
+	return nil
+}
+
+// StateChecksums are state checksums by ledger entry type.
+type StateChecksums map[xdr.LedgerEntryType]StateChecksum
+
+// StateVerificationResult is the result of the last state verification.
+type StateVerificationResult struct {
+	Ledger     uint32    `json:"ledger"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	// Incremental is true when the state was verified by comparing checksums,
+	// falling back to reading the state tables only for the entry types
+	// whose checksum did not match.
+	Incremental bool `json:"incremental"`
+	// Types are the results of each ledger entry type.
+	Types []StateVerificationTypeResult `json:"types"`
+	Error string                        `json:"error,omitempty"`
+}
+
+// StateVerificationTypeResult is the result of the verification of the
+// ledger entries of one type.
+type StateVerificationTypeResult struct {
+	Type string `json:"type"`
+	// Expected is the checksum of the entries in the checkpoint and Actual
+	// the checksum maintained by ingestion, which is empty when unknown.
+	Expected string `json:"expected_checksum"`
+	Actual   string `json:"actual_checksum,omitempty"`
+	// FullVerification is true when the entries were verified against the
+	// state tables because the checksums did not match.
+	FullVerification bool  `json:"full_verification"`
+	Entries          int64 `json:"entries"`
+}
+
+// QStateChecksums defines the state checksums related queries.
+type QStateChecksums interface {
+	GetStateChecksums(ctx context.Context) (StateChecksums, error)
+	UpdateStateChecksums(ctx context.Context, checksums StateChecksums) error
+	GetStateChecksumOffsets(ctx context.Context) (StateChecksums, error)
+	UpdateStateChecksumOffsets(ctx context.Context, offsets StateChecksums) error
+}
+
+// GetStateChecksums returns the rolling checksums of the state tables, which
+// ingestion updates with the changes of every ledger. They only match the
+// checksums of the state once adjusted by the offsets returned by
+// GetStateChecksumOffsets.
+func (q *Q) GetStateChecksums(ctx context.Context) (StateChecksums, error) {
+	return q.getStateChecksums(ctx, stateChecksumsKey)
+}
+
+// UpdateStateChecksums updates the rolling checksums of the state tables.
+func (q *Q) UpdateStateChecksums(ctx context.Context, checksums StateChecksums) error {
+	return q.updateStateChecksums(ctx, stateChecksumsKey, checksums)
+}
+
+// GetStateChecksumOffsets returns the offsets to add to the rolling checksums
+// to obtain the checksums of the state. The offset of a type is missing when
+// it is unknown, e.g. until the state is verified for the first time after
+// upgrading.
+func (q *Q) GetStateChecksumOffsets(ctx context.Context) (StateChecksums, error) {
+	return q.getStateChecksums(ctx, stateChecksumOffsetsKey)
+}
+
+// UpdateStateChecksumOffsets updates the offsets of the rolling checksums.
+func (q *Q) UpdateStateChecksumOffsets(ctx context.Context, offsets StateChecksums) error {
+	return q.updateStateChecksums(ctx, stateChecksumOffsetsKey, offsets)
+}
+
+func (q *Q) getStateChecksums(ctx context.Context, key string) (StateChecksums, error) {
+	value, err := q.getValueFromStore(ctx, key, false)
+	if err != nil {
+		return nil, err
+	}
+	checksums := StateChecksums{}
+	if value == "" {
+		return checksums, nil
+	}
+	if err = json.Unmarshal([]byte(value), &checksums); err != nil {
+		return nil, errors.Wrapf(err, "invalid %s value", key)
+	}
+	return checksums, nil
+}
+
+func (q *Q) updateStateChecksums(ctx context.Context, key string, checksums StateChecksums) error {
+	value, err := json.Marshal(checksums)
+	if err != nil {
+		return err
+	}
+	return q.updateValueInStore(ctx, key, string(value))
+}
+
+// GetStateVerificationResult returns the result of the last state
+// verification, or nil if the state was never verified.
+func (q *Q) GetStateVerificationResult(ctx context.Context) (*StateVerificationResult, error) {
+	value, err := q.getValueFromStore(ctx, stateVerificationResultKey, false)
+	if err != nil || value == "" {
+		return nil, err
+	}
+	var result StateVerificationResult
+	if err = json.Unmarshal([]byte(value), &result); err != nil {
+		return nil, errors.Wrapf(err, "invalid %s value", stateVerificationResultKey)
+	}
+	return &result, nil
+}
+
+// UpdateStateVerificationResult stores the result of the last state
+// verification.
+func (q *Q) UpdateStateVerificationResult(ctx context.Context, result StateVerificationResult) error {
+	value, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	return q.updateValueInStore(ctx, stateVerificationResultKey, string(value))
+}
@@ -0,0 +1,389 @@
+package history
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/pownieh/stellar_go/support/db"
+	"github.com/pownieh/stellar_go/support/errors"
+	"github.com/pownieh/stellar_go/xdr"
+)
+
+// StateRebuildStatus is the status of a zero-downtime state rebuild.
+type StateRebuildStatus string
+
+const (
+	// StateRebuildRequested rebuilds start at the next checkpoint ledger
+	// ingested.
+	StateRebuildRequested StateRebuildStatus = "requested"
+	// StateRebuildBuilding rebuilds populate the shadow state tables with the
+	// ledger entries of the checkpoint.
+	StateRebuildBuilding StateRebuildStatus = "building"
+	// StateRebuildCatchingUp rebuilds apply the ledgers ingested since the
+	// checkpoint to the shadow state tables.
+	StateRebuildCatchingUp StateRebuildStatus = "catching_up"
+	// StateRebuildCompleted rebuilds swapped the shadow state tables with the
+	// state tables.
+	StateRebuildCompleted StateRebuildStatus = "completed"
+	// StateRebuildFailed rebuilds stopped because of an error, the state
+	// tables were left untouched.
+	StateRebuildFailed StateRebuildStatus = "failed"
+)
+
+const (
+	stateRebuildKey = "state_rebuild"
+	// ShadowStateSchema is the schema of the shadow state tables populated by
+	// zero-downtime state rebuilds.
+	ShadowStateSchema             = "horizon_shadow"
+	stateRebuildLedgersTableName  = "state_rebuild_ledgers"
+	stateRebuildLockTimeout       = "5s"
+	shadowStateKeyValueStoreTable = ShadowStateSchema + ".key_value_store"
+	// stateRebuildLockId is the objid for the advisory lock acquired while
+	// the shadow state tables are populated.
+	stateRebuildLockId = 73897214
+)
+
+// StateTables are the state tables populated by the ingestion system using
+// history archive snapshots.
+var StateTables = []string{
+	"accounts",
+	"accounts_data",
+	"accounts_signers",
+	"claimable_balances",
+	"claimable_balance_claimants",
+	"exp_asset_stats",
+	"liquidity_pools",
+	"offers",
+	"trust_lines",
+}
+
+// StateRebuild is the progress of the last zero-downtime state rebuild.
+type StateRebuild struct {
+	Status StateRebuildStatus `json:"status"`
+	// CheckpointLedger is the checkpoint of the ledger entries inserted in
+	// the shadow state tables.
+	CheckpointLedger uint32 `json:"checkpoint_ledger,omitempty"`
+	// ShadowLedger is the last ledger applied to the shadow state tables.
+	ShadowLedger uint32 `json:"shadow_ledger,omitempty"`
+	// EntriesProcessed is the number of ledger entries of the checkpoint
+	// inserted in the shadow state tables and Progress the percentage of the
+	// checkpoint processed.
+	EntriesProcessed int64      `json:"entries_processed"`
+	Progress         float64    `json:"progress"`
+	RequestedAt      *time.Time `json:"requested_at,omitempty"`
+	StartedAt        *time.Time `json:"started_at,omitempty"`
+	FinishedAt       *time.Time `json:"finished_at,omitempty"`
+	Error            string     `json:"error,omitempty"`
+}
+
+// InProgress returns true when the shadow state tables are being populated.
+func (r StateRebuild) InProgress() bool {
+	return r.Status == StateRebuildBuilding || r.Status == StateRebuildCatchingUp
+}
+
+// QStateRebuild defines the zero-downtime state rebuild related queries.
+type QStateRebuild interface {
+	GetStateRebuild(ctx context.Context) (StateRebuild, error)
+	GetStateRebuildForUpdate(ctx context.Context) (StateRebuild, error)
+	UpdateStateRebuild(ctx context.Context, rebuild StateRebuild) error
+	TryStateRebuildLock(ctx context.Context) (bool, error)
+	InsertStateRebuildLedger(ctx context.Context, ledger xdr.LedgerCloseMeta) error
+	GetStateRebuildLedgers(ctx context.Context, fromSequence uint32, limit uint64) ([]xdr.LedgerCloseMeta, error)
+	DeleteStateRebuildLedgers(ctx context.Context, toSequence uint32) error
+	TruncateStateRebuildLedgers(ctx context.Context) error
+	CreateShadowStateTables(ctx context.Context, tables []string) error
+	UseShadowStateTables(ctx context.Context, shadow bool) error
+	SwapShadowStateTables(ctx context.Context, tables []string) error
+}
+
+// GetStateRebuild returns the progress of the last zero-downtime state
+// rebuild. The status is empty if the state was never rebuilt.
+func (q *Q) GetStateRebuild(ctx context.Context) (StateRebuild, error) {
+	return q.getStateRebuild(ctx, false)
+}
+
+// GetStateRebuildForUpdate works like GetStateRebuild but it blocks the
+// value until the end of the transaction. The ingestion system uses it to
+// serialize the updates of the shadow state tables.
+func (q *Q) GetStateRebuildForUpdate(ctx context.Context) (StateRebuild, error) {
+	return q.getStateRebuild(ctx, true)
+}
+
+func (q *Q) getStateRebuild(ctx context.Context, forUpdate bool) (StateRebuild, error) {
+	var rebuild StateRebuild
+	value, err := q.getValueFromStore(ctx, stateRebuildKey, forUpdate)
+	if err != nil || value == "" {
+		return rebuild, err
+	}
+	if err = json.Unmarshal([]byte(value), &rebuild); err != nil {
+		return rebuild, errors.Wrapf(err, "invalid %s value", stateRebuildKey)
+	}
+	return rebuild, nil
+}
+
+// UpdateStateRebuild updates the progress of the zero-downtime state rebuild.
+func (q *Q) UpdateStateRebuild(ctx context.Context, rebuild StateRebuild) error {
+	value, err := json.Marshal(rebuild)
+	if err != nil {
+		return err
+	}
+	return q.updateValueInStore(ctx, stateRebuildKey, string(value))
+}
+
+// TryStateRebuildLock attempts to acquire the lock which gives the ingesting
+// node exclusive access to populate the shadow state tables. The lock is
+// released at the end of the transaction.
+func (q *Q) TryStateRebuildLock(ctx context.Context) (bool, error) {
+	if tx := q.GetTx(); tx == nil {
+		return false, errors.New("cannot be called outside of a transaction")
+	}
+
+	var acquired []bool
+	err := q.SelectRaw(
+		context.WithValue(ctx, &db.QueryTypeContextKey, db.AdvisoryLockQueryType),
+		&acquired,
+		"SELECT pg_try_advisory_xact_lock(?)",
+		stateRebuildLockId,
+	)
+	if err != nil {
+		return false, errors.Wrap(err, "error acquiring advisory lock for state rebuild")
+	}
+	if len(acquired) != 1 {
+		return false, errors.New("invalid response from advisory lock")
+	}
+	return acquired[0], nil
+}
+
+// InsertStateRebuildLedger buffers a ledger ingested while the shadow state
+// tables are populated, it is applied to them before they are swapped.
+func (q *Q) InsertStateRebuildLedger(ctx context.Context, ledger xdr.LedgerCloseMeta) error {
+	raw, err := ledger.MarshalBinary()
+	if err != nil {
+		return errors.Wrap(err, "could not marshal ledger")
+	}
+	sql := sq.Insert(stateRebuildLedgersTableName).
+		Columns("sequence", "ledger_close_meta").
+		Values(ledger.LedgerSequence(), raw).
+		Suffix("ON CONFLICT (sequence) DO UPDATE SET ledger_close_meta = EXCLUDED.ledger_close_meta")
+	_, err = q.Exec(ctx, sql)
+	return err
+}
+
+// GetStateRebuildLedgers returns the buffered ledgers starting from
+// fromSequence, ordered by sequence. All the ledgers are returned when limit
+// is 0.
+func (q *Q) GetStateRebuildLedgers(ctx context.Context, fromSequence uint32, limit uint64) ([]xdr.LedgerCloseMeta, error) {
+	sql := sq.Select("ledger_close_meta").
+		From(stateRebuildLedgersTableName).
+		Where("sequence >= ?", fromSequence).
+		OrderBy("sequence asc")
+	if limit > 0 {
+		sql = sql.Limit(limit)
+	}
+
+	var rows [][]byte
+	if err := q.Select(ctx, &rows, sql); err != nil {
+		return nil, errors.Wrap(err, "could not get buffered ledgers")
+	}
+
+	ledgers := make([]xdr.LedgerCloseMeta, len(rows))
+	for i, raw := range rows {
+		if err := ledgers[i].UnmarshalBinary(raw); err != nil {
+			return nil, errors.Wrap(err, "could not unmarshal buffered ledger")
+		}
+	}
+	return ledgers, nil
+}
+
+// DeleteStateRebuildLedgers deletes the buffered ledgers up to toSequence
+// (inclusive).
+func (q *Q) DeleteStateRebuildLedgers(ctx context.Context, toSequence uint32) error {
+	sql := sq.Delete(stateRebuildLedgersTableName).Where("sequence <= ?", toSequence)
+	_, err := q.Exec(ctx, sql)
+	return err
+}
+
+// TruncateStateRebuildLedgers deletes all the buffered ledgers.
+func (q *Q) TruncateStateRebuildLedgers(ctx context.Context) error {
+	return q.TruncateTables(ctx, []string{stateRebuildLedgersTableName})
+}
+
+// CreateShadowStateTables creates empty copies of the given state tables, and
+// of the key value store, in the shadow schema. The copies have the same
+// columns, constraints and indexes, with the same names, so that they can
+// replace the state tables. Previous shadow tables are dropped.
+func (q *Q) CreateShadowStateTables(ctx context.Context, tables []string) error {
+	if _, err := q.ExecRaw(ctx, "DROP SCHEMA IF EXISTS "+ShadowStateSchema+" CASCADE"); err != nil {
+		return errors.Wrap(err, "could not drop shadow schema")
+	}
+	if _, err := q.ExecRaw(ctx, "CREATE SCHEMA "+ShadowStateSchema); err != nil {
+		return errors.Wrap(err, "could not create shadow schema")
+	}
+	_, err := q.ExecRaw(ctx, fmt.Sprintf(
+		"CREATE TABLE %s (LIKE public.key_value_store INCLUDING ALL)", shadowStateKeyValueStoreTable,
+	))
+	if err != nil {
+		return errors.Wrap(err, "could not create shadow key value store")
+	}
+
+	for _, table := range tables {
+		if err = q.createShadowStateTable(ctx, table); err != nil {
+			return errors.Wrapf(err, "could not create shadow table of %s", table)
+		}
+	}
+	return nil
+}
+
+type stateTableIndex struct {
+	Name           string `db:"name"`
+	Definition     string `db:"definition"`
+	Constraint     string `db:"constraint_name"`
+	ConstraintType string `db:"constraint_type"`
+}
+
+func (q *Q) createShadowStateTable(ctx context.Context, table string) error {
+	shadowTable := ShadowStateSchema + "." + table
+	_, err := q.ExecRaw(ctx, fmt.Sprintf(
+		"CREATE TABLE %s (LIKE public.%s INCLUDING DEFAULTS INCLUDING CONSTRAINTS INCLUDING STORAGE)",
+		shadowTable, table,
+	))
+	if err != nil {
+		return err
+	}
+
+	var indexes []stateTableIndex
+	err = q.SelectRaw(ctx, &indexes, `
+		SELECT
+			ic.relname AS name,
+			pg_get_indexdef(i.indexrelid) AS definition,
+			COALESCE(c.conname, '') AS constraint_name,
+			COALESCE(c.contype::text, '') AS constraint_type
+		FROM pg_index i
+		JOIN pg_class ic ON ic.oid = i.indexrelid
+		LEFT JOIN pg_constraint c ON c.conindid = i.indexrelid AND c.contype IN ('p', 'u')
+		WHERE i.indrelid = ?::regclass
+		ORDER BY ic.relname`, "public."+table)
+	if err != nil {
+		return errors.Wrap(err, "could not get indexes")
+	}
+
+	for _, index := range indexes {
+		definition := index.Definition
+		for _, name := range []string{"public." + table, table} {
+			on := " ON " + name + " "
+			if strings.Contains(definition, on) {
+				definition = strings.Replace(definition, on, " ON "+shadowTable+" ", 1)
+				break
+			}
+		}
+		if !strings.Contains(definition, " ON "+shadowTable+" ") {
+			return errors.Errorf("unexpected definition of index %s: %s", index.Name, definition)
+		}
+		if _, err = q.ExecRaw(ctx, definition); err != nil {
+			return errors.Wrapf(err, "could not create index %s", index.Name)
+		}
+
+		var constraint string
+		switch index.ConstraintType {
+		case "p":
+			constraint = "PRIMARY KEY"
+		case "u":
+			constraint = "UNIQUE"
+		default:
+			continue
+		}
+		_, err = q.ExecRaw(ctx, fmt.Sprintf(
+			"ALTER TABLE %s ADD CONSTRAINT %s %s USING INDEX %s",
+			shadowTable, index.Constraint, constraint, index.Name,
+		))
+		if err != nil {
+			return errors.Wrapf(err, "could not add constraint %s", index.Constraint)
+		}
+	}
+	return nil
+}
+
+// UseShadowStateTables makes the statements of the current transaction use
+// the shadow state tables, and the shadow key value store, instead of the
+// state tables until it is called again with shadow set to false.
+func (q *Q) UseShadowStateTables(ctx context.Context, shadow bool) error {
+	if tx := q.GetTx(); tx == nil {
+		return errors.New("cannot be called outside of a transaction")
+	}
+
+	sql := "SET LOCAL search_path TO DEFAULT"
+	if shadow {
+		sql = "SET LOCAL search_path TO " + ShadowStateSchema + ", public"
+	}
+	_, err := q.ExecRaw(ctx, sql)
+	return err
+}
+
+// SwapShadowStateTables replaces the given state tables with their shadow
+// tables, along with the state checksums, and drops the shadow schema. The
+// privileges on the state tables are granted on their replacements. The
+// statements wait at most a few seconds for the queries reading the state
+// tables, pg.IsLockNotAvailable returns true for the error returned when they
+// time out.
+func (q *Q) SwapShadowStateTables(ctx context.Context, tables []string) error {
+	if tx := q.GetTx(); tx == nil {
+		return errors.New("cannot be called outside of a transaction")
+	}
+
+	if _, err := q.ExecRaw(ctx, "SET LOCAL lock_timeout TO '"+stateRebuildLockTimeout+"'"); err != nil {
+		return err
+	}
+
+	for _, table := range tables {
+		var grants []struct {
+			Grantee   string `db:"grantee"`
+			Privilege string `db:"privilege_type"`
+		}
+		err := q.SelectRaw(ctx, &grants, `
+			SELECT grantee, privilege_type FROM information_schema.role_table_grants
+			WHERE table_schema = 'public' AND table_name = ?`, table)
+		if err != nil {
+			return errors.Wrapf(err, "could not get privileges on %s", table)
+		}
+		for _, grant := range grants {
+			grantee := grant.Grantee
+			if grantee != "PUBLIC" {
+				grantee = `"` + strings.ReplaceAll(grantee, `"`, `""`) + `"`
+			}
+			_, err = q.ExecRaw(ctx, fmt.Sprintf(
+				"GRANT %s ON %s.%s TO %s", grant.Privilege, ShadowStateSchema, table, grantee,
+			))
+			if err != nil {
+				return errors.Wrapf(err, "could not grant privileges on shadow table of %s", table)
+			}
+		}
+
+		if _, err = q.ExecRaw(ctx, "DROP TABLE public."+table); err != nil {
+			return errors.Wrapf(err, "could not drop %s", table)
+		}
+		if _, err = q.ExecRaw(ctx, fmt.Sprintf("ALTER TABLE %s.%s SET SCHEMA public", ShadowStateSchema, table)); err != nil {
+			return errors.Wrapf(err, "could not move shadow table of %s", table)
+		}
+	}
+
+	_, err := q.ExecRaw(ctx, fmt.Sprintf(`
+		INSERT INTO public.key_value_store (key, value)
+		SELECT key, value FROM %s WHERE key IN (?, ?)
+		ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value`, shadowStateKeyValueStoreTable),
+		stateChecksumsKey, stateChecksumOffsetsKey,
+	)
+	if err != nil {
+		return errors.Wrap(err, "could not copy state checksums")
+	}
+
+	if _, err = q.ExecRaw(ctx, "DROP SCHEMA "+ShadowStateSchema+" CASCADE"); err != nil {
+		return errors.Wrap(err, "could not drop shadow schema")
+	}
+	_, err = q.ExecRaw(ctx, "SET LOCAL lock_timeout TO DEFAULT")
+	return err
+}
@@ -0,0 +1,118 @@
+package history
+
+import (
+	"testing"
+
+	"github.com/pownieh/stellar_go/services/horizon/internal/test"
+	"github.com/pownieh/stellar_go/xdr"
+)
+
+func TestStateRebuildLedgers(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetHorizonDB(t, tt.HorizonDB)
+	q := &Q{tt.HorizonSession()}
+
+	for sequence := uint32(65); sequence <= 68; sequence++ {
+		ledger := xdr.LedgerCloseMeta{
+			V0: &xdr.LedgerCloseMetaV0{
+				LedgerHeader: xdr.LedgerHeaderHistoryEntry{
+					Header: xdr.LedgerHeader{LedgerSeq: xdr.Uint32(sequence)},
+				},
+			},
+		}
+		tt.Assert.NoError(q.InsertStateRebuildLedger(tt.Ctx, ledger))
+	}
+
+	ledgers, err := q.GetStateRebuildLedgers(tt.Ctx, 66, 2)
+	tt.Assert.NoError(err)
+	tt.Assert.Len(ledgers, 2)
+	tt.Assert.Equal(uint32(66), ledgers[0].LedgerSequence())
+	tt.Assert.Equal(uint32(67), ledgers[1].LedgerSequence())
+
+	tt.Assert.NoError(q.DeleteStateRebuildLedgers(tt.Ctx, 67))
+	ledgers, err = q.GetStateRebuildLedgers(tt.Ctx, 0, 0)
+	tt.Assert.NoError(err)
+	tt.Assert.Len(ledgers, 1)
+	tt.Assert.Equal(uint32(68), ledgers[0].LedgerSequence())
+
+	tt.Assert.NoError(q.TruncateStateRebuildLedgers(tt.Ctx))
+	ledgers, err = q.GetStateRebuildLedgers(tt.Ctx, 0, 0)
+	tt.Assert.NoError(err)
+	tt.Assert.Empty(ledgers)
+}
+
+func TestStateRebuildStatus(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetHorizonDB(t, tt.HorizonDB)
+	q := &Q{tt.HorizonSession()}
+
+	rebuild, err := q.GetStateRebuild(tt.Ctx)
+	tt.Assert.NoError(err)
+	tt.Assert.Equal(StateRebuild{}, rebuild)
+
+	expected := StateRebuild{
+		Status:           StateRebuildCatchingUp,
+		CheckpointLedger: 63,
+		ShadowLedger:     70,
+		EntriesProcessed: 1000,
+		Progress:         100,
+	}
+	tt.Assert.NoError(q.UpdateStateRebuild(tt.Ctx, expected))
+	rebuild, err = q.GetStateRebuild(tt.Ctx)
+	tt.Assert.NoError(err)
+	tt.Assert.Equal(expected, rebuild)
+	tt.Assert.True(rebuild.InProgress())
+}
+
+func TestSwapShadowStateTables(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetHorizonDB(t, tt.HorizonDB)
+	q := &Q{tt.HorizonSession()}
+
+	indexNames := func() []string {
+		var names []string
+		tt.Assert.NoError(q.SelectRaw(tt.Ctx, &names,
+			"SELECT indexname FROM pg_indexes WHERE schemaname = 'public' AND tablename = 'accounts' ORDER BY indexname"))
+		return names
+	}
+	expectedIndexNames := indexNames()
+
+	tt.Assert.NoError(q.UpsertAccounts(tt.Ctx, []AccountEntry{account1}))
+	tt.Assert.NoError(q.UpdateStateChecksums(tt.Ctx, StateChecksums{}))
+	tt.Assert.NoError(q.CreateShadowStateTables(tt.Ctx, StateTables))
+
+	tt.Assert.NoError(q.Begin(tt.Ctx))
+	tt.Assert.NoError(q.UseShadowStateTables(tt.Ctx, true))
+	tt.Assert.NoError(q.UpsertAccounts(tt.Ctx, []AccountEntry{account2}))
+	checksums := StateChecksums{xdr.LedgerEntryTypeAccount: StateChecksum{1}}
+	tt.Assert.NoError(q.UpdateStateChecksums(tt.Ctx, checksums))
+	tt.Assert.NoError(q.UseShadowStateTables(tt.Ctx, false))
+
+	// the state tables are untouched until they are swapped
+	accounts, err := q.GetAccountsByIDs(tt.Ctx, []string{account1.AccountID, account2.AccountID})
+	tt.Assert.NoError(err)
+	tt.Assert.Len(accounts, 1)
+	tt.Assert.Equal(account1.AccountID, accounts[0].AccountID)
+
+	tt.Assert.NoError(q.SwapShadowStateTables(tt.Ctx, StateTables))
+	tt.Assert.NoError(q.Commit())
+
+	accounts, err = q.GetAccountsByIDs(tt.Ctx, []string{account1.AccountID, account2.AccountID})
+	tt.Assert.NoError(err)
+	tt.Assert.Len(accounts, 1)
+	tt.Assert.Equal(account2.AccountID, accounts[0].AccountID)
+
+	stored, err := q.GetStateChecksums(tt.Ctx)
+	tt.Assert.NoError(err)
+	tt.Assert.Equal(checksums, stored)
+
+	tt.Assert.Equal(expectedIndexNames, indexNames())
+
+	var schemas int
+	tt.Assert.NoError(q.GetRaw(tt.Ctx, &schemas,
+		"SELECT COUNT(*) FROM information_schema.schemata WHERE schema_name = ?", ShadowStateSchema))
+	tt.Assert.Equal(0, schemas)
+}
@@ -3,6 +3,7 @@ package history
 import (
 	"context"
 	"fmt"
+	"math"
 	"time"
 
 	sq "github.com/Masterminds/squirrel"
@@ -25,9 +26,37 @@ var AllowedResolutions = map[time.Duration]struct{}{
 }
 
 // StrictResolutionFiltering represents a simple feature flag to determine whether only
-// predetermined resolutions of trade aggregations are allowed.
+// predetermined resolutions of trade aggregations are allowed. Otherwise any
+// multiple of the 1 minute buckets is allowed.
 var StrictResolutionFiltering = true
 
+// DefaultRoundingSlippageFilter is the default --rounding-slippage-filter, in
+// bips, the trades with a larger rounding slippage are left out of the trade
+// aggregation buckets. Migration 71 backfills the existing buckets with it.
+const DefaultRoundingSlippageFilter = 1000
+
+// tradeAggregationBucketResolution is the resolution of the buckets
+// precomputed in the HistoryTradesTableName table, the other resolutions are
+// aggregated from them.
+const tradeAggregationBucketResolution = int64(60_000)
+
+// ValidTradeAggregationResolution returns true if trade aggregations can be
+// computed with the given resolution in milliseconds.
+func ValidTradeAggregationResolution(resolution int64) bool {
+	if resolution <= 0 || resolution%tradeAggregationBucketResolution != 0 {
+		return false
+	}
+	// the resolution must fit in a time.Duration
+	if resolution > int64(math.MaxInt64/time.Millisecond) {
+		return false
+	}
+	if StrictResolutionFiltering {
+		_, ok := AllowedResolutions[time.Duration(resolution)*time.Millisecond]
+		return ok
+	}
+	return true
+}
+
 // TradeAggregation represents an aggregation of trades from the trades table
 type TradeAggregation struct {
 	Timestamp     int64   `db:"timestamp"`
@@ -43,6 +72,12 @@ type TradeAggregation struct {
 	OpenD         int64   `db:"open_d"`
 	CloseN        int64   `db:"close_n"`
 	CloseD        int64   `db:"close_d"`
+	// LiquidityPoolTradeCount, LiquidityPoolBaseVolume and
+	// LiquidityPoolCounterVolume are the share of the liquidity pool trades,
+	// the rest of the trades exercised offers on the orderbook.
+	LiquidityPoolTradeCount    int64  `db:"lp_count"`
+	LiquidityPoolBaseVolume    string `db:"lp_base_volume"`
+	LiquidityPoolCounterVolume string `db:"lp_counter_volume"`
 }
 
 const HistoryTradesTableName = "history_trades_60000"
@@ -68,10 +103,8 @@ func (q Q) GetTradeAggregationsQ(baseAssetID int64, counterAssetID int64, resolu
 	offsetDuration := time.Duration(offset) * time.Millisecond
 
 	//check if resolution allowed
-	if StrictResolutionFiltering {
-		if _, ok := AllowedResolutions[resolutionDuration]; !ok {
-			return &TradeAggregationsQ{}, errors.New("resolution is not allowed")
-		}
+	if !ValidTradeAggregationResolution(resolution) {
+		return &TradeAggregationsQ{}, errors.New("resolution is not allowed")
 	}
 	// check if offset is allowed. Offset must be 1) a multiple of an hour 2) less than the resolution and 3)
 	// less than 24 hours
@@ -144,7 +177,7 @@ func (q *TradeAggregationsQ) getRawTradesSql(orderPreserved bool) sq.SelectBuild
 		Where(fmt.Sprintf("r.max_ts >= %s", bucketTs)).
 		Where(fmt.Sprintf("r.min_ts <= %s", bucketTs))
 
-	if q.resolution != 60000 {
+	if q.resolution != tradeAggregationBucketResolution {
 		//ensure open/close order for cases when multiple trades occur in the same ledger
 		rawTradesSQL = rawTradesSQL.OrderBy("timestamp ASC", "open_ledger_toid ASC")
 		// Do on-the-fly aggregation for higher resolutions.
@@ -231,6 +264,9 @@ func bucketTrades(resolution int64, offset int64) sq.SelectBuilder {
 		"open_d",
 		"close_n",
 		"close_d",
+		"lp_count",
+		"lp_base_volume",
+		"lp_counter_volume",
 	)
 }
 
@@ -251,6 +287,9 @@ func reverseBucketTrades(resolution int64, offset int64) sq.SelectBuilder {
 		"open_d as open_n",
 		"close_n as close_d",
 		"close_d as close_n",
+		"lp_count",
+		"lp_base_volume as lp_counter_volume",
+		"lp_counter_volume as lp_base_volume",
 	)
 }
 
@@ -269,6 +308,9 @@ func aggregate(rawTradesTable string) sq.SelectBuilder {
 		"(first(ARRAY[open_n, open_d]))[2] as open_d",
 		"(last(ARRAY[close_n, close_d]))[1] as close_n",
 		"(last(ARRAY[close_n, close_d]))[2] as close_d",
+		"sum(lp_count) as lp_count",
+		"sum(lp_base_volume) as lp_base_volume",
+		"sum(lp_counter_volume) as lp_counter_volume",
 	).From(rawTradesTable).GroupBy("timestamp")
 }
 
@@ -276,8 +318,8 @@ func aggregate(rawTradesTable string) sq.SelectBuilder {
 // buckets, (specified by start and end times) to ensure complete data in case
 // of partial reingestion.
 func (q Q) RebuildTradeAggregationTimes(ctx context.Context, from, to strtime.Millis, roundingSlippageFilter int) error {
-	from = from.RoundDown(60_000)
-	to = to.RoundDown(60_000)
+	from = from.RoundDown(tradeAggregationBucketResolution)
+	to = to.RoundDown(tradeAggregationBucketResolution)
 	// Clear out the old bucket values.
 	_, err := q.Exec(ctx, sq.Delete(HistoryTradesTableName).Where(
 		sq.GtOrEq{"timestamp": from},
@@ -298,6 +340,7 @@ func (q Q) RebuildTradeAggregationTimes(ctx context.Context, from, to strtime.Mi
 		"counter_asset_id",
 		"counter_amount",
 		"ARRAY[price_n, price_d] as price",
+		"trade_type",
 	).From("history_trades").Where(
 		// db rounding is stored as bips. so 0.95% = 95
 		sq.Lt{"coalesce(rounding_slippage, 0)": roundingSlippageFilter},
@@ -326,6 +369,9 @@ func (q Q) RebuildTradeAggregationTimes(ctx context.Context, from, to strtime.Mi
 		"last(history_operation_id) as close_ledger_toid",
 		"(last(price))[1] as close_n",
 		"(last(price))[2] as close_d",
+		fmt.Sprintf("count(*) filter (where trade_type = %d) as lp_count", LiquidityPoolTradeType),
+		fmt.Sprintf("coalesce(sum(base_amount) filter (where trade_type = %d), 0) as lp_base_volume", LiquidityPoolTradeType),
+		fmt.Sprintf("coalesce(sum(counter_amount) filter (where trade_type = %d), 0) as lp_counter_volume", LiquidityPoolTradeType),
 	).FromSelect(trades, "trades").GroupBy("base_asset_id", "counter_asset_id", "timestamp")
 
 	// Insert the new bucket values.
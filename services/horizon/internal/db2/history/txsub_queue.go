@@ -0,0 +1,185 @@
+package history
+
+import (
+	"context"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/guregu/null"
+)
+
+const txSubQueueTable = "txsub_queue"
+
+// The statuses of the transactions in the transaction submission queue.
+const (
+	// QueuedTransactionPending is the status of the transactions which are
+	// rebroadcast until they are included in a ledger or expire.
+	QueuedTransactionPending = "pending"
+	// QueuedTransactionSuccess is the status of the transactions which were
+	// included in a ledger and succeeded.
+	QueuedTransactionSuccess = "success"
+	// QueuedTransactionFailed is the status of the transactions which were
+	// included in a ledger and failed or which were rejected by stellar-core.
+	QueuedTransactionFailed = "failed"
+	// QueuedTransactionExpired is the status of the transactions whose time
+	// bounds or ledger bounds expired before they were included in a ledger.
+	QueuedTransactionExpired = "expired"
+	// QueuedTransactionDropped is the status of the transactions whose
+	// sequence number was consumed by another transaction.
+	QueuedTransactionDropped = "dropped"
+)
+
+// QueuedTransaction is a row of data from the `txsub_queue` table
+type QueuedTransaction struct {
+	TransactionHash      string      `db:"transaction_hash"`
+	InnerTransactionHash null.String `db:"inner_transaction_hash"`
+	TxEnvelope           string      `db:"tx_envelope"`
+	Account              string      `db:"account"`
+	AccountSequence      int64       `db:"account_sequence"`
+	// MaxTime and MaxLedger are the upper time bound and ledger bound of the
+	// transaction, they are null when the transaction doesn't have one.
+	MaxTime   null.Int `db:"max_time"`
+	MaxLedger null.Int `db:"max_ledger"`
+	Status    string   `db:"status"`
+	// SubmissionCount is the number of times the transaction was submitted to
+	// stellar-core.
+	SubmissionCount int32 `db:"submission_count"`
+	// SubmittedLedger is the latest ingested ledger when the transaction was
+	// first submitted.
+	SubmittedLedger int32     `db:"submitted_ledger"`
+	CreatedAt       time.Time `db:"created_at"`
+	LastSubmittedAt time.Time `db:"last_submitted_at"`
+	FinishedAt      null.Time `db:"finished_at"`
+	// LedgerSequence is the ledger the transaction was included in.
+	LedgerSequence null.Int    `db:"ledger_sequence"`
+	TxResult       null.String `db:"tx_result"`
+}
+
+func queuedTransactionToMap(tx QueuedTransaction) map[string]interface{} {
+	return map[string]interface{}{
+		"transaction_hash":       tx.TransactionHash,
+		"inner_transaction_hash": tx.InnerTransactionHash,
+		"tx_envelope":            tx.TxEnvelope,
+		"account":                tx.Account,
+		"account_sequence":       tx.AccountSequence,
+		"max_time":               tx.MaxTime,
+		"max_ledger":             tx.MaxLedger,
+		"status":                 tx.Status,
+		"submission_count":       tx.SubmissionCount,
+		"submitted_ledger":       tx.SubmittedLedger,
+		"created_at":             tx.CreatedAt,
+		"last_submitted_at":      tx.LastSubmittedAt,
+		"finished_at":            tx.FinishedAt,
+		"ledger_sequence":        tx.LedgerSequence,
+		"tx_result":              tx.TxResult,
+	}
+}
+
+var selectQueuedTransaction = sq.Select(
+	"transaction_hash",
+	"inner_transaction_hash",
+	"tx_envelope",
+	"account",
+	"account_sequence",
+	"max_time",
+	"max_ledger",
+	"status",
+	"submission_count",
+	"submitted_ledger",
+	"created_at",
+	"last_submitted_at",
+	"finished_at",
+	"ledger_sequence",
+	"tx_result",
+).From(txSubQueueTable)
+
+// InsertQueuedTransaction adds a transaction to the transaction submission
+// queue unless it's already pending. A finished transaction which is
+// submitted again is pending again, with its submission recorded. Returns the
+// number of rows affected and error.
+func (q *Q) InsertQueuedTransaction(ctx context.Context, tx QueuedTransaction) (int64, error) {
+	sql := sq.Insert(txSubQueueTable).
+		SetMap(queuedTransactionToMap(tx)).
+		Suffix(`ON CONFLICT (transaction_hash) DO UPDATE SET
+		status = EXCLUDED.status, submission_count = txsub_queue.submission_count + 1,
+		submitted_ledger = EXCLUDED.submitted_ledger, last_submitted_at = EXCLUDED.last_submitted_at,
+		finished_at = NULL, ledger_sequence = NULL, tx_result = NULL
+		WHERE txsub_queue.status <> 'pending'`)
+	result, err := q.Exec(ctx, sql)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// UpdateQueuedTransaction updates the status, the submissions and the result
+// of a pending transaction of the transaction submission queue. The finished
+// transactions are left untouched. Returns the number of rows affected and
+// error.
+func (q *Q) UpdateQueuedTransaction(ctx context.Context, tx QueuedTransaction) (int64, error) {
+	sql := sq.Update(txSubQueueTable).
+		SetMap(map[string]interface{}{
+			"status":            tx.Status,
+			"submission_count":  tx.SubmissionCount,
+			"last_submitted_at": tx.LastSubmittedAt,
+			"finished_at":       tx.FinishedAt,
+			"ledger_sequence":   tx.LedgerSequence,
+			"tx_result":         tx.TxResult,
+		}).
+		Where(sq.Eq{
+			"transaction_hash": tx.TransactionHash,
+			"status":           QueuedTransactionPending,
+		})
+	result, err := q.Exec(ctx, sql)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// QueuedTransactionByHash returns the queued transaction with the given hash
+// or inner transaction hash.
+func (q *Q) QueuedTransactionByHash(ctx context.Context, hash string) (QueuedTransaction, error) {
+	var tx QueuedTransaction
+	sql := selectQueuedTransaction.
+		Where(sq.Or{
+			sq.Eq{"transaction_hash": hash},
+			sq.Eq{"inner_transaction_hash": hash},
+		}).
+		OrderBy("created_at DESC").
+		Limit(1)
+	err := q.Get(ctx, &tx, sql)
+	return tx, err
+}
+
+// ClaimPendingQueuedTransactions returns the transactions of the transaction
+// submission queue which are still pending, the oldest first, and locks them
+// until the end of the current transaction. The transactions locked by
+// another Horizon instance are skipped so each pending transaction is
+// processed by a single instance at a time.
+func (q *Q) ClaimPendingQueuedTransactions(ctx context.Context) ([]QueuedTransaction, error) {
+	var txs []QueuedTransaction
+	sql := selectQueuedTransaction.
+		Where(sq.Eq{"status": QueuedTransactionPending}).
+		OrderBy("created_at ASC").
+		Suffix("FOR UPDATE SKIP LOCKED")
+	err := q.Select(ctx, &txs, sql)
+	return txs, err
+}
+
+// DeleteQueuedTransactionsFinishedBefore removes the transactions of the
+// transaction submission queue which finished before the given time. Returns
+// the number of rows affected and error.
+func (q *Q) DeleteQueuedTransactionsFinishedBefore(ctx context.Context, before time.Time) (int64, error) {
+	sql := sq.Delete(txSubQueueTable).
+		Where(sq.NotEq{"status": QueuedTransactionPending}).
+		Where(sq.Lt{"finished_at": before})
+	result, err := q.Exec(ctx, sql)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
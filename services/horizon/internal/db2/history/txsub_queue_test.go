@@ -0,0 +1,136 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/guregu/null"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pownieh/stellar_go/services/horizon/internal/test"
+)
+
+func TestTxSubQueue(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetHorizonDB(t, tt.HorizonDB)
+	q := &Q{tt.HorizonSession()}
+
+	createdAt := time.Date(2023, 11, 14, 22, 0, 0, 0, time.UTC)
+	first := QueuedTransaction{
+		TransactionHash: "2374e99349b9ef7dba9a5db3339b78fda8f34777b1af33ba468ad5c0df946d4d",
+		TxEnvelope:      "AAAA",
+		Account:         "GBRPYHIL2CI3FNQ4BXLFMNDLFJUNPU2HY3ZMFSHONUCEOASW7QC7OX2H",
+		AccountSequence: 10,
+		MaxTime:         null.IntFrom(createdAt.Add(time.Minute).Unix()),
+		Status:          QueuedTransactionPending,
+		SubmissionCount: 1,
+		SubmittedLedger: 100,
+		CreatedAt:       createdAt,
+		LastSubmittedAt: createdAt,
+	}
+	second := first
+	second.TransactionHash = "3374e99349b9ef7dba9a5db3339b78fda8f34777b1af33ba468ad5c0df946d4d"
+	second.InnerTransactionHash = null.StringFrom("4374e99349b9ef7dba9a5db3339b78fda8f34777b1af33ba468ad5c0df946d4d")
+	second.AccountSequence = 11
+	second.MaxTime = null.Int{}
+	second.MaxLedger = null.IntFrom(120)
+	second.CreatedAt = createdAt.Add(time.Second)
+
+	inserted, err := q.InsertQueuedTransaction(tt.Ctx, second)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), inserted)
+	inserted, err = q.InsertQueuedTransaction(tt.Ctx, first)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), inserted)
+	// queuing a transaction again is a no-op
+	inserted, err = q.InsertQueuedTransaction(tt.Ctx, first)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), inserted)
+
+	pending, err := q.ClaimPendingQueuedTransactions(tt.Ctx)
+	assert.NoError(t, err)
+	if assert.Len(t, pending, 2) {
+		assert.Equal(t, first.TransactionHash, pending[0].TransactionHash)
+		assert.Equal(t, first.MaxTime, pending[0].MaxTime)
+		assert.Equal(t, second.TransactionHash, pending[1].TransactionHash)
+		assert.Equal(t, second.MaxLedger, pending[1].MaxLedger)
+	}
+
+	byInnerHash, err := q.QueuedTransactionByHash(tt.Ctx, second.InnerTransactionHash.String)
+	assert.NoError(t, err)
+	assert.Equal(t, second.TransactionHash, byInnerHash.TransactionHash)
+
+	first.Status = QueuedTransactionSuccess
+	first.FinishedAt = null.TimeFrom(createdAt.Add(10 * time.Second))
+	first.LedgerSequence = null.IntFrom(102)
+	first.TxResult = null.StringFrom("AAAAAAAAAGQAAAAAAAAAAAAAAAA=")
+	updated, err := q.UpdateQueuedTransaction(tt.Ctx, first)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), updated)
+	// a finished transaction is not updated anymore
+	dropped := first
+	dropped.Status = QueuedTransactionDropped
+	updated, err = q.UpdateQueuedTransaction(tt.Ctx, dropped)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), updated)
+
+	// a finished transaction which is queued again is pending again
+	succeeded := first
+	requeued := first
+	requeued.SubmittedLedger = 103
+	requeued.LastSubmittedAt = createdAt.Add(20 * time.Second)
+	requeued.Status = QueuedTransactionPending
+	requeued.FinishedAt = null.Time{}
+	requeued.LedgerSequence = null.Int{}
+	requeued.TxResult = null.String{}
+	inserted, err = q.InsertQueuedTransaction(tt.Ctx, requeued)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), inserted)
+	byHash, err := q.QueuedTransactionByHash(tt.Ctx, first.TransactionHash)
+	assert.NoError(t, err)
+	assert.Equal(t, QueuedTransactionPending, byHash.Status)
+	assert.Equal(t, succeeded.SubmissionCount+1, byHash.SubmissionCount)
+	assert.Equal(t, int32(103), byHash.SubmittedLedger)
+	assert.False(t, byHash.FinishedAt.Valid)
+	assert.False(t, byHash.LedgerSequence.Valid)
+	assert.False(t, byHash.TxResult.Valid)
+	// the rest of the test expects the transaction to be finished
+	updated, err = q.UpdateQueuedTransaction(tt.Ctx, succeeded)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), updated)
+
+	pending, err = q.ClaimPendingQueuedTransactions(tt.Ctx)
+	assert.NoError(t, err)
+	assert.Len(t, pending, 1)
+
+	// the transactions claimed by another session are skipped
+	other := &Q{tt.HorizonSession()}
+	assert.NoError(t, q.Begin(tt.Ctx))
+	pending, err = q.ClaimPendingQueuedTransactions(tt.Ctx)
+	assert.NoError(t, err)
+	assert.Len(t, pending, 1)
+	pending, err = other.ClaimPendingQueuedTransactions(tt.Ctx)
+	assert.NoError(t, err)
+	assert.Len(t, pending, 0)
+	assert.NoError(t, q.Rollback())
+	pending, err = other.ClaimPendingQueuedTransactions(tt.Ctx)
+	assert.NoError(t, err)
+	assert.Len(t, pending, 1)
+
+	finished, err := q.QueuedTransactionByHash(tt.Ctx, first.TransactionHash)
+	assert.NoError(t, err)
+	assert.Equal(t, QueuedTransactionSuccess, finished.Status)
+	assert.Equal(t, first.LedgerSequence, finished.LedgerSequence)
+	assert.Equal(t, first.TxResult, finished.TxResult)
+
+	deleted, err := q.DeleteQueuedTransactionsFinishedBefore(tt.Ctx, createdAt.Add(10*time.Second))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), deleted)
+	deleted, err = q.DeleteQueuedTransactionsFinishedBefore(tt.Ctx, createdAt.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+
+	_, err = q.QueuedTransactionByHash(tt.Ctx, first.TransactionHash)
+	assert.True(t, q.NoRows(err))
+}
@@ -62,7 +62,16 @@
 // migrations/63_add_contract_id_to_asset_stats.sql (153B)
 // migrations/64_add_payment_flag_history_ops.sql (300B)
 // migrations/65_remove_unused_indexes.sql (2.897kB)
+// migrations/66_ingestion_filter_rules.sql (341B)
+// migrations/67_reingest_jobs.sql (677B)
+// migrations/68_state_rebuild_ledgers.sql (188B)
+// migrations/69_order_book_snapshots.sql (688B)
 // migrations/6_create_assets_table.sql (366B)
+// migrations/70_liquidity_pool_stats.sql (1.407kB)
+// migrations/71_trade_aggregations_liquidity_pool_split.sql (1.382kB)
+// migrations/72_ledger_fee_stats.sql (1.008kB)
+// migrations/73_txsub_queue.sql (940B)
+// migrations/74_sponsorship_effects_by_sponsor.sql (683B)
 // migrations/7_modify_trades_table.sql (2.303kB)
 // migrations/8_add_aggregators.sql (907B)
 // migrations/8_create_asset_stats_table.sql (441B)
@@ -1375,6 +1384,86 @@ func migrations65_remove_unused_indexesSql() (*asset, error) {
 	return a, nil
 }
 
+var _migrations66_ingestion_filter_rulesSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x7c\x90\x4f\x4b\xc3\x40\x10\xc5\xef\xfb\x29\x1e\xb9\xb4\x62\x02\x9e\xed\x29\xda\x1c\x0a\x21\x91\x74\xe3\x45\x24\x6c\xba\x93\x38\xb2\xdd\x95\xec\x94\x22\xe2\x77\x17\x1b\xc9\xcd\xde\x06\xe6\xf7\xfe\xf0\xb2\x0c\xb7\x47\x1e\x27\x23\x84\xf6\x43\xa9\xc7\xa6\xc8\x75\x01\x9d\x3f\x94\x05\xd8\x8f\x14\x85\x83\xef\x06\x76\x42\x53\x37\x9d\x1c\x45\xac\x15\x00\x90\x37\xbd\x23\x8b\x3e\x04\x87\xaa\xd6\xa8\xda\xb2\x84\xa5\xc1\x9c\x9c\x60\x30\x2e\x52\x7a\x01\x67\xd1\x7b\x0c\xbe\x5f\xb8\xf9\xe3\x4c\x94\xee\x18\x2c\x0f\xfc\x6b\xc4\x23\x7b\x59\x10\x75\xb3\x51\x2a\xcb\xc0\x3e\xd2\x24\x90\x37\x5a\xcc\x2d\xc7\x39\x3b\x8a\x11\x52\xbb\x6a\x5f\x34\x1a\xbb\x4a\xd7\xff\x55\x7e\xce\xcb\xb6\xd8\x63\x3d\xd7\xc2\xea\x2b\x31\xce\x85\x73\x72\x8f\x97\xd7\x14\x89\x25\xff\x79\xb9\xbf\x57\x29\xee\xfe\x82\x97\x5d\xb6\xe1\xec\x95\xda\x36\xf5\xd3\xf5\x5d\x0e\x26\x1e\x8c\xa5\x8d\xfa\x01\x00\x00\xff\xff\x03\x00\x96\x05\xba\x3c\x55\x01\x00\x00")
+
+func migrations66_ingestion_filter_rulesSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations66_ingestion_filter_rulesSql,
+		"migrations/66_ingestion_filter_rules.sql",
+	)
+}
+
+func migrations66_ingestion_filter_rulesSql() (*asset, error) {
+	bytes, err := migrations66_ingestion_filter_rulesSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/66_ingestion_filter_rules.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0xc, 0x56, 0x3c, 0x6c, 0x47, 0x1f, 0x82, 0xdf, 0x9e, 0x54, 0x1a, 0xea, 0x77, 0xd7, 0x14, 0xe, 0x3e, 0x8d, 0x4f, 0xac, 0x1b, 0x68, 0x4b, 0x2d, 0x52, 0xa2, 0x6b, 0x9a, 0x70, 0x5b, 0xd3, 0x6d}}
+	return a, nil
+}
+
+var _migrations67_reingest_jobsSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xb4\x92\xc1\x4e\x83\x40\x10\x86\xef\xfb\x14\x73\x03\x62\x9b\x78\xef\x09\x65\x35\x44\xa4\x15\x21\xb1\xa7\xcd\x02\x23\xae\x29\xbb\x64\x77\x08\xd5\xa7\x37\x2d\x0d\xd2\xd4\xe8\xc9\xd3\x26\xf3\xff\xf3\x65\x93\xf9\x96\x4b\xb8\x6a\x55\x63\x25\x21\x14\x1d\x63\xb7\x19\x0f\x73\x0e\x79\x78\x93\x70\xb0\xa8\x74\x83\x8e\xc4\xbb\x29\x1d\xf8\x0c\x00\x40\xd5\x50\xaa\xc6\xa1\x55\x72\x07\x9b\x2c\x7e\x0c\xb3\x2d\x3c\xf0\xed\xe2\x98\xbe\x5a\xd3\x8a\x1d\xd6\x0d\x5a\x50\x9a\xf0\xf0\xa6\xeb\x1c\xd2\x22\x49\xc6\x06\x99\xdf\x73\x47\x92\x7a\x07\x84\x7b\x9a\x12\x88\xf8\x5d\x58\x24\x39\x78\x1d\xea\x5a\xe9\xc6\x1b\xbb\x92\x08\xdb\x8e\xdc\x05\x6a\x5a\xb8\x1e\x8b\x3b\x94\x0e\x85\x19\x34\xda\x23\x79\x3e\xc5\x7d\xa7\x2c\x3a\x21\x09\x48\xb5\xe8\x48\xb6\x1d\x0c\x8a\xde\x4c\x3f\x4e\xe0\xd3\x68\x3c\x6d\x48\x47\x02\xad\x35\x73\x4c\x65\x51\x12\xd6\x7f\x00\x2e\xff\xe6\x6b\x33\xf8\x01\xc8\x79\xc9\xeb\xa9\xf2\x82\x91\xdb\x77\xf5\xbf\x70\x8b\x34\x7e\x2a\x38\xf8\xb3\x5b\x2d\xbe\xcf\x12\xb0\x60\x35\x69\x10\xa7\x11\x7f\x39\xd7\x40\x94\x1f\xe2\x74\xa3\x75\x7a\x1e\x41\xf1\x1c\xa7\xf7\x50\x92\x45\x04\x7f\x2c\x2d\xe6\x4a\x1c\xc8\x73\xe1\x22\x33\x68\xc6\xa2\x6c\xbd\xf9\x51\xb8\x4a\xba\x4a\xd6\xb8\x62\x5f\x00\x00\x00\xff\xff\x03\x00\x90\x9e\x28\x4c\xa5\x02\x00\x00")
+
+func migrations67_reingest_jobsSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations67_reingest_jobsSql,
+		"migrations/67_reingest_jobs.sql",
+	)
+}
+
+func migrations67_reingest_jobsSql() (*asset, error) {
+	bytes, err := migrations67_reingest_jobsSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/67_reingest_jobs.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x83, 0x9e, 0xbf, 0x6d, 0xeb, 0xa3, 0xa3, 0x78, 0x7c, 0x25, 0xad, 0xcd, 0xe9, 0xb7, 0xde, 0xaa, 0xe8, 0xaf, 0xfc, 0xec, 0x61, 0x7, 0x60, 0x6, 0x85, 0x92, 0xb, 0x8f, 0x5, 0x85, 0x62, 0x72}}
+	return a, nil
+}
+
+var _migrations68_state_rebuild_ledgersSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x7c\xcd\xbf\xaa\x83\x30\x1c\x05\xe0\xfd\xf7\x14\x67\xbc\x97\x7b\x7d\x02\x27\x5b\x33\x94\x5a\x95\xa0\x83\x53\x88\xf1\x20\x82\x7f\xda\x24\x52\xfa\xf6\x85\x76\xe9\xd4\xfd\x83\x2f\x49\xf0\xb7\x4c\xa3\xb7\x91\x68\xaf\x22\x47\xad\xb2\x46\xa1\xc9\x0e\x85\x42\x88\x36\xd2\x78\xf6\xfb\x34\x0f\x66\xe6\x30\xd2\x07\xfc\x08\x00\x04\xde\x76\xae\x8e\x98\xd6\xc8\x91\x1e\xb5\x3e\x5d\x32\xdd\xe1\xac\xba\xff\x97\x78\x7b\xe3\xe6\x2d\xd0\x2c\x8c\x16\xfd\x23\xd2\xa2\xac\x1a\x94\x6d\x51\xc8\x6f\x2a\xf2\xf9\xe7\xdb\x7d\x15\xc9\x75\x55\x7f\xfd\x9d\x0d\xce\x0e\x4c\xe5\x09\x00\x00\xff\xff\x03\x00\x26\x73\x24\x42\xbc\x00\x00\x00")
+
+func migrations68_state_rebuild_ledgersSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations68_state_rebuild_ledgersSql,
+		"migrations/68_state_rebuild_ledgers.sql",
+	)
+}
+
+func migrations68_state_rebuild_ledgersSql() (*asset, error) {
+	bytes, err := migrations68_state_rebuild_ledgersSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/68_state_rebuild_ledgers.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x61, 0x1d, 0xdd, 0xc4, 0x8b, 0x1, 0xeb, 0xab, 0x7a, 0x18, 0x84, 0x95, 0x9d, 0xea, 0xcc, 0x83, 0xea, 0x91, 0xb8, 0x25, 0x1e, 0x34, 0x68, 0x3b, 0x26, 0x3c, 0xb3, 0x94, 0xe0, 0xb2, 0x43, 0x3b}}
+	return a, nil
+}
+
+var _migrations69_order_book_snapshotsSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x94\x92\xc1\x6e\xab\x30\x10\x45\xf7\xfe\x8a\x59\x06\xbd\x64\xf7\xd4\x0d\xab\xa4\xb1\x2a\x54\x0a\x11\x05\xa9\x59\x59\xc6\x8c\xc0\x0d\xd8\xd4\x63\x1a\xd1\xaf\xaf\xd4\x48\x69\x83\x2a\x54\xb6\x9e\xb9\xf6\xf1\xd1\xdd\x6c\xe0\x5f\xa7\x6b\x27\x3d\x42\xd1\x33\x76\x9f\xf1\x6d\xce\x21\xdf\xee\x62\x0e\x8d\x26\x6f\xdd\x28\xac\xab\xd0\x89\xd2\xda\x93\x20\x23\x7b\x6a\xac\x27\x58\x31\x00\x80\x16\xab\x1a\x9d\x20\x7c\x1b\xd0\x28\x04\x6d\x3c\xd6\xe8\x20\x49\x73\x48\x8a\x38\x5e\x7f\x6d\xa9\xd6\x12\x56\x42\x7a\xf0\xba\x43\xf2\xb2\xeb\xe1\xac\x7d\x63\x87\xcb\x09\x7c\x58\x83\x93\x0c\x61\xdb\x6a\x53\x0b\x49\x84\x1e\x54\x23\x9d\x54\x1e\x1d\xbc\x4b\x37\x6a\x53\xaf\xee\xfe\x07\x93\x44\x39\x8c\x0b\x03\xba\x22\x78\x25\x6b\xca\xc9\x40\xd2\xe9\xf7\xc1\x21\x8b\x9e\xb6\xd9\x11\x1e\xf9\x11\x56\x37\x84\xeb\x9b\xe7\xd7\x53\x31\x01\x0b\xc2\xab\xdd\x28\xd9\xf3\x97\x59\xbb\xa2\x1c\xc5\xb7\xb4\x34\x99\x5d\x86\xe2\x39\x4a\x1e\x60\x97\x67\x9c\xcf\x43\x5d\xaf\x0c\xc2\x65\x2c\x97\xdf\x2c\x02\x99\x0a\x08\x19\xfb\x59\xb6\xbd\x3d\x1b\xc6\xf6\x59\x7a\xf8\x4b\xd9\x94\x24\x25\x2b\x0c\xd9\x27\x00\x00\x00\xff\xff\x03\x00\x2f\x0d\xfe\x75\xb0\x02\x00\x00")
+
+func migrations69_order_book_snapshotsSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations69_order_book_snapshotsSql,
+		"migrations/69_order_book_snapshots.sql",
+	)
+}
+
+func migrations69_order_book_snapshotsSql() (*asset, error) {
+	bytes, err := migrations69_order_book_snapshotsSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/69_order_book_snapshots.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0xe4, 0x5a, 0xa0, 0x5b, 0x55, 0xec, 0xb, 0x8a, 0xbf, 0xfe, 0x6e, 0x8, 0x25, 0x28, 0xd6, 0x0, 0x6f, 0x4f, 0xa9, 0x47, 0xa4, 0x19, 0x5d, 0x56, 0xb6, 0xcd, 0x9b, 0x8b, 0x61, 0x7c, 0xb3, 0xdd}}
+	return a, nil
+}
+
 var _migrations6_create_assets_tableSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x6c\x90\x3d\x4f\xc3\x30\x18\x84\x77\xff\x8a\x1b\x1d\x91\x0e\x20\xe8\x92\xc9\x34\x16\x58\x18\xa7\xb8\x31\xa2\x53\xe5\x26\x16\x78\x80\x54\xb6\x11\xca\xbf\x47\xaa\x28\xf9\x50\xe6\x7b\xf4\xbc\xef\xdd\x6a\x85\xab\x4f\xff\x1e\x6c\x72\x30\x27\xb2\xd1\x9c\xd5\x1c\x35\xbb\x97\x1c\x1f\x3e\xa6\x2e\xf4\x07\x1b\xa3\x4b\x11\x94\x00\x80\x6f\xb1\xe3\x5a\x30\x89\xad\x16\xcf\x4c\xef\xf1\xc4\xf7\xc8\xcf\xd9\x19\x3c\xa4\xfe\xe4\xf0\xca\xf4\xe6\x91\x69\xba\xbe\xcd\xa0\xaa\x1a\xca\x48\x39\x86\x9a\xae\x1d\xa0\xeb\x9b\x65\xc8\xc7\xf8\xed\xc2\x3f\x76\xb7\x9e\x63\x46\x89\x17\xc3\xe9\xa0\xcc\x47\x3f\xe4\x13\x4b\x46\xb2\x82\x5c\xfa\x09\x55\xf2\xb7\xbf\xf8\xd8\x5f\xee\x54\x6a\x5e\xd9\xec\x84\x7a\xc0\x31\x05\xe7\x40\x27\xb6\x82\x90\xf1\x74\x65\xf7\xf3\x45\x4a\x5d\x6d\x97\xa7\x6b\x6c\x6c\x6c\xeb\x8a\xdf\x00\x00\x00\xff\xff\xfb\x53\x3e\x81\x6e\x01\x00\x00")
 
 func migrations6_create_assets_tableSqlBytes() ([]byte, error) {
@@ -1395,6 +1484,106 @@ func migrations6_create_assets_tableSql() (*asset, error) {
 	return a, nil
 }
 
+var _migrations70_liquidity_pool_statsSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xe4\x94\x4f\x6f\xaa\x40\x14\xc5\xf7\x7c\x8a\xbb\x13\xf2\x30\x79\xc9\x4b\xde\x42\x57\xb6\x92\xc6\xd4\x82\xa1\x9a\xd4\xd5\x64\x80\x5b\x98\x64\x60\xe8\xcc\x45\x63\x3f\x7d\xa3\xa5\xfe\xa1\x50\x71\x5d\x97\x9e\x73\xe6\xc2\x3d\xbf\x61\x38\x84\x3f\xb9\x48\x35\x27\x84\x55\x69\x59\xf7\xa1\x37\x59\x7a\xb0\x9c\xdc\xcd\x3d\xc8\x84\x21\xa5\x77\x4c\x8a\xb7\x4a\x24\x82\x76\xac\x54\x4a\x32\x89\x49\x8a\x9a\x19\xe2\x64\xc0\xb6\x00\xa0\xcb\x29\x12\x88\x44\x2a\x0a\x02\x3f\x58\x82\xbf\x9a\xcf\xdd\x83\xbd\x3e\x81\xd4\x15\x43\x2c\x95\xc1\x84\x71\x02\x12\x39\x1a\xe2\x79\x09\x5b\x41\x99\xaa\x3e\xff\x81\x77\x55\x60\x23\x4a\x9a\x27\xc8\x62\x55\x15\x04\xa2\x20\x4c\x51\x37\x1c\x1b\x25\xab\x1c\x19\x87\xa2\xca\x51\x8b\xb8\x5d\x8e\x3a\xe4\x57\x44\xc3\xf8\x4f\x62\x57\x52\xa3\x41\xbd\xd9\x0f\x6e\x7d\xe7\x2f\x39\x6a\x97\x49\x11\x97\xcc\x64\x5c\xa3\x69\x77\x2c\xc2\xd9\xd3\x24\x5c\xc3\xa3\xb7\x06\xfb\x6c\xc5\x6e\x77\x3d\x8e\xe5\x8c\x8f\xa5\xcf\xfc\xa9\xf7\x02\x99\x2c\xa5\x61\xd1\xee\x6c\xf9\x81\xdf\x0b\x85\xd5\xf3\xcc\x7f\x80\x88\x34\x22\xd8\xa4\x58\x2e\xa4\x14\xc6\x6e\x76\xe9\xc2\xe0\xdf\xff\xbf\xfb\xdf\x60\x34\xaa\x37\xe5\x38\xe3\x7e\xec\x1d\x26\xb1\x3a\x5f\xc3\x77\x62\xa3\x75\x2d\x37\xb2\xf9\x7b\xf9\x51\x25\x16\xec\xea\xd5\x3c\xf4\x78\xdd\x76\x01\x63\x67\x05\xee\xa9\xbc\x76\x14\x0d\xfb\xf6\x54\x81\xdf\x0f\x8e\x0b\x1c\x9b\xa7\xec\x67\x9d\x7f\xfb\xa6\x6a\x5b\x58\xd6\x34\x0c\x16\xb7\xf0\x17\x73\x13\xf3\x04\xc7\x3d\x82\x17\x37\xe5\x98\xfb\x00\x00\x00\xff\xff\x03\x00\xe3\xe0\x3d\x43\x7f\x05\x00\x00")
+
+func migrations70_liquidity_pool_statsSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations70_liquidity_pool_statsSql,
+		"migrations/70_liquidity_pool_stats.sql",
+	)
+}
+
+func migrations70_liquidity_pool_statsSql() (*asset, error) {
+	bytes, err := migrations70_liquidity_pool_statsSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/70_liquidity_pool_stats.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0xdb, 0x7, 0x16, 0xfa, 0x6f, 0x5b, 0x23, 0x49, 0xa5, 0x40, 0x67, 0x5b, 0x7, 0xf9, 0x8c, 0x13, 0xd5, 0xe5, 0x32, 0x4a, 0x81, 0x1d, 0x28, 0xd0, 0x1a, 0x9c, 0x1f, 0x14, 0xb4, 0x26, 0x0, 0xd7}}
+	return a, nil
+}
+
+var _migrations71_trade_aggregations_liquidity_pool_splitSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x8d\x54\xc1\x72\x9b\x30\x10\xbd\xf3\x15\x7b\xc4\x2d\x78\xd2\x1e\x7a\x49\x73\xc0\x01\x37\x9d\x21\xb6\x07\xc3\x64\x7a\x22\x02\x64\xac\x09\x20\x2a\x89\xa6\xee\xd7\x67\x11\x18\x1b\xec\x99\xda\x07\x1b\xef\xee\x7b\xec\xbe\xa7\x95\x6d\xc3\xe7\x92\xe5\x82\x28\x0a\x51\x6d\x18\x8e\x1f\x7a\x01\x84\xce\xc2\xf7\x60\xcf\xa4\xe2\xe2\x10\x2b\x41\x32\x2a\xe3\x6f\x77\xf8\x31\x00\x1c\xd7\x85\xc7\xb5\x1f\x3d\xaf\xa0\xa8\xe3\x94\x37\x95\x02\x56\x29\x9a\x53\x01\xab\x75\x08\xab\xc8\xf7\xc1\xf5\x96\x4e\xe4\x87\x70\x67\x5d\x20\x12\x22\x69\xfc\x87\x17\x4d\x49\xa1\xc2\x2f\xc1\xd2\x9b\x70\xfa\x4d\x54\xfc\x1f\x7a\x6f\x18\xb6\x0d\x0b\x92\xbe\xed\x58\x51\x80\xda\x53\x28\xd8\xef\x86\x65\x4c\x1d\xa0\xe6\xbc\x00\xb9\x27\x82\x02\xdf\xe9\x1c\xfd\x8b\x73\xb2\x2a\x87\xa4\x49\xdf\xa8\x92\x96\x8e\x76\x43\x03\xd6\xb5\x64\x48\x84\xaf\xa6\x19\xbc\x33\xb5\xd7\xf9\x8c\xee\x48\x53\x28\xb0\x6d\x81\x7d\x65\x88\xb7\x65\xc1\xea\x9a\xe4\xd4\xee\xaa\x5b\xfe\x2f\x28\x19\x24\xac\x96\x2d\x89\xd9\x0b\x3a\x77\x3b\x6c\xd0\x03\xb7\x3d\x6e\xa9\x61\xb3\x39\x84\xc8\xdf\x37\xd3\x92\xb0\x4a\x2a\x52\xa5\xd8\x8d\x68\xaa\x0a\x01\x2d\x99\x6e\x84\x54\x1c\x7b\x11\x7d\x7b\x6d\xb3\x20\x68\xd2\xe0\xbf\x2e\xcf\x14\x24\x07\x78\xdd\x73\xc1\xfe\xf1\x0a\xb2\x04\xd3\x88\xa7\x52\x81\x20\xf8\xfb\x3a\x37\x5e\x7e\x86\x4f\xad\xba\xfd\xbc\xce\x16\x4c\x54\x7e\xeb\xf9\xde\x63\x88\x0f\x00\x8a\xc7\x25\xca\xc8\xa4\x59\xd0\x0c\x3d\x8e\xd3\x82\x4b\x9a\xc5\x44\x59\xa0\x8f\xc4\x0c\x88\x04\xc5\x4a\x64\x25\x65\x6d\x69\x90\xf6\x98\x48\x49\x55\xcc\xb2\x2e\x74\xb4\xef\x4a\xd4\xfc\xa4\x39\x8e\x1e\x77\x19\xd9\x94\x66\x47\x53\xb6\xc1\x63\xc5\xd9\xe9\x39\xd5\x0d\xdc\xa3\xd2\xf1\x81\xc1\xe2\x65\xb0\x7e\x9e\x9c\x6a\x8c\xbe\x3c\x79\x81\xd7\xf9\x1d\xab\x43\x4d\xe1\x01\xbe\x82\xb3\x72\xb1\x37\x52\x50\x99\x52\xf3\xe8\x70\x7c\x74\xd8\x02\x9c\xfa\xbb\x76\x17\x09\x7e\x04\xeb\x68\x03\x8b\x5f\x93\xa9\x2f\x27\xbe\x41\x4b\x63\x66\x44\x1b\xd7\x09\xaf\x6f\x1f\x90\x3c\x47\x6f\x5a\x63\x86\xd5\x7b\x38\xb9\x37\x3f\x57\x70\xb2\x69\x93\xb2\x89\x8c\x97\xeb\x75\x8d\xf6\xa4\xa5\x56\x72\x28\x30\x3a\x09\xb1\xb9\xf9\x48\x82\x11\xc9\x28\xd3\x2e\x37\x2a\xdc\x22\xa6\x2a\x8d\x40\xd3\xe4\x19\x6e\x38\x71\x23\xc0\x10\xed\x2e\x81\xe1\x6e\x73\xf9\x7b\x75\xd3\xed\xe6\x06\xeb\xcd\xf4\xd2\xb1\x2e\xe3\x13\xfd\xae\xa1\x06\xb5\xee\x8d\x0f\xef\x45\x89\xb3\x66\x05\x00\x00")
+
+func migrations71_trade_aggregations_liquidity_pool_splitSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations71_trade_aggregations_liquidity_pool_splitSql,
+		"migrations/71_trade_aggregations_liquidity_pool_split.sql",
+	)
+}
+
+func migrations71_trade_aggregations_liquidity_pool_splitSql() (*asset, error) {
+	bytes, err := migrations71_trade_aggregations_liquidity_pool_splitSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/71_trade_aggregations_liquidity_pool_split.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0xea, 0x87, 0x69, 0x73, 0x3e, 0x3d, 0xdf, 0x0, 0xdc, 0x11, 0x47, 0x6d, 0x2a, 0xb2, 0xcb, 0xa9, 0xc9, 0x37, 0x83, 0xc1, 0xa0, 0x4, 0x8d, 0xd7, 0xae, 0x93, 0x89, 0x4d, 0xb2, 0xd0, 0x17, 0x4e}}
+	return a, nil
+}
+
+var _migrations72_ledger_fee_statsSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x8c\x93\x31\x6f\xfa\x30\x10\xc5\x77\x7f\x8a\x1b\xff\x7f\xb5\x48\xed\x90\xc1\x62\x4a\x4b\x86\xaa\x29\xa0\x28\x0c\x4c\xd6\x25\x39\x82\x25\x62\x23\xfb\x50\xe9\xb7\xaf\x4a\x01\xb5\x69\x1c\xbc\xbe\xf7\xd3\xf9\xde\xb3\x6e\x32\x81\xbb\x4e\xb7\x0e\x99\x60\xb5\x17\xe2\xb9\xc8\xd2\x32\x83\x32\x7d\xca\x33\xd8\x6a\xcf\xd6\x7d\xa8\x1d\x35\x2d\x39\xb5\x21\x52\x9e\x91\x3d\xfc\x13\x00\x00\x67\x99\xad\x6e\xa0\xd2\xad\x36\x0c\xcb\xe2\xe5\x2d\x2d\xd6\xf0\x9a\xad\xef\x4f\x0c\x3b\x34\x1e\x6b\xd6\xd6\xa8\xda\x1e\x0c\x83\x36\x4c\x2d\x39\x98\x2f\x4a\x98\xaf\xf2\xfc\x9b\xfb\x9a\x5d\x6f\xd1\xb5\xd4\xa8\x4e\x9b\xf3\xbc\xbf\xde\xfe\xf1\x21\xec\x25\x23\x9e\x1c\xf3\x64\xd0\xeb\xf0\xf8\xcb\xeb\xf0\x78\xea\xa1\xbf\xe3\x45\xef\xef\x77\xd5\x93\x80\x2e\x43\xba\x1c\x7e\xb7\xb7\x8f\xb7\xce\x56\x68\x54\x6c\xcf\x17\x7e\xac\xef\x21\xa6\x9f\x6b\x90\x49\x22\x18\x19\xc3\xc8\x9b\x4c\xa8\x07\x47\x9b\x83\x69\xb0\xda\xd1\xe0\x37\x05\xb0\x50\xba\x3e\x96\xc4\x61\x32\x12\x93\x51\x11\xae\x49\xc5\xff\xa9\x10\x3f\x0f\x76\x66\xdf\x8d\x10\xb3\x62\xb1\xbc\x75\xb0\x35\xfa\x1a\x1b\x9a\x8a\x4f\x00\x00\x00\xff\xff\x03\x00\x2a\xc3\xba\x95\xf0\x03\x00\x00")
+
+func migrations72_ledger_fee_statsSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations72_ledger_fee_statsSql,
+		"migrations/72_ledger_fee_stats.sql",
+	)
+}
+
+func migrations72_ledger_fee_statsSql() (*asset, error) {
+	bytes, err := migrations72_ledger_fee_statsSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/72_ledger_fee_stats.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x40, 0xe6, 0x96, 0xc0, 0x8e, 0x2d, 0xdc, 0x4b, 0xc4, 0x66, 0xc5, 0xe5, 0xe2, 0xf0, 0xe, 0xf6, 0x44, 0xf4, 0x40, 0x22, 0xda, 0x60, 0xde, 0x71, 0x34, 0x6, 0x53, 0x2f, 0xd6, 0x40, 0x85, 0x35}}
+	return a, nil
+}
+
+var _migrations73_txsub_queueSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x94\x93\x4f\x4f\xb3\x40\x10\xc6\xef\x7c\x8a\xb9\xb5\xe4\x7d\x7b\x30\x31\xbd\x34\x1e\xaa\x25\xda\x58\x69\x83\x6d\xb4\xa7\xcd\x42\x47\xd8\x04\x16\xba\x3b\xdb\xa2\x9f\xde\x00\xad\x20\x52\x8c\xd7\xd9\xe7\x99\xdf\xec\xfc\x19\x8d\xe0\x5f\x22\x42\xc5\x09\x61\x93\x59\xd6\x9d\xe7\x4c\xd7\x0e\xac\xa7\xb7\x0b\x07\x28\xd7\xc6\x67\x7b\x83\x06\x61\x68\x01\x00\x90\xe2\x52\xf3\x80\x44\x2a\x59\xc4\x75\x04\x41\xc4\x15\x0f\x08\xd5\x70\x7c\x6d\xc3\xca\x9b\x3f\x4d\xbd\x2d\x3c\x3a\xdb\xff\xa5\x5e\x48\x89\x8a\xf5\xbb\x2a\x25\xe5\x0c\xe5\x01\xe3\x34\x43\x20\xcc\x09\xdc\xe5\x1a\xdc\xcd\x62\x51\x3d\xf3\x20\x48\x8d\xa4\xda\x09\x07\xae\xde\x85\x0c\x4b\x6e\xa7\x96\x69\xdc\x1b\x94\x01\x82\x2f\x42\x21\xdb\x19\x13\x9e\x33\x12\xc9\xf9\xb5\x0e\xc6\xb8\x0b\x51\x81\x90\x84\x21\xaa\x2a\xae\x89\x93\xd1\x1d\xf4\xab\x71\x9b\xae\x8d\x9f\x08\xad\x8b\xbf\x56\x25\x9f\x12\x75\xc9\x88\x70\xd7\xe2\xb5\x64\x81\x42\x5e\x88\x38\x41\x51\xac\x26\x9e\x64\x70\x14\x14\xa5\xa6\x8a\xc0\x47\x2a\xb1\x65\x8a\xb9\x26\x56\x03\xfe\xe4\x7d\x13\x52\xe8\xe8\x57\xd7\x09\x54\x96\x5e\x37\xfa\x5b\xcf\x28\x67\x0a\xb5\x89\xa9\x9c\xa7\x65\x4f\xbe\xb6\x6b\xee\xce\x9c\xd7\xe6\x76\xb1\x0c\xe5\x4e\xc8\x10\x96\x6e\x33\x0c\x9b\xe7\xb9\x7b\x0f\x3e\x29\x44\x18\x56\x33\xb0\xe1\xe5\xc1\xf1\x9c\xf3\x44\x6e\x60\x70\xf2\x0e\x26\x97\xd3\x5f\x58\xc3\x3e\x5a\xb7\xc5\xee\x81\x34\x3b\xd7\x97\xb9\xa1\x2b\x7a\xd2\xbc\xc0\x59\x7a\x94\x96\x35\xf3\x96\xab\x9f\x17\x38\xb1\x3e\x01\x00\x00\xff\xff\x03\x00\xc6\x9f\xd9\x58\xac\x03\x00\x00")
+
+func migrations73_txsub_queueSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations73_txsub_queueSql,
+		"migrations/73_txsub_queue.sql",
+	)
+}
+
+func migrations73_txsub_queueSql() (*asset, error) {
+	bytes, err := migrations73_txsub_queueSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/73_txsub_queue.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0xe6, 0x30, 0x59, 0xb4, 0xd5, 0x14, 0xa6, 0xac, 0xc0, 0x68, 0xbc, 0x79, 0x94, 0x5a, 0x25, 0x45, 0x5f, 0x49, 0xae, 0x39, 0xd4, 0x4f, 0x22, 0xb4, 0xef, 0x46, 0xe7, 0xdf, 0x90, 0xa1, 0x7e, 0xdd}}
+	return a, nil
+}
+
+var _migrations74_sponsorship_effects_by_sponsorSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xbc\x92\x41\x4b\xc3\x40\x10\x85\xef\xfb\x2b\x1e\xbd\xb4\xc5\x06\x3c\x88\x1e\x02\x85\x6a\x06\xed\x65\x2b\xb1\xa5\xde\x42\x6a\x26\x76\xc1\x66\x96\xd9\x85\x92\x7f\xef\xa5\x4a\x7a\x5b\x22\x78\x9d\x99\x0f\xbe\x79\xbc\x2c\xc3\xcd\xc9\x7d\x6a\x1d\x19\x3b\x6f\xcc\x53\x49\xab\x2d\x61\x6d\x0b\x7a\x47\xf0\xd2\x05\xd1\x70\x74\xbe\xe2\xb6\xe5\x8f\x18\xaa\x43\x5f\x5d\xc6\xd8\x58\x1c\x5d\x88\xa2\xfd\xcf\x16\xbb\xb7\xb5\x7d\xc6\x21\x2a\x33\x66\xb3\x86\x63\xed\xbe\x02\xb2\xe5\x12\xd3\x0b\x35\x9d\x2f\x7e\x29\xf1\xac\x75\x74\xd2\x55\xae\x59\x60\x22\xda\xb0\x4e\xe6\xd8\xbf\x50\x49\x88\xbd\x67\x3c\xd2\x76\x4f\x64\x71\x7f\x8b\x95\x2d\xf0\x70\x97\x27\x19\xb6\xa2\x27\xd6\x91\xa2\xd7\xf0\xbf\xf8\x76\x7c\x1e\x29\x3b\x20\xff\x6c\x6a\x86\x65\x28\xe4\xdc\x19\x53\x94\x9b\xd7\xa4\x32\xe4\x09\xa7\xd7\xc1\xa6\x10\x83\xef\x72\xf3\x0d\x00\x00\xff\xff\x03\x00\xae\xcf\x6a\xd4\xab\x02\x00\x00")
+
+func migrations74_sponsorship_effects_by_sponsorSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations74_sponsorship_effects_by_sponsorSql,
+		"migrations/74_sponsorship_effects_by_sponsor.sql",
+	)
+}
+
+func migrations74_sponsorship_effects_by_sponsorSql() (*asset, error) {
+	bytes, err := migrations74_sponsorship_effects_by_sponsorSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/74_sponsorship_effects_by_sponsor.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x54, 0xb8, 0xb5, 0x78, 0x4b, 0x80, 0xf6, 0xde, 0xd7, 0xc, 0x4f, 0xe8, 0x2b, 0x23, 0x9e, 0x78, 0xa1, 0x68, 0xb5, 0xa2, 0x6c, 0x39, 0xe3, 0xa6, 0x90, 0xa0, 0x10, 0x87, 0xff, 0xb9, 0xba, 0x98}}
+	return a, nil
+}
+
 var _migrations7_modify_trades_tableSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xc4\x54\x4d\x8f\xda\x30\x14\xbc\xe7\x57\x3c\xed\x29\x51\xc3\xaa\xad\xda\xbd\x6c\x55\x09\x58\x97\x46\x65\xc3\x36\x04\xa9\xb7\xc8\x89\xdf\x06\xab\xc1\x8e\x6c\xa7\x88\x7f\x5f\x05\x08\xcd\x27\xb0\xbb\x87\x5e\x93\x99\x79\x6f\xec\xf1\x8c\x46\xf0\x6e\xc3\x53\x45\x0d\xc2\x2a\xb7\x46\x23\x60\x4a\xe6\x60\xd6\x08\x32\x63\x60\x14\x65\xa8\xc1\xd0\x38\xc3\x5b\xc8\x0b\x03\x14\x04\x6e\x41\x0a\x04\x2e\x20\xcf\x68\x82\xd6\x43\xb0\x78\x82\x70\x3c\x99\x13\x58\x73\x6d\xa4\xda\x45\x07\xde\xbd\x35\x0d\xc8\x38\x24\xbd\x3f\xc1\xb6\x00\xe0\xf4\x51\xe6\xa8\xa8\xe1\x52\x44\x9c\xc1\xc4\x9b\x79\x7e\x08\xfe\x22\x04\x7f\x35\x9f\xbb\x7b\xe4\x8d\x54\x0c\xd5\x0d\x78\x7e\x48\x66\x24\x68\xfd\xcd\x90\xa5\xa8\xa2\x24\x93\x1a\x59\x44\x0d\x84\xde\x23\x59\x86\xe3\xc7\xa7\x16\x50\x3e\x3f\xa3\x1a\x1c\x12\x53\x8d\x11\x4d\x12\x59\x08\xd3\x03\x82\x80\x7c\x23\x01\xf1\xa7\x64\x79\xda\xfc\x88\xd6\x36\x67\x4e\x5d\x44\x6b\xbc\x5a\xa2\xc4\x76\x04\x36\xa5\x6c\x87\x3e\xfd\x4e\xa6\x3f\xc0\xae\x43\xbe\xc2\xfb\x23\x71\xbf\x09\xaa\x37\x3b\x38\xe9\xbc\xc1\xc4\x49\xe3\xac\x8f\x16\xea\x9f\x95\xbd\x41\xae\x23\x8d\x59\x86\x0a\x26\x8b\xc5\x9c\x8c\xfd\xc3\xbf\x3d\xd7\x6e\x1e\xf3\x97\xce\xd2\x8e\xe5\xdc\x5b\x55\x04\x57\xbe\xf7\x73\x45\xc0\xf3\x1f\xc8\x2f\x58\x1b\xc5\xa2\x9c\x33\x58\xf8\xed\x54\xae\x96\x9e\x3f\x83\xd8\x28\x44\xb0\xfb\xc2\xe9\x56\x41\x74\x4e\xf1\xae\x8b\x52\xae\x22\xc3\x37\x18\x65\x52\xfe\x2e\xf2\xc1\x09\x93\x30\x20\xa4\x69\xc1\xed\x38\x70\x3b\xb1\xee\x1d\x5a\xd1\xae\x1a\xd9\x39\xa5\x3e\xc5\xeb\x1d\x5c\xb5\x60\xbc\x8b\xf6\xcf\xee\xd2\x79\x57\x6f\xb3\xbc\x37\xab\x5e\x4d\x0f\x72\x2b\x1a\xe5\x24\x70\x8b\xaa\xea\x25\x85\x5c\x68\x53\xe2\xaa\xde\x92\x02\x6f\x87\x7b\x09\x12\xaa\x13\xca\xf0\xd5\xfd\x14\xf3\x94\x0b\x33\xd0\x4f\x5c\x18\x4c\x51\x0d\xd5\x4e\x2f\xf7\x10\xf2\xc1\xdf\x71\xb1\x3b\x47\x96\x19\x3b\x5e\xa7\xd9\xe5\x08\xc9\x9a\x2a\x9a\x18\x54\xf0\x87\xaa\x1d\x17\xa9\x7d\xf7\xc9\x19\xe6\x70\xad\x0b\x54\x3d\xac\xcf\x77\x67\x58\x89\x64\x7d\x93\x3e\x7c\xec\xe7\x1c\x5e\x77\x6b\xfd\xaa\x03\xea\x90\x5a\x01\xc8\x22\x5d\x9b\x97\x1a\x6b\xb0\x5e\x60\xad\xc1\xbb\xda\x5c\xc5\x3a\x6b\xaf\x09\x2a\x0d\xfe\x87\x62\x7a\xc5\x13\x6c\x8b\x94\x1a\xe5\x55\x5d\x92\x68\xe5\xd1\x6d\xc7\xc6\xed\xa6\x6f\x60\xda\xe1\xe4\x2e\xcd\xeb\x04\xc5\xed\xde\xa6\xdb\x17\x0c\xe7\xfe\x6f\x00\x00\x00\xff\xff\x2a\xff\xe8\x4a\xff\x08\x00\x00")
 
 func migrations7_modify_trades_tableSqlBytes() ([]byte, error) {
@@ -1628,7 +1817,16 @@ var _bindata = map[string]func() (*asset, error){
 	"migrations/63_add_contract_id_to_asset_stats.sql":                   migrations63_add_contract_id_to_asset_statsSql,
 	"migrations/64_add_payment_flag_history_ops.sql":                     migrations64_add_payment_flag_history_opsSql,
 	"migrations/65_remove_unused_indexes.sql":                            migrations65_remove_unused_indexesSql,
+	"migrations/66_ingestion_filter_rules.sql":                           migrations66_ingestion_filter_rulesSql,
+	"migrations/67_reingest_jobs.sql":                                    migrations67_reingest_jobsSql,
+	"migrations/68_state_rebuild_ledgers.sql":                            migrations68_state_rebuild_ledgersSql,
+	"migrations/69_order_book_snapshots.sql":                             migrations69_order_book_snapshotsSql,
 	"migrations/6_create_assets_table.sql":                               migrations6_create_assets_tableSql,
+	"migrations/70_liquidity_pool_stats.sql":                             migrations70_liquidity_pool_statsSql,
+	"migrations/71_trade_aggregations_liquidity_pool_split.sql":          migrations71_trade_aggregations_liquidity_pool_splitSql,
+	"migrations/72_ledger_fee_stats.sql":                                 migrations72_ledger_fee_statsSql,
+	"migrations/73_txsub_queue.sql":                                      migrations73_txsub_queueSql,
+	"migrations/74_sponsorship_effects_by_sponsor.sql":                   migrations74_sponsorship_effects_by_sponsorSql,
 	"migrations/7_modify_trades_table.sql":                               migrations7_modify_trades_tableSql,
 	"migrations/8_add_aggregators.sql":                                   migrations8_add_aggregatorsSql,
 	"migrations/8_create_asset_stats_table.sql":                          migrations8_create_asset_stats_tableSql,
@@ -1741,7 +1939,16 @@ var _bintree = &bintree{nil, map[string]*bintree{
 		"63_add_contract_id_to_asset_stats.sql":                   {migrations63_add_contract_id_to_asset_statsSql, map[string]*bintree{}},
 		"64_add_payment_flag_history_ops.sql":                     {migrations64_add_payment_flag_history_opsSql, map[string]*bintree{}},
 		"65_remove_unused_indexes.sql":                            {migrations65_remove_unused_indexesSql, map[string]*bintree{}},
+		"66_ingestion_filter_rules.sql":                           {migrations66_ingestion_filter_rulesSql, map[string]*bintree{}},
+		"67_reingest_jobs.sql":                                    {migrations67_reingest_jobsSql, map[string]*bintree{}},
+		"68_state_rebuild_ledgers.sql":                            {migrations68_state_rebuild_ledgersSql, map[string]*bintree{}},
+		"69_order_book_snapshots.sql":                             {migrations69_order_book_snapshotsSql, map[string]*bintree{}},
 		"6_create_assets_table.sql":                               {migrations6_create_assets_tableSql, map[string]*bintree{}},
+		"70_liquidity_pool_stats.sql":                             {migrations70_liquidity_pool_statsSql, map[string]*bintree{}},
+		"71_trade_aggregations_liquidity_pool_split.sql":          {migrations71_trade_aggregations_liquidity_pool_splitSql, map[string]*bintree{}},
+		"72_ledger_fee_stats.sql":                                 {migrations72_ledger_fee_statsSql, map[string]*bintree{}},
+		"73_txsub_queue.sql":                                      {migrations73_txsub_queueSql, map[string]*bintree{}},
+		"74_sponsorship_effects_by_sponsor.sql":                   {migrations74_sponsorship_effects_by_sponsorSql, map[string]*bintree{}},
 		"7_modify_trades_table.sql":                               {migrations7_modify_trades_tableSql, map[string]*bintree{}},
 		"8_add_aggregators.sql":                                   {migrations8_add_aggregatorsSql, map[string]*bintree{}},
 		"8_create_asset_stats_table.sql":                          {migrations8_create_asset_stats_tableSql, map[string]*bintree{}},
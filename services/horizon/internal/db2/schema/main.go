@@ -92,23 +92,58 @@ func Migrate(db *sql.DB, dir MigrateDir, count int) (int, error) {
 		}
 	}
 
+	return execMigrations(db, migrate.MigrationSet{}, Migrations, dir, count)
+}
+
+// PluginMigrationsTable returns the table recording the migrations applied
+// by the ingestion processor plugin with the given name.
+func PluginMigrationsTable(name string) string {
+	return "gorp_migrations_plugin_" + name
+}
+
+// MigratePlugin performs the schema migration of the tables of an ingestion
+// processor plugin, in the same ways as Migrate. The migrations of each
+// plugin are recorded in their own table so they are independent of the
+// Horizon migrations.
+func MigratePlugin(db *sql.DB, name string, migrations migrate.MigrationSource, dir MigrateDir, count int) (int, error) {
+	set := migrate.MigrationSet{TableName: PluginMigrationsTable(name)}
+	return execMigrations(db, set, migrations, dir, count)
+}
+
+// GetPluginMigrationsUp returns the names of the migrations of an ingestion
+// processor plugin needed in the "up" direction.
+func GetPluginMigrationsUp(db *sql.DB, name string, migrations migrate.MigrationSource) ([]string, error) {
+	set := migrate.MigrationSet{TableName: PluginMigrationsTable(name)}
+	possibleMigrations, _, err := set.PlanMigration(db, "postgres", migrations, migrate.Up, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var migrationIds []string
+	for _, m := range possibleMigrations {
+		migrationIds = append(migrationIds, m.Id)
+	}
+	return migrationIds, nil
+}
+
+func execMigrations(db *sql.DB, set migrate.MigrationSet, migrations migrate.MigrationSource, dir MigrateDir, count int) (int, error) {
 	switch dir {
 	case MigrateUp:
-		return migrate.ExecMax(db, "postgres", Migrations, migrate.Up, count)
+		return set.ExecMax(db, "postgres", migrations, migrate.Up, count)
 	case MigrateDown:
-		return migrate.ExecMax(db, "postgres", Migrations, migrate.Down, count)
+		return set.ExecMax(db, "postgres", migrations, migrate.Down, count)
 	case MigrateRedo:
 
 		if count == 0 {
 			count = 1
 		}
 
-		down, err := migrate.ExecMax(db, "postgres", Migrations, migrate.Down, count)
+		down, err := set.ExecMax(db, "postgres", migrations, migrate.Down, count)
 		if err != nil {
 			return down, err
 		}
 
-		return migrate.ExecMax(db, "postgres", Migrations, migrate.Up, down)
+		return set.ExecMax(db, "postgres", migrations, migrate.Up, down)
 	default:
 		return 0, errors.New("Invalid migration direction")
 	}
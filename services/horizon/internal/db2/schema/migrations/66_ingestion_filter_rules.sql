@@ -0,0 +1,14 @@
+-- +migrate Up
+
+CREATE TABLE ingestion_filter_rules (
+    enabled bool NOT NULL default false,
+    rules jsonb NOT NULL,
+    last_modified bigint NOT NULL
+);
+
+-- insert the default disabled state
+INSERT INTO ingestion_filter_rules VALUES (false, '{"allow": [], "deny": []}', 0);
+
+-- +migrate Down
+
+DROP TABLE ingestion_filter_rules cascade;
@@ -0,0 +1,21 @@
+-- +migrate Up
+
+CREATE TABLE reingest_jobs (
+    id bigserial PRIMARY KEY,
+    from_ledger integer NOT NULL,
+    to_ledger integer NOT NULL,
+    status text NOT NULL DEFAULT 'pending',
+    attempts integer NOT NULL DEFAULT 0,
+    lease_owner text,
+    lease_expires_at timestamp without time zone,
+    last_error text,
+    created_at timestamp without time zone NOT NULL DEFAULT (now() at time zone 'utc'),
+    updated_at timestamp without time zone NOT NULL DEFAULT (now() at time zone 'utc'),
+    UNIQUE (from_ledger, to_ledger)
+);
+
+CREATE INDEX reingest_jobs_by_status ON reingest_jobs USING btree (status, from_ledger);
+
+-- +migrate Down
+
+DROP TABLE reingest_jobs cascade;
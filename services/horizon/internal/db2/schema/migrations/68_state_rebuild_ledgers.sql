@@ -0,0 +1,10 @@
+-- +migrate Up
+
+CREATE TABLE state_rebuild_ledgers (
+    sequence integer PRIMARY KEY,
+    ledger_close_meta bytea NOT NULL
+);
+
+-- +migrate Down
+
+DROP TABLE state_rebuild_ledgers cascade;
@@ -0,0 +1,18 @@
+-- +migrate Up
+
+CREATE TABLE history_order_book_snapshots (
+    ledger_sequence integer NOT NULL,
+    closed_at timestamp without time zone NOT NULL,
+    selling_asset character varying(64) NOT NULL,
+    buying_asset character varying(64) NOT NULL,
+    bids jsonb NOT NULL,
+    asks jsonb NOT NULL,
+    PRIMARY KEY (selling_asset, buying_asset, ledger_sequence)
+);
+
+CREATE INDEX history_order_book_snapshots_by_closed_at ON history_order_book_snapshots USING BTREE(selling_asset, buying_asset, closed_at);
+CREATE INDEX history_order_book_snapshots_by_ledger ON history_order_book_snapshots USING BTREE(ledger_sequence);
+
+-- +migrate Down
+
+DROP TABLE history_order_book_snapshots cascade;
@@ -0,0 +1,41 @@
+-- +migrate Up
+
+CREATE TABLE history_liquidity_pool_ledger_stats (
+    history_liquidity_pool_id bigint NOT NULL,
+    ledger_toid bigint NOT NULL,
+    ledger_closed_at timestamp without time zone NOT NULL,
+    trade_count integer NOT NULL,
+    volume_a numeric NOT NULL,
+    volume_b numeric NOT NULL,
+    fees_a numeric NOT NULL,
+    fees_b numeric NOT NULL,
+    reserve_a bigint NOT NULL,
+    reserve_b bigint NOT NULL,
+    total_shares bigint NOT NULL,
+    PRIMARY KEY (ledger_toid, history_liquidity_pool_id)
+);
+
+CREATE INDEX hlpls_by_closed_at ON history_liquidity_pool_ledger_stats USING btree (to_millis(ledger_closed_at, '3600000'::numeric));
+
+CREATE TABLE history_liquidity_pool_stats_3600000 (
+    timestamp bigint NOT NULL,
+    history_liquidity_pool_id bigint NOT NULL,
+    trade_count integer NOT NULL,
+    volume_a numeric NOT NULL,
+    volume_b numeric NOT NULL,
+    fees_a numeric NOT NULL,
+    fees_b numeric NOT NULL,
+    reserve_a bigint NOT NULL,
+    reserve_b bigint NOT NULL,
+    total_shares bigint NOT NULL,
+    open_ledger_toid bigint NOT NULL,
+    close_ledger_toid bigint NOT NULL,
+    PRIMARY KEY (history_liquidity_pool_id, timestamp)
+);
+
+CREATE INDEX hlps_open_ledger_toid ON history_liquidity_pool_stats_3600000 USING btree (open_ledger_toid);
+
+-- +migrate Down
+
+DROP TABLE history_liquidity_pool_stats_3600000 cascade;
+DROP TABLE history_liquidity_pool_ledger_stats cascade;
@@ -0,0 +1,38 @@
+-- +migrate Up
+
+ALTER TABLE history_trades_60000
+  ADD COLUMN lp_count integer NOT NULL DEFAULT 0,
+  ADD COLUMN lp_base_volume numeric NOT NULL DEFAULT 0,
+  ADD COLUMN lp_counter_volume numeric NOT NULL DEFAULT 0;
+
+-- Backfill the liquidity pool share of the existing buckets, the trades are
+-- filtered with the default --rounding-slippage-filter of 1000 bips
+-- (history.DefaultRoundingSlippageFilter). The buckets of instances running
+-- with another filter are rebuilt with it by `horizon db reingest range`.
+WITH lp_trades AS (
+  SELECT
+    to_millis(ledger_closed_at, 60000) as timestamp,
+    base_asset_id,
+    counter_asset_id,
+    count(*) as lp_count,
+    sum(base_amount) as lp_base_volume,
+    sum(counter_amount) as lp_counter_volume
+  FROM history_trades
+  WHERE trade_type = 2 AND coalesce(rounding_slippage, 0) < 1000
+  GROUP BY base_asset_id, counter_asset_id, to_millis(ledger_closed_at, 60000)
+)
+UPDATE history_trades_60000 agg SET
+  lp_count = lp_trades.lp_count,
+  lp_base_volume = lp_trades.lp_base_volume,
+  lp_counter_volume = lp_trades.lp_counter_volume
+FROM lp_trades
+WHERE agg.base_asset_id = lp_trades.base_asset_id
+  AND agg.counter_asset_id = lp_trades.counter_asset_id
+  AND agg.timestamp = lp_trades.timestamp;
+
+-- +migrate Down
+
+ALTER TABLE history_trades_60000
+  DROP COLUMN lp_count,
+  DROP COLUMN lp_base_volume,
+  DROP COLUMN lp_counter_volume;
@@ -0,0 +1,35 @@
+-- +migrate Up
+
+CREATE TABLE history_ledger_fee_stats (
+    ledger_toid bigint PRIMARY KEY,
+    transaction_count integer NOT NULL,
+    fee_charged_min bigint,
+    fee_charged_p10 bigint,
+    fee_charged_p50 bigint,
+    fee_charged_p90 bigint,
+    fee_charged_p99 bigint,
+    fee_charged_max bigint,
+    max_fee_min bigint,
+    max_fee_p10 bigint,
+    max_fee_p50 bigint,
+    max_fee_p90 bigint,
+    max_fee_p99 bigint,
+    max_fee_max bigint,
+    soroban_transaction_count integer NOT NULL,
+    soroban_fee_charged_min bigint,
+    soroban_fee_charged_p10 bigint,
+    soroban_fee_charged_p50 bigint,
+    soroban_fee_charged_p90 bigint,
+    soroban_fee_charged_p99 bigint,
+    soroban_fee_charged_max bigint,
+    soroban_refundable_fee_min bigint,
+    soroban_refundable_fee_p10 bigint,
+    soroban_refundable_fee_p50 bigint,
+    soroban_refundable_fee_p90 bigint,
+    soroban_refundable_fee_p99 bigint,
+    soroban_refundable_fee_max bigint
+);
+
+-- +migrate Down
+
+DROP TABLE history_ledger_fee_stats cascade;
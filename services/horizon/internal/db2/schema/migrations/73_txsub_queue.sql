@@ -0,0 +1,27 @@
+-- +migrate Up
+
+CREATE TABLE txsub_queue (
+    transaction_hash character(64) PRIMARY KEY,
+    inner_transaction_hash character(64),
+    tx_envelope text NOT NULL,
+    account character varying(64) NOT NULL,
+    account_sequence bigint NOT NULL,
+    max_time bigint,
+    max_ledger integer,
+    status character varying(16) NOT NULL,
+    submission_count integer NOT NULL,
+    submitted_ledger integer NOT NULL,
+    created_at timestamp without time zone NOT NULL,
+    last_submitted_at timestamp without time zone NOT NULL,
+    finished_at timestamp without time zone,
+    ledger_sequence integer,
+    tx_result text
+);
+
+CREATE INDEX txsub_queue_pending ON txsub_queue USING btree (status) WHERE status = 'pending';
+CREATE INDEX txsub_queue_inner_transaction_hash ON txsub_queue USING btree (inner_transaction_hash);
+CREATE INDEX txsub_queue_finished_at ON txsub_queue USING btree (finished_at);
+
+-- +migrate Down
+
+DROP TABLE txsub_queue;
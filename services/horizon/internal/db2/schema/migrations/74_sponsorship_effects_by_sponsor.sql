@@ -0,0 +1,11 @@
+-- +migrate Up
+
+CREATE INDEX sponsorship_effects_by_sponsor ON history_effects USING btree ((details ->> 'sponsor'), history_operation_id, "order") WHERE type BETWEEN 60 AND 74;
+CREATE INDEX sponsorship_effects_by_former_sponsor ON history_effects USING btree ((details ->> 'former_sponsor'), history_operation_id, "order") WHERE type BETWEEN 60 AND 74;
+CREATE INDEX sponsorship_effects_by_new_sponsor ON history_effects USING btree ((details ->> 'new_sponsor'), history_operation_id, "order") WHERE type BETWEEN 60 AND 74;
+
+-- +migrate Down
+
+DROP INDEX sponsorship_effects_by_sponsor;
+DROP INDEX sponsorship_effects_by_former_sponsor;
+DROP INDEX sponsorship_effects_by_new_sponsor;
@@ -16,12 +16,16 @@ import (
 
 	"github.com/pownieh/stellar_go/ingest/ledgerbackend"
 	"github.com/pownieh/stellar_go/network"
+	"github.com/pownieh/stellar_go/services/horizon/internal/db2/history"
 	"github.com/pownieh/stellar_go/services/horizon/internal/db2/schema"
+	"github.com/pownieh/stellar_go/services/horizon/internal/ingest"
+	"github.com/pownieh/stellar_go/services/horizon/internal/ledger"
 	apkg "github.com/pownieh/stellar_go/support/app"
 	support "github.com/pownieh/stellar_go/support/config"
 	"github.com/pownieh/stellar_go/support/db"
 	"github.com/pownieh/stellar_go/support/errors"
 	"github.com/pownieh/stellar_go/support/log"
+	"github.com/pownieh/stellar_go/xdr"
 )
 
 const (
@@ -57,6 +61,10 @@ const (
 	EnableIngestionFilteringFlagName = "exp-enable-ingestion-filtering"
 	// DisableTxSubFlagName is the command line flag for disabling transaction submission feature of Horizon
 	DisableTxSubFlagName = "disable-tx-sub"
+	// HistoryColdStorageURLFlagName is the command line flag for specifying the URL of the history cold storage archive
+	HistoryColdStorageURLFlagName = "history-cold-storage-url"
+	// ReplicaDatabaseURLsFlagName is the command line flag for specifying the URLs of the read replicas of the Horizon database
+	ReplicaDatabaseURLsFlagName = "replica-database-urls"
 
 	// StellarPubnet is a constant representing the Stellar public network
 	StellarPubnet = "pubnet"
@@ -74,6 +82,7 @@ var (
 	HorizonCmd       = "horizon"
 
 	DbFillGapsCmd             = "fill-gaps"
+	DbReapCmd                 = "reap"
 	DbReingestCmd             = "reingest"
 	IngestTriggerStateRebuild = "trigger-state-rebuild"
 	IngestInitGenesisStateCmd = "init-genesis-state"
@@ -90,6 +99,9 @@ var (
 		DbFillGapsCmd,
 		DbReingestCmd)
 	DatabaseBoundCommands = append(ApiServerCommands, DbCmd, IngestCmd)
+	// HistoryRetentionCommands are the commands reaping the history, which
+	// includes the API servers whether they ingest or not.
+	HistoryRetentionCommands = append(IngestionCommands, DbReapCmd)
 )
 
 // validateBothOrNeither ensures that both options are provided, if either is provided.
@@ -104,6 +116,30 @@ func validateBothOrNeither(option1, option2 string) error {
 	return nil
 }
 
+// parseOrderBookPairs parses a comma-separated list of trading pairs written
+// as selling/buying, e.g. "native/USD:GDUKMGUGDZQK6YHYA5Z6AY2G4XDSZPSZ3SW5UN3ARVMO6QSRDWP5YLEX".
+func parseOrderBookPairs(s string) ([]history.OrderBookPair, error) {
+	var pairs []history.OrderBookPair
+	for _, pair := range strings.Split(s, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		parts := strings.Split(pair, "/")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("%s is not a valid trading pair", pair)
+		}
+		assets, err := xdr.BuildAssets(parts[0] + "," + parts[1])
+		if err != nil {
+			return nil, err
+		}
+		if assets[0].Equals(assets[1]) {
+			return nil, fmt.Errorf("%s is not a valid trading pair, its assets are the same", pair)
+		}
+		pairs = append(pairs, history.OrderBookPair{Selling: assets[0], Buying: assets[1]})
+	}
+	return pairs, nil
+}
+
 func applyMigrations(config Config) error {
 	dbConn, err := db.Open("postgres", config.DatabaseURL)
 	if err != nil {
@@ -118,6 +154,14 @@ func applyMigrations(config Config) error {
 	if numMigrations > 0 {
 		stdLog.Printf("successfully applied %v horizon migrations\n", numMigrations)
 	}
+
+	numMigrations, err = ingest.MigrateProcessorPlugins(dbConn.DB.DB, schema.MigrateUp, 0)
+	if err != nil {
+		return fmt.Errorf("could not apply processor plugin migrations: %v", err)
+	}
+	if numMigrations > 0 {
+		stdLog.Printf("successfully applied %v processor plugin migrations\n", numMigrations)
+	}
 	return nil
 }
 
@@ -144,6 +188,25 @@ In order to migrate the database DOWN, using the HIGHEST version number of Horiz
 			nMigrationsDown,
 		)
 	}
+
+	dbConn, err := db.Open("postgres", config.DatabaseURL)
+	if err != nil {
+		return fmt.Errorf("could not connect to horizon db: %v", err)
+	}
+	defer dbConn.Close()
+	pluginMigrationsToApplyUp, err := ingest.ProcessorPluginMigrationsUp(dbConn.DB.DB)
+	if err != nil {
+		return err
+	}
+	if len(pluginMigrationsToApplyUp) > 0 {
+		return fmt.Errorf(
+			`There are %v processor plugin migrations to apply in the "up" direction.
+The necessary migrations are: %v
+Run "horizon db migrate up" to update your DB.`,
+			len(pluginMigrationsToApplyUp),
+			pluginMigrationsToApplyUp,
+		)
+	}
 	return nil
 }
 
@@ -171,6 +234,25 @@ func Flags() (*Config, support.ConfigOptions) {
 			Usage:          "horizon postgres read-replica to connect with, when set it will return stale history error when replica is behind primary",
 			UsedInCommands: IngestionCommands,
 		},
+		&support.ConfigOption{
+			Name:      ReplicaDatabaseURLsFlagName,
+			ConfigKey: &config.ReplicaDatabaseURLs,
+			OptType:   types.String,
+			Required:  false,
+			CustomSetValue: func(co *support.ConfigOption) error {
+				var urls []string
+				for _, url := range strings.Split(viper.GetString(co.Name), ",") {
+					if url = strings.TrimSpace(url); url != "" {
+						urls = append(urls, url)
+					}
+				}
+				*(co.ConfigKey.(*[]string)) = urls
+				return nil
+			},
+			Usage: "comma-separated list of horizon postgres read-replicas to connect with, requests are routed to a replica " +
+				"which has ingested the ledgers they need or to the primary database otherwise",
+			UsedInCommands: IngestionCommands,
+		},
 		&support.ConfigOption{
 			Name:           StellarCoreBinaryPathName,
 			OptType:        types.String,
@@ -190,6 +272,42 @@ func Flags() (*Config, support.ConfigOptions) {
 			Hidden:         false,
 			UsedInCommands: ApiServerCommands,
 		},
+		&support.ConfigOption{
+			Name:           "txsub-queue",
+			OptType:        types.Bool,
+			FlagDefault:    false,
+			Required:       false,
+			Usage:          "stores the transactions accepted by stellar-core in the Horizon DB and rebroadcasts them until they are included in a ledger or their time bounds or ledger bounds expire (cannot be used with --disable-tx-sub)",
+			ConfigKey:      &config.TxSubQueue,
+			UsedInCommands: ApiServerCommands,
+		},
+		&support.ConfigOption{
+			Name:           "txsub-queue-rebroadcast-interval",
+			ConfigKey:      &config.TxSubQueueRebroadcastInterval,
+			OptType:        types.Int,
+			FlagDefault:    15,
+			CustomSetValue: support.SetDuration,
+			Usage:          "defines how often the pending transactions of the --txsub-queue are rebroadcast to stellar-core (in seconds)",
+			UsedInCommands: ApiServerCommands,
+		},
+		&support.ConfigOption{
+			Name:           "txsub-validation",
+			OptType:        types.Bool,
+			FlagDefault:    false,
+			Required:       false,
+			Usage:          "validates the submitted transactions against the latest ingested ledger (consumed sequence number, maximum time and ledger, signatures, balance to pay the fee and trust lines; the reserves required by the operations are not checked) and rejects the ones which would fail without submitting them to stellar-core (cannot be used with --disable-tx-sub)",
+			ConfigKey:      &config.TxSubValidation,
+			UsedInCommands: ApiServerCommands,
+		},
+		&support.ConfigOption{
+			Name:           "transactions-dry-run",
+			OptType:        types.Bool,
+			FlagDefault:    false,
+			Required:       false,
+			Usage:          "enables the /transactions/dry_run endpoint which returns the expected outcome of a transaction given the latest ingested ledger without submitting it, also with --disable-tx-sub",
+			ConfigKey:      &config.TxDryRun,
+			UsedInCommands: ApiServerCommands,
+		},
 		&support.ConfigOption{
 			Name:        captiveCoreConfigAppendPathName,
 			OptType:     types.String,
@@ -607,7 +725,33 @@ func Flags() (*Config, support.ConfigOptions) {
 			OptType:        types.Uint,
 			FlagDefault:    uint(0),
 			Usage:          "the minimum number of ledgers to maintain within horizon's history tables.  0 signifies an unlimited number of ledgers will be retained",
-			UsedInCommands: IngestionCommands,
+			UsedInCommands: HistoryRetentionCommands,
+		},
+		&support.ConfigOption{
+			Name:        "history-retention-count-by-resource",
+			OptType:     types.String,
+			FlagDefault: "",
+			Required:    false,
+			Usage: "comma separated list of resource=count pairs overriding --history-retention-count for some history resources, " +
+				"e.g. effects=518400,trades=0. Resources: transactions, operations, effects and trades. The ledgers are retained " +
+				"for the longest retention",
+			CustomSetValue: func(opt *support.ConfigOption) error {
+				byResource, err := ledger.ParseHistoryRetentionByResource(viper.GetString(opt.Name))
+				if err != nil {
+					return errors.Wrapf(err, "invalid --%s", opt.Name)
+				}
+				config.HistoryRetentionCountByResource = byResource
+				return nil
+			},
+			UsedInCommands: HistoryRetentionCommands,
+		},
+		&support.ConfigOption{
+			Name:           HistoryColdStorageURLFlagName,
+			ConfigKey:      &config.HistoryColdStorageURL,
+			OptType:        types.String,
+			FlagDefault:    "",
+			Usage:          "URL of the archive (e.g. file:///var/lib/horizon/cold or s3://bucket/prefix) the history is exported to as Parquet files before being reaped. Empty disables the export",
+			UsedInCommands: HistoryRetentionCommands,
 		},
 		&support.ConfigOption{
 			Name:           "history-stale-threshold",
@@ -643,6 +787,16 @@ func Flags() (*Config, support.ConfigOptions) {
 				"A value of 2 implies running state verification on every second checkpoint.",
 			UsedInCommands: IngestionCommands,
 		},
+		&support.ConfigOption{
+			Name:        "ingest-state-verification-full-frequency",
+			ConfigKey:   &config.IngestStateVerificationFullFrequency,
+			OptType:     types.Uint,
+			FlagDefault: uint(16),
+			Usage: "the frequency in units per checkpoint for how often state verification reads all the state tables, " +
+				"even when the checksums of the state tables match the checkpoint. " +
+				"A value of 1 implies reading the state tables on every state verification.",
+			UsedInCommands: IngestionCommands,
+		},
 		&support.ConfigOption{
 			Name:           "ingest-state-verification-timeout",
 			ConfigKey:      &config.IngestStateVerificationTimeout,
@@ -697,15 +851,50 @@ func Flags() (*Config, support.ConfigOptions) {
 			Usage:          "determines if Horizon instance is behind AWS load balances like ELB or ALB, in such case client IP in the logs will be replaced with the last IP in X-Forwarded-For header (cannot be used with --behind-cloudflare)",
 			UsedInCommands: ApiServerCommands,
 		},
+		&support.ConfigOption{
+			Name:      "order-book-snapshot-pairs",
+			ConfigKey: &config.OrderBookSnapshotPairs,
+			OptType:   types.String,
+			Required:  false,
+			CustomSetValue: func(co *support.ConfigOption) error {
+				pairs, err := parseOrderBookPairs(viper.GetString(co.Name))
+				if err != nil {
+					return errors.Wrapf(err, "invalid %s", co.Name)
+				}
+				*(co.ConfigKey.(*[]history.OrderBookPair)) = pairs
+				return nil
+			},
+			Usage: "comma-separated list of trading pairs whose order book depth is stored by ingestion, " +
+				"each pair is written as selling/buying where the assets are either native or code:issuer",
+			UsedInCommands: IngestionCommands,
+		},
+		&support.ConfigOption{
+			Name:           "order-book-snapshot-interval",
+			ConfigKey:      &config.OrderBookSnapshotInterval,
+			OptType:        types.Uint,
+			FlagDefault:    uint(12),
+			Required:       false,
+			Usage:          "number of ledgers between the order book snapshots of the --order-book-snapshot-pairs",
+			UsedInCommands: IngestionCommands,
+		},
 		&support.ConfigOption{
 			Name:           "rounding-slippage-filter",
 			ConfigKey:      &config.RoundingSlippageFilter,
 			OptType:        types.Int,
-			FlagDefault:    1000,
+			FlagDefault:    history.DefaultRoundingSlippageFilter,
 			Required:       false,
 			Usage:          "excludes trades from /trade_aggregations unless their rounding slippage is <x bps",
 			UsedInCommands: IngestionCommands,
 		},
+		&support.ConfigOption{
+			Name:           "trade-aggregations-any-resolution",
+			ConfigKey:      &config.TradeAggregationsAnyResolution,
+			OptType:        types.Bool,
+			FlagDefault:    false,
+			Required:       false,
+			Usage:          "serves /trade_aggregations with any multiple of 1 minute as resolution, instead of only 1 minute, 5 minutes, 15 minutes, 1 hour, 1 day and 1 week",
+			UsedInCommands: ApiServerCommands,
+		},
 		&support.ConfigOption{
 			Name:      NetworkFlagName,
 			ConfigKey: &config.Network,
@@ -911,6 +1100,10 @@ func ApplyFlags(config *Config, flags support.ConfigOptions, options ApplyOption
 		return err
 	}
 
+	if config.RoDatabaseURL != "" && len(config.ReplicaDatabaseURLs) > 0 {
+		return fmt.Errorf("invalid config: only one option of --ro-database-url and --%s is allowed", ReplicaDatabaseURLsFlagName)
+	}
+
 	if options.AlwaysIngest {
 		config.Ingest = true
 	}
@@ -961,5 +1154,13 @@ func ApplyFlags(config *Config, flags support.ConfigOptions, options ApplyOption
 			" If Horizon is behind both, use --behind-cloudflare only")
 	}
 
+	if config.TxSubQueue && config.DisableTxSub {
+		return fmt.Errorf("invalid config: --txsub-queue cannot be used with --%s", DisableTxSubFlagName)
+	}
+
+	if config.TxSubValidation && config.DisableTxSub {
+		return fmt.Errorf("invalid config: --txsub-validation cannot be used with --%s", DisableTxSubFlagName)
+	}
+
 	return nil
 }
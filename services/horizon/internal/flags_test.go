@@ -259,3 +259,27 @@ func TestEnvironmentVariables(t *testing.T) {
 	assert.Equal(t, config.CaptiveCoreConfigPath, "../docker/captive-core-classic-integration-tests.cfg")
 	assert.Equal(t, config.CaptiveCoreConfigUseDB, true)
 }
+
+func TestParseOrderBookPairs(t *testing.T) {
+	issuer := "GDUKMGUGDZQK6YHYA5Z6AY2G4XDSZPSZ3SW5UN3ARVMO6QSRDWP5YLEX"
+	pairs, err := parseOrderBookPairs(" native/USD:" + issuer + ", EUR:" + issuer + "/USD:" + issuer + ",")
+	require.NoError(t, err)
+	require.Len(t, pairs, 2)
+	assert.Equal(t, "native/USD:"+issuer, pairs[0].String())
+	assert.Equal(t, "EUR:"+issuer+"/USD:"+issuer, pairs[1].String())
+
+	pairs, err = parseOrderBookPairs("")
+	require.NoError(t, err)
+	assert.Empty(t, pairs)
+
+	for _, invalid := range []string{
+		"native",
+		"native/",
+		"native/USD",
+		"native/USD:" + issuer + "/EUR:" + issuer,
+		"native/native",
+	} {
+		_, err = parseOrderBookPairs(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
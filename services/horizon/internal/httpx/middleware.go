@@ -26,6 +26,7 @@ import (
 	supportErrors "github.com/pownieh/stellar_go/support/errors"
 	"github.com/pownieh/stellar_go/support/log"
 	"github.com/pownieh/stellar_go/support/render/problem"
+	"github.com/pownieh/stellar_go/toid"
 )
 
 // requestCacheHeadersMiddleware adds caching headers to each response.
@@ -231,8 +232,10 @@ func recoverMiddleware(h http.Handler) http.Handler {
 
 // NewHistoryMiddleware adds session to the request context and ensures Horizon
 // is not in a stale state, which is when the difference between latest core
-// ledger and latest history ledger is higher than the given threshold
-func NewHistoryMiddleware(ledgerState *ledger.State, staleThreshold int32, session db.SessionInterface) func(http.Handler) http.Handler {
+// ledger and latest history ledger is higher than the given threshold.
+// When replicas is not nil, the session is connected to a replica which has
+// ingested the ledgers needed by the request.
+func NewHistoryMiddleware(ledgerState *ledger.State, staleThreshold int32, session db.SessionInterface, replicas *db.ReplicaPool) func(http.Handler) http.Handler {
 	return func(h http.Handler) http.Handler {
 
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -255,7 +258,18 @@ func NewHistoryMiddleware(ledgerState *ledger.State, staleThreshold int32, sessi
 				}
 			}
 
-			requestSession := session.Clone()
+			if chiRoute != nil {
+				if err := checkHistoryRetention(r, chiRoute, ledgerState.CurrentStatus()); err != nil {
+					problem.Render(ctx, w, err)
+					return
+				}
+			}
+
+			minLedger := ledgerState.CurrentStatus().HistoryLatest
+			if chiRoute != nil {
+				minLedger = neededLedger(r, chiRoute, minLedger)
+			}
+			requestSession := sessionForLedger(replicas, session, minLedger)
 			h.ServeHTTP(w, r.WithContext(
 				context.WithValue(
 					ctx,
@@ -267,13 +281,145 @@ func NewHistoryMiddleware(ledgerState *ledger.State, staleThreshold int32, sessi
 	}
 }
 
+// sessionForLedger returns a new session connected to a replica which has
+// ingested minLedger, falling back to the primary database, or a clone of
+// session when there are no replicas.
+func sessionForLedger(replicas *db.ReplicaPool, session db.SessionInterface, minLedger int32) db.SessionInterface {
+	if replicas == nil {
+		return session.Clone()
+	}
+	if minLedger < 0 {
+		minLedger = 0
+	}
+	return replicas.Session(uint64(minLedger))
+}
+
+// neededLedger returns the ledger a database must have ingested to serve r:
+// the ledger requested by r, historyLatest for the requests which return the
+// latest data, e.g. the pages without a descending cursor, or 0 when any
+// ingested ledger is enough.
+func neededLedger(r *http.Request, chiRoute *chi.Context, historyLatest int32) int32 {
+	if requested := requestedLedger(r, chiRoute); requested > 0 {
+		return requested
+	}
+	if isSingleResourceRoute(chiRoute.RoutePattern()) {
+		return 0
+	}
+	return historyLatest
+}
+
+// isSingleResourceRoute returns true if the route pattern ends with a
+// parameter, e.g. /transactions/{tx_id}, so the route serves a single
+// resource instead of a list.
+func isSingleResourceRoute(pattern string) bool {
+	segments := strings.Split(pattern, "/")
+	for i := len(segments) - 1; i >= 0; i-- {
+		if segments[i] != "" {
+			return strings.HasPrefix(segments[i], "{")
+		}
+	}
+	return false
+}
+
+// historyResourceForRoute returns the history resource served by a route,
+// which is named by the last segment of the route pattern which is not a
+// parameter, e.g. effects for /accounts/{account_id}/effects. The sponsorship
+// history is made of effects.
+func historyResourceForRoute(pattern string) (ledger.HistoryResource, bool) {
+	segments := strings.Split(pattern, "/")
+	for i := len(segments) - 1; i >= 0; i-- {
+		segment := segments[i]
+		if segment == "" || strings.HasPrefix(segment, "{") {
+			continue
+		}
+		switch segment {
+		case "transactions":
+			return ledger.HistoryResourceTransactions, true
+		case "operations", "payments":
+			return ledger.HistoryResourceOperations, true
+		case "effects":
+			return ledger.HistoryResourceEffects, true
+		case "trades":
+			return ledger.HistoryResourceTrades, true
+		case "history":
+			if i > 0 && segments[i-1] == "sponsorships" {
+				return ledger.HistoryResourceEffects, true
+			}
+		}
+		return "", false
+	}
+	return "", false
+}
+
+// requestedLedger returns the oldest ledger requested by r, from the ledger
+// or operation in the url or from the cursor of a descending page, or 0 if
+// it cannot be determined. Invalid values are ignored, they are rejected by
+// the actions.
+func requestedLedger(r *http.Request, chiRoute *chi.Context) int32 {
+	if ledgerID := chiRoute.URLParam("ledger_id"); ledgerID != "" {
+		sequence, err := strconv.ParseInt(ledgerID, 10, 32)
+		if err == nil {
+			return int32(sequence)
+		}
+		return 0
+	}
+
+	var id string
+	if opID := chiRoute.URLParam("op_id"); opID != "" {
+		id = opID
+	} else if opID = chiRoute.URLParam("id"); opID != "" {
+		id = opID
+	} else if query := r.URL.Query(); query.Get("order") == "desc" {
+		// the cursor of trades and effects is a pair of ids separated by "-"
+		id = strings.SplitN(query.Get("cursor"), "-", 2)[0]
+	}
+	if id == "" {
+		return 0
+	}
+	parsed, err := strconv.ParseInt(id, 10, 64)
+	if err != nil || parsed <= 0 {
+		return 0
+	}
+	return toid.Parse(parsed).LedgerSequence
+}
+
+// checkHistoryRetention returns a HistoryPruned problem if the request is
+// asking for history of a resource which has been pruned because the resource
+// is retained for fewer ledgers than the rest of the history.
+func checkHistoryRetention(r *http.Request, chiRoute *chi.Context, ls ledger.Status) error {
+	resource, ok := historyResourceForRoute(chiRoute.RoutePattern())
+	if !ok {
+		return nil
+	}
+	elder := ls.ResourceElder(resource)
+	if elder <= ls.HistoryElder {
+		return nil
+	}
+	sequence := requestedLedger(r, chiRoute)
+	if sequence == 0 || sequence >= elder {
+		return nil
+	}
+
+	err := hProblem.HistoryPruned
+	err.Extras = map[string]interface{}{
+		"resource":             resource,
+		"history_elder_ledger": elder,
+	}
+	return &err
+}
+
 // StateMiddleware is a middleware which enables a state handler if the state
 // has been initialized.
 // Unless NoStateVerification is set, it ensures that the state (ledger entries)
 // has been verified and is correct (Otherwise returns `500 Internal Server Error` to prevent
 // returning invalid data to the user)
+// When ReplicaPool is set, the lists of entries are loaded from a replica which
+// has ingested the latest ledger known to LedgerState and the single entries
+// from any replica.
 type StateMiddleware struct {
 	HorizonSession      db.SessionInterface
+	ReplicaPool         *db.ReplicaPool
+	LedgerState         *ledger.State
 	NoStateVerification bool
 }
 
@@ -313,7 +459,16 @@ func (m *StateMiddleware) WrapFunc(h http.HandlerFunc) http.HandlerFunc {
 		if chiRoute != nil {
 			ctx = context.WithValue(ctx, &db.RouteContextKey, sanitizeMetricRoute(chiRoute.RoutePattern()))
 		}
-		session := m.HorizonSession.Clone()
+		var minLedger int32
+		if m.LedgerState != nil {
+			minLedger = m.LedgerState.CurrentStatus().HistoryLatest
+			// the state of a single entry is served from any ingested ledger,
+			// the lists of entries from the latest one
+			if chiRoute != nil && isSingleResourceRoute(chiRoute.RoutePattern()) {
+				minLedger = 0
+			}
+		}
+		session := sessionForLedger(m.ReplicaPool, m.HorizonSession, minLedger)
 		q := &history.Q{session}
 		sseRequest := render.Negotiate(r) == render.MimeEventStream
 
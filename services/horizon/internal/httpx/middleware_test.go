@@ -1,9 +1,20 @@
 package httpx
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
+	"github.com/go-chi/chi"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	horizonContext "github.com/pownieh/stellar_go/services/horizon/internal/context"
+	"github.com/pownieh/stellar_go/services/horizon/internal/db2/history"
+	"github.com/pownieh/stellar_go/services/horizon/internal/ledger"
+	"github.com/pownieh/stellar_go/support/db"
+	"github.com/pownieh/stellar_go/toid"
 )
 
 func TestMiddlewareSanitizesRoutesForPrometheus(t *testing.T) {
@@ -45,3 +56,55 @@ func TestMiddlewareSanitizesRoutesForPrometheus(t *testing.T) {
 	}
 
 }
+
+func replicaTestSession(sequence uint64) *db.MockSession {
+	session := &db.MockSession{}
+	session.On("Clone").Return(session)
+	session.On("GetRaw", mock.Anything, mock.Anything, history.LatestLedgerQuery, []interface{}(nil)).
+		Run(func(args mock.Arguments) {
+			*args.Get(1).(*uint64) = sequence
+		}).
+		Return(nil)
+	return session
+}
+
+func TestHistoryMiddlewareRoutesToCaughtUpReplica(t *testing.T) {
+	primary := replicaTestSession(100)
+	replica := replicaTestSession(90)
+	replicas := db.NewReplicaPool(primary, []db.Replica{{Name: "replica", Session: replica}}, history.LatestLedgerQuery)
+	replicas.Check(context.Background())
+
+	ledgerState := &ledger.State{}
+	var selected db.SessionInterface
+	router := chi.NewRouter()
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		selected = r.Context().Value(&horizonContext.SessionContextKey).(db.SessionInterface)
+	}
+	router.With(NewHistoryMiddleware(ledgerState, 0, primary, replicas)).Get("/ledgers/{ledger_id}", handler)
+	router.With(NewHistoryMiddleware(ledgerState, 0, primary, replicas)).Get("/ledgers", handler)
+	router.With(NewHistoryMiddleware(ledgerState, 0, primary, replicas)).Get("/transactions/{tx_id}", handler)
+
+	for _, testCase := range []struct {
+		name          string
+		historyLatest int32
+		path          string
+		expected      db.SessionInterface
+	}{
+		{"replica has the requested ledger", 90, "/ledgers/80", replica},
+		{"replica is behind the requested ledger", 90, "/ledgers/95", primary},
+		{"replica has the requested ledger behind the latest ledger", 100, "/ledgers/80", replica},
+		{"replica has the latest ledger of a list", 90, "/ledgers", replica},
+		{"replica is behind the latest ledger of a list", 100, "/ledgers", primary},
+		{"replica has the cursor ledger of a descending list", 100, "/ledgers?order=desc&cursor=" + toid.New(80, 0, 0).String(), replica},
+		{"replica is behind the cursor ledger of a descending list", 100, "/ledgers?order=desc&cursor=" + toid.New(95, 0, 0).String(), primary},
+		{"replica is behind the latest ledger of an ascending list", 100, "/ledgers?order=asc&cursor=" + toid.New(80, 0, 0).String(), primary},
+		{"replica has any ledger of a single resource", 100, "/transactions/abc", replica},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			ledgerState.SetHorizonStatus(ledger.HorizonStatus{HistoryLatest: testCase.historyLatest})
+			selected = nil
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, testCase.path, nil))
+			assert.Same(t, testCase.expected, selected)
+		})
+	}
+}
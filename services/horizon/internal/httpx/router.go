@@ -30,7 +30,10 @@ import (
 type RouterConfig struct {
 	DBSession        db.SessionInterface
 	PrimaryDBSession db.SessionInterface
+	ReplicaPool      *db.ReplicaPool
 	TxSubmitter      *txsub.System
+	TxValidator      actions.TransactionValidator
+	TxDryRunner      actions.TransactionDryRunner
 	RateQuota        *throttled.RateQuota
 
 	BehindCloudflare         bool
@@ -139,6 +142,8 @@ func (r *Router) addMiddleware(config *RouterConfig,
 func (r *Router) addRoutes(config *RouterConfig, rateLimiter *throttled.HTTPRateLimiter, ledgerState *ledger.State) {
 	stateMiddleware := StateMiddleware{
 		HorizonSession: config.DBSession,
+		ReplicaPool:    config.ReplicaPool,
+		LedgerState:    ledgerState,
 	}
 
 	r.Method(http.MethodGet, "/health", config.HealthCheck)
@@ -156,7 +161,7 @@ func (r *Router) addRoutes(config *RouterConfig, rateLimiter *throttled.HTTPRate
 		LedgerSourceFactory: historyLedgerSourceFactory{ledgerState: ledgerState, updateFrequency: config.SSEUpdateFrequency},
 	}
 
-	historyMiddleware := NewHistoryMiddleware(ledgerState, int32(config.StaleThreshold), config.DBSession)
+	historyMiddleware := NewHistoryMiddleware(ledgerState, int32(config.StaleThreshold), config.DBSession, config.ReplicaPool)
 	// State endpoints behind stateMiddleware
 	r.Group(func(r chi.Router) {
 		r.Route("/accounts", func(r chi.Router) {
@@ -176,6 +181,8 @@ func (r *Router) addRoutes(config *RouterConfig, rateLimiter *throttled.HTTPRate
 					accountData,
 				))
 				r.With(stateMiddleware.Wrap).Method(http.MethodGet, "/offers", streamableStatePageHandler(ledgerState, actions.GetAccountOffersHandler{LedgerState: ledgerState}, streamHandler))
+				r.With(stateMiddleware.Wrap).Method(http.MethodGet, "/sponsorships", ObjectActionHandler{actions.GetAccountSponsorshipsHandler{}})
+				r.With(stateMiddleware.Wrap).Method(http.MethodGet, "/sponsors", ObjectActionHandler{actions.GetAccountSponsorsHandler{}})
 			})
 		})
 
@@ -195,6 +202,7 @@ func (r *Router) addRoutes(config *RouterConfig, rateLimiter *throttled.HTTPRate
 				r.With(historyMiddleware).Method(http.MethodGet, "/transactions", streamableHistoryPageHandler(ledgerState, actions.GetTransactionsHandler{LedgerState: ledgerState}, streamHandler))
 				r.With(historyMiddleware).Method(http.MethodGet, "/effects", streamableHistoryPageHandler(ledgerState, actions.GetEffectsHandler{LedgerState: ledgerState}, streamHandler))
 				r.With(historyMiddleware).Method(http.MethodGet, "/trades", streamableHistoryPageHandler(ledgerState, actions.GetTradesHandler{LedgerState: ledgerState, CoreStateGetter: config.CoreGetter}, streamHandler))
+				r.With(historyMiddleware).Method(http.MethodGet, "/stats", ObjectActionHandler{actions.GetLiquidityPoolStatsHandler{LedgerState: ledgerState}})
 			})
 		})
 
@@ -222,6 +230,28 @@ func (r *Router) addRoutes(config *RouterConfig, rateLimiter *throttled.HTTPRate
 			r.With(stateMiddleware.Wrap).Method(http.MethodGet, "/paths", findPaths)
 			r.With(stateMiddleware.Wrap).Method(http.MethodGet, "/paths/strict-receive", findPaths)
 			r.With(stateMiddleware.Wrap).Method(http.MethodGet, "/paths/strict-send", findFixedPaths)
+			r.With(stateMiddleware.Wrap).Method(http.MethodGet, "/paths/split/strict-receive", ObjectActionHandler{actions.FindSplitPathsHandler{
+				StrictSend:          false,
+				MaxPathLength:       config.MaxPathLength,
+				SetLastLedgerHeader: true,
+				PathFinder:          config.PathFinder,
+			}})
+			r.With(stateMiddleware.Wrap).Method(http.MethodGet, "/paths/split/strict-send", ObjectActionHandler{actions.FindSplitPathsHandler{
+				StrictSend:          true,
+				MaxPathLength:       config.MaxPathLength,
+				SetLastLedgerHeader: true,
+				PathFinder:          config.PathFinder,
+			}})
+			r.With(stateMiddleware.Wrap).Method(http.MethodGet, "/quote/strict-receive", ObjectActionHandler{actions.QuoteHandler{
+				StrictSend:          false,
+				SetLastLedgerHeader: true,
+				PathFinder:          config.PathFinder,
+			}})
+			r.With(stateMiddleware.Wrap).Method(http.MethodGet, "/quote/strict-send", ObjectActionHandler{actions.QuoteHandler{
+				StrictSend:          true,
+				SetLastLedgerHeader: true,
+				PathFinder:          config.PathFinder,
+			}})
 		}
 		r.With(stateMiddleware.Wrap).Method(
 			http.MethodGet,
@@ -231,6 +261,7 @@ func (r *Router) addRoutes(config *RouterConfig, rateLimiter *throttled.HTTPRate
 				action:        actions.GetOrderbookHandler{},
 			},
 		)
+		r.With(historyMiddleware).Method(http.MethodGet, "/order_book/history", ObjectActionHandler{actions.GetOrderBookSnapshotHandler{}})
 	})
 
 	// account actions - /accounts/{account_id} has been created above so we
@@ -248,6 +279,10 @@ func (r *Router) addRoutes(config *RouterConfig, rateLimiter *throttled.HTTPRate
 		}, streamHandler))
 		r.With(historyMiddleware).Method(http.MethodGet, "/accounts/{account_id:\\w+}/trades", streamableHistoryPageHandler(ledgerState, actions.GetTradesHandler{LedgerState: ledgerState, CoreStateGetter: config.CoreGetter}, streamHandler))
 		r.With(historyMiddleware).Method(http.MethodGet, "/accounts/{account_id:\\w+}/transactions", streamableHistoryPageHandler(ledgerState, actions.GetTransactionsHandler{LedgerState: ledgerState}, streamHandler))
+		r.With(historyMiddleware).Method(http.MethodGet, "/accounts/{account_id:\\w+}/sponsorships/history", streamableHistoryPageHandler(ledgerState, actions.GetEffectsHandler{
+			LedgerState:      ledgerState,
+			OnlySponsorships: true,
+		}, streamHandler))
 	})
 	// ledger actions
 	r.Route("/ledgers", func(r chi.Router) {
@@ -328,11 +363,25 @@ func (r *Router) addRoutes(config *RouterConfig, rateLimiter *throttled.HTTPRate
 		Submitter:         config.TxSubmitter,
 		NetworkPassphrase: config.NetworkPassphrase,
 		DisableTxSub:      config.DisableTxSub,
+		Validator:         config.TxValidator,
 		CoreStateGetter:   config.CoreGetter,
 	}})
+	if config.TxDryRunner != nil {
+		r.With(historyMiddleware).Method(http.MethodPost, "/transactions/dry_run", ObjectActionHandler{actions.DryRunTransactionHandler{
+			DryRunner:         config.TxDryRunner,
+			NetworkPassphrase: config.NetworkPassphrase,
+		}})
+	}
+	if config.TxSubmitter != nil && config.TxSubmitter.Queue != nil {
+		r.With(historyMiddleware).Method(http.MethodGet, "/transaction_queue/{tx_id}", ObjectActionHandler{actions.GetQueuedTransactionHandler{}})
+	}
 
 	// Network state related endpoints
-	r.Method(http.MethodGet, "/fee_stats", ObjectActionHandler{actions.FeeStatsHandler{}})
+	r.Route("/fee_stats", func(r chi.Router) {
+		r.Method(http.MethodGet, "/", ObjectActionHandler{actions.FeeStatsHandler{}})
+		r.With(historyMiddleware).Method(http.MethodGet, "/history", restPageHandler(ledgerState, actions.FeeStatsHistoryHandler{LedgerState: ledgerState}))
+		r.With(historyMiddleware).Method(http.MethodGet, "/estimate", ObjectActionHandler{actions.FeeEstimateHandler{LedgerState: ledgerState}})
+	})
 
 	// friendbot
 	if config.FriendbotURL != nil {
@@ -361,6 +410,8 @@ func (r *Router) addRoutes(config *RouterConfig, rateLimiter *throttled.HTTPRate
 	r.Internal.Get("/metrics", promhttp.HandlerFor(config.PrometheusRegistry, promhttp.HandlerOpts{}).ServeHTTP)
 	r.Internal.Get("/debug/pprof/heap", pprof.Index)
 	r.Internal.Get("/debug/pprof/profile", pprof.Profile)
+	r.Internal.With(historyMiddleware).Get("/ingestion/state_verification", actions.StateVerificationHandler{}.GetResult)
+	r.Internal.With(historyMiddleware).Get("/ingestion/state_rebuild", actions.StateRebuildHandler{}.GetStatus)
 	if config.EnableIngestionFiltering {
 		r.Internal.Route("/ingestion/filters", func(r chi.Router) {
 			handler := actions.FilterConfigHandler{}
@@ -368,6 +419,8 @@ func (r *Router) addRoutes(config *RouterConfig, rateLimiter *throttled.HTTPRate
 			r.With(historyMiddleware).Put("/account", handler.UpdateAccountConfig)
 			r.With(historyMiddleware).Get("/asset", handler.GetAssetConfig)
 			r.With(historyMiddleware).Get("/account", handler.GetAccountConfig)
+			r.With(historyMiddleware).Put("/rules", handler.UpdateRulesConfig)
+			r.With(historyMiddleware).Get("/rules", handler.GetRulesConfig)
 		})
 	}
 }
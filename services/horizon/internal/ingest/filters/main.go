@@ -39,6 +39,7 @@ var (
 type filtersCache struct {
 	assetFilter                    AssetFilter
 	accountFilter                  AccountFilter
+	rulesFilter                    RulesFilter
 	lastFilterConfigCheckUnixEpoch int64
 }
 
@@ -50,6 +51,7 @@ func NewFilters() Filters {
 	return &filtersCache{
 		assetFilter:   NewAssetFilter(),
 		accountFilter: NewAccountFilter(),
+		rulesFilter:   NewRulesFilter(),
 	}
 }
 
@@ -81,9 +83,17 @@ func (f *filtersCache) GetFilters(filterQ history.QFilter, ctx context.Context)
 		}
 	}
 
+	if filterConfig, err := filterQ.GetRulesFilterConfig(ctx); err != nil {
+		LOG.Errorf("unable to refresh rules filter config %v", err)
+	} else {
+		if err := f.rulesFilter.RefreshRulesFilter(&filterConfig); err != nil {
+			LOG.Errorf("unable to refresh rules filter config %v", err)
+		}
+	}
+
 	return f.convertCacheToList()
 }
 
 func (f *filtersCache) convertCacheToList() []processors.LedgerTransactionFilterer {
-	return []processors.LedgerTransactionFilterer{f.assetFilter, f.accountFilter}
+	return []processors.LedgerTransactionFilterer{f.assetFilter, f.accountFilter, f.rulesFilter}
 }
@@ -0,0 +1,280 @@
+package filters
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/pownieh/stellar_go/ingest"
+	"github.com/pownieh/stellar_go/protocols/horizon/operations"
+	"github.com/pownieh/stellar_go/services/horizon/internal/db2/history"
+	"github.com/pownieh/stellar_go/services/horizon/internal/ingest/processors"
+	"github.com/pownieh/stellar_go/strkey"
+	"github.com/pownieh/stellar_go/support/collections/set"
+	"github.com/pownieh/stellar_go/support/errors"
+	"github.com/pownieh/stellar_go/xdr"
+)
+
+// operationTypesByName maps the names of operation types, as used in
+// operation resources, to their xdr type.
+var operationTypesByName = func() map[string]xdr.OperationType {
+	m := make(map[string]xdr.OperationType, len(operations.TypeNames))
+	for opType, name := range operations.TypeNames {
+		m[name] = opType
+	}
+	return m
+}()
+
+type rulesFilter struct {
+	allow        []compiledRule
+	deny         []compiledRule
+	lastModified int64
+	enabled      bool
+}
+
+type RulesFilter interface {
+	processors.LedgerTransactionFilterer
+	RefreshRulesFilter(filterConfig *history.RulesFilterConfig) error
+}
+
+func NewRulesFilter() RulesFilter {
+	return &rulesFilter{}
+}
+
+// ValidateRules returns an error if the given rules are not valid, e.g. if
+// they reference an unknown operation type or an invalid memo pattern.
+func ValidateRules(rules history.FilterRules) error {
+	_, _, err := compileRules(rules)
+	return err
+}
+
+func (filter *rulesFilter) RefreshRulesFilter(filterConfig *history.RulesFilterConfig) error {
+	// only need to re-initialize the filter config state(rules) if its cached version(in  memory)
+	// is older than the incoming config version based on lastModified epoch timestamp
+	if filterConfig.LastModified > filter.lastModified {
+		logger.Infof("New Rules Filter config detected, reloading new config %v ", *filterConfig)
+
+		allow, deny, err := compileRules(filterConfig.Rules)
+		if err != nil {
+			return errors.Wrap(err, "invalid filter rules")
+		}
+		filter.enabled = filterConfig.Enabled
+		filter.allow = allow
+		filter.deny = deny
+		filter.lastModified = filterConfig.LastModified
+	}
+
+	return nil
+}
+
+func (f *rulesFilter) FilterTransaction(ctx context.Context, transaction ingest.LedgerTransaction) (bool, error) {
+	if !f.enabled || (len(f.allow) == 0 && len(f.deny) == 0) {
+		return true, nil
+	}
+
+	facts := newTransactionFacts(transaction)
+	if len(f.allow) > 0 && !anyRuleMatches(f.allow, facts) {
+		logger.Debugf("No allow rule match, dropped tx with seq %v ", transaction.Envelope.SeqNum())
+		return false, nil
+	}
+	if anyRuleMatches(f.deny, facts) {
+		logger.Debugf("Deny rule match, dropped tx with seq %v ", transaction.Envelope.SeqNum())
+		return false, nil
+	}
+	return true, nil
+}
+
+// transactionFacts are the properties of a transaction that rules can match.
+type transactionFacts struct {
+	operationTypes set.Set[xdr.OperationType]
+	contractIDs    set.Set[string]
+	sourceAccounts set.Set[string]
+	memo           string
+	hasMemo        bool
+}
+
+func newTransactionFacts(transaction ingest.LedgerTransaction) transactionFacts {
+	envelope := transaction.Envelope
+	txSource := envelope.SourceAccount().ToAccountId().Address()
+	facts := transactionFacts{
+		operationTypes: set.Set[xdr.OperationType]{},
+		contractIDs:    set.Set[string]{},
+		sourceAccounts: set.Set[string]{},
+	}
+	facts.sourceAccounts.Add(txSource)
+
+	for _, op := range envelope.Operations() {
+		facts.operationTypes.Add(op.Body.Type)
+		if op.SourceAccount != nil {
+			facts.sourceAccounts.Add(op.SourceAccount.ToAccountId().Address())
+		}
+		if invoke, ok := op.Body.GetInvokeHostFunctionOp(); ok {
+			if args, ok := invoke.HostFunction.GetInvokeContract(); ok {
+				if contractID, ok := args.ContractAddress.GetContractId(); ok {
+					facts.contractIDs.Add(strkey.MustEncode(strkey.VersionByteContract, contractID[:]))
+				}
+			}
+		}
+	}
+
+	facts.memo, facts.hasMemo = memoString(envelope.Memo())
+	return facts
+}
+
+func memoString(memo xdr.Memo) (string, bool) {
+	switch memo.Type {
+	case xdr.MemoTypeMemoText:
+		return memo.MustText(), true
+	case xdr.MemoTypeMemoId:
+		return strconv.FormatUint(uint64(memo.MustId()), 10), true
+	case xdr.MemoTypeMemoHash:
+		hash := memo.MustHash()
+		return hex.EncodeToString(hash[:]), true
+	case xdr.MemoTypeMemoReturn:
+		hash := memo.MustRetHash()
+		return hex.EncodeToString(hash[:]), true
+	}
+	return "", false
+}
+
+// compiledRule is a history.FilterRule prepared for matching.
+type compiledRule struct {
+	and            []compiledRule
+	or             []compiledRule
+	not            *compiledRule
+	operationTypes set.Set[xdr.OperationType]
+	contractIDs    set.Set[string]
+	sourceAccounts set.Set[string]
+	memoPattern    *regexp.Regexp
+}
+
+func compileRules(rules history.FilterRules) ([]compiledRule, []compiledRule, error) {
+	allow, err := compileRuleList(rules.Allow, "allow")
+	if err != nil {
+		return nil, nil, err
+	}
+	deny, err := compileRuleList(rules.Deny, "deny")
+	if err != nil {
+		return nil, nil, err
+	}
+	return allow, deny, nil
+}
+
+func compileRuleList(rules []history.FilterRule, path string) ([]compiledRule, error) {
+	compiled := make([]compiledRule, 0, len(rules))
+	for i, rule := range rules {
+		c, err := compileRule(rule, fmt.Sprintf("%s[%d]", path, i))
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, c)
+	}
+	return compiled, nil
+}
+
+func compileRule(rule history.FilterRule, path string) (compiledRule, error) {
+	var (
+		c   compiledRule
+		err error
+	)
+
+	if c.and, err = compileRuleList(rule.And, path+".and"); err != nil {
+		return c, err
+	}
+	if c.or, err = compileRuleList(rule.Or, path+".or"); err != nil {
+		return c, err
+	}
+	if rule.Not != nil {
+		not, err := compileRule(*rule.Not, path+".not")
+		if err != nil {
+			return c, err
+		}
+		c.not = &not
+	}
+
+	if len(rule.OperationTypes) > 0 {
+		c.operationTypes = set.NewSet[xdr.OperationType](len(rule.OperationTypes))
+		for _, name := range rule.OperationTypes {
+			opType, ok := operationTypesByName[name]
+			if !ok {
+				return c, errors.Errorf("%s: unknown operation type %q", path, name)
+			}
+			c.operationTypes.Add(opType)
+		}
+	}
+
+	if len(rule.ContractIDs) > 0 {
+		for _, id := range rule.ContractIDs {
+			if _, err := strkey.Decode(strkey.VersionByteContract, id); err != nil {
+				return c, errors.Errorf("%s: invalid contract id %q", path, id)
+			}
+		}
+		c.contractIDs = listToSet(rule.ContractIDs)
+	}
+
+	if len(rule.SourceAccounts) > 0 {
+		for _, account := range rule.SourceAccounts {
+			if !strkey.IsValidEd25519PublicKey(account) {
+				return c, errors.Errorf("%s: invalid source account %q", path, account)
+			}
+		}
+		c.sourceAccounts = listToSet(rule.SourceAccounts)
+	}
+
+	if rule.MemoPattern != "" {
+		if c.memoPattern, err = regexp.Compile(rule.MemoPattern); err != nil {
+			return c, errors.Wrapf(err, "%s: invalid memo pattern", path)
+		}
+	}
+
+	return c, nil
+}
+
+func anyRuleMatches(rules []compiledRule, facts transactionFacts) bool {
+	for _, rule := range rules {
+		if rule.matches(facts) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches returns true if all the conditions of the rule match. A rule
+// without conditions matches all transactions.
+func (r compiledRule) matches(facts transactionFacts) bool {
+	for _, rule := range r.and {
+		if !rule.matches(facts) {
+			return false
+		}
+	}
+	if len(r.or) > 0 && !anyRuleMatches(r.or, facts) {
+		return false
+	}
+	if r.not != nil && r.not.matches(facts) {
+		return false
+	}
+	if r.operationTypes != nil && !intersects(r.operationTypes, facts.operationTypes) {
+		return false
+	}
+	if r.contractIDs != nil && !intersects(r.contractIDs, facts.contractIDs) {
+		return false
+	}
+	if r.sourceAccounts != nil && !intersects(r.sourceAccounts, facts.sourceAccounts) {
+		return false
+	}
+	if r.memoPattern != nil && (!facts.hasMemo || !r.memoPattern.MatchString(facts.memo)) {
+		return false
+	}
+	return true
+}
+
+func intersects[T comparable](a, b set.Set[T]) bool {
+	for item := range b {
+		if a.Contains(item) {
+			return true
+		}
+	}
+	return false
+}
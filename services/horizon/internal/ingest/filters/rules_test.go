@@ -0,0 +1,204 @@
+package filters
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pownieh/stellar_go/ingest"
+	"github.com/pownieh/stellar_go/services/horizon/internal/db2/history"
+	"github.com/pownieh/stellar_go/strkey"
+	"github.com/pownieh/stellar_go/xdr"
+)
+
+const (
+	rulesTestSource   = "GD6WNNTW664WH7FXC5RUMUTF7P5QSURC2IT36VOQEEGFZ4UWUEQGECAL"
+	rulesTestOpSource = "GBRPYHIL2CI3FNQ4BXLFMNDLFJUNPU2HY3ZMFSHONUCEOASW7QC7OX2H"
+)
+
+var rulesTestContractID = xdr.Hash{1, 2, 3}
+
+func TestRulesFilter(t *testing.T) {
+	contract := strkey.MustEncode(strkey.VersionByteContract, rulesTestContractID[:])
+	invoke := getRulesTestTx(t, xdr.MemoText("spam offer"), getInvokeContractOp(rulesTestOpSource))
+	payment := getRulesTestTx(t, xdr.MemoID(1234), getAccountTestTx(t, rulesTestSource, rulesTestOpSource).Envelope.Operations()[0])
+
+	for _, testCase := range []struct {
+		name    string
+		rules   history.FilterRules
+		invoke  bool
+		payment bool
+	}{
+		{
+			name:    "no rules",
+			invoke:  true,
+			payment: true,
+		},
+		{
+			name:    "allow operation type",
+			rules:   history.FilterRules{Allow: []history.FilterRule{{OperationTypes: []string{"invoke_host_function"}}}},
+			invoke:  true,
+			payment: false,
+		},
+		{
+			name:    "allow contract id",
+			rules:   history.FilterRules{Allow: []history.FilterRule{{ContractIDs: []string{contract}}}},
+			invoke:  true,
+			payment: false,
+		},
+		{
+			name:    "allow operation source account",
+			rules:   history.FilterRules{Allow: []history.FilterRule{{SourceAccounts: []string{rulesTestOpSource}}}},
+			invoke:  true,
+			payment: false,
+		},
+		{
+			name:    "allow id memo",
+			rules:   history.FilterRules{Allow: []history.FilterRule{{MemoPattern: "^12"}}},
+			invoke:  false,
+			payment: true,
+		},
+		{
+			name:    "deny text memo",
+			rules:   history.FilterRules{Deny: []history.FilterRule{{MemoPattern: "spam"}}},
+			invoke:  false,
+			payment: true,
+		},
+		{
+			name: "all conditions of a rule must match",
+			rules: history.FilterRules{Allow: []history.FilterRule{{
+				SourceAccounts: []string{rulesTestSource},
+				OperationTypes: []string{"payment"},
+			}}},
+			invoke:  false,
+			payment: true,
+		},
+		{
+			name: "or",
+			rules: history.FilterRules{Allow: []history.FilterRule{{Or: []history.FilterRule{
+				{OperationTypes: []string{"payment"}},
+				{ContractIDs: []string{contract}},
+			}}}},
+			invoke:  true,
+			payment: true,
+		},
+		{
+			name: "and",
+			rules: history.FilterRules{Allow: []history.FilterRule{{And: []history.FilterRule{
+				{SourceAccounts: []string{rulesTestSource}},
+				{MemoPattern: "spam"},
+			}}}},
+			invoke:  true,
+			payment: false,
+		},
+		{
+			name:    "not",
+			rules:   history.FilterRules{Deny: []history.FilterRule{{Not: &history.FilterRule{OperationTypes: []string{"payment"}}}}},
+			invoke:  false,
+			payment: true,
+		},
+		{
+			name: "deny takes precedence over allow",
+			rules: history.FilterRules{
+				Allow: []history.FilterRule{{SourceAccounts: []string{rulesTestSource}}},
+				Deny:  []history.FilterRule{{OperationTypes: []string{"payment"}}},
+			},
+			invoke:  true,
+			payment: false,
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			filter := NewRulesFilter()
+			require.NoError(t, filter.RefreshRulesFilter(&history.RulesFilterConfig{
+				Enabled:      true,
+				Rules:        testCase.rules,
+				LastModified: 1,
+			}))
+
+			result, err := filter.FilterTransaction(context.Background(), invoke)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.invoke, result)
+
+			result, err = filter.FilterTransaction(context.Background(), payment)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.payment, result)
+		})
+	}
+}
+
+func TestRulesFilterAllowsWhenDisabled(t *testing.T) {
+	filter := NewRulesFilter()
+	require.NoError(t, filter.RefreshRulesFilter(&history.RulesFilterConfig{
+		Enabled:      false,
+		Rules:        history.FilterRules{Deny: []history.FilterRule{{}}},
+		LastModified: 1,
+	}))
+
+	result, err := filter.FilterTransaction(context.Background(), getAccountTestTx(t, rulesTestSource, rulesTestOpSource))
+	require.NoError(t, err)
+	assert.True(t, result)
+}
+
+func TestRulesFilterKeepsRulesOnInvalidConfig(t *testing.T) {
+	filter := NewRulesFilter()
+	require.NoError(t, filter.RefreshRulesFilter(&history.RulesFilterConfig{
+		Enabled:      true,
+		Rules:        history.FilterRules{Deny: []history.FilterRule{{OperationTypes: []string{"payment"}}}},
+		LastModified: 1,
+	}))
+
+	err := filter.RefreshRulesFilter(&history.RulesFilterConfig{
+		Enabled:      true,
+		Rules:        history.FilterRules{Deny: []history.FilterRule{{OperationTypes: []string{"unknown"}}}},
+		LastModified: 2,
+	})
+	assert.EqualError(t, err, `invalid filter rules: deny[0]: unknown operation type "unknown"`)
+
+	result, err := filter.FilterTransaction(context.Background(), getAccountTestTx(t, rulesTestSource, rulesTestOpSource))
+	require.NoError(t, err)
+	assert.False(t, result)
+}
+
+func TestValidateRules(t *testing.T) {
+	assert.NoError(t, ValidateRules(history.FilterRules{}))
+	assert.EqualError(t, ValidateRules(history.FilterRules{Allow: []history.FilterRule{
+		{},
+		{Or: []history.FilterRule{{SourceAccounts: []string{"GABC"}}}},
+	}}), `allow[1].or[0]: invalid source account "GABC"`)
+	assert.EqualError(t, ValidateRules(history.FilterRules{Deny: []history.FilterRule{
+		{Not: &history.FilterRule{ContractIDs: []string{rulesTestSource}}},
+	}}), `deny[0].not: invalid contract id "`+rulesTestSource+`"`)
+	assert.Error(t, ValidateRules(history.FilterRules{Deny: []history.FilterRule{{MemoPattern: "("}}}))
+}
+
+func getInvokeContractOp(source string) xdr.Operation {
+	opSource := xdr.MustMuxedAddress(source)
+	contractID := rulesTestContractID
+	return xdr.Operation{
+		SourceAccount: &opSource,
+		Body: xdr.OperationBody{
+			Type: xdr.OperationTypeInvokeHostFunction,
+			InvokeHostFunctionOp: &xdr.InvokeHostFunctionOp{
+				HostFunction: xdr.HostFunction{
+					Type: xdr.HostFunctionTypeHostFunctionTypeInvokeContract,
+					InvokeContract: &xdr.InvokeContractArgs{
+						ContractAddress: xdr.ScAddress{
+							Type:       xdr.ScAddressTypeScAddressTypeContract,
+							ContractId: &contractID,
+						},
+						FunctionName: "transfer",
+					},
+				},
+			},
+		},
+	}
+}
+
+func getRulesTestTx(t *testing.T, memo xdr.Memo, op xdr.Operation) ingest.LedgerTransaction {
+	transaction := getAccountTestTx(t, rulesTestSource, rulesTestSource)
+	transaction.Envelope.V1.Tx.Memo = memo
+	transaction.Envelope.V1.Tx.Operations = []xdr.Operation{op}
+	return transaction
+}
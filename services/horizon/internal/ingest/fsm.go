@@ -351,6 +351,10 @@ func (b buildState) run(s *system) (transition, error) {
 	if err != nil {
 		return nextFailState, errors.Wrap(err, "Error clearing ingest tables")
 	}
+	err = truncatePluginStateTables(s.ctx, s.historyQ, s.processorPlugins)
+	if err != nil {
+		return nextFailState, errors.Wrap(err, "Error clearing processor plugin tables")
+	}
 
 	log.WithFields(logpkg.F{
 		"sequence": b.checkpointLedger,
@@ -518,6 +522,15 @@ func (r resumeState) run(s *system) (transition, error) {
 	rebuildDuration := time.Since(rebuildStart).Seconds()
 	s.Metrics().LedgerIngestionTradeAggregationDuration.Observe(float64(rebuildDuration))
 
+	err = s.historyQ.RebuildLiquidityPoolStatsBuckets(s.ctx, ingestLedger, ingestLedger)
+	if err != nil {
+		return retryResume(r), errors.Wrap(err, "error rebuilding liquidity pool stats")
+	}
+
+	if err = s.updateStateRebuild(ledgerCloseMeta); err != nil {
+		return retryResume(r), errors.Wrap(err, "error updating zero-downtime state rebuild")
+	}
+
 	if err = s.completeIngestion(s.ctx, ingestLedger); err != nil {
 		return retryResume(r), err
 	}
@@ -560,6 +573,7 @@ func (r resumeState) run(s *system) (transition, error) {
 	localLog.Info("Processed ledger")
 
 	s.maybeVerifyState(ingestLedger)
+	s.maybeStartStateRebuild()
 	s.maybeReapLookupTables(ingestLedger)
 
 	return resumeImmediately(ingestLedger), nil
@@ -737,6 +751,11 @@ func (v verifyRangeState) run(s *system) (transition, error) {
 		return stop(), errors.Wrap(err, "error rebuilding trade aggregations")
 	}
 
+	err = s.historyQ.RebuildLiquidityPoolStatsBuckets(s.ctx, v.fromLedger, v.toLedger)
+	if err != nil {
+		return stop(), errors.Wrap(err, "error rebuilding liquidity pool stats")
+	}
+
 	if v.verifyState {
 		err = s.verifyState(false)
 	}
@@ -52,6 +52,16 @@ func (h reingestHistoryRangeState) ingestRange(s *system, fromLedger, toLedger u
 	if err != nil {
 		return errors.Wrap(err, "error in DeleteRangeAll")
 	}
+	err = deletePluginHistoryRange(s.ctx, s.historyQ, s.processorPlugins, start, end)
+	if err != nil {
+		return errors.Wrap(err, "error deleting processor plugin history")
+	}
+
+	// The partitions of the range may have been dropped by the reaper
+	err = s.historyQ.CreateHistoryPartitions(s.ctx, fromLedger, toLedger)
+	if err != nil {
+		return errors.Wrap(err, "error in CreateHistoryPartitions")
+	}
 
 	// s.maxLedgerPerFlush has been validated to be at least 1
 	ledgers := make([]xdr.LedgerCloseMeta, 0, s.maxLedgerPerFlush)
@@ -186,6 +196,11 @@ func (h reingestHistoryRangeState) run(s *system) (transition, error) {
 		return stop(), errors.Wrap(err, "Error rebuilding trade aggregations")
 	}
 
+	err = s.historyQ.RebuildLiquidityPoolStatsBuckets(s.ctx, h.fromLedger, h.toLedger)
+	if err != nil {
+		return stop(), errors.Wrap(err, "Error rebuilding liquidity pool stats")
+	}
+
 	log.WithFields(logpkg.F{
 		"from":     h.fromLedger,
 		"to":       h.toLedger,
@@ -18,9 +18,21 @@ func (d processorsRunDurations) AddRunDuration(name string, startTime time.Time)
 	d[name] += time.Since(startTime)
 }
 
+// processorHook is called with the name of a processor before it writes to
+// the DB.
+type processorHook func(ctx context.Context, processor string) error
+
+func (h processorHook) run(ctx context.Context, processor interface{}) error {
+	if h == nil {
+		return nil
+	}
+	return h(ctx, fmt.Sprintf("%T", processor))
+}
+
 type groupChangeProcessors struct {
 	processors []horizonChangeProcessor
 	processorsRunDurations
+	hook processorHook
 }
 
 func newGroupChangeProcessors(processors []horizonChangeProcessor) *groupChangeProcessors {
@@ -32,6 +44,9 @@ func newGroupChangeProcessors(processors []horizonChangeProcessor) *groupChangeP
 
 func (g groupChangeProcessors) ProcessChange(ctx context.Context, change ingest.Change) error {
 	for _, p := range g.processors {
+		if err := g.hook.run(ctx, p); err != nil {
+			return err
+		}
 		startTime := time.Now()
 		if err := p.ProcessChange(ctx, change); err != nil {
 			return errors.Wrapf(err, "error in %T.ProcessChange", p)
@@ -43,6 +58,9 @@ func (g groupChangeProcessors) ProcessChange(ctx context.Context, change ingest.
 
 func (g groupChangeProcessors) Commit(ctx context.Context) error {
 	for _, p := range g.processors {
+		if err := g.hook.run(ctx, p); err != nil {
+			return err
+		}
 		startTime := time.Now()
 		if err := p.Commit(ctx); err != nil {
 			return errors.Wrapf(err, "error in %T.Commit", p)
@@ -58,6 +76,7 @@ type groupTransactionProcessors struct {
 	processorsRunDurations
 	transactionStatsProcessor *processors.StatsLedgerTransactionProcessor
 	tradeProcessor            *processors.TradeProcessor
+	hook                      processorHook
 }
 
 // build the group processor for all tx processors
@@ -99,6 +118,9 @@ func (g groupTransactionProcessors) Flush(ctx context.Context, session db.Sessio
 	// need to trigger all lazy loaders to now resolve their future placeholders
 	// with real db values first
 	for _, loader := range g.lazyLoaders {
+		if err := g.hook.run(ctx, loader); err != nil {
+			return err
+		}
 		if err := loader.Exec(ctx, session); err != nil {
 			return errors.Wrapf(err, "error during lazy loader resolution, %T.Exec", loader)
 		}
@@ -107,6 +129,9 @@ func (g groupTransactionProcessors) Flush(ctx context.Context, session db.Sessio
 	// now flush each processor which may call loader.GetNow(), which
 	// required the prior loader.Exec() to have been called.
 	for _, p := range g.processors {
+		if err := g.hook.run(ctx, p); err != nil {
+			return err
+		}
 		startTime := time.Now()
 		if err := p.Flush(ctx, session); err != nil {
 			return errors.Wrapf(err, "error in %T.Flush", p)
@@ -383,6 +383,9 @@ func (s *ReingestHistoryRangeStateTestSuite) TestReingestHistoryRangeStateRunTra
 	s.historyQ.On(
 		"DeleteRangeAll", s.ctx, toidFrom.ToInt64(), toidTo.ToInt64(),
 	).Return(nil).Once()
+	s.historyQ.On(
+		"CreateHistoryPartitions", s.ctx, uint32(toidFrom.LedgerSequence), uint32(toidTo.LedgerSequence-1),
+	).Return(nil).Once()
 
 	meta := xdr.LedgerCloseMeta{
 		V0: &xdr.LedgerCloseMetaV0{
@@ -413,6 +416,9 @@ func (s *ReingestHistoryRangeStateTestSuite) TestReingestHistoryRangeStateCommit
 	s.historyQ.On(
 		"DeleteRangeAll", s.ctx, toidFrom.ToInt64(), toidTo.ToInt64(),
 	).Return(nil).Once()
+	s.historyQ.On(
+		"CreateHistoryPartitions", s.ctx, uint32(toidFrom.LedgerSequence), uint32(toidTo.LedgerSequence-1),
+	).Return(nil).Once()
 
 	for i := uint32(100); i <= uint32(200); i++ {
 		meta := xdr.LedgerCloseMeta{
@@ -442,8 +448,12 @@ func (s *ReingestHistoryRangeStateTestSuite) TestReingestHistoryRangeStateSucces
 	s.historyQ.On(
 		"DeleteRangeAll", s.ctx, toidFrom.ToInt64(), toidTo.ToInt64(),
 	).Return(nil).Once()
+	s.historyQ.On(
+		"CreateHistoryPartitions", s.ctx, uint32(toidFrom.LedgerSequence), uint32(toidTo.LedgerSequence-1),
+	).Return(nil).Once()
 	s.historyQ.On("Commit").Return(nil).Once()
 	s.historyQ.On("RebuildTradeAggregationBuckets", s.ctx, uint32(100), uint32(200), 0).Return(nil).Once()
+	s.historyQ.On("RebuildLiquidityPoolStatsBuckets", s.ctx, uint32(100), uint32(200)).Return(nil).Once()
 
 	for i := uint32(100); i <= uint32(200); i++ {
 		meta := xdr.LedgerCloseMeta{
@@ -474,8 +484,12 @@ func (s *ReingestHistoryRangeStateTestSuite) TestReingestHistoryRangeStateSucces
 	s.historyQ.On(
 		"DeleteRangeAll", s.ctx, toidFrom.ToInt64(), toidTo.ToInt64(),
 	).Return(nil).Once()
+	s.historyQ.On(
+		"CreateHistoryPartitions", s.ctx, uint32(toidFrom.LedgerSequence), uint32(toidTo.LedgerSequence-1),
+	).Return(nil).Once()
 	s.historyQ.On("Commit").Return(nil).Once()
 	s.historyQ.On("RebuildTradeAggregationBuckets", s.ctx, uint32(100), uint32(200), 0).Return(nil).Once()
+	s.historyQ.On("RebuildLiquidityPoolStatsBuckets", s.ctx, uint32(100), uint32(200)).Return(nil).Once()
 
 	firstLedgersBatch := []xdr.LedgerCloseMeta{}
 	secondLedgersBatch := []xdr.LedgerCloseMeta{}
@@ -511,6 +525,7 @@ func (s *ReingestHistoryRangeStateTestSuite) TestReingestHistoryRangeStateSucces
 	s.historyQ.On("GetTx").Return(&sqlx.Tx{}).Once()
 	s.historyQ.On("Commit").Return(nil).Once()
 	s.historyQ.On("RebuildTradeAggregationBuckets", s.ctx, uint32(100), uint32(100), 0).Return(nil).Once()
+	s.historyQ.On("RebuildLiquidityPoolStatsBuckets", s.ctx, uint32(100), uint32(100)).Return(nil).Once()
 	// Recreate mock in this single ledger test to remove setup assertion on ledger range.
 	*s.ledgerBackend = mockLedgerBackend{}
 	s.ledgerBackend.On("PrepareRange", s.ctx, ledgerbackend.BoundedRange(100, 100)).Return(nil).Once()
@@ -520,6 +535,9 @@ func (s *ReingestHistoryRangeStateTestSuite) TestReingestHistoryRangeStateSucces
 	s.historyQ.On(
 		"DeleteRangeAll", s.ctx, toidFrom.ToInt64(), toidTo.ToInt64(),
 	).Return(nil).Once()
+	s.historyQ.On(
+		"CreateHistoryPartitions", s.ctx, uint32(toidFrom.LedgerSequence), uint32(toidTo.LedgerSequence-1),
+	).Return(nil).Once()
 
 	meta := xdr.LedgerCloseMeta{
 		V0: &xdr.LedgerCloseMetaV0{
@@ -554,12 +572,16 @@ func (s *ReingestHistoryRangeStateTestSuite) TestReingestHistoryRangeStateForce(
 	s.historyQ.On("GetLastLedgerIngest", s.ctx).Return(uint32(190), nil).Once()
 	s.historyQ.On("Commit").Return(nil).Once()
 	s.historyQ.On("RebuildTradeAggregationBuckets", s.ctx, uint32(100), uint32(200), 0).Return(nil).Once()
+	s.historyQ.On("RebuildLiquidityPoolStatsBuckets", s.ctx, uint32(100), uint32(200)).Return(nil).Once()
 
 	toidFrom := toid.New(100, 0, 0)
 	toidTo := toid.New(201, 0, 0)
 	s.historyQ.On(
 		"DeleteRangeAll", s.ctx, toidFrom.ToInt64(), toidTo.ToInt64(),
 	).Return(nil).Once()
+	s.historyQ.On(
+		"CreateHistoryPartitions", s.ctx, uint32(toidFrom.LedgerSequence), uint32(toidTo.LedgerSequence-1),
+	).Return(nil).Once()
 
 	for i := 100; i <= 200; i++ {
 		meta := xdr.LedgerCloseMeta{
@@ -592,6 +614,9 @@ func (s *ReingestHistoryRangeStateTestSuite) TestReingestHistoryRangeStateForceL
 	s.historyQ.On(
 		"DeleteRangeAll", s.ctx, toidFrom.ToInt64(), toidTo.ToInt64(),
 	).Return(nil).Once()
+	s.historyQ.On(
+		"CreateHistoryPartitions", s.ctx, uint32(toidFrom.LedgerSequence), uint32(toidTo.LedgerSequence-1),
+	).Return(nil).Once()
 
 	for i := 100; i <= 105; i++ {
 		meta := xdr.LedgerCloseMeta{
@@ -626,6 +651,9 @@ func (s *ReingestHistoryRangeStateTestSuite) TestReingestHistoryRangeStateForceL
 	s.historyQ.On(
 		"DeleteRangeAll", s.ctx, toidFrom.ToInt64(), toidTo.ToInt64(),
 	).Return(nil).Once()
+	s.historyQ.On(
+		"CreateHistoryPartitions", s.ctx, uint32(toidFrom.LedgerSequence), uint32(toidTo.LedgerSequence-1),
+	).Return(nil).Once()
 
 	for i := 100; i <= 105; i++ {
 		meta := xdr.LedgerCloseMeta{
@@ -655,12 +683,16 @@ func (s *ReingestHistoryRangeStateTestSuite) TestReingestHistoryRangeStateForceW
 	s.historyQ.On("GetLastLedgerIngest", s.ctx).Return(uint32(190), nil).Once()
 	s.historyQ.On("Commit").Return(nil).Once()
 	s.historyQ.On("RebuildTradeAggregationBuckets", s.ctx, uint32(100), uint32(200), 0).Return(nil).Once()
+	s.historyQ.On("RebuildLiquidityPoolStatsBuckets", s.ctx, uint32(100), uint32(200)).Return(nil).Once()
 
 	toidFrom := toid.New(100, 0, 0)
 	toidTo := toid.New(201, 0, 0)
 	s.historyQ.On(
 		"DeleteRangeAll", s.ctx, toidFrom.ToInt64(), toidTo.ToInt64(),
 	).Return(nil).Once()
+	s.historyQ.On(
+		"CreateHistoryPartitions", s.ctx, uint32(toidFrom.LedgerSequence), uint32(toidTo.LedgerSequence-1),
+	).Return(nil).Once()
 
 	firstLedgersBatch := []xdr.LedgerCloseMeta{}
 	secondLedgersBatch := []xdr.LedgerCloseMeta{}
@@ -26,6 +26,9 @@ type loggingChangeReader struct {
 	frequency int
 	source    string
 	sequence  uint32
+	// progress, if set, is called with the number of entries read and the
+	// percentage of the checkpoint read every time the progress is logged.
+	progress func(entries int, percentage float64)
 }
 
 func newloggingChangeReader(
@@ -56,12 +59,17 @@ func (lcr *loggingChangeReader) Read() (ingest.Change, error) {
 				WithField("source", lcr.source).
 				WithField("sequence", lcr.sequence)
 
+			percentage := 0.0
 			if reader, ok := lcr.ChangeReader.(*ingest.CheckpointChangeReader); ok {
+				percentage = reader.Progress()
 				logger = logger.WithField(
 					"progress",
-					fmt.Sprintf("%.02f%%", reader.Progress()),
+					fmt.Sprintf("%.02f%%", percentage),
 				)
 			}
+			if lcr.progress != nil {
+				lcr.progress(lcr.entryCount, percentage)
+			}
 
 			if lcr.profile {
 				curHeap, sysHeap := getMemStats()
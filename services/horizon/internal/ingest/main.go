@@ -102,11 +102,20 @@ type Config struct {
 	CheckpointFrequency                  uint32
 	StateVerificationCheckpointFrequency uint32
 	StateVerificationTimeout             time.Duration
+	// StateVerificationFullFrequency is the frequency, in checkpoints, of the
+	// state verifications which read the state tables even when the state
+	// checksums match.
+	StateVerificationFullFrequency uint32
 
 	RoundingSlippageFilter int
 
 	EnableIngestionFiltering bool
 	MaxLedgerPerFlush        uint32
+
+	// OrderBookSnapshotPairs are the trading pairs whose order book is
+	// stored every OrderBookSnapshotInterval ledgers.
+	OrderBookSnapshotPairs    []history.OrderBookPair
+	OrderBookSnapshotInterval uint32
 }
 
 // LocalCaptiveCoreEnabled returns true if configured to run
@@ -166,6 +175,16 @@ type Metrics struct {
 	// checked by the state verifier by type.
 	StateVerifyLedgerEntriesCount *prometheus.GaugeVec
 
+	// StateVerifyChecksumMatch exposes, by ledger entry type, whether the
+	// state checksum matched the checkpoint during the last state
+	// verification: 1 if it matched, 0 otherwise.
+	StateVerifyChecksumMatch *prometheus.GaugeVec
+
+	// StateVerifyFullVerificationCounter counts, by ledger entry type, the
+	// state verifications which read the state tables because the state
+	// checksum did not match or was unknown.
+	StateVerifyFullVerificationCounter *prometheus.CounterVec
+
 	// LedgerStatsCounter exposes ledger stats counters (like number of ops/changes).
 	LedgerStatsCounter *prometheus.CounterVec
 
@@ -186,6 +205,7 @@ type System interface {
 	BuildState(sequence uint32, skipChecks bool) error
 	ReingestRange(ledgerRanges []history.LedgerRange, force bool) error
 	BuildGenesisState() error
+	ReplayLedger(sequence uint32) (LedgerReplay, error)
 	Shutdown()
 	GetCurrentState() State
 }
@@ -199,6 +219,8 @@ type system struct {
 
 	historyQ history.IngestionQ
 	runner   ProcessorRunnerInterface
+	// processorPlugins are the processor plugins run by runner.
+	processorPlugins []ProcessorPlugin
 
 	ledgerBackend  ledgerbackend.LedgerBackend
 	historyAdapter historyArchiveAdapterInterface
@@ -218,6 +240,21 @@ type system struct {
 	disableStateVerification bool
 
 	runStateVerificationOnLedger func(uint32) bool
+	// runFullStateVerificationOnLedger returns true for the ledgers whose
+	// state verification reads the state tables even when the state
+	// checksums match the checkpoint.
+	runFullStateVerificationOnLedger func(uint32) bool
+
+	// stateRebuildRunning is true when the shadow state tables of a
+	// zero-downtime state rebuild are populated by this node.
+	stateRebuildMutex   sync.Mutex
+	stateRebuildRunning bool
+	// newStateRebuildRunner returns the runner of the processors populating
+	// the shadow state tables in the transactions of historyQ.
+	newStateRebuildRunner func(historyQ history.IngestionQ, progress func(entries int, percentage float64)) ProcessorRunnerInterface
+	// newReplayRunner returns the runner of the processors replaying a
+	// ledger, which write to historyQ.
+	newReplayRunner func(historyQ *history.Q) ProcessorRunnerInterface
 
 	reapOffsets       map[string]int64
 	maxLedgerPerFlush uint32
@@ -283,6 +320,7 @@ func NewSystem(config Config) (System, error) {
 	historyQ := &history.Q{config.HistorySession.Clone()}
 	historyAdapter := newHistoryArchiveAdapter(archive)
 	filters := filters.NewFilters()
+	plugins := ProcessorPlugins()
 
 	maxLedgersPerFlush := config.MaxLedgerPerFlush
 	if maxLedgersPerFlush < 1 {
@@ -310,11 +348,40 @@ func NewSystem(config Config) (System, error) {
 			session:        historyQ,
 			historyAdapter: historyAdapter,
 			filters:        filters,
+			plugins:        plugins,
+		},
+		processorPlugins: plugins,
+		newStateRebuildRunner: func(q history.IngestionQ, progress func(int, float64)) ProcessorRunnerInterface {
+			return &ProcessorRunner{
+				ctx:            ctx,
+				config:         config,
+				historyQ:       q,
+				session:        q.(*history.Q),
+				historyAdapter: historyAdapter,
+				filters:        filters,
+				plugins:        plugins,
+				progress:       progress,
+			}
+		},
+		newReplayRunner: func(q *history.Q) ProcessorRunnerInterface {
+			return &ProcessorRunner{
+				ctx:            ctx,
+				config:         config,
+				historyQ:       q,
+				session:        q.SessionInterface,
+				historyAdapter: historyAdapter,
+				filters:        filters,
+				plugins:        plugins,
+			}
 		},
 		runStateVerificationOnLedger: ledgerEligibleForStateVerification(
 			config.CheckpointFrequency,
 			config.StateVerificationCheckpointFrequency,
 		),
+		runFullStateVerificationOnLedger: ledgerEligibleForStateVerification(
+			config.CheckpointFrequency,
+			config.StateVerificationFullFrequency,
+		),
 		maxLedgerPerFlush: maxLedgersPerFlush,
 	}
 
@@ -395,6 +462,22 @@ func (s *system) initMetrics() {
 		[]string{"type"},
 	)
 
+	s.metrics.StateVerifyChecksumMatch = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "horizon", Subsystem: "ingest", Name: "state_verify_checksum_match",
+			Help: "1 if the state checksum of the ledger entry type matched the checkpoint in the last state verifier run, 0 otherwise",
+		},
+		[]string{"type"},
+	)
+
+	s.metrics.StateVerifyFullVerificationCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "horizon", Subsystem: "ingest", Name: "state_verify_full_verifications_total",
+			Help: "number of state verifier runs which read the ledger entries of the type from the state tables",
+		},
+		[]string{"type"},
+	)
+
 	s.metrics.LedgerStatsCounter = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Namespace: "horizon", Subsystem: "ingest", Name: "ledger_stats_total",
@@ -444,6 +527,8 @@ func (s *system) RegisterMetrics(registry *prometheus.Registry) {
 	registry.MustRegister(s.metrics.ProcessorsRunDuration)
 	registry.MustRegister(s.metrics.ProcessorsRunDurationSummary)
 	registry.MustRegister(s.metrics.StateVerifyLedgerEntriesCount)
+	registry.MustRegister(s.metrics.StateVerifyChecksumMatch)
+	registry.MustRegister(s.metrics.StateVerifyFullVerificationCounter)
 	s.ledgerBackend = ledgerbackend.WithMetrics(s.ledgerBackend, registry, "horizon")
 }
 
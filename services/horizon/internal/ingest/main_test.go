@@ -175,9 +175,10 @@ func TestStateMachineRunReturnsErrorWhenNextStateIsShutdownWithError(t *testing.
 func TestMaybeVerifyStateGetExpStateInvalidError(t *testing.T) {
 	historyQ := &mockDBQ{}
 	system := &system{
-		historyQ:                     historyQ,
-		ctx:                          context.Background(),
-		runStateVerificationOnLedger: ledgerEligibleForStateVerification(64, 1),
+		historyQ:                         historyQ,
+		ctx:                              context.Background(),
+		runStateVerificationOnLedger:     ledgerEligibleForStateVerification(64, 1),
+		runFullStateVerificationOnLedger: ledgerEligibleForStateVerification(64, 16),
 	}
 
 	var out bytes.Buffer
@@ -210,9 +211,10 @@ func TestMaybeVerifyStateGetExpStateInvalidError(t *testing.T) {
 func TestMaybeVerifyInternalDBErrCancelOrContextCanceled(t *testing.T) {
 	historyQ := &mockDBQ{}
 	system := &system{
-		historyQ:                     historyQ,
-		ctx:                          context.Background(),
-		runStateVerificationOnLedger: ledgerEligibleForStateVerification(64, 1),
+		historyQ:                         historyQ,
+		ctx:                              context.Background(),
+		runStateVerificationOnLedger:     ledgerEligibleForStateVerification(64, 1),
+		runFullStateVerificationOnLedger: ledgerEligibleForStateVerification(64, 16),
 	}
 
 	var out bytes.Buffer
@@ -300,6 +302,10 @@ type mockDBQ struct {
 	history.MockQOffers
 	history.MockQOperations
 	history.MockQSigners
+	history.MockQStateChecksums
+	history.MockQOrderBookSnapshots
+	history.MockQLiquidityPoolStats
+	history.MockQLedgerFeeStats
 	history.MockQTransactions
 	history.MockQTrustLines
 }
@@ -339,6 +345,76 @@ func (m *mockDBQ) TryStateVerificationLock(ctx context.Context) (bool, error) {
 	return args.Get(0).(bool), args.Error(1)
 }
 
+func (m *mockDBQ) Savepoint(ctx context.Context, name string) error {
+	args := m.Called(ctx, name)
+	return args.Error(0)
+}
+
+func (m *mockDBQ) RollbackToSavepoint(ctx context.Context, name string) error {
+	args := m.Called(ctx, name)
+	return args.Error(0)
+}
+
+func (m *mockDBQ) ReleaseSavepoint(ctx context.Context, name string) error {
+	args := m.Called(ctx, name)
+	return args.Error(0)
+}
+
+func (m *mockDBQ) GetStateRebuild(ctx context.Context) (history.StateRebuild, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(history.StateRebuild), args.Error(1)
+}
+
+func (m *mockDBQ) GetStateRebuildForUpdate(ctx context.Context) (history.StateRebuild, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(history.StateRebuild), args.Error(1)
+}
+
+func (m *mockDBQ) UpdateStateRebuild(ctx context.Context, rebuild history.StateRebuild) error {
+	args := m.Called(ctx, rebuild)
+	return args.Error(0)
+}
+
+func (m *mockDBQ) TryStateRebuildLock(ctx context.Context) (bool, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(bool), args.Error(1)
+}
+
+func (m *mockDBQ) InsertStateRebuildLedger(ctx context.Context, ledger xdr.LedgerCloseMeta) error {
+	args := m.Called(ctx, ledger)
+	return args.Error(0)
+}
+
+func (m *mockDBQ) GetStateRebuildLedgers(ctx context.Context, fromSequence uint32, limit uint64) ([]xdr.LedgerCloseMeta, error) {
+	args := m.Called(ctx, fromSequence, limit)
+	return args.Get(0).([]xdr.LedgerCloseMeta), args.Error(1)
+}
+
+func (m *mockDBQ) DeleteStateRebuildLedgers(ctx context.Context, toSequence uint32) error {
+	args := m.Called(ctx, toSequence)
+	return args.Error(0)
+}
+
+func (m *mockDBQ) TruncateStateRebuildLedgers(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+func (m *mockDBQ) CreateShadowStateTables(ctx context.Context, tables []string) error {
+	args := m.Called(ctx, tables)
+	return args.Error(0)
+}
+
+func (m *mockDBQ) UseShadowStateTables(ctx context.Context, shadow bool) error {
+	args := m.Called(ctx, shadow)
+	return args.Error(0)
+}
+
+func (m *mockDBQ) SwapShadowStateTables(ctx context.Context, tables []string) error {
+	args := m.Called(ctx, tables)
+	return args.Error(0)
+}
+
 func (m *mockDBQ) GetTx() *sqlx.Tx {
 	args := m.Called()
 	if args.Get(0) == nil {
@@ -402,16 +478,36 @@ func (m *mockDBQ) GetLatestHistoryLedger(ctx context.Context) (uint32, error) {
 	return args.Get(0).(uint32), args.Error(1)
 }
 
+func (m *mockDBQ) UpdateStateVerificationResult(ctx context.Context, result history.StateVerificationResult) error {
+	args := m.Called(ctx, result)
+	return args.Error(0)
+}
+
 func (m *mockDBQ) TruncateIngestStateTables(ctx context.Context) error {
 	args := m.Called(ctx)
 	return args.Error(0)
 }
 
+func (m *mockDBQ) TruncateTables(ctx context.Context, tables []string) error {
+	args := m.Called(ctx, tables)
+	return args.Error(0)
+}
+
+func (m *mockDBQ) DeleteRange(ctx context.Context, start, end int64, table string, idCol string) error {
+	args := m.Called(ctx, start, end, table, idCol)
+	return args.Error(0)
+}
+
 func (m *mockDBQ) DeleteRangeAll(ctx context.Context, start, end int64) error {
 	args := m.Called(ctx, start, end)
 	return args.Error(0)
 }
 
+func (m *mockDBQ) CreateHistoryPartitions(ctx context.Context, fromLedger, toLedger uint32) error {
+	args := m.Called(ctx, fromLedger, toLedger)
+	return args.Error(0)
+}
+
 // Methods from interfaces duplicating methods:
 
 func (m *mockDBQ) NewTransactionParticipantsBatchInsertBuilder() history.TransactionParticipantsBatchInsertBuilder {
@@ -451,6 +547,11 @@ func (m *mockDBQ) RebuildTradeAggregationBuckets(ctx context.Context, fromLedger
 	return args.Error(0)
 }
 
+func (m *mockDBQ) RebuildLiquidityPoolStatsBuckets(ctx context.Context, fromSeq, toSeq uint32) error {
+	args := m.Called(ctx, fromSeq, toSeq)
+	return args.Error(0)
+}
+
 func (m *mockDBQ) CreateAssets(ctx context.Context, assets []xdr.Asset, batchSize int) (map[string]history.Asset, error) {
 	args := m.Called(ctx, assets)
 	return args.Get(0).(map[string]history.Asset), args.Error(1)
@@ -548,6 +649,15 @@ func (m *mockProcessorsRunner) RunTransactionProcessorsOnLedgers(ledgers []xdr.L
 	return args.Error(0)
 }
 
+func (m *mockProcessorsRunner) RunChangeProcessorsOnLedgers(ledgers []xdr.LedgerCloseMeta) error {
+	args := m.Called(ledgers)
+	return args.Error(0)
+}
+
+func (m *mockProcessorsRunner) SetProcessorHook(hook func(ctx context.Context, processor string) error) {
+	m.Called(hook)
+}
+
 var _ ProcessorRunnerInterface = (*mockProcessorsRunner)(nil)
 
 type mockStellarCoreClient struct {
@@ -603,6 +713,11 @@ func (m *mockSystem) BuildGenesisState() error {
 	return args.Error(0)
 }
 
+func (m *mockSystem) ReplayLedger(sequence uint32) (LedgerReplay, error) {
+	args := m.Called(sequence)
+	return args.Get(0).(LedgerReplay), args.Error(1)
+}
+
 func (m *mockSystem) GetCurrentState() State {
 	args := m.Called()
 	return args.Get(0).(State)
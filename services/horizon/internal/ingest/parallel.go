@@ -27,6 +27,7 @@ type ParallelSystems struct {
 	config        Config
 	workerCount   uint
 	systemFactory func(Config) (System, error)
+	jobsQ         history.QReingestJobs
 }
 
 func NewParallelSystems(config Config, workerCount uint) (*ParallelSystems, error) {
@@ -0,0 +1,179 @@
+package ingest
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"sort"
+	"sync"
+
+	migrate "github.com/rubenv/sql-migrate"
+
+	"github.com/pownieh/stellar_go/services/horizon/internal/db2/history"
+	"github.com/pownieh/stellar_go/services/horizon/internal/db2/schema"
+	"github.com/pownieh/stellar_go/services/horizon/internal/ingest/processors"
+	"github.com/pownieh/stellar_go/support/db"
+	"github.com/pownieh/stellar_go/support/errors"
+)
+
+// PluginChangeProcessor processes the ledger entry changes of a ledger, or
+// the ledger entries of a checkpoint when the state is rebuilt. Commit is
+// called once all the changes have been processed.
+type PluginChangeProcessor interface {
+	processors.ChangeProcessor
+	Commit(context.Context) error
+}
+
+// ProcessorPlugin extends ingestion with custom processors which maintain
+// their own tables. The processors write to the tables in the same DB
+// transaction as the built-in processors, so the tables are always
+// consistent with the rest of the Horizon DB.
+type ProcessorPlugin struct {
+	// Name identifies the plugin, it must be unique and only contain
+	// lowercase letters, digits and underscores.
+	Name string
+	// Migrations create the tables of the plugin. They are applied with the
+	// Horizon migrations and recorded in their own table.
+	Migrations migrate.MigrationSource
+	// StateTables are truncated when the state is rebuilt, before the change
+	// processor processes the ledger entries of the checkpoint.
+	StateTables []string
+	// HistoryTables map the history tables of the plugin to their toid
+	// column. The ledgers of a range are deleted from them before the range
+	// is reingested, and they are reaped with the ledgers and exported to
+	// cold storage like the built-in history tables.
+	HistoryTables map[string]string
+	// NewChangeProcessor, if set, returns the processor of the changes of a
+	// ledger. fromCheckpoint is true when the changes are the ledger entries
+	// of a checkpoint. session is in the ingestion transaction.
+	NewChangeProcessor func(session db.SessionInterface, ledgerSequence uint32, fromCheckpoint bool) PluginChangeProcessor
+	// NewTransactionProcessor, if set, returns the processor of the
+	// transactions of the ledgers, which is also used during reingestion.
+	NewTransactionProcessor func() processors.LedgerTransactionProcessor
+}
+
+var (
+	pluginNameRegexp = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+	processorPluginsLock sync.Mutex
+	processorPlugins     []ProcessorPlugin
+)
+
+// RegisterProcessorPlugin registers a processor plugin. Plugins must be
+// registered before Horizon is started.
+func RegisterProcessorPlugin(plugin ProcessorPlugin) error {
+	if !pluginNameRegexp.MatchString(plugin.Name) {
+		return errors.Errorf("invalid processor plugin name %q", plugin.Name)
+	}
+	if plugin.NewChangeProcessor == nil && plugin.NewTransactionProcessor == nil {
+		return errors.Errorf("processor plugin %s has no processors", plugin.Name)
+	}
+
+	processorPluginsLock.Lock()
+	defer processorPluginsLock.Unlock()
+	for _, registered := range processorPlugins {
+		if registered.Name == plugin.Name {
+			return errors.Errorf("processor plugin %s is already registered", plugin.Name)
+		}
+	}
+	if err := history.RegisterHistoryTables(plugin.HistoryTables); err != nil {
+		return errors.Wrapf(err, "could not register the history tables of processor plugin %s", plugin.Name)
+	}
+	processorPlugins = append(processorPlugins, plugin)
+	return nil
+}
+
+// ProcessorPlugins returns the registered processor plugins, in the order in
+// which they were registered.
+func ProcessorPlugins() []ProcessorPlugin {
+	processorPluginsLock.Lock()
+	defer processorPluginsLock.Unlock()
+	return append([]ProcessorPlugin(nil), processorPlugins...)
+}
+
+// MigrateProcessorPlugins performs the schema migration of the registered
+// processor plugins and returns the number of migrations applied.
+func MigrateProcessorPlugins(db *sql.DB, dir schema.MigrateDir, count int) (int, error) {
+	total := 0
+	for _, plugin := range ProcessorPlugins() {
+		if plugin.Migrations == nil {
+			continue
+		}
+		applied, err := schema.MigratePlugin(db, plugin.Name, plugin.Migrations, dir, count)
+		total += applied
+		if err != nil {
+			return total, errors.Wrapf(err, "could not migrate processor plugin %s", plugin.Name)
+		}
+	}
+	return total, nil
+}
+
+// ProcessorPluginMigrationsUp returns the migrations of the registered
+// processor plugins which need to be applied in the "up" direction.
+func ProcessorPluginMigrationsUp(db *sql.DB) ([]string, error) {
+	var migrationIds []string
+	for _, plugin := range ProcessorPlugins() {
+		if plugin.Migrations == nil {
+			continue
+		}
+		ids, err := schema.GetPluginMigrationsUp(db, plugin.Name, plugin.Migrations)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not plan migrations of processor plugin %s", plugin.Name)
+		}
+		for _, id := range ids {
+			migrationIds = append(migrationIds, plugin.Name+"/"+id)
+		}
+	}
+	return migrationIds, nil
+}
+
+func pluginChangeProcessors(plugins []ProcessorPlugin, session db.SessionInterface, ledgerSequence uint32, fromCheckpoint bool) []horizonChangeProcessor {
+	var result []horizonChangeProcessor
+	for _, plugin := range plugins {
+		if plugin.NewChangeProcessor != nil {
+			result = append(result, plugin.NewChangeProcessor(session, ledgerSequence, fromCheckpoint))
+		}
+	}
+	return result
+}
+
+func pluginTransactionProcessors(plugins []ProcessorPlugin) []horizonTransactionProcessor {
+	var result []horizonTransactionProcessor
+	for _, plugin := range plugins {
+		if plugin.NewTransactionProcessor != nil {
+			result = append(result, plugin.NewTransactionProcessor())
+		}
+	}
+	return result
+}
+
+// truncatePluginStateTables truncates the state tables of the plugins, it
+// must be called in the transaction which rebuilds the state.
+func truncatePluginStateTables(ctx context.Context, q history.IngestionQ, plugins []ProcessorPlugin) error {
+	var tables []string
+	for _, plugin := range plugins {
+		tables = append(tables, plugin.StateTables...)
+	}
+	if len(tables) == 0 {
+		return nil
+	}
+	return q.TruncateTables(ctx, tables)
+}
+
+// deletePluginHistoryRange deletes the rows of the history tables of the
+// plugins whose toid is in [start, end).
+func deletePluginHistoryRange(ctx context.Context, q history.IngestionQ, plugins []ProcessorPlugin, start, end int64) error {
+	for _, plugin := range plugins {
+		tables := make([]string, 0, len(plugin.HistoryTables))
+		for table := range plugin.HistoryTables {
+			tables = append(tables, table)
+		}
+		sort.Strings(tables)
+		for _, table := range tables {
+			if err := q.DeleteRange(ctx, start, end, table, plugin.HistoryTables[table]); err != nil {
+				return errors.Wrapf(err, "error deleting range from %s", table)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package ingest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/pownieh/stellar_go/ingest"
+	"github.com/pownieh/stellar_go/services/horizon/internal/db2/history"
+	"github.com/pownieh/stellar_go/services/horizon/internal/ingest/processors"
+	"github.com/pownieh/stellar_go/support/db"
+	"github.com/pownieh/stellar_go/xdr"
+)
+
+type testPluginChangeProcessor struct {
+	session        db.SessionInterface
+	ledgerSequence uint32
+	fromCheckpoint bool
+}
+
+func (p *testPluginChangeProcessor) ProcessChange(ctx context.Context, change ingest.Change) error {
+	return nil
+}
+
+func (p *testPluginChangeProcessor) Commit(ctx context.Context) error {
+	return nil
+}
+
+type testPluginTransactionProcessor struct{}
+
+func (testPluginTransactionProcessor) ProcessTransaction(lcm xdr.LedgerCloseMeta, transaction ingest.LedgerTransaction) error {
+	return nil
+}
+
+func (testPluginTransactionProcessor) Flush(ctx context.Context, session db.SessionInterface) error {
+	return nil
+}
+
+func newTestPluginTransactionProcessor() processors.LedgerTransactionProcessor {
+	return testPluginTransactionProcessor{}
+}
+
+func newTestPluginChangeProcessor(session db.SessionInterface, ledgerSequence uint32, fromCheckpoint bool) PluginChangeProcessor {
+	return &testPluginChangeProcessor{
+		session:        session,
+		ledgerSequence: ledgerSequence,
+		fromCheckpoint: fromCheckpoint,
+	}
+}
+
+func TestRegisterProcessorPlugin(t *testing.T) {
+	defer func() {
+		processorPlugins = nil
+	}()
+
+	assert.EqualError(t, RegisterProcessorPlugin(ProcessorPlugin{
+		Name:               "Invalid-Name",
+		NewChangeProcessor: newTestPluginChangeProcessor,
+	}), `invalid processor plugin name "Invalid-Name"`)
+	assert.EqualError(t, RegisterProcessorPlugin(ProcessorPlugin{
+		Name: "empty",
+	}), "processor plugin empty has no processors")
+
+	plugin := ProcessorPlugin{
+		Name:               "balances",
+		NewChangeProcessor: newTestPluginChangeProcessor,
+	}
+	assert.NoError(t, RegisterProcessorPlugin(plugin))
+	assert.EqualError(t, RegisterProcessorPlugin(plugin), "processor plugin balances is already registered")
+
+	registered := ProcessorPlugins()
+	assert.Len(t, registered, 1)
+	assert.Equal(t, "balances", registered[0].Name)
+}
+
+func TestRegisterProcessorPluginHistoryTables(t *testing.T) {
+	defer func() {
+		processorPlugins = nil
+	}()
+
+	assert.EqualError(t, RegisterProcessorPlugin(ProcessorPlugin{
+		Name:                    "shadow",
+		HistoryTables:           map[string]string{"history_ledgers": "id"},
+		NewTransactionProcessor: newTestPluginTransactionProcessor,
+	}), "could not register the history tables of processor plugin shadow: history table history_ledgers is already known")
+	assert.Empty(t, ProcessorPlugins())
+
+	assert.NoError(t, RegisterProcessorPlugin(ProcessorPlugin{
+		Name:                    "payments_by_day",
+		HistoryTables:           map[string]string{"plugin_payments_by_day": "history_operation_id"},
+		NewTransactionProcessor: newTestPluginTransactionProcessor,
+	}))
+	assert.Contains(t, history.RegisteredHistoryTables(), "plugin_payments_by_day")
+	column, ok := history.HistoryTableToidColumn("plugin_payments_by_day")
+	assert.True(t, ok)
+	assert.Equal(t, "history_operation_id", column)
+}
+
+func TestBuildProcessorsWithPlugins(t *testing.T) {
+	ctx := context.Background()
+	q := &mockDBQ{}
+	defer mock.AssertExpectationsForObjects(t, mockChangeProcessorBatchBuilders(q, ctx, false)...)
+
+	plugins := []ProcessorPlugin{
+		{
+			Name:               "balances",
+			NewChangeProcessor: newTestPluginChangeProcessor,
+		},
+		{
+			Name: "payments",
+			NewTransactionProcessor: func() processors.LedgerTransactionProcessor {
+				return testPluginTransactionProcessor{}
+			},
+		},
+	}
+
+	session := &db.MockSession{}
+	processor := buildChangeProcessor(q, &ingest.StatsChangeProcessor{}, historyArchiveSource, 456, "", session, plugins)
+	assert.Len(t, processor.processors, 11)
+	assert.Equal(t, &testPluginChangeProcessor{
+		session:        session,
+		ledgerSequence: 456,
+		fromCheckpoint: true,
+	}, processor.processors[10])
+
+	assert.Equal(t, []horizonTransactionProcessor{testPluginTransactionProcessor{}}, pluginTransactionProcessors(plugins))
+}
+
+func TestPluginTablesAreCleared(t *testing.T) {
+	ctx := context.Background()
+	q := &mockDBQ{}
+	defer mock.AssertExpectationsForObjects(t, q)
+
+	plugins := []ProcessorPlugin{
+		{
+			Name:        "balances",
+			StateTables: []string{"plugin_balances"},
+		},
+		{
+			Name: "payments",
+			HistoryTables: map[string]string{
+				"plugin_payments":      "operation_id",
+				"plugin_payment_memos": "transaction_id",
+			},
+		},
+	}
+
+	q.On("TruncateTables", ctx, []string{"plugin_balances"}).Return(nil).Once()
+	assert.NoError(t, truncatePluginStateTables(ctx, q, plugins))
+	assert.NoError(t, truncatePluginStateTables(ctx, q, nil))
+
+	q.On("DeleteRange", ctx, int64(100), int64(200), "plugin_payment_memos", "transaction_id").Return(nil).Once()
+	q.On("DeleteRange", ctx, int64(100), int64(200), "plugin_payments", "operation_id").Return(nil).Once()
+	assert.NoError(t, deletePluginHistoryRange(ctx, q, plugins, 100, 200))
+}
@@ -77,6 +77,8 @@ type ProcessorRunnerInterface interface {
 		stats ledgerStats,
 		err error,
 	)
+	RunChangeProcessorsOnLedgers(ledgers []xdr.LedgerCloseMeta) error
+	SetProcessorHook(hook func(ctx context.Context, processor string) error)
 }
 
 var _ ProcessorRunnerInterface = (*ProcessorRunner)(nil)
@@ -91,12 +93,26 @@ type ProcessorRunner struct {
 	logMemoryStats        bool
 	filters               filters.Filters
 	lastTransactionsTmpGC time.Time
+	plugins               []ProcessorPlugin
+	// progress, if set, is called periodically with the number of ledger
+	// entries processed by RunHistoryArchiveIngestion and the percentage of
+	// the checkpoint processed.
+	progress func(entries int, percentage float64)
+	// processorHook, if set, is called with the name of each processor
+	// before it writes to the DB.
+	processorHook processorHook
 }
 
 func (s *ProcessorRunner) SetHistoryAdapter(historyAdapter historyArchiveAdapterInterface) {
 	s.historyAdapter = historyAdapter
 }
 
+// SetProcessorHook sets the function called with the name of each processor
+// before it writes to the DB.
+func (s *ProcessorRunner) SetProcessorHook(hook func(ctx context.Context, processor string) error) {
+	s.processorHook = hook
+}
+
 func (s *ProcessorRunner) EnableMemoryStatsLogging() {
 	s.logMemoryStats = true
 }
@@ -111,13 +127,15 @@ func buildChangeProcessor(
 	source ingestionSource,
 	ledgerSequence uint32,
 	networkPassphrase string,
+	session db.SessionInterface,
+	plugins []ProcessorPlugin,
 ) *groupChangeProcessors {
 	statsChangeProcessor := &statsChangeProcessor{
 		StatsChangeProcessor: changeStats,
 	}
 
 	useLedgerCache := source == ledgerSource
-	return newGroupChangeProcessors([]horizonChangeProcessor{
+	changeProcessors := []horizonChangeProcessor{
 		statsChangeProcessor,
 		processors.NewAccountDataProcessor(historyQ),
 		processors.NewAccountsProcessor(historyQ),
@@ -127,7 +145,17 @@ func buildChangeProcessor(
 		processors.NewTrustLinesProcessor(historyQ),
 		processors.NewClaimableBalancesChangeProcessor(historyQ),
 		processors.NewLiquidityPoolsChangeProcessor(historyQ, ledgerSequence),
-	})
+		processors.NewStateChecksumsProcessor(
+			historyQ,
+			func(ctx context.Context, keys []xdr.LedgerKey) ([]xdr.LedgerEntry, error) {
+				return loadStateEntries(ctx, historyQ, keys)
+			},
+			source == historyArchiveSource,
+		),
+	}
+	changeProcessors = append(changeProcessors,
+		pluginChangeProcessors(plugins, session, ledgerSequence, source == historyArchiveSource)...)
+	return newGroupChangeProcessors(changeProcessors)
 }
 
 func (s *ProcessorRunner) buildTransactionProcessor(ledgersProcessor *processors.LedgersProcessor) *groupTransactionProcessors {
@@ -154,9 +182,14 @@ func (s *ProcessorRunner) buildTransactionProcessor(ledgersProcessor *processors
 		processors.NewClaimableBalancesTransactionProcessor(cbLoader,
 			s.historyQ.NewTransactionClaimableBalanceBatchInsertBuilder(), s.historyQ.NewOperationClaimableBalanceBatchInsertBuilder()),
 		processors.NewLiquidityPoolsTransactionProcessor(lpLoader,
-			s.historyQ.NewTransactionLiquidityPoolBatchInsertBuilder(), s.historyQ.NewOperationLiquidityPoolBatchInsertBuilder())}
-
-	return newGroupTransactionProcessors(processors, lazyLoaders, statsLedgerTransactionProcessor, tradeProcessor)
+			s.historyQ.NewTransactionLiquidityPoolBatchInsertBuilder(), s.historyQ.NewOperationLiquidityPoolBatchInsertBuilder()),
+		processors.NewLiquidityPoolStatsProcessor(lpLoader, s.historyQ.NewLiquidityPoolLedgerStatsBatchInsertBuilder()),
+		processors.NewLedgerFeeStatsProcessor(s.historyQ.NewLedgerFeeStatsBatchInsertBuilder())}
+	processors = append(processors, pluginTransactionProcessors(s.plugins)...)
+
+	group := newGroupTransactionProcessors(processors, lazyLoaders, statsLedgerTransactionProcessor, tradeProcessor)
+	group.hook = s.processorHook
+	return group
 }
 
 func (s *ProcessorRunner) buildTransactionFilterer() *groupTransactionFilterers {
@@ -176,7 +209,25 @@ func (s *ProcessorRunner) buildFilteredOutProcessor() *groupTransactionProcessor
 		p = append(p, txSubProc)
 	}
 
-	return newGroupTransactionProcessors(p, nil, nil, nil)
+	group := newGroupTransactionProcessors(p, nil, nil, nil)
+	group.hook = s.processorHook
+	return group
+}
+
+// buildOrderBookSnapshotsProcessor returns the processor storing the order
+// book snapshots of the ledger, or nil when no snapshot is due.
+func (s *ProcessorRunner) buildOrderBookSnapshotsProcessor(ledger xdr.LedgerCloseMeta) horizonChangeProcessor {
+	interval := s.config.OrderBookSnapshotInterval
+	if len(s.config.OrderBookSnapshotPairs) == 0 || interval == 0 || ledger.LedgerSequence()%interval != 0 {
+		return nil
+	}
+	closedAt := time.Unix(int64(ledger.LedgerHeaderHistoryEntry().Header.ScpValue.CloseTime), 0).UTC()
+	return processors.NewOrderBookSnapshotsProcessor(
+		s.historyQ,
+		s.config.OrderBookSnapshotPairs,
+		ledger.LedgerSequence(),
+		closedAt,
+	)
 }
 
 // checkIfProtocolVersionSupported checks if this Horizon version supports the
@@ -235,6 +286,8 @@ func (s *ProcessorRunner) RunHistoryArchiveIngestion(
 		historyArchiveSource,
 		checkpointLedger,
 		s.config.NetworkPassphrase,
+		s.session,
+		s.plugins,
 	)
 
 	if checkpointLedger == 1 {
@@ -262,13 +315,15 @@ func (s *ProcessorRunner) RunHistoryArchiveIngestion(
 		log.WithField("sequence", checkpointLedger).
 			Info("Processing entries from History Archive Snapshot")
 
-		err = processors.StreamChanges(s.ctx, changeProcessor, newloggingChangeReader(
+		reader := newloggingChangeReader(
 			changeReader,
 			"historyArchive",
 			checkpointLedger,
 			logFrequency,
 			s.logMemoryStats,
-		))
+		)
+		reader.progress = s.progress
+		err = processors.StreamChanges(s.ctx, changeProcessor, reader)
 		if err != nil {
 			return changeStats.GetResults(), errors.Wrap(err, "Error streaming changes from HAS")
 		}
@@ -493,7 +548,15 @@ func (s *ProcessorRunner) RunAllProcessorsOnLedger(ledger xdr.LedgerCloseMeta) (
 		ledgerSource,
 		ledger.LedgerSequence(),
 		s.config.NetworkPassphrase,
+		s.session,
+		s.plugins,
 	)
+	if snapshots := s.buildOrderBookSnapshotsProcessor(ledger); snapshots != nil {
+		// appended last so the offers of the ledger are committed before
+		// the snapshots are taken
+		groupChangeProcessors.processors = append(groupChangeProcessors.processors, snapshots)
+	}
+	groupChangeProcessors.hook = s.processorHook
 	err = s.runChangeProcessorOnLedger(groupChangeProcessors, ledger)
 	if err != nil {
 		return
@@ -509,3 +572,30 @@ func (s *ProcessorRunner) RunAllProcessorsOnLedger(ledger xdr.LedgerCloseMeta) (
 
 	return
 }
+
+// RunChangeProcessorsOnLedgers runs the change processors, and only them,
+// on the given ledgers in order. It is used to apply ledgers to the shadow
+// state tables of a zero-downtime state rebuild.
+func (s *ProcessorRunner) RunChangeProcessorsOnLedgers(ledgers []xdr.LedgerCloseMeta) error {
+	for _, ledger := range ledgers {
+		if err := s.checkIfProtocolVersionSupported(ledger.ProtocolVersion()); err != nil {
+			return errors.Wrap(err, "Error while checking for supported protocol version")
+		}
+
+		changeStats := ingest.StatsChangeProcessor{}
+		groupChangeProcessors := buildChangeProcessor(
+			s.historyQ,
+			&changeStats,
+			ledgerSource,
+			ledger.LedgerSequence(),
+			s.config.NetworkPassphrase,
+			s.session,
+			s.plugins,
+		)
+		groupChangeProcessors.hook = s.processorHook
+		if err := s.runChangeProcessorOnLedger(groupChangeProcessors, ledger); err != nil {
+			return errors.Wrapf(err, "Error running change processors on ledger %d", ledger.LedgerSequence())
+		}
+	}
+	return nil
+}
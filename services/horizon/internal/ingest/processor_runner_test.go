@@ -6,6 +6,7 @@ import (
 	"io"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/guregu/null"
 	"github.com/guregu/null/zero"
@@ -50,6 +51,10 @@ func TestProcessorRunnerRunHistoryArchiveIngestionGenesis(t *testing.T) {
 
 	q.MockQAssetStats.On("InsertAssetStats", ctx, []history.ExpAssetStat{}, 100000).
 		Return(nil)
+	q.MockQStateChecksums.On("UpdateStateChecksums", ctx, mock.AnythingOfType("history.StateChecksums")).
+		Return(nil).Once()
+	q.MockQStateChecksums.On("UpdateStateChecksumOffsets", ctx, mock.AnythingOfType("history.StateChecksums")).
+		Return(nil).Once()
 
 	runner := ProcessorRunner{
 		ctx: ctx,
@@ -114,6 +119,10 @@ func TestProcessorRunnerRunHistoryArchiveIngestionHistoryArchive(t *testing.T) {
 
 	q.MockQAssetStats.On("InsertAssetStats", ctx, []history.ExpAssetStat{}, 100000).
 		Return(nil)
+	q.MockQStateChecksums.On("UpdateStateChecksums", ctx, mock.AnythingOfType("history.StateChecksums")).
+		Return(nil).Once()
+	q.MockQStateChecksums.On("UpdateStateChecksumOffsets", ctx, mock.AnythingOfType("history.StateChecksums")).
+		Return(nil).Once()
 
 	runner := ProcessorRunner{
 		ctx:            ctx,
@@ -180,7 +189,7 @@ func TestProcessorRunnerBuildChangeProcessor(t *testing.T) {
 	}
 
 	stats := &ingest.StatsChangeProcessor{}
-	processor := buildChangeProcessor(runner.historyQ, stats, ledgerSource, 123, "")
+	processor := buildChangeProcessor(runner.historyQ, stats, ledgerSource, 123, "", nil, nil)
 	assert.IsType(t, &groupChangeProcessors{}, processor)
 
 	assert.IsType(t, &statsChangeProcessor{}, processor.processors[0])
@@ -194,6 +203,9 @@ func TestProcessorRunnerBuildChangeProcessor(t *testing.T) {
 	assert.True(t, reflect.ValueOf(processor.processors[5]).
 		Elem().FieldByName("useLedgerEntryCache").Bool())
 	assert.IsType(t, &processors.TrustLinesProcessor{}, processor.processors[6])
+	assert.IsType(t, &processors.StateChecksumsProcessor{}, processor.processors[9])
+	assert.False(t, reflect.ValueOf(processor.processors[9]).
+		Elem().FieldByName("fromCheckpoint").Bool())
 
 	runner = ProcessorRunner{
 		ctx:      ctx,
@@ -201,7 +213,7 @@ func TestProcessorRunnerBuildChangeProcessor(t *testing.T) {
 		filters:  &MockFilters{},
 	}
 
-	processor = buildChangeProcessor(runner.historyQ, stats, historyArchiveSource, 456, "")
+	processor = buildChangeProcessor(runner.historyQ, stats, historyArchiveSource, 456, "", nil, nil)
 	assert.IsType(t, &groupChangeProcessors{}, processor)
 
 	assert.IsType(t, &statsChangeProcessor{}, processor.processors[0])
@@ -215,6 +227,9 @@ func TestProcessorRunnerBuildChangeProcessor(t *testing.T) {
 	assert.False(t, reflect.ValueOf(processor.processors[5]).
 		Elem().FieldByName("useLedgerEntryCache").Bool())
 	assert.IsType(t, &processors.TrustLinesProcessor{}, processor.processors[6])
+	assert.IsType(t, &processors.StateChecksumsProcessor{}, processor.processors[9])
+	assert.True(t, reflect.ValueOf(processor.processors[9]).
+		Elem().FieldByName("fromCheckpoint").Bool())
 }
 
 func TestProcessorRunnerBuildTransactionProcessor(t *testing.T) {
@@ -244,6 +259,10 @@ func TestProcessorRunnerBuildTransactionProcessor(t *testing.T) {
 		Return(&history.MockTransactionLiquidityPoolBatchInsertBuilder{})
 	q.MockQHistoryLiquidityPools.On("NewOperationLiquidityPoolBatchInsertBuilder").
 		Return(&history.MockOperationLiquidityPoolBatchInsertBuilder{})
+	q.MockQLiquidityPoolStats.On("NewLiquidityPoolLedgerStatsBatchInsertBuilder").
+		Return(&history.MockLiquidityPoolLedgerStatsBatchInsertBuilder{})
+	q.MockQLedgerFeeStats.On("NewLedgerFeeStatsBatchInsertBuilder").
+		Return(&history.MockLedgerFeeStatsBatchInsertBuilder{})
 
 	runner := ProcessorRunner{
 		ctx:      ctx,
@@ -263,6 +282,8 @@ func TestProcessorRunnerBuildTransactionProcessor(t *testing.T) {
 	assert.IsType(t, &processors.ParticipantsProcessor{}, processor.processors[5])
 	assert.IsType(t, &processors.ClaimableBalancesTransactionProcessor{}, processor.processors[7])
 	assert.IsType(t, &processors.LiquidityPoolsTransactionProcessor{}, processor.processors[8])
+	assert.IsType(t, &processors.LiquidityPoolStatsProcessor{}, processor.processors[9])
+	assert.IsType(t, &processors.LedgerFeeStatsProcessor{}, processor.processors[10])
 }
 
 func TestProcessorRunnerWithFilterEnabled(t *testing.T) {
@@ -556,6 +577,14 @@ func mockTxProcessorBatchBuilders(q *mockDBQ, mockSession *db.MockSession, ctx c
 	q.MockQHistoryLiquidityPools.On("NewOperationLiquidityPoolBatchInsertBuilder").
 		Return(mockOperationLiquidityPoolBatchInsertBuilder).Once()
 
+	mockLiquidityPoolLedgerStatsBatchInsertBuilder := &history.MockLiquidityPoolLedgerStatsBatchInsertBuilder{}
+	q.MockQLiquidityPoolStats.On("NewLiquidityPoolLedgerStatsBatchInsertBuilder").
+		Return(mockLiquidityPoolLedgerStatsBatchInsertBuilder).Once()
+
+	mockLedgerFeeStatsBatchInsertBuilder := &history.MockLedgerFeeStatsBatchInsertBuilder{}
+	q.MockQLedgerFeeStats.On("NewLedgerFeeStatsBatchInsertBuilder").
+		Return(mockLedgerFeeStatsBatchInsertBuilder).Once()
+
 	return []interface{}{mockTradeBatchInsertBuilder,
 		mockTransactionsBatchInsertBuilder,
 		mockOperationsBatchInsertBuilder,
@@ -565,7 +594,9 @@ func mockTxProcessorBatchBuilders(q *mockDBQ, mockSession *db.MockSession, ctx c
 		mockTransactionClaimableBalanceBatchInsertBuilder,
 		mockOperationClaimableBalanceBatchInsertBuilder,
 		mockTransactionLiquidityPoolBatchInsertBuilder,
-		mockOperationLiquidityPoolBatchInsertBuilder}
+		mockOperationLiquidityPoolBatchInsertBuilder,
+		mockLiquidityPoolLedgerStatsBatchInsertBuilder,
+		mockLedgerFeeStatsBatchInsertBuilder}
 }
 
 func mockChangeProcessorBatchBuilders(q *mockDBQ, ctx context.Context, mockExec bool) []interface{} {
@@ -636,3 +667,49 @@ func mockChangeProcessorBatchBuilders(q *mockDBQ, ctx context.Context, mockExec
 		mockTrustLinesBatchInsertBuilder,
 	}
 }
+
+func TestProcessorRunnerBuildOrderBookSnapshotsProcessor(t *testing.T) {
+	ctx := context.Background()
+	q := &mockDBQ{}
+	defer mock.AssertExpectationsForObjects(t, &q.MockQOrderBookSnapshots)
+
+	pair := history.OrderBookPair{
+		Selling: xdr.MustNewNativeAsset(),
+		Buying:  xdr.MustNewCreditAsset("USD", "GDUKMGUGDZQK6YHYA5Z6AY2G4XDSZPSZ3SW5UN3ARVMO6QSRDWP5YLEX"),
+	}
+	ledger := func(sequence uint32) xdr.LedgerCloseMeta {
+		return xdr.LedgerCloseMeta{
+			V0: &xdr.LedgerCloseMetaV0{
+				LedgerHeader: xdr.LedgerHeaderHistoryEntry{
+					Header: xdr.LedgerHeader{
+						LedgerSeq: xdr.Uint32(sequence),
+						ScpValue:  xdr.StellarValue{CloseTime: 1700000000},
+					},
+				},
+			},
+		}
+	}
+
+	runner := ProcessorRunner{
+		ctx:      ctx,
+		historyQ: q,
+		config: Config{
+			OrderBookSnapshotPairs:    []history.OrderBookPair{pair},
+			OrderBookSnapshotInterval: 12,
+		},
+	}
+	assert.Nil(t, runner.buildOrderBookSnapshotsProcessor(ledger(13)))
+
+	processor := runner.buildOrderBookSnapshotsProcessor(ledger(24))
+	assert.IsType(t, &processors.OrderBookSnapshotsProcessor{}, processor)
+	q.MockQOrderBookSnapshots.On(
+		"InsertOrderBookSnapshot", ctx, uint32(24), time.Unix(1700000000, 0).UTC(), pair, processors.MaxOrderBookSnapshotLevels,
+	).Return(nil).Once()
+	assert.NoError(t, processor.Commit(ctx))
+
+	runner.config.OrderBookSnapshotInterval = 0
+	assert.Nil(t, runner.buildOrderBookSnapshotsProcessor(ledger(24)))
+	runner.config.OrderBookSnapshotInterval = 12
+	runner.config.OrderBookSnapshotPairs = nil
+	assert.Nil(t, runner.buildOrderBookSnapshotsProcessor(ledger(24)))
+}
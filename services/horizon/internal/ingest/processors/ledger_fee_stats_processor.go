@@ -0,0 +1,136 @@
+package processors
+
+import (
+	"context"
+	"math"
+	"sort"
+
+	"github.com/pownieh/stellar_go/ingest"
+	"github.com/pownieh/stellar_go/services/horizon/internal/db2/history"
+	"github.com/pownieh/stellar_go/support/db"
+	"github.com/pownieh/stellar_go/support/errors"
+	"github.com/pownieh/stellar_go/xdr"
+)
+
+// LedgerFeeStatsProcessor computes the distribution of the fees of the
+// transactions included in each ledger. The fees of classic transactions are
+// per operation while the fees of Soroban transactions are per transaction.
+type LedgerFeeStatsProcessor struct {
+	batch   history.LedgerFeeStatsBatchInsertBuilder
+	ledgers map[uint32]*ledgerFees
+}
+
+// ledgerFees are the fees of the transactions of a ledger.
+type ledgerFees struct {
+	feeCharged           []int64
+	maxFee               []int64
+	sorobanFeeCharged    []int64
+	sorobanRefundableFee []int64
+}
+
+func NewLedgerFeeStatsProcessor(batch history.LedgerFeeStatsBatchInsertBuilder) *LedgerFeeStatsProcessor {
+	return &LedgerFeeStatsProcessor{
+		batch:   batch,
+		ledgers: map[uint32]*ledgerFees{},
+	}
+}
+
+func (p *LedgerFeeStatsProcessor) ProcessTransaction(lcm xdr.LedgerCloseMeta, transaction ingest.LedgerTransaction) error {
+	sequence := lcm.LedgerSequence()
+	fees, ok := p.ledgers[sequence]
+	if !ok {
+		fees = &ledgerFees{}
+		p.ledgers[sequence] = fees
+	}
+
+	// like in /fee_stats, the fee bump counts as an extra operation
+	opCount := int64(len(transaction.Envelope.Operations()))
+	maxFee := int64(transaction.Envelope.Fee())
+	if transaction.Envelope.IsFeeBump() {
+		opCount++
+		maxFee = transaction.Envelope.FeeBumpFee()
+	}
+	if opCount == 0 {
+		return errors.New("transaction has no operations")
+	}
+
+	if sorobanData, ok := transactionSorobanData(transaction.Envelope); ok {
+		fees.sorobanFeeCharged = append(fees.sorobanFeeCharged, int64(transaction.Result.Result.FeeCharged))
+		fees.sorobanRefundableFee = append(fees.sorobanRefundableFee, int64(sorobanData.RefundableFee))
+		return nil
+	}
+	fees.feeCharged = append(fees.feeCharged, int64(transaction.Result.Result.FeeCharged)/opCount)
+	fees.maxFee = append(fees.maxFee, maxFee/opCount)
+	return nil
+}
+
+// transactionSorobanData returns the Soroban resources of the transaction if
+// it is a Soroban transaction.
+func transactionSorobanData(envelope xdr.TransactionEnvelope) (xdr.SorobanTransactionData, bool) {
+	switch envelope.Type {
+	case xdr.EnvelopeTypeEnvelopeTypeTx:
+		return envelope.V1.Tx.Ext.GetSorobanData()
+	case xdr.EnvelopeTypeEnvelopeTypeTxFeeBump:
+		return envelope.FeeBump.Tx.InnerTx.V1.Tx.Ext.GetSorobanData()
+	default:
+		return xdr.SorobanTransactionData{}, false
+	}
+}
+
+// feeDistribution summarizes the fees, the percentiles are discrete like
+// percentile_disc in postgres.
+func feeDistribution(fees []int64) history.FeeDistribution {
+	if len(fees) == 0 {
+		return history.FeeDistribution{}
+	}
+	sorted := append([]int64(nil), fees...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	percentile := func(p float64) int64 {
+		i := int(math.Ceil(p*float64(len(sorted)))) - 1
+		if i < 0 {
+			i = 0
+		}
+		return sorted[i]
+	}
+	return history.FeeDistribution{
+		Min: sorted[0],
+		P10: percentile(0.1),
+		P50: percentile(0.5),
+		P90: percentile(0.9),
+		P99: percentile(0.99),
+		Max: sorted[len(sorted)-1],
+	}
+}
+
+func (p *LedgerFeeStatsProcessor) Flush(ctx context.Context, session db.SessionInterface) error {
+	if len(p.ledgers) == 0 {
+		return nil
+	}
+
+	sequences := make([]uint32, 0, len(p.ledgers))
+	for sequence := range p.ledgers {
+		sequences = append(sequences, sequence)
+	}
+	sort.Slice(sequences, func(i, j int) bool { return sequences[i] < sequences[j] })
+
+	for _, sequence := range sequences {
+		fees := p.ledgers[sequence]
+		err := p.batch.Add(history.LedgerFeeStats{
+			LedgerSequence:          sequence,
+			TransactionCount:        int32(len(fees.feeCharged)),
+			FeeCharged:              feeDistribution(fees.feeCharged),
+			MaxFee:                  feeDistribution(fees.maxFee),
+			SorobanTransactionCount: int32(len(fees.sorobanFeeCharged)),
+			SorobanFeeCharged:       feeDistribution(fees.sorobanFeeCharged),
+			SorobanRefundableFee:    feeDistribution(fees.sorobanRefundableFee),
+		})
+		if err != nil {
+			return errors.Wrapf(err, "error adding fee stats of ledger %d to batch", sequence)
+		}
+	}
+
+	if err := p.batch.Exec(ctx, session); err != nil {
+		return errors.Wrap(err, "error flushing ledger fee stats batch")
+	}
+	return nil
+}
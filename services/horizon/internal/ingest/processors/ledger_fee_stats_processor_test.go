@@ -0,0 +1,114 @@
+package processors
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pownieh/stellar_go/ingest"
+	"github.com/pownieh/stellar_go/services/horizon/internal/db2/history"
+	"github.com/pownieh/stellar_go/support/db"
+	"github.com/pownieh/stellar_go/xdr"
+)
+
+func TestLedgerFeeStatsProcessor(t *testing.T) {
+	ctx := context.Background()
+	transaction := func(numOps int, maxFee uint32, feeCharged xdr.Int64) ingest.LedgerTransaction {
+		tx := createTransaction(true, numOps)
+		tx.Envelope.V1.Tx.Fee = xdr.Uint32(maxFee)
+		tx.Result.Result.FeeCharged = feeCharged
+		return tx
+	}
+	sorobanTransaction := func(feeCharged, refundableFee xdr.Int64) ingest.LedgerTransaction {
+		tx := transaction(1, 100000, feeCharged)
+		tx.Envelope.V1.Tx.Ext = xdr.TransactionExt{
+			V:           1,
+			SorobanData: &xdr.SorobanTransactionData{RefundableFee: refundableFee},
+		}
+		return tx
+	}
+	lcm := func(sequence uint32) xdr.LedgerCloseMeta {
+		return xdr.LedgerCloseMeta{
+			V0: &xdr.LedgerCloseMetaV0{
+				LedgerHeader: xdr.LedgerHeaderHistoryEntry{
+					Header: xdr.LedgerHeader{LedgerSeq: xdr.Uint32(sequence)},
+				},
+			},
+		}
+	}
+
+	batch := &history.MockLedgerFeeStatsBatchInsertBuilder{}
+	defer batch.AssertExpectations(t)
+	processor := NewLedgerFeeStatsProcessor(batch)
+
+	for _, tx := range []ingest.LedgerTransaction{
+		transaction(1, 100, 100),
+		transaction(2, 1000, 400),
+		transaction(1, 300, 300),
+		sorobanTransaction(50000, 2000),
+		sorobanTransaction(30000, 1000),
+	} {
+		assert.NoError(t, processor.ProcessTransaction(lcm(21), tx))
+	}
+	assert.NoError(t, processor.ProcessTransaction(lcm(20), transaction(1, 200, 100)))
+
+	session := &db.MockSession{}
+	addCall := batch.On("Add", history.LedgerFeeStats{
+		LedgerSequence:   20,
+		TransactionCount: 1,
+		FeeCharged:       history.FeeDistribution{Min: 100, P10: 100, P50: 100, P90: 100, P99: 100, Max: 100},
+		MaxFee:           history.FeeDistribution{Min: 200, P10: 200, P50: 200, P90: 200, P99: 200, Max: 200},
+	}).Return(nil).Once()
+	batch.On("Add", history.LedgerFeeStats{
+		LedgerSequence:   21,
+		TransactionCount: 3,
+		// the fees of classic transactions are per operation
+		FeeCharged:              history.FeeDistribution{Min: 100, P10: 100, P50: 200, P90: 300, P99: 300, Max: 300},
+		MaxFee:                  history.FeeDistribution{Min: 100, P10: 100, P50: 300, P90: 500, P99: 500, Max: 500},
+		SorobanTransactionCount: 2,
+		SorobanFeeCharged:       history.FeeDistribution{Min: 30000, P10: 30000, P50: 30000, P90: 50000, P99: 50000, Max: 50000},
+		SorobanRefundableFee:    history.FeeDistribution{Min: 1000, P10: 1000, P50: 1000, P90: 2000, P99: 2000, Max: 2000},
+	}).Return(nil).Once().NotBefore(addCall)
+	batch.On("Exec", ctx, session).Return(nil).Once()
+	assert.NoError(t, processor.Flush(ctx, session))
+}
+
+func TestLedgerFeeStatsProcessorFeeBump(t *testing.T) {
+	tx := createTransaction(true, 1)
+	tx.Result.Result.FeeCharged = 400
+	inner := *tx.Envelope.V1
+	tx.Envelope = xdr.TransactionEnvelope{
+		Type: xdr.EnvelopeTypeEnvelopeTypeTxFeeBump,
+		FeeBump: &xdr.FeeBumpTransactionEnvelope{
+			Tx: xdr.FeeBumpTransaction{
+				Fee: 1000,
+				InnerTx: xdr.FeeBumpTransactionInnerTx{
+					Type: xdr.EnvelopeTypeEnvelopeTypeTx,
+					V1:   &inner,
+				},
+			},
+		},
+	}
+
+	batch := &history.MockLedgerFeeStatsBatchInsertBuilder{}
+	defer batch.AssertExpectations(t)
+	processor := NewLedgerFeeStatsProcessor(batch)
+	assert.NoError(t, processor.ProcessTransaction(xdr.LedgerCloseMeta{
+		V0: &xdr.LedgerCloseMetaV0{
+			LedgerHeader: xdr.LedgerHeaderHistoryEntry{Header: xdr.LedgerHeader{LedgerSeq: 20}},
+		},
+	}, tx))
+
+	ctx := context.Background()
+	session := &db.MockSession{}
+	// the fee bump counts as an extra operation
+	batch.On("Add", history.LedgerFeeStats{
+		LedgerSequence:   20,
+		TransactionCount: 1,
+		FeeCharged:       history.FeeDistribution{Min: 200, P10: 200, P50: 200, P90: 200, P99: 200, Max: 200},
+		MaxFee:           history.FeeDistribution{Min: 500, P10: 500, P50: 500, P90: 500, P99: 500, Max: 500},
+	}).Return(nil).Once()
+	batch.On("Exec", ctx, session).Return(nil).Once()
+	assert.NoError(t, processor.Flush(ctx, session))
+}
@@ -0,0 +1,177 @@
+package processors
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/pownieh/stellar_go/ingest"
+	"github.com/pownieh/stellar_go/services/horizon/internal/db2/history"
+	"github.com/pownieh/stellar_go/support/db"
+	"github.com/pownieh/stellar_go/support/errors"
+	"github.com/pownieh/stellar_go/xdr"
+)
+
+// LiquidityPoolStatsProcessor computes the volumes, fees and trade count of
+// the liquidity pools traded with in each ledger and their reserves at the
+// end of the ledger.
+type LiquidityPoolStatsProcessor struct {
+	lpLoader *history.LiquidityPoolLoader
+	batch    history.LiquidityPoolLedgerStatsBatchInsertBuilder
+	// stats are kept in the order the pools are first changed so the rows are
+	// inserted in a deterministic order
+	stats []*liquidityPoolLedgerStats
+	index map[liquidityPoolLedgerKey]*liquidityPoolLedgerStats
+}
+
+type liquidityPoolLedgerKey struct {
+	sequence uint32
+	poolID   string
+}
+
+type liquidityPoolLedgerStats struct {
+	key        liquidityPoolLedgerKey
+	closeTime  time.Time
+	pool       xdr.LiquidityPoolEntry
+	removed    bool
+	tradeCount int32
+	volumeA    big.Int
+	volumeB    big.Int
+	feesA      big.Int
+	feesB      big.Int
+}
+
+func NewLiquidityPoolStatsProcessor(
+	lpLoader *history.LiquidityPoolLoader,
+	batch history.LiquidityPoolLedgerStatsBatchInsertBuilder,
+) *LiquidityPoolStatsProcessor {
+	return &LiquidityPoolStatsProcessor{
+		lpLoader: lpLoader,
+		batch:    batch,
+		index:    map[liquidityPoolLedgerKey]*liquidityPoolLedgerStats{},
+	}
+}
+
+func (p *LiquidityPoolStatsProcessor) ProcessTransaction(lcm xdr.LedgerCloseMeta, transaction ingest.LedgerTransaction) error {
+	sequence := lcm.LedgerSequence()
+	closeTime := time.Unix(int64(lcm.LedgerHeaderHistoryEntry().Header.ScpValue.CloseTime), 0).UTC()
+
+	changes, err := transaction.GetChanges()
+	if err != nil {
+		return err
+	}
+	for _, change := range changes {
+		if change.Type != xdr.LedgerEntryTypeLiquidityPool {
+			continue
+		}
+		if change.Post != nil {
+			pool := change.Post.Data.MustLiquidityPool()
+			stats := p.ledgerStats(sequence, closeTime, pool)
+			stats.pool, stats.removed = pool, false
+		} else if change.Pre != nil {
+			stats := p.ledgerStats(sequence, closeTime, change.Pre.Data.MustLiquidityPool())
+			stats.removed = true
+		}
+	}
+
+	if !transaction.Result.Successful() {
+		return nil
+	}
+	opResults, ok := transaction.Result.OperationResults()
+	if !ok {
+		return errors.New("transaction has no operation results")
+	}
+	for opidx, op := range transaction.Envelope.Operations() {
+		trades, _, _ := operationTrades(op, opResults[opidx])
+		for _, trade := range trades {
+			if trade.Type != xdr.ClaimAtomTypeClaimAtomTypeLiquidityPool {
+				continue
+			}
+			if trade.AmountBought() == 0 && trade.AmountSold() == 0 {
+				continue
+			}
+			stats, ok := p.index[liquidityPoolLedgerKey{
+				sequence: sequence,
+				poolID:   PoolIDToString(trade.MustLiquidityPool().LiquidityPoolId),
+			}]
+			if !ok {
+				return errors.Errorf(
+					"could not find change for liquidity pool %s",
+					PoolIDToString(trade.MustLiquidityPool().LiquidityPoolId),
+				)
+			}
+			stats.addTrade(trade)
+		}
+	}
+	return nil
+}
+
+// ledgerStats returns the stats of the pool in the ledger, creating them if
+// the pool wasn't changed before in the ledger.
+func (p *LiquidityPoolStatsProcessor) ledgerStats(
+	sequence uint32, closeTime time.Time, pool xdr.LiquidityPoolEntry,
+) *liquidityPoolLedgerStats {
+	key := liquidityPoolLedgerKey{sequence: sequence, poolID: PoolIDToString(pool.LiquidityPoolId)}
+	if stats, ok := p.index[key]; ok {
+		return stats
+	}
+	stats := &liquidityPoolLedgerStats{key: key, closeTime: closeTime, pool: pool}
+	p.index[key] = stats
+	p.stats = append(p.stats, stats)
+	p.lpLoader.GetFuture(key.poolID)
+	return stats
+}
+
+// addTrade adds a trade with the pool. The pool sells AssetSold and buys
+// AssetBought, the fee is taken from the amount bought.
+func (s *liquidityPoolLedgerStats) addTrade(trade xdr.ClaimAtom) {
+	params := s.pool.Body.MustConstantProduct().Params
+	bought := big.NewInt(int64(trade.AmountBought()))
+	fee := new(big.Int).Mul(bought, big.NewInt(int64(params.Fee)))
+	fee.Quo(fee, big.NewInt(10000))
+
+	s.tradeCount++
+	if trade.AssetBought().Equals(params.AssetA) {
+		s.volumeA.Add(&s.volumeA, bought)
+		s.feesA.Add(&s.feesA, fee)
+		s.volumeB.Add(&s.volumeB, big.NewInt(int64(trade.AmountSold())))
+	} else {
+		s.volumeB.Add(&s.volumeB, bought)
+		s.feesB.Add(&s.feesB, fee)
+		s.volumeA.Add(&s.volumeA, big.NewInt(int64(trade.AmountSold())))
+	}
+}
+
+func (p *LiquidityPoolStatsProcessor) Flush(ctx context.Context, session db.SessionInterface) error {
+	if len(p.stats) == 0 {
+		return nil
+	}
+
+	for _, stats := range p.stats {
+		row := history.LiquidityPoolLedgerStats{
+			LiquidityPoolID: p.lpLoader.GetFuture(stats.key.poolID),
+			LedgerSequence:  stats.key.sequence,
+			LedgerCloseTime: stats.closeTime,
+			TradeCount:      stats.tradeCount,
+			VolumeA:         stats.volumeA.String(),
+			VolumeB:         stats.volumeB.String(),
+			FeesA:           stats.feesA.String(),
+			FeesB:           stats.feesB.String(),
+		}
+		// the reserves of a removed pool are left empty
+		if !stats.removed {
+			details := stats.pool.Body.MustConstantProduct()
+			row.ReserveA = int64(details.ReserveA)
+			row.ReserveB = int64(details.ReserveB)
+			row.TotalShares = int64(details.TotalPoolShares)
+		}
+		if err := p.batch.Add(row); err != nil {
+			return errors.Wrap(err, "Error adding liquidity pool stats to batch")
+		}
+	}
+
+	if err := p.batch.Exec(ctx, session); err != nil {
+		return errors.Wrap(err, "Error flushing liquidity pool stats batch")
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package processors
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pownieh/stellar_go/ingest"
+	"github.com/pownieh/stellar_go/services/horizon/internal/db2/history"
+	"github.com/pownieh/stellar_go/support/db"
+	"github.com/pownieh/stellar_go/xdr"
+)
+
+func TestLiquidityPoolStatsProcessor(t *testing.T) {
+	ctx := context.Background()
+	usd := xdr.MustNewCreditAsset("USD", "GAXMF43TGZHW3QN3REOUA2U5PW5BTARXGGYJ3JIFHW3YT6QRKRL3CPPU")
+	poolID := xdr.PoolId{1, 2, 3}
+	pool := func(reserveA, reserveB xdr.Int64) *xdr.LedgerEntry {
+		return &xdr.LedgerEntry{
+			Data: xdr.LedgerEntryData{
+				Type: xdr.LedgerEntryTypeLiquidityPool,
+				LiquidityPool: &xdr.LiquidityPoolEntry{
+					LiquidityPoolId: poolID,
+					Body: xdr.LiquidityPoolEntryBody{
+						Type: xdr.LiquidityPoolTypeLiquidityPoolConstantProduct,
+						ConstantProduct: &xdr.LiquidityPoolEntryConstantProduct{
+							Params: xdr.LiquidityPoolConstantProductParameters{
+								AssetA: xdr.MustNewNativeAsset(),
+								AssetB: usd,
+								Fee:    xdr.LiquidityPoolFeeV18,
+							},
+							ReserveA:                 reserveA,
+							ReserveB:                 reserveB,
+							TotalPoolShares:          5000,
+							PoolSharesTrustLineCount: 1,
+						},
+					},
+				},
+			},
+		}
+	}
+
+	operationResults := []xdr.OperationResult{
+		{
+			Code: xdr.OperationResultCodeOpInner,
+			Tr: &xdr.OperationResultTr{
+				Type: xdr.OperationTypePathPaymentStrictSend,
+				PathPaymentStrictSendResult: &xdr.PathPaymentStrictSendResult{
+					Code: xdr.PathPaymentStrictSendResultCodePathPaymentStrictSendSuccess,
+					Success: &xdr.PathPaymentStrictSendResultSuccess{
+						Offers: []xdr.ClaimAtom{
+							{
+								Type: xdr.ClaimAtomTypeClaimAtomTypeLiquidityPool,
+								LiquidityPool: &xdr.ClaimLiquidityAtom{
+									LiquidityPoolId: poolID,
+									AssetSold:       usd,
+									AmountSold:      950,
+									AssetBought:     xdr.MustNewNativeAsset(),
+									AmountBought:    1000,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	tx := ingest.LedgerTransaction{
+		Index: 1,
+		Result: xdr.TransactionResultPair{
+			Result: xdr.TransactionResult{
+				Result: xdr.TransactionResultResult{
+					Code:    xdr.TransactionResultCodeTxSuccess,
+					Results: &operationResults,
+				},
+			},
+		},
+		Envelope: xdr.TransactionEnvelope{
+			Type: xdr.EnvelopeTypeEnvelopeTypeTx,
+			V1: &xdr.TransactionV1Envelope{
+				Tx: xdr.Transaction{
+					Operations: []xdr.Operation{
+						{
+							Body: xdr.OperationBody{
+								Type:                    xdr.OperationTypePathPaymentStrictSend,
+								PathPaymentStrictSendOp: &xdr.PathPaymentStrictSendOp{},
+							},
+						},
+					},
+				},
+			},
+		},
+		UnsafeMeta: xdr.TransactionMeta{
+			V: 2,
+			V2: &xdr.TransactionMetaV2{
+				Operations: []xdr.OperationMeta{
+					{
+						Changes: xdr.LedgerEntryChanges{
+							{
+								Type:  xdr.LedgerEntryChangeTypeLedgerEntryState,
+								State: pool(10000, 20000),
+							},
+							{
+								Type:    xdr.LedgerEntryChangeTypeLedgerEntryUpdated,
+								Updated: pool(11000, 19050),
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	closeTime := time.Date(2023, 11, 14, 22, 0, 0, 0, time.UTC)
+	lcm := xdr.LedgerCloseMeta{
+		V0: &xdr.LedgerCloseMetaV0{
+			LedgerHeader: xdr.LedgerHeaderHistoryEntry{
+				Header: xdr.LedgerHeader{
+					LedgerSeq: 20,
+					ScpValue:  xdr.StellarValue{CloseTime: xdr.TimePoint(closeTime.Unix())},
+				},
+			},
+		},
+	}
+
+	lpLoader := history.NewLiquidityPoolLoader()
+	batch := &history.MockLiquidityPoolLedgerStatsBatchInsertBuilder{}
+	defer batch.AssertExpectations(t)
+	processor := NewLiquidityPoolStatsProcessor(lpLoader, batch)
+	assert.NoError(t, processor.ProcessTransaction(lcm, tx))
+
+	session := &db.MockSession{}
+	batch.On("Add", history.LiquidityPoolLedgerStats{
+		LiquidityPoolID: lpLoader.GetFuture(PoolIDToString(poolID)),
+		LedgerSequence:  20,
+		LedgerCloseTime: closeTime,
+		TradeCount:      1,
+		VolumeA:         "1000",
+		VolumeB:         "950",
+		// the 0.3% fee is taken from the native asset bought by the pool
+		FeesA:       "3",
+		FeesB:       "0",
+		ReserveA:    11000,
+		ReserveB:    19050,
+		TotalShares: 5000,
+	}).Return(nil).Once()
+	batch.On("Exec", ctx, session).Return(nil).Once()
+	assert.NoError(t, processor.Flush(ctx, session))
+}
@@ -0,0 +1,53 @@
+package processors
+
+import (
+	"context"
+	"time"
+
+	"github.com/pownieh/stellar_go/ingest"
+	"github.com/pownieh/stellar_go/services/horizon/internal/db2/history"
+	"github.com/pownieh/stellar_go/support/errors"
+)
+
+// MaxOrderBookSnapshotLevels is the maximum number of price levels stored on
+// each side of an order book snapshot.
+const MaxOrderBookSnapshotLevels = 200
+
+// OrderBookSnapshotsProcessor stores a snapshot of the order book of each
+// configured trading pair. It doesn't use the changes, the snapshots are
+// taken from the offers table so it must be committed after the
+// OffersProcessor.
+type OrderBookSnapshotsProcessor struct {
+	snapshotsQ history.QOrderBookSnapshots
+	pairs      []history.OrderBookPair
+	sequence   uint32
+	closedAt   time.Time
+}
+
+func NewOrderBookSnapshotsProcessor(
+	snapshotsQ history.QOrderBookSnapshots,
+	pairs []history.OrderBookPair,
+	sequence uint32,
+	closedAt time.Time,
+) *OrderBookSnapshotsProcessor {
+	return &OrderBookSnapshotsProcessor{
+		snapshotsQ: snapshotsQ,
+		pairs:      pairs,
+		sequence:   sequence,
+		closedAt:   closedAt,
+	}
+}
+
+func (p *OrderBookSnapshotsProcessor) ProcessChange(ctx context.Context, change ingest.Change) error {
+	return nil
+}
+
+func (p *OrderBookSnapshotsProcessor) Commit(ctx context.Context) error {
+	for _, pair := range p.pairs {
+		err := p.snapshotsQ.InsertOrderBookSnapshot(ctx, p.sequence, p.closedAt, pair, MaxOrderBookSnapshotLevels)
+		if err != nil {
+			return errors.Wrapf(err, "error inserting order book snapshot of %s", pair)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package processors
+
+import (
+	"context"
+
+	"github.com/pownieh/stellar_go/ingest"
+	"github.com/pownieh/stellar_go/services/horizon/internal/db2/history"
+	"github.com/pownieh/stellar_go/support/errors"
+	"github.com/pownieh/stellar_go/xdr"
+)
+
+// StateEntriesLoader returns the ledger entries with the given keys as
+// reconstructed from the rows of the state tables. The keys without rows are
+// omitted.
+type StateEntriesLoader func(ctx context.Context, keys []xdr.LedgerKey) ([]xdr.LedgerEntry, error)
+
+// StateChecksumsProcessor maintains the rolling checksums of the ledger
+// entries of the state tables, which allow the state verifier to skip
+// reading the entry types whose checksum matches the checkpoint.
+//
+// The checksums are computed from the rows written to the state tables: once
+// the other processors have committed the changes of a ledger, the entries of
+// the changed keys are reconstructed from their rows and added to the
+// checksums while the entries preceding the changes are subtracted. A row
+// which does not match the entry of the ledger therefore makes the checksum
+// differ from the checkpoint until the state verifier reads the state tables.
+type StateChecksumsProcessor struct {
+	checksumsQ  history.QStateChecksums
+	loadEntries StateEntriesLoader
+	// fromCheckpoint is true when the changes are the entries of a
+	// checkpoint, in which case the checksums are reset.
+	fromCheckpoint bool
+	cache          *ingest.ChangeCompactor
+}
+
+func NewStateChecksumsProcessor(
+	checksumsQ history.QStateChecksums,
+	loadEntries StateEntriesLoader,
+	fromCheckpoint bool,
+) *StateChecksumsProcessor {
+	return &StateChecksumsProcessor{
+		checksumsQ:     checksumsQ,
+		loadEntries:    loadEntries,
+		fromCheckpoint: fromCheckpoint,
+		cache:          ingest.NewChangeCompactor(),
+	}
+}
+
+func (p *StateChecksumsProcessor) ProcessChange(ctx context.Context, change ingest.Change) error {
+	if p.fromCheckpoint || !isStateChecksumEntryType(change.Type) {
+		return nil
+	}
+	return p.cache.AddChange(change)
+}
+
+func (p *StateChecksumsProcessor) Commit(ctx context.Context) error {
+	defer func() {
+		p.cache = ingest.NewChangeCompactor()
+		p.fromCheckpoint = false
+	}()
+
+	if p.fromCheckpoint {
+		// The checksums of the rows written from the checkpoint are unknown
+		// until the state verifier reads the state tables.
+		if err := p.checksumsQ.UpdateStateChecksums(ctx, history.StateChecksums{}); err != nil {
+			return errors.Wrap(err, "error updating state checksums")
+		}
+		if err := p.checksumsQ.UpdateStateChecksumOffsets(ctx, history.StateChecksums{}); err != nil {
+			return errors.Wrap(err, "error updating state checksum offsets")
+		}
+		return nil
+	}
+
+	changes := p.cache.GetChanges()
+	if len(changes) == 0 {
+		return nil
+	}
+
+	deltas := history.StateChecksums{}
+	keys := make([]xdr.LedgerKey, 0, len(changes))
+	for _, change := range changes {
+		entry := change.Post
+		if change.Pre != nil {
+			entry = change.Pre
+			checksum, err := history.NewStateChecksum(*change.Pre)
+			if err != nil {
+				return err
+			}
+			deltas[change.Type] = deltas[change.Type].Sub(checksum)
+		}
+		key, err := entry.LedgerKey()
+		if err != nil {
+			return errors.Wrap(err, "error getting ledger key")
+		}
+		keys = append(keys, key)
+	}
+
+	entries, err := p.loadEntries(ctx, keys)
+	if _, ok := errors.Cause(err).(ingest.StateError); ok {
+		// The rows can't be converted to ledger entries. Ingestion goes on
+		// with unknown checksums so the state verifier reads the state
+		// tables and reports the error.
+		if err = p.checksumsQ.UpdateStateChecksumOffsets(ctx, history.StateChecksums{}); err != nil {
+			return errors.Wrap(err, "error updating state checksum offsets")
+		}
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "error loading ledger entries from the state tables")
+	}
+	for _, entry := range entries {
+		checksum, err := history.NewStateChecksum(entry)
+		if err != nil {
+			return err
+		}
+		deltas[entry.Data.Type] = deltas[entry.Data.Type].Add(checksum)
+	}
+
+	checksums, err := p.checksumsQ.GetStateChecksums(ctx)
+	if err != nil {
+		return errors.Wrap(err, "error getting state checksums")
+	}
+	for entryType, delta := range deltas {
+		checksums[entryType] = checksums[entryType].Add(delta)
+	}
+	if err = p.checksumsQ.UpdateStateChecksums(ctx, checksums); err != nil {
+		return errors.Wrap(err, "error updating state checksums")
+	}
+	return nil
+}
+
+func isStateChecksumEntryType(entryType xdr.LedgerEntryType) bool {
+	for _, t := range history.StateChecksumEntryTypes {
+		if t == entryType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+package processors
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/pownieh/stellar_go/ingest"
+	"github.com/pownieh/stellar_go/services/horizon/internal/db2/history"
+	"github.com/pownieh/stellar_go/support/errors"
+	"github.com/pownieh/stellar_go/xdr"
+)
+
+func checksumTestAccount(balance xdr.Int64) *xdr.LedgerEntry {
+	return &xdr.LedgerEntry{
+		LastModifiedLedgerSeq: 10,
+		Data: xdr.LedgerEntryData{
+			Type: xdr.LedgerEntryTypeAccount,
+			Account: &xdr.AccountEntry{
+				AccountId:  xdr.MustAddress("GAOQJGUAB7NI7K7I62ORBXMN3J4SSWQUQ7FOEPSDJ322W2HMCNWPHXFB"),
+				Balance:    balance,
+				Thresholds: [4]byte{1, 1, 1, 1},
+			},
+		},
+	}
+}
+
+func mustStateChecksum(t *testing.T, entry *xdr.LedgerEntry) history.StateChecksum {
+	checksum, err := history.NewStateChecksum(*entry)
+	assert.NoError(t, err)
+	return checksum
+}
+
+func TestStateChecksumIsNormalized(t *testing.T) {
+	entry := checksumTestAccount(100)
+	normalized := checksumTestAccount(100)
+	normalized.Normalize()
+
+	assert.Equal(t, mustStateChecksum(t, normalized), mustStateChecksum(t, entry))
+	// the entry itself is not modified
+	assert.Equal(t, xdr.LedgerEntryExt{}, entry.Ext)
+
+	var parsed history.StateChecksum
+	text, err := mustStateChecksum(t, entry).MarshalText()
+	assert.NoError(t, err)
+	assert.NoError(t, parsed.UnmarshalText(text))
+	assert.Equal(t, mustStateChecksum(t, entry), parsed)
+}
+
+func TestStateChecksumsProcessorFromCheckpoint(t *testing.T) {
+	ctx := context.Background()
+	q := &history.MockQStateChecksums{}
+	processor := NewStateChecksumsProcessor(q, nil, true)
+
+	assert.NoError(t, processor.ProcessChange(ctx, ingest.Change{
+		Type: xdr.LedgerEntryTypeAccount,
+		Post: checksumTestAccount(100),
+	}))
+
+	// the checksums are unknown until the state tables are verified
+	q.On("UpdateStateChecksums", ctx, history.StateChecksums{}).Return(nil).Once()
+	q.On("UpdateStateChecksumOffsets", ctx, history.StateChecksums{}).Return(nil).Once()
+
+	assert.NoError(t, processor.Commit(ctx))
+	q.AssertExpectations(t)
+}
+
+func TestStateChecksumsProcessorFromLedger(t *testing.T) {
+	ctx := context.Background()
+	q := &history.MockQStateChecksums{}
+
+	created := checksumTestAccount(100)
+	updated := checksumTestAccount(200)
+	removed := checksumTestAccount(300)
+	removed.Data.Account.AccountId = xdr.MustAddress("GCXKG6RN4ONIEPCMNFB732A436Z5PNDSRLGWK7GBLCMQLIFO4S7EYWVU")
+	// the row written for the account differs from the ledger entry
+	written := checksumTestAccount(201)
+
+	var loadedKeys []xdr.LedgerKey
+	processor := NewStateChecksumsProcessor(q, func(ctx context.Context, keys []xdr.LedgerKey) ([]xdr.LedgerEntry, error) {
+		loadedKeys = keys
+		return []xdr.LedgerEntry{*written}, nil
+	}, false)
+
+	changes := []ingest.Change{
+		{Type: xdr.LedgerEntryTypeAccount, Post: created},
+		{Type: xdr.LedgerEntryTypeAccount, Pre: created, Post: updated},
+		{Type: xdr.LedgerEntryTypeAccount, Pre: removed},
+		// entries which are not stored in the state tables are ignored
+		{
+			Type: xdr.LedgerEntryTypeContractCode,
+			Post: &xdr.LedgerEntry{
+				Data: xdr.LedgerEntryData{
+					Type:         xdr.LedgerEntryTypeContractCode,
+					ContractCode: &xdr.ContractCodeEntry{},
+				},
+			},
+		},
+	}
+	for _, change := range changes {
+		assert.NoError(t, processor.ProcessChange(ctx, change))
+	}
+
+	existing := checksumTestAccount(1)
+	q.On("GetStateChecksums", ctx).Return(history.StateChecksums{
+		xdr.LedgerEntryTypeAccount: mustStateChecksum(t, existing).Add(mustStateChecksum(t, removed)),
+	}, nil).Once()
+	q.On("UpdateStateChecksums", ctx, history.StateChecksums{
+		xdr.LedgerEntryTypeAccount: mustStateChecksum(t, existing).Add(mustStateChecksum(t, written)),
+	}).Return(nil).Once()
+
+	assert.NoError(t, processor.Commit(ctx))
+	q.AssertExpectations(t)
+	assert.Len(t, loadedKeys, 2)
+
+	// nothing is updated when there are no changes
+	assert.NoError(t, processor.Commit(ctx))
+	q.AssertNotCalled(t, "UpdateStateChecksumOffsets", mock.Anything, mock.Anything)
+	q.AssertExpectations(t)
+}
+
+func TestStateChecksumsProcessorInvalidRows(t *testing.T) {
+	ctx := context.Background()
+	q := &history.MockQStateChecksums{}
+	processor := NewStateChecksumsProcessor(q, func(ctx context.Context, keys []xdr.LedgerKey) ([]xdr.LedgerEntry, error) {
+		return nil, ingest.NewStateError(errors.New("invalid row"))
+	}, false)
+
+	assert.NoError(t, processor.ProcessChange(ctx, ingest.Change{
+		Type: xdr.LedgerEntryTypeAccount,
+		Post: checksumTestAccount(100),
+	}))
+
+	// the checksums become unknown so the state verifier reads the state
+	// tables
+	q.On("UpdateStateChecksumOffsets", ctx, history.StateChecksums{}).Return(nil).Once()
+
+	assert.NoError(t, processor.Commit(ctx))
+	q.AssertExpectations(t)
+}
@@ -312,6 +312,53 @@ type ingestTrade struct {
 	soldAsset       xdr.Asset
 }
 
+// operationTrades returns the offers and liquidity pools claimed by a
+// successful operation and the offer it created or updated, if any.
+func operationTrades(op xdr.Operation, opResult xdr.OperationResult) (
+	trades []xdr.ClaimAtom, buyOffer xdr.OfferEntry, buyOfferExists bool,
+) {
+	switch op.Body.Type {
+	case xdr.OperationTypePathPaymentStrictReceive:
+		trades = opResult.MustTr().MustPathPaymentStrictReceiveResult().
+			MustSuccess().
+			Offers
+
+	case xdr.OperationTypePathPaymentStrictSend:
+		trades = opResult.MustTr().
+			MustPathPaymentStrictSendResult().
+			MustSuccess().
+			Offers
+
+	case xdr.OperationTypeManageBuyOffer:
+		manageOfferResult := opResult.MustTr().MustManageBuyOfferResult().
+			MustSuccess()
+		trades = manageOfferResult.OffersClaimed
+		buyOffer, buyOfferExists = manageOfferResult.Offer.GetOffer()
+
+	case xdr.OperationTypeManageSellOffer:
+		manageOfferResult := opResult.MustTr().MustManageSellOfferResult().
+			MustSuccess()
+		trades = manageOfferResult.OffersClaimed
+		buyOffer, buyOfferExists = manageOfferResult.Offer.GetOffer()
+
+	case xdr.OperationTypeCreatePassiveSellOffer:
+		result := opResult.MustTr()
+
+		// KNOWN ISSUE:  stellar-core creates results for CreatePassiveOffer operations
+		// with the wrong result arm set.
+		if result.Type == xdr.OperationTypeManageSellOffer {
+			manageOfferResult := result.MustManageSellOfferResult().MustSuccess()
+			trades = manageOfferResult.OffersClaimed
+			buyOffer, buyOfferExists = manageOfferResult.Offer.GetOffer()
+		} else {
+			passiveOfferResult := result.MustCreatePassiveSellOfferResult().MustSuccess()
+			trades = passiveOfferResult.OffersClaimed
+			buyOffer, buyOfferExists = passiveOfferResult.Offer.GetOffer()
+		}
+	}
+	return trades, buyOffer, buyOfferExists
+}
+
 func (p *TradeProcessor) extractTrades(
 	ledger xdr.LedgerHeaderHistoryEntry,
 	transaction ingest.LedgerTransaction,
@@ -325,49 +372,7 @@ func (p *TradeProcessor) extractTrades(
 		return result, errors.New("transaction has no operation results")
 	}
 	for opidx, op := range transaction.Envelope.Operations() {
-		var trades []xdr.ClaimAtom
-		var buyOfferExists bool
-		var buyOffer xdr.OfferEntry
-
-		switch op.Body.Type {
-		case xdr.OperationTypePathPaymentStrictReceive:
-			trades = opResults[opidx].MustTr().MustPathPaymentStrictReceiveResult().
-				MustSuccess().
-				Offers
-
-		case xdr.OperationTypePathPaymentStrictSend:
-			trades = opResults[opidx].MustTr().
-				MustPathPaymentStrictSendResult().
-				MustSuccess().
-				Offers
-
-		case xdr.OperationTypeManageBuyOffer:
-			manageOfferResult := opResults[opidx].MustTr().MustManageBuyOfferResult().
-				MustSuccess()
-			trades = manageOfferResult.OffersClaimed
-			buyOffer, buyOfferExists = manageOfferResult.Offer.GetOffer()
-
-		case xdr.OperationTypeManageSellOffer:
-			manageOfferResult := opResults[opidx].MustTr().MustManageSellOfferResult().
-				MustSuccess()
-			trades = manageOfferResult.OffersClaimed
-			buyOffer, buyOfferExists = manageOfferResult.Offer.GetOffer()
-
-		case xdr.OperationTypeCreatePassiveSellOffer:
-			result := opResults[opidx].MustTr()
-
-			// KNOWN ISSUE:  stellar-core creates results for CreatePassiveOffer operations
-			// with the wrong result arm set.
-			if result.Type == xdr.OperationTypeManageSellOffer {
-				manageOfferResult := result.MustManageSellOfferResult().MustSuccess()
-				trades = manageOfferResult.OffersClaimed
-				buyOffer, buyOfferExists = manageOfferResult.Offer.GetOffer()
-			} else {
-				passiveOfferResult := result.MustCreatePassiveSellOfferResult().MustSuccess()
-				trades = passiveOfferResult.OffersClaimed
-				buyOffer, buyOfferExists = passiveOfferResult.Offer.GetOffer()
-			}
-		}
+		trades, buyOffer, buyOfferExists := operationTrades(op, opResults[opidx])
 
 		opID := toid.New(
 			int32(ledger.Header.LedgerSeq), int32(transaction.Index), int32(opidx+1),
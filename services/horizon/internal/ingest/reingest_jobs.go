@@ -0,0 +1,253 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pownieh/stellar_go/services/horizon/internal/db2/history"
+	"github.com/pownieh/stellar_go/support/errors"
+	logpkg "github.com/pownieh/stellar_go/support/log"
+)
+
+const (
+	defaultReingestJobLease        = 5 * time.Minute
+	defaultReingestJobMaxAttempts  = 3
+	defaultReingestJobPollInterval = 30 * time.Second
+)
+
+// ReingestJobsOptions configures how ParallelSystems works on the
+// reingestion jobs stored in the Horizon database.
+type ReingestJobsOptions struct {
+	// Owner identifies this process in job leases. It defaults to the
+	// hostname and pid of the process.
+	Owner string
+	// LeaseDuration is the time after which a job claimed by a worker which
+	// stopped renewing its lease, e.g. because it crashed, can be claimed by
+	// another worker.
+	LeaseDuration time.Duration
+	// MaxAttempts is the number of times a job is attempted before it is
+	// marked as failed.
+	MaxAttempts int
+	// PollInterval is the interval at which idle workers check for jobs when
+	// all the remaining jobs are leased by other workers.
+	PollInterval time.Duration
+}
+
+func (o *ReingestJobsOptions) setDefaults() {
+	if o.Owner == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			hostname = "unknown"
+		}
+		o.Owner = fmt.Sprintf("%s:%d", hostname, os.Getpid())
+	}
+	if o.LeaseDuration == 0 {
+		o.LeaseDuration = defaultReingestJobLease
+	}
+	if o.MaxAttempts == 0 {
+		o.MaxAttempts = defaultReingestJobMaxAttempts
+	}
+	if o.PollInterval == 0 {
+		o.PollInterval = defaultReingestJobPollInterval
+	}
+}
+
+// EnqueueReingestJobs splits the ledger ranges into batches, sized like in
+// ReingestRange, and stores them as jobs in the Horizon database so that they
+// can be processed by reingestion workers running on any machine. Returns the
+// number of jobs added.
+func (ps *ParallelSystems) EnqueueReingestJobs(ledgerRanges []history.LedgerRange, batchSizeSuggestion uint32) (int64, error) {
+	if err := validateRanges(ledgerRanges); err != nil {
+		return 0, err
+	}
+
+	batchSize := calculateParallelLedgerBatchSize(totalRangeSize(ledgerRanges), batchSizeSuggestion, ps.workerCount)
+	var batches []history.LedgerRange
+	for _, cur := range ledgerRanges {
+		for from := cur.StartSequence; from <= cur.EndSequence; from += batchSize {
+			to := from + (batchSize - 1)
+			if to > cur.EndSequence {
+				to = cur.EndSequence
+			}
+			batches = append(batches, history.LedgerRange{StartSequence: from, EndSequence: to})
+		}
+	}
+
+	return ps.reingestJobsQ().EnqueueReingestJobs(context.Background(), batches)
+}
+
+// ReingestJobs runs the configured number of workers, which claim the
+// reingestion jobs stored in the Horizon database and reingest their ranges,
+// until all the jobs are done or failed. Jobs which fail are retried, by any
+// worker, up to options.MaxAttempts times. An error is returned if a worker
+// stopped because of an error, or if there are failed or unfinished jobs once
+// all the workers stopped.
+func (ps *ParallelSystems) ReingestJobs(options ReingestJobsOptions) error {
+	options.setDefaults()
+	defer ps.Shutdown()
+
+	q := ps.reingestJobsQ()
+	var (
+		wg           sync.WaitGroup
+		mu           sync.Mutex
+		workerErrors []error
+	)
+	for i := uint(0); i < ps.workerCount; i++ {
+		s, err := ps.systemFactory(ps.config)
+		if err != nil {
+			return errors.Wrap(err, "error creating new system")
+		}
+		owner := fmt.Sprintf("%s/%d", options.Owner, i)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := runReingestJobsWorker(s, q, owner, options); err != nil {
+				log.WithError(err).WithField("owner", owner).Error("error in reingest jobs worker")
+				mu.Lock()
+				workerErrors = append(workerErrors, err)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(workerErrors) > 0 {
+		return errors.Wrapf(
+			workerErrors[0], "%d of %d reingest jobs workers stopped with an error, first error",
+			len(workerErrors), ps.workerCount,
+		)
+	}
+
+	jobs, err := q.GetReingestJobs(context.Background())
+	if err != nil {
+		return err
+	}
+	var failed, unfinished []history.ReingestJob
+	for _, job := range jobs {
+		switch job.Status {
+		case history.ReingestJobFailed:
+			failed = append(failed, job)
+		case history.ReingestJobPending, history.ReingestJobRunning:
+			unfinished = append(unfinished, job)
+		}
+	}
+	if len(failed) > 0 {
+		return errors.Errorf(
+			"%d reingest jobs failed, first failed range: [%d, %d]: %s",
+			len(failed), failed[0].FromLedger, failed[0].ToLedger, failed[0].LastError.String,
+		)
+	}
+	if len(unfinished) > 0 {
+		return errors.Errorf(
+			"%d reingest jobs are unfinished, first unfinished range: [%d, %d] (%s)",
+			len(unfinished), unfinished[0].FromLedger, unfinished[0].ToLedger, unfinished[0].Status,
+		)
+	}
+	return nil
+}
+
+func (ps *ParallelSystems) reingestJobsQ() history.QReingestJobs {
+	if ps.jobsQ == nil {
+		ps.jobsQ = &history.Q{SessionInterface: ps.config.HistorySession.Clone()}
+	}
+	return ps.jobsQ
+}
+
+func runReingestJobsWorker(s System, q history.QReingestJobs, owner string, options ReingestJobsOptions) error {
+	ctx := context.Background()
+	for {
+		job, ok, err := q.ClaimReingestJob(ctx, owner, options.LeaseDuration, options.MaxAttempts)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			remaining, err := hasUnfinishedReingestJobs(ctx, q)
+			if err != nil {
+				return err
+			}
+			if !remaining {
+				return nil
+			}
+			// the remaining jobs are leased by other workers, wait in case
+			// one of them stops renewing its lease
+			time.Sleep(options.PollInterval)
+			continue
+		}
+
+		logger := log.WithFields(logpkg.F{
+			"from":    job.FromLedger,
+			"to":      job.ToLedger,
+			"attempt": job.Attempts,
+			"owner":   owner,
+		})
+		logger.Info("claimed reingest job")
+
+		stop := make(chan struct{})
+		heartbeatDone := make(chan struct{})
+		go func() {
+			defer close(heartbeatDone)
+			renewReingestJobLease(q, job, owner, options.LeaseDuration, stop, logger)
+		}()
+		err = s.ReingestRange([]history.LedgerRange{job.LedgerRange()}, false)
+		close(stop)
+		<-heartbeatDone
+
+		if err != nil {
+			logger.WithError(err).Error("reingest job failed")
+			released, failErr := q.FailReingestJob(ctx, job.ID, owner, err.Error(), options.MaxAttempts)
+			if failErr != nil {
+				return failErr
+			}
+			if !released {
+				logger.Warn("lost reingest job lease, the job was claimed by another worker")
+			}
+			continue
+		}
+		completed, err := q.CompleteReingestJob(ctx, job.ID, owner)
+		if err != nil {
+			return err
+		}
+		if !completed {
+			// the range was reingested but the lease expired in the meantime,
+			// the worker which claimed the job will reingest it again
+			logger.Warn("lost reingest job lease, the job was claimed by another worker")
+			continue
+		}
+		logger.Info("successfully reingested range")
+	}
+}
+
+// renewReingestJobLease renews the lease of job until stop is closed.
+func renewReingestJobLease(q history.QReingestJobs, job history.ReingestJob, owner string, lease time.Duration, stop <-chan struct{}, logger *logpkg.Entry) {
+	ticker := time.NewTicker(lease / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			renewed, err := q.RenewReingestJobLease(context.Background(), job.ID, owner, lease)
+			if err != nil {
+				logger.WithError(err).Warn("could not renew reingest job lease")
+			} else if !renewed {
+				logger.Warn("lost reingest job lease")
+			}
+		}
+	}
+}
+
+func hasUnfinishedReingestJobs(ctx context.Context, q history.QReingestJobs) (bool, error) {
+	jobs, err := q.GetReingestJobs(ctx)
+	if err != nil {
+		return false, err
+	}
+	for _, job := range jobs {
+		if job.Status == history.ReingestJobPending || job.Status == history.ReingestJobRunning {
+			return true, nil
+		}
+	}
+	return false, nil
+}
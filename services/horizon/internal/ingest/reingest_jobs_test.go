@@ -0,0 +1,340 @@
+package ingest
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/guregu/null"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pownieh/stellar_go/services/horizon/internal/db2/history"
+	"github.com/pownieh/stellar_go/support/errors"
+)
+
+// memoryReingestJobsQ is an in memory implementation of the job queue.
+type memoryReingestJobsQ struct {
+	mu   sync.Mutex
+	jobs []history.ReingestJob
+}
+
+func (q *memoryReingestJobsQ) EnqueueReingestJobs(ctx context.Context, ledgerRanges []history.LedgerRange) (int64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var added int64
+	for _, ledgerRange := range ledgerRanges {
+		found := false
+		for i := range q.jobs {
+			if q.jobs[i].LedgerRange() == ledgerRange {
+				found = true
+				if q.jobs[i].Status == history.ReingestJobFailed {
+					q.jobs[i].Status = history.ReingestJobPending
+					q.jobs[i].Attempts = 0
+					added++
+				}
+			}
+		}
+		if !found {
+			q.jobs = append(q.jobs, history.ReingestJob{
+				ID:         int64(len(q.jobs) + 1),
+				FromLedger: ledgerRange.StartSequence,
+				ToLedger:   ledgerRange.EndSequence,
+				Status:     history.ReingestJobPending,
+			})
+			added++
+		}
+	}
+	return added, nil
+}
+
+func (q *memoryReingestJobsQ) ClaimReingestJob(ctx context.Context, owner string, lease time.Duration, maxAttempts int) (history.ReingestJob, bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i := range q.jobs {
+		job := &q.jobs[i]
+		expired := job.Status == history.ReingestJobRunning && job.LeaseExpiresAt.Time.Before(time.Now())
+		if expired && job.Attempts >= maxAttempts {
+			job.Status = history.ReingestJobFailed
+			job.LeaseOwner = null.String{}
+			job.LastError = null.StringFrom("lease expired")
+			continue
+		}
+		if job.Status == history.ReingestJobPending || expired {
+			job.Status = history.ReingestJobRunning
+			job.Attempts++
+			job.LeaseOwner = null.StringFrom(owner)
+			job.LeaseExpiresAt = null.TimeFrom(time.Now().Add(lease))
+			return *job, true, nil
+		}
+	}
+	return history.ReingestJob{}, false, nil
+}
+
+func (q *memoryReingestJobsQ) RenewReingestJobLease(ctx context.Context, id int64, owner string, lease time.Duration) (bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job := &q.jobs[id-1]
+	if job.LeaseOwner.String != owner || job.Status != history.ReingestJobRunning {
+		return false, nil
+	}
+	job.LeaseExpiresAt = null.TimeFrom(time.Now().Add(lease))
+	return true, nil
+}
+
+func (q *memoryReingestJobsQ) CompleteReingestJob(ctx context.Context, id int64, owner string) (bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job := &q.jobs[id-1]
+	if job.LeaseOwner.String != owner {
+		return false, nil
+	}
+	job.Status = history.ReingestJobDone
+	job.LeaseOwner = null.String{}
+	return true, nil
+}
+
+func (q *memoryReingestJobsQ) FailReingestJob(ctx context.Context, id int64, owner string, reason string, maxAttempts int) (bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job := &q.jobs[id-1]
+	if job.LeaseOwner.String != owner {
+		return false, nil
+	}
+	job.Status = history.ReingestJobPending
+	if job.Attempts >= maxAttempts {
+		job.Status = history.ReingestJobFailed
+	}
+	job.LeaseOwner = null.String{}
+	job.LastError = null.StringFrom(reason)
+	return true, nil
+}
+
+func (q *memoryReingestJobsQ) GetReingestJobs(ctx context.Context) ([]history.ReingestJob, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]history.ReingestJob{}, q.jobs...), nil
+}
+
+func TestEnqueueReingestJobs(t *testing.T) {
+	system, err := newParallelSystems(Config{}, 1, nil)
+	require.NoError(t, err)
+	q := &memoryReingestJobsQ{}
+	system.jobsQ = q
+
+	added, err := system.EnqueueReingestJobs([]history.LedgerRange{{1, 600}, {1000, 1064}}, 256)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), added)
+
+	jobs, err := q.GetReingestJobs(context.Background())
+	require.NoError(t, err)
+	var ranges []history.LedgerRange
+	for _, job := range jobs {
+		ranges = append(ranges, job.LedgerRange())
+	}
+	assert.Equal(t, []history.LedgerRange{
+		{StartSequence: 1, EndSequence: 256}, {StartSequence: 257, EndSequence: 512},
+		{StartSequence: 513, EndSequence: 600}, {StartSequence: 1000, EndSequence: 1064},
+	}, ranges)
+
+	// enqueuing the same ranges again is a no-op
+	added, err = system.EnqueueReingestJobs([]history.LedgerRange{{1, 600}, {1000, 1064}}, 256)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), added)
+}
+
+func TestReingestJobs(t *testing.T) {
+	var (
+		rangesCalled []history.LedgerRange
+		m            sync.Mutex
+	)
+	result := &mockSystem{}
+	result.On("ReingestRange", mock.AnythingOfType("[]history.LedgerRange"), false).Run(
+		func(args mock.Arguments) {
+			m.Lock()
+			defer m.Unlock()
+			rangesCalled = append(rangesCalled, args.Get(0).([]history.LedgerRange)...)
+		}).Return(error(nil))
+	factory := func(c Config) (System, error) {
+		return result, nil
+	}
+	system, err := newParallelSystems(Config{}, 3, factory)
+	require.NoError(t, err)
+	q := &memoryReingestJobsQ{}
+	system.jobsQ = q
+
+	_, err = system.EnqueueReingestJobs([]history.LedgerRange{{1, 2050}}, 256)
+	require.NoError(t, err)
+	require.NoError(t, system.ReingestJobs(ReingestJobsOptions{}))
+
+	sort.Slice(rangesCalled, func(i, j int) bool {
+		return rangesCalled[i].StartSequence < rangesCalled[j].StartSequence
+	})
+	assert.Len(t, rangesCalled, 9)
+	assert.Equal(t, history.LedgerRange{StartSequence: 2049, EndSequence: 2050}, rangesCalled[8])
+	for _, job := range q.jobs {
+		assert.Equal(t, history.ReingestJobDone, job.Status)
+		assert.Equal(t, 1, job.Attempts)
+	}
+}
+
+func TestReingestJobsRetriesFailedJobs(t *testing.T) {
+	result := &mockSystem{}
+	result.On("ReingestRange", []history.LedgerRange{{257, 512}}, false).Return(errors.New("failed because of foo")).Once()
+	result.On("ReingestRange", []history.LedgerRange{{513, 768}}, false).Return(errors.New("failed because of bar"))
+	result.On("ReingestRange", mock.AnythingOfType("[]history.LedgerRange"), false).Return(error(nil))
+	factory := func(c Config) (System, error) {
+		return result, nil
+	}
+	system, err := newParallelSystems(Config{}, 2, factory)
+	require.NoError(t, err)
+	q := &memoryReingestJobsQ{}
+	system.jobsQ = q
+
+	_, err = system.EnqueueReingestJobs([]history.LedgerRange{{1, 1024}}, 256)
+	require.NoError(t, err)
+	err = system.ReingestJobs(ReingestJobsOptions{MaxAttempts: 2})
+	assert.EqualError(t, err, "1 reingest jobs failed, first failed range: [513, 768]: failed because of bar")
+
+	jobs, err := q.GetReingestJobs(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, history.ReingestJobDone, jobs[1].Status)
+	assert.Equal(t, 2, jobs[1].Attempts)
+	assert.Equal(t, history.ReingestJobFailed, jobs[2].Status)
+	assert.Equal(t, 2, jobs[2].Attempts)
+	assert.Equal(t, history.ReingestJobDone, jobs[3].Status)
+}
+
+func TestReingestJobsReclaimsExpiredLeases(t *testing.T) {
+	result := &mockSystem{}
+	result.On("ReingestRange", mock.AnythingOfType("[]history.LedgerRange"), false).Return(error(nil))
+	factory := func(c Config) (System, error) {
+		return result, nil
+	}
+	system, err := newParallelSystems(Config{}, 1, factory)
+	require.NoError(t, err)
+	q := &memoryReingestJobsQ{}
+	system.jobsQ = q
+
+	_, err = system.EnqueueReingestJobs([]history.LedgerRange{{1, 64}}, 64)
+	require.NoError(t, err)
+	// a worker which crashed after claiming the job
+	_, ok, err := q.ClaimReingestJob(context.Background(), "crashed", 10*time.Millisecond, 3)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	require.NoError(t, system.ReingestJobs(ReingestJobsOptions{PollInterval: 5 * time.Millisecond}))
+	assert.Equal(t, history.ReingestJobDone, q.jobs[0].Status)
+	assert.Equal(t, 2, q.jobs[0].Attempts)
+}
+
+func TestReingestJobsFailsExhaustedExpiredLeases(t *testing.T) {
+	result := &mockSystem{}
+	factory := func(c Config) (System, error) {
+		return result, nil
+	}
+	system, err := newParallelSystems(Config{}, 1, factory)
+	require.NoError(t, err)
+	q := &memoryReingestJobsQ{}
+	system.jobsQ = q
+
+	_, err = system.EnqueueReingestJobs([]history.LedgerRange{{1, 64}}, 64)
+	require.NoError(t, err)
+	// a range which crashed the workers in all of its attempts
+	for i := 0; i < 2; i++ {
+		q.jobs[0].LeaseExpiresAt = null.TimeFrom(time.Now().Add(-time.Minute))
+		_, ok, claimErr := q.ClaimReingestJob(context.Background(), "crashed", -time.Minute, 2)
+		require.NoError(t, claimErr)
+		require.True(t, ok)
+	}
+
+	err = system.ReingestJobs(ReingestJobsOptions{MaxAttempts: 2, PollInterval: 5 * time.Millisecond})
+	assert.EqualError(t, err, "1 reingest jobs failed, first failed range: [1, 64]: lease expired")
+	assert.Equal(t, history.ReingestJobFailed, q.jobs[0].Status)
+	result.AssertNotCalled(t, "ReingestRange", mock.Anything, mock.Anything)
+}
+
+// failingReingestJobsQ is a job queue which cannot claim jobs.
+type failingReingestJobsQ struct {
+	memoryReingestJobsQ
+}
+
+func (q *failingReingestJobsQ) ClaimReingestJob(ctx context.Context, owner string, lease time.Duration, maxAttempts int) (history.ReingestJob, bool, error) {
+	return history.ReingestJob{}, false, errors.New("connection refused")
+}
+
+func TestReingestJobsReturnsWorkerErrors(t *testing.T) {
+	factory := func(c Config) (System, error) {
+		return &mockSystem{}, nil
+	}
+	system, err := newParallelSystems(Config{}, 2, factory)
+	require.NoError(t, err)
+	q := &failingReingestJobsQ{}
+	system.jobsQ = q
+
+	_, err = system.EnqueueReingestJobs([]history.LedgerRange{{1, 64}}, 64)
+	require.NoError(t, err)
+	err = system.ReingestJobs(ReingestJobsOptions{})
+	assert.EqualError(t, err, "2 of 2 reingest jobs workers stopped with an error, first error: connection refused")
+}
+
+// stolenLeaseReingestJobsQ is a job queue in which the lease of every job
+// expires, and the job is completed by another worker, before the worker
+// holding the lease completes it.
+type stolenLeaseReingestJobsQ struct {
+	memoryReingestJobsQ
+}
+
+func (q *stolenLeaseReingestJobsQ) CompleteReingestJob(ctx context.Context, id int64, owner string) (bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job := &q.jobs[id-1]
+	job.Status = history.ReingestJobDone
+	job.LeaseOwner = null.StringFrom("other")
+	return false, nil
+}
+
+func TestReingestJobsContinuesAfterLostLease(t *testing.T) {
+	result := &mockSystem{}
+	result.On("ReingestRange", mock.AnythingOfType("[]history.LedgerRange"), false).Return(error(nil))
+	factory := func(c Config) (System, error) {
+		return result, nil
+	}
+	system, err := newParallelSystems(Config{}, 1, factory)
+	require.NoError(t, err)
+	q := &stolenLeaseReingestJobsQ{}
+	system.jobsQ = q
+
+	_, err = system.EnqueueReingestJobs([]history.LedgerRange{{1, 128}}, 64)
+	require.NoError(t, err)
+
+	// the worker moves on to the next job after losing the lease of the
+	// first one
+	require.NoError(t, system.ReingestJobs(ReingestJobsOptions{}))
+	result.AssertNumberOfCalls(t, "ReingestRange", 2)
+}
+
+func TestReingestJobsFailsWithUnfinishedJobs(t *testing.T) {
+	factory := func(c Config) (System, error) {
+		return &mockSystem{}, nil
+	}
+	system, err := newParallelSystems(Config{}, 1, factory)
+	require.NoError(t, err)
+	q := &memoryReingestJobsQ{}
+	system.jobsQ = q
+
+	_, err = system.EnqueueReingestJobs([]history.LedgerRange{{1, 64}}, 64)
+	require.NoError(t, err)
+	// a job which is still leased once the workers stop
+	_, ok, err := q.ClaimReingestJob(context.Background(), "other", time.Hour, 3)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// without workers the job is still leased once they stop
+	system.workerCount = 0
+	err = system.ReingestJobs(ReingestJobsOptions{})
+	assert.EqualError(t, err, "1 reingest jobs are unfinished, first unfinished range: [1, 64] (running)")
+}
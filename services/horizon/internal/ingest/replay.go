@@ -0,0 +1,347 @@
+package ingest
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pownieh/stellar_go/ingest/ledgerbackend"
+	"github.com/pownieh/stellar_go/services/horizon/internal/db2/history"
+	"github.com/pownieh/stellar_go/support/db"
+	"github.com/pownieh/stellar_go/support/errors"
+	"github.com/pownieh/stellar_go/toid"
+)
+
+// ReplayedRow is a row of a table.
+type ReplayedRow struct {
+	Table string
+	// Values are the values of the columns, formatted by replayValue so that
+	// the rows written by the processors can be compared to the rows held by
+	// the DB.
+	Values map[string]interface{}
+}
+
+// ReplayedWrite is a write a processor would make to the DB.
+type ReplayedWrite struct {
+	Processor string
+	// Operation is db.RecordedInsert, db.RecordedUpsert or the first keyword
+	// of the statement.
+	Operation string
+	// Row is the inserted row, it is only set for inserts and upserts.
+	Row *ReplayedRow
+	// Statement is the statement with its arguments, it is only set for the
+	// writes other than inserts and upserts.
+	Statement string
+}
+
+// LedgerReplay holds the writes the processors would make to the DB when
+// ingesting a ledger.
+type LedgerReplay struct {
+	Sequence uint32
+	// LastIngestedLedger is the last ledger ingested when the ledger was
+	// replayed.
+	LastIngestedLedger uint32
+	// StateProcessors is true when the state processors were run, which is
+	// only possible for the ledger following the last ingested ledger.
+	StateProcessors bool
+	// Existing are the history rows of the ledger held by the DB.
+	Existing []ReplayedRow
+	// Writes are the writes of the processors, in the order in which they
+	// would be made.
+	Writes []ReplayedWrite
+}
+
+// ReplayLedger runs the processors on the given ledger and returns the writes
+// they would make to the DB. The processors write to a db.RecordingSession, so
+// nothing is written: the DB is only read, in a read-only transaction, and
+// can be a read-only replica. Only the history processors are run for an
+// already ingested ledger and its history rows are returned, to be compared to
+// the rows written by the processors. The state processors are run for the
+// ledger following the last ingested ledger, the ledgers after it can't be
+// replayed.
+func (s *system) ReplayLedger(sequence uint32) (LedgerReplay, error) {
+	replay := LedgerReplay{Sequence: sequence}
+	if sequence < 2 {
+		return replay, errors.New("ledger 1 is pregenerated and can't be replayed")
+	}
+
+	err := s.ledgerBackend.PrepareRange(s.ctx, ledgerbackend.BoundedRange(sequence, sequence))
+	if err != nil {
+		return replay, errors.Wrap(err, "error preparing range")
+	}
+	ledgerCloseMeta, err := s.ledgerBackend.GetLedger(s.ctx, sequence)
+	if err != nil {
+		return replay, errors.Wrap(err, "error getting ledger")
+	}
+
+	session := db.NewRecordingSession(s.config.HistorySession.Clone())
+	defer session.Close()
+	historyQ := &history.Q{SessionInterface: session}
+	if err = historyQ.Begin(s.ctx); err != nil {
+		return replay, errors.Wrap(err, "Error starting a transaction")
+	}
+	defer historyQ.Rollback()
+
+	replay.LastIngestedLedger, err = historyQ.GetLastLedgerIngestNonBlocking(s.ctx)
+	if err != nil {
+		return replay, errors.Wrap(err, getLastIngestedErrMsg)
+	}
+	if sequence > replay.LastIngestedLedger+1 {
+		return replay, errors.Errorf(
+			"ledger %d can't be replayed, the last ingested ledger is %d",
+			sequence, replay.LastIngestedLedger,
+		)
+	}
+	replay.StateProcessors = sequence == replay.LastIngestedLedger+1
+
+	if !replay.StateProcessors {
+		start, end, rangeErr := toid.LedgerRangeInclusive(int32(sequence), int32(sequence))
+		if rangeErr != nil {
+			return replay, errors.Wrap(rangeErr, "Invalid range")
+		}
+		rows, rowsErr := historyQ.GetHistoryRowsInRange(s.ctx, start, end, pluginHistoryTables(s.processorPlugins))
+		if rowsErr != nil {
+			return replay, errors.Wrap(rowsErr, "error getting the history rows of the ledger")
+		}
+		replay.Existing = replayedRows(rows)
+	}
+
+	runner := s.newReplayRunner(historyQ)
+	runner.SetProcessorHook(func(ctx context.Context, processor string) error {
+		session.SetLabel(processor)
+		return nil
+	})
+	if replay.StateProcessors {
+		_, err = runner.RunAllProcessorsOnLedger(ledgerCloseMeta)
+	} else {
+		_, _, _, err = runner.RunTransactionProcessorsOnLedger(ledgerCloseMeta)
+	}
+	if err != nil {
+		return replay, errors.Wrapf(err, "error processing ledger %d", sequence)
+	}
+
+	for _, write := range session.Writes() {
+		replayed := ReplayedWrite{Processor: write.Label, Operation: write.Operation}
+		if write.Row != nil {
+			row := replayedRow(write.Table, write.Row)
+			replayed.Row = &row
+		} else {
+			replayed.Statement = replayStatement(write.Query, write.Args)
+		}
+		replay.Writes = append(replay.Writes, replayed)
+	}
+	return replay, nil
+}
+
+func pluginHistoryTables(plugins []ProcessorPlugin) map[string]string {
+	tables := map[string]string{}
+	for _, plugin := range plugins {
+		for table, column := range plugin.HistoryTables {
+			tables[table] = column
+		}
+	}
+	return tables
+}
+
+// replayedRows returns the rows sorted by table.
+func replayedRows(rows map[string][]map[string]interface{}) []ReplayedRow {
+	tables := make([]string, 0, len(rows))
+	for table := range rows {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+
+	var result []ReplayedRow
+	for _, table := range tables {
+		for _, row := range rows[table] {
+			result = append(result, replayedRow(table, row))
+		}
+	}
+	return result
+}
+
+func replayedRow(table string, row map[string]interface{}) ReplayedRow {
+	values := make(map[string]interface{}, len(row))
+	for column, value := range row {
+		values[column] = replayValue(value)
+	}
+	return ReplayedRow{Table: table, Values: values}
+}
+
+// replayValue formats the value of a column as a string, or nil for NULL,
+// whether it was written by a processor or read from the DB.
+func replayValue(value interface{}) interface{} {
+	converted, err := driver.DefaultParameterConverter.ConvertValue(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	switch v := converted.(type) {
+	case nil:
+		return nil
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano)
+	case []byte:
+		return replayString(string(v))
+	case string:
+		return replayString(v)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// replayString compacts JSON objects and arrays, the DB formats the values of
+// jsonb columns with spaces.
+func replayString(value string) string {
+	if strings.HasPrefix(value, "{") || strings.HasPrefix(value, "[") {
+		var decoded interface{}
+		decoder := json.NewDecoder(strings.NewReader(value))
+		decoder.UseNumber()
+		if err := decoder.Decode(&decoded); err == nil {
+			if encoded, err := json.Marshal(decoded); err == nil {
+				return string(encoded)
+			}
+		}
+	}
+	return value
+}
+
+func replayStatement(query string, args []interface{}) string {
+	statement := strings.Join(strings.Fields(query), " ")
+	if len(args) == 0 {
+		return statement
+	}
+	values := make([]interface{}, len(args))
+	for i, arg := range args {
+		values[i] = replayValue(arg)
+	}
+	return statement + " " + canonicalJSON(values)
+}
+
+// WriteLedgerReplay writes the writes of the replay grouped by processor. When
+// diff is true the rows inserted by the processors are compared to the
+// existing rows of the ledger: only the rows which differ are written.
+func WriteLedgerReplay(w io.Writer, replay LedgerReplay, diff bool) error {
+	pw := &replayWriter{w: w}
+
+	processorsRun := "history processors"
+	if replay.StateProcessors {
+		processorsRun = "state and history processors"
+	}
+	pw.printf("Ledger %d replayed with the %s, last ingested ledger is %d\n",
+		replay.Sequence, processorsRun, replay.LastIngestedLedger)
+
+	// number of existing rows not matched by an inserted row, by table
+	existing := map[string]map[string]int{}
+	if diff {
+		for _, row := range replay.Existing {
+			if existing[row.Table] == nil {
+				existing[row.Table] = map[string]int{}
+			}
+			existing[row.Table][canonicalJSON(row.Values)]++
+		}
+	}
+
+	var processors []string
+	byProcessor := map[string][]ReplayedWrite{}
+	for _, write := range replay.Writes {
+		if _, ok := byProcessor[write.Processor]; !ok {
+			processors = append(processors, write.Processor)
+		}
+		byProcessor[write.Processor] = append(byProcessor[write.Processor], write)
+	}
+
+	for _, processor := range processors {
+		pw.printf("\n%s\n", processor)
+		unchanged := map[string]int{}
+		var tables []string
+		for _, write := range byProcessor[processor] {
+			if diff && write.Operation == db.RecordedInsert {
+				table := write.Row.Table
+				if row := canonicalJSON(write.Row.Values); existing[table][row] > 0 {
+					existing[table][row]--
+					if unchanged[table] == 0 {
+						tables = append(tables, table)
+					}
+					unchanged[table]++
+					continue
+				}
+			}
+			pw.writeWrite(write, diff)
+		}
+		for _, table := range tables {
+			pw.printf("  = %s: %d rows unchanged\n", table, unchanged[table])
+		}
+	}
+
+	if diff {
+		var removed []string
+		for table, rows := range existing {
+			for row, count := range rows {
+				for i := 0; i < count; i++ {
+					removed = append(removed, table+" "+row)
+				}
+			}
+		}
+		sort.Strings(removed)
+		if len(removed) > 0 {
+			pw.printf("\nrows held by the DB which were not produced by the processors\n")
+		}
+		for _, row := range removed {
+			pw.printf("  - %s\n", row)
+		}
+	}
+	return pw.err
+}
+
+type replayWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (pw *replayWriter) printf(format string, args ...interface{}) {
+	if pw.err == nil {
+		_, pw.err = fmt.Fprintf(pw.w, format, args...)
+	}
+}
+
+func (pw *replayWriter) writeWrite(write ReplayedWrite, diff bool) {
+	if write.Row == nil {
+		if diff {
+			pw.printf("  ! %s\n", write.Statement)
+		} else {
+			pw.printf("  %s\n", write.Statement)
+		}
+		return
+	}
+
+	prefix := write.Operation
+	if diff {
+		prefix = "+"
+		if write.Operation == db.RecordedUpsert {
+			prefix = "~"
+		}
+	}
+	pw.printf("  %s %s %s\n", prefix, write.Row.Table, canonicalJSON(write.Row.Values))
+}
+
+// canonicalJSON encodes the value with the columns of rows sorted, so equal
+// rows have the same encoding.
+func canonicalJSON(value interface{}) string {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(encoded)
+}
@@ -0,0 +1,215 @@
+package ingest
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/pownieh/stellar_go/ingest/ledgerbackend"
+	"github.com/pownieh/stellar_go/services/horizon/internal/db2/history"
+	"github.com/pownieh/stellar_go/support/db"
+)
+
+func newReplayTestSystem(sequence, lastIngested uint32) (*system, *db.MockSession, *mockProcessorsRunner, *ledgerbackend.MockDatabaseBackend) {
+	session := &db.MockSession{}
+	clone := &db.MockSession{}
+	runner := &mockProcessorsRunner{}
+	ledgerBackend := &ledgerbackend.MockDatabaseBackend{}
+	s := &system{
+		ctx:           context.Background(),
+		config:        Config{HistorySession: session},
+		ledgerBackend: ledgerBackend,
+		newReplayRunner: func(*history.Q) ProcessorRunnerInterface {
+			return runner
+		},
+	}
+
+	ledgerBackend.On("PrepareRange", s.ctx, ledgerbackend.BoundedRange(sequence, sequence)).Return(nil).Once()
+	ledgerBackend.On("GetLedger", s.ctx, sequence).Return(stateRebuildLedger(sequence), nil).Once()
+	session.On("Clone").Return(clone).Once()
+	clone.On("BeginTx", s.ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}).Return(nil).Once()
+	clone.On("Get", s.ctx, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		*args.Get(1).(*string) = "120"
+		if lastIngested != 120 {
+			*args.Get(1).(*string) = "100"
+		}
+	}).Return(nil).Once()
+	clone.On("Rollback").Return(nil).Once()
+	clone.On("Close").Return(nil).Once()
+	return s, clone, runner, ledgerBackend
+}
+
+func TestReplayLedgerNotIngested(t *testing.T) {
+	s, session, runner, ledgerBackend := newReplayTestSystem(102, 100)
+	defer mock.AssertExpectationsForObjects(t, session, runner, ledgerBackend)
+
+	_, err := s.ReplayLedger(102)
+	assert.EqualError(t, err, "ledger 102 can't be replayed, the last ingested ledger is 100")
+}
+
+func TestReplayLedgerRecordsWrites(t *testing.T) {
+	s, session, runner, ledgerBackend := newReplayTestSystem(121, 120)
+	defer mock.AssertExpectationsForObjects(t, session, runner, ledgerBackend)
+
+	var recording *db.RecordingSession
+	s.newReplayRunner = func(q *history.Q) ProcessorRunnerInterface {
+		recording = q.SessionInterface.(*db.RecordingSession)
+		return runner
+	}
+
+	var hook processorHook
+	runner.On("SetProcessorHook", mock.Anything).Run(func(args mock.Arguments) {
+		hook = args.Get(0).(func(ctx context.Context, processor string) error)
+	}).Return().Once()
+
+	session.On("GetTx").Return(&sqlx.Tx{}).Once()
+	session.On("GetRaw", s.ctx, mock.Anything, "SELECT count(*) FROM accounts WHERE account_id = ?", []interface{}{"GA"}).
+		Run(func(args mock.Arguments) {
+			*args.Get(1).(*int64) = 1
+		}).Return(nil).Once()
+
+	runner.On("RunAllProcessorsOnLedger", stateRebuildLedger(121)).Run(func(mock.Arguments) {
+		assert.NoError(t, hook(s.ctx, "*processors.EffectProcessor"))
+		insertBuilder := &db.FastBatchInsertBuilder{}
+		assert.NoError(t, insertBuilder.Row(map[string]interface{}{
+			"history_operation_id": int64(1),
+			"order":                uint32(1),
+			"details":              []byte(`{"amount": "10.0000000"}`),
+		}))
+		assert.NoError(t, insertBuilder.Exec(s.ctx, recording, "history_effects"))
+
+		assert.NoError(t, hook(s.ctx, "*processors.AccountsProcessor"))
+		result, err := recording.ExecRaw(s.ctx, "UPDATE accounts SET balance = ? WHERE account_id = ?", 5, "GA")
+		assert.NoError(t, err)
+		affected, err := result.RowsAffected()
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), affected)
+	}).Return(ledgerStats{}, nil).Once()
+
+	result, err := s.ReplayLedger(121)
+	assert.NoError(t, err)
+	assert.Equal(t, LedgerReplay{
+		Sequence:           121,
+		LastIngestedLedger: 120,
+		StateProcessors:    true,
+		Writes: []ReplayedWrite{
+			{
+				Processor: "*processors.EffectProcessor",
+				Operation: db.RecordedInsert,
+				Row: &ReplayedRow{
+					Table: "history_effects",
+					Values: map[string]interface{}{
+						"history_operation_id": "1",
+						"order":                "1",
+						"details":              `{"amount":"10.0000000"}`,
+					},
+				},
+			},
+			{
+				Processor: "*processors.AccountsProcessor",
+				Operation: "UPDATE",
+				Statement: `UPDATE accounts SET balance = ? WHERE account_id = ? ["5","GA"]`,
+			},
+		},
+	}, result)
+}
+
+func TestReplayValue(t *testing.T) {
+	closedAt := time.Date(2022, 1, 2, 3, 4, 5, 123456789, time.FixedZone("", 3600))
+	for _, testCase := range []struct {
+		value    interface{}
+		expected interface{}
+	}{
+		{nil, nil},
+		{int32(-3), "-3"},
+		{uint64(3), "3"},
+		{true, "true"},
+		{1.5, "1.5"},
+		{closedAt, "2022-01-02T02:04:05.123456Z"},
+		{[]byte("GA"), "GA"},
+		{`{"b": 1, "a": [1, 2]}`, `{"a":[1,2],"b":1}`},
+	} {
+		assert.Equal(t, testCase.expected, replayValue(testCase.value))
+	}
+}
+
+func testLedgerReplay() LedgerReplay {
+	return LedgerReplay{
+		Sequence:           100,
+		LastIngestedLedger: 120,
+		Existing: []ReplayedRow{
+			{Table: "history_effects", Values: map[string]interface{}{"history_operation_id": "1", "order": "1"}},
+			{Table: "history_effects", Values: map[string]interface{}{"history_operation_id": "1", "order": "2"}},
+		},
+		Writes: []ReplayedWrite{
+			{
+				Processor: "*processors.EffectProcessor",
+				Operation: db.RecordedInsert,
+				Row: &ReplayedRow{
+					Table:  "history_effects",
+					Values: map[string]interface{}{"order": "1", "history_operation_id": "1"},
+				},
+			},
+			{
+				Processor: "*processors.EffectProcessor",
+				Operation: db.RecordedInsert,
+				Row: &ReplayedRow{
+					Table:  "history_effects",
+					Values: map[string]interface{}{"history_operation_id": "1", "order": "3"},
+				},
+			},
+			{
+				Processor: "*history.AccountLoader",
+				Operation: db.RecordedUpsert,
+				Row: &ReplayedRow{
+					Table:  "history_accounts",
+					Values: map[string]interface{}{"address": "GA"},
+				},
+			},
+			{
+				Processor: "*history.AccountLoader",
+				Operation: "DELETE",
+				Statement: `DELETE FROM history_accounts WHERE id = ? ["5"]`,
+			},
+		},
+	}
+}
+
+func TestWriteLedgerReplay(t *testing.T) {
+	var out bytes.Buffer
+	assert.NoError(t, WriteLedgerReplay(&out, testLedgerReplay(), false))
+	assert.Equal(t, `Ledger 100 replayed with the history processors, last ingested ledger is 120
+
+*processors.EffectProcessor
+  INSERT history_effects {"history_operation_id":"1","order":"1"}
+  INSERT history_effects {"history_operation_id":"1","order":"3"}
+
+*history.AccountLoader
+  UPSERT history_accounts {"address":"GA"}
+  DELETE FROM history_accounts WHERE id = ? ["5"]
+`, out.String())
+}
+
+func TestWriteLedgerReplayDiff(t *testing.T) {
+	var out bytes.Buffer
+	assert.NoError(t, WriteLedgerReplay(&out, testLedgerReplay(), true))
+	assert.Equal(t, `Ledger 100 replayed with the history processors, last ingested ledger is 120
+
+*processors.EffectProcessor
+  + history_effects {"history_operation_id":"1","order":"3"}
+  = history_effects: 1 rows unchanged
+
+*history.AccountLoader
+  ~ history_accounts {"address":"GA"}
+  ! DELETE FROM history_accounts WHERE id = ? ["5"]
+
+rows held by the DB which were not produced by the processors
+  - history_effects {"history_operation_id":"1","order":"2"}
+`, out.String())
+}
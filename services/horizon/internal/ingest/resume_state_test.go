@@ -10,6 +10,7 @@ import (
 	"github.com/stretchr/testify/suite"
 
 	"github.com/pownieh/stellar_go/ingest/ledgerbackend"
+	"github.com/pownieh/stellar_go/services/horizon/internal/db2/history"
 	"github.com/pownieh/stellar_go/support/errors"
 	"github.com/pownieh/stellar_go/xdr"
 )
@@ -37,17 +38,19 @@ func (s *ResumeTestTestSuite) SetupTest() {
 	s.runner = &mockProcessorsRunner{}
 	s.stellarCoreClient = &mockStellarCoreClient{}
 	s.system = &system{
-		ctx:                          s.ctx,
-		historyQ:                     s.historyQ,
-		historyAdapter:               s.historyAdapter,
-		runner:                       s.runner,
-		ledgerBackend:                s.ledgerBackend,
-		stellarCoreClient:            s.stellarCoreClient,
-		runStateVerificationOnLedger: ledgerEligibleForStateVerification(64, 1),
+		ctx:                              s.ctx,
+		historyQ:                         s.historyQ,
+		historyAdapter:                   s.historyAdapter,
+		runner:                           s.runner,
+		ledgerBackend:                    s.ledgerBackend,
+		stellarCoreClient:                s.stellarCoreClient,
+		runStateVerificationOnLedger:     ledgerEligibleForStateVerification(64, 1),
+		runFullStateVerificationOnLedger: ledgerEligibleForStateVerification(64, 16),
 	}
 	s.system.initMetrics()
 
 	s.historyQ.On("Rollback").Return(nil).Once()
+	s.historyQ.On("GetStateRebuild", s.ctx).Return(history.StateRebuild{}, nil).Maybe()
 
 	s.ledgerBackend.On("IsPrepared", s.ctx, ledgerbackend.UnboundedRange(101)).Return(false, nil).Once()
 	s.ledgerBackend.On("PrepareRange", s.ctx, ledgerbackend.UnboundedRange(101)).Return(nil).Once()
@@ -273,6 +276,7 @@ func (s *ResumeTestTestSuite) mockSuccessfulIngestion() {
 	s.historyQ.On("UpdateLastLedgerIngest", s.ctx, uint32(101)).Return(nil).Once()
 	s.historyQ.On("Commit").Return(nil).Once()
 	s.historyQ.On("RebuildTradeAggregationBuckets", s.ctx, uint32(101), uint32(101), 0).Return(nil).Once()
+	s.historyQ.On("RebuildLiquidityPoolStatsBuckets", s.ctx, uint32(101), uint32(101)).Return(nil).Once()
 
 	s.stellarCoreClient.On(
 		"SetCursor",
@@ -362,6 +366,7 @@ func (s *ResumeTestTestSuite) TestErrorSettingCursorIgnored() {
 
 	s.historyQ.On("GetExpStateInvalid", s.ctx).Return(false, nil).Once()
 	s.historyQ.On("RebuildTradeAggregationBuckets", s.ctx, uint32(101), uint32(101), 0).Return(nil).Once()
+	s.historyQ.On("RebuildLiquidityPoolStatsBuckets", s.ctx, uint32(101), uint32(101)).Return(nil).Once()
 
 	next, err := resumeState{latestSuccessfullyProcessedLedger: 100}.run(s.system)
 	s.Assert().NoError(err)
@@ -431,6 +436,7 @@ func (s *ResumeTestTestSuite) TestReapingObjectsDisabled() {
 
 	s.historyQ.On("GetExpStateInvalid", s.ctx).Return(false, nil).Once()
 	s.historyQ.On("RebuildTradeAggregationBuckets", s.ctx, uint32(101), uint32(101), 0).Return(nil).Once()
+	s.historyQ.On("RebuildLiquidityPoolStatsBuckets", s.ctx, uint32(101), uint32(101)).Return(nil).Once()
 	// Reap lookup tables not executed
 
 	next, err := resumeState{latestSuccessfullyProcessedLedger: 100}.run(s.system)
@@ -475,6 +481,7 @@ func (s *ResumeTestTestSuite) TestErrorReapingObjectsIgnored() {
 
 	s.historyQ.On("GetExpStateInvalid", s.ctx).Return(false, nil).Once()
 	s.historyQ.On("RebuildTradeAggregationBuckets", s.ctx, uint32(101), uint32(101), 0).Return(nil).Once()
+	s.historyQ.On("RebuildLiquidityPoolStatsBuckets", s.ctx, uint32(101), uint32(101)).Return(nil).Once()
 	// Reap lookup tables:
 	s.ledgerBackend.On("GetLatestLedgerSequence", s.ctx).Return(uint32(101), nil)
 	s.historyQ.On("Begin", s.ctx).Return(nil).Once()
@@ -0,0 +1,531 @@
+package ingest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pownieh/stellar_go/historyarchive"
+	"github.com/pownieh/stellar_go/services/horizon/internal/db2/history"
+	"github.com/pownieh/stellar_go/support/db/pg"
+	"github.com/pownieh/stellar_go/support/errors"
+	logpkg "github.com/pownieh/stellar_go/support/log"
+	"github.com/pownieh/stellar_go/xdr"
+)
+
+// A zero-downtime state rebuild populates shadow copies of the state tables
+// while the state tables keep serving requests:
+//
+//  1. When a rebuild is requested, the next checkpoint ledger ingested
+//     becomes the checkpoint of the rebuild. The ledgers ingested after it
+//     are buffered in the ledger ingestion transaction.
+//  2. The ledger entries of the checkpoint are inserted in the shadow state
+//     tables in the background, once the checkpoint is published.
+//  3. The buffered ledgers are applied to the shadow state tables in the
+//     background, in batches.
+//  4. Once the shadow state tables are at most stateRebuildSwapLag ledgers
+//     behind, the ledger ingestion transaction applies the remaining ledgers
+//     and swaps them with the state tables.
+const (
+	stateRebuildSwapLag      = 10
+	stateRebuildBatchSize    = 64
+	stateRebuildPollInterval = time.Second
+	// stateRebuildCheckpointPollInterval is how often the history archive is
+	// checked until the checkpoint of the rebuild is published.
+	stateRebuildCheckpointPollInterval = 10 * time.Second
+	// stateRebuildProgressInterval is how often the number of ledger entries
+	// of the checkpoint inserted in the shadow state tables is updated.
+	stateRebuildProgressInterval = 10 * time.Second
+	stateRebuildSavepoint        = "state_rebuild"
+)
+
+var errStateRebuildCancelled = errors.New("state rebuild was cancelled")
+
+// stateRebuildTables returns the state tables copied by zero-downtime state
+// rebuilds, which include the state tables of the processor plugins.
+func (s *system) stateRebuildTables() []string {
+	tables := append([]string{}, history.StateTables...)
+	for _, plugin := range s.processorPlugins {
+		tables = append(tables, plugin.StateTables...)
+	}
+	return tables
+}
+
+// updateStateRebuild is called in the ledger ingestion transaction, once the
+// ledger was processed. It starts the requested zero-downtime state rebuild
+// if the ledger is a checkpoint, buffers the ledger when a rebuild is in
+// progress and swaps the shadow state tables when they have caught up.
+func (s *system) updateStateRebuild(ledger xdr.LedgerCloseMeta) error {
+	rebuild, err := s.historyQ.GetStateRebuild(s.ctx)
+	if err != nil {
+		return errors.Wrap(err, "Error getting state rebuild")
+	}
+
+	sequence := ledger.LedgerSequence()
+	switch {
+	case rebuild.Status == history.StateRebuildRequested:
+		if historyarchive.NewCheckpointManager(s.config.CheckpointFrequency).IsCheckpoint(sequence) {
+			return s.startStateRebuild(sequence)
+		}
+	case rebuild.InProgress():
+		if err = s.historyQ.InsertStateRebuildLedger(s.ctx, ledger); err != nil {
+			return errors.Wrap(err, "Error buffering ledger")
+		}
+		if rebuild.Status == history.StateRebuildCatchingUp && sequence-rebuild.ShadowLedger <= stateRebuildSwapLag {
+			return s.swapShadowState(sequence)
+		}
+	}
+	return nil
+}
+
+func (s *system) startStateRebuild(checkpointLedger uint32) error {
+	rebuild, err := s.historyQ.GetStateRebuildForUpdate(s.ctx)
+	if err != nil {
+		return errors.Wrap(err, "Error getting state rebuild")
+	}
+	if rebuild.Status != history.StateRebuildRequested {
+		return nil
+	}
+
+	// Remove the ledgers buffered by a previous rebuild
+	if err = s.historyQ.TruncateStateRebuildLedgers(s.ctx); err != nil {
+		return errors.Wrap(err, "Error truncating buffered ledgers")
+	}
+
+	now := time.Now().UTC()
+	rebuild = history.StateRebuild{
+		Status:           history.StateRebuildBuilding,
+		CheckpointLedger: checkpointLedger,
+		RequestedAt:      rebuild.RequestedAt,
+		StartedAt:        &now,
+	}
+	if err = s.historyQ.UpdateStateRebuild(s.ctx, rebuild); err != nil {
+		return errors.Wrap(err, "Error updating state rebuild")
+	}
+
+	log.WithField("checkpoint_ledger", checkpointLedger).Info("Started zero-downtime state rebuild")
+	return nil
+}
+
+// swapShadowState applies the remaining buffered ledgers to the shadow state
+// tables and swaps them with the state tables. The swap is done in a
+// savepoint so that its errors do not prevent the ledger from being
+// ingested: the swap is retried with the next ledger if the state tables
+// could not be locked, and the rebuild fails otherwise.
+func (s *system) swapShadowState(sequence uint32) error {
+	if err := s.historyQ.Savepoint(s.ctx, stateRebuildSavepoint); err != nil {
+		return errors.Wrap(err, "Error creating savepoint")
+	}
+
+	rebuild, err := s.applyAndSwapShadowState(sequence)
+	if err == nil {
+		return s.historyQ.ReleaseSavepoint(s.ctx, stateRebuildSavepoint)
+	}
+	if isCancelledError(s.ctx, err) {
+		return err
+	}
+	if rollbackErr := s.historyQ.RollbackToSavepoint(s.ctx, stateRebuildSavepoint); rollbackErr != nil {
+		return errors.Wrap(rollbackErr, "Error rolling back to savepoint")
+	}
+
+	if pg.IsLockNotAvailable(err) {
+		log.WithField("err", err).Warn("Could not lock the state tables to swap the shadow state tables, retrying with the next ledger")
+		return nil
+	}
+	log.WithField("err", err).Error("Error swapping the shadow state tables")
+	return s.markStateRebuildFailed(s.historyQ, rebuild.CheckpointLedger, err)
+}
+
+func (s *system) applyAndSwapShadowState(sequence uint32) (history.StateRebuild, error) {
+	rebuild, err := s.historyQ.GetStateRebuildForUpdate(s.ctx)
+	if err != nil {
+		return rebuild, errors.Wrap(err, "Error getting state rebuild")
+	}
+	if rebuild.Status != history.StateRebuildCatchingUp {
+		return rebuild, nil
+	}
+
+	// The state tables must not be swapped while they are verified
+	ok, err := s.historyQ.TryStateVerificationLock(s.ctx)
+	if err != nil {
+		return rebuild, errors.Wrap(err, "Error acquiring state verification lock")
+	}
+	if !ok {
+		log.Info("State verification is in progress, retrying to swap the shadow state tables with the next ledger")
+		return rebuild, nil
+	}
+
+	ledgers, err := s.historyQ.GetStateRebuildLedgers(s.ctx, rebuild.ShadowLedger+1, 0)
+	if err != nil {
+		return rebuild, err
+	}
+	if err = checkStateRebuildLedgers(ledgers, rebuild.ShadowLedger+1); err != nil {
+		return rebuild, err
+	}
+	if len(ledgers) == 0 || ledgers[len(ledgers)-1].LedgerSequence() != sequence {
+		return rebuild, errors.Errorf("buffered ledgers do not end with ledger %d", sequence)
+	}
+
+	if err = s.applyShadowStateLedgers(s.historyQ, s.runner, ledgers); err != nil {
+		return rebuild, err
+	}
+	if err = s.historyQ.SwapShadowStateTables(s.ctx, s.stateRebuildTables()); err != nil {
+		return rebuild, errors.Wrap(err, "Error swapping shadow state tables")
+	}
+	if err = s.historyQ.DeleteStateRebuildLedgers(s.ctx, sequence); err != nil {
+		return rebuild, errors.Wrap(err, "Error deleting buffered ledgers")
+	}
+
+	now := time.Now().UTC()
+	rebuild.Status = history.StateRebuildCompleted
+	rebuild.ShadowLedger = sequence
+	rebuild.FinishedAt = &now
+	if err = s.historyQ.UpdateStateRebuild(s.ctx, rebuild); err != nil {
+		return rebuild, errors.Wrap(err, "Error updating state rebuild")
+	}
+
+	log.WithFields(logpkg.F{
+		"checkpoint_ledger": rebuild.CheckpointLedger,
+		"sequence":          sequence,
+	}).Info("Swapped the shadow state tables, zero-downtime state rebuild completed")
+	return rebuild, nil
+}
+
+// checkStateRebuildLedgers returns an error if the buffered ledgers are not
+// consecutive ledgers starting from fromSequence, which happens when ledgers
+// are ingested without being buffered, e.g. when the state is rebuilt by
+// the ingestion state machine.
+func checkStateRebuildLedgers(ledgers []xdr.LedgerCloseMeta, fromSequence uint32) error {
+	for i, ledger := range ledgers {
+		if ledger.LedgerSequence() != fromSequence+uint32(i) {
+			return errors.Errorf(
+				"ledger %d was not buffered, the state was updated without the shadow state tables",
+				fromSequence+uint32(i),
+			)
+		}
+	}
+	return nil
+}
+
+// applyShadowStateLedgers runs the change processors on the ledgers in the
+// shadow state tables, in the current transaction of historyQ.
+func (s *system) applyShadowStateLedgers(historyQ history.IngestionQ, runner ProcessorRunnerInterface, ledgers []xdr.LedgerCloseMeta) error {
+	if err := historyQ.UseShadowStateTables(s.ctx, true); err != nil {
+		return errors.Wrap(err, "Error using shadow state tables")
+	}
+	if err := runner.RunChangeProcessorsOnLedgers(ledgers); err != nil {
+		return err
+	}
+	if err := historyQ.UseShadowStateTables(s.ctx, false); err != nil {
+		return errors.Wrap(err, "Error using state tables")
+	}
+	return nil
+}
+
+func (s *system) markStateRebuildFailed(historyQ history.IngestionQ, checkpointLedger uint32, cause error) error {
+	rebuild, err := historyQ.GetStateRebuildForUpdate(s.ctx)
+	if err != nil {
+		return errors.Wrap(err, "Error getting state rebuild")
+	}
+	if !rebuild.InProgress() || rebuild.CheckpointLedger != checkpointLedger {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	rebuild.Status = history.StateRebuildFailed
+	rebuild.FinishedAt = &now
+	rebuild.Error = cause.Error()
+	if err = historyQ.UpdateStateRebuild(s.ctx, rebuild); err != nil {
+		return errors.Wrap(err, "Error updating state rebuild")
+	}
+	return nil
+}
+
+// maybeStartStateRebuild populates the shadow state tables in the background
+// when a zero-downtime state rebuild is in progress, unless this node is
+// already populating them.
+func (s *system) maybeStartStateRebuild() {
+	rebuild, err := s.historyQ.GetStateRebuild(s.ctx)
+	if err != nil {
+		if !isCancelledError(s.ctx, err) {
+			log.WithField("err", err).Error("Error getting state rebuild")
+		}
+		return
+	}
+	if !rebuild.InProgress() {
+		return
+	}
+
+	s.stateRebuildMutex.Lock()
+	if s.stateRebuildRunning {
+		s.stateRebuildMutex.Unlock()
+		return
+	}
+	s.stateRebuildRunning = true
+	s.stateRebuildMutex.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer func() {
+			s.stateRebuildMutex.Lock()
+			s.stateRebuildRunning = false
+			s.stateRebuildMutex.Unlock()
+		}()
+
+		if err := s.rebuildShadowState(); err != nil && !isCancelledError(s.ctx, err) {
+			log.WithField("err", err).Error("Error rebuilding the state in the shadow state tables")
+		}
+	}()
+}
+
+// rebuildShadowState inserts the ledger entries of the checkpoint in the
+// shadow state tables, unless it was done already, and applies the buffered
+// ledgers to them until they are swapped with the state tables.
+func (s *system) rebuildShadowState() error {
+	// The lock is held until the shadow state tables are swapped so that
+	// a single node populates them.
+	lockQ := s.historyQ.CloneIngestionQ()
+	defer lockQ.Rollback()
+	if err := lockQ.Begin(s.ctx); err != nil {
+		return errors.Wrap(err, "Error starting a transaction")
+	}
+	ok, err := lockQ.TryStateRebuildLock(s.ctx)
+	if err != nil {
+		return errors.Wrap(err, "Error acquiring state rebuild lock")
+	}
+	if !ok {
+		log.Info("The shadow state tables are populated by another node")
+		return nil
+	}
+
+	historyQ := s.historyQ.CloneIngestionQ()
+	defer historyQ.Rollback()
+
+	rebuild, err := historyQ.GetStateRebuild(s.ctx)
+	if err != nil {
+		return errors.Wrap(err, "Error getting state rebuild")
+	}
+
+	if rebuild.Status == history.StateRebuildBuilding {
+		err = s.buildShadowState(historyQ, rebuild.CheckpointLedger)
+	}
+	if err == nil {
+		err = s.catchUpShadowState(historyQ, rebuild.CheckpointLedger)
+	}
+
+	if err == errStateRebuildCancelled {
+		return nil
+	}
+	if err != nil && !isCancelledError(s.ctx, err) {
+		historyQ.Rollback()
+		if failErr := s.failStateRebuild(historyQ, rebuild.CheckpointLedger, err); failErr != nil {
+			log.WithField("err", failErr).Error("Error marking the state rebuild as failed")
+		}
+	}
+	return err
+}
+
+func (s *system) failStateRebuild(historyQ history.IngestionQ, checkpointLedger uint32, cause error) error {
+	if err := historyQ.Begin(s.ctx); err != nil {
+		return errors.Wrap(err, "Error starting a transaction")
+	}
+	defer historyQ.Rollback()
+	if err := s.markStateRebuildFailed(historyQ, checkpointLedger, cause); err != nil {
+		return err
+	}
+	return historyQ.Commit()
+}
+
+// buildShadowState creates the shadow state tables and inserts the ledger
+// entries of the checkpoint, once it is published, in them.
+func (s *system) buildShadowState(historyQ history.IngestionQ, checkpointLedger uint32) error {
+	localLog := log.WithField("checkpoint_ledger", checkpointLedger)
+	localLog.Info("Waiting for the checkpoint of the state rebuild to be published")
+	for {
+		sequence, err := s.historyAdapter.GetLatestLedgerSequence()
+		if err != nil {
+			return errors.Wrap(err, "Error getting the latest checkpoint from the history archive")
+		}
+		if sequence >= checkpointLedger {
+			break
+		}
+		select {
+		case <-s.ctx.Done():
+			return s.ctx.Err()
+		case <-time.After(stateRebuildCheckpointPollInterval):
+		}
+	}
+
+	if err := historyQ.CreateShadowStateTables(s.ctx, s.stateRebuildTables()); err != nil {
+		return errors.Wrap(err, "Error creating shadow state tables")
+	}
+
+	var (
+		progressMutex sync.Mutex
+		entries       int64
+		percentage    float64
+	)
+	runner := s.newStateRebuildRunner(historyQ, func(processed int, processedPercentage float64) {
+		progressMutex.Lock()
+		defer progressMutex.Unlock()
+		entries = int64(processed)
+		percentage = processedPercentage
+	})
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		progressQ := s.historyQ.CloneIngestionQ()
+		ticker := time.NewTicker(stateRebuildProgressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			progressMutex.Lock()
+			processed, processedPercentage := entries, percentage
+			progressMutex.Unlock()
+			err := s.updateStateRebuildProgress(progressQ, checkpointLedger, processed, processedPercentage)
+			if err != nil && !isCancelledError(s.ctx, err) {
+				localLog.WithField("err", err).Warn("Error updating the progress of the state rebuild")
+			}
+		}
+	}()
+
+	localLog.Info("Inserting the ledger entries of the checkpoint in the shadow state tables")
+	startTime := time.Now()
+	if err := historyQ.Begin(s.ctx); err != nil {
+		return errors.Wrap(err, "Error starting a transaction")
+	}
+	defer historyQ.Rollback()
+
+	if err := historyQ.UseShadowStateTables(s.ctx, true); err != nil {
+		return errors.Wrap(err, "Error using shadow state tables")
+	}
+	stats, err := runner.RunHistoryArchiveIngestion(checkpointLedger, true, 0, xdr.Hash{})
+	if err != nil {
+		return errors.Wrap(err, "Error ingesting the checkpoint in the shadow state tables")
+	}
+	if err = historyQ.UseShadowStateTables(s.ctx, false); err != nil {
+		return errors.Wrap(err, "Error using state tables")
+	}
+
+	rebuild, err := historyQ.GetStateRebuildForUpdate(s.ctx)
+	if err != nil {
+		return errors.Wrap(err, "Error getting state rebuild")
+	}
+	if rebuild.Status != history.StateRebuildBuilding || rebuild.CheckpointLedger != checkpointLedger {
+		return errStateRebuildCancelled
+	}
+	rebuild.Status = history.StateRebuildCatchingUp
+	rebuild.ShadowLedger = checkpointLedger
+	rebuild.EntriesProcessed = 0
+	for _, count := range stats.Map() {
+		rebuild.EntriesProcessed += count.(int64)
+	}
+	rebuild.Progress = 100
+	if err = historyQ.UpdateStateRebuild(s.ctx, rebuild); err != nil {
+		return errors.Wrap(err, "Error updating state rebuild")
+	}
+	if err = historyQ.Commit(); err != nil {
+		return errors.Wrap(err, commitErrMsg)
+	}
+
+	localLog.WithFields(logpkg.F{
+		"entries":  rebuild.EntriesProcessed,
+		"duration": time.Since(startTime).Seconds(),
+	}).Info("Inserted the ledger entries of the checkpoint in the shadow state tables")
+	return nil
+}
+
+func (s *system) updateStateRebuildProgress(historyQ history.IngestionQ, checkpointLedger uint32, entries int64, percentage float64) error {
+	if err := historyQ.Begin(s.ctx); err != nil {
+		return err
+	}
+	defer historyQ.Rollback()
+
+	rebuild, err := historyQ.GetStateRebuildForUpdate(s.ctx)
+	if err != nil {
+		return err
+	}
+	if rebuild.Status != history.StateRebuildBuilding || rebuild.CheckpointLedger != checkpointLedger {
+		return nil
+	}
+	rebuild.EntriesProcessed = entries
+	rebuild.Progress = percentage
+	if err = historyQ.UpdateStateRebuild(s.ctx, rebuild); err != nil {
+		return err
+	}
+	return historyQ.Commit()
+}
+
+// catchUpShadowState applies the buffered ledgers to the shadow state tables
+// until the rebuild is not in progress anymore, i.e. when the ledger
+// ingestion swapped the shadow state tables.
+func (s *system) catchUpShadowState(historyQ history.IngestionQ, checkpointLedger uint32) error {
+	runner := s.newStateRebuildRunner(historyQ, nil)
+	for {
+		applied, err := s.applyShadowStateBatch(historyQ, runner, checkpointLedger)
+		if err != nil {
+			return err
+		}
+		if applied == stateRebuildBatchSize {
+			continue
+		}
+		select {
+		case <-s.ctx.Done():
+			return s.ctx.Err()
+		case <-time.After(stateRebuildPollInterval):
+		}
+	}
+}
+
+// applyShadowStateBatch applies a batch of buffered ledgers to the shadow
+// state tables and returns the number of ledgers applied.
+func (s *system) applyShadowStateBatch(historyQ history.IngestionQ, runner ProcessorRunnerInterface, checkpointLedger uint32) (int, error) {
+	if err := historyQ.Begin(s.ctx); err != nil {
+		return 0, errors.Wrap(err, "Error starting a transaction")
+	}
+	defer historyQ.Rollback()
+
+	rebuild, err := historyQ.GetStateRebuildForUpdate(s.ctx)
+	if err != nil {
+		return 0, errors.Wrap(err, "Error getting state rebuild")
+	}
+	if rebuild.Status != history.StateRebuildCatchingUp || rebuild.CheckpointLedger != checkpointLedger {
+		return 0, errStateRebuildCancelled
+	}
+
+	ledgers, err := historyQ.GetStateRebuildLedgers(s.ctx, rebuild.ShadowLedger+1, stateRebuildBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	if err = checkStateRebuildLedgers(ledgers, rebuild.ShadowLedger+1); err != nil {
+		return 0, err
+	}
+	if len(ledgers) == 0 {
+		return 0, nil
+	}
+
+	if err = s.applyShadowStateLedgers(historyQ, runner, ledgers); err != nil {
+		return 0, err
+	}
+	rebuild.ShadowLedger = ledgers[len(ledgers)-1].LedgerSequence()
+	if err = historyQ.DeleteStateRebuildLedgers(s.ctx, rebuild.ShadowLedger); err != nil {
+		return 0, errors.Wrap(err, "Error deleting buffered ledgers")
+	}
+	if err = historyQ.UpdateStateRebuild(s.ctx, rebuild); err != nil {
+		return 0, errors.Wrap(err, "Error updating state rebuild")
+	}
+	if err = historyQ.Commit(); err != nil {
+		return 0, errors.Wrap(err, commitErrMsg)
+	}
+
+	log.WithFields(logpkg.F{
+		"checkpoint_ledger": checkpointLedger,
+		"shadow_ledger":     rebuild.ShadowLedger,
+	}).Info("Applied buffered ledgers to the shadow state tables")
+	return len(ledgers), nil
+}
@@ -0,0 +1,178 @@
+package ingest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/pownieh/stellar_go/services/horizon/internal/db2/history"
+	"github.com/pownieh/stellar_go/xdr"
+)
+
+func stateRebuildLedger(sequence uint32) xdr.LedgerCloseMeta {
+	return xdr.LedgerCloseMeta{
+		V0: &xdr.LedgerCloseMetaV0{
+			LedgerHeader: xdr.LedgerHeaderHistoryEntry{
+				Header: xdr.LedgerHeader{
+					LedgerSeq:     xdr.Uint32(sequence),
+					LedgerVersion: xdr.Uint32(MaxSupportedProtocolVersion),
+				},
+			},
+		},
+	}
+}
+
+func newStateRebuildTestSystem() (*system, *mockDBQ, *mockProcessorsRunner) {
+	historyQ := &mockDBQ{}
+	runner := &mockProcessorsRunner{}
+	s := &system{
+		ctx:      context.Background(),
+		config:   Config{CheckpointFrequency: 64},
+		historyQ: historyQ,
+		runner:   runner,
+	}
+	return s, historyQ, runner
+}
+
+func TestUpdateStateRebuildStartsAtCheckpoint(t *testing.T) {
+	s, historyQ, runner := newStateRebuildTestSystem()
+	defer mock.AssertExpectationsForObjects(t, historyQ, runner)
+
+	requested := history.StateRebuild{Status: history.StateRebuildRequested}
+	historyQ.On("GetStateRebuild", s.ctx).Return(requested, nil).Twice()
+	// not a checkpoint ledger
+	assert.NoError(t, s.updateStateRebuild(stateRebuildLedger(126)))
+
+	historyQ.On("GetStateRebuildForUpdate", s.ctx).Return(requested, nil).Once()
+	historyQ.On("TruncateStateRebuildLedgers", s.ctx).Return(nil).Once()
+	historyQ.On("UpdateStateRebuild", s.ctx, mock.MatchedBy(func(rebuild history.StateRebuild) bool {
+		return rebuild.Status == history.StateRebuildBuilding &&
+			rebuild.CheckpointLedger == 127 &&
+			rebuild.StartedAt != nil
+	})).Return(nil).Once()
+	assert.NoError(t, s.updateStateRebuild(stateRebuildLedger(127)))
+}
+
+func TestUpdateStateRebuildBuffersLedgers(t *testing.T) {
+	s, historyQ, runner := newStateRebuildTestSystem()
+	defer mock.AssertExpectationsForObjects(t, historyQ, runner)
+
+	historyQ.On("GetStateRebuild", s.ctx).Return(history.StateRebuild{
+		Status:           history.StateRebuildCatchingUp,
+		CheckpointLedger: 127,
+		ShadowLedger:     127,
+	}, nil).Once()
+	ledger := stateRebuildLedger(150)
+	historyQ.On("InsertStateRebuildLedger", s.ctx, ledger).Return(nil).Once()
+
+	// the shadow state tables are too far behind to be swapped
+	assert.NoError(t, s.updateStateRebuild(ledger))
+}
+
+func mockShadowStateSwap(s *system, historyQ *mockDBQ, runner *mockProcessorsRunner, buffered []xdr.LedgerCloseMeta) {
+	rebuild := history.StateRebuild{
+		Status:           history.StateRebuildCatchingUp,
+		CheckpointLedger: 127,
+		ShadowLedger:     130,
+	}
+	historyQ.On("GetStateRebuild", s.ctx).Return(rebuild, nil).Once()
+	historyQ.On("InsertStateRebuildLedger", s.ctx, stateRebuildLedger(132)).Return(nil).Once()
+	historyQ.On("Savepoint", s.ctx, stateRebuildSavepoint).Return(nil).Once()
+	historyQ.On("GetStateRebuildForUpdate", s.ctx).Return(rebuild, nil).Once()
+	historyQ.On("TryStateVerificationLock", s.ctx).Return(true, nil).Once()
+	historyQ.On("GetStateRebuildLedgers", s.ctx, uint32(131), uint64(0)).Return(buffered, nil).Once()
+}
+
+func TestUpdateStateRebuildSwapsShadowState(t *testing.T) {
+	s, historyQ, runner := newStateRebuildTestSystem()
+	defer mock.AssertExpectationsForObjects(t, historyQ, runner)
+
+	ledgers := []xdr.LedgerCloseMeta{stateRebuildLedger(131), stateRebuildLedger(132)}
+	mockShadowStateSwap(s, historyQ, runner, ledgers)
+	historyQ.On("UseShadowStateTables", s.ctx, true).Return(nil).Once()
+	runner.On("RunChangeProcessorsOnLedgers", ledgers).Return(nil).Once()
+	historyQ.On("UseShadowStateTables", s.ctx, false).Return(nil).Once()
+	historyQ.On("SwapShadowStateTables", s.ctx, history.StateTables).Return(nil).Once()
+	historyQ.On("DeleteStateRebuildLedgers", s.ctx, uint32(132)).Return(nil).Once()
+	historyQ.On("UpdateStateRebuild", s.ctx, mock.MatchedBy(func(rebuild history.StateRebuild) bool {
+		return rebuild.Status == history.StateRebuildCompleted &&
+			rebuild.ShadowLedger == 132 &&
+			rebuild.FinishedAt != nil
+	})).Return(nil).Once()
+	historyQ.On("ReleaseSavepoint", s.ctx, stateRebuildSavepoint).Return(nil).Once()
+
+	assert.NoError(t, s.updateStateRebuild(stateRebuildLedger(132)))
+}
+
+func TestUpdateStateRebuildRetriesSwapOnLockTimeout(t *testing.T) {
+	s, historyQ, runner := newStateRebuildTestSystem()
+	defer mock.AssertExpectationsForObjects(t, historyQ, runner)
+
+	ledgers := []xdr.LedgerCloseMeta{stateRebuildLedger(131), stateRebuildLedger(132)}
+	mockShadowStateSwap(s, historyQ, runner, ledgers)
+	historyQ.On("UseShadowStateTables", s.ctx, true).Return(nil).Once()
+	runner.On("RunChangeProcessorsOnLedgers", ledgers).Return(nil).Once()
+	historyQ.On("UseShadowStateTables", s.ctx, false).Return(nil).Once()
+	historyQ.On("SwapShadowStateTables", s.ctx, history.StateTables).
+		Return(&pq.Error{Code: "55P03", Message: "canceling statement due to lock timeout"}).Once()
+	historyQ.On("RollbackToSavepoint", s.ctx, stateRebuildSavepoint).Return(nil).Once()
+
+	// the rebuild is still in progress and the ledger is ingested
+	assert.NoError(t, s.updateStateRebuild(stateRebuildLedger(132)))
+}
+
+func TestUpdateStateRebuildFailsOnMissingLedger(t *testing.T) {
+	s, historyQ, runner := newStateRebuildTestSystem()
+	defer mock.AssertExpectationsForObjects(t, historyQ, runner)
+
+	mockShadowStateSwap(s, historyQ, runner, []xdr.LedgerCloseMeta{stateRebuildLedger(132)})
+	historyQ.On("RollbackToSavepoint", s.ctx, stateRebuildSavepoint).Return(nil).Once()
+	historyQ.On("GetStateRebuildForUpdate", s.ctx).Return(history.StateRebuild{
+		Status:           history.StateRebuildCatchingUp,
+		CheckpointLedger: 127,
+		ShadowLedger:     130,
+	}, nil).Once()
+	historyQ.On("UpdateStateRebuild", s.ctx, mock.MatchedBy(func(rebuild history.StateRebuild) bool {
+		return rebuild.Status == history.StateRebuildFailed &&
+			rebuild.Error == "ledger 131 was not buffered, the state was updated without the shadow state tables"
+	})).Return(nil).Once()
+
+	assert.NoError(t, s.updateStateRebuild(stateRebuildLedger(132)))
+}
+
+func TestApplyShadowStateBatch(t *testing.T) {
+	s, historyQ, runner := newStateRebuildTestSystem()
+	defer mock.AssertExpectationsForObjects(t, historyQ, runner)
+
+	rebuild := history.StateRebuild{
+		Status:           history.StateRebuildCatchingUp,
+		CheckpointLedger: 127,
+		ShadowLedger:     127,
+	}
+	ledgers := []xdr.LedgerCloseMeta{stateRebuildLedger(128), stateRebuildLedger(129)}
+	historyQ.On("Begin", s.ctx).Return(nil).Once()
+	historyQ.On("Rollback").Return(nil).Once()
+	historyQ.On("GetStateRebuildForUpdate", s.ctx).Return(rebuild, nil).Once()
+	historyQ.On("GetStateRebuildLedgers", s.ctx, uint32(128), uint64(stateRebuildBatchSize)).Return(ledgers, nil).Once()
+	historyQ.On("UseShadowStateTables", s.ctx, true).Return(nil).Once()
+	runner.On("RunChangeProcessorsOnLedgers", ledgers).Return(nil).Once()
+	historyQ.On("UseShadowStateTables", s.ctx, false).Return(nil).Once()
+	historyQ.On("DeleteStateRebuildLedgers", s.ctx, uint32(129)).Return(nil).Once()
+	rebuild.ShadowLedger = 129
+	historyQ.On("UpdateStateRebuild", s.ctx, rebuild).Return(nil).Once()
+	historyQ.On("Commit").Return(nil).Once()
+
+	applied, err := s.applyShadowStateBatch(historyQ, runner, 127)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, applied)
+
+	// the batch is not applied once the rebuild was requested again
+	historyQ.On("Begin", s.ctx).Return(nil).Once()
+	historyQ.On("Rollback").Return(nil).Once()
+	historyQ.On("GetStateRebuildForUpdate", s.ctx).Return(history.StateRebuild{Status: history.StateRebuildRequested}, nil).Once()
+	_, err = s.applyShadowStateBatch(historyQ, runner, 127)
+	assert.Equal(t, errStateRebuildCancelled, err)
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"encoding/hex"
 	"fmt"
+	"io"
 	"time"
 
 	"github.com/guregu/null"
@@ -31,10 +32,27 @@ const assetStatsBatchSize = 500
 // method instead of just updating this value!
 const stateVerifierExpectedIngestionVersion = 17
 
+// stateChecksumTypeNames are the names of the ledger entry types with state
+// checksums in metrics and verification results.
+var stateChecksumTypeNames = map[xdr.LedgerEntryType]string{
+	xdr.LedgerEntryTypeAccount:          "accounts",
+	xdr.LedgerEntryTypeTrustline:        "trust_lines",
+	xdr.LedgerEntryTypeOffer:            "offers",
+	xdr.LedgerEntryTypeData:             "data",
+	xdr.LedgerEntryTypeClaimableBalance: "claimable_balances",
+	xdr.LedgerEntryTypeLiquidityPool:    "liquidity_pools",
+}
+
 // verifyState is called as a go routine from pipeline post hook every 64
 // ledgers. It checks if the state is correct. If another go routine is already
 // running it exits.
-func (s *system) verifyState(verifyAgainstLatestCheckpoint bool) error {
+//
+// The checksums of the entries of each type in the checkpoint are compared
+// first with the checksums of the state tables maintained by ingestion, and
+// only the entry types whose checksum does not match, or is unknown, are
+// verified against the state tables. All the entry types are verified against
+// the state tables on the ledgers selected by runFullStateVerificationOnLedger.
+func (s *system) verifyState(verifyAgainstLatestCheckpoint bool) (err error) {
 	s.stateVerificationMutex.Lock()
 	if s.stateVerificationRunning {
 		log.Warn("State verification is already running...")
@@ -62,7 +80,7 @@ func (s *system) verifyState(verifyAgainstLatestCheckpoint bool) error {
 
 	historyQ := s.historyQ.CloneIngestionQ()
 	defer historyQ.Rollback()
-	err := historyQ.BeginTx(s.ctx, &sql.TxOptions{
+	err = historyQ.BeginTx(s.ctx, &sql.TxOptions{
 		Isolation: sql.LevelRepeatableRead,
 		ReadOnly:  true,
 	})
@@ -144,6 +162,28 @@ func (s *system) verifyState(verifyAgainstLatestCheckpoint bool) error {
 	totalByType := map[string]int64{}
 
 	startTime := time.Now()
+	result := history.StateVerificationResult{Ledger: ledgerSequence, StartedAt: startTime.UTC()}
+	typeResults := map[xdr.LedgerEntryType]*history.StateVerificationTypeResult{}
+	for _, entryType := range history.StateChecksumEntryTypes {
+		typeResults[entryType] = &history.StateVerificationTypeResult{Type: stateChecksumTypeNames[entryType]}
+	}
+	// offsets are the new offsets of the rolling checksums, set when some
+	// entry types were verified against the state tables
+	var offsets history.StateChecksums
+	defer func() {
+		// Don't record the result if context canceled.
+		if ctx.Err() == context.Canceled {
+			return
+		}
+		for _, entryType := range history.StateChecksumEntryTypes {
+			result.Types = append(result.Types, *typeResults[entryType])
+		}
+		if err != nil {
+			result.Error = err.Error()
+			offsets = nil
+		}
+		s.recordStateVerification(result, offsets)
+	}()
 	defer func() {
 		duration := time.Since(startTime).Seconds()
 		if updateMetrics {
@@ -160,6 +200,87 @@ func (s *system) verifyState(verifyAgainstLatestCheckpoint bool) error {
 
 	}()
 
+	rollingChecksums, err := historyQ.GetStateChecksums(ctx)
+	if err != nil {
+		return errors.Wrap(err, "Error running historyQ.GetStateChecksums")
+	}
+	checksumOffsets, err := historyQ.GetStateChecksumOffsets(ctx)
+	if err != nil {
+		return errors.Wrap(err, "Error running historyQ.GetStateChecksumOffsets")
+	}
+
+	// The state tables are read, even when the checksums match, on every
+	// StateVerificationFullFrequency checkpoints.
+	forceFullVerification := s.runFullStateVerificationOnLedger(ledgerSequence)
+	fullVerification := map[xdr.LedgerEntryType]bool{}
+	for _, entryType := range history.StateChecksumEntryTypes {
+		if _, ok := checksumOffsets[entryType]; !ok || forceFullVerification {
+			fullVerification[entryType] = true
+		}
+	}
+	// checkpointAssetStats are the asset stats computed from the entries of
+	// the checkpoint, when the checksums are compared.
+	var checkpointAssetStats processors.AssetStatSet
+	if len(fullVerification) < len(history.StateChecksumEntryTypes) {
+		localLog.Info("Computing checkpoint state checksums...")
+		var expected history.StateChecksums
+		var counts map[xdr.LedgerEntryType]int64
+		expected, counts, checkpointAssetStats, err = s.checkpointStateChecksums(ctx, ledgerSequence)
+		if err != nil {
+			return errors.Wrap(err, "Error computing checkpoint state checksums")
+		}
+
+		result.Incremental = true
+		for _, entryType := range history.StateChecksumEntryTypes {
+			typeResult := typeResults[entryType]
+			typeResult.Expected = expected[entryType].String()
+			typeResult.Entries = counts[entryType]
+			totalByType[typeResult.Type] = counts[entryType]
+
+			offset, ok := checksumOffsets[entryType]
+			if !ok {
+				continue
+			}
+			actual := rollingChecksums[entryType].Add(offset)
+			typeResult.Actual = actual.String()
+			matches := actual == expected[entryType]
+			if matches {
+				s.Metrics().StateVerifyChecksumMatch.With(prometheus.Labels{"type": typeResult.Type}).Set(1)
+			} else {
+				s.Metrics().StateVerifyChecksumMatch.With(prometheus.Labels{"type": typeResult.Type}).Set(0)
+				localLog.WithFields(logpkg.F{
+					"type":     typeResult.Type,
+					"expected": typeResult.Expected,
+					"actual":   typeResult.Actual,
+				}).Warn("State checksum does not match")
+				fullVerification[entryType] = true
+			}
+		}
+
+		if len(fullVerification) == 0 {
+			// The state tables match the checkpoint, the asset stats are
+			// checked against the entries of the checkpoint.
+			err = checkAssetStats(ctx, checkpointAssetStats, historyQ)
+			if err != nil {
+				return errors.Wrap(err, "checkAssetStats failed")
+			}
+
+			localLog.Info("State checksums match, state correct")
+			updateMetrics = true
+			return nil
+		}
+	}
+
+	verifiedChecksums := history.StateChecksums{}
+	for entryType := range fullVerification {
+		typeResults[entryType].FullVerification = true
+		// counted again while verifying
+		delete(totalByType, stateChecksumTypeNames[entryType])
+		s.Metrics().StateVerifyFullVerificationCounter.
+			With(prometheus.Labels{"type": stateChecksumTypeNames[entryType]}).Inc()
+	}
+	localLog.WithField("types", len(fullVerification)).Info("Verifying state against the state tables")
+
 	localLog.Info("Creating state reader...")
 
 	stateReader, err := s.historyAdapter.GetState(ctx, ledgerSequence)
@@ -176,6 +297,16 @@ func (s *system) verifyState(verifyAgainstLatestCheckpoint bool) error {
 		if entryType == xdr.LedgerEntryTypeConfigSetting || entryType == xdr.LedgerEntryTypeContractCode {
 			return true, entry
 		}
+		// The entry types whose checksum matches are not verified again.
+		if _, ok := stateChecksumTypeNames[entryType]; ok && !fullVerification[entryType] {
+			return true, entry
+		}
+		// Contract data is only read for the asset stats, which are
+		// computed from the entries of the checkpoint when the checksums
+		// are compared.
+		if (entryType == xdr.LedgerEntryTypeContractData || entryType == xdr.LedgerEntryTypeExpiration) && result.Incremental {
+			return true, entry
+		}
 
 		return false, entry
 	})
@@ -200,6 +331,15 @@ func (s *system) verifyState(verifyAgainstLatestCheckpoint bool) error {
 		cBalances := make([]xdr.ClaimableBalanceId, 0, verifyBatchSize)
 		lPools := make([]xdr.PoolId, 0, verifyBatchSize)
 		for _, entry := range entries {
+			if _, ok := stateChecksumTypeNames[entry.Data.Type]; ok {
+				var checksum history.StateChecksum
+				checksum, err = history.NewStateChecksum(entry)
+				if err != nil {
+					return err
+				}
+				verifiedChecksums[entry.Data.Type] = verifiedChecksums[entry.Data.Type].Add(checksum)
+			}
+
 			switch entry.Data.Type {
 			case xdr.LedgerEntryTypeAccount:
 				accounts = append(accounts, entry.Data.MustAccount().AccountId.Address())
@@ -290,34 +430,47 @@ func (s *system) verifyState(verifyAgainstLatestCheckpoint bool) error {
 
 	localLog.WithField("total", total).Info("Finished writing to StateVerifier")
 
-	countAccounts, err := historyQ.CountAccounts(ctx)
-	if err != nil {
-		return errors.Wrap(err, "Error running historyQ.CountAccounts")
+	var countAccounts, countData, countOffers, countTrustLines, countClaimableBalances, countLiquidityPools int
+	if fullVerification[xdr.LedgerEntryTypeAccount] {
+		countAccounts, err = historyQ.CountAccounts(ctx)
+		if err != nil {
+			return errors.Wrap(err, "Error running historyQ.CountAccounts")
+		}
 	}
 
-	countData, err := historyQ.CountAccountsData(ctx)
-	if err != nil {
-		return errors.Wrap(err, "Error running historyQ.CountData")
+	if fullVerification[xdr.LedgerEntryTypeData] {
+		countData, err = historyQ.CountAccountsData(ctx)
+		if err != nil {
+			return errors.Wrap(err, "Error running historyQ.CountData")
+		}
 	}
 
-	countOffers, err := historyQ.CountOffers(ctx)
-	if err != nil {
-		return errors.Wrap(err, "Error running historyQ.CountOffers")
+	if fullVerification[xdr.LedgerEntryTypeOffer] {
+		countOffers, err = historyQ.CountOffers(ctx)
+		if err != nil {
+			return errors.Wrap(err, "Error running historyQ.CountOffers")
+		}
 	}
 
-	countTrustLines, err := historyQ.CountTrustLines(ctx)
-	if err != nil {
-		return errors.Wrap(err, "Error running historyQ.CountTrustLines")
+	if fullVerification[xdr.LedgerEntryTypeTrustline] {
+		countTrustLines, err = historyQ.CountTrustLines(ctx)
+		if err != nil {
+			return errors.Wrap(err, "Error running historyQ.CountTrustLines")
+		}
 	}
 
-	countClaimableBalances, err := historyQ.CountClaimableBalances(ctx)
-	if err != nil {
-		return errors.Wrap(err, "Error running historyQ.CountClaimableBalances")
+	if fullVerification[xdr.LedgerEntryTypeClaimableBalance] {
+		countClaimableBalances, err = historyQ.CountClaimableBalances(ctx)
+		if err != nil {
+			return errors.Wrap(err, "Error running historyQ.CountClaimableBalances")
+		}
 	}
 
-	countLiquidityPools, err := historyQ.CountLiquidityPools(ctx)
-	if err != nil {
-		return errors.Wrap(err, "Error running historyQ.CountLiquidityPools")
+	if fullVerification[xdr.LedgerEntryTypeLiquidityPool] {
+		countLiquidityPools, err = historyQ.CountLiquidityPools(ctx)
+		if err != nil {
+			return errors.Wrap(err, "Error running historyQ.CountLiquidityPools")
+		}
 	}
 
 	err = verifier.Verify(
@@ -328,16 +481,104 @@ func (s *system) verifyState(verifyAgainstLatestCheckpoint bool) error {
 		return errors.Wrap(err, "verifier.Verify failed")
 	}
 
+	// The asset stats computed while verifying only include the entry
+	// types read from the state tables.
+	if result.Incremental {
+		assetStats = checkpointAssetStats
+	}
 	err = checkAssetStats(ctx, assetStats, historyQ)
 	if err != nil {
 		return errors.Wrap(err, "checkAssetStats failed")
 	}
 
+	// The state tables match the checkpoint so the checksums of the verified
+	// types are now known.
+	offsets = history.StateChecksums{}
+	for entryType, offset := range checksumOffsets {
+		offsets[entryType] = offset
+	}
+	for entryType := range fullVerification {
+		offsets[entryType] = verifiedChecksums[entryType].Sub(rollingChecksums[entryType])
+		typeResult := typeResults[entryType]
+		typeResult.Expected = verifiedChecksums[entryType].String()
+		typeResult.Actual = typeResult.Expected
+		s.Metrics().StateVerifyChecksumMatch.With(prometheus.Labels{"type": typeResult.Type}).Set(1)
+	}
+
 	localLog.Info("State correct")
 	updateMetrics = true
 	return nil
 }
 
+// checkpointStateChecksums returns the checksums and the number of the
+// entries of each type in the checkpoint, and the asset stats of the
+// checkpoint.
+func (s *system) checkpointStateChecksums(ctx context.Context, ledgerSequence uint32) (
+	history.StateChecksums,
+	map[xdr.LedgerEntryType]int64,
+	processors.AssetStatSet,
+	error,
+) {
+	stateReader, err := s.historyAdapter.GetState(ctx, ledgerSequence)
+	if err != nil {
+		return nil, nil, processors.AssetStatSet{}, errors.Wrap(err, "Error running GetState")
+	}
+	defer stateReader.Close()
+
+	checksums := history.StateChecksums{}
+	counts := map[xdr.LedgerEntryType]int64{}
+	assetStats := processors.NewAssetStatSet(s.config.NetworkPassphrase)
+	for {
+		change, err := stateReader.Read()
+		if err == io.EOF {
+			return checksums, counts, assetStats, nil
+		}
+		if err != nil {
+			return nil, nil, processors.AssetStatSet{}, errors.Wrap(err, "Error reading state")
+		}
+
+		switch change.Type {
+		case xdr.LedgerEntryTypeTrustline:
+			err = assetStats.AddTrustline(change)
+		case xdr.LedgerEntryTypeClaimableBalance:
+			err = assetStats.AddClaimableBalance(change)
+		case xdr.LedgerEntryTypeLiquidityPool:
+			err = assetStats.AddLiquidityPool(change)
+		case xdr.LedgerEntryTypeContractData:
+			err = assetStats.AddContractData(change)
+		}
+		if err != nil {
+			return nil, nil, processors.AssetStatSet{}, errors.Wrap(err, "Error adding entry to asset stats")
+		}
+
+		entryType := change.Post.Data.Type
+		if _, ok := stateChecksumTypeNames[entryType]; !ok {
+			continue
+		}
+		checksum, err := history.NewStateChecksum(*change.Post)
+		if err != nil {
+			return nil, nil, processors.AssetStatSet{}, err
+		}
+		checksums[entryType] = checksums[entryType].Add(checksum)
+		counts[entryType]++
+	}
+}
+
+// recordStateVerification stores the result of a state verification and,
+// when set, the new offsets of the state checksums.
+func (s *system) recordStateVerification(result history.StateVerificationResult, offsets history.StateChecksums) {
+	result.FinishedAt = time.Now().UTC()
+	historyQ := s.historyQ.CloneIngestionQ()
+	if offsets != nil {
+		if err := historyQ.UpdateStateChecksumOffsets(s.ctx, offsets); err != nil {
+			log.WithError(err).Error("Error updating state checksum offsets")
+		}
+	}
+	if err := historyQ.UpdateStateVerificationResult(s.ctx, result); err != nil {
+		log.WithError(err).Error("Error updating state verification result")
+	}
+}
+
 func checkAssetStats(ctx context.Context, set processors.AssetStatSet, q history.IngestionQ) error {
 	page := db2.PageQuery{
 		Order: "asc",
@@ -401,7 +642,73 @@ func checkAssetStats(ctx context.Context, set processors.AssetStatSet, q history
 	return nil
 }
 
-func addAccountsToStateVerifier(ctx context.Context, verifier *verify.StateVerifier, q history.IngestionQ, ids []string) error {
+// ledgerEntryWriter receives the ledger entries reconstructed from the rows of
+// the state tables.
+type ledgerEntryWriter interface {
+	Write(entry xdr.LedgerEntry) error
+}
+
+type ledgerEntries []xdr.LedgerEntry
+
+func (l *ledgerEntries) Write(entry xdr.LedgerEntry) error {
+	*l = append(*l, entry)
+	return nil
+}
+
+// loadStateEntries returns the ledger entries with the given keys
+// reconstructed from the rows of the state tables, like the state verifier
+// does. It is the processors.StateEntriesLoader of the state checksums.
+func loadStateEntries(ctx context.Context, q history.IngestionQ, keys []xdr.LedgerKey) ([]xdr.LedgerEntry, error) {
+	var accounts []string
+	var data []xdr.LedgerKeyData
+	var offers []int64
+	var trustLines []xdr.LedgerKeyTrustLine
+	var cBalances []xdr.ClaimableBalanceId
+	var lPools []xdr.PoolId
+	for _, key := range keys {
+		switch key.Type {
+		case xdr.LedgerEntryTypeAccount:
+			accounts = append(accounts, key.MustAccount().AccountId.Address())
+		case xdr.LedgerEntryTypeData:
+			data = append(data, key.MustData())
+		case xdr.LedgerEntryTypeOffer:
+			offers = append(offers, int64(key.MustOffer().OfferId))
+		case xdr.LedgerEntryTypeTrustline:
+			trustLines = append(trustLines, key.MustTrustLine())
+		case xdr.LedgerEntryTypeClaimableBalance:
+			cBalances = append(cBalances, key.MustClaimableBalance().BalanceId)
+		case xdr.LedgerEntryTypeLiquidityPool:
+			lPools = append(lPools, key.MustLiquidityPool().LiquidityPoolId)
+		default:
+			return nil, errors.Errorf("unexpected ledger key type %s", key.Type)
+		}
+	}
+
+	var entries ledgerEntries
+	// the asset stats are only checked by the state verifier
+	assetStats := processors.NewAssetStatSet("")
+	if err := addAccountsToStateVerifier(ctx, &entries, q, accounts); err != nil {
+		return nil, err
+	}
+	if err := addDataToStateVerifier(ctx, &entries, q, data); err != nil {
+		return nil, err
+	}
+	if err := addOffersToStateVerifier(ctx, &entries, q, offers); err != nil {
+		return nil, err
+	}
+	if err := addTrustLinesToStateVerifier(ctx, &entries, assetStats, q, trustLines); err != nil {
+		return nil, err
+	}
+	if err := addClaimableBalanceToStateVerifier(ctx, &entries, assetStats, q, cBalances); err != nil {
+		return nil, err
+	}
+	if err := addLiquidityPoolsToStateVerifier(ctx, &entries, assetStats, q, lPools); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func addAccountsToStateVerifier(ctx context.Context, verifier ledgerEntryWriter, q history.IngestionQ, ids []string) error {
 	if len(ids) == 0 {
 		return nil
 	}
@@ -530,7 +837,7 @@ func addAccountsToStateVerifier(ctx context.Context, verifier *verify.StateVerif
 	return nil
 }
 
-func addDataToStateVerifier(ctx context.Context, verifier *verify.StateVerifier, q history.IngestionQ, lkeys []xdr.LedgerKeyData) error {
+func addDataToStateVerifier(ctx context.Context, verifier ledgerEntryWriter, q history.IngestionQ, lkeys []xdr.LedgerKeyData) error {
 	if len(lkeys) == 0 {
 		return nil
 	}
@@ -570,7 +877,7 @@ func addDataToStateVerifier(ctx context.Context, verifier *verify.StateVerifier,
 
 func addOffersToStateVerifier(
 	ctx context.Context,
-	verifier *verify.StateVerifier,
+	verifier ledgerEntryWriter,
 	q history.IngestionQ,
 	ids []int64,
 ) error {
@@ -619,7 +926,7 @@ func offerToXDR(row history.Offer) xdr.OfferEntry {
 
 func addTrustLinesToStateVerifier(
 	ctx context.Context,
-	verifier *verify.StateVerifier,
+	verifier ledgerEntryWriter,
 	assetStats processors.AssetStatSet,
 	q history.IngestionQ,
 	keys []xdr.LedgerKeyTrustLine,
@@ -721,7 +1028,7 @@ func trustLineToXDR(row history.TrustLine) (xdr.LedgerEntry, error) {
 
 func addClaimableBalanceToStateVerifier(
 	ctx context.Context,
-	verifier *verify.StateVerifier,
+	verifier ledgerEntryWriter,
 	assetStats processors.AssetStatSet,
 	q history.IngestionQ,
 	ids []xdr.ClaimableBalanceId,
@@ -775,14 +1082,14 @@ func addClaimableBalanceToStateVerifier(
 		for i, claimant := range claimants {
 			if claimant.MustV0().Destination.Address() != cBalancesClaimants[row.BalanceID][i].Destination ||
 				row.LastModifiedLedger != cBalancesClaimants[row.BalanceID][i].LastModifiedLedger {
-				return fmt.Errorf(
+				return ingest.NewStateError(fmt.Errorf(
 					"claimable_balance_claimants table for balance %s does not match. expectedDestination=%s actualDestination=%s, expectedLastModifiedLedger=%d actualLastModifiedLedger=%d",
 					row.BalanceID,
 					claimant.MustV0().Destination.Address(),
 					cBalancesClaimants[row.BalanceID][i].Destination,
 					row.LastModifiedLedger,
 					cBalancesClaimants[row.BalanceID][i].LastModifiedLedger,
-				)
+				))
 			}
 		}
 
@@ -832,7 +1139,7 @@ func addClaimableBalanceToStateVerifier(
 
 func addLiquidityPoolsToStateVerifier(
 	ctx context.Context,
-	verifier *verify.StateVerifier,
+	verifier ledgerEntryWriter,
 	assetStats processors.AssetStatSet,
 	q history.IngestionQ,
 	ids []xdr.PoolId,
@@ -20,6 +20,7 @@ import (
 	"github.com/pownieh/stellar_go/services/horizon/internal/ingest/processors"
 	"github.com/pownieh/stellar_go/support/errors"
 	"github.com/pownieh/stellar_go/xdr"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 )
@@ -45,12 +46,13 @@ func (s *VerifyRangeStateTestSuite) SetupTest() {
 	s.historyAdapter = &mockHistoryArchiveAdapter{}
 	s.runner = &mockProcessorsRunner{}
 	s.system = &system{
-		ctx:                          s.ctx,
-		historyQ:                     s.historyQ,
-		historyAdapter:               s.historyAdapter,
-		ledgerBackend:                s.ledgerBackend,
-		runner:                       s.runner,
-		runStateVerificationOnLedger: ledgerEligibleForStateVerification(64, 1),
+		ctx:                              s.ctx,
+		historyQ:                         s.historyQ,
+		historyAdapter:                   s.historyAdapter,
+		ledgerBackend:                    s.ledgerBackend,
+		runner:                           s.runner,
+		runStateVerificationOnLedger:     ledgerEligibleForStateVerification(64, 1),
+		runFullStateVerificationOnLedger: ledgerEligibleForStateVerification(64, 16),
 	}
 	s.system.initMetrics()
 
@@ -215,6 +217,8 @@ func (s *VerifyRangeStateTestSuite) TestSuccess() {
 
 	s.historyQ.On("RebuildTradeAggregationBuckets", s.ctx, uint32(100), uint32(200), 0).Return(nil).Once()
 
+	s.historyQ.On("RebuildLiquidityPoolStatsBuckets", s.ctx, uint32(100), uint32(200)).Return(nil).Once()
+
 	next, err := verifyRangeState{fromLedger: 100, toLedger: 200}.run(s.system)
 	s.Assert().NoError(err)
 	s.Assert().Equal(
@@ -271,8 +275,10 @@ func (s *VerifyRangeStateTestSuite) TestSuccessWithVerify() {
 
 	s.historyQ.On("RebuildTradeAggregationBuckets", s.ctx, uint32(100), uint32(110), 0).Return(nil).Once()
 
+	s.historyQ.On("RebuildLiquidityPoolStatsBuckets", s.ctx, uint32(100), uint32(110)).Return(nil).Once()
+
 	clonedQ := &mockDBQ{}
-	s.historyQ.On("CloneIngestionQ").Return(clonedQ).Once()
+	s.historyQ.On("CloneIngestionQ").Return(clonedQ).Twice()
 
 	clonedQ.On("BeginTx", s.ctx, mock.AnythingOfType("*sql.TxOptions")).Run(func(args mock.Arguments) {
 		arg := args.Get(1).(*sql.TxOptions)
@@ -282,6 +288,15 @@ func (s *VerifyRangeStateTestSuite) TestSuccessWithVerify() {
 	clonedQ.On("Rollback").Return(nil).Once()
 	clonedQ.On("GetLastLedgerIngestNonBlocking", s.ctx).Return(uint32(63), nil).Once()
 	clonedQ.On("TryStateVerificationLock", s.ctx).Return(true, nil).Once()
+	// the state checksums are unknown so all the entries are verified
+	clonedQ.MockQStateChecksums.On("GetStateChecksums", s.ctx).Return(history.StateChecksums{}, nil).Once()
+	clonedQ.MockQStateChecksums.On("GetStateChecksumOffsets", s.ctx).Return(history.StateChecksums{}, nil).Once()
+	clonedQ.MockQStateChecksums.On("UpdateStateChecksumOffsets", s.ctx, mock.MatchedBy(func(offsets history.StateChecksums) bool {
+		return len(offsets) == len(history.StateChecksumEntryTypes)
+	})).Return(nil).Once()
+	clonedQ.On("UpdateStateVerificationResult", s.ctx, mock.MatchedBy(func(result history.StateVerificationResult) bool {
+		return result.Ledger == 63 && !result.Incremental && result.Error == "" && result.Types[2].FullVerification
+	})).Return(nil).Once()
 	mockChangeReader := &ingest.MockChangeReader{}
 	mockChangeReader.On("Close").Return(nil).Once()
 	mockAccountID := "GACMZD5VJXTRLKVET72CETCYKELPNCOTTBDC6DHFEUPLG5DHEK534JQX"
@@ -602,6 +617,241 @@ func (s *VerifyRangeStateTestSuite) TestSuccessWithVerify() {
 	clonedQ.AssertExpectations(s.T())
 }
 
+func (s *VerifyRangeStateTestSuite) mockIncrementalStateVerification(
+	rolling history.StateChecksums,
+	assetStats []history.ExpAssetStat,
+) (*mockDBQ, history.StateChecksum) {
+	offerChange := ingest.Change{
+		Type: xdr.LedgerEntryTypeOffer,
+		Post: &xdr.LedgerEntry{
+			Data: xdr.LedgerEntryData{
+				Type:  xdr.LedgerEntryTypeOffer,
+				Offer: &eurOffer,
+			},
+			LastModifiedLedgerSeq: xdr.Uint32(62),
+		},
+	}
+	checksum, err := history.NewStateChecksum(*offerChange.Post)
+	s.Assert().NoError(err)
+
+	clonedQ := &mockDBQ{}
+	s.historyQ.On("CloneIngestionQ").Return(clonedQ).Twice()
+	clonedQ.On("BeginTx", s.ctx, mock.AnythingOfType("*sql.TxOptions")).Return(nil).Once()
+	clonedQ.On("Rollback").Return(nil).Once()
+	clonedQ.On("GetLastLedgerIngestNonBlocking", s.ctx).Return(uint32(63), nil).Once()
+	clonedQ.On("TryStateVerificationLock", s.ctx).Return(true, nil).Once()
+
+	offsets := history.StateChecksums{}
+	for _, entryType := range history.StateChecksumEntryTypes {
+		offsets[entryType] = history.StateChecksum{}
+	}
+	clonedQ.MockQStateChecksums.On("GetStateChecksums", s.ctx).Return(rolling, nil).Once()
+	clonedQ.MockQStateChecksums.On("GetStateChecksumOffsets", s.ctx).Return(offsets, nil).Once()
+
+	mockChangeReader := &ingest.MockChangeReader{}
+	mockChangeReader.On("Read").Return(offerChange, nil).Once()
+	mockChangeReader.On("Read").Return(ingest.Change{}, io.EOF).Once()
+	mockChangeReader.On("Close").Return(nil).Once()
+	s.historyAdapter.On("GetState", s.ctx, uint32(63)).Return(mockChangeReader, nil).Once()
+
+	// the asset stats are checked against the entries of the checkpoint
+	clonedQ.MockQAssetStats.On("GetAssetStats", s.ctx, "", "", db2.PageQuery{
+		Order: "asc",
+		Limit: assetStatsBatchSize,
+	}).Return(assetStats, nil).Once()
+
+	return clonedQ, checksum
+}
+
+func (s *VerifyRangeStateTestSuite) TestIncrementalVerifyChecksumsMatch() {
+	offerChecksum, err := history.NewStateChecksum(xdr.LedgerEntry{
+		Data: xdr.LedgerEntryData{
+			Type:  xdr.LedgerEntryTypeOffer,
+			Offer: &eurOffer,
+		},
+		LastModifiedLedgerSeq: xdr.Uint32(62),
+	})
+	s.Assert().NoError(err)
+	clonedQ, _ := s.mockIncrementalStateVerification(history.StateChecksums{
+		xdr.LedgerEntryTypeOffer: offerChecksum,
+	}, []history.ExpAssetStat{})
+
+	// the state tables are not read
+	clonedQ.On("UpdateStateVerificationResult", s.ctx, mock.MatchedBy(func(result history.StateVerificationResult) bool {
+		offers := result.Types[2]
+		return result.Ledger == 63 && result.Incremental && result.Error == "" &&
+			offers.Type == "offers" && offers.Entries == 1 && !offers.FullVerification &&
+			offers.Expected == offerChecksum.String() && offers.Actual == offers.Expected
+	})).Return(nil).Once()
+
+	s.Assert().NoError(s.system.verifyState(false))
+	clonedQ.AssertExpectations(s.T())
+
+	// Satisfy the mock
+	s.historyQ.Rollback()
+}
+
+func (s *VerifyRangeStateTestSuite) TestIncrementalVerifyChecksumMismatch() {
+	clonedQ, offerChecksum := s.mockIncrementalStateVerification(history.StateChecksums{}, []history.ExpAssetStat{})
+
+	// only the offers are verified against the state tables
+	mockChangeReader := &ingest.MockChangeReader{}
+	mockChangeReader.On("Read").Return(ingest.Change{
+		Type: xdr.LedgerEntryTypeOffer,
+		Post: &xdr.LedgerEntry{
+			Data: xdr.LedgerEntryData{
+				Type:  xdr.LedgerEntryTypeOffer,
+				Offer: &eurOffer,
+			},
+			LastModifiedLedgerSeq: xdr.Uint32(62),
+		},
+	}, nil).Once()
+	mockChangeReader.On("Read").Return(ingest.Change{}, io.EOF).Twice()
+	mockChangeReader.On("Close").Return(nil).Once()
+	s.historyAdapter.On("GetState", s.ctx, uint32(63)).Return(mockChangeReader, nil).Once()
+
+	clonedQ.MockQOffers.On("GetOffersByIDs", s.ctx, []int64{int64(eurOffer.OfferId)}).Return([]history.Offer{{
+		SellerID:           eurOffer.SellerId.Address(),
+		OfferID:            int64(eurOffer.OfferId),
+		SellingAsset:       eurOffer.Selling,
+		BuyingAsset:        eurOffer.Buying,
+		Amount:             int64(eurOffer.Amount),
+		Pricen:             int32(eurOffer.Price.N),
+		Priced:             int32(eurOffer.Price.D),
+		Price:              float64(eurOffer.Price.N) / float64(eurOffer.Price.D),
+		Flags:              int32(eurOffer.Flags),
+		LastModifiedLedger: 62,
+	}}, nil).Once()
+	clonedQ.MockQOffers.On("CountOffers", s.ctx).Return(1, nil).Once()
+
+	expectedOffsets := history.StateChecksums{}
+	for _, entryType := range history.StateChecksumEntryTypes {
+		expectedOffsets[entryType] = history.StateChecksum{}
+	}
+	expectedOffsets[xdr.LedgerEntryTypeOffer] = offerChecksum
+	clonedQ.MockQStateChecksums.On("UpdateStateChecksumOffsets", s.ctx, expectedOffsets).Return(nil).Once()
+	clonedQ.On("UpdateStateVerificationResult", s.ctx, mock.MatchedBy(func(result history.StateVerificationResult) bool {
+		offers := result.Types[2]
+		return result.Incremental && result.Error == "" && offers.FullVerification &&
+			offers.Actual == offerChecksum.String() && !result.Types[0].FullVerification
+	})).Return(nil).Once()
+
+	s.Assert().NoError(s.system.verifyState(false))
+	clonedQ.AssertExpectations(s.T())
+
+	// Satisfy the mock
+	s.historyQ.Rollback()
+}
+
+func (s *VerifyRangeStateTestSuite) TestIncrementalVerifyChecksumsMatchAssetStatsMismatch() {
+	offerChecksum, err := history.NewStateChecksum(xdr.LedgerEntry{
+		Data: xdr.LedgerEntryData{
+			Type:  xdr.LedgerEntryTypeOffer,
+			Offer: &eurOffer,
+		},
+		LastModifiedLedgerSeq: xdr.Uint32(62),
+	})
+	s.Assert().NoError(err)
+	clonedQ, _ := s.mockIncrementalStateVerification(history.StateChecksums{
+		xdr.LedgerEntryTypeOffer: offerChecksum,
+	}, []history.ExpAssetStat{{
+		AssetType:   xdr.AssetTypeAssetTypeCreditAlphanum4,
+		AssetCode:   "EUR",
+		AssetIssuer: eurOffer.SellerId.Address(),
+	}})
+
+	clonedQ.On("UpdateStateVerificationResult", s.ctx, mock.MatchedBy(func(result history.StateVerificationResult) bool {
+		return result.Incremental && result.Error != "" && !result.Types[2].FullVerification
+	})).Return(nil).Once()
+
+	err = s.system.verifyState(false)
+	s.Assert().ErrorContains(err, "checkAssetStats failed: db contains asset stat with code EUR")
+	_, ok := errors.Cause(err).(ingest.StateError)
+	s.Assert().True(ok)
+	clonedQ.AssertExpectations(s.T())
+
+	// Satisfy the mock
+	s.historyQ.Rollback()
+}
+
+func (s *VerifyRangeStateTestSuite) TestFullVerificationForcedWhenChecksumsMatch() {
+	s.system.runFullStateVerificationOnLedger = ledgerEligibleForStateVerification(64, 1)
+	offerEntry := xdr.LedgerEntry{
+		Data: xdr.LedgerEntryData{
+			Type:  xdr.LedgerEntryTypeOffer,
+			Offer: &eurOffer,
+		},
+		LastModifiedLedgerSeq: xdr.Uint32(62),
+	}
+	offerChecksum, err := history.NewStateChecksum(offerEntry)
+	s.Assert().NoError(err)
+
+	clonedQ := &mockDBQ{}
+	s.historyQ.On("CloneIngestionQ").Return(clonedQ).Twice()
+	clonedQ.On("BeginTx", s.ctx, mock.AnythingOfType("*sql.TxOptions")).Return(nil).Once()
+	clonedQ.On("Rollback").Return(nil).Once()
+	clonedQ.On("GetLastLedgerIngestNonBlocking", s.ctx).Return(uint32(63), nil).Once()
+	clonedQ.On("TryStateVerificationLock", s.ctx).Return(true, nil).Once()
+
+	offsets := history.StateChecksums{}
+	for _, entryType := range history.StateChecksumEntryTypes {
+		offsets[entryType] = history.StateChecksum{}
+	}
+	clonedQ.MockQStateChecksums.On("GetStateChecksums", s.ctx).Return(history.StateChecksums{
+		xdr.LedgerEntryTypeOffer: offerChecksum,
+	}, nil).Once()
+	clonedQ.MockQStateChecksums.On("GetStateChecksumOffsets", s.ctx).Return(offsets, nil).Once()
+
+	// the checkpoint is only read once, by the state verifier
+	mockChangeReader := &ingest.MockChangeReader{}
+	mockChangeReader.On("Read").Return(ingest.Change{
+		Type: xdr.LedgerEntryTypeOffer,
+		Post: &offerEntry,
+	}, nil).Once()
+	mockChangeReader.On("Read").Return(ingest.Change{}, io.EOF).Twice()
+	mockChangeReader.On("Close").Return(nil).Once()
+	s.historyAdapter.On("GetState", s.ctx, uint32(63)).Return(mockChangeReader, nil).Once()
+
+	clonedQ.MockQOffers.On("GetOffersByIDs", s.ctx, []int64{int64(eurOffer.OfferId)}).Return([]history.Offer{{
+		SellerID:           eurOffer.SellerId.Address(),
+		OfferID:            int64(eurOffer.OfferId),
+		SellingAsset:       eurOffer.Selling,
+		BuyingAsset:        eurOffer.Buying,
+		Amount:             int64(eurOffer.Amount),
+		Pricen:             int32(eurOffer.Price.N),
+		Priced:             int32(eurOffer.Price.D),
+		Price:              float64(eurOffer.Price.N) / float64(eurOffer.Price.D),
+		Flags:              int32(eurOffer.Flags),
+		LastModifiedLedger: 62,
+	}}, nil).Once()
+	clonedQ.MockQSigners.On("CountAccounts", s.ctx).Return(0, nil).Once()
+	clonedQ.MockQData.On("CountAccountsData", s.ctx).Return(0, nil).Once()
+	clonedQ.MockQOffers.On("CountOffers", s.ctx).Return(1, nil).Once()
+	clonedQ.MockQAssetStats.On("CountTrustLines", s.ctx).Return(0, nil).Once()
+	clonedQ.MockQClaimableBalances.On("CountClaimableBalances", s.ctx).Return(0, nil).Once()
+	clonedQ.MockQLiquidityPools.On("CountLiquidityPools", s.ctx).Return(0, nil).Once()
+	clonedQ.MockQAssetStats.On("GetAssetStats", s.ctx, "", "", db2.PageQuery{
+		Order: "asc",
+		Limit: assetStatsBatchSize,
+	}).Return([]history.ExpAssetStat{}, nil).Once()
+
+	clonedQ.MockQStateChecksums.On("UpdateStateChecksumOffsets", s.ctx, offsets).Return(nil).Once()
+	clonedQ.On("UpdateStateVerificationResult", s.ctx, mock.MatchedBy(func(result history.StateVerificationResult) bool {
+		for _, typeResult := range result.Types {
+			if !typeResult.FullVerification {
+				return false
+			}
+		}
+		return !result.Incremental && result.Error == "" && result.Types[2].Actual == offerChecksum.String()
+	})).Return(nil).Once()
+
+	s.Assert().NoError(s.system.verifyState(false))
+	clonedQ.AssertExpectations(s.T())
+
+	// Satisfy the mock
+	s.historyQ.Rollback()
+}
+
 func (s *VerifyRangeStateTestSuite) TestVerifyFailsWhenAssetStatsMismatch() {
 	set := processors.NewAssetStatSet(s.system.config.NetworkPassphrase)
 
@@ -653,3 +903,40 @@ func (s *VerifyRangeStateTestSuite) TestVerifyFailsWhenAssetStatsMismatch() {
 	// Satisfy the mock
 	s.historyQ.Rollback()
 }
+
+func TestLoadStateEntries(t *testing.T) {
+	ctx := context.Background()
+	q := &mockDBQ{}
+	defer mock.AssertExpectationsForObjects(t, q)
+
+	var offerKey, missingKey xdr.LedgerKey
+	assert.NoError(t, offerKey.SetOffer(eurOffer.SellerId, uint64(eurOffer.OfferId)))
+	assert.NoError(t, missingKey.SetOffer(eurOffer.SellerId, 1234))
+	q.MockQOffers.On("GetOffersByIDs", ctx, []int64{int64(eurOffer.OfferId), 1234}).Return([]history.Offer{{
+		SellerID:           eurOffer.SellerId.Address(),
+		OfferID:            int64(eurOffer.OfferId),
+		SellingAsset:       eurOffer.Selling,
+		BuyingAsset:        eurOffer.Buying,
+		Amount:             int64(eurOffer.Amount),
+		Pricen:             int32(eurOffer.Price.N),
+		Priced:             int32(eurOffer.Price.D),
+		Flags:              int32(eurOffer.Flags),
+		LastModifiedLedger: 62,
+	}}, nil).Once()
+
+	entries, err := loadStateEntries(ctx, q, []xdr.LedgerKey{offerKey, missingKey})
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	// the entry reconstructed from the row has the checksum of the entry
+	expected, err := history.NewStateChecksum(xdr.LedgerEntry{
+		LastModifiedLedgerSeq: 62,
+		Data: xdr.LedgerEntryData{
+			Type:  xdr.LedgerEntryTypeOffer,
+			Offer: &eurOffer,
+		},
+	})
+	assert.NoError(t, err)
+	actual, err := history.NewStateChecksum(entries[0])
+	assert.NoError(t, err)
+	assert.Equal(t, expected, actual)
+}
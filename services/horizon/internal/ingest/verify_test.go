@@ -274,7 +274,7 @@ func TestStateVerifierLockBusy(t *testing.T) {
 	tt.Assert.NoError(q.BeginTx(tt.Ctx, &sql.TxOptions{}))
 
 	checkpointLedger := uint32(63)
-	changeProcessor := buildChangeProcessor(q, &ingest.StatsChangeProcessor{}, ledgerSource, checkpointLedger, "")
+	changeProcessor := buildChangeProcessor(q, &ingest.StatsChangeProcessor{}, ledgerSource, checkpointLedger, "", nil, nil)
 
 	gen := randxdr.NewGenerator()
 	var changes []xdr.LedgerEntryChange
@@ -299,11 +299,12 @@ func TestStateVerifierLockBusy(t *testing.T) {
 
 	mockHistoryAdapter := &mockHistoryArchiveAdapter{}
 	sys := &system{
-		ctx:                          tt.Ctx,
-		historyQ:                     q,
-		historyAdapter:               mockHistoryAdapter,
-		runStateVerificationOnLedger: ledgerEligibleForStateVerification(64, 1),
-		config:                       Config{StateVerificationTimeout: time.Hour},
+		ctx:                              tt.Ctx,
+		historyQ:                         q,
+		historyAdapter:                   mockHistoryAdapter,
+		runStateVerificationOnLedger:     ledgerEligibleForStateVerification(64, 1),
+		runFullStateVerificationOnLedger: ledgerEligibleForStateVerification(64, 16),
+		config:                           Config{StateVerificationTimeout: time.Hour},
 	}
 	sys.initMetrics()
 
@@ -331,7 +332,7 @@ func TestStateVerifier(t *testing.T) {
 	tt.Assert.NoError(q.BeginTx(tt.Ctx, &sql.TxOptions{}))
 
 	checkpointLedger := uint32(63)
-	changeProcessor := buildChangeProcessor(q, &ingest.StatsChangeProcessor{}, ledgerSource, checkpointLedger, "")
+	changeProcessor := buildChangeProcessor(q, &ingest.StatsChangeProcessor{}, ledgerSource, checkpointLedger, "", nil, nil)
 	mockChangeReader := &ingest.MockChangeReader{}
 
 	gen := randxdr.NewGenerator()
@@ -371,11 +372,12 @@ func TestStateVerifier(t *testing.T) {
 	mockHistoryAdapter.On("GetState", mock.AnythingOfType("*context.timerCtx"), uint32(checkpointLedger)).Return(mockChangeReader, nil).Once()
 
 	sys := &system{
-		ctx:                          tt.Ctx,
-		historyQ:                     q,
-		historyAdapter:               mockHistoryAdapter,
-		runStateVerificationOnLedger: ledgerEligibleForStateVerification(64, 1),
-		config:                       Config{StateVerificationTimeout: time.Hour},
+		ctx:                              tt.Ctx,
+		historyQ:                         q,
+		historyAdapter:                   mockHistoryAdapter,
+		runStateVerificationOnLedger:     ledgerEligibleForStateVerification(64, 1),
+		runFullStateVerificationOnLedger: ledgerEligibleForStateVerification(64, 16),
+		config:                           Config{StateVerificationTimeout: time.Hour},
 	}
 	sys.initMetrics()
 
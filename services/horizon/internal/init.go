@@ -2,6 +2,7 @@ package horizon
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"runtime"
 
@@ -64,6 +65,9 @@ func mustInitHorizonDB(app *App) {
 			app.prometheusRegistry,
 			clientConfigs...,
 		)}
+		if len(app.config.ReplicaDatabaseURLs) > 0 {
+			mustInitReplicaPool(app, maxIdle, maxOpen)
+		}
 	} else {
 		// If RO set, use it for all DB queries
 		roClientConfigs := []db.ClientConfig{
@@ -89,6 +93,35 @@ func mustInitHorizonDB(app *App) {
 	}
 }
 
+// mustInitReplicaPool connects to the read replicas of the horizon db. Requests
+// are routed to the replicas which have ingested the ledgers they need, their
+// replication lag is checked every replicaCheckInterval.
+func mustInitReplicaPool(app *App, maxIdle, maxOpen int) {
+	replicaClientConfigs := []db.ClientConfig{
+		db.StatementTimeout(app.config.ConnectionTimeout),
+		db.IdleTransactionTimeout(app.config.ConnectionTimeout),
+	}
+	var replicas []db.Replica
+	for i, url := range app.config.ReplicaDatabaseURLs {
+		subservice := db.Subservice(fmt.Sprintf("%s_replica_%d", db.HistorySubservice, i))
+		replicas = append(replicas, db.Replica{
+			Name: string(subservice),
+			Session: mustNewDBSession(
+				subservice,
+				url,
+				maxIdle,
+				maxOpen,
+				app.prometheusRegistry,
+				replicaClientConfigs...,
+			),
+		})
+	}
+
+	app.replicaPool = db.NewReplicaPool(app.historyQ.SessionInterface, replicas, history.LatestLedgerQuery)
+	app.replicaPool.RegisterMetrics("horizon", app.prometheusRegistry)
+	app.replicaPool.Start(replicaCheckInterval)
+}
+
 func initIngester(app *App) {
 	var err error
 	var coreSession db.SessionInterface
@@ -109,11 +142,14 @@ func initIngester(app *App) {
 		RemoteCaptiveCoreURL:                 app.config.RemoteCaptiveCoreURL,
 		DisableStateVerification:             app.config.IngestDisableStateVerification,
 		StateVerificationCheckpointFrequency: uint32(app.config.IngestStateVerificationCheckpointFrequency),
+		StateVerificationFullFrequency:       uint32(app.config.IngestStateVerificationFullFrequency),
 		StateVerificationTimeout:             app.config.IngestStateVerificationTimeout,
-		EnableReapLookupTables:               app.config.HistoryRetentionCount > 0,
+		EnableReapLookupTables:               !app.config.HistoryRetention().RetainsAll(),
 		EnableExtendedLogLedgerStats:         app.config.IngestEnableExtendedLogLedgerStats,
 		RoundingSlippageFilter:               app.config.RoundingSlippageFilter,
 		EnableIngestionFiltering:             app.config.EnableIngestionFiltering,
+		OrderBookSnapshotPairs:               app.config.OrderBookSnapshotPairs,
+		OrderBookSnapshotInterval:            uint32(app.config.OrderBookSnapshotInterval),
 	})
 
 	if err != nil {
@@ -239,4 +275,15 @@ func initSubmissionSystem(app *App) {
 			return &history.Q{SessionInterface: app.HorizonSession()}
 		},
 	}
+
+	if app.config.TxSubQueue {
+		app.submitter.Queue = &txsub.Queue{
+			DB: func(ctx context.Context) txsub.QueueDB {
+				return &history.Q{SessionInterface: app.HorizonSession()}
+			},
+			Submitter:           app.submitter.Submitter,
+			NetworkPassphrase:   app.config.NetworkPassphrase,
+			RebroadcastInterval: app.config.TxSubQueueRebroadcastInterval,
+		}
+	}
 }
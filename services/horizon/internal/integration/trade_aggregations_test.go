@@ -20,6 +20,13 @@ func TestTradeAggregations(t *testing.T) {
 	ctx := context.Background()
 	historyQ := itest.HorizonIngest().HistoryQ()
 
+	// some scenarios use resolutions which are only allowed without the strict
+	// resolution filtering
+	history.StrictResolutionFiltering = false
+	defer func() {
+		history.StrictResolutionFiltering = true
+	}()
+
 	// Insert some trades
 	now := strtime.Now().RoundDown(60_000)
 	base, err := xdr.BuildAsset("credit_alphanum4", "GDUKMGUGDZQK6YHYA5Z6AY2G4XDSZPSZ3SW5UN3ARVMO6QSRDWP5YLEX", "EUR")
@@ -82,19 +89,21 @@ func TestTradeAggregations(t *testing.T) {
 			pq:         db2.PageQuery{Limit: 100},
 			expected: []history.TradeAggregation{
 				{
-					Timestamp:     now.ToInt64(),
-					TradeCount:    1,
-					BaseVolume:    "4263291501",
-					CounterVolume: "100",
-					Average:       float64(100) / 4_263_291_501,
-					HighN:         23456,
-					HighD:         10000,
-					LowN:          23456,
-					LowD:          10000,
-					OpenN:         23456,
-					OpenD:         10000,
-					CloseN:        23456,
-					CloseD:        10000,
+					Timestamp:                  now.ToInt64(),
+					TradeCount:                 1,
+					BaseVolume:                 "4263291501",
+					CounterVolume:              "100",
+					Average:                    float64(100) / 4_263_291_501,
+					HighN:                      23456,
+					HighD:                      10000,
+					LowN:                       23456,
+					LowD:                       10000,
+					OpenN:                      23456,
+					OpenD:                      10000,
+					CloseN:                     23456,
+					CloseD:                     10000,
+					LiquidityPoolBaseVolume:    "0",
+					LiquidityPoolCounterVolume: "0",
 				},
 			},
 		},
@@ -138,19 +147,21 @@ func TestTradeAggregations(t *testing.T) {
 			pq:         db2.PageQuery{Limit: 100},
 			expected: []history.TradeAggregation{
 				{
-					Timestamp:     now.ToInt64(),
-					TradeCount:    2,
-					BaseVolume:    "8526583002",
-					CounterVolume: "1100",
-					Average:       float64(1100) / 8_526_583_002,
-					HighN:         23456,
-					HighD:         10000,
-					LowN:          13456,
-					LowD:          10000,
-					OpenN:         23456,
-					OpenD:         10000,
-					CloseN:        13456,
-					CloseD:        10000,
+					Timestamp:                  now.ToInt64(),
+					TradeCount:                 2,
+					BaseVolume:                 "8526583002",
+					CounterVolume:              "1100",
+					Average:                    float64(1100) / 8_526_583_002,
+					HighN:                      23456,
+					HighD:                      10000,
+					LowN:                       13456,
+					LowD:                       10000,
+					OpenN:                      23456,
+					OpenD:                      10000,
+					CloseN:                     13456,
+					CloseD:                     10000,
+					LiquidityPoolBaseVolume:    "0",
+					LiquidityPoolCounterVolume: "0",
 				},
 			},
 		},
@@ -194,19 +205,21 @@ func TestTradeAggregations(t *testing.T) {
 			pq:         db2.PageQuery{Limit: 100},
 			expected: []history.TradeAggregation{
 				{
-					Timestamp:     now.RoundDown(86_400_000).ToInt64(),
-					TradeCount:    2,
-					BaseVolume:    "8526593002",
-					CounterVolume: "1100",
-					Average:       float64(1100) / 8_526_593_002,
-					HighN:         23456,
-					HighD:         10000,
-					LowN:          13456,
-					LowD:          10000,
-					OpenN:         23456,
-					OpenD:         10000,
-					CloseN:        13456,
-					CloseD:        10000,
+					Timestamp:                  now.RoundDown(86_400_000).ToInt64(),
+					TradeCount:                 2,
+					BaseVolume:                 "8526593002",
+					CounterVolume:              "1100",
+					Average:                    float64(1100) / 8_526_593_002,
+					HighN:                      23456,
+					HighD:                      10000,
+					LowN:                       13456,
+					LowD:                       10000,
+					OpenN:                      23456,
+					OpenD:                      10000,
+					CloseN:                     13456,
+					CloseD:                     10000,
+					LiquidityPoolBaseVolume:    "0",
+					LiquidityPoolCounterVolume: "0",
 				},
 			},
 		},
@@ -252,19 +265,81 @@ func TestTradeAggregations(t *testing.T) {
 			pq:         db2.PageQuery{Limit: 100},
 			expected: []history.TradeAggregation{
 				{
-					Timestamp:     now.RoundDown(86_400_000).ToInt64(),
-					TradeCount:    1,
-					BaseVolume:    "4263301501",
-					CounterVolume: "100",
-					Average:       float64(100) / 4_263_301_501,
-					HighN:         23456,
-					HighD:         10000,
-					LowN:          23456,
-					LowD:          10000,
-					OpenN:         23456,
-					OpenD:         10000,
-					CloseN:        23456,
-					CloseD:        10000,
+					Timestamp:                  now.RoundDown(86_400_000).ToInt64(),
+					TradeCount:                 1,
+					BaseVolume:                 "4263301501",
+					CounterVolume:              "100",
+					Average:                    float64(100) / 4_263_301_501,
+					HighN:                      23456,
+					HighD:                      10000,
+					LowN:                       23456,
+					LowD:                       10000,
+					OpenN:                      23456,
+					OpenD:                      10000,
+					CloseN:                     23456,
+					CloseD:                     10000,
+					LiquidityPoolBaseVolume:    "0",
+					LiquidityPoolCounterVolume: "0",
+				},
+			},
+		},
+		{
+			name: "liquidity pool and orderbook trades in a 2m bucket",
+			trades: []history.InsertTrade{
+				{
+					HistoryOperationID: 0,
+					Order:              0,
+					LedgerCloseTime:    now.RoundDown(120_000).ToTime().Add(5 * time.Second),
+					BaseAccountID:      null.IntFrom(accounts[itest.Master().Address()]),
+					CounterAccountID:   null.IntFrom(accounts[itest.Master().Address()]),
+					BaseAssetID:        baseAssetId,
+					BaseAmount:         int64(2000),
+					BaseOfferID:        null.IntFrom(int64(600)),
+					BaseIsSeller:       true,
+					CounterAmount:      int64(1000),
+					CounterAssetID:     counterAssetId,
+					PriceN:             1,
+					PriceD:             2,
+					Type:               history.OrderbookTradeType,
+				},
+				{
+					HistoryOperationID:  0,
+					Order:               1,
+					LedgerCloseTime:     now.RoundDown(120_000).ToTime().Add(65 * time.Second),
+					BaseAccountID:       null.IntFrom(accounts[itest.Master().Address()]),
+					CounterAccountID:    null.IntFrom(accounts[itest.Master().Address()]),
+					BaseAssetID:         baseAssetId,
+					BaseAmount:          int64(1000),
+					BaseLiquidityPoolID: null.IntFrom(int64(700)),
+					LiquidityPoolFee:    null.IntFrom(30),
+					BaseIsSeller:        true,
+					CounterAmount:       int64(1000),
+					CounterAssetID:      counterAssetId,
+					PriceN:              1,
+					PriceD:              1,
+					Type:                history.LiquidityPoolTradeType,
+				},
+			},
+			resolution: 120_000,
+			pq:         db2.PageQuery{Limit: 100},
+			expected: []history.TradeAggregation{
+				{
+					Timestamp:                  now.RoundDown(120_000).ToInt64(),
+					TradeCount:                 2,
+					BaseVolume:                 "3000",
+					CounterVolume:              "2000",
+					Average:                    float64(2000) / 3000,
+					HighN:                      1,
+					HighD:                      1,
+					LowN:                       1,
+					LowD:                       2,
+					OpenN:                      1,
+					OpenD:                      2,
+					CloseN:                     1,
+					CloseD:                     1,
+					LiquidityPoolTradeCount:    1,
+					LiquidityPoolBaseVolume:    "1000",
+					LiquidityPoolCounterVolume: "1000",
 				},
 			},
 		},
@@ -284,7 +359,7 @@ func TestTradeAggregations(t *testing.T) {
 			// Rebuild the aggregates.
 			for _, trade := range scenario.trades {
 				ledgerCloseTime := strtime.MillisFromTime(trade.LedgerCloseTime)
-				assert.NoError(t, historyQ.RebuildTradeAggregationTimes(ctx, ledgerCloseTime, ledgerCloseTime, 1000))
+				assert.NoError(t, historyQ.RebuildTradeAggregationTimes(ctx, ledgerCloseTime, ledgerCloseTime, history.DefaultRoundingSlippageFilter))
 			}
 
 			// Check the result is what we expect
@@ -28,6 +28,19 @@ type HorizonStatus struct {
 	HistoryLatestClosedAt time.Time `db:"history_latest_closed_at"`
 	HistoryElder          int32     `db:"history_elder"`
 	ExpHistoryLatest      uint32    `db:"exp_history_latest"`
+	// HistoryElderByResource is the oldest ledger retained for each history
+	// resource as recorded by the reaper once the older rows are deleted,
+	// which is more recent than HistoryElder when the resource is retained
+	// for fewer ledgers than the ledgers history.
+	HistoryElderByResource map[HistoryResource]int32 `db:"-"`
+}
+
+// ResourceElder returns the oldest ledger retained for resource.
+func (s HorizonStatus) ResourceElder(resource HistoryResource) int32 {
+	if elder, ok := s.HistoryElderByResource[resource]; ok && elder > s.HistoryElder {
+		return elder
+	}
+	return s.HistoryElder
 }
 
 // State is an in-memory data structure which holds a snapshot of both
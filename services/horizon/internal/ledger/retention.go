@@ -0,0 +1,106 @@
+package ledger
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// HistoryResource is a group of history tables which can be retained for a
+// different number of ledgers than the rest of the history.
+type HistoryResource string
+
+const (
+	HistoryResourceTransactions HistoryResource = "transactions"
+	HistoryResourceOperations   HistoryResource = "operations"
+	HistoryResourceEffects      HistoryResource = "effects"
+	HistoryResourceTrades       HistoryResource = "trades"
+)
+
+// HistoryResources are the history resources with a configurable retention.
+var HistoryResources = []HistoryResource{
+	HistoryResourceTransactions,
+	HistoryResourceOperations,
+	HistoryResourceEffects,
+	HistoryResourceTrades,
+}
+
+func isHistoryResource(name string) bool {
+	for _, resource := range HistoryResources {
+		if string(resource) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// HistoryRetention is the number of ledgers of history retained for each
+// history resource. A count of 0 retains all the ledgers.
+type HistoryRetention struct {
+	// Count is the retention of the resources which are not in ByResource.
+	Count uint
+	// ByResource overrides Count for some resources.
+	ByResource map[HistoryResource]uint
+}
+
+// ParseHistoryRetentionByResource parses a comma separated list of
+// `resource=count` pairs, e.g. `effects=518400,trades=0`.
+func ParseHistoryRetentionByResource(value string) (map[HistoryResource]uint, error) {
+	byResource := map[HistoryResource]uint{}
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid retention %q, expected resource=count", pair)
+		}
+		name := strings.TrimSpace(parts[0])
+		if !isHistoryResource(name) {
+			return nil, fmt.Errorf("unknown history resource %q, expected one of %v", name, HistoryResources)
+		}
+		count, err := strconv.ParseUint(strings.TrimSpace(parts[1]), 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid retention count for %s: %v", name, err)
+		}
+		byResource[HistoryResource(name)] = uint(count)
+	}
+	return byResource, nil
+}
+
+// ResourceCount returns the number of ledgers retained for resource.
+func (r HistoryRetention) ResourceCount(resource HistoryResource) uint {
+	if count, ok := r.ByResource[resource]; ok {
+		return count
+	}
+	return r.Count
+}
+
+// LedgersCount returns the number of ledgers retained in the ledgers history,
+// which is the longest retention of all the resources because the ledgers
+// of the retained history of any resource are kept.
+func (r HistoryRetention) LedgersCount() uint {
+	var longest uint
+	for _, resource := range HistoryResources {
+		count := r.ResourceCount(resource)
+		if count == 0 {
+			return 0
+		}
+		if count > longest {
+			longest = count
+		}
+	}
+	return longest
+}
+
+// RetainsAll returns true if all the history of all the resources is
+// retained.
+func (r HistoryRetention) RetainsAll() bool {
+	for _, resource := range HistoryResources {
+		if r.ResourceCount(resource) > 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,55 @@
+package ledger
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseHistoryRetentionByResource(t *testing.T) {
+	byResource, err := ParseHistoryRetentionByResource("")
+	require.NoError(t, err)
+	assert.Empty(t, byResource)
+
+	byResource, err = ParseHistoryRetentionByResource("effects=518400, trades=0")
+	require.NoError(t, err)
+	assert.Equal(t, map[HistoryResource]uint{
+		HistoryResourceEffects: 518400,
+		HistoryResourceTrades:  0,
+	}, byResource)
+
+	_, err = ParseHistoryRetentionByResource("effects")
+	assert.EqualError(t, err, `invalid retention "effects", expected resource=count`)
+	_, err = ParseHistoryRetentionByResource("ledgers=10")
+	assert.EqualError(t, err, `unknown history resource "ledgers", expected one of [transactions operations effects trades]`)
+	_, err = ParseHistoryRetentionByResource("effects=-1")
+	assert.Error(t, err)
+}
+
+func TestHistoryRetention(t *testing.T) {
+	retention := HistoryRetention{}
+	assert.True(t, retention.RetainsAll())
+	assert.Equal(t, uint(0), retention.LedgersCount())
+
+	retention = HistoryRetention{
+		Count:      100,
+		ByResource: map[HistoryResource]uint{HistoryResourceEffects: 10, HistoryResourceTrades: 1000},
+	}
+	assert.False(t, retention.RetainsAll())
+	assert.Equal(t, uint(10), retention.ResourceCount(HistoryResourceEffects))
+	assert.Equal(t, uint(100), retention.ResourceCount(HistoryResourceOperations))
+	assert.Equal(t, uint(1000), retention.LedgersCount())
+
+	// trades are retained forever so all the ledgers are retained
+	retention.ByResource[HistoryResourceTrades] = 0
+	assert.False(t, retention.RetainsAll())
+	assert.Equal(t, uint(0), retention.LedgersCount())
+
+	status := HorizonStatus{
+		HistoryElder:           5,
+		HistoryElderByResource: map[HistoryResource]int32{HistoryResourceEffects: 50},
+	}
+	assert.Equal(t, int32(50), status.ResourceElder(HistoryResourceEffects))
+	assert.Equal(t, int32(5), status.ResourceElder(HistoryResourceTrades))
+}
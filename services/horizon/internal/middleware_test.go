@@ -4,6 +4,7 @@ package horizon
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
@@ -25,6 +26,7 @@ import (
 	tdb "github.com/pownieh/stellar_go/services/horizon/internal/test/db"
 	"github.com/pownieh/stellar_go/support/db"
 	"github.com/pownieh/stellar_go/support/log"
+	"github.com/pownieh/stellar_go/toid"
 	"github.com/pownieh/stellar_go/xdr"
 )
 
@@ -402,7 +404,7 @@ func TestCheckHistoryStaleMiddleware(t *testing.T) {
 			}
 			ledgerState := &ledger.State{}
 			ledgerState.SetStatus(state)
-			historyMiddleware := httpx.NewHistoryMiddleware(ledgerState, testCase.staleThreshold, tt.HorizonSession())
+			historyMiddleware := httpx.NewHistoryMiddleware(ledgerState, testCase.staleThreshold, tt.HorizonSession(), nil)
 			handler := chi.NewRouter()
 			handler.With(historyMiddleware).MethodFunc("GET", "/", endpoint)
 			w := httptest.NewRecorder()
@@ -411,3 +413,58 @@ func TestCheckHistoryStaleMiddleware(t *testing.T) {
 		})
 	}
 }
+
+func TestHistoryMiddlewarePrunedResource(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+
+	endpoint := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	ledgerState := &ledger.State{}
+	ledgerState.SetStatus(ledger.Status{
+		HorizonStatus: ledger.HorizonStatus{
+			HistoryLatest: 1000,
+			HistoryElder:  10,
+			HistoryElderByResource: map[ledger.HistoryResource]int32{
+				ledger.HistoryResourceEffects: 500,
+			},
+		},
+	})
+	historyMiddleware := httpx.NewHistoryMiddleware(ledgerState, 0, tt.HorizonSession(), nil)
+	handler := chi.NewRouter()
+	handler.With(historyMiddleware).MethodFunc("GET", "/effects", endpoint)
+	handler.With(historyMiddleware).MethodFunc("GET", "/operations", endpoint)
+	handler.With(historyMiddleware).MethodFunc("GET", "/ledgers/{ledger_id}/effects", endpoint)
+	handler.With(historyMiddleware).MethodFunc("GET", "/operations/{op_id}/effects", endpoint)
+	handler.With(historyMiddleware).MethodFunc("GET", "/accounts/{account_id}/sponsorships/history", endpoint)
+
+	for _, testCase := range []struct {
+		url            string
+		expectedStatus int
+	}{
+		{"/effects", http.StatusOK},
+		{"/effects?order=desc", http.StatusOK},
+		{fmt.Sprintf("/effects?order=desc&cursor=%d-1", toid.New(600, 0, 0).ToInt64()), http.StatusOK},
+		{fmt.Sprintf("/effects?order=desc&cursor=%d-1", toid.New(400, 0, 0).ToInt64()), http.StatusGone},
+		{fmt.Sprintf("/effects?order=asc&cursor=%d-1", toid.New(400, 0, 0).ToInt64()), http.StatusOK},
+		{fmt.Sprintf("/operations?order=desc&cursor=%d", toid.New(400, 0, 0).ToInt64()), http.StatusOK},
+		{"/ledgers/499/effects", http.StatusGone},
+		{"/ledgers/500/effects", http.StatusOK},
+		{fmt.Sprintf("/operations/%d/effects", toid.New(20, 1, 1).ToInt64()), http.StatusGone},
+		{fmt.Sprintf("/accounts/GABC/sponsorships/history?order=desc&cursor=%d-1", toid.New(600, 0, 0).ToInt64()), http.StatusOK},
+		{fmt.Sprintf("/accounts/GABC/sponsorships/history?order=desc&cursor=%d-1", toid.New(400, 0, 0).ToInt64()), http.StatusGone},
+	} {
+		t.Run(testCase.url, func(t *testing.T) {
+			request, err := http.NewRequest("GET", "http://localhost"+testCase.url, nil)
+			tt.Assert.NoError(err)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, request)
+			tt.Assert.Equal(testCase.expectedStatus, w.Code)
+			if testCase.expectedStatus == http.StatusGone {
+				tt.Assert.Contains(w.Body.String(), "history_pruned")
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package operationfeestats
+
+import (
+	"math"
+	"sort"
+)
+
+// InclusionQuantile returns the fraction of ledgers whose inclusion fee a fee
+// bid must cover so that a transaction is included with the given probability
+// within the given number of ledgers. The ledgers are assumed to be
+// independent: a bid covering the inclusion fee of a fraction q of the ledgers
+// is included within n ledgers with probability 1-(1-q)^n.
+func InclusionQuantile(probability float64, withinLedgers uint32) float64 {
+	if probability >= 1 {
+		return 1
+	}
+	if probability <= 0 || withinLedgers == 0 {
+		return 0
+	}
+	return 1 - math.Pow(1-probability, 1/float64(withinLedgers))
+}
+
+// EstimateInclusionFee returns the fee per operation to bid so that a
+// transaction is included with the given probability within the given number
+// of ledgers. inclusionFees are the fees per operation which were needed to be
+// included in each of the recent ledgers: the lowest fee charged when the
+// ledger was surge priced and the base fee otherwise.
+func EstimateInclusionFee(inclusionFees []int64, probability float64, withinLedgers uint32) int64 {
+	if len(inclusionFees) == 0 {
+		return 0
+	}
+	sorted := append([]int64(nil), inclusionFees...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	quantile := InclusionQuantile(probability, withinLedgers)
+	i := int(math.Ceil(quantile*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	return sorted[i]
+}
@@ -0,0 +1,30 @@
+package operationfeestats
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInclusionQuantile(t *testing.T) {
+	assert.Equal(t, 0.9, InclusionQuantile(0.9, 1))
+	// 1-(1-0.5)^2 = 0.75
+	assert.InDelta(t, 0.5, InclusionQuantile(0.75, 2), 1e-9)
+	assert.Equal(t, 1.0, InclusionQuantile(1, 5))
+	assert.Equal(t, 0.0, InclusionQuantile(0, 5))
+	assert.Equal(t, 0.0, InclusionQuantile(0.9, 0))
+}
+
+func TestEstimateInclusionFee(t *testing.T) {
+	assert.Equal(t, int64(0), EstimateInclusionFee(nil, 0.9, 1))
+
+	fees := []int64{100, 500, 100, 100, 200, 100, 100, 1000, 100, 100}
+	// within a single ledger, the bid must cover 90% of the ledgers
+	assert.Equal(t, int64(500), EstimateInclusionFee(fees, 0.9, 1))
+	// within 2 ledgers, covering ~68% of the ledgers is enough
+	assert.Equal(t, int64(100), EstimateInclusionFee(fees, 0.9, 2))
+	assert.Equal(t, int64(1000), EstimateInclusionFee(fees, 1, 10))
+	assert.Equal(t, int64(100), EstimateInclusionFee(fees, 0, 1))
+	// the fees are not modified
+	assert.Equal(t, int64(500), fees[1])
+}
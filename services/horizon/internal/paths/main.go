@@ -2,6 +2,7 @@ package paths
 
 import (
 	"context"
+	"math/big"
 
 	"github.com/pownieh/stellar_go/xdr"
 )
@@ -27,6 +28,93 @@ type Path struct {
 	DestinationAmount xdr.Int64
 }
 
+// SplitQuery is a query for a payment split across several paths
+type SplitQuery struct {
+	// StrictSend is true when Amount is the amount of SourceAsset to send and
+	// false when it is the amount of DestinationAsset to receive.
+	StrictSend       bool
+	SourceAsset      xdr.Asset
+	DestinationAsset xdr.Asset
+	Amount           xdr.Int64
+	// Parts is the number of equal parts Amount is split in to be routed
+	Parts int
+	// if SourceAccount is set then its offers aren't considered in strict
+	// receive split paths
+	SourceAccount *xdr.AccountId
+}
+
+// SplitPath is the result returned by a path finder for a SplitQuery. Each
+// leg is a path submitted as its own path payment operation, the legs are
+// priced in order against the order book left by the previous legs.
+type SplitPath struct {
+	Source            string
+	SourceAmount      xdr.Int64
+	Destination       string
+	DestinationAmount xdr.Int64
+	Legs              []Path
+}
+
+// QuoteQuery is a query for the quote of a trade between two assets
+type QuoteQuery struct {
+	// StrictSend is true when Amount is the amount of SourceAsset to send and
+	// false when it is the amount of DestinationAsset to receive.
+	StrictSend       bool
+	SourceAsset      xdr.Asset
+	DestinationAsset xdr.Asset
+	Amount           xdr.Int64
+	// if SourceAccount is set then its offers aren't considered
+	SourceAccount *xdr.AccountId
+}
+
+// OfferQuery is a query for the part of a new offer which is immediately
+// executed against the order book
+type OfferQuery struct {
+	// Buy is true when Amount is the amount of BuyingAsset to buy and Price
+	// the price of BuyingAsset in terms of SellingAsset, like in
+	// ManageBuyOffer operations. Otherwise Amount is the amount of
+	// SellingAsset to sell and Price the price of SellingAsset in terms of
+	// BuyingAsset.
+	Buy           bool
+	SellingAsset  xdr.Asset
+	BuyingAsset   xdr.Asset
+	Amount        xdr.Int64
+	Price         xdr.Price
+	Passive       bool
+	SellerAccount xdr.AccountId
+}
+
+// Quote is the result returned by a path finder for a QuoteQuery. The trade is
+// executed against the offers or the liquidity pool of the pair, whichever
+// gives the better result.
+type Quote struct {
+	Source            string
+	SourceAmount      xdr.Int64
+	Destination       string
+	DestinationAmount xdr.Int64
+	// MidPrice is the price of the destination asset in terms of the source
+	// asset halfway between the best bid and the best ask, nil when the pair
+	// has no liquidity.
+	MidPrice *big.Rat
+	Offers   []QuoteOffer
+	// Pool is nil when the trade is executed against the offers.
+	Pool *QuotePool
+}
+
+// QuoteOffer is an offer consumed by a quote, Amount is the amount of its
+// selling asset bought.
+type QuoteOffer struct {
+	Offer  xdr.OfferEntry
+	Amount xdr.Int64
+}
+
+// QuotePool is the liquidity pool a quote is executed against, ReserveA and
+// ReserveB are its reserves after the trade.
+type QuotePool struct {
+	Pool     xdr.LiquidityPoolEntry
+	ReserveA xdr.Int64
+	ReserveB xdr.Int64
+}
+
 // Finder finds paths.
 type Finder interface {
 	// Find returns a list of payment paths and the most recent ledger
@@ -44,4 +132,16 @@ type Finder interface {
 		destinationAssets []xdr.Asset,
 		maxLength uint,
 	) ([]Path, uint32, error)
+	// FindSplitPaths returns the payment split across several paths which
+	// routes the amount of the SplitQuery, accounting for the depth of the
+	// order book consumed by each leg, and the most recent ledger.
+	FindSplitPaths(ctx context.Context, q SplitQuery, maxLength uint) (SplitPath, uint32, error)
+	// Quote returns the result of trading the amount of the QuoteQuery
+	// directly between its assets and the most recent ledger.
+	Quote(ctx context.Context, q QuoteQuery) (Quote, uint32, error)
+	// CrossOffer returns the trade of the offer of the OfferQuery against the
+	// offers of the order book, as a Quote selling its SellingAsset, and the
+	// most recent ledger. The trade is smaller than the amount of the offer
+	// when the offer isn't fully executed.
+	CrossOffer(ctx context.Context, q OfferQuery) (Quote, uint32, error)
 }
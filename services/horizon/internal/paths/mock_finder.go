@@ -31,3 +31,21 @@ func (m *MockFinder) FindFixedPaths(
 
 	return args.Get(0).([]Path), args.Get(1).(uint32), args.Error(2)
 }
+
+func (m *MockFinder) FindSplitPaths(ctx context.Context, q SplitQuery, maxLength uint) (SplitPath, uint32, error) {
+	args := m.Called(ctx, q, maxLength)
+
+	return args.Get(0).(SplitPath), args.Get(1).(uint32), args.Error(2)
+}
+
+func (m *MockFinder) Quote(ctx context.Context, q QuoteQuery) (Quote, uint32, error) {
+	args := m.Called(ctx, q)
+
+	return args.Get(0).(Quote), args.Get(1).(uint32), args.Error(2)
+}
+
+func (m *MockFinder) CrossOffer(ctx context.Context, q OfferQuery) (Quote, uint32, error) {
+	args := m.Called(ctx, q)
+
+	return args.Get(0).(Quote), args.Get(1).(uint32), args.Error(2)
+}
@@ -57,3 +57,30 @@ func (f *RateLimitedFinder) FindFixedPaths(
 	}
 	return f.finder.FindFixedPaths(ctx, sourceAsset, amountToSpend, destinationAssets, maxLength)
 }
+
+// FindSplitPaths implements the Finder interface and returns ErrRateLimitExceeded if the
+// RateLimitedFinder is unable to complete the request due to rate limits.
+func (f *RateLimitedFinder) FindSplitPaths(ctx context.Context, q SplitQuery, maxLength uint) (SplitPath, uint32, error) {
+	if !f.limiter.Allow() {
+		return SplitPath{}, 0, ErrRateLimitExceeded
+	}
+	return f.finder.FindSplitPaths(ctx, q, maxLength)
+}
+
+// Quote implements the Finder interface and returns ErrRateLimitExceeded if the
+// RateLimitedFinder is unable to complete the request due to rate limits.
+func (f *RateLimitedFinder) Quote(ctx context.Context, q QuoteQuery) (Quote, uint32, error) {
+	if !f.limiter.Allow() {
+		return Quote{}, 0, ErrRateLimitExceeded
+	}
+	return f.finder.Quote(ctx, q)
+}
+
+// CrossOffer implements the Finder interface and returns ErrRateLimitExceeded if the
+// RateLimitedFinder is unable to complete the request due to rate limits.
+func (f *RateLimitedFinder) CrossOffer(ctx context.Context, q OfferQuery) (Quote, uint32, error) {
+	if !f.limiter.Allow() {
+		return Quote{}, 0, ErrRateLimitExceeded
+	}
+	return f.finder.CrossOffer(ctx, q)
+}
@@ -7,6 +7,7 @@ package reap
 import (
 	"context"
 
+	"github.com/pownieh/stellar_go/services/horizon/internal/coldstorage"
 	"github.com/pownieh/stellar_go/services/horizon/internal/db2/history"
 	"github.com/pownieh/stellar_go/services/horizon/internal/ledger"
 	"github.com/pownieh/stellar_go/support/db"
@@ -16,9 +17,19 @@ import (
 type System struct {
 	HistoryQ       *history.Q
 	RetentionCount uint
-	ledgerState    *ledger.State
-	ctx            context.Context
-	cancel         context.CancelFunc
+	// RetentionCountByResource overrides RetentionCount for some history
+	// resources.
+	RetentionCountByResource map[ledger.HistoryResource]uint
+	// ColdStorage, when set, is the archive the history is exported to
+	// before being reaped.
+	ColdStorage *coldstorage.Archive
+	// MaintainPartitions enables the creation of the partitions of the
+	// history tables ahead of ingestion. It should only be enabled on
+	// ingesting instances.
+	MaintainPartitions bool
+	ledgerState        *ledger.State
+	ctx                context.Context
+	cancel             context.CancelFunc
 }
 
 // New initializes the reaper, causing it to begin polling the stellar-core
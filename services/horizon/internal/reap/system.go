@@ -2,49 +2,222 @@ package reap
 
 import (
 	"context"
+	"sort"
 	"time"
 
+	"github.com/pownieh/stellar_go/services/horizon/internal/db2/history"
 	herrors "github.com/pownieh/stellar_go/services/horizon/internal/errors"
+	"github.com/pownieh/stellar_go/services/horizon/internal/ledger"
 	"github.com/pownieh/stellar_go/support/errors"
 	"github.com/pownieh/stellar_go/support/log"
 	"github.com/pownieh/stellar_go/toid"
 )
 
+// resourceTables are the history tables of each history resource.
+var resourceTables = map[ledger.HistoryResource][]string{
+	ledger.HistoryResourceTransactions: {
+		"history_transactions",
+		"history_transaction_claimable_balances",
+		"history_transaction_liquidity_pools",
+		"history_transaction_participants",
+		"history_ledger_fee_stats",
+	},
+	ledger.HistoryResourceOperations: {
+		"history_operations",
+		"history_operation_claimable_balances",
+		"history_operation_liquidity_pools",
+		"history_operation_participants",
+	},
+	ledger.HistoryResourceEffects: {"history_effects"},
+	ledger.HistoryResourceTrades: {
+		"history_trades",
+		"history_trades_60000",
+		"history_liquidity_pool_ledger_stats",
+		"history_liquidity_pool_stats_3600000",
+	},
+}
+
+// ledgersTables are the history tables retained for the longest retention of
+// all the history resources.
+var ledgersTables = []string{"history_ledgers"}
+
 // DeleteUnretainedHistory removes all data associated with unretained ledgers.
+// Each history resource is reaped according to its own retention, the tables
+// of the resources sharing the same retention are reaped together.
 func (r *System) DeleteUnretainedHistory(ctx context.Context) error {
+	retention := r.retention()
 	// RetentionCount of 0 indicates "keep all history"
-	if r.RetentionCount == 0 {
+	if retention.RetainsAll() {
 		return nil
 	}
 
-	var (
-		latest      = r.ledgerState.CurrentStatus()
-		targetElder = (latest.HistoryLatest - int32(r.RetentionCount)) + 1
-	)
+	tablesByCount := map[uint][]string{}
+	resourcesByCount := map[uint][]ledger.HistoryResource{}
+	for _, resource := range ledger.HistoryResources {
+		count := retention.ResourceCount(resource)
+		tablesByCount[count] = append(tablesByCount[count], resourceTables[resource]...)
+		resourcesByCount[count] = append(resourcesByCount[count], resource)
+	}
+	ledgersCount := retention.LedgersCount()
+	tablesByCount[ledgersCount] = append(tablesByCount[ledgersCount], ledgersTables...)
+	// the history tables of the processor plugins are retained like the
+	// ledgers
+	tablesByCount[ledgersCount] = append(tablesByCount[ledgersCount], history.RegisteredHistoryTables()...)
+
+	counts := make([]uint, 0, len(tablesByCount))
+	for count := range tablesByCount {
+		counts = append(counts, count)
+	}
+	sort.Slice(counts, func(i, j int) bool { return counts[i] < counts[j] })
+
+	latest := r.ledgerState.CurrentStatus()
+	for _, count := range counts {
+		if count == 0 {
+			continue
+		}
+		targetElder := (latest.HistoryLatest - int32(count)) + 1
+		if err := r.deleteBefore(ctx, targetElder, tablesByCount[count]); err != nil {
+			return err
+		}
+		if targetElder <= 0 {
+			continue
+		}
+		// the API serves the resources from the elders recorded once their
+		// history is reaped
+		for _, resource := range resourcesByCount[count] {
+			if err := r.HistoryQ.UpdateHistoryResourceElder(ctx, string(resource), uint32(targetElder)); err != nil {
+				return errors.Wrap(err, "Error in UpdateHistoryResourceElder")
+			}
+		}
+	}
+
+	// the order book snapshots can't be reingested, they are reaped with the
+	// ledgers
+	if ledgersCount > 0 {
+		if targetElder := (latest.HistoryLatest - int32(ledgersCount)) + 1; targetElder > 0 {
+			deleted, err := r.HistoryQ.DeleteOrderBookSnapshotsBefore(ctx, uint32(targetElder))
+			if err != nil {
+				return errors.Wrap(err, "Error clearing order book snapshots")
+			}
+			log.WithField("deleted", deleted).Info("reaper: cleared order book snapshots")
+		}
+	}
+
+	log.Info("reaper succeeded")
+	return nil
+}
+
+func (r *System) retention() ledger.HistoryRetention {
+	return ledger.HistoryRetention{
+		Count:      r.RetentionCount,
+		ByResource: r.RetentionCountByResource,
+	}
+}
+
+// deleteBefore removes the rows of the given tables for the ledgers before
+// targetElder.
+func (r *System) deleteBefore(ctx context.Context, targetElder int32, tables []string) error {
+	if r.ColdStorage != nil {
+		if err := r.exportBefore(ctx, targetElder, tables); err != nil {
+			return err
+		}
+	}
+
+	err := r.dropPartitionsBefore(ctx, targetElder, tables)
+	if err != nil {
+		return err
+	}
 
-	if targetElder < latest.HistoryElder {
+	elder, err := r.tablesElder(ctx, tables)
+	if err != nil {
+		return err
+	}
+	if elder == 0 || targetElder <= elder {
 		return nil
 	}
 
-	err := r.clearBefore(ctx, latest.HistoryElder, targetElder)
+	err = r.clearBefore(ctx, elder, targetElder, tables)
 	if err != nil {
 		return err
 	}
 
 	log.
 		WithField("new_elder", targetElder).
-		Info("reaper succeeded")
+		WithField("tables", tables).
+		Info("reaper: cleared tables")
+	return nil
+}
+
+// exportBefore exports the rows of the given tables for the ledgers before
+// targetElder to cold storage, skipping the ledgers exported by a previous run.
+func (r *System) exportBefore(ctx context.Context, targetElder int32, tables []string) error {
+	elder, err := r.tablesElder(ctx, tables)
+	if err != nil {
+		return err
+	}
+	if elder == 0 || targetElder <= elder {
+		return nil
+	}
+
+	exported, err := r.ColdStorage.ExportedLedger(tables)
+	if err != nil {
+		return errors.Wrap(err, "Error in ExportedLedger")
+	}
+	startSeq := elder
+	if int32(exported) >= startSeq {
+		startSeq = int32(exported) + 1
+	}
 
+	for batchStartSeq := startSeq; batchStartSeq < targetElder; batchStartSeq += batchSize {
+		batchEndSeq := batchStartSeq + batchSize - 1
+		if batchEndSeq >= targetElder {
+			batchEndSeq = targetElder - 1
+		}
+		log.WithField("start_ledger", batchStartSeq).WithField("end_ledger", batchEndSeq).Info("reaper: exporting")
+
+		_, err = r.ColdStorage.ExportRange(ctx, r.HistoryQ, uint32(batchStartSeq), uint32(batchEndSeq), tables)
+		if err != nil {
+			return errors.Wrap(err, "Error in ExportRange")
+		}
+	}
 	return nil
 }
 
+// tablesElder returns the oldest ledger with rows in any of the tables, or 0
+// if all the tables are empty.
+func (r *System) tablesElder(ctx context.Context, tables []string) (int32, error) {
+	var elder int32
+	for _, table := range tables {
+		tableElder, err := r.HistoryQ.HistoryTableElderLedger(ctx, table)
+		if err != nil {
+			return 0, errors.Wrap(err, "Error in HistoryTableElderLedger")
+		}
+		if tableElder > 0 && (elder == 0 || tableElder < elder) {
+			elder = tableElder
+		}
+	}
+	return elder, nil
+}
+
 // Run triggers the reaper system to update itself, deleted unretained history
 // if it is the appropriate time.
 func (r *System) Run() {
+	partitionsTicker := time.NewTicker(partitionsInterval)
+	defer partitionsTicker.Stop()
+	if r.MaintainPartitions {
+		r.maintainPartitionsOnce(r.ctx)
+	}
+
+	reapTimer := time.After(1 * time.Hour)
 	for {
 		select {
-		case <-time.After(1 * time.Hour):
+		case <-reapTimer:
 			r.runOnce(r.ctx)
+			reapTimer = time.After(1 * time.Hour)
+		case <-partitionsTicker.C:
+			if r.MaintainPartitions {
+				r.maintainPartitionsOnce(r.ctx)
+			}
 		case <-r.ctx.Done():
 			return
 		}
@@ -70,6 +243,76 @@ func (r *System) runOnce(ctx context.Context) {
 	}
 }
 
+func (r *System) maintainPartitionsOnce(ctx context.Context) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err := herrors.FromPanic(rec)
+			log.Errorf("reaper panicked: %s", err)
+			herrors.ReportToSentry(err, nil)
+		}
+	}()
+
+	err := r.CreateUpcomingPartitions(ctx)
+	if err != nil {
+		log.Errorf("reaper failed to create history partitions: %s", err)
+	}
+}
+
+// partitionsAhead is the number of partitions created ahead of the latest
+// ingested ledger so that ingestion never has to wait for a partition.
+var partitionsAhead = uint32(2)
+var partitionsInterval = 1 * time.Minute
+
+// CreateUpcomingPartitions creates the partitions of the history tables for
+// the ledgers following the latest ingested ledger. It does nothing if the
+// history tables are not partitioned.
+func (r *System) CreateUpcomingPartitions(ctx context.Context) error {
+	partitionSize, err := r.HistoryQ.GetHistoryPartitionSize(ctx)
+	if err != nil || partitionSize == 0 {
+		return err
+	}
+
+	latest := uint32(r.ledgerState.CurrentStatus().HistoryLatest)
+	err = r.HistoryQ.Begin(ctx)
+	if err != nil {
+		return errors.Wrap(err, "Error in begin")
+	}
+	defer r.HistoryQ.Rollback()
+
+	err = r.HistoryQ.CreateHistoryPartitions(ctx, latest, latest+partitionsAhead*partitionSize)
+	if err != nil {
+		return errors.Wrap(err, "Error in CreateHistoryPartitions")
+	}
+
+	return errors.Wrap(r.HistoryQ.Commit(), "Error in commit")
+}
+
+// dropPartitionsBefore drops the partitions of the tables which only contain
+// ledgers before endSeq. Dropping a partition is much cheaper than deleting
+// its rows, clearBefore only needs to delete the remaining rows.
+func (r *System) dropPartitionsBefore(ctx context.Context, endSeq int32, tables []string) error {
+	err := r.HistoryQ.Begin(ctx)
+	if err != nil {
+		return errors.Wrap(err, "Error in begin")
+	}
+	defer r.HistoryQ.Rollback()
+
+	dropped, err := r.HistoryQ.DropHistoryPartitionsBefore(ctx, uint32(endSeq), tables)
+	if err != nil {
+		return errors.Wrap(err, "Error in DropHistoryPartitionsBefore")
+	}
+
+	err = r.HistoryQ.Commit()
+	if err != nil {
+		return errors.Wrap(err, "Error in commit")
+	}
+
+	if dropped > 0 {
+		log.WithField("partitions", dropped).WithField("end_ledger", endSeq).Info("reaper: dropped history partitions")
+	}
+	return nil
+}
+
 // Work backwards in 100k ledger blocks to prevent using all the CPU.
 //
 // This runs every hour, so we need to make sure it doesn't
@@ -82,7 +325,7 @@ func (r *System) runOnce(ctx context.Context) {
 var batchSize = int32(100_000)
 var sleep = 1 * time.Second
 
-func (r *System) clearBefore(ctx context.Context, startSeq, endSeq int32) error {
+func (r *System) clearBefore(ctx context.Context, startSeq, endSeq int32, tables []string) error {
 	for batchEndSeq := endSeq - 1; batchEndSeq >= startSeq; batchEndSeq -= batchSize {
 		batchStartSeq := batchEndSeq - batchSize
 		if batchStartSeq < startSeq {
@@ -101,9 +344,9 @@ func (r *System) clearBefore(ctx context.Context, startSeq, endSeq int32) error
 		}
 		defer r.HistoryQ.Rollback()
 
-		err = r.HistoryQ.DeleteRangeAll(ctx, batchStart, batchEnd)
+		err = r.HistoryQ.DeleteRangeTables(ctx, batchStart, batchEnd, tables)
 		if err != nil {
-			return errors.Wrap(err, "Error in DeleteRangeAll")
+			return errors.Wrap(err, "Error in DeleteRangeTables")
 		}
 
 		err = r.HistoryQ.Commit()
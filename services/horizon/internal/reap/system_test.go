@@ -52,3 +52,59 @@ func TestDeleteUnretainedHistory(t *testing.T) {
 		tt.Assert.Equal(1, cur)
 	}
 }
+
+func TestDeleteUnretainedHistoryByResource(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	ledgerState := &ledger.State{}
+	ledgerState.SetStatus(tt.Scenario("kahuna"))
+
+	db := tt.HorizonSession()
+
+	sys := New(10, db, ledgerState)
+	sys.RetentionCountByResource = map[ledger.HistoryResource]uint{
+		ledger.HistoryResourceEffects: 1,
+		ledger.HistoryResourceTrades:  0,
+	}
+
+	// Disable sleeps for this.
+	sleep = 0
+
+	var ledgersBefore, ledgersAfter, tradesBefore, tradesAfter int
+	err := db.GetRaw(tt.Ctx, &ledgersBefore, `SELECT COUNT(*) FROM history_ledgers`)
+	tt.Require.NoError(err)
+	err = db.GetRaw(tt.Ctx, &tradesBefore, `SELECT COUNT(*) FROM history_trades`)
+	tt.Require.NoError(err)
+
+	err = sys.DeleteUnretainedHistory(tt.Ctx)
+	tt.Require.NoError(err)
+
+	// the ledgers are retained for the longest retention, trades are never
+	// reaped
+	err = db.GetRaw(tt.Ctx, &ledgersAfter, `SELECT COUNT(*) FROM history_ledgers`)
+	tt.Require.NoError(err)
+	tt.Assert.Equal(ledgersBefore, ledgersAfter)
+	err = db.GetRaw(tt.Ctx, &tradesAfter, `SELECT COUNT(*) FROM history_trades`)
+	tt.Require.NoError(err)
+	tt.Assert.Equal(tradesBefore, tradesAfter)
+
+	elder, err := sys.HistoryQ.HistoryTableElderLedger(tt.Ctx, "history_effects")
+	tt.Require.NoError(err)
+	tt.Assert.True(elder == 0 || elder == ledgerState.CurrentStatus().HistoryLatest)
+
+	elder, err = sys.HistoryQ.HistoryTableElderLedger(tt.Ctx, "history_operations")
+	tt.Require.NoError(err)
+	tt.Assert.GreaterOrEqual(elder, ledgerState.CurrentStatus().HistoryLatest-9)
+
+	// the elders of the reaped resources are recorded for the API
+	latest := uint32(ledgerState.CurrentStatus().HistoryLatest)
+	recorded, err := sys.HistoryQ.GetHistoryResourceElder(tt.Ctx, string(ledger.HistoryResourceEffects))
+	tt.Require.NoError(err)
+	tt.Assert.Equal(latest, recorded)
+	recorded, err = sys.HistoryQ.GetHistoryResourceElder(tt.Ctx, string(ledger.HistoryResourceOperations))
+	tt.Require.NoError(err)
+	tt.Assert.Equal(latest-9, recorded)
+	recorded, err = sys.HistoryQ.GetHistoryResourceElder(tt.Ctx, string(ledger.HistoryResourceTrades))
+	tt.Require.NoError(err)
+	tt.Assert.Equal(uint32(0), recorded)
+}
@@ -103,6 +103,18 @@ var (
 			"this horizon instance.",
 	}
 
+	// HistoryPruned is a well-known problem type.  Use it as a shortcut
+	// in your actions.
+	HistoryPruned = problem.P{
+		Type:   "history_pruned",
+		Title:  "Data Requested Has Been Pruned For This Resource",
+		Status: http.StatusGone,
+		Detail: "This horizon instance is configured to retain the history of " +
+			"this resource for fewer ledgers than the rest of its history. This " +
+			"request is asking for results prior to the retained history of " +
+			"the resource.",
+	}
+
 	// StaleHistory is a well-known problem type.  Use it as a shortcut
 	// in your actions.
 	StaleHistory = problem.P{
@@ -0,0 +1,156 @@
+package resourceadapter
+
+import (
+	"context"
+	"math"
+	"sort"
+
+	"github.com/guregu/null"
+
+	protocol "github.com/pownieh/stellar_go/protocols/horizon"
+	"github.com/pownieh/stellar_go/services/horizon/internal/db2/history"
+	"github.com/pownieh/stellar_go/services/horizon/internal/operationfeestats"
+)
+
+// PopulateLedgerFeeStats fills out the fee stats of a ledger.
+func PopulateLedgerFeeStats(ctx context.Context, dest *protocol.LedgerFeeStats, row history.LedgerFeeStatsRow) {
+	dest.PT = row.PagingToken()
+	dest.Ledger = uint32(row.Sequence)
+	dest.ClosedAt = row.ClosedAt
+	dest.BaseFee = int64(row.BaseFee)
+	if row.TxSetOperationCount != nil && row.MaxTxSetSize > 0 {
+		usage := float64(*row.TxSetOperationCount) / float64(row.MaxTxSetSize)
+		dest.LedgerCapacityUsage = math.Round(usage*100) / 100
+	}
+
+	dest.TransactionCount = row.TransactionCount
+	if row.TransactionCount > 0 {
+		dest.FeeCharged = ledgerFeeDistribution(
+			row.FeeChargedMin, row.FeeChargedP10, row.FeeChargedP50,
+			row.FeeChargedP90, row.FeeChargedP99, row.FeeChargedMax,
+		)
+		dest.MaxFee = ledgerFeeDistribution(
+			row.MaxFeeMin, row.MaxFeeP10, row.MaxFeeP50,
+			row.MaxFeeP90, row.MaxFeeP99, row.MaxFeeMax,
+		)
+	} else {
+		// like /fee_stats, the fees of a ledger without transactions are the
+		// base fee
+		baseFee := int64(row.BaseFee)
+		dest.FeeCharged = protocol.LedgerFeeDistribution{
+			Max: baseFee, Min: baseFee, P10: baseFee, P50: baseFee, P90: baseFee, P99: baseFee,
+		}
+		dest.MaxFee = dest.FeeCharged
+	}
+
+	dest.SorobanTransactionCount = row.SorobanTransactionCount
+	dest.SorobanFeeCharged = protocol.LedgerFeeDistribution{}
+	dest.SorobanRefundableFee = protocol.LedgerFeeDistribution{}
+	if row.SorobanTransactionCount > 0 {
+		dest.SorobanFeeCharged = ledgerFeeDistribution(
+			row.SorobanFeeChargedMin, row.SorobanFeeChargedP10, row.SorobanFeeChargedP50,
+			row.SorobanFeeChargedP90, row.SorobanFeeChargedP99, row.SorobanFeeChargedMax,
+		)
+		dest.SorobanRefundableFee = ledgerFeeDistribution(
+			row.SorobanRefundableFeeMin, row.SorobanRefundableFeeP10, row.SorobanRefundableFeeP50,
+			row.SorobanRefundableFeeP90, row.SorobanRefundableFeeP99, row.SorobanRefundableFeeMax,
+		)
+	}
+}
+
+func ledgerFeeDistribution(min, p10, p50, p90, p99, max null.Int) protocol.LedgerFeeDistribution {
+	return protocol.LedgerFeeDistribution{
+		Max: max.Int64,
+		Min: min.Int64,
+		P10: p10.Int64,
+		P50: p50.Int64,
+		P90: p90.Int64,
+		P99: p99.Int64,
+	}
+}
+
+// PopulateFeeEstimate fills out the fee to bid for a transaction with the
+// given number of operations to be included with the given probability within
+// the given number of ledgers, given the fee stats of the recent ledgers
+// (latest first).
+func PopulateFeeEstimate(
+	ctx context.Context,
+	dest *protocol.FeeEstimate,
+	rows []history.LedgerFeeStatsRow,
+	probability float64,
+	withinLedgers uint32,
+	operations int64,
+) {
+	dest.Ledgers = uint32(len(rows))
+	dest.Probability = probability
+	dest.WithinLedgers = withinLedgers
+	if len(rows) == 0 {
+		return
+	}
+	dest.LastLedger = uint32(rows[0].Sequence)
+	dest.LastLedgerBaseFee = int64(rows[0].BaseFee)
+
+	inclusionFees := make([]int64, 0, len(rows))
+	var sorobanLedgers []protocol.LedgerFeeStats
+	for _, row := range rows {
+		var ledgerFees protocol.LedgerFeeStats
+		PopulateLedgerFeeStats(ctx, &ledgerFees, row)
+		// the lowest fee charged is the base fee unless the ledger was surge
+		// priced
+		inclusionFees = append(inclusionFees, ledgerFees.FeeCharged.Min)
+		if ledgerFees.SorobanTransactionCount > 0 {
+			sorobanLedgers = append(sorobanLedgers, ledgerFees)
+			dest.Soroban.TransactionCount += int64(ledgerFees.SorobanTransactionCount)
+		}
+	}
+	dest.InclusionFee = operationfeestats.EstimateInclusionFee(inclusionFees, probability, withinLedgers)
+	if dest.InclusionFee < dest.LastLedgerBaseFee {
+		dest.InclusionFee = dest.LastLedgerBaseFee
+	}
+	dest.MaxFee = dest.InclusionFee * operations
+
+	dest.Soroban.FeeCharged = mergeLedgerFeeDistributions(sorobanLedgers, func(l protocol.LedgerFeeStats) protocol.LedgerFeeDistribution {
+		return l.SorobanFeeCharged
+	})
+	dest.Soroban.RefundableFee = mergeLedgerFeeDistributions(sorobanLedgers, func(l protocol.LedgerFeeStats) protocol.LedgerFeeDistribution {
+		return l.SorobanRefundableFee
+	})
+}
+
+// mergeLedgerFeeDistributions summarizes the fee distributions of several
+// ledgers: the minimum and maximum are the ones of all the ledgers and each
+// percentile is the median of the percentiles of the ledgers.
+func mergeLedgerFeeDistributions(
+	ledgers []protocol.LedgerFeeStats,
+	distribution func(protocol.LedgerFeeStats) protocol.LedgerFeeDistribution,
+) protocol.LedgerFeeDistribution {
+	var merged protocol.LedgerFeeDistribution
+	if len(ledgers) == 0 {
+		return merged
+	}
+
+	var p10, p50, p90, p99 []int64
+	for i, ledger := range ledgers {
+		d := distribution(ledger)
+		if i == 0 || d.Min < merged.Min {
+			merged.Min = d.Min
+		}
+		if d.Max > merged.Max {
+			merged.Max = d.Max
+		}
+		p10 = append(p10, d.P10)
+		p50 = append(p50, d.P50)
+		p90 = append(p90, d.P90)
+		p99 = append(p99, d.P99)
+	}
+	merged.P10 = median(p10)
+	merged.P50 = median(p50)
+	merged.P90 = median(p90)
+	merged.P99 = median(p99)
+	return merged
+}
+
+func median(values []int64) int64 {
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	return values[(len(values)-1)/2]
+}
@@ -0,0 +1,129 @@
+package resourceadapter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/guregu/null"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pownieh/stellar_go/protocols/horizon"
+	"github.com/pownieh/stellar_go/services/horizon/internal/db2/history"
+	"github.com/pownieh/stellar_go/toid"
+)
+
+func ledgerFeeStatsRow(sequence int32, minFee int64, sorobanFees ...int64) history.LedgerFeeStatsRow {
+	opCount := int32(50)
+	row := history.LedgerFeeStatsRow{
+		TotalOrderID:        history.TotalOrderID{ID: toid.New(sequence, 0, 0).ToInt64()},
+		Sequence:            sequence,
+		ClosedAt:            time.Unix(1700000000, 0).UTC(),
+		BaseFee:             100,
+		MaxTxSetSize:        1000,
+		TxSetOperationCount: &opCount,
+	}
+	if minFee > 0 {
+		row.TransactionCount = 10
+		row.FeeChargedMin = null.IntFrom(minFee)
+		row.FeeChargedP10 = null.IntFrom(minFee)
+		row.FeeChargedP50 = null.IntFrom(minFee)
+		row.FeeChargedP90 = null.IntFrom(minFee * 2)
+		row.FeeChargedP99 = null.IntFrom(minFee * 2)
+		row.FeeChargedMax = null.IntFrom(minFee * 3)
+		row.MaxFeeMin = null.IntFrom(minFee)
+		row.MaxFeeP10 = null.IntFrom(minFee)
+		row.MaxFeeP50 = null.IntFrom(minFee * 10)
+		row.MaxFeeP90 = null.IntFrom(minFee * 10)
+		row.MaxFeeP99 = null.IntFrom(minFee * 10)
+		row.MaxFeeMax = null.IntFrom(minFee * 100)
+	}
+	if len(sorobanFees) > 0 {
+		row.SorobanTransactionCount = int32(len(sorobanFees))
+		row.SorobanFeeChargedMin = null.IntFrom(sorobanFees[0])
+		row.SorobanFeeChargedP10 = null.IntFrom(sorobanFees[0])
+		row.SorobanFeeChargedP50 = null.IntFrom(sorobanFees[len(sorobanFees)/2])
+		row.SorobanFeeChargedP90 = null.IntFrom(sorobanFees[len(sorobanFees)-1])
+		row.SorobanFeeChargedP99 = null.IntFrom(sorobanFees[len(sorobanFees)-1])
+		row.SorobanFeeChargedMax = null.IntFrom(sorobanFees[len(sorobanFees)-1])
+		row.SorobanRefundableFeeMin = null.IntFrom(10)
+		row.SorobanRefundableFeeP10 = null.IntFrom(10)
+		row.SorobanRefundableFeeP50 = null.IntFrom(20)
+		row.SorobanRefundableFeeP90 = null.IntFrom(30)
+		row.SorobanRefundableFeeP99 = null.IntFrom(30)
+		row.SorobanRefundableFeeMax = null.IntFrom(30)
+	}
+	return row
+}
+
+func TestPopulateLedgerFeeStats(t *testing.T) {
+	var dest horizon.LedgerFeeStats
+	PopulateLedgerFeeStats(context.Background(), &dest, ledgerFeeStatsRow(10, 200, 5000, 7000))
+
+	assert.Equal(t, toid.New(10, 0, 0).String(), dest.PagingToken())
+	assert.Equal(t, uint32(10), dest.Ledger)
+	assert.Equal(t, int64(100), dest.BaseFee)
+	assert.Equal(t, 0.05, dest.LedgerCapacityUsage)
+	assert.Equal(t, int32(10), dest.TransactionCount)
+	assert.Equal(t, horizon.LedgerFeeDistribution{
+		Max: 600, Min: 200, P10: 200, P50: 200, P90: 400, P99: 400,
+	}, dest.FeeCharged)
+	assert.Equal(t, int64(20000), dest.MaxFee.Max)
+	assert.Equal(t, int32(2), dest.SorobanTransactionCount)
+	assert.Equal(t, horizon.LedgerFeeDistribution{
+		Max: 7000, Min: 5000, P10: 5000, P50: 7000, P90: 7000, P99: 7000,
+	}, dest.SorobanFeeCharged)
+	assert.Equal(t, int64(20), dest.SorobanRefundableFee.P50)
+}
+
+func TestPopulateLedgerFeeStatsWithoutTransactions(t *testing.T) {
+	var dest horizon.LedgerFeeStats
+	PopulateLedgerFeeStats(context.Background(), &dest, ledgerFeeStatsRow(10, 0))
+
+	baseFee := horizon.LedgerFeeDistribution{
+		Max: 100, Min: 100, P10: 100, P50: 100, P90: 100, P99: 100,
+	}
+	assert.Equal(t, int32(0), dest.TransactionCount)
+	assert.Equal(t, baseFee, dest.FeeCharged)
+	assert.Equal(t, baseFee, dest.MaxFee)
+	assert.Equal(t, int32(0), dest.SorobanTransactionCount)
+	assert.Equal(t, horizon.LedgerFeeDistribution{}, dest.SorobanFeeCharged)
+}
+
+func TestPopulateFeeEstimate(t *testing.T) {
+	// latest ledger first, one ledger out of 4 was surge priced
+	rows := []history.LedgerFeeStatsRow{
+		ledgerFeeStatsRow(14, 100, 3000),
+		ledgerFeeStatsRow(13, 0),
+		ledgerFeeStatsRow(12, 1000, 1000, 2000, 9000),
+		ledgerFeeStatsRow(11, 100, 4000, 5000),
+	}
+
+	var dest horizon.FeeEstimate
+	PopulateFeeEstimate(context.Background(), &dest, rows, 0.9, 1, 3)
+	assert.Equal(t, uint32(14), dest.LastLedger)
+	assert.Equal(t, int64(100), dest.LastLedgerBaseFee)
+	assert.Equal(t, uint32(4), dest.Ledgers)
+	assert.Equal(t, 0.9, dest.Probability)
+	assert.Equal(t, uint32(1), dest.WithinLedgers)
+	assert.Equal(t, int64(1000), dest.InclusionFee)
+	assert.Equal(t, int64(3000), dest.MaxFee)
+
+	assert.Equal(t, int64(6), dest.Soroban.TransactionCount)
+	assert.Equal(t, horizon.LedgerFeeDistribution{
+		Max: 9000, Min: 1000, P10: 3000, P50: 3000, P90: 5000, P99: 5000,
+	}, dest.Soroban.FeeCharged)
+	assert.Equal(t, int64(20), dest.Soroban.RefundableFee.P50)
+
+	// within 4 ledgers, a bid covering half of the ledgers is enough
+	PopulateFeeEstimate(context.Background(), &dest, rows, 0.9, 4, 1)
+	assert.Equal(t, int64(100), dest.InclusionFee)
+	assert.Equal(t, int64(100), dest.MaxFee)
+}
+
+func TestPopulateFeeEstimateWithoutLedgers(t *testing.T) {
+	var dest horizon.FeeEstimate
+	PopulateFeeEstimate(context.Background(), &dest, nil, 0.9, 1, 1)
+	assert.Equal(t, uint32(0), dest.Ledgers)
+	assert.Equal(t, int64(0), dest.MaxFee)
+}
@@ -0,0 +1,98 @@
+package resourceadapter
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/pownieh/stellar_go/amount"
+	protocol "github.com/pownieh/stellar_go/protocols/horizon"
+	"github.com/pownieh/stellar_go/services/horizon/internal/db2/history"
+	"github.com/pownieh/stellar_go/support/errors"
+)
+
+const yearMillis = int64(365 * 24 * time.Hour / time.Millisecond)
+
+// PopulateLiquidityPoolStats fills out the stats of a liquidity pool over a
+// window of `resolution` milliseconds using a row of the liquidity pool stats.
+// liquidityPool is nil when the pool was removed, the assets of the amounts
+// are unknown then and left empty.
+func PopulateLiquidityPoolStats(
+	ctx context.Context,
+	dest *protocol.LiquidityPoolStats,
+	liquidityPool *history.LiquidityPool,
+	row history.LiquidityPoolStats,
+	resolution int64,
+) error {
+	var assetA, assetB string
+	if liquidityPool != nil {
+		if len(liquidityPool.AssetReserves) != 2 {
+			return errors.Errorf("unexpected number of reserves: %d", len(liquidityPool.AssetReserves))
+		}
+		assetA = liquidityPool.AssetReserves[0].Asset.StringCanonical()
+		assetB = liquidityPool.AssetReserves[1].Asset.StringCanonical()
+	}
+
+	dest.Timestamp = row.Timestamp
+	dest.TradeCount = row.TradeCount
+	amounts := []struct {
+		dest *[]protocol.LiquidityPoolReserve
+		a, b string
+	}{
+		{dest: &dest.Volume, a: row.VolumeA, b: row.VolumeB},
+		{dest: &dest.Fees, a: row.FeesA, b: row.FeesB},
+	}
+	for _, a := range amounts {
+		amountA, err := amount.IntStringToAmount(a.a)
+		if err != nil {
+			return err
+		}
+		amountB, err := amount.IntStringToAmount(a.b)
+		if err != nil {
+			return err
+		}
+		*a.dest = []protocol.LiquidityPoolReserve{
+			{Asset: assetA, Amount: amountA},
+			{Asset: assetB, Amount: amountB},
+		}
+	}
+
+	dest.Reserves = []protocol.LiquidityPoolReserve{
+		{Asset: assetA, Amount: amount.StringFromInt64(row.ReserveA)},
+		{Asset: assetB, Amount: amount.StringFromInt64(row.ReserveB)},
+	}
+	dest.TotalShares = amount.StringFromInt64(row.TotalShares)
+
+	// the price of a pool share in each asset, the shares and the reserves
+	// are both in stroops
+	sharePrice := func(reserve int64) string {
+		if row.TotalShares == 0 {
+			return new(big.Rat).FloatString(7)
+		}
+		return big.NewRat(reserve, row.TotalShares).FloatString(7)
+	}
+	dest.SharePrice = []protocol.LiquidityPoolReserve{
+		{Asset: assetA, Amount: sharePrice(row.ReserveA)},
+		{Asset: assetB, Amount: sharePrice(row.ReserveB)},
+	}
+
+	// Both reserves of a constant product pool have the same value so the
+	// yield of the fees is the average of the yield in each asset.
+	yield := new(big.Rat)
+	for _, f := range []struct {
+		fees    string
+		reserve int64
+	}{{row.FeesA, row.ReserveA}, {row.FeesB, row.ReserveB}} {
+		if f.reserve == 0 {
+			continue
+		}
+		fees, ok := new(big.Rat).SetString(f.fees)
+		if !ok {
+			return errors.Errorf("invalid fees %s", f.fees)
+		}
+		yield.Add(yield, fees.Quo(fees, big.NewRat(f.reserve, 1)))
+	}
+	yield.Mul(yield, big.NewRat(yearMillis, 2*resolution))
+	dest.FeeAPR = yield.FloatString(7)
+	return nil
+}
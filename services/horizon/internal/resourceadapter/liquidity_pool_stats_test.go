@@ -0,0 +1,62 @@
+package resourceadapter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pownieh/stellar_go/protocols/horizon"
+	"github.com/pownieh/stellar_go/services/horizon/internal/db2/history"
+	"github.com/pownieh/stellar_go/xdr"
+)
+
+func TestPopulateLiquidityPoolStats(t *testing.T) {
+	usdc := xdr.MustNewCreditAsset("USDC", "GC3C4AKRBQLHOJ45U4XG35ESVWRDECWO5XLDGYADO6DPR3L7KIDVUMML")
+	pool := history.MakeTestPool(xdr.MustNewNativeAsset(), 100, usdc, 200)
+	row := history.LiquidityPoolStats{
+		Timestamp:   1700000000000,
+		TradeCount:  3,
+		VolumeA:     "1000000000",
+		VolumeB:     "2100000000",
+		FeesA:       "3000000",
+		FeesB:       "0",
+		ReserveA:    10000000000,
+		ReserveB:    20000000000,
+		TotalShares: 14142135623,
+	}
+
+	var dest horizon.LiquidityPoolStats
+	assert.NoError(t, PopulateLiquidityPoolStats(context.Background(), &dest, &pool, row, 86400000))
+	assert.Equal(t, horizon.LiquidityPoolStats{
+		Timestamp:  1700000000000,
+		TradeCount: 3,
+		Volume: []horizon.LiquidityPoolReserve{
+			{Asset: "native", Amount: "100.0000000"},
+			{Asset: usdc.StringCanonical(), Amount: "210.0000000"},
+		},
+		Fees: []horizon.LiquidityPoolReserve{
+			{Asset: "native", Amount: "0.3000000"},
+			{Asset: usdc.StringCanonical(), Amount: "0.0000000"},
+		},
+		Reserves: []horizon.LiquidityPoolReserve{
+			{Asset: "native", Amount: "1000.0000000"},
+			{Asset: usdc.StringCanonical(), Amount: "2000.0000000"},
+		},
+		TotalShares: "1414.2135623",
+		SharePrice: []horizon.LiquidityPoolReserve{
+			{Asset: "native", Amount: "0.7071068"},
+			{Asset: usdc.StringCanonical(), Amount: "1.4142136"},
+		},
+		FeeAPR: "0.0547500",
+	}, dest)
+
+	// the assets of a removed pool are unknown
+	dest = horizon.LiquidityPoolStats{}
+	assert.NoError(t, PopulateLiquidityPoolStats(context.Background(), &dest, nil, row, 86400000))
+	assert.Equal(t, []horizon.LiquidityPoolReserve{
+		{Asset: "", Amount: "1000.0000000"},
+		{Asset: "", Amount: "2000.0000000"},
+	}, dest.Reserves)
+	assert.Equal(t, "0.0547500", dest.FeeAPR)
+}
@@ -3,11 +3,14 @@ package resourceadapter
 import (
 	"context"
 	"fmt"
+	"math/big"
 	"strings"
 
 	"github.com/pownieh/stellar_go/amount"
 	"github.com/pownieh/stellar_go/protocols/horizon"
+	"github.com/pownieh/stellar_go/protocols/horizon/operations"
 	"github.com/pownieh/stellar_go/services/horizon/internal/paths"
+	"github.com/pownieh/stellar_go/xdr"
 )
 
 func extractAsset(asset string, t, c, i *string) error {
@@ -61,3 +64,136 @@ func PopulatePath(ctx context.Context, dest *horizon.Path, p paths.Path) (err er
 	}
 	return
 }
+
+// PopulateSplitPath converts the paths.SplitPath into a SplitPath
+func PopulateSplitPath(ctx context.Context, dest *horizon.SplitPath, p paths.SplitPath, strictSend bool) (err error) {
+	dest.OperationType = operations.TypeNames[xdr.OperationTypePathPaymentStrictReceive]
+	if strictSend {
+		dest.OperationType = operations.TypeNames[xdr.OperationTypePathPaymentStrictSend]
+	}
+	dest.DestinationAmount = amount.String(p.DestinationAmount)
+	dest.SourceAmount = amount.String(p.SourceAmount)
+
+	err = extractAsset(
+		p.Source,
+		&dest.SourceAssetType,
+		&dest.SourceAssetCode,
+		&dest.SourceAssetIssuer)
+	if err != nil {
+		return
+	}
+
+	err = extractAsset(
+		p.Destination,
+		&dest.DestinationAssetType,
+		&dest.DestinationAssetCode,
+		&dest.DestinationAssetIssuer)
+	if err != nil {
+		return
+	}
+
+	dest.Legs = make([]horizon.Path, len(p.Legs))
+	for i, leg := range p.Legs {
+		if err = PopulatePath(ctx, &dest.Legs[i], leg); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// Venues of a Quote.
+const (
+	QuoteVenueOrderBook     = "order_book"
+	QuoteVenueLiquidityPool = "liquidity_pool"
+)
+
+// PopulateQuote converts the paths.Quote into a Quote
+func PopulateQuote(ctx context.Context, dest *horizon.Quote, q paths.Quote, strictSend bool) (err error) {
+	dest.OperationType = operations.TypeNames[xdr.OperationTypePathPaymentStrictReceive]
+	if strictSend {
+		dest.OperationType = operations.TypeNames[xdr.OperationTypePathPaymentStrictSend]
+	}
+	dest.DestinationAmount = amount.String(q.DestinationAmount)
+	dest.SourceAmount = amount.String(q.SourceAmount)
+
+	err = extractAsset(
+		q.Source,
+		&dest.SourceAssetType,
+		&dest.SourceAssetCode,
+		&dest.SourceAssetIssuer)
+	if err != nil {
+		return
+	}
+
+	err = extractAsset(
+		q.Destination,
+		&dest.DestinationAssetType,
+		&dest.DestinationAssetCode,
+		&dest.DestinationAssetIssuer)
+	if err != nil {
+		return
+	}
+
+	averagePrice := big.NewRat(int64(q.SourceAmount), int64(q.DestinationAmount))
+	dest.AveragePrice = averagePrice.FloatString(7)
+	if q.MidPrice != nil && q.MidPrice.Sign() > 0 {
+		dest.MidPrice = q.MidPrice.FloatString(7)
+		impact := new(big.Rat).Sub(averagePrice, q.MidPrice)
+		dest.PriceImpact = impact.Quo(impact, q.MidPrice).FloatString(7)
+	}
+
+	dest.Offers = make([]horizon.QuoteOffer, len(q.Offers))
+	for i, offer := range q.Offers {
+		dest.Offers[i] = populateQuoteOffer(offer)
+	}
+
+	if q.Pool == nil {
+		dest.Venue = QuoteVenueOrderBook
+		if len(q.Offers) > 0 {
+			// the price of the last unit bought is the price of the last offer
+			dest.ExecutionPrice = dest.Offers[len(dest.Offers)-1].Price
+		}
+		return
+	}
+
+	dest.Venue = QuoteVenueLiquidityPool
+	details := q.Pool.Pool.Body.MustConstantProduct()
+	pool := populateQuoteLiquidityPool(*q.Pool)
+	dest.LiquidityPool = &pool
+	// the price of the last unit bought is the spot price of the pool after
+	// the trade
+	sourceReserve, destinationReserve := q.Pool.ReserveA, q.Pool.ReserveB
+	if details.Params.AssetA.String() != q.Source {
+		sourceReserve, destinationReserve = destinationReserve, sourceReserve
+	}
+	if destinationReserve > 0 {
+		dest.ExecutionPrice = big.NewRat(int64(sourceReserve), int64(destinationReserve)).FloatString(7)
+	}
+	return
+}
+
+func populateQuoteOffer(offer paths.QuoteOffer) horizon.QuoteOffer {
+	return horizon.QuoteOffer{
+		ID:     int64(offer.Offer.OfferId),
+		Seller: offer.Offer.SellerId.Address(),
+		PriceR: horizon.Price{N: int32(offer.Offer.Price.N), D: int32(offer.Offer.Price.D)},
+		Price:  offer.Offer.Price.String(),
+		Amount: amount.String(offer.Amount),
+	}
+}
+
+func populateQuoteLiquidityPool(pool paths.QuotePool) horizon.QuoteLiquidityPool {
+	details := pool.Pool.Body.MustConstantProduct()
+	return horizon.QuoteLiquidityPool{
+		ID:    xdr.Hash(pool.Pool.LiquidityPoolId).HexString(),
+		FeeBP: uint32(details.Params.Fee),
+		Reserves: []horizon.LiquidityPoolReserve{
+			{Asset: details.Params.AssetA.StringCanonical(), Amount: amount.String(details.ReserveA)},
+			{Asset: details.Params.AssetB.StringCanonical(), Amount: amount.String(details.ReserveB)},
+		},
+		ReservesAfter: []horizon.LiquidityPoolReserve{
+			{Asset: details.Params.AssetA.StringCanonical(), Amount: amount.String(pool.ReserveA)},
+			{Asset: details.Params.AssetB.StringCanonical(), Amount: amount.String(pool.ReserveB)},
+		},
+	}
+}
@@ -27,6 +27,14 @@ func PopulateRoot(
 	dest.HorizonSequence = ledgerState.HistoryLatest
 	dest.HorizonLatestClosedAt = ledgerState.HistoryLatestClosedAt
 	dest.HistoryElderSequence = ledgerState.HistoryElder
+	for _, resource := range ledger.HistoryResources {
+		if elder := ledgerState.ResourceElder(resource); elder > ledgerState.HistoryElder {
+			if dest.HistoryElderSequenceByResource == nil {
+				dest.HistoryElderSequenceByResource = map[string]int32{}
+			}
+			dest.HistoryElderSequenceByResource[string(resource)] = elder
+		}
+	}
 	dest.CoreSequence = ledgerState.CoreLatest
 	dest.HorizonVersion = hVersion
 	dest.StellarCoreVersion = cVersion
@@ -0,0 +1,107 @@
+package resourceadapter
+
+import (
+	"context"
+
+	"github.com/pownieh/stellar_go/amount"
+	protocol "github.com/pownieh/stellar_go/protocols/horizon"
+	"github.com/pownieh/stellar_go/services/horizon/internal/db2/history"
+)
+
+// sponsoredEntryTypes is the order in which the entry types are listed.
+var sponsoredEntryTypes = []string{
+	history.SponsoredAccount,
+	history.SponsoredSigner,
+	history.SponsoredTrustLine,
+	history.SponsoredData,
+	history.SponsoredOffer,
+	history.SponsoredClaimableBalance,
+}
+
+// PopulateAccountSponsorships fills out the entries sponsored by `account`
+// using the totals of every entry type and a subset of the entries.
+func PopulateAccountSponsorships(
+	ctx context.Context,
+	dest *protocol.AccountSponsorships,
+	account history.AccountEntry,
+	baseReserve int32,
+	totals []history.SponsorshipTotal,
+	entries []history.SponsoredEntry,
+) {
+	dest.AccountID = account.AccountID
+	dest.BaseReserve = amount.StringFromInt64(int64(baseReserve))
+	dest.NumSponsoring = account.NumSponsoring
+
+	byType := map[string]history.SponsorshipTotal{}
+	for _, total := range totals {
+		byType[total.Type] = total
+		dest.Reserves += total.Reserves
+	}
+	dest.ReserveAmount = reserveAmount(dest.Reserves, baseReserve)
+
+	dest.EntryTypes = []protocol.SponsorshipType{}
+	for _, entryType := range sponsoredEntryTypes {
+		total, ok := byType[entryType]
+		if !ok {
+			continue
+		}
+		group := protocol.SponsorshipType{
+			Type:          entryType,
+			Count:         total.Count,
+			Reserves:      total.Reserves,
+			ReserveAmount: reserveAmount(total.Reserves, baseReserve),
+			Entries:       []protocol.SponsoredEntry{},
+		}
+		for _, entry := range entries {
+			if entry.Type == entryType {
+				group.Entries = append(group.Entries, newSponsoredEntry(entry))
+			}
+		}
+		dest.EntryTypes = append(dest.EntryTypes, group)
+	}
+}
+
+// PopulateAccountSponsors fills out the entries of `account` sponsored by
+// other accounts. The entries must be ordered by sponsor.
+func PopulateAccountSponsors(
+	ctx context.Context,
+	dest *protocol.AccountSponsors,
+	account history.AccountEntry,
+	baseReserve int32,
+	entries []history.SponsoredEntry,
+) {
+	dest.AccountID = account.AccountID
+	dest.BaseReserve = amount.StringFromInt64(int64(baseReserve))
+	dest.NumSponsored = account.NumSponsored
+
+	dest.Sponsors = []protocol.Sponsor{}
+	for _, entry := range entries {
+		if len(dest.Sponsors) == 0 || dest.Sponsors[len(dest.Sponsors)-1].Sponsor != entry.Sponsor {
+			dest.Sponsors = append(dest.Sponsors, protocol.Sponsor{Sponsor: entry.Sponsor})
+		}
+		sponsor := &dest.Sponsors[len(dest.Sponsors)-1]
+		sponsor.Reserves += entry.Reserves
+		resource := newSponsoredEntry(entry)
+		// the entries belong to the account of the resource
+		resource.AccountID = ""
+		sponsor.Entries = append(sponsor.Entries, resource)
+		dest.Reserves += entry.Reserves
+	}
+	for i := range dest.Sponsors {
+		dest.Sponsors[i].ReserveAmount = reserveAmount(dest.Sponsors[i].Reserves, baseReserve)
+	}
+	dest.ReserveAmount = reserveAmount(dest.Reserves, baseReserve)
+}
+
+func newSponsoredEntry(entry history.SponsoredEntry) protocol.SponsoredEntry {
+	return protocol.SponsoredEntry{
+		Type:      entry.Type,
+		AccountID: entry.AccountID,
+		Key:       entry.Key,
+		Reserves:  entry.Reserves,
+	}
+}
+
+func reserveAmount(reserves int64, baseReserve int32) string {
+	return amount.StringFromInt64(reserves * int64(baseReserve))
+}
@@ -0,0 +1,106 @@
+package resourceadapter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pownieh/stellar_go/protocols/horizon"
+	"github.com/pownieh/stellar_go/services/horizon/internal/db2/history"
+)
+
+const (
+	sponsorA   = "GCO26ZSBD63TKYX45H2C7D2WOFWOUSG5BMTNC3BG4QMXM3PAYI6WHKVZ"
+	sponsorB   = "GC3C4AKRBQLHOJ45U4XG35ESVWRDECWO5XLDGYADO6DPR3L7KIDVUMML"
+	sponsoredA = "GAOQJGUAB7NI7K7I62ORBXMN3J4SSWQUQ7FOEPSDJ322W2HMCNWPHXFB"
+	sponsoredB = "GCT2NQM5KJJEF55NPMY444C6M6CA7T33HRNCMA6ZFBIIXKNCRO6J25K7"
+)
+
+func TestPopulateAccountSponsorships(t *testing.T) {
+	account := history.AccountEntry{AccountID: sponsorA, NumSponsoring: 5}
+	totals := []history.SponsorshipTotal{
+		{Type: history.SponsoredClaimableBalance, Count: 1, Reserves: 2},
+		{Type: history.SponsoredAccount, Count: 1, Reserves: 2},
+		{Type: history.SponsoredTrustLine, Count: 1, Reserves: 1},
+	}
+	entries := []history.SponsoredEntry{
+		{Type: history.SponsoredAccount, AccountID: sponsoredA, Sponsor: sponsorA, Key: sponsoredA, Reserves: 2},
+		{Type: history.SponsoredTrustLine, AccountID: sponsoredB, Sponsor: sponsorA, Key: "USD:" + sponsorB, Reserves: 1},
+	}
+
+	var dest horizon.AccountSponsorships
+	PopulateAccountSponsorships(context.Background(), &dest, account, 5000000, totals, entries)
+	assert.Equal(t, horizon.AccountSponsorships{
+		AccountID:     sponsorA,
+		BaseReserve:   "0.5000000",
+		NumSponsoring: 5,
+		Reserves:      5,
+		ReserveAmount: "2.5000000",
+		EntryTypes: []horizon.SponsorshipType{
+			{
+				Type:          history.SponsoredAccount,
+				Count:         1,
+				Reserves:      2,
+				ReserveAmount: "1.0000000",
+				Entries: []horizon.SponsoredEntry{
+					{Type: history.SponsoredAccount, AccountID: sponsoredA, Key: sponsoredA, Reserves: 2},
+				},
+			},
+			{
+				Type:          history.SponsoredTrustLine,
+				Count:         1,
+				Reserves:      1,
+				ReserveAmount: "0.5000000",
+				Entries: []horizon.SponsoredEntry{
+					{Type: history.SponsoredTrustLine, AccountID: sponsoredB, Key: "USD:" + sponsorB, Reserves: 1},
+				},
+			},
+			{
+				Type:          history.SponsoredClaimableBalance,
+				Count:         1,
+				Reserves:      2,
+				ReserveAmount: "1.0000000",
+				Entries:       []horizon.SponsoredEntry{},
+			},
+		},
+	}, dest)
+}
+
+func TestPopulateAccountSponsors(t *testing.T) {
+	account := history.AccountEntry{AccountID: sponsoredA, NumSponsored: 4}
+	entries := []history.SponsoredEntry{
+		{Type: history.SponsoredData, AccountID: sponsoredA, Sponsor: sponsorB, Key: "name", Reserves: 1},
+		{Type: history.SponsoredAccount, AccountID: sponsoredA, Sponsor: sponsorA, Key: sponsoredA, Reserves: 2},
+		{Type: history.SponsoredSigner, AccountID: sponsoredA, Sponsor: sponsorA, Key: sponsoredB, Reserves: 1},
+	}
+
+	var dest horizon.AccountSponsors
+	PopulateAccountSponsors(context.Background(), &dest, account, 5000000, entries)
+	assert.Equal(t, horizon.AccountSponsors{
+		AccountID:     sponsoredA,
+		BaseReserve:   "0.5000000",
+		NumSponsored:  4,
+		Reserves:      4,
+		ReserveAmount: "2.0000000",
+		Sponsors: []horizon.Sponsor{
+			{
+				Sponsor:       sponsorB,
+				Reserves:      1,
+				ReserveAmount: "0.5000000",
+				Entries: []horizon.SponsoredEntry{
+					{Type: history.SponsoredData, Key: "name", Reserves: 1},
+				},
+			},
+			{
+				Sponsor:       sponsorA,
+				Reserves:      3,
+				ReserveAmount: "1.5000000",
+				Entries: []horizon.SponsoredEntry{
+					{Type: history.SponsoredAccount, Key: sponsoredA, Reserves: 2},
+					{Type: history.SponsoredSigner, Key: sponsoredB, Reserves: 1},
+				},
+			},
+		},
+	}, dest)
+}
@@ -2,11 +2,13 @@ package resourceadapter
 
 import (
 	"context"
+	"math/big"
 
 	"github.com/pownieh/stellar_go/amount"
 	"github.com/pownieh/stellar_go/price"
 	protocol "github.com/pownieh/stellar_go/protocols/horizon"
 	"github.com/pownieh/stellar_go/services/horizon/internal/db2/history"
+	"github.com/pownieh/stellar_go/support/errors"
 )
 
 // PopulateTradeAggregation fills out the details of a trade aggregation using a row from the trade aggregations
@@ -48,5 +50,59 @@ func PopulateTradeAggregation(
 		D: row.CloseD,
 	}
 	dest.Close = dest.CloseR.String()
+
+	dest.VWAP, err = vwap(row.BaseVolume, row.CounterVolume)
+	if err != nil {
+		return err
+	}
+	dest.LiquidityPoolTradeCount = row.LiquidityPoolTradeCount
+	dest.LiquidityPoolBaseVolume, err = amount.IntStringToAmount(row.LiquidityPoolBaseVolume)
+	if err != nil {
+		return err
+	}
+	dest.LiquidityPoolCounterVolume, err = amount.IntStringToAmount(row.LiquidityPoolCounterVolume)
+	if err != nil {
+		return err
+	}
+	dest.OrderbookTradeCount = row.TradeCount - row.LiquidityPoolTradeCount
+	dest.OrderbookBaseVolume, err = volumeDifference(row.BaseVolume, row.LiquidityPoolBaseVolume)
+	if err != nil {
+		return err
+	}
+	dest.OrderbookCounterVolume, err = volumeDifference(row.CounterVolume, row.LiquidityPoolCounterVolume)
+	if err != nil {
+		return err
+	}
 	return nil
 }
+
+// vwap returns the volume weighted average price of trades given their total
+// base and counter volumes.
+func vwap(baseVolume, counterVolume string) (string, error) {
+	base, ok := new(big.Rat).SetString(baseVolume)
+	if !ok {
+		return "", errors.Errorf("invalid base volume %s", baseVolume)
+	}
+	counter, ok := new(big.Rat).SetString(counterVolume)
+	if !ok {
+		return "", errors.Errorf("invalid counter volume %s", counterVolume)
+	}
+	if base.Sign() == 0 {
+		return "0.0000000", nil
+	}
+	return new(big.Rat).Quo(counter, base).FloatString(7), nil
+}
+
+// volumeDifference returns the amount of total - part, where both are volumes
+// in stroops.
+func volumeDifference(total, part string) (string, error) {
+	t, ok := new(big.Int).SetString(total, 10)
+	if !ok {
+		return "", errors.Errorf("invalid volume %s", total)
+	}
+	p, ok := new(big.Int).SetString(part, 10)
+	if !ok {
+		return "", errors.Errorf("invalid volume %s", part)
+	}
+	return amount.IntStringToAmount(t.Sub(t, p).String())
+}
@@ -0,0 +1,47 @@
+package resourceadapter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pownieh/stellar_go/protocols/horizon"
+	"github.com/pownieh/stellar_go/services/horizon/internal/db2/history"
+)
+
+func TestPopulateTradeAggregation(t *testing.T) {
+	row := history.TradeAggregation{
+		Timestamp:                  1700000000000,
+		TradeCount:                 5,
+		BaseVolume:                 "30000000",
+		CounterVolume:              "70000000",
+		Average:                    2.3333333,
+		HighN:                      3,
+		HighD:                      1,
+		LowN:                       2,
+		LowD:                       1,
+		OpenN:                      2,
+		OpenD:                      1,
+		CloseN:                     3,
+		CloseD:                     1,
+		LiquidityPoolTradeCount:    2,
+		LiquidityPoolBaseVolume:    "10000000",
+		LiquidityPoolCounterVolume: "30000000",
+	}
+
+	var dest horizon.TradeAggregation
+	assert.NoError(t, PopulateTradeAggregation(context.Background(), &dest, row))
+	assert.Equal(t, "3.0000000", dest.BaseVolume)
+	assert.Equal(t, "7.0000000", dest.CounterVolume)
+	assert.Equal(t, "2.3333333", dest.VWAP)
+	assert.Equal(t, int64(2), dest.LiquidityPoolTradeCount)
+	assert.Equal(t, "1.0000000", dest.LiquidityPoolBaseVolume)
+	assert.Equal(t, "3.0000000", dest.LiquidityPoolCounterVolume)
+	assert.Equal(t, int64(3), dest.OrderbookTradeCount)
+	assert.Equal(t, "2.0000000", dest.OrderbookBaseVolume)
+	assert.Equal(t, "4.0000000", dest.OrderbookCounterVolume)
+
+	row.BaseVolume = "invalid"
+	assert.Error(t, PopulateTradeAggregation(context.Background(), &dest, row))
+}
@@ -0,0 +1,158 @@
+package resourceadapter
+
+import (
+	"context"
+
+	"github.com/pownieh/stellar_go/amount"
+	protocol "github.com/pownieh/stellar_go/protocols/horizon"
+	"github.com/pownieh/stellar_go/protocols/horizon/base"
+	"github.com/pownieh/stellar_go/protocols/horizon/operations"
+	"github.com/pownieh/stellar_go/services/horizon/internal/codes"
+	"github.com/pownieh/stellar_go/services/horizon/internal/txsub"
+	"github.com/pownieh/stellar_go/xdr"
+)
+
+// PopulateTransactionDryRun fills out the resource's fields
+func PopulateTransactionDryRun(
+	ctx context.Context,
+	dest *protocol.TransactionDryRun,
+	hash string,
+	feeBump bool,
+	result txsub.DryRunResult,
+) (err error) {
+	dest.Hash = hash
+	dest.Ledger = result.Ledger
+	dest.OrderBookLedger = result.OrderBookLedger
+	dest.Signed = result.Signed
+	dest.Complete = result.Complete
+	dest.FeeAccount = result.FeeAccount
+	dest.FeeCharged = result.FeeCharged
+
+	switch {
+	case result.Error != nil:
+		dest.Reason = result.Error.Reason
+		if dest.ResultCodes.TransactionCode, err = codes.String(result.Error.Code); err != nil {
+			return
+		}
+		if result.Error.Code == xdr.TransactionResultCodeTxFeeBumpInnerFailed {
+			if dest.ResultCodes.InnerTransactionCode, err = codes.String(result.Error.InnerCode); err != nil {
+				return
+			}
+		}
+	case result.Complete:
+		dest.Successful = true
+		dest.ResultCodes.TransactionCode, _ = codes.String(xdr.TransactionResultCodeTxSuccess)
+		if feeBump {
+			dest.ResultCodes.InnerTransactionCode = dest.ResultCodes.TransactionCode
+			dest.ResultCodes.TransactionCode, _ = codes.String(xdr.TransactionResultCodeTxFeeBumpInnerSuccess)
+		}
+	}
+
+	dest.Operations = make([]protocol.DryRunOperation, len(result.Operations))
+	for i, op := range result.Operations {
+		if err = populateDryRunOperation(&dest.Operations[i], op); err != nil {
+			return
+		}
+		if op.Code != nil {
+			dest.ResultCodes.OperationCodes = append(dest.ResultCodes.OperationCodes, dest.Operations[i].ResultCode)
+		}
+	}
+
+	dest.BalanceChanges = make([]protocol.DryRunBalanceChange, len(result.BalanceChanges))
+	for i, change := range result.BalanceChanges {
+		dest.BalanceChanges[i] = protocol.DryRunBalanceChange{
+			Account: change.Account,
+			Amount:  amount.StringFromInt64(change.Amount),
+		}
+		if err = populateDryRunAsset(&dest.BalanceChanges[i].Asset, change.Asset); err != nil {
+			return
+		}
+	}
+
+	dest.TrustLinesCreated = make([]protocol.DryRunTrustLine, len(result.TrustLinesCreated))
+	for i, trustLine := range result.TrustLinesCreated {
+		dest.TrustLinesCreated[i] = protocol.DryRunTrustLine{
+			Account:    trustLine.Account,
+			Limit:      amount.StringFromInt64(trustLine.Limit),
+			Authorized: trustLine.Authorized,
+			Sponsor:    trustLine.Sponsor,
+		}
+		if err = populateDryRunAsset(&dest.TrustLinesCreated[i].Asset, trustLine.Asset); err != nil {
+			return
+		}
+	}
+
+	dest.ClaimableBalancesCreated = make([]protocol.DryRunClaimableBalance, len(result.ClaimableBalancesCreated))
+	for i, balance := range result.ClaimableBalancesCreated {
+		dest.ClaimableBalancesCreated[i] = protocol.DryRunClaimableBalance{
+			Sponsor:   balance.Sponsor,
+			Amount:    amount.StringFromInt64(balance.Amount),
+			Claimants: balance.Claimants,
+		}
+		if err = populateDryRunAsset(&dest.ClaimableBalancesCreated[i].Asset, balance.Asset); err != nil {
+			return
+		}
+	}
+
+	dest.SponsorshipChanges = make([]protocol.DryRunSponsorshipChange, len(result.SponsorshipChanges))
+	for i, change := range result.SponsorshipChanges {
+		dest.SponsorshipChanges[i] = protocol.DryRunSponsorshipChange{
+			OperationIndex: change.OperationIndex,
+			Sponsor:        change.Sponsor,
+			Account:        change.Account,
+			EntryType:      change.EntryType,
+			Removed:        change.Removed,
+		}
+	}
+
+	dest.ReserveChanges = make([]protocol.DryRunReserveChange, len(result.ReserveChanges))
+	for i, change := range result.ReserveChanges {
+		dest.ReserveChanges[i] = protocol.DryRunReserveChange{
+			Account: change.Account,
+			Before:  amount.StringFromInt64(change.Before),
+			After:   amount.StringFromInt64(change.After),
+		}
+	}
+	return
+}
+
+func populateDryRunOperation(dest *protocol.DryRunOperation, op txsub.DryRunOperation) (err error) {
+	dest.Type = operations.TypeNames[op.Type]
+	dest.SourceAccount = op.SourceAccount
+	dest.Supported = op.Supported
+	if op.Code != nil {
+		if dest.ResultCode, err = codes.String(op.Code); err != nil {
+			return
+		}
+	}
+	if !op.Supported {
+		return
+	}
+
+	switch op.Type {
+	case xdr.OperationTypePathPaymentStrictReceive, xdr.OperationTypePathPaymentStrictSend:
+		dest.MinimumReceived = amount.StringFromInt64(op.MinimumReceived)
+		dest.MaximumSent = amount.StringFromInt64(op.MaximumSent)
+	case xdr.OperationTypeManageSellOffer, xdr.OperationTypeCreatePassiveSellOffer, xdr.OperationTypeManageBuyOffer:
+		dest.OfferAmount = amount.StringFromInt64(op.OfferAmount)
+	default:
+		return
+	}
+	if op.Code == nil {
+		// the trade isn't estimated when the transaction is rejected
+		return
+	}
+	dest.SourceAmount = amount.StringFromInt64(op.SourceAmount)
+	dest.DestinationAmount = amount.StringFromInt64(op.DestinationAmount)
+	for _, offer := range op.OffersCrossed {
+		dest.OffersCrossed = append(dest.OffersCrossed, populateQuoteOffer(offer))
+	}
+	for _, pool := range op.LiquidityPools {
+		dest.LiquidityPools = append(dest.LiquidityPools, populateQuoteLiquidityPool(pool))
+	}
+	return
+}
+
+func populateDryRunAsset(dest *base.Asset, asset xdr.Asset) error {
+	return asset.Extract(&dest.Type, &dest.Code, &dest.Issuer)
+}
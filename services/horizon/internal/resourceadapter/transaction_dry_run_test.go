@@ -0,0 +1,131 @@
+package resourceadapter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pownieh/stellar_go/protocols/horizon"
+	"github.com/pownieh/stellar_go/protocols/horizon/base"
+	"github.com/pownieh/stellar_go/services/horizon/internal/paths"
+	"github.com/pownieh/stellar_go/services/horizon/internal/txsub"
+	"github.com/pownieh/stellar_go/xdr"
+)
+
+func TestPopulateTransactionDryRun(t *testing.T) {
+	source := "GBRPYHIL2CI3FNQ4BXLFMNDLFJUNPU2HY3ZMFSHONUCEOASW7QC7OX2H"
+	issuer := "GCEZWKCA5VLDNRLN3RPRJMRZOX3Z6G5CHCGSNFHEYVXM3XOJMDS674JZ"
+	usd := xdr.MustNewCreditAsset("USD", issuer)
+	offer := xdr.OfferEntry{
+		SellerId: xdr.MustAddress(issuer),
+		OfferId:  7,
+		Selling:  xdr.MustNewNativeAsset(),
+		Buying:   usd,
+		Price:    xdr.Price{N: 1, D: 2},
+		Amount:   1000000000,
+	}
+	result := txsub.DryRunResult{
+		Ledger:          100,
+		OrderBookLedger: 101,
+		Complete:        true,
+		FeeAccount:      source,
+		FeeCharged:      200,
+		Operations: []txsub.DryRunOperation{
+			{
+				Type:              xdr.OperationTypePathPaymentStrictSend,
+				SourceAccount:     source,
+				Supported:         true,
+				Code:              xdr.PathPaymentStrictSendResultCodePathPaymentStrictSendSuccess,
+				SourceAmount:      100000000,
+				DestinationAmount: 200000000,
+				MinimumReceived:   150000000,
+				MaximumSent:       100000000,
+				OffersCrossed:     []paths.QuoteOffer{{Offer: offer, Amount: 200000000}},
+			},
+			{
+				Type:          xdr.OperationTypeChangeTrust,
+				SourceAccount: source,
+				Supported:     true,
+				Code:          xdr.ChangeTrustResultCodeChangeTrustSuccess,
+			},
+		},
+		BalanceChanges: []txsub.BalanceChange{
+			{Account: source, Asset: xdr.MustNewNativeAsset(), Amount: 199999800},
+			{Account: source, Asset: usd, Amount: -100000000},
+		},
+		TrustLinesCreated: []txsub.TrustLineCreated{
+			{Account: source, Asset: usd, Limit: 10000000000, Authorized: true},
+		},
+		ReserveChanges: []txsub.ReserveChange{{Account: source, Before: 10000000, After: 15000000}},
+	}
+
+	var dest horizon.TransactionDryRun
+	require.NoError(t, PopulateTransactionDryRun(context.Background(), &dest, "abc", false, result))
+	assert.Equal(t, "abc", dest.Hash)
+	assert.Equal(t, uint32(100), dest.Ledger)
+	assert.Equal(t, uint32(101), dest.OrderBookLedger)
+	assert.True(t, dest.Successful)
+	assert.Equal(t, horizon.TransactionResultCodes{
+		TransactionCode: "tx_success",
+		OperationCodes:  []string{"op_success", "op_success"},
+	}, dest.ResultCodes)
+	assert.Equal(t, int64(200), dest.FeeCharged)
+	assert.Equal(t, horizon.DryRunOperation{
+		Type:              "path_payment_strict_send",
+		SourceAccount:     source,
+		Supported:         true,
+		ResultCode:        "op_success",
+		SourceAmount:      "10.0000000",
+		DestinationAmount: "20.0000000",
+		MinimumReceived:   "15.0000000",
+		MaximumSent:       "10.0000000",
+		OffersCrossed: []horizon.QuoteOffer{{
+			ID:     7,
+			Seller: issuer,
+			PriceR: horizon.Price{N: 1, D: 2},
+			Price:  "0.5000000",
+			Amount: "20.0000000",
+		}},
+	}, dest.Operations[0])
+	assert.Equal(t, horizon.DryRunOperation{
+		Type:          "change_trust",
+		SourceAccount: source,
+		Supported:     true,
+		ResultCode:    "op_success",
+	}, dest.Operations[1])
+	assert.Equal(t, []horizon.DryRunBalanceChange{
+		{Account: source, Asset: base.Asset{Type: "native"}, Amount: "19.9999800"},
+		{Account: source, Asset: base.Asset{Type: "credit_alphanum4", Code: "USD", Issuer: issuer}, Amount: "-10.0000000"},
+	}, dest.BalanceChanges)
+	assert.Equal(t, []horizon.DryRunTrustLine{{
+		Account:    source,
+		Asset:      base.Asset{Type: "credit_alphanum4", Code: "USD", Issuer: issuer},
+		Limit:      "1000.0000000",
+		Authorized: true,
+	}}, dest.TrustLinesCreated)
+	assert.Equal(t, []horizon.DryRunReserveChange{
+		{Account: source, Before: "1.0000000", After: "1.5000000"},
+	}, dest.ReserveChanges)
+	assert.Empty(t, dest.ClaimableBalancesCreated)
+
+	// failed fee bump transaction
+	result.Error = &txsub.ValidationError{
+		Code:           xdr.TransactionResultCodeTxFeeBumpInnerFailed,
+		InnerCode:      xdr.TransactionResultCodeTxFailed,
+		OperationIndex: 1,
+		OperationCode:  xdr.ChangeTrustResultCodeChangeTrustLowReserve,
+		Reason:         "low reserve",
+	}
+	result.Operations[1].Code = xdr.ChangeTrustResultCodeChangeTrustLowReserve
+	dest = horizon.TransactionDryRun{}
+	require.NoError(t, PopulateTransactionDryRun(context.Background(), &dest, "abc", true, result))
+	assert.False(t, dest.Successful)
+	assert.Equal(t, "low reserve", dest.Reason)
+	assert.Equal(t, horizon.TransactionResultCodes{
+		TransactionCode:      "tx_fee_bump_inner_failed",
+		InnerTransactionCode: "tx_failed",
+		OperationCodes:       []string{"op_success", "op_low_reserve"},
+	}, dest.ResultCodes)
+}
@@ -0,0 +1,39 @@
+package resourceadapter
+
+import (
+	"context"
+
+	protocol "github.com/pownieh/stellar_go/protocols/horizon"
+	horizonContext "github.com/pownieh/stellar_go/services/horizon/internal/context"
+	"github.com/pownieh/stellar_go/services/horizon/internal/db2/history"
+	"github.com/pownieh/stellar_go/support/render/hal"
+)
+
+// PopulateQueuedTransaction fills out the resource's fields
+func PopulateQueuedTransaction(
+	ctx context.Context,
+	dest *protocol.QueuedTransaction,
+	row history.QueuedTransaction,
+) {
+	dest.Hash = row.TransactionHash
+	dest.InnerHash = row.InnerTransactionHash.String
+	dest.Status = row.Status
+	dest.Account = row.Account
+	dest.AccountSequence = row.AccountSequence
+	dest.SubmissionCount = row.SubmissionCount
+	dest.CreatedAt = row.CreatedAt
+	dest.LastSubmittedAt = row.LastSubmittedAt
+	if row.FinishedAt.Valid {
+		finishedAt := row.FinishedAt.Time
+		dest.FinishedAt = &finishedAt
+	}
+	dest.MaxTime = timestampString(row.MaxTime)
+	dest.MaxLedger = uint32(row.MaxLedger.Int64)
+	dest.Ledger = int32(row.LedgerSequence.Int64)
+	dest.EnvelopeXdr = row.TxEnvelope
+	dest.ResultXdr = row.TxResult.String
+
+	lb := hal.LinkBuilder{Base: horizonContext.BaseURL(ctx)}
+	dest.Links.Self = lb.Link("/transaction_queue", dest.Hash)
+	dest.Links.Transaction = lb.Link("/transactions", dest.Hash)
+}
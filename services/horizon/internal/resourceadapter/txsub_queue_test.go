@@ -0,0 +1,59 @@
+package resourceadapter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/guregu/null"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pownieh/stellar_go/protocols/horizon"
+	"github.com/pownieh/stellar_go/services/horizon/internal/db2/history"
+)
+
+func TestPopulateQueuedTransaction(t *testing.T) {
+	createdAt := time.Unix(1700000000, 0).UTC()
+	row := history.QueuedTransaction{
+		TransactionHash: "2374e99349b9ef7dba9a5db3339b78fda8f34777b1af33ba468ad5c0df946d4d",
+		TxEnvelope:      "AAAA",
+		Account:         "GBRPYHIL2CI3FNQ4BXLFMNDLFJUNPU2HY3ZMFSHONUCEOASW7QC7OX2H",
+		AccountSequence: 10,
+		MaxTime:         null.IntFrom(1700000060),
+		Status:          history.QueuedTransactionPending,
+		SubmissionCount: 2,
+		SubmittedLedger: 100,
+		CreatedAt:       createdAt,
+		LastSubmittedAt: createdAt.Add(15 * time.Second),
+	}
+
+	var dest horizon.QueuedTransaction
+	PopulateQueuedTransaction(context.Background(), &dest, row)
+	assert.Equal(t, row.TransactionHash, dest.Hash)
+	assert.Equal(t, "", dest.InnerHash)
+	assert.Equal(t, "pending", dest.Status)
+	assert.Equal(t, int64(10), dest.AccountSequence)
+	assert.Equal(t, int32(2), dest.SubmissionCount)
+	assert.Equal(t, "1700000060", dest.MaxTime)
+	assert.Equal(t, uint32(0), dest.MaxLedger)
+	assert.Nil(t, dest.FinishedAt)
+	assert.Equal(t, int32(0), dest.Ledger)
+	assert.Equal(t, "", dest.ResultXdr)
+	assert.Equal(t, "/transaction_queue/"+row.TransactionHash, dest.Links.Self.Href)
+	assert.Equal(t, "/transactions/"+row.TransactionHash, dest.Links.Transaction.Href)
+
+	row.Status = history.QueuedTransactionSuccess
+	row.InnerTransactionHash = null.StringFrom("3374e99349b9ef7dba9a5db3339b78fda8f34777b1af33ba468ad5c0df946d4d")
+	row.FinishedAt = null.TimeFrom(createdAt.Add(20 * time.Second))
+	row.LedgerSequence = null.IntFrom(103)
+	row.TxResult = null.StringFrom("AAAAAAAAAGQAAAAAAAAAAAAAAAA=")
+	dest = horizon.QueuedTransaction{}
+	PopulateQueuedTransaction(context.Background(), &dest, row)
+	assert.Equal(t, row.InnerTransactionHash.String, dest.InnerHash)
+	assert.Equal(t, "success", dest.Status)
+	if assert.NotNil(t, dest.FinishedAt) {
+		assert.Equal(t, row.FinishedAt.Time, *dest.FinishedAt)
+	}
+	assert.Equal(t, int32(103), dest.Ledger)
+	assert.Equal(t, row.TxResult.String, dest.ResultXdr)
+}
@@ -18,6 +18,16 @@ const (
 var (
 	// ErrEmptyInMemoryOrderBook indicates that the in memory order book is not yet populated
 	ErrEmptyInMemoryOrderBook = errors.New("Empty orderbook")
+	// ErrSplitPathNotFound indicates that the in memory order book does not
+	// hold enough liquidity to route the amount of a split path
+	ErrSplitPathNotFound = orderbook.ErrSplitPathNotFound
+	// ErrQuoteNotFound indicates that the in memory order book does not hold
+	// enough liquidity to trade the amount of a quote
+	ErrQuoteNotFound = orderbook.ErrQuoteNotFound
+
+	// ErrOfferCrossSelf indicates that an offer would cross an offer of its
+	// seller
+	ErrOfferCrossSelf = orderbook.ErrOfferCrossSelf
 )
 
 // InMemoryFinder is an implementation of the path finding interface
@@ -117,3 +127,154 @@ func (finder InMemoryFinder) FindFixedPaths(
 	}
 	return results, lastLedger, err
 }
+
+// FindSplitPaths returns the payment split across several paths which routes
+// the amount of the query. The amount is split in `q.Parts` parts routed one
+// after the other, each through the best path of the order book left by the
+// previous parts.
+func (finder InMemoryFinder) FindSplitPaths(
+	ctx context.Context,
+	q paths.SplitQuery,
+	maxLength uint,
+) (paths.SplitPath, uint32, error) {
+	if finder.graph.IsEmpty() {
+		return paths.SplitPath{}, 0, ErrEmptyInMemoryOrderBook
+	}
+
+	if maxLength == 0 {
+		maxLength = MaxInMemoryPathLength
+	}
+	if maxLength > MaxInMemoryPathLength {
+		return paths.SplitPath{}, 0, errors.New("invalid value of maxLength")
+	}
+
+	var split orderbook.SplitPath
+	var lastLedger uint32
+	var err error
+	if q.StrictSend {
+		split, lastLedger, err = finder.graph.FindSplitPathsStrictSend(
+			ctx,
+			int(maxLength),
+			q.SourceAsset,
+			q.Amount,
+			q.DestinationAsset,
+			q.Parts,
+			finder.includePools,
+		)
+	} else {
+		split, lastLedger, err = finder.graph.FindSplitPathsStrictReceive(
+			ctx,
+			int(maxLength),
+			q.SourceAsset,
+			q.DestinationAsset,
+			q.Amount,
+			q.SourceAccount,
+			q.Parts,
+			finder.includePools,
+		)
+	}
+	if err != nil {
+		return paths.SplitPath{}, lastLedger, err
+	}
+
+	result := paths.SplitPath{
+		Source:            split.SourceAsset,
+		SourceAmount:      split.SourceAmount,
+		Destination:       split.DestinationAsset,
+		DestinationAmount: split.DestinationAmount,
+		Legs:              make([]paths.Path, len(split.Legs)),
+	}
+	for i, leg := range split.Legs {
+		result.Legs[i] = paths.Path{
+			Path:              leg.InteriorNodes,
+			Source:            leg.SourceAsset,
+			SourceAmount:      leg.SourceAmount,
+			Destination:       leg.DestinationAsset,
+			DestinationAmount: leg.DestinationAmount,
+		}
+	}
+	return result, lastLedger, nil
+}
+
+// Quote returns the result of trading the amount of the query directly
+// between its assets, against the offers or the liquidity pool of the pair.
+func (finder InMemoryFinder) Quote(ctx context.Context, q paths.QuoteQuery) (paths.Quote, uint32, error) {
+	if finder.graph.IsEmpty() {
+		return paths.Quote{}, 0, ErrEmptyInMemoryOrderBook
+	}
+
+	var (
+		quote      orderbook.Quote
+		lastLedger uint32
+		err        error
+	)
+	if q.StrictSend {
+		quote, lastLedger, err = finder.graph.QuoteStrictSend(
+			ctx,
+			q.SourceAsset,
+			q.Amount,
+			q.DestinationAsset,
+			q.SourceAccount,
+			finder.includePools,
+		)
+	} else {
+		quote, lastLedger, err = finder.graph.QuoteStrictReceive(
+			ctx,
+			q.SourceAsset,
+			q.DestinationAsset,
+			q.Amount,
+			q.SourceAccount,
+			finder.includePools,
+		)
+	}
+	if err != nil {
+		return paths.Quote{}, lastLedger, err
+	}
+
+	return newQuote(quote), lastLedger, nil
+}
+
+// CrossOffer returns the trade of a new offer against the offers of the order
+// book.
+func (finder InMemoryFinder) CrossOffer(ctx context.Context, q paths.OfferQuery) (paths.Quote, uint32, error) {
+	if finder.graph.IsEmpty() {
+		return paths.Quote{}, 0, ErrEmptyInMemoryOrderBook
+	}
+
+	quote, lastLedger, err := finder.graph.CrossOffer(
+		ctx,
+		q.SellingAsset,
+		q.BuyingAsset,
+		q.Amount,
+		q.Buy,
+		q.Price,
+		q.Passive,
+		q.SellerAccount,
+	)
+	if err != nil {
+		return paths.Quote{}, lastLedger, err
+	}
+	return newQuote(quote), lastLedger, nil
+}
+
+func newQuote(quote orderbook.Quote) paths.Quote {
+	result := paths.Quote{
+		Source:            quote.SourceAsset,
+		SourceAmount:      quote.SourceAmount,
+		Destination:       quote.DestinationAsset,
+		DestinationAmount: quote.DestinationAmount,
+		MidPrice:          quote.MidPrice,
+		Offers:            make([]paths.QuoteOffer, len(quote.Offers)),
+	}
+	for i, offer := range quote.Offers {
+		result.Offers[i] = paths.QuoteOffer{Offer: offer.Offer, Amount: offer.Amount}
+	}
+	if quote.Pool != nil {
+		result.Pool = &paths.QuotePool{
+			Pool:     quote.Pool.Pool,
+			ReserveA: quote.Pool.ReserveA,
+			ReserveB: quote.Pool.ReserveB,
+		}
+	}
+	return result
+}
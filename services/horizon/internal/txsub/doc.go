@@ -10,3 +10,6 @@ package txsub
 // - system.go: txsub.System, the struct that ties all the interfaces together
 // - open_submission_list.go: A default implementation of the OpenSubmissionList interface
 // - submitter.go: A default implementation of the Submitter interface
+// - queue.go: txsub.Queue, the persistent queue rebroadcasting submitted transactions
+// - validator.go: txsub.Validator, which checks transactions against the ingested state before submission
+// - dry_run.go: txsub.DryRunner, which estimates the outcome of transactions without submitting them
@@ -0,0 +1,1192 @@
+package txsub
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+
+	"github.com/pownieh/stellar_go/price"
+	"github.com/pownieh/stellar_go/services/horizon/internal/db2/history"
+	"github.com/pownieh/stellar_go/services/horizon/internal/paths"
+	"github.com/pownieh/stellar_go/services/horizon/internal/simplepath"
+	"github.com/pownieh/stellar_go/support/errors"
+	"github.com/pownieh/stellar_go/xdr"
+)
+
+var (
+	// ErrNoIngestedLedger is returned by DryRunner before the first ledger is
+	// ingested.
+	ErrNoIngestedLedger = errors.New("no ledger was ingested yet")
+	// ErrOrderBookChanged is returned by DryRunner when the order book kept
+	// changing while the trades of a transaction were priced.
+	ErrOrderBookChanged = errors.New("the order book changed during the dry run")
+)
+
+// maxDryRunAttempts is the number of times a dry run is attempted when the
+// order book changes while the trades are priced.
+const maxDryRunAttempts = 3
+
+// Entry types of the sponsorship changes.
+const (
+	SponsoredAccount          = "account"
+	SponsoredTrustLine        = "trustline"
+	SponsoredOffer            = "offer"
+	SponsoredSigner           = "signer"
+	SponsoredClaimableBalance = "claimable_balance"
+)
+
+// DryRunResult is the expected outcome of a transaction.
+type DryRunResult struct {
+	// Ledger is the latest ingested ledger the transaction is run against.
+	Ledger uint32
+	// OrderBookLedger is the ledger of the in-memory order book the trades of
+	// path payments and offers are priced against, 0 when no trade is priced.
+	// The order book is updated by the ingestion of the Horizon instance so
+	// it may differ from Ledger, e.g. when the ledger entries are read from
+	// a replica.
+	OrderBookLedger uint32
+	// Signed is false when the envelope has no signatures, the signatures
+	// aren't checked then.
+	Signed bool
+	// Complete is false when the transaction has operations which the dry
+	// run doesn't support. The result and the effects only cover the
+	// operations before the first of them.
+	Complete bool
+	// Error is the expected failure of the transaction, nil when it is
+	// expected to succeed.
+	Error *ValidationError
+
+	FeeAccount string
+	// FeeCharged is the fee charged when the network isn't congested, capped
+	// by the maximum fee of the transaction.
+	FeeCharged int64
+
+	Operations []DryRunOperation
+
+	// The effects of the transaction. Only the fee is charged when the
+	// transaction fails.
+	BalanceChanges           []BalanceChange
+	TrustLinesCreated        []TrustLineCreated
+	ClaimableBalancesCreated []ClaimableBalanceCreated
+	SponsorshipChanges       []SponsorshipChange
+	ReserveChanges           []ReserveChange
+}
+
+// DryRunOperation is the expected outcome of an operation.
+type DryRunOperation struct {
+	Type          xdr.OperationType
+	SourceAccount string
+	// Supported is false for the operations the dry run can't estimate.
+	Supported bool
+	// Code is the expected result code of the operation, nil when the
+	// operation isn't run.
+	Code interface{}
+
+	// SourceAmount and DestinationAmount are the amounts sent and received
+	// by path payments, or sold and bought by the offers when they are
+	// created.
+	SourceAmount      int64
+	DestinationAmount int64
+	// MinimumReceived and MaximumSent are the bounds of path payments.
+	MinimumReceived int64
+	MaximumSent     int64
+	// OfferAmount is the amount of an offer left in the order book after it
+	// is created, in units of its selling asset for sell offers and of its
+	// buying asset for buy offers.
+	OfferAmount int64
+
+	OffersCrossed  []paths.QuoteOffer
+	LiquidityPools []paths.QuotePool
+}
+
+// BalanceChange is the change of the balance of an account in an asset. The
+// balances of the sellers of the offers crossed and of the issuers aren't
+// listed.
+type BalanceChange struct {
+	Account string
+	Asset   xdr.Asset
+	Amount  int64
+}
+
+// TrustLineCreated is a trust line created by a ChangeTrust operation.
+type TrustLineCreated struct {
+	Account    string
+	Asset      xdr.Asset
+	Limit      int64
+	Authorized bool
+	Sponsor    string
+}
+
+// ClaimableBalanceCreated is a claimable balance created by a
+// CreateClaimableBalance operation.
+type ClaimableBalanceCreated struct {
+	Sponsor   string
+	Asset     xdr.Asset
+	Amount    int64
+	Claimants []string
+}
+
+// SponsorshipChange is a reserve which is sponsored, or no longer sponsored
+// when Removed is true.
+type SponsorshipChange struct {
+	OperationIndex int
+	Sponsor        string
+	// Account is the account whose reserve is sponsored, it is empty for
+	// claimable balances.
+	Account   string
+	EntryType string
+	Removed   bool
+}
+
+// ReserveChange is the change of the minimum balance of an account.
+type ReserveChange struct {
+	Account string
+	Before  int64
+	After   int64
+}
+
+// DryRunner estimates the outcome of transactions given the latest ingested
+// ledger and the order book, without submitting them. The trades of path
+// payments and offers are priced by PathFinder, they aren't supported when it
+// is nil.
+type DryRunner struct {
+	NetworkPassphrase string
+	PathFinder        paths.Finder
+}
+
+// DryRun returns the expected outcome of the transaction. It is run with the
+// checks of Validator and the classic operations supported are applied in
+// order to a copy of the ledger entries they use, which are read with db.
+func (r *DryRunner) DryRun(ctx context.Context, db ValidatorDB, envelope xdr.TransactionEnvelope) (DryRunResult, error) {
+	// The data must belong to the same ledger
+	err := db.BeginTx(ctx, &sql.TxOptions{
+		Isolation: sql.LevelRepeatableRead,
+		ReadOnly:  true,
+	})
+	if err != nil {
+		return DryRunResult{}, errors.Wrap(err, "could not begin repeatable read transaction")
+	}
+	defer db.Rollback()
+
+	latestLedger, err := db.GetLatestHistoryLedger(ctx)
+	if err != nil {
+		return DryRunResult{}, errors.Wrap(err, "could not get latest ledger")
+	}
+	if latestLedger == 0 {
+		return DryRunResult{}, ErrNoIngestedLedger
+	}
+
+	signed := len(envelope.Signatures()) > 0
+	if envelope.IsFeeBump() {
+		signed = signed || len(envelope.FeeBumpSignatures()) > 0
+	}
+	state := validationState{ignoreSignatures: !signed}
+	if err = db.LedgerBySequence(ctx, &state.ledger, int32(latestLedger)); err != nil {
+		return DryRunResult{}, errors.Wrap(err, "could not load latest ledger")
+	}
+	entries, err := dryRunEntries(envelope)
+	if err != nil {
+		return DryRunResult{}, err
+	}
+	if err = state.loadEntries(ctx, db, entries); err != nil {
+		return DryRunResult{}, err
+	}
+	// the order book is used after the ledger entries are loaded
+	db.Rollback()
+
+	// the trades of the transaction must be priced against the same order
+	// book, which is updated when a ledger is ingested
+	for attempt := 1; ; attempt++ {
+		result, err := dryRunTransaction(ctx, envelope, r.NetworkPassphrase, r.PathFinder, state, entries.addresses)
+		if err != ErrOrderBookChanged || attempt == maxDryRunAttempts {
+			return result, err
+		}
+	}
+}
+
+// dryRunEntries returns the ledger entries used by the validation and the
+// dry run of a transaction.
+func dryRunEntries(envelope xdr.TransactionEnvelope) (ledgerEntries, error) {
+	var entries ledgerEntries
+	sourceAccount := envelope.SourceAccount().ToAccountId().Address()
+	if envelope.IsFeeBump() {
+		entries.addAccount(envelope.FeeBumpAccount().ToAccountId().Address())
+	}
+	entries.addAccount(sourceAccount)
+	for _, op := range envelope.Operations() {
+		opSource := operationSourceAccount(op, sourceAccount)
+		entries.addAccount(opSource)
+
+		var err error
+		switch op.Body.Type {
+		case xdr.OperationTypeCreateAccount:
+			entries.addAccount(op.Body.MustCreateAccountOp().Destination.Address())
+		case xdr.OperationTypePayment:
+			payment := op.Body.MustPaymentOp()
+			if err = entries.addTrustLine(opSource, payment.Asset); err == nil {
+				err = entries.addTrustLine(payment.Destination.ToAccountId().Address(), payment.Asset)
+			}
+		case xdr.OperationTypePathPaymentStrictReceive:
+			pathPayment := op.Body.MustPathPaymentStrictReceiveOp()
+			if err = entries.addTrustLine(opSource, pathPayment.SendAsset); err == nil {
+				err = entries.addTrustLine(pathPayment.Destination.ToAccountId().Address(), pathPayment.DestAsset)
+			}
+		case xdr.OperationTypePathPaymentStrictSend:
+			pathPayment := op.Body.MustPathPaymentStrictSendOp()
+			if err = entries.addTrustLine(opSource, pathPayment.SendAsset); err == nil {
+				err = entries.addTrustLine(pathPayment.Destination.ToAccountId().Address(), pathPayment.DestAsset)
+			}
+		case xdr.OperationTypeManageSellOffer, xdr.OperationTypeCreatePassiveSellOffer, xdr.OperationTypeManageBuyOffer:
+			selling, buying := offerAssets(op)
+			if err = entries.addTrustLine(opSource, selling); err == nil {
+				err = entries.addTrustLine(opSource, buying)
+			}
+		case xdr.OperationTypeChangeTrust:
+			if line := op.Body.MustChangeTrustOp().Line; line.Type != xdr.AssetTypeAssetTypePoolShare {
+				asset := line.ToAsset()
+				if asset.Type != xdr.AssetTypeAssetTypeNative {
+					entries.addAccount(asset.GetIssuer())
+				}
+				err = entries.addTrustLine(opSource, asset)
+			}
+		case xdr.OperationTypeCreateClaimableBalance:
+			err = entries.addTrustLine(opSource, op.Body.MustCreateClaimableBalanceOp().Asset)
+		case xdr.OperationTypeBeginSponsoringFutureReserves:
+			entries.addAccount(op.Body.MustBeginSponsoringFutureReservesOp().SponsoredId.Address())
+		}
+		if err != nil {
+			return entries, err
+		}
+	}
+	return entries, nil
+}
+
+func offerAssets(op xdr.Operation) (selling, buying xdr.Asset) {
+	switch op.Body.Type {
+	case xdr.OperationTypeManageSellOffer:
+		offer := op.Body.MustManageSellOfferOp()
+		return offer.Selling, offer.Buying
+	case xdr.OperationTypeCreatePassiveSellOffer:
+		offer := op.Body.MustCreatePassiveSellOfferOp()
+		return offer.Selling, offer.Buying
+	default:
+		offer := op.Body.MustManageBuyOfferOp()
+		return offer.Selling, offer.Buying
+	}
+}
+
+func dryRunTransaction(
+	ctx context.Context,
+	envelope xdr.TransactionEnvelope,
+	passphrase string,
+	finder paths.Finder,
+	state validationState,
+	addresses []string,
+) (DryRunResult, error) {
+	result := DryRunResult{
+		Ledger:   uint32(state.ledger.Sequence),
+		Signed:   !state.ignoreSignatures,
+		Complete: true,
+	}
+	sourceAddress := envelope.SourceAccount().ToAccountId().Address()
+	ops := envelope.Operations()
+	result.Operations = make([]DryRunOperation, len(ops))
+	for i, op := range ops {
+		result.Operations[i] = DryRunOperation{
+			Type:          op.Body.Type,
+			SourceAccount: operationSourceAccount(op, sourceAddress),
+		}
+		_, result.Operations[i].Supported = dryRunSuccessCodes[op.Body.Type]
+	}
+
+	result.FeeAccount = sourceAddress
+	maxFee, feeOps := int64(envelope.Fee()), int64(len(ops))
+	if envelope.IsFeeBump() {
+		result.FeeAccount = envelope.FeeBumpAccount().ToAccountId().Address()
+		maxFee, feeOps = envelope.FeeBumpFee(), feeOps+1
+	}
+	result.FeeCharged = int64(state.ledger.BaseFee) * feeOps
+	if result.FeeCharged > maxFee {
+		result.FeeCharged = maxFee
+	}
+
+	failedOp := -1
+	var opFailure *ValidationError
+	if err := validateTransaction(envelope, passphrase, state); err != nil {
+		verr, ok := err.(*ValidationError)
+		if !ok {
+			return result, err
+		}
+		code := verr.Code
+		if code == xdr.TransactionResultCodeTxFeeBumpInnerFailed {
+			code = verr.InnerCode
+		}
+		if code != xdr.TransactionResultCodeTxFailed {
+			// the transaction is rejected and no fee is charged
+			result.Error = verr
+			result.FeeCharged = 0
+			return result, nil
+		}
+		// the fee bump result code is set once the operations are run
+		failedOp, opFailure = verr.OperationIndex, &ValidationError{
+			Code:           xdr.TransactionResultCodeTxFailed,
+			OperationIndex: verr.OperationIndex,
+			OperationCode:  verr.OperationCode,
+			Reason:         verr.Reason,
+		}
+	}
+
+	run := newDryRun(ctx, finder, state, addresses)
+	run.changeBalance(result.FeeAccount, xdr.MustNewNativeAsset(), -result.FeeCharged)
+	for i, op := range ops {
+		dest := &result.Operations[i]
+		if i == failedOp {
+			dest.Code = opFailure.OperationCode
+			result.Error = opFailure
+			break
+		}
+		if !dest.Supported {
+			result.Complete = false
+			break
+		}
+
+		failure, err := run.apply(i, op, dest)
+		if err != nil {
+			return result, err
+		}
+		if !dest.Supported {
+			result.Complete = false
+			break
+		}
+		if failure != nil {
+			dest.Code = failure.code
+			result.Error = &ValidationError{
+				Code:           xdr.TransactionResultCodeTxFailed,
+				OperationIndex: i,
+				OperationCode:  failure.code,
+				Reason:         failure.reason,
+			}
+			break
+		}
+		dest.Code = dryRunSuccessCodes[op.Body.Type]
+	}
+	result.OrderBookLedger = run.orderBookLedger
+
+	if result.Error == nil && result.Complete && len(run.sponsors) > 0 {
+		for sponsored, sponsor := range run.sponsors {
+			result.Error = &ValidationError{
+				Code:   xdr.TransactionResultCodeTxBadSponsorship,
+				Reason: fmt.Sprintf("the sponsorship of the reserves of %s by %s is not ended", sponsored, sponsor),
+			}
+			break
+		}
+	}
+	if result.Error != nil && envelope.IsFeeBump() {
+		result.Error.InnerCode = result.Error.Code
+		result.Error.Code = xdr.TransactionResultCodeTxFeeBumpInnerFailed
+	}
+
+	if result.Error != nil {
+		if result.FeeCharged > 0 {
+			result.BalanceChanges = []BalanceChange{{
+				Account: result.FeeAccount,
+				Asset:   xdr.MustNewNativeAsset(),
+				Amount:  -result.FeeCharged,
+			}}
+		}
+		return result, nil
+	}
+
+	for _, change := range run.balanceChanges {
+		if change.Amount != 0 {
+			result.BalanceChanges = append(result.BalanceChanges, change)
+		}
+	}
+	result.TrustLinesCreated = run.trustLinesCreated
+	result.ClaimableBalancesCreated = run.claimableBalancesCreated
+	result.SponsorshipChanges = run.sponsorshipChanges
+	for _, address := range run.order {
+		after, ok := run.accounts[address]
+		if !ok {
+			continue
+		}
+		change := ReserveChange{Account: address, After: minimumBalance(*after, state.ledger.BaseReserve)}
+		if before, ok := state.accounts[address]; ok {
+			change.Before = minimumBalance(before, state.ledger.BaseReserve)
+		}
+		if change.Before != change.After {
+			result.ReserveChanges = append(result.ReserveChanges, change)
+		}
+	}
+	return result, nil
+}
+
+// dryRunSuccessCodes are the success codes of the operations supported by the
+// dry run.
+var dryRunSuccessCodes = map[xdr.OperationType]interface{}{
+	xdr.OperationTypeCreateAccount:                 xdr.CreateAccountResultCodeCreateAccountSuccess,
+	xdr.OperationTypePayment:                       xdr.PaymentResultCodePaymentSuccess,
+	xdr.OperationTypePathPaymentStrictReceive:      xdr.PathPaymentStrictReceiveResultCodePathPaymentStrictReceiveSuccess,
+	xdr.OperationTypePathPaymentStrictSend:         xdr.PathPaymentStrictSendResultCodePathPaymentStrictSendSuccess,
+	xdr.OperationTypeManageSellOffer:               xdr.ManageSellOfferResultCodeManageSellOfferSuccess,
+	xdr.OperationTypeCreatePassiveSellOffer:        xdr.ManageSellOfferResultCodeManageSellOfferSuccess,
+	xdr.OperationTypeManageBuyOffer:                xdr.ManageBuyOfferResultCodeManageBuyOfferSuccess,
+	xdr.OperationTypeSetOptions:                    xdr.SetOptionsResultCodeSetOptionsSuccess,
+	xdr.OperationTypeChangeTrust:                   xdr.ChangeTrustResultCodeChangeTrustSuccess,
+	xdr.OperationTypeBumpSequence:                  xdr.BumpSequenceResultCodeBumpSequenceSuccess,
+	xdr.OperationTypeCreateClaimableBalance:        xdr.CreateClaimableBalanceResultCodeCreateClaimableBalanceSuccess,
+	xdr.OperationTypeBeginSponsoringFutureReserves: xdr.BeginSponsoringFutureReservesResultCodeBeginSponsoringFutureReservesSuccess,
+	xdr.OperationTypeEndSponsoringFutureReserves:   xdr.EndSponsoringFutureReservesResultCodeEndSponsoringFutureReservesSuccess,
+}
+
+// operationFailure is the result code of an operation which fails.
+type operationFailure struct {
+	code   interface{}
+	reason string
+}
+
+func failOperation(code interface{}, format string, args ...interface{}) *operationFailure {
+	return &operationFailure{code: code, reason: fmt.Sprintf(format, args...)}
+}
+
+// dryRun applies operations to copies of the ledger entries of a
+// validationState.
+type dryRun struct {
+	ctx         context.Context
+	finder      paths.Finder
+	baseReserve int32
+
+	accounts   map[string]*history.AccountEntry
+	trustLines map[string]*history.TrustLine
+	signers    map[string][]history.AccountSigner
+	// order is the order in which the accounts are listed
+	order []string
+	// sponsors maps the accounts whose future reserves are sponsored to their
+	// sponsor
+	sponsors map[string]string
+
+	balanceChanges           []BalanceChange
+	balanceChangeIndex       map[string]int
+	trustLinesCreated        []TrustLineCreated
+	claimableBalancesCreated []ClaimableBalanceCreated
+	sponsorshipChanges       []SponsorshipChange
+	// orderBookLedger is the ledger of the order book the trades are priced
+	// against
+	orderBookLedger uint32
+}
+
+func newDryRun(ctx context.Context, finder paths.Finder, state validationState, addresses []string) *dryRun {
+	run := &dryRun{
+		ctx:                ctx,
+		finder:             finder,
+		baseReserve:        state.ledger.BaseReserve,
+		accounts:           make(map[string]*history.AccountEntry, len(state.accounts)),
+		trustLines:         make(map[string]*history.TrustLine, len(state.trustLines)),
+		signers:            make(map[string][]history.AccountSigner, len(state.signers)),
+		order:              append([]string(nil), addresses...),
+		sponsors:           map[string]string{},
+		balanceChangeIndex: map[string]int{},
+	}
+	for address, account := range state.accounts {
+		account := account
+		run.accounts[address] = &account
+	}
+	for key, trustLine := range state.trustLines {
+		trustLine := trustLine
+		run.trustLines[key] = &trustLine
+	}
+	for address, signers := range state.signers {
+		run.signers[address] = append([]history.AccountSigner(nil), signers...)
+	}
+	return run
+}
+
+// apply applies the operation and fills dest with its outcome. The operation
+// fails when a non-nil operationFailure is returned.
+func (d *dryRun) apply(index int, op xdr.Operation, dest *DryRunOperation) (*operationFailure, error) {
+	if _, ok := d.accounts[dest.SourceAccount]; !ok {
+		return failOperation(xdr.OperationResultCodeOpNoAccount, "the operation source account %s does not exist", dest.SourceAccount), nil
+	}
+
+	switch op.Body.Type {
+	case xdr.OperationTypeCreateAccount:
+		return d.createAccount(index, dest.SourceAccount, op.Body.MustCreateAccountOp()), nil
+	case xdr.OperationTypePayment:
+		return d.payment(op, dest.SourceAccount, op.Body.MustPaymentOp()), nil
+	case xdr.OperationTypePathPaymentStrictReceive, xdr.OperationTypePathPaymentStrictSend:
+		return d.pathPayment(op, dest)
+	case xdr.OperationTypeManageSellOffer, xdr.OperationTypeCreatePassiveSellOffer, xdr.OperationTypeManageBuyOffer:
+		return d.offer(index, op, dest)
+	case xdr.OperationTypeSetOptions:
+		return d.setOptions(index, dest.SourceAccount, op.Body.MustSetOptionsOp()), nil
+	case xdr.OperationTypeChangeTrust:
+		line := op.Body.MustChangeTrustOp().Line
+		if line.Type == xdr.AssetTypeAssetTypePoolShare {
+			dest.Supported = false
+			return nil, nil
+		}
+		return d.changeTrust(index, dest.SourceAccount, line.ToAsset(), int64(op.Body.MustChangeTrustOp().Limit)), nil
+	case xdr.OperationTypeBumpSequence:
+		return nil, nil
+	case xdr.OperationTypeCreateClaimableBalance:
+		return d.createClaimableBalance(index, dest.SourceAccount, op.Body.MustCreateClaimableBalanceOp()), nil
+	case xdr.OperationTypeBeginSponsoringFutureReserves:
+		sponsored := op.Body.MustBeginSponsoringFutureReservesOp().SponsoredId.Address()
+		switch {
+		case sponsored == dest.SourceAccount:
+			return failOperation(xdr.BeginSponsoringFutureReservesResultCodeBeginSponsoringFutureReservesMalformed,
+				"the account %s cannot sponsor its own reserves", sponsored), nil
+		case d.sponsors[sponsored] != "":
+			return failOperation(xdr.BeginSponsoringFutureReservesResultCodeBeginSponsoringFutureReservesAlreadySponsored,
+				"the reserves of %s are already sponsored", sponsored), nil
+		case d.sponsors[dest.SourceAccount] != "" || d.isSponsor(sponsored):
+			return failOperation(xdr.BeginSponsoringFutureReservesResultCodeBeginSponsoringFutureReservesRecursive,
+				"the sponsorship of the reserves of %s by %s is recursive", sponsored, dest.SourceAccount), nil
+		}
+		d.sponsors[sponsored] = dest.SourceAccount
+		return nil, nil
+	case xdr.OperationTypeEndSponsoringFutureReserves:
+		if d.sponsors[dest.SourceAccount] == "" {
+			return failOperation(xdr.EndSponsoringFutureReservesResultCodeEndSponsoringFutureReservesNotSponsored,
+				"the reserves of %s are not sponsored", dest.SourceAccount), nil
+		}
+		delete(d.sponsors, dest.SourceAccount)
+		return nil, nil
+	}
+	dest.Supported = false
+	return nil, nil
+}
+
+func (d *dryRun) isSponsor(account string) bool {
+	for _, sponsor := range d.sponsors {
+		if sponsor == account {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *dryRun) createAccount(index int, source string, op xdr.CreateAccountOp) *operationFailure {
+	destination := op.Destination.Address()
+	if _, ok := d.accounts[destination]; ok {
+		return failOperation(xdr.CreateAccountResultCodeCreateAccountAlreadyExist, "the account %s already exists", destination)
+	}
+	native := xdr.MustNewNativeAsset()
+	startingBalance := int64(op.StartingBalance)
+	if available := d.available(source, native); available < startingBalance {
+		return failOperation(xdr.CreateAccountResultCodeCreateAccountUnderfunded,
+			"the account %s cannot send the starting balance of %d stroops, its available balance is %d stroops",
+			source, startingBalance, available)
+	}
+
+	account := &history.AccountEntry{AccountID: destination}
+	if sponsor := d.sponsors[destination]; sponsor != "" {
+		if available := d.available(sponsor, native); available < 2*int64(d.baseReserve) {
+			return failOperation(xdr.CreateAccountResultCodeCreateAccountLowReserve,
+				"the sponsor %s cannot pay the reserve of the account %s", sponsor, destination)
+		}
+		account.NumSponsored = 2
+		account.Sponsor.SetValid(sponsor)
+		d.accounts[sponsor].NumSponsoring += 2
+		d.sponsorshipChanges = append(d.sponsorshipChanges, SponsorshipChange{
+			OperationIndex: index,
+			Sponsor:        sponsor,
+			Account:        destination,
+			EntryType:      SponsoredAccount,
+		})
+	} else if startingBalance < 2*int64(d.baseReserve) {
+		return failOperation(xdr.CreateAccountResultCodeCreateAccountLowReserve,
+			"the starting balance of %d stroops is below the minimum balance of %d stroops", startingBalance, 2*int64(d.baseReserve))
+	}
+	d.accounts[destination] = account
+	d.order = append(d.order, destination)
+	d.changeBalance(source, native, -startingBalance)
+	d.changeBalance(destination, native, startingBalance)
+	return nil
+}
+
+func (d *dryRun) payment(op xdr.Operation, source string, payment xdr.PaymentOp) *operationFailure {
+	destination := payment.Destination.ToAccountId().Address()
+	if _, ok := d.accounts[destination]; !ok {
+		return failOperation(xdr.PaymentResultCodePaymentNoDestination, "the destination account %s does not exist", destination)
+	}
+	if failure := d.checkTrustLines(op, source); failure != nil {
+		return failure
+	}
+	amount := int64(payment.Amount)
+	if available := d.available(source, payment.Asset); available < amount {
+		return failOperation(xdr.PaymentResultCodePaymentUnderfunded,
+			"the account %s cannot send %d of %s, its available balance is %d",
+			source, amount, payment.Asset.StringCanonical(), available)
+	}
+	if capacity := d.capacity(destination, payment.Asset); capacity < amount {
+		return failOperation(xdr.PaymentResultCodePaymentLineFull,
+			"the account %s can only receive %d of %s", destination, capacity, payment.Asset.StringCanonical())
+	}
+	d.changeBalance(source, payment.Asset, -amount)
+	d.changeBalance(destination, payment.Asset, amount)
+	return nil
+}
+
+// pathPaymentCodes are the result codes of path payment operations.
+type pathPaymentCodes struct {
+	noDestination, tooFewOffers, crossSelf, underfunded, lineFull, bound interface{}
+}
+
+func (d *dryRun) pathPayment(op xdr.Operation, dest *DryRunOperation) (*operationFailure, error) {
+	var (
+		destination          string
+		sendAsset, destAsset xdr.Asset
+		path                 []xdr.Asset
+		codes                pathPaymentCodes
+	)
+	strictSend := op.Body.Type == xdr.OperationTypePathPaymentStrictSend
+	if strictSend {
+		pathPayment := op.Body.MustPathPaymentStrictSendOp()
+		destination = pathPayment.Destination.ToAccountId().Address()
+		sendAsset, destAsset, path = pathPayment.SendAsset, pathPayment.DestAsset, pathPayment.Path
+		dest.SourceAmount, dest.MaximumSent = int64(pathPayment.SendAmount), int64(pathPayment.SendAmount)
+		dest.MinimumReceived = int64(pathPayment.DestMin)
+		codes = pathPaymentCodes{
+			noDestination: xdr.PathPaymentStrictSendResultCodePathPaymentStrictSendNoDestination,
+			tooFewOffers:  xdr.PathPaymentStrictSendResultCodePathPaymentStrictSendTooFewOffers,
+			crossSelf:     xdr.PathPaymentStrictSendResultCodePathPaymentStrictSendOfferCrossSelf,
+			underfunded:   xdr.PathPaymentStrictSendResultCodePathPaymentStrictSendUnderfunded,
+			lineFull:      xdr.PathPaymentStrictSendResultCodePathPaymentStrictSendLineFull,
+			bound:         xdr.PathPaymentStrictSendResultCodePathPaymentStrictSendUnderDestmin,
+		}
+	} else {
+		pathPayment := op.Body.MustPathPaymentStrictReceiveOp()
+		destination = pathPayment.Destination.ToAccountId().Address()
+		sendAsset, destAsset, path = pathPayment.SendAsset, pathPayment.DestAsset, pathPayment.Path
+		dest.DestinationAmount, dest.MinimumReceived = int64(pathPayment.DestAmount), int64(pathPayment.DestAmount)
+		dest.MaximumSent = int64(pathPayment.SendMax)
+		codes = pathPaymentCodes{
+			noDestination: xdr.PathPaymentStrictReceiveResultCodePathPaymentStrictReceiveNoDestination,
+			tooFewOffers:  xdr.PathPaymentStrictReceiveResultCodePathPaymentStrictReceiveTooFewOffers,
+			crossSelf:     xdr.PathPaymentStrictReceiveResultCodePathPaymentStrictReceiveOfferCrossSelf,
+			underfunded:   xdr.PathPaymentStrictReceiveResultCodePathPaymentStrictReceiveUnderfunded,
+			lineFull:      xdr.PathPaymentStrictReceiveResultCodePathPaymentStrictReceiveLineFull,
+			bound:         xdr.PathPaymentStrictReceiveResultCodePathPaymentStrictReceiveOverSendmax,
+		}
+	}
+
+	if _, ok := d.accounts[destination]; !ok {
+		return failOperation(codes.noDestination, "the destination account %s does not exist", destination), nil
+	}
+	if failure := d.checkTrustLines(op, dest.SourceAccount); failure != nil {
+		return failure, nil
+	}
+
+	// the assets traded by each hop of the path, in order
+	assets := []xdr.Asset{sendAsset}
+	for _, asset := range append(append([]xdr.Asset(nil), path...), destAsset) {
+		if !asset.Equals(assets[len(assets)-1]) {
+			assets = append(assets, asset)
+		}
+	}
+	if len(assets) > 1 && d.finder == nil {
+		dest.Supported = false
+		return nil, nil
+	}
+
+	sourceAccount := xdr.MustAddress(dest.SourceAccount)
+	quotes := make([]paths.Quote, len(assets)-1)
+	if strictSend {
+		amount := xdr.Int64(dest.SourceAmount)
+		for i := 0; i < len(quotes); i++ {
+			quote, ledger, err := d.finder.Quote(d.ctx, paths.QuoteQuery{
+				StrictSend:       true,
+				SourceAsset:      assets[i],
+				DestinationAsset: assets[i+1],
+				Amount:           amount,
+				SourceAccount:    &sourceAccount,
+			})
+			if failure, err := d.tradeFailure(err, codes); failure != nil || err != nil {
+				return failure, err
+			}
+			if err = d.useOrderBook(ledger); err != nil {
+				return nil, err
+			}
+			quotes[i], amount = quote, quote.DestinationAmount
+		}
+		dest.DestinationAmount = int64(amount)
+	} else {
+		amount := xdr.Int64(dest.DestinationAmount)
+		for i := len(quotes) - 1; i >= 0; i-- {
+			quote, ledger, err := d.finder.Quote(d.ctx, paths.QuoteQuery{
+				StrictSend:       false,
+				SourceAsset:      assets[i],
+				DestinationAsset: assets[i+1],
+				Amount:           amount,
+				SourceAccount:    &sourceAccount,
+			})
+			if failure, err := d.tradeFailure(err, codes); failure != nil || err != nil {
+				return failure, err
+			}
+			if err = d.useOrderBook(ledger); err != nil {
+				return nil, err
+			}
+			quotes[i], amount = quote, quote.SourceAmount
+		}
+		dest.SourceAmount = int64(amount)
+	}
+	for _, quote := range quotes {
+		dest.OffersCrossed = append(dest.OffersCrossed, quote.Offers...)
+		if quote.Pool != nil {
+			dest.LiquidityPools = append(dest.LiquidityPools, *quote.Pool)
+		}
+	}
+
+	if strictSend && dest.DestinationAmount < dest.MinimumReceived {
+		return failOperation(codes.bound, "the payment would deliver %d of %s, less than the minimum %d",
+			dest.DestinationAmount, destAsset.StringCanonical(), dest.MinimumReceived), nil
+	}
+	if !strictSend && dest.SourceAmount > dest.MaximumSent {
+		return failOperation(codes.bound, "the payment would cost %d of %s, more than the maximum %d",
+			dest.SourceAmount, sendAsset.StringCanonical(), dest.MaximumSent), nil
+	}
+	if available := d.available(dest.SourceAccount, sendAsset); available < dest.SourceAmount {
+		return failOperation(codes.underfunded, "the account %s cannot send %d of %s, its available balance is %d",
+			dest.SourceAccount, dest.SourceAmount, sendAsset.StringCanonical(), available), nil
+	}
+	if capacity := d.capacity(destination, destAsset); capacity < dest.DestinationAmount {
+		return failOperation(codes.lineFull, "the account %s can only receive %d of %s",
+			destination, capacity, destAsset.StringCanonical()), nil
+	}
+	d.changeBalance(dest.SourceAccount, sendAsset, -dest.SourceAmount)
+	d.changeBalance(destination, destAsset, dest.DestinationAmount)
+	return nil, nil
+}
+
+// useOrderBook records the ledger of the order book a trade was priced
+// against. It returns ErrOrderBookChanged when a previous trade was priced
+// against another ledger.
+func (d *dryRun) useOrderBook(ledger uint32) error {
+	if d.orderBookLedger != 0 && d.orderBookLedger != ledger {
+		return ErrOrderBookChanged
+	}
+	d.orderBookLedger = ledger
+	return nil
+}
+
+// tradeFailure converts the errors of the path finder to operation failures.
+func (d *dryRun) tradeFailure(err error, codes pathPaymentCodes) (*operationFailure, error) {
+	switch err {
+	case nil:
+		return nil, nil
+	case simplepath.ErrQuoteNotFound:
+		return failOperation(codes.tooFewOffers, "the order book does not hold enough liquidity for the payment"), nil
+	case simplepath.ErrOfferCrossSelf:
+		return failOperation(codes.crossSelf, "the payment would cross an offer of its source account"), nil
+	}
+	return nil, err
+}
+
+// offerCodes are the result codes of offer operations.
+type offerCodes struct {
+	crossSelf, underfunded, lineFull, lowReserve interface{}
+}
+
+func (d *dryRun) offer(index int, op xdr.Operation, dest *DryRunOperation) (*operationFailure, error) {
+	query := paths.OfferQuery{SellerAccount: xdr.MustAddress(dest.SourceAccount)}
+	var offerID xdr.Int64
+	codes := offerCodes{
+		crossSelf:   xdr.ManageSellOfferResultCodeManageSellOfferCrossSelf,
+		underfunded: xdr.ManageSellOfferResultCodeManageSellOfferUnderfunded,
+		lineFull:    xdr.ManageSellOfferResultCodeManageSellOfferLineFull,
+		lowReserve:  xdr.ManageSellOfferResultCodeManageSellOfferLowReserve,
+	}
+	switch op.Body.Type {
+	case xdr.OperationTypeManageSellOffer:
+		offer := op.Body.MustManageSellOfferOp()
+		query.Amount, query.Price, offerID = offer.Amount, offer.Price, offer.OfferId
+	case xdr.OperationTypeCreatePassiveSellOffer:
+		offer := op.Body.MustCreatePassiveSellOfferOp()
+		query.Amount, query.Price, query.Passive = offer.Amount, offer.Price, true
+	case xdr.OperationTypeManageBuyOffer:
+		offer := op.Body.MustManageBuyOfferOp()
+		query.Amount, query.Price, offerID, query.Buy = offer.BuyAmount, offer.Price, offer.OfferId, true
+		codes = offerCodes{
+			crossSelf:   xdr.ManageBuyOfferResultCodeManageBuyOfferCrossSelf,
+			underfunded: xdr.ManageBuyOfferResultCodeManageBuyOfferUnderfunded,
+			lineFull:    xdr.ManageBuyOfferResultCodeManageBuyOfferLineFull,
+			lowReserve:  xdr.ManageBuyOfferResultCodeManageBuyOfferLowReserve,
+		}
+	}
+	query.SellingAsset, query.BuyingAsset = offerAssets(op)
+	// the offers of the order book aren't loaded, so updating or deleting
+	// them isn't supported
+	if offerID != 0 || query.Amount <= 0 || d.finder == nil {
+		dest.Supported = false
+		return nil, nil
+	}
+
+	if failure := d.checkTrustLines(op, dest.SourceAccount); failure != nil {
+		return failure, nil
+	}
+	source := dest.SourceAccount
+	if available := d.available(source, query.SellingAsset); available <= 0 ||
+		(!query.Buy && available < int64(query.Amount)) {
+		return failOperation(codes.underfunded, "the account %s cannot sell %s, its available balance is %d",
+			source, query.SellingAsset.StringCanonical(), available), nil
+	}
+
+	quote, ledger, err := d.finder.CrossOffer(d.ctx, query)
+	switch err {
+	case nil:
+	case simplepath.ErrOfferCrossSelf:
+		return failOperation(codes.crossSelf, "the offer would cross an offer of %s", source), nil
+	default:
+		return nil, err
+	}
+	if err = d.useOrderBook(ledger); err != nil {
+		return nil, err
+	}
+	dest.SourceAmount, dest.DestinationAmount = int64(quote.SourceAmount), int64(quote.DestinationAmount)
+	dest.OffersCrossed = quote.Offers
+
+	if available := d.available(source, query.SellingAsset); available < dest.SourceAmount {
+		return failOperation(codes.underfunded, "the account %s cannot sell %d of %s, its available balance is %d",
+			source, dest.SourceAmount, query.SellingAsset.StringCanonical(), available), nil
+	}
+	if capacity := d.capacity(source, query.BuyingAsset); capacity < dest.DestinationAmount {
+		return failOperation(codes.lineFull, "the account %s can only receive %d of %s",
+			source, capacity, query.BuyingAsset.StringCanonical()), nil
+	}
+	d.changeBalance(source, query.SellingAsset, -dest.SourceAmount)
+	d.changeBalance(source, query.BuyingAsset, dest.DestinationAmount)
+
+	// the rest of the offer is left in the order book
+	var selling, buying int64
+	n, p := int64(query.Price.N), int64(query.Price.D)
+	if query.Buy {
+		buying = int64(query.Amount) - dest.DestinationAmount
+		selling = mulPrice(buying, n, p)
+		dest.OfferAmount = buying
+	} else {
+		selling = int64(query.Amount) - dest.SourceAmount
+		buying = mulPrice(selling, n, p)
+		dest.OfferAmount = selling
+	}
+	if dest.OfferAmount <= 0 {
+		return nil, nil
+	}
+	if available := d.available(source, query.SellingAsset); available < selling {
+		return failOperation(codes.underfunded, "the account %s cannot sell %d of %s in the offer, its available balance is %d",
+			source, selling, query.SellingAsset.StringCanonical(), available), nil
+	}
+	if capacity := d.capacity(source, query.BuyingAsset); capacity < buying {
+		return failOperation(codes.lineFull, "the account %s can only buy %d of %s in the offer",
+			source, capacity, query.BuyingAsset.StringCanonical()), nil
+	}
+	if failure := d.addReserves(index, source, SponsoredOffer, 1, codes.lowReserve); failure != nil {
+		return failure, nil
+	}
+	d.addLiabilities(source, query.SellingAsset, selling, 0)
+	d.addLiabilities(source, query.BuyingAsset, 0, buying)
+	return nil, nil
+}
+
+// mulPrice returns amount*n/d rounded down, or the largest amount on overflow.
+func mulPrice(amount, n, d int64) int64 {
+	result, err := price.MulFractionRoundDown(amount, n, d)
+	if err != nil {
+		return math.MaxInt64
+	}
+	return result
+}
+
+func (d *dryRun) setOptions(index int, source string, op xdr.SetOptionsOp) *operationFailure {
+	if op.Signer == nil {
+		return nil
+	}
+	key := op.Signer.Key.Address()
+	signers := d.signers[source]
+	for i, signer := range signers {
+		if signer.Signer != key {
+			continue
+		}
+		if op.Signer.Weight > 0 {
+			signers[i].Weight = int32(op.Signer.Weight)
+			return nil
+		}
+		d.signers[source] = append(signers[:i:i], signers[i+1:]...)
+		d.removeReserves(index, source, signer.Sponsor.String, SponsoredSigner, 1)
+		return nil
+	}
+	if op.Signer.Weight == 0 {
+		return nil
+	}
+
+	// the master key is listed with the signers of the account
+	count := 0
+	for _, signer := range signers {
+		if signer.Signer != source {
+			count++
+		}
+	}
+	if count >= 20 {
+		return failOperation(xdr.SetOptionsResultCodeSetOptionsTooManySigners, "the account %s has too many signers", source)
+	}
+	if failure := d.addReserves(index, source, SponsoredSigner, 1, xdr.SetOptionsResultCodeSetOptionsLowReserve); failure != nil {
+		return failure
+	}
+	d.signers[source] = append(signers, history.AccountSigner{
+		Account: source,
+		Signer:  key,
+		Weight:  int32(op.Signer.Weight),
+	})
+	return nil
+}
+
+func (d *dryRun) changeTrust(index int, source string, asset xdr.Asset, limit int64) *operationFailure {
+	if asset.Type == xdr.AssetTypeAssetTypeNative {
+		return failOperation(xdr.ChangeTrustResultCodeChangeTrustMalformed, "native assets don't have trust lines")
+	}
+	issuer := asset.GetIssuer()
+	if issuer == source {
+		return failOperation(xdr.ChangeTrustResultCodeChangeTrustMalformed, "the issuer %s cannot trust its own asset", issuer)
+	}
+	key, err := trustLineKey(source, asset)
+	if err != nil {
+		return failOperation(xdr.ChangeTrustResultCodeChangeTrustMalformed, "invalid asset %s", asset.StringCanonical())
+	}
+
+	if trustLine, ok := d.trustLines[key]; ok {
+		if limit == 0 {
+			if trustLine.Balance > 0 || trustLine.BuyingLiabilities > 0 || trustLine.SellingLiabilities > 0 {
+				return failOperation(xdr.ChangeTrustResultCodeChangeTrustInvalidLimit,
+					"the trust line of %s for %s cannot be removed, it holds a balance or offers", source, asset.StringCanonical())
+			}
+			delete(d.trustLines, key)
+			d.removeReserves(index, source, trustLine.Sponsor.String, SponsoredTrustLine, 1)
+			return nil
+		}
+		if limit < trustLine.Balance+trustLine.BuyingLiabilities {
+			return failOperation(xdr.ChangeTrustResultCodeChangeTrustInvalidLimit,
+				"the limit %d is below the balance and the buying liabilities of the trust line", limit)
+		}
+		trustLine.Limit = limit
+		return nil
+	}
+
+	if limit == 0 {
+		return failOperation(xdr.ChangeTrustResultCodeChangeTrustInvalidLimit,
+			"the account %s does not trust %s", source, asset.StringCanonical())
+	}
+	issuerAccount, ok := d.accounts[issuer]
+	if !ok {
+		return failOperation(xdr.ChangeTrustResultCodeChangeTrustNoIssuer, "the issuer %s does not exist", issuer)
+	}
+	if failure := d.addReserves(index, source, SponsoredTrustLine, 1, xdr.ChangeTrustResultCodeChangeTrustLowReserve); failure != nil {
+		return failure
+	}
+
+	trustLine := &history.TrustLine{AccountID: source, Limit: limit, LedgerKey: key}
+	if xdr.AccountFlags(issuerAccount.Flags)&xdr.AccountFlagsAuthRequiredFlag == 0 {
+		trustLine.Flags = uint32(xdr.TrustLineFlagsAuthorizedFlag)
+	}
+	if sponsor := d.sponsors[source]; sponsor != "" {
+		trustLine.Sponsor.SetValid(sponsor)
+	}
+	d.trustLines[key] = trustLine
+	d.trustLinesCreated = append(d.trustLinesCreated, TrustLineCreated{
+		Account:    source,
+		Asset:      asset,
+		Limit:      limit,
+		Authorized: xdr.TrustLineFlags(trustLine.Flags).IsAuthorized(),
+		Sponsor:    trustLine.Sponsor.String,
+	})
+	return nil
+}
+
+func (d *dryRun) createClaimableBalance(index int, source string, create xdr.CreateClaimableBalanceOp) *operationFailure {
+	if create.Asset.Type != xdr.AssetTypeAssetTypeNative && create.Asset.GetIssuer() != source {
+		key, err := trustLineKey(source, create.Asset)
+		if err != nil {
+			return failOperation(xdr.CreateClaimableBalanceResultCodeCreateClaimableBalanceMalformed, "invalid asset")
+		}
+		trustLine, ok := d.trustLines[key]
+		if !ok {
+			return failOperation(xdr.CreateClaimableBalanceResultCodeCreateClaimableBalanceNoTrust,
+				"the account %s does not trust %s", source, create.Asset.StringCanonical())
+		}
+		if !xdr.TrustLineFlags(trustLine.Flags).IsAuthorized() {
+			return failOperation(xdr.CreateClaimableBalanceResultCodeCreateClaimableBalanceNotAuthorized,
+				"the account %s is not authorized to hold %s", source, create.Asset.StringCanonical())
+		}
+	}
+	amount := int64(create.Amount)
+	if available := d.available(source, create.Asset); available < amount {
+		return failOperation(xdr.CreateClaimableBalanceResultCodeCreateClaimableBalanceUnderfunded,
+			"the account %s cannot send %d of %s, its available balance is %d",
+			source, amount, create.Asset.StringCanonical(), available)
+	}
+	reserves := uint32(len(create.Claimants))
+	if failure := d.addReserves(index, source, SponsoredClaimableBalance, reserves,
+		xdr.CreateClaimableBalanceResultCodeCreateClaimableBalanceLowReserve); failure != nil {
+		return failure
+	}
+
+	sponsor := source
+	if d.sponsors[source] != "" {
+		sponsor = d.sponsors[source]
+	}
+	created := ClaimableBalanceCreated{Sponsor: sponsor, Asset: create.Asset, Amount: amount}
+	for _, claimant := range create.Claimants {
+		created.Claimants = append(created.Claimants, claimant.MustV0().Destination.Address())
+	}
+	d.claimableBalancesCreated = append(d.claimableBalancesCreated, created)
+	d.changeBalance(source, create.Asset, -amount)
+	return nil
+}
+
+// addReserves adds the reserves of new entries of account, which are paid by
+// the sponsor of its future reserves if there is one. Claimable balances don't
+// belong to an account, their reserves are paid by their sponsor.
+func (d *dryRun) addReserves(index int, account, entryType string, count uint32, lowReserve interface{}) *operationFailure {
+	payer := account
+	sponsor := d.sponsors[account]
+	if sponsor != "" {
+		payer = sponsor
+	}
+	reserve := int64(count) * int64(d.baseReserve)
+	if available := d.available(payer, xdr.MustNewNativeAsset()); available < reserve {
+		return failOperation(lowReserve, "the account %s cannot pay the reserve of %d stroops, its available balance is %d stroops",
+			payer, reserve, available)
+	}
+
+	if entryType != SponsoredClaimableBalance {
+		d.accounts[account].NumSubEntries += count
+	}
+	if sponsor == "" {
+		if entryType == SponsoredClaimableBalance {
+			d.accounts[account].NumSponsoring += count
+		}
+		return nil
+	}
+	change := SponsorshipChange{OperationIndex: index, Sponsor: sponsor, EntryType: entryType}
+	d.accounts[sponsor].NumSponsoring += count
+	if entryType != SponsoredClaimableBalance {
+		d.accounts[account].NumSponsored += count
+		change.Account = account
+	}
+	d.sponsorshipChanges = append(d.sponsorshipChanges, change)
+	return nil
+}
+
+// removeReserves releases the reserves of the removed subentries of account,
+// which were paid by sponsor if it isn't empty.
+func (d *dryRun) removeReserves(index int, account, sponsor, entryType string, count uint32) {
+	d.accounts[account].NumSubEntries -= count
+	if sponsor == "" {
+		return
+	}
+	d.accounts[account].NumSponsored -= count
+	if sponsorAccount, ok := d.accounts[sponsor]; ok {
+		sponsorAccount.NumSponsoring -= count
+	}
+	d.sponsorshipChanges = append(d.sponsorshipChanges, SponsorshipChange{
+		OperationIndex: index,
+		Sponsor:        sponsor,
+		Account:        account,
+		EntryType:      entryType,
+		Removed:        true,
+	})
+}
+
+// checkTrustLines checks the trust lines which must exist and be authorized
+// for the operation to succeed.
+func (d *dryRun) checkTrustLines(op xdr.Operation, source string) *operationFailure {
+	for _, check := range trustLineChecks(op, source) {
+		key, err := trustLineKey(check.account, check.asset)
+		if err != nil {
+			return failOperation(check.noTrust, "invalid asset %s", check.asset.StringCanonical())
+		}
+		trustLine, ok := d.trustLines[key]
+		if !ok {
+			return failOperation(check.noTrust, "the account %s does not trust %s", check.account, check.asset.StringCanonical())
+		}
+		if !xdr.TrustLineFlags(trustLine.Flags).IsAuthorized() {
+			return failOperation(check.notAuthorized, "the account %s is not authorized to hold %s",
+				check.account, check.asset.StringCanonical())
+		}
+	}
+	return nil
+}
+
+// available returns the amount of the asset the account can send.
+func (d *dryRun) available(account string, asset xdr.Asset) int64 {
+	if asset.Type == xdr.AssetTypeAssetTypeNative {
+		entry, ok := d.accounts[account]
+		if !ok {
+			return 0
+		}
+		return availableBalance(*entry, d.baseReserve)
+	}
+	if asset.GetIssuer() == account {
+		return math.MaxInt64
+	}
+	if trustLine := d.trustLine(account, asset); trustLine != nil {
+		return trustLine.Balance - trustLine.SellingLiabilities
+	}
+	return 0
+}
+
+// capacity returns the amount of the asset the account can receive.
+func (d *dryRun) capacity(account string, asset xdr.Asset) int64 {
+	if asset.Type == xdr.AssetTypeAssetTypeNative {
+		entry, ok := d.accounts[account]
+		if !ok {
+			return 0
+		}
+		return math.MaxInt64 - entry.Balance - entry.BuyingLiabilities
+	}
+	if asset.GetIssuer() == account {
+		return math.MaxInt64
+	}
+	if trustLine := d.trustLine(account, asset); trustLine != nil {
+		return trustLine.Limit - trustLine.Balance - trustLine.BuyingLiabilities
+	}
+	return 0
+}
+
+func (d *dryRun) trustLine(account string, asset xdr.Asset) *history.TrustLine {
+	key, err := trustLineKey(account, asset)
+	if err != nil {
+		return nil
+	}
+	return d.trustLines[key]
+}
+
+func (d *dryRun) addLiabilities(account string, asset xdr.Asset, selling, buying int64) {
+	if asset.Type == xdr.AssetTypeAssetTypeNative {
+		d.accounts[account].SellingLiabilities += selling
+		d.accounts[account].BuyingLiabilities += buying
+	} else if trustLine := d.trustLine(account, asset); trustLine != nil {
+		trustLine.SellingLiabilities += selling
+		trustLine.BuyingLiabilities += buying
+	}
+}
+
+// changeBalance adds amount to the balance of the account. The issuers don't
+// hold balances of their assets.
+func (d *dryRun) changeBalance(account string, asset xdr.Asset, amount int64) {
+	if asset.Type == xdr.AssetTypeAssetTypeNative {
+		d.accounts[account].Balance += amount
+	} else if asset.GetIssuer() == account {
+		return
+	} else if trustLine := d.trustLine(account, asset); trustLine != nil {
+		trustLine.Balance += amount
+	}
+
+	key := account + ":" + asset.StringCanonical()
+	if i, ok := d.balanceChangeIndex[key]; ok {
+		d.balanceChanges[i].Amount += amount
+		return
+	}
+	d.balanceChangeIndex[key] = len(d.balanceChanges)
+	d.balanceChanges = append(d.balanceChanges, BalanceChange{Account: account, Asset: asset, Amount: amount})
+}
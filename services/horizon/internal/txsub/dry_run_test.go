@@ -0,0 +1,321 @@
+package txsub
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pownieh/stellar_go/keypair"
+	"github.com/pownieh/stellar_go/network"
+	"github.com/pownieh/stellar_go/services/horizon/internal/paths"
+	"github.com/pownieh/stellar_go/services/horizon/internal/simplepath"
+	"github.com/pownieh/stellar_go/txnbuild"
+	"github.com/pownieh/stellar_go/xdr"
+)
+
+// dryRunState returns the validator state with a base fee and 50 USD held by
+// the source account.
+func dryRunState() validationState {
+	state := validatorState()
+	state.ledger.BaseFee = 100
+	for key, trustLine := range state.trustLines {
+		trustLine.Limit = 10000000000
+		if trustLine.AccountID == validatorSource.Address() {
+			trustLine.Balance = 500000000
+		}
+		state.trustLines[key] = trustLine
+	}
+	return state
+}
+
+func runDryRun(t *testing.T, finder paths.Finder, state validationState, tx *txnbuild.Transaction, signers ...*keypair.Full) DryRunResult {
+	tx, err := tx.Sign(network.TestNetworkPassphrase, signers...)
+	require.NoError(t, err)
+	state.ignoreSignatures = len(signers) == 0
+	var addresses []string
+	for address := range state.accounts {
+		addresses = append(addresses, address)
+	}
+	result, err := dryRunTransaction(context.Background(), tx.ToXDR(), network.TestNetworkPassphrase, finder, state, addresses)
+	require.NoError(t, err)
+	return result
+}
+
+func TestDryRunPayment(t *testing.T) {
+	usd := xdr.MustNewCreditAsset("USD", validatorIssuer.Address())
+	native := xdr.MustNewNativeAsset()
+
+	result := runDryRun(t, nil, dryRunState(), validatorTransaction(t, 11, txnbuild.Preconditions{}))
+	assert.False(t, result.Signed)
+	assert.True(t, result.Complete)
+	assert.Nil(t, result.Error)
+	assert.Equal(t, int64(100), result.FeeCharged)
+	assert.Equal(t, validatorSource.Address(), result.FeeAccount)
+	require.Len(t, result.Operations, 1)
+	assert.Equal(t, xdr.PaymentResultCodePaymentSuccess, result.Operations[0].Code)
+	assert.Equal(t, []BalanceChange{
+		{Account: validatorSource.Address(), Asset: native, Amount: -100},
+		{Account: validatorSource.Address(), Asset: usd, Amount: -100000000},
+		{Account: validatorDestination.Address(), Asset: usd, Amount: 100000000},
+	}, result.BalanceChanges)
+	assert.Empty(t, result.ReserveChanges)
+
+	// the signatures are checked when the transaction is signed
+	result = runDryRun(t, nil, dryRunState(), validatorTransaction(t, 11, txnbuild.Preconditions{}), validatorDestination)
+	assert.True(t, result.Signed)
+	assertValidationError(t, result.Error, xdr.TransactionResultCodeTxBadAuth, nil)
+	assert.Zero(t, result.FeeCharged)
+	assert.Empty(t, result.BalanceChanges)
+
+	// only the fee is charged when an operation fails
+	tx := validatorTransaction(t, 11, txnbuild.Preconditions{}, &txnbuild.Payment{
+		Destination: validatorDestination.Address(),
+		Amount:      "60",
+		Asset:       validatorUSD,
+	})
+	result = runDryRun(t, nil, dryRunState(), tx)
+	assertValidationError(t, result.Error, xdr.TransactionResultCodeTxFailed, xdr.PaymentResultCodePaymentUnderfunded)
+	assert.Equal(t, xdr.PaymentResultCodePaymentUnderfunded, result.Operations[0].Code)
+	assert.Equal(t, []BalanceChange{{Account: validatorSource.Address(), Asset: native, Amount: -100}}, result.BalanceChanges)
+
+	newAccount := keypair.MustRandom()
+	tx = validatorTransaction(t, 11, txnbuild.Preconditions{}, &txnbuild.Payment{
+		Destination: newAccount.Address(),
+		Amount:      "1",
+		Asset:       txnbuild.NativeAsset{},
+	})
+	result = runDryRun(t, nil, dryRunState(), tx)
+	assertValidationError(t, result.Error, xdr.TransactionResultCodeTxFailed, xdr.PaymentResultCodePaymentNoDestination)
+
+	// the operations the dry run doesn't support stop it
+	tx = validatorTransaction(t, 11, txnbuild.Preconditions{},
+		&txnbuild.Payment{Destination: validatorDestination.Address(), Amount: "1", Asset: txnbuild.NativeAsset{}},
+		&txnbuild.ManageData{Name: "name", Value: []byte("value")},
+		&txnbuild.Payment{Destination: validatorDestination.Address(), Amount: "1", Asset: txnbuild.NativeAsset{}},
+	)
+	result = runDryRun(t, nil, dryRunState(), tx)
+	assert.False(t, result.Complete)
+	assert.Nil(t, result.Error)
+	assert.Equal(t, xdr.PaymentResultCodePaymentSuccess, result.Operations[0].Code)
+	assert.False(t, result.Operations[1].Supported)
+	assert.Nil(t, result.Operations[1].Code)
+	assert.True(t, result.Operations[2].Supported)
+	assert.Nil(t, result.Operations[2].Code)
+}
+
+func TestDryRunPathPayment(t *testing.T) {
+	usd := xdr.MustNewCreditAsset("USD", validatorIssuer.Address())
+	native := xdr.MustNewNativeAsset()
+	offer := xdr.OfferEntry{
+		SellerId: xdr.MustAddress(validatorIssuer.Address()),
+		OfferId:  1,
+		Selling:  native,
+		Buying:   usd,
+		Price:    xdr.Price{N: 1, D: 2},
+		Amount:   1000000000,
+	}
+	// the offers of the source account aren't crossed
+	sourceAccount := xdr.MustAddress(validatorSource.Address())
+	finder := &paths.MockFinder{}
+	finder.On("Quote", mock.Anything, paths.QuoteQuery{
+		StrictSend:       true,
+		SourceAsset:      usd,
+		DestinationAsset: native,
+		Amount:           100000000,
+		SourceAccount:    &sourceAccount,
+	}).Return(paths.Quote{
+		Source:            usd.String(),
+		SourceAmount:      100000000,
+		Destination:       native.String(),
+		DestinationAmount: 200000000,
+		Offers:            []paths.QuoteOffer{{Offer: offer, Amount: 200000000}},
+	}, uint32(100), nil)
+	finder.On("Quote", mock.Anything, paths.QuoteQuery{
+		StrictSend:       true,
+		SourceAsset:      usd,
+		DestinationAsset: native,
+		Amount:           500000000,
+		SourceAccount:    &sourceAccount,
+	}).Return(paths.Quote{}, uint32(100), simplepath.ErrQuoteNotFound)
+
+	pathPayment := func(amount, destMin string) *txnbuild.Transaction {
+		return validatorTransaction(t, 11, txnbuild.Preconditions{}, &txnbuild.PathPaymentStrictSend{
+			SendAsset:   validatorUSD,
+			SendAmount:  amount,
+			Destination: validatorDestination.Address(),
+			DestAsset:   txnbuild.NativeAsset{},
+			DestMin:     destMin,
+		})
+	}
+
+	result := runDryRun(t, finder, dryRunState(), pathPayment("10", "15"))
+	assert.Nil(t, result.Error)
+	op := result.Operations[0]
+	assert.Equal(t, xdr.PathPaymentStrictSendResultCodePathPaymentStrictSendSuccess, op.Code)
+	assert.Equal(t, int64(100000000), op.SourceAmount)
+	assert.Equal(t, int64(200000000), op.DestinationAmount)
+	assert.Equal(t, int64(150000000), op.MinimumReceived)
+	assert.Equal(t, []paths.QuoteOffer{{Offer: offer, Amount: 200000000}}, op.OffersCrossed)
+	assert.Equal(t, uint32(100), result.OrderBookLedger)
+	assert.Equal(t, []BalanceChange{
+		{Account: validatorSource.Address(), Asset: native, Amount: -100},
+		{Account: validatorSource.Address(), Asset: usd, Amount: -100000000},
+		{Account: validatorDestination.Address(), Asset: native, Amount: 200000000},
+	}, result.BalanceChanges)
+
+	result = runDryRun(t, finder, dryRunState(), pathPayment("10", "25"))
+	assertValidationError(t, result.Error, xdr.TransactionResultCodeTxFailed,
+		xdr.PathPaymentStrictSendResultCodePathPaymentStrictSendUnderDestmin)
+	// the amount expected is reported with the failure
+	assert.Equal(t, int64(200000000), result.Operations[0].DestinationAmount)
+
+	result = runDryRun(t, finder, dryRunState(), pathPayment("50", "1"))
+	assertValidationError(t, result.Error, xdr.TransactionResultCodeTxFailed,
+		xdr.PathPaymentStrictSendResultCodePathPaymentStrictSendTooFewOffers)
+
+	// path payments aren't supported without the order book
+	result = runDryRun(t, nil, dryRunState(), pathPayment("10", "15"))
+	assert.False(t, result.Complete)
+	assert.False(t, result.Operations[0].Supported)
+}
+
+func TestDryRunOffer(t *testing.T) {
+	usd := xdr.MustNewCreditAsset("USD", validatorIssuer.Address())
+	native := xdr.MustNewNativeAsset()
+	finder := &paths.MockFinder{}
+	finder.On("CrossOffer", mock.Anything, paths.OfferQuery{
+		SellingAsset:  usd,
+		BuyingAsset:   native,
+		Amount:        100000000,
+		Price:         xdr.Price{N: 2, D: 1},
+		SellerAccount: xdr.MustAddress(validatorSource.Address()),
+	}).Return(paths.Quote{
+		Source:            usd.String(),
+		SourceAmount:      40000000,
+		Destination:       native.String(),
+		DestinationAmount: 80000000,
+	}, uint32(100), nil)
+
+	tx := validatorTransaction(t, 11, txnbuild.Preconditions{}, &txnbuild.ManageSellOffer{
+		Selling: validatorUSD,
+		Buying:  txnbuild.NativeAsset{},
+		Amount:  "10",
+		Price:   xdr.Price{N: 2, D: 1},
+	})
+	result := runDryRun(t, finder, dryRunState(), tx)
+	assert.Nil(t, result.Error)
+	op := result.Operations[0]
+	assert.Equal(t, xdr.ManageSellOfferResultCodeManageSellOfferSuccess, op.Code)
+	assert.Equal(t, int64(40000000), op.SourceAmount)
+	assert.Equal(t, int64(80000000), op.DestinationAmount)
+	assert.Equal(t, int64(60000000), op.OfferAmount)
+	assert.Equal(t, []BalanceChange{
+		{Account: validatorSource.Address(), Asset: native, Amount: 80000000 - 100},
+		{Account: validatorSource.Address(), Asset: usd, Amount: -40000000},
+	}, result.BalanceChanges)
+	// the offer left in the order book is a subentry
+	assert.Equal(t, []ReserveChange{
+		{Account: validatorSource.Address(), Before: 10000000, After: 15000000},
+	}, result.ReserveChanges)
+}
+
+func TestDryRunOrderBookChanged(t *testing.T) {
+	usd := xdr.MustNewCreditAsset("USD", validatorIssuer.Address())
+	native := xdr.MustNewNativeAsset()
+	query := paths.OfferQuery{
+		SellingAsset:  usd,
+		BuyingAsset:   native,
+		Amount:        100000000,
+		Price:         xdr.Price{N: 2, D: 1},
+		SellerAccount: xdr.MustAddress(validatorSource.Address()),
+	}
+	quote := paths.Quote{
+		Source:            usd.String(),
+		SourceAmount:      40000000,
+		Destination:       native.String(),
+		DestinationAmount: 80000000,
+	}
+	finder := &paths.MockFinder{}
+	defer finder.AssertExpectations(t)
+	finder.On("CrossOffer", mock.Anything, query).Return(quote, uint32(100), nil).Once()
+	// the order book is updated before the second offer is priced
+	finder.On("CrossOffer", mock.Anything, query).Return(quote, uint32(101), nil).Once()
+
+	offer := &txnbuild.ManageSellOffer{
+		Selling: validatorUSD,
+		Buying:  txnbuild.NativeAsset{},
+		Amount:  "10",
+		Price:   xdr.Price{N: 2, D: 1},
+	}
+	tx, err := validatorTransaction(t, 11, txnbuild.Preconditions{}, offer, offer).
+		Sign(network.TestNetworkPassphrase)
+	require.NoError(t, err)
+	state := dryRunState()
+	state.ignoreSignatures = true
+	_, err = dryRunTransaction(context.Background(), tx.ToXDR(), network.TestNetworkPassphrase, finder, state, nil)
+	assert.Equal(t, ErrOrderBookChanged, err)
+}
+
+func TestDryRunSponsorship(t *testing.T) {
+	newAccount := keypair.MustRandom()
+	native := xdr.MustNewNativeAsset()
+	usd := xdr.MustNewCreditAsset("USD", validatorIssuer.Address())
+	ops := []txnbuild.Operation{
+		&txnbuild.BeginSponsoringFutureReserves{SponsoredID: newAccount.Address()},
+		&txnbuild.CreateAccount{Destination: newAccount.Address(), Amount: "0"},
+		&txnbuild.ChangeTrust{
+			Line:          validatorUSD.MustToChangeTrustAsset(),
+			Limit:         "1000",
+			SourceAccount: newAccount.Address(),
+		},
+		&txnbuild.EndSponsoringFutureReserves{SourceAccount: newAccount.Address()},
+		&txnbuild.CreateClaimableBalance{
+			Amount:       "5",
+			Asset:        validatorUSD,
+			Destinations: []txnbuild.Claimant{txnbuild.NewClaimant(validatorDestination.Address(), nil)},
+		},
+	}
+
+	result := runDryRun(t, nil, dryRunState(), validatorTransaction(t, 11, txnbuild.Preconditions{}, ops...))
+	require.Nil(t, result.Error)
+	assert.True(t, result.Complete)
+	assert.Equal(t, int64(500), result.FeeCharged)
+	assert.Equal(t, []TrustLineCreated{{
+		Account:    newAccount.Address(),
+		Asset:      usd,
+		Limit:      10000000000,
+		Authorized: true,
+		Sponsor:    validatorSource.Address(),
+	}}, result.TrustLinesCreated)
+	assert.Equal(t, []ClaimableBalanceCreated{{
+		Sponsor:   validatorSource.Address(),
+		Asset:     usd,
+		Amount:    50000000,
+		Claimants: []string{validatorDestination.Address()},
+	}}, result.ClaimableBalancesCreated)
+	assert.Equal(t, []SponsorshipChange{
+		{OperationIndex: 1, Sponsor: validatorSource.Address(), Account: newAccount.Address(), EntryType: SponsoredAccount},
+		{OperationIndex: 2, Sponsor: validatorSource.Address(), Account: newAccount.Address(), EntryType: SponsoredTrustLine},
+	}, result.SponsorshipChanges)
+	// the source account pays the reserves of the new account, its trust line
+	// and the claimable balance
+	assert.Equal(t, []BalanceChange{
+		{Account: validatorSource.Address(), Asset: native, Amount: -500},
+		{Account: validatorSource.Address(), Asset: usd, Amount: -50000000},
+	}, result.BalanceChanges)
+	assert.Equal(t, []ReserveChange{
+		{Account: validatorSource.Address(), Before: 10000000, After: 30000000},
+	}, result.ReserveChanges)
+
+	// the sponsorship must be ended
+	result = runDryRun(t, nil, dryRunState(), validatorTransaction(t, 11, txnbuild.Preconditions{}, ops[:3]...))
+	assertValidationError(t, result.Error, xdr.TransactionResultCodeTxBadSponsorship, nil)
+
+	// the reserves of the account are paid by the source account without
+	// sponsorship
+	result = runDryRun(t, nil, dryRunState(), validatorTransaction(t, 11, txnbuild.Preconditions{}, ops[1]))
+	assertValidationError(t, result.Error, xdr.TransactionResultCodeTxFailed, xdr.CreateAccountResultCodeCreateAccountLowReserve)
+}
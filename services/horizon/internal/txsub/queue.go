@@ -0,0 +1,361 @@
+package txsub
+
+import (
+	"context"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/guregu/null"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/pownieh/stellar_go/network"
+	"github.com/pownieh/stellar_go/services/horizon/internal/db2/history"
+	"github.com/pownieh/stellar_go/support/errors"
+	"github.com/pownieh/stellar_go/support/log"
+	"github.com/pownieh/stellar_go/xdr"
+)
+
+// QueueDB is the Horizon DB interface used by Queue.
+type QueueDB interface {
+	LatestLedgerSequenceClosedAt(ctx context.Context) (int32, time.Time, error)
+	AllTransactionsByHashesSinceLedger(ctx context.Context, hashes []string, sinceLedgerSeq uint32) ([]history.Transaction, error)
+	GetSequenceNumbers(ctx context.Context, addresses []string) (map[string]uint64, error)
+	InsertQueuedTransaction(ctx context.Context, tx history.QueuedTransaction) (int64, error)
+	UpdateQueuedTransaction(ctx context.Context, tx history.QueuedTransaction) (int64, error)
+	ClaimPendingQueuedTransactions(ctx context.Context) ([]history.QueuedTransaction, error)
+	DeleteQueuedTransactionsFinishedBefore(ctx context.Context, before time.Time) (int64, error)
+	Begin(ctx context.Context) error
+	Commit() error
+	Rollback() error
+	NoRows(error) bool
+}
+
+// Queue persists the transactions accepted by stellar-core in the Horizon DB
+// and rebroadcasts them until they are included in a ledger or cannot be
+// included anymore, so that clients don't need to resubmit the transactions
+// dropped by stellar-core. The queue survives Horizon restarts and can be
+// shared by several Horizon instances: each tick claims the pending
+// transactions which are not being processed by another instance.
+type Queue struct {
+	initializer sync.Once
+
+	DB                func(context.Context) QueueDB
+	Submitter         Submitter
+	NetworkPassphrase string
+	// RebroadcastInterval is the minimum time between two submissions of a
+	// pending transaction.
+	RebroadcastInterval time.Duration
+	// Retention is how long the transactions are kept in the queue after
+	// they're finished.
+	Retention time.Duration
+	Log       *log.Entry
+
+	now func() time.Time
+
+	Metrics struct {
+		// PendingGauge tracks the number of pending transactions in the queue
+		PendingGauge prometheus.Gauge
+
+		// RebroadcastsCounter tracks the rate of rebroadcast transactions
+		RebroadcastsCounter prometheus.Counter
+	}
+}
+
+// RegisterMetrics registers the prometheus metrics
+func (q *Queue) RegisterMetrics(registry *prometheus.Registry) {
+	registry.MustRegister(q.Metrics.PendingGauge)
+	registry.MustRegister(q.Metrics.RebroadcastsCounter)
+}
+
+// Init initializes `q`
+func (q *Queue) Init() {
+	q.initializer.Do(func() {
+		if q.Log == nil {
+			q.Log = log.DefaultLogger.WithField("service", "txsub.Queue")
+		}
+		if q.now == nil {
+			q.now = time.Now
+		}
+		if q.RebroadcastInterval == 0 {
+			// a few ledgers, stellar-core keeps the transactions it accepted
+			// in its queue for 4 ledgers
+			q.RebroadcastInterval = 15 * time.Second
+		}
+		if q.Retention == 0 {
+			q.Retention = 24 * time.Hour
+		}
+
+		q.Metrics.PendingGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "horizon", Subsystem: "txsub", Name: "queue_pending",
+		})
+		q.Metrics.RebroadcastsCounter = prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "horizon", Subsystem: "txsub", Name: "queue_rebroadcasts",
+		})
+	})
+}
+
+// Add queues a transaction which was accepted by stellar-core.
+func (q *Queue) Add(ctx context.Context, rawTx string, envelope xdr.TransactionEnvelope, hash string) error {
+	q.Init()
+	db := q.DB(ctx)
+
+	latestLedger, _, err := db.LatestLedgerSequenceClosedAt(ctx)
+	if err != nil {
+		return errors.Wrap(err, "could not get latest ledger")
+	}
+
+	now := q.now().UTC()
+	tx := history.QueuedTransaction{
+		TransactionHash: hash,
+		TxEnvelope:      rawTx,
+		// The database doesn't (yet) store muxed accounts, so we query
+		// the corresponding AccountId
+		Account:         envelope.SourceAccount().ToAccountId().Address(),
+		AccountSequence: envelope.SeqNum(),
+		Status:          history.QueuedTransactionPending,
+		SubmissionCount: 1,
+		SubmittedLedger: latestLedger,
+		CreatedAt:       now,
+		LastSubmittedAt: now,
+	}
+	if envelope.IsFeeBump() {
+		innerHash, err := network.HashTransaction(envelope.FeeBump.Tx.InnerTx.V1.Tx, q.NetworkPassphrase)
+		if err != nil {
+			return errors.Wrap(err, "could not hash inner transaction")
+		}
+		tx.InnerTransactionHash = null.StringFrom(hex.EncodeToString(innerHash[:]))
+	}
+	if timeBounds := envelope.TimeBounds(); timeBounds != nil && timeBounds.MaxTime != 0 {
+		tx.MaxTime = null.IntFrom(int64(timeBounds.MaxTime))
+	}
+	if ledgerBounds := envelope.LedgerBounds(); ledgerBounds != nil && ledgerBounds.MaxLedger != 0 {
+		tx.MaxLedger = null.IntFrom(int64(ledgerBounds.MaxLedger))
+	}
+
+	if _, err = db.InsertQueuedTransaction(ctx, tx); err != nil {
+		return errors.Wrap(err, "could not insert queued transaction")
+	}
+	return nil
+}
+
+// Tick finishes the pending transactions which were included in a ledger or
+// cannot be included anymore and rebroadcasts the other ones.
+func (q *Queue) Tick(ctx context.Context) {
+	q.Init()
+	logger := q.Log.Ctx(ctx)
+	db := q.DB(ctx)
+
+	if err := q.processPending(ctx, db); err != nil {
+		logger.WithError(err).Error("error processing pending queued transactions")
+		return
+	}
+
+	if _, err := db.DeleteQueuedTransactionsFinishedBefore(ctx, q.now().UTC().Add(-q.Retention)); err != nil {
+		logger.WithError(err).Error("error deleting finished queued transactions")
+	}
+}
+
+// processPending processes the pending transactions in a DB transaction which
+// keeps them locked, so that the other Horizon instances skip them. The
+// transactions to rebroadcast are marked as submitted in the DB transaction and
+// submitted to stellar-core once it's committed, so the rows aren't kept
+// locked while waiting for stellar-core.
+func (q *Queue) processPending(ctx context.Context, db QueueDB) error {
+	rebroadcast, stillPending, err := q.claimPending(ctx, db)
+	if err != nil {
+		return err
+	}
+	for _, tx := range rebroadcast {
+		status, err := q.rebroadcast(ctx, db, tx)
+		if err != nil {
+			return err
+		}
+		if status != history.QueuedTransactionPending {
+			stillPending--
+		}
+	}
+	q.Metrics.PendingGauge.Set(float64(stillPending))
+	return nil
+}
+
+// claimPending finishes the pending transactions which were included in a
+// ledger or cannot be included anymore and marks the ones to rebroadcast as
+// submitted. Returns the transactions to rebroadcast and the number of
+// transactions which are still pending.
+func (q *Queue) claimPending(ctx context.Context, db QueueDB) ([]history.QueuedTransaction, int, error) {
+	if err := db.Begin(ctx); err != nil {
+		return nil, 0, errors.Wrap(err, "could not begin transaction")
+	}
+	defer db.Rollback()
+
+	pending, err := db.ClaimPendingQueuedTransactions(ctx)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "could not claim pending queued transactions")
+	}
+	if len(pending) == 0 {
+		return nil, 0, nil
+	}
+	rebroadcast, stillPending, err := q.process(ctx, db, pending)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err = db.Commit(); err != nil {
+		return nil, 0, errors.Wrap(err, "could not commit transaction")
+	}
+	return rebroadcast, stillPending, nil
+}
+
+func (q *Queue) process(ctx context.Context, db QueueDB, pending []history.QueuedTransaction) ([]history.QueuedTransaction, int, error) {
+	latestLedger, latestClosedAt, err := db.LatestLedgerSequenceClosedAt(ctx)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "could not get latest ledger")
+	}
+
+	// The sequence numbers are loaded before the transactions: if the
+	// sequence number of an account was consumed by one of the queued
+	// transactions, the transaction is then guaranteed to be found.
+	var addresses []string
+	seen := map[string]bool{}
+	hashes := make([]string, 0, len(pending))
+	sinceLedger := pending[0].SubmittedLedger
+	for _, tx := range pending {
+		if !seen[tx.Account] {
+			seen[tx.Account] = true
+			addresses = append(addresses, tx.Account)
+		}
+		hashes = append(hashes, tx.TransactionHash)
+		if tx.InnerTransactionHash.Valid {
+			hashes = append(hashes, tx.InnerTransactionHash.String)
+		}
+		if tx.SubmittedLedger < sinceLedger {
+			sinceLedger = tx.SubmittedLedger
+		}
+	}
+	sequenceNumbers, err := db.GetSequenceNumbers(ctx, addresses)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "could not get sequence numbers")
+	}
+
+	txs, err := db.AllTransactionsByHashesSinceLedger(ctx, hashes, uint32(sinceLedger))
+	if err != nil && !db.NoRows(err) {
+		return nil, 0, errors.Wrap(err, "could not get transactions by hashes")
+	}
+	txMap := make(map[string]history.Transaction, len(txs))
+	for _, tx := range txs {
+		txMap[tx.TransactionHash] = tx
+		if tx.InnerTransactionHash.Valid {
+			txMap[tx.InnerTransactionHash.String] = tx
+		}
+	}
+
+	var rebroadcast []history.QueuedTransaction
+	stillPending := 0
+	for _, tx := range pending {
+		tx, resubmit, err := q.processTransaction(ctx, db, tx, txMap, sequenceNumbers, latestLedger, latestClosedAt)
+		if err != nil {
+			return nil, 0, err
+		}
+		if tx.Status == history.QueuedTransactionPending {
+			stillPending++
+		}
+		if resubmit {
+			rebroadcast = append(rebroadcast, tx)
+		}
+	}
+	return rebroadcast, stillPending, nil
+}
+
+func (q *Queue) processTransaction(
+	ctx context.Context,
+	db QueueDB,
+	tx history.QueuedTransaction,
+	txMap map[string]history.Transaction,
+	sequenceNumbers map[string]uint64,
+	latestLedger int32,
+	latestClosedAt time.Time,
+) (history.QueuedTransaction, bool, error) {
+	logger := q.Log.Ctx(ctx).WithField("hash", tx.TransactionHash)
+	now := q.now().UTC()
+
+	result, found := txMap[tx.TransactionHash]
+	if !found && tx.InnerTransactionHash.Valid {
+		// the inner transaction may have been included in another fee bump
+		// transaction
+		result, found = txMap[tx.InnerTransactionHash.String]
+	}
+	if found {
+		status := history.QueuedTransactionSuccess
+		if _, err := txResultFromHistory(result); err != nil {
+			status = history.QueuedTransactionFailed
+		}
+		logger.WithField("status", status).Info("queued transaction was included in a ledger")
+		tx.LedgerSequence = null.IntFrom(int64(result.LedgerSequence))
+		tx.TxResult = null.StringFrom(result.TxResult)
+		tx, err := q.finish(ctx, db, tx, status)
+		return tx, false, err
+	}
+
+	// The transaction cannot be included in a ledger after its max ledger
+	// (exclusive) or in a ledger closed after its max time. The ledgers up to
+	// the latest ingested ledger are known not to include it.
+	if (tx.MaxLedger.Valid && int64(latestLedger) >= tx.MaxLedger.Int64-1) ||
+		(tx.MaxTime.Valid && latestClosedAt.Unix() > tx.MaxTime.Int64) {
+		logger.Info("queued transaction expired")
+		tx, err := q.finish(ctx, db, tx, history.QueuedTransactionExpired)
+		return tx, false, err
+	}
+
+	if sequence, ok := sequenceNumbers[tx.Account]; ok && int64(sequence) >= tx.AccountSequence {
+		logger.Info("queued transaction sequence number was consumed by another transaction")
+		tx, err := q.finish(ctx, db, tx, history.QueuedTransactionDropped)
+		return tx, false, err
+	}
+
+	if now.Sub(tx.LastSubmittedAt) < q.RebroadcastInterval {
+		return tx, false, nil
+	}
+
+	// The submission is recorded before rebroadcasting the transaction so
+	// the other Horizon instances don't rebroadcast it too once the rows are
+	// unlocked.
+	tx.SubmissionCount++
+	tx.LastSubmittedAt = now
+	if _, err := db.UpdateQueuedTransaction(ctx, tx); err != nil {
+		return tx, false, errors.Wrap(err, "could not update queued transaction")
+	}
+	return tx, true, nil
+}
+
+// rebroadcast submits a pending transaction to stellar-core again and finishes
+// it if stellar-core rejects it. Returns the status of the transaction.
+func (q *Queue) rebroadcast(ctx context.Context, db QueueDB, tx history.QueuedTransaction) (string, error) {
+	logger := q.Log.Ctx(ctx).WithField("hash", tx.TransactionHash)
+
+	sr := q.Submitter.Submit(ctx, tx.TxEnvelope)
+	q.Metrics.RebroadcastsCounter.Inc()
+	if sr.Err != nil {
+		isBadSeq, err := sr.IsBadSeq()
+		if fte, ok := sr.Err.(*FailedTransactionError); ok && err == nil && !isBadSeq {
+			logger.WithError(sr.Err).Info("queued transaction was rejected by stellar-core")
+			tx.TxResult = null.StringFrom(fte.ResultXDR)
+			tx, err = q.finish(ctx, db, tx, history.QueuedTransactionFailed)
+			return tx.Status, err
+		}
+		// txBAD_SEQ is expected when the previous transaction of the account
+		// isn't included yet or when the transaction was included in a ledger
+		// which isn't ingested yet, the transaction stays pending until the
+		// sequence number of the account catches up.
+		logger.WithError(sr.Err).Warn("could not rebroadcast queued transaction")
+	}
+	return tx.Status, nil
+}
+
+func (q *Queue) finish(ctx context.Context, db QueueDB, tx history.QueuedTransaction, status string) (history.QueuedTransaction, error) {
+	tx.Status = status
+	tx.FinishedAt = null.TimeFrom(q.now().UTC())
+	if _, err := db.UpdateQueuedTransaction(ctx, tx); err != nil {
+		return tx, errors.Wrap(err, "could not update queued transaction")
+	}
+	return tx, nil
+}
@@ -0,0 +1,340 @@
+package txsub
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/guregu/null"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/pownieh/stellar_go/network"
+	"github.com/pownieh/stellar_go/services/horizon/internal/db2/history"
+	"github.com/pownieh/stellar_go/xdr"
+)
+
+type mockQueueDB struct {
+	mock.Mock
+}
+
+func (m *mockQueueDB) LatestLedgerSequenceClosedAt(ctx context.Context) (int32, time.Time, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(int32), args.Get(1).(time.Time), args.Error(2)
+}
+
+func (m *mockQueueDB) AllTransactionsByHashesSinceLedger(ctx context.Context, hashes []string, sinceLedgerSeq uint32) ([]history.Transaction, error) {
+	args := m.Called(ctx, hashes, sinceLedgerSeq)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]history.Transaction), args.Error(1)
+}
+
+func (m *mockQueueDB) GetSequenceNumbers(ctx context.Context, addresses []string) (map[string]uint64, error) {
+	args := m.Called(ctx, addresses)
+	return args.Get(0).(map[string]uint64), args.Error(1)
+}
+
+func (m *mockQueueDB) InsertQueuedTransaction(ctx context.Context, tx history.QueuedTransaction) (int64, error) {
+	args := m.Called(ctx, tx)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *mockQueueDB) UpdateQueuedTransaction(ctx context.Context, tx history.QueuedTransaction) (int64, error) {
+	args := m.Called(ctx, tx)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *mockQueueDB) ClaimPendingQueuedTransactions(ctx context.Context) ([]history.QueuedTransaction, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]history.QueuedTransaction), args.Error(1)
+}
+
+func (m *mockQueueDB) DeleteQueuedTransactionsFinishedBefore(ctx context.Context, before time.Time) (int64, error) {
+	args := m.Called(ctx, before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *mockQueueDB) Begin(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+func (m *mockQueueDB) Commit() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (m *mockQueueDB) Rollback() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (m *mockQueueDB) NoRows(err error) bool {
+	args := m.Called(err)
+	return args.Bool(0)
+}
+
+var (
+	queueNow       = time.Date(2023, 11, 14, 22, 0, 0, 0, time.UTC)
+	queueSource    = "GBRPYHIL2CI3FNQ4BXLFMNDLFJUNPU2HY3ZMFSHONUCEOASW7QC7OX2H"
+	queueSourceTwo = "GCXKG6RN4ONIEPCMNFB732A436Z5PNDSRLGWK7GBLCMQLIFO4S7EYWVU"
+)
+
+func newTestQueue(db *mockQueueDB, submitter *MockSubmitter) *Queue {
+	queue := &Queue{
+		DB: func(ctx context.Context) QueueDB {
+			return db
+		},
+		Submitter:         submitter,
+		NetworkPassphrase: network.TestNetworkPassphrase,
+		now: func() time.Time {
+			return queueNow
+		},
+	}
+	queue.Init()
+	return queue
+}
+
+func queuedTransaction(hash string, account string, sequence int64) history.QueuedTransaction {
+	return history.QueuedTransaction{
+		TransactionHash: hash,
+		TxEnvelope:      "envelope-" + hash,
+		Account:         account,
+		AccountSequence: sequence,
+		Status:          history.QueuedTransactionPending,
+		SubmissionCount: 1,
+		SubmittedLedger: 100,
+		CreatedAt:       queueNow.Add(-time.Minute),
+		LastSubmittedAt: queueNow.Add(-time.Minute),
+	}
+}
+
+func resultXDR(t *testing.T, code xdr.TransactionResultCode) string {
+	result := xdr.TransactionResult{
+		FeeCharged: 100,
+		Result: xdr.TransactionResultResult{
+			Code:    code,
+			Results: &[]xdr.OperationResult{},
+		},
+	}
+	resultXDR, err := xdr.MarshalBase64(result)
+	assert.NoError(t, err)
+	return resultXDR
+}
+
+func TestQueueAdd(t *testing.T) {
+	ctx := context.Background()
+	db := &mockQueueDB{}
+	defer db.AssertExpectations(t)
+	queue := newTestQueue(db, &MockSubmitter{})
+
+	source := xdr.MustAddress(queueSource)
+	innerTx := xdr.Transaction{
+		SourceAccount: source.ToMuxedAccount(),
+		Fee:           100,
+		SeqNum:        11,
+		Cond: xdr.Preconditions{
+			Type: xdr.PreconditionTypePrecondV2,
+			V2: &xdr.PreconditionsV2{
+				TimeBounds:   &xdr.TimeBounds{MaxTime: 1700000100},
+				LedgerBounds: &xdr.LedgerBounds{MinLedger: 0, MaxLedger: 150},
+			},
+		},
+	}
+	envelope := xdr.TransactionEnvelope{
+		Type: xdr.EnvelopeTypeEnvelopeTypeTxFeeBump,
+		FeeBump: &xdr.FeeBumpTransactionEnvelope{
+			Tx: xdr.FeeBumpTransaction{
+				FeeSource: xdr.MustMuxedAddress(queueSourceTwo),
+				Fee:       400,
+				InnerTx: xdr.FeeBumpTransactionInnerTx{
+					Type: xdr.EnvelopeTypeEnvelopeTypeTx,
+					V1:   &xdr.TransactionV1Envelope{Tx: innerTx},
+				},
+			},
+		},
+	}
+	innerHash, err := network.HashTransaction(innerTx, network.TestNetworkPassphrase)
+	assert.NoError(t, err)
+
+	db.On("LatestLedgerSequenceClosedAt", ctx).Return(int32(100), queueNow, nil).Once()
+	expected := queuedTransaction("outer", queueSource, 11)
+	expected.TxEnvelope = "raw"
+	expected.InnerTransactionHash = null.StringFrom(xdr.Hash(innerHash).HexString())
+	expected.MaxTime = null.IntFrom(1700000100)
+	expected.MaxLedger = null.IntFrom(150)
+	expected.CreatedAt = queueNow
+	expected.LastSubmittedAt = queueNow
+	db.On("InsertQueuedTransaction", ctx, expected).Return(int64(1), nil).Once()
+
+	assert.NoError(t, queue.Add(ctx, "raw", envelope, "outer"))
+}
+
+func TestQueueTick(t *testing.T) {
+	ctx := context.Background()
+	db := &mockQueueDB{}
+	defer db.AssertExpectations(t)
+	submitter := &MockSubmitter{}
+	queue := newTestQueue(db, submitter)
+
+	included := queuedTransaction("included", queueSource, 11)
+	expiredLedger := queuedTransaction("expired_ledger", queueSourceTwo, 20)
+	expiredLedger.MaxLedger = null.IntFrom(121)
+	expiredTime := queuedTransaction("expired_time", queueSourceTwo, 21)
+	expiredTime.MaxTime = null.IntFrom(queueNow.Add(-time.Minute).Unix())
+	dropped := queuedTransaction("dropped", queueSource, 10)
+	recent := queuedTransaction("recent", queueSourceTwo, 22)
+	recent.LastSubmittedAt = queueNow.Add(-time.Second)
+	rebroadcast := queuedTransaction("rebroadcast", queueSourceTwo, 23)
+	rebroadcast.SubmittedLedger = 90
+	pending := []history.QueuedTransaction{included, expiredLedger, expiredTime, dropped, recent, rebroadcast}
+
+	db.On("Begin", ctx).Return(nil).Once()
+	db.On("ClaimPendingQueuedTransactions", ctx).Return(pending, nil).Once()
+	db.On("LatestLedgerSequenceClosedAt", ctx).Return(int32(120), queueNow.Add(-5*time.Second), nil).Once()
+	db.On("GetSequenceNumbers", ctx, []string{queueSource, queueSourceTwo}).
+		Return(map[string]uint64{queueSource: 11, queueSourceTwo: 19}, nil).Once()
+	result := resultXDR(t, xdr.TransactionResultCodeTxSuccess)
+	db.On("AllTransactionsByHashesSinceLedger", ctx,
+		[]string{"included", "expired_ledger", "expired_time", "dropped", "recent", "rebroadcast"}, uint32(90)).
+		Return([]history.Transaction{
+			{
+				TransactionWithoutLedger: history.TransactionWithoutLedger{
+					TransactionHash: "included",
+					LedgerSequence:  105,
+					TxResult:        result,
+				},
+			},
+		}, nil).Once()
+
+	finished := func(tx history.QueuedTransaction, status string) history.QueuedTransaction {
+		tx.Status = status
+		tx.FinishedAt = null.TimeFrom(queueNow)
+		return tx
+	}
+	included = finished(included, history.QueuedTransactionSuccess)
+	included.LedgerSequence = null.IntFrom(105)
+	included.TxResult = null.StringFrom(result)
+	db.On("UpdateQueuedTransaction", ctx, included).Return(int64(1), nil).Once()
+	db.On("UpdateQueuedTransaction", ctx, finished(expiredLedger, history.QueuedTransactionExpired)).Return(int64(1), nil).Once()
+	db.On("UpdateQueuedTransaction", ctx, finished(expiredTime, history.QueuedTransactionExpired)).Return(int64(1), nil).Once()
+	db.On("UpdateQueuedTransaction", ctx, finished(dropped, history.QueuedTransactionDropped)).Return(int64(1), nil).Once()
+	rebroadcast.SubmissionCount = 2
+	rebroadcast.LastSubmittedAt = queueNow
+	db.On("UpdateQueuedTransaction", ctx, rebroadcast).Return(int64(1), nil).Once()
+	db.On("Commit").Run(func(mock.Arguments) {
+		// the transaction is rebroadcast once the rows are unlocked
+		assert.False(t, submitter.WasSubmittedTo)
+	}).Return(nil).Once()
+	db.On("Rollback").Return(nil).Once()
+	db.On("DeleteQueuedTransactionsFinishedBefore", ctx, queueNow.Add(-24*time.Hour)).Return(int64(3), nil).Once()
+
+	queue.Tick(ctx)
+	assert.True(t, submitter.WasSubmittedTo)
+	assert.Equal(t, float64(2), getMetricValue(queue.Metrics.PendingGauge).GetGauge().GetValue())
+	assert.Equal(t, float64(1), getMetricValue(queue.Metrics.RebroadcastsCounter).GetCounter().GetValue())
+}
+
+func TestQueueTickRebroadcastErrors(t *testing.T) {
+	for _, testCase := range []struct {
+		name     string
+		err      error
+		status   string
+		txResult null.String
+	}{
+		{
+			name:   "rejected",
+			err:    &FailedTransactionError{ResultXDR: resultXDR(t, xdr.TransactionResultCodeTxInsufficientBalance)},
+			status: history.QueuedTransactionFailed,
+			txResult: null.StringFrom(
+				resultXDR(t, xdr.TransactionResultCodeTxInsufficientBalance),
+			),
+		},
+		{
+			// the previous transaction of the account isn't included yet
+			name:   "bad seq",
+			err:    ErrBadSequence,
+			status: history.QueuedTransactionPending,
+		},
+		{
+			name:   "core unavailable",
+			err:    sql.ErrConnDone,
+			status: history.QueuedTransactionPending,
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctx := context.Background()
+			db := &mockQueueDB{}
+			defer db.AssertExpectations(t)
+			queue := newTestQueue(db, &MockSubmitter{R: SubmissionResult{Err: testCase.err}})
+
+			tx := queuedTransaction("hash", queueSource, 12)
+			db.On("Begin", ctx).Return(nil).Once()
+			db.On("ClaimPendingQueuedTransactions", ctx).Return([]history.QueuedTransaction{tx}, nil).Once()
+			db.On("LatestLedgerSequenceClosedAt", ctx).Return(int32(120), queueNow, nil).Once()
+			db.On("GetSequenceNumbers", ctx, []string{queueSource}).
+				Return(map[string]uint64{queueSource: 10}, nil).Once()
+			db.On("AllTransactionsByHashesSinceLedger", ctx, []string{"hash"}, uint32(100)).
+				Return(nil, sql.ErrNoRows).Once()
+			db.On("NoRows", sql.ErrNoRows).Return(true).Once()
+
+			tx.SubmissionCount = 2
+			tx.LastSubmittedAt = queueNow
+			db.On("UpdateQueuedTransaction", ctx, tx).Return(int64(1), nil).Once()
+			db.On("Commit").Return(nil).Once()
+			db.On("Rollback").Return(nil).Once()
+			if testCase.status != history.QueuedTransactionPending {
+				// the transaction is finished after the DB transaction
+				tx.Status = testCase.status
+				tx.TxResult = testCase.txResult
+				tx.FinishedAt = null.TimeFrom(queueNow)
+				db.On("UpdateQueuedTransaction", ctx, tx).Return(int64(1), nil).Once()
+			}
+			db.On("DeleteQueuedTransactionsFinishedBefore", ctx, queueNow.Add(-24*time.Hour)).Return(int64(0), nil).Once()
+
+			queue.Tick(ctx)
+			expectedPending := float64(1)
+			if testCase.status != history.QueuedTransactionPending {
+				expectedPending = 0
+			}
+			assert.Equal(t, expectedPending, getMetricValue(queue.Metrics.PendingGauge).GetGauge().GetValue())
+		})
+	}
+}
+
+func TestQueueTickWithoutPendingTransactions(t *testing.T) {
+	ctx := context.Background()
+	db := &mockQueueDB{}
+	defer db.AssertExpectations(t)
+	submitter := &MockSubmitter{}
+	queue := newTestQueue(db, submitter)
+
+	db.On("Begin", ctx).Return(nil).Once()
+	db.On("ClaimPendingQueuedTransactions", ctx).Return([]history.QueuedTransaction{}, nil).Once()
+	db.On("Rollback").Return(nil).Once()
+	db.On("DeleteQueuedTransactionsFinishedBefore", ctx, queueNow.Add(-24*time.Hour)).Return(int64(0), nil).Once()
+
+	queue.Tick(ctx)
+	assert.False(t, submitter.WasSubmittedTo)
+}
+
+func TestQueueTickRollsBackOnError(t *testing.T) {
+	ctx := context.Background()
+	db := &mockQueueDB{}
+	defer db.AssertExpectations(t)
+	submitter := &MockSubmitter{}
+	queue := newTestQueue(db, submitter)
+
+	tx := queuedTransaction("hash", queueSource, 12)
+	db.On("Begin", ctx).Return(nil).Once()
+	db.On("ClaimPendingQueuedTransactions", ctx).Return([]history.QueuedTransaction{tx}, nil).Once()
+	db.On("LatestLedgerSequenceClosedAt", ctx).Return(int32(0), time.Time{}, sql.ErrConnDone).Once()
+	// the claimed transactions are released without being updated
+	db.On("Rollback").Return(nil).Once()
+
+	queue.Tick(ctx)
+	assert.False(t, submitter.WasSubmittedTo)
+}
@@ -40,6 +40,9 @@ type System struct {
 	Submitter         Submitter
 	SubmissionTimeout time.Duration
 	Log               *log.Entry
+	// Queue, when set, persists the transactions accepted by stellar-core
+	// and rebroadcasts them until they are included in a ledger or expire.
+	Queue *Queue
 
 	Metrics struct {
 		// SubmissionDuration exposes timing metrics about the rate and latency of
@@ -81,6 +84,9 @@ func (sys *System) RegisterMetrics(registry *prometheus.Registry) {
 	registry.MustRegister(sys.Metrics.V0TransactionsCounter)
 	registry.MustRegister(sys.Metrics.V1TransactionsCounter)
 	registry.MustRegister(sys.Metrics.FeeBumpTransactionsCounter)
+	if sys.Queue != nil {
+		sys.Queue.RegisterMetrics(registry)
+	}
 }
 
 // Submit submits the provided base64 encoded transaction envelope to the
@@ -162,6 +168,12 @@ func (sys *System) Submit(
 		return
 	}
 
+	if sys.Queue != nil {
+		if err = sys.Queue.Add(ctx, rawTx, envelope, hash); err != nil {
+			sys.Log.Ctx(ctx).WithError(err).WithField("hash", hash).Error("Error adding transaction to the queue")
+		}
+	}
+
 	// Add transaction to open list of pending txns: the transaction has been successfully submitted to core
 	// but that does not mean it is included in the ledger. The txn status remains pending
 	// until we see an ingestion in the db.
@@ -276,6 +288,10 @@ func (sys *System) Tick(ctx context.Context) {
 
 	logger.Debug("ticking txsub system")
 
+	if sys.Queue != nil {
+		sys.Queue.Tick(ctx)
+	}
+
 	db := sys.DB(ctx)
 	options := &sql.TxOptions{
 		Isolation: sql.LevelRepeatableRead,
@@ -377,6 +393,10 @@ func (sys *System) Init() {
 
 		sys.accountSeqPollInterval = time.Second
 
+		if sys.Queue != nil {
+			sys.Queue.Init()
+		}
+
 		if sys.SubmissionTimeout == 0 {
 			// HTTP clients in SDKs usually timeout in 60 seconds. We want SubmissionTimeout
 			// to be lower than that to make sure that they read the response before the client
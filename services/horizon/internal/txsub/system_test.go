@@ -318,6 +318,35 @@ func (suite *SystemTestSuite) TestSubmit_OpenTransactionList() {
 	assert.Equal(suite.T(), uint64(1), getMetricValue(suite.system.Metrics.SubmissionDuration).GetSummary().GetSampleCount())
 }
 
+// Transactions accepted by stellar-core are added to the queue when it's enabled.
+func (suite *SystemTestSuite) TestSubmit_Queue() {
+	queueDB := &mockQueueDB{}
+	defer queueDB.AssertExpectations(suite.T())
+	suite.system.Queue = newTestQueue(queueDB, suite.submitter)
+
+	suite.db.On("PreFilteredTransactionByHash", suite.ctx, mock.Anything, suite.successTx.Transaction.TransactionHash).
+		Return(sql.ErrNoRows).Once()
+	suite.db.On("TransactionByHash", suite.ctx, mock.Anything, suite.successTx.Transaction.TransactionHash).
+		Return(sql.ErrNoRows).Once()
+	suite.db.On("NoRows", sql.ErrNoRows).Return(true).Twice()
+	queueDB.On("LatestLedgerSequenceClosedAt", suite.ctx).Return(int32(1000), queueNow, nil).Once()
+	queueDB.On("InsertQueuedTransaction", suite.ctx, mock.MatchedBy(func(tx history.QueuedTransaction) bool {
+		return tx.TransactionHash == suite.successTx.Transaction.TransactionHash &&
+			tx.Account == suite.unmuxedSource.Address() &&
+			tx.AccountSequence == 1 &&
+			tx.Status == history.QueuedTransactionPending &&
+			tx.SubmittedLedger == 1000
+	})).Return(int64(1), nil).Once()
+
+	suite.system.Submit(
+		suite.ctx,
+		suite.successTx.Transaction.TxEnvelope,
+		suite.successXDR,
+		suite.successTx.Transaction.TransactionHash,
+	)
+	assert.Len(suite.T(), suite.system.Pending.Pending(), 1)
+}
+
 // Tick should be a no-op if there are no open submissions.
 func (suite *SystemTestSuite) TestTick_Noop() {
 	suite.db.On("BeginTx", mock.AnythingOfType("*context.valueCtx"), &sql.TxOptions{
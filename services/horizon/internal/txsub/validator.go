@@ -0,0 +1,619 @@
+package txsub
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"fmt"
+
+	"github.com/pownieh/stellar_go/keypair"
+	"github.com/pownieh/stellar_go/network"
+	"github.com/pownieh/stellar_go/services/horizon/internal/db2/history"
+	"github.com/pownieh/stellar_go/strkey"
+	"github.com/pownieh/stellar_go/support/errors"
+	"github.com/pownieh/stellar_go/xdr"
+)
+
+// ValidatorDB is the Horizon DB interface used by Validator.
+type ValidatorDB interface {
+	HorizonDB
+	LedgerBySequence(ctx context.Context, dest interface{}, seq int32) error
+	GetAccountsByIDs(ctx context.Context, ids []string) ([]history.AccountEntry, error)
+	SignersForAccounts(ctx context.Context, accounts []string) ([]history.AccountSigner, error)
+	GetTrustLinesByKeys(ctx context.Context, ledgerKeys []string) ([]history.TrustLine, error)
+}
+
+// ValidationError is returned by Validator when a transaction would be
+// rejected by stellar-core or fail given the latest ingested ledger.
+type ValidationError struct {
+	// Code is the expected result code of the transaction.
+	Code xdr.TransactionResultCode
+	// InnerCode is the expected result code of the inner transaction when
+	// Code is txFEE_BUMP_INNER_FAILED.
+	InnerCode xdr.TransactionResultCode
+	// OperationIndex and OperationCode identify the operation which would
+	// fail when the result code of the (inner) transaction is txFAILED.
+	OperationIndex int
+	OperationCode  interface{}
+	// Reason describes why the transaction is invalid.
+	Reason string
+}
+
+func (err *ValidationError) Error() string {
+	return "transaction validation failed: " + err.Reason
+}
+
+// Validator checks transactions against the latest ingested ledger before they
+// are submitted to stellar-core, so that clients get a detailed explanation
+// instead of a result code for the transactions which are bound to fail.
+//
+// Horizon ingestion may lag behind stellar-core, so only the preconditions
+// which cannot be met by a ledger more recent than the latest ingested ledger
+// are checked: a sequence number which is already consumed, a maximum time
+// before the close time of the latest ingested ledger and a maximum ledger
+// which is not after it. The minimum time, the minimum ledger, the minimum
+// sequence age and the minimum sequence ledger gap may be met by the ledger
+// stellar-core closes next, so they are left to stellar-core.
+//
+// The reserves are only checked for the fee: the reserves required by the
+// entries the operations create (trust lines, offers, signers, data entries
+// and claimable balances) are out of scope, because whether an entry is
+// created and which account is charged for it depend on the entries the
+// validator doesn't load, on the order book and on the sponsorships of the
+// transaction. Such transactions fail with the low reserve result code of the
+// operation, DryRunner reports it for the operations it supports.
+type Validator struct {
+	DB                func(context.Context) ValidatorDB
+	NetworkPassphrase string
+}
+
+// Validate returns a *ValidationError when the transaction is invalid. The
+// transactions which were already included in a ledger are not validated.
+func (v *Validator) Validate(ctx context.Context, envelope xdr.TransactionEnvelope, hash string) error {
+	db := v.DB(ctx)
+	// The data must belong to the same ledger
+	err := db.BeginTx(ctx, &sql.TxOptions{
+		Isolation: sql.LevelRepeatableRead,
+		ReadOnly:  true,
+	})
+	if err != nil {
+		return errors.Wrap(err, "could not begin repeatable read transaction")
+	}
+	defer db.Rollback()
+
+	latestLedger, err := db.GetLatestHistoryLedger(ctx)
+	if err != nil {
+		return errors.Wrap(err, "could not get latest ledger")
+	}
+	if latestLedger == 0 {
+		// nothing was ingested yet
+		return nil
+	}
+
+	if _, err = txResultByHash(ctx, db, hash); err != ErrNoResults {
+		if _, ok := err.(*FailedTransactionError); err == nil || ok {
+			// the submission system returns the result of the transaction
+			return nil
+		}
+		return err
+	}
+
+	var state validationState
+	if err = db.LedgerBySequence(ctx, &state.ledger, int32(latestLedger)); err != nil {
+		return errors.Wrap(err, "could not load latest ledger")
+	}
+	if err = state.load(ctx, db, envelope); err != nil {
+		return err
+	}
+
+	return validateTransaction(envelope, v.NetworkPassphrase, state)
+}
+
+// validationState is the subset of the ingested state used to validate a
+// transaction.
+type validationState struct {
+	ledger     history.Ledger
+	accounts   map[string]history.AccountEntry
+	signers    map[string][]history.AccountSigner
+	trustLines map[string]history.TrustLine
+	// ignoreSignatures skips the signature checks, to validate unsigned
+	// transactions
+	ignoreSignatures bool
+}
+
+func (s *validationState) load(ctx context.Context, db ValidatorDB, envelope xdr.TransactionEnvelope) error {
+	var entries ledgerEntries
+	sourceAccount := envelope.SourceAccount().ToAccountId().Address()
+	if envelope.IsFeeBump() {
+		entries.addAccount(envelope.FeeBumpAccount().ToAccountId().Address())
+	}
+	entries.addAccount(sourceAccount)
+	for _, op := range envelope.Operations() {
+		entries.addAccount(operationSourceAccount(op, sourceAccount))
+		for _, check := range trustLineChecks(op, sourceAccount) {
+			if err := entries.addTrustLine(check.account, check.asset); err != nil {
+				return err
+			}
+		}
+	}
+	return s.loadEntries(ctx, db, entries)
+}
+
+// ledgerEntries are the accounts and the trust lines loaded in a
+// validationState.
+type ledgerEntries struct {
+	addresses  []string
+	ledgerKeys []string
+	seen       map[string]bool
+}
+
+func (e *ledgerEntries) add(list *[]string, key string) {
+	if e.seen == nil {
+		e.seen = map[string]bool{}
+	}
+	if !e.seen[key] {
+		e.seen[key] = true
+		*list = append(*list, key)
+	}
+}
+
+func (e *ledgerEntries) addAccount(address string) {
+	e.add(&e.addresses, address)
+}
+
+// addTrustLine adds the trust line and its account. Native assets and
+// issuers don't have trust lines.
+func (e *ledgerEntries) addTrustLine(account string, asset xdr.Asset) error {
+	e.addAccount(account)
+	if asset.Type == xdr.AssetTypeAssetTypeNative || asset.GetIssuer() == account {
+		return nil
+	}
+	key, err := trustLineKey(account, asset)
+	if err != nil {
+		return err
+	}
+	e.add(&e.ledgerKeys, key)
+	return nil
+}
+
+func (s *validationState) loadEntries(ctx context.Context, db ValidatorDB, entries ledgerEntries) error {
+	accounts, err := db.GetAccountsByIDs(ctx, entries.addresses)
+	if err != nil {
+		return errors.Wrap(err, "could not load accounts")
+	}
+	s.accounts = make(map[string]history.AccountEntry, len(accounts))
+	for _, account := range accounts {
+		s.accounts[account.AccountID] = account
+	}
+
+	signers, err := db.SignersForAccounts(ctx, entries.addresses)
+	if err != nil {
+		return errors.Wrap(err, "could not load signers")
+	}
+	s.signers = map[string][]history.AccountSigner{}
+	for _, signer := range signers {
+		s.signers[signer.Account] = append(s.signers[signer.Account], signer)
+	}
+
+	s.trustLines = map[string]history.TrustLine{}
+	if len(entries.ledgerKeys) > 0 {
+		trustLines, err := db.GetTrustLinesByKeys(ctx, entries.ledgerKeys)
+		if err != nil {
+			return errors.Wrap(err, "could not load trust lines")
+		}
+		for _, trustLine := range trustLines {
+			s.trustLines[trustLine.LedgerKey] = trustLine
+		}
+	}
+	return nil
+}
+
+func validateTransaction(envelope xdr.TransactionEnvelope, passphrase string, state validationState) error {
+	hash, err := network.HashTransactionInEnvelope(envelope, passphrase)
+	if err != nil {
+		return errors.Wrap(err, "could not hash transaction")
+	}
+
+	if !envelope.IsFeeBump() {
+		if verr := validateInnerTransaction(envelope, hash, state); verr != nil {
+			return verr
+		}
+		sourceAddress := envelope.SourceAccount().ToAccountId().Address()
+		if verr := validateBalance(state.accounts[sourceAddress], int64(envelope.Fee()), state); verr != nil {
+			return verr
+		}
+		return nil
+	}
+
+	feeSourceAddress := envelope.FeeBumpAccount().ToAccountId().Address()
+	feeSource, ok := state.accounts[feeSourceAddress]
+	if !ok {
+		return &ValidationError{
+			Code:   xdr.TransactionResultCodeTxNoAccount,
+			Reason: fmt.Sprintf("the fee account %s does not exist", feeSourceAddress),
+		}
+	}
+	signatures := signatureChecker{hash: hash, signatures: envelope.FeeBumpSignatures(), ignore: state.ignoreSignatures}
+	if !signatures.hasWeight(state.signers[feeSourceAddress], feeSource.ThresholdLow) {
+		return &ValidationError{
+			Code:   xdr.TransactionResultCodeTxBadAuth,
+			Reason: fmt.Sprintf("the signatures do not meet the low threshold of the fee account %s", feeSourceAddress),
+		}
+	}
+	if verr := validateBalance(feeSource, envelope.FeeBumpFee(), state); verr != nil {
+		return verr
+	}
+
+	innerHash, err := network.HashTransaction(envelope.FeeBump.Tx.InnerTx.V1.Tx, passphrase)
+	if err != nil {
+		return errors.Wrap(err, "could not hash inner transaction")
+	}
+	if verr := validateInnerTransaction(envelope, innerHash, state); verr != nil {
+		verr.InnerCode = verr.Code
+		verr.Code = xdr.TransactionResultCodeTxFeeBumpInnerFailed
+		return verr
+	}
+	return nil
+}
+
+// validateBalance checks that the account paying the fee can pay it without
+// going below its current minimum balance. The reserves of the entries created
+// by the operations are not included, see Validator.
+func validateBalance(account history.AccountEntry, fee int64, state validationState) *ValidationError {
+	if available := availableBalance(account, state.ledger.BaseReserve); available < fee {
+		return &ValidationError{
+			Code: xdr.TransactionResultCodeTxInsufficientBalance,
+			Reason: fmt.Sprintf(
+				"the account %s cannot pay the fee of %d stroops, its balance above the minimum balance is %d stroops",
+				account.AccountID, fee, available,
+			),
+		}
+	}
+	return nil
+}
+
+// availableBalance returns the balance of the account which is not locked by
+// its minimum balance or by its selling offers.
+func availableBalance(account history.AccountEntry, baseReserve int32) int64 {
+	return account.Balance - account.SellingLiabilities - minimumBalance(account, baseReserve)
+}
+
+// minimumBalance returns the balance the account must hold for its reserves.
+func minimumBalance(account history.AccountEntry, baseReserve int32) int64 {
+	entries := 2 + int64(account.NumSubEntries) + int64(account.NumSponsoring) - int64(account.NumSponsored)
+	return entries * int64(baseReserve)
+}
+
+func validateInnerTransaction(envelope xdr.TransactionEnvelope, hash [32]byte, state validationState) *ValidationError {
+	// stellar-core checks the transactions against a ledger which is not
+	// older than the latest ingested ledger.
+	closeTime := state.ledger.ClosedAt.Unix()
+	nextLedger := int64(state.ledger.Sequence) + 1
+
+	if timeBounds := envelope.TimeBounds(); timeBounds != nil {
+		if timeBounds.MaxTime != 0 && int64(timeBounds.MaxTime) < closeTime {
+			return &ValidationError{
+				Code:   xdr.TransactionResultCodeTxTooLate,
+				Reason: fmt.Sprintf("the maximum time %d is before the close time of the latest ledger %d", timeBounds.MaxTime, closeTime),
+			}
+		}
+	}
+	if ledgerBounds := envelope.LedgerBounds(); ledgerBounds != nil {
+		if ledgerBounds.MaxLedger != 0 && int64(ledgerBounds.MaxLedger) <= nextLedger {
+			return &ValidationError{
+				Code:   xdr.TransactionResultCodeTxTooLate,
+				Reason: fmt.Sprintf("the maximum ledger %d is not after the next ledger %d", ledgerBounds.MaxLedger, nextLedger),
+			}
+		}
+	}
+
+	sourceAddress := envelope.SourceAccount().ToAccountId().Address()
+	source, ok := state.accounts[sourceAddress]
+	if !ok {
+		return &ValidationError{
+			Code:   xdr.TransactionResultCodeTxNoAccount,
+			Reason: fmt.Sprintf("the source account %s does not exist", sourceAddress),
+		}
+	}
+
+	// A greater sequence number may be valid if Horizon ingestion lags behind
+	// stellar-core.
+	if envelope.SeqNum() <= source.SequenceNumber {
+		return &ValidationError{
+			Code: xdr.TransactionResultCodeTxBadSeq,
+			Reason: fmt.Sprintf(
+				"the sequence number %d was already consumed, the sequence number of the source account is %d",
+				envelope.SeqNum(), source.SequenceNumber,
+			),
+		}
+	}
+
+	signatures := signatureChecker{hash: hash, signatures: envelope.Signatures(), ignore: state.ignoreSignatures}
+	if !signatures.hasWeight(state.signers[sourceAddress], source.ThresholdLow) {
+		return &ValidationError{
+			Code:   xdr.TransactionResultCodeTxBadAuth,
+			Reason: fmt.Sprintf("the signatures do not meet the low threshold of the source account %s", sourceAddress),
+		}
+	}
+	for _, signer := range envelope.ExtraSigners() {
+		if address := signer.Address(); !signatures.signed(address) {
+			return &ValidationError{
+				Code:   xdr.TransactionResultCodeTxBadAuth,
+				Reason: fmt.Sprintf("the transaction is not signed by the extra signer %s", address),
+			}
+		}
+	}
+
+	return validateOperations(envelope.Operations(), sourceAddress, signatures, state)
+}
+
+func validateOperations(ops []xdr.Operation, sourceAddress string, signatures signatureChecker, state validationState) *ValidationError {
+	// the accounts and trust lines created or updated by the previous
+	// operations of the transaction aren't validated
+	created := map[string]bool{}
+	for i, op := range ops {
+		opSource := operationSourceAccount(op, sourceAddress)
+		if account, ok := state.accounts[opSource]; ok && !created[opSource] {
+			if !signatures.hasWeight(state.signers[opSource], operationThreshold(account, op)) {
+				return &ValidationError{
+					Code:           xdr.TransactionResultCodeTxFailed,
+					OperationIndex: i,
+					OperationCode:  xdr.OperationResultCodeOpBadAuth,
+					Reason:         fmt.Sprintf("the signatures do not meet the threshold of the operation source account %s", opSource),
+				}
+			}
+		}
+
+		for _, check := range trustLineChecks(op, sourceAddress) {
+			key, err := trustLineKey(check.account, check.asset)
+			if err != nil || created[check.account] || created[key] {
+				continue
+			}
+			if _, ok := state.accounts[check.account]; !ok {
+				continue
+			}
+			trustLine, ok := state.trustLines[key]
+			if !ok {
+				return &ValidationError{
+					Code:           xdr.TransactionResultCodeTxFailed,
+					OperationIndex: i,
+					OperationCode:  check.noTrust,
+					Reason:         fmt.Sprintf("the account %s does not trust %s", check.account, check.asset.StringCanonical()),
+				}
+			}
+			if !xdr.TrustLineFlags(trustLine.Flags).IsAuthorized() {
+				return &ValidationError{
+					Code:           xdr.TransactionResultCodeTxFailed,
+					OperationIndex: i,
+					OperationCode:  check.notAuthorized,
+					Reason:         fmt.Sprintf("the account %s is not authorized to hold %s", check.account, check.asset.StringCanonical()),
+				}
+			}
+		}
+
+		switch op.Body.Type {
+		case xdr.OperationTypeCreateAccount:
+			created[op.Body.MustCreateAccountOp().Destination.Address()] = true
+		case xdr.OperationTypeChangeTrust:
+			if line := op.Body.MustChangeTrustOp().Line; line.Type != xdr.AssetTypeAssetTypePoolShare {
+				markTrustLine(created, opSource, line.ToAsset())
+			}
+		case xdr.OperationTypeAllowTrust:
+			allowTrust := op.Body.MustAllowTrustOp()
+			markTrustLine(created, allowTrust.Trustor.Address(), allowTrust.Asset.ToAsset(xdr.MustAddress(opSource)))
+		case xdr.OperationTypeSetTrustLineFlags:
+			setFlags := op.Body.MustSetTrustLineFlagsOp()
+			markTrustLine(created, setFlags.Trustor.Address(), setFlags.Asset)
+		}
+	}
+	return nil
+}
+
+func markTrustLine(created map[string]bool, account string, asset xdr.Asset) {
+	if key, err := trustLineKey(account, asset); err == nil {
+		created[key] = true
+	}
+}
+
+func operationSourceAccount(op xdr.Operation, txSource string) string {
+	if op.SourceAccount != nil {
+		return op.SourceAccount.ToAccountId().Address()
+	}
+	return txSource
+}
+
+// operationThreshold returns the threshold of the account that the signatures
+// must meet for the operation, see
+// https://developers.stellar.org/docs/learn/encyclopedia/security/signatures-multisig#thresholds
+func operationThreshold(account history.AccountEntry, op xdr.Operation) byte {
+	switch op.Body.Type {
+	case xdr.OperationTypeAllowTrust,
+		xdr.OperationTypeSetTrustLineFlags,
+		xdr.OperationTypeBumpSequence,
+		xdr.OperationTypeClaimClaimableBalance,
+		xdr.OperationTypeInflation,
+		xdr.OperationTypeBumpFootprintExpiration,
+		xdr.OperationTypeRestoreFootprint:
+		return account.ThresholdLow
+	case xdr.OperationTypeAccountMerge:
+		return account.ThresholdHigh
+	case xdr.OperationTypeSetOptions:
+		setOptions := op.Body.MustSetOptionsOp()
+		if setOptions.MasterWeight != nil || setOptions.LowThreshold != nil ||
+			setOptions.MedThreshold != nil || setOptions.HighThreshold != nil ||
+			setOptions.Signer != nil {
+			return account.ThresholdHigh
+		}
+	}
+	return account.ThresholdMedium
+}
+
+// trustLineCheck is a trust line which must exist and be authorized for an
+// operation to succeed, with the operation result codes returned otherwise.
+type trustLineCheck struct {
+	account       string
+	asset         xdr.Asset
+	noTrust       interface{}
+	notAuthorized interface{}
+}
+
+func trustLineChecks(op xdr.Operation, txSource string) []trustLineCheck {
+	source := operationSourceAccount(op, txSource)
+	var checks []trustLineCheck
+	add := func(account string, asset xdr.Asset, noTrust, notAuthorized interface{}) {
+		if asset.Type == xdr.AssetTypeAssetTypeNative {
+			return
+		}
+		if asset.GetIssuer() == account {
+			return
+		}
+		checks = append(checks, trustLineCheck{
+			account:       account,
+			asset:         asset,
+			noTrust:       noTrust,
+			notAuthorized: notAuthorized,
+		})
+	}
+
+	switch op.Body.Type {
+	case xdr.OperationTypePayment:
+		payment := op.Body.MustPaymentOp()
+		add(source, payment.Asset,
+			xdr.PaymentResultCodePaymentSrcNoTrust, xdr.PaymentResultCodePaymentSrcNotAuthorized)
+		add(payment.Destination.ToAccountId().Address(), payment.Asset,
+			xdr.PaymentResultCodePaymentNoTrust, xdr.PaymentResultCodePaymentNotAuthorized)
+	case xdr.OperationTypePathPaymentStrictReceive:
+		pathPayment := op.Body.MustPathPaymentStrictReceiveOp()
+		add(source, pathPayment.SendAsset,
+			xdr.PathPaymentStrictReceiveResultCodePathPaymentStrictReceiveSrcNoTrust,
+			xdr.PathPaymentStrictReceiveResultCodePathPaymentStrictReceiveSrcNotAuthorized)
+		add(pathPayment.Destination.ToAccountId().Address(), pathPayment.DestAsset,
+			xdr.PathPaymentStrictReceiveResultCodePathPaymentStrictReceiveNoTrust,
+			xdr.PathPaymentStrictReceiveResultCodePathPaymentStrictReceiveNotAuthorized)
+	case xdr.OperationTypePathPaymentStrictSend:
+		pathPayment := op.Body.MustPathPaymentStrictSendOp()
+		add(source, pathPayment.SendAsset,
+			xdr.PathPaymentStrictSendResultCodePathPaymentStrictSendSrcNoTrust,
+			xdr.PathPaymentStrictSendResultCodePathPaymentStrictSendSrcNotAuthorized)
+		add(pathPayment.Destination.ToAccountId().Address(), pathPayment.DestAsset,
+			xdr.PathPaymentStrictSendResultCodePathPaymentStrictSendNoTrust,
+			xdr.PathPaymentStrictSendResultCodePathPaymentStrictSendNotAuthorized)
+	case xdr.OperationTypeManageSellOffer:
+		offer := op.Body.MustManageSellOfferOp()
+		if offer.Amount > 0 {
+			addOfferChecks(add, source, offer.Selling, offer.Buying)
+		}
+	case xdr.OperationTypeCreatePassiveSellOffer:
+		offer := op.Body.MustCreatePassiveSellOfferOp()
+		if offer.Amount > 0 {
+			addOfferChecks(add, source, offer.Selling, offer.Buying)
+		}
+	case xdr.OperationTypeManageBuyOffer:
+		offer := op.Body.MustManageBuyOfferOp()
+		if offer.BuyAmount > 0 {
+			add(source, offer.Selling,
+				xdr.ManageBuyOfferResultCodeManageBuyOfferSellNoTrust,
+				xdr.ManageBuyOfferResultCodeManageBuyOfferSellNotAuthorized)
+			add(source, offer.Buying,
+				xdr.ManageBuyOfferResultCodeManageBuyOfferBuyNoTrust,
+				xdr.ManageBuyOfferResultCodeManageBuyOfferBuyNotAuthorized)
+		}
+	}
+	return checks
+}
+
+func addOfferChecks(add func(string, xdr.Asset, interface{}, interface{}), source string, selling, buying xdr.Asset) {
+	add(source, selling,
+		xdr.ManageSellOfferResultCodeManageSellOfferSellNoTrust,
+		xdr.ManageSellOfferResultCodeManageSellOfferSellNotAuthorized)
+	add(source, buying,
+		xdr.ManageSellOfferResultCodeManageSellOfferBuyNoTrust,
+		xdr.ManageSellOfferResultCodeManageSellOfferBuyNotAuthorized)
+}
+
+func trustLineKey(account string, asset xdr.Asset) (string, error) {
+	accountID, err := xdr.AddressToAccountId(account)
+	if err != nil {
+		return "", errors.Wrap(err, "invalid account")
+	}
+	var ledgerKey xdr.LedgerKey
+	if err = ledgerKey.SetTrustline(accountID, asset.ToTrustLineAsset()); err != nil {
+		return "", errors.Wrap(err, "could not create ledger key")
+	}
+	return ledgerKey.MarshalBinaryBase64()
+}
+
+// signatureChecker checks the signatures of a transaction. All the checks
+// pass when ignore is true.
+type signatureChecker struct {
+	hash       [32]byte
+	signatures []xdr.DecoratedSignature
+	ignore     bool
+}
+
+// hasWeight returns true if the transaction is signed by the signers of an
+// account with a total weight meeting the threshold.
+func (c signatureChecker) hasWeight(signers []history.AccountSigner, threshold byte) bool {
+	if c.ignore {
+		return true
+	}
+	found := false
+	var weight int32
+	for _, signer := range signers {
+		if c.signed(signer.Signer) {
+			found = true
+			weight += signer.Weight
+		}
+	}
+	return found && weight >= int32(threshold)
+}
+
+// signed returns true if the transaction is signed by the signer.
+func (c signatureChecker) signed(signer string) bool {
+	if c.ignore {
+		return true
+	}
+	version, raw, err := strkey.DecodeAny(signer)
+	if err != nil {
+		return false
+	}
+
+	switch version {
+	case strkey.VersionByteAccountID:
+		kp, err := keypair.ParseAddress(signer)
+		if err != nil {
+			return false
+		}
+		hint := kp.Hint()
+		for _, signature := range c.signatures {
+			if signature.Hint == hint && kp.Verify(c.hash[:], signature.Signature) == nil {
+				return true
+			}
+		}
+	case strkey.VersionByteHashTx:
+		return bytes.Equal(raw, c.hash[:])
+	case strkey.VersionByteHashX:
+		for _, signature := range c.signatures {
+			if hash := sha256.Sum256(signature.Signature); bytes.Equal(raw, hash[:]) {
+				return true
+			}
+		}
+	case strkey.VersionByteSignedPayload:
+		var key xdr.SignerKey
+		if err = key.SetAddress(signer); err != nil {
+			return false
+		}
+		signedPayload := key.MustEd25519SignedPayload()
+		kp, err := keypair.ParseAddress(strkey.MustEncode(strkey.VersionByteAccountID, signedPayload.Ed25519[:]))
+		if err != nil {
+			return false
+		}
+		for _, signature := range c.signatures {
+			if kp.Verify(signedPayload.Payload, signature.Signature) == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
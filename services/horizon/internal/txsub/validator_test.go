@@ -0,0 +1,275 @@
+package txsub
+
+import (
+	"testing"
+	"time"
+
+	"github.com/guregu/null/zero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pownieh/stellar_go/keypair"
+	"github.com/pownieh/stellar_go/network"
+	"github.com/pownieh/stellar_go/services/horizon/internal/db2/history"
+	"github.com/pownieh/stellar_go/txnbuild"
+	"github.com/pownieh/stellar_go/xdr"
+)
+
+var (
+	validatorSource      = keypair.MustRandom()
+	validatorDestination = keypair.MustRandom()
+	validatorIssuer      = keypair.MustRandom()
+	validatorUSD         = txnbuild.CreditAsset{Code: "USD", Issuer: validatorIssuer.Address()}
+)
+
+func validatorAccount(kp *keypair.Full, sequence int64) history.AccountEntry {
+	return history.AccountEntry{
+		AccountID:      kp.Address(),
+		Balance:        100000000,
+		SequenceNumber: sequence,
+		SequenceLedger: zero.IntFrom(90),
+		SequenceTime:   zero.IntFrom(1700000000),
+		MasterWeight:   1,
+	}
+}
+
+func validatorState() validationState {
+	state := validationState{
+		ledger: history.Ledger{
+			Sequence:    100,
+			ClosedAt:    time.Unix(1700001000, 0).UTC(),
+			BaseReserve: 5000000,
+		},
+		accounts:   map[string]history.AccountEntry{},
+		signers:    map[string][]history.AccountSigner{},
+		trustLines: map[string]history.TrustLine{},
+	}
+	for _, kp := range []*keypair.Full{validatorSource, validatorDestination, validatorIssuer} {
+		state.accounts[kp.Address()] = validatorAccount(kp, 10)
+		state.signers[kp.Address()] = []history.AccountSigner{
+			{Account: kp.Address(), Signer: kp.Address(), Weight: 1},
+		}
+	}
+	addValidatorTrustLine(state, validatorDestination, xdr.TrustLineFlagsAuthorizedFlag)
+	addValidatorTrustLine(state, validatorSource, xdr.TrustLineFlagsAuthorizedFlag)
+	return state
+}
+
+func addValidatorTrustLine(state validationState, kp *keypair.Full, flags xdr.TrustLineFlags) {
+	asset, err := validatorUSD.ToXDR()
+	if err != nil {
+		panic(err)
+	}
+	key, err := trustLineKey(kp.Address(), asset)
+	if err != nil {
+		panic(err)
+	}
+	state.trustLines[key] = history.TrustLine{
+		AccountID: kp.Address(),
+		LedgerKey: key,
+		Flags:     uint32(flags),
+	}
+}
+
+func validatorTransaction(t *testing.T, sequence int64, preconditions txnbuild.Preconditions, ops ...txnbuild.Operation) *txnbuild.Transaction {
+	if preconditions.TimeBounds == (txnbuild.TimeBounds{}) {
+		preconditions.TimeBounds = txnbuild.NewInfiniteTimeout()
+	}
+	if len(ops) == 0 {
+		ops = []txnbuild.Operation{&txnbuild.Payment{
+			Destination: validatorDestination.Address(),
+			Amount:      "10",
+			Asset:       validatorUSD,
+		}}
+	}
+	tx, err := txnbuild.NewTransaction(txnbuild.TransactionParams{
+		SourceAccount:        &txnbuild.SimpleAccount{AccountID: validatorSource.Address(), Sequence: sequence - 1},
+		IncrementSequenceNum: true,
+		Operations:           ops,
+		BaseFee:              txnbuild.MinBaseFee,
+		Preconditions:        preconditions,
+	})
+	require.NoError(t, err)
+	return tx
+}
+
+func validate(t *testing.T, tx *txnbuild.Transaction, state validationState, signers ...*keypair.Full) error {
+	tx, err := tx.Sign(network.TestNetworkPassphrase, signers...)
+	require.NoError(t, err)
+	envelope := tx.ToXDR()
+	return validateTransaction(envelope, network.TestNetworkPassphrase, state)
+}
+
+func assertValidationError(t *testing.T, err error, code xdr.TransactionResultCode, opCode interface{}) {
+	if assert.IsType(t, &ValidationError{}, err) {
+		verr := err.(*ValidationError)
+		assert.Equal(t, code, verr.Code)
+		assert.Equal(t, opCode, verr.OperationCode)
+		assert.NotEmpty(t, verr.Reason)
+	}
+}
+
+func TestValidateTransaction(t *testing.T) {
+	state := validatorState()
+	tx := validatorTransaction(t, 11, txnbuild.Preconditions{})
+	assert.NoError(t, validate(t, tx, state, validatorSource))
+
+	// the sequence number may follow a transaction which is not ingested yet
+	assert.NoError(t, validate(t, validatorTransaction(t, 13, txnbuild.Preconditions{}), state, validatorSource))
+	err := validate(t, validatorTransaction(t, 10, txnbuild.Preconditions{}), state, validatorSource)
+	assertValidationError(t, err, xdr.TransactionResultCodeTxBadSeq, nil)
+
+	err = validate(t, tx, state)
+	assertValidationError(t, err, xdr.TransactionResultCodeTxBadAuth, nil)
+	err = validate(t, tx, state, validatorDestination)
+	assertValidationError(t, err, xdr.TransactionResultCodeTxBadAuth, nil)
+
+	delete(state.accounts, validatorSource.Address())
+	err = validate(t, tx, state, validatorSource)
+	assertValidationError(t, err, xdr.TransactionResultCodeTxNoAccount, nil)
+}
+
+func TestValidateTransactionPreconditions(t *testing.T) {
+	state := validatorState()
+
+	err := validate(t, validatorTransaction(t, 11, txnbuild.Preconditions{
+		TimeBounds: txnbuild.NewTimebounds(0, 1700000999),
+	}), state, validatorSource)
+	assertValidationError(t, err, xdr.TransactionResultCodeTxTooLate, nil)
+
+	err = validate(t, validatorTransaction(t, 11, txnbuild.Preconditions{
+		TimeBounds: txnbuild.NewTimebounds(0, 1700001000),
+	}), state, validatorSource)
+	assert.NoError(t, err)
+
+	err = validate(t, validatorTransaction(t, 11, txnbuild.Preconditions{
+		LedgerBounds: &txnbuild.LedgerBounds{MinLedger: 0, MaxLedger: 101},
+	}), state, validatorSource)
+	assertValidationError(t, err, xdr.TransactionResultCodeTxTooLate, nil)
+	err = validate(t, validatorTransaction(t, 11, txnbuild.Preconditions{
+		LedgerBounds: &txnbuild.LedgerBounds{MinLedger: 0, MaxLedger: 102},
+	}), state, validatorSource)
+	assert.NoError(t, err)
+
+	// stellar-core may have closed ledgers which are not ingested yet, so the
+	// minimum time, the minimum ledger, the minimum sequence age and the
+	// minimum sequence ledger gap are left to stellar-core
+	err = validate(t, validatorTransaction(t, 11, txnbuild.Preconditions{
+		TimeBounds:                 txnbuild.NewTimebounds(1800000000, 0),
+		LedgerBounds:               &txnbuild.LedgerBounds{MinLedger: 200, MaxLedger: 0},
+		MinSequenceNumberAge:       100000000,
+		MinSequenceNumberLedgerGap: 1000,
+	}), state, validatorSource)
+	assert.NoError(t, err)
+
+	tx := validatorTransaction(t, 11, txnbuild.Preconditions{
+		ExtraSigners: []string{validatorDestination.Address()},
+	})
+	err = validate(t, tx, state, validatorSource)
+	assertValidationError(t, err, xdr.TransactionResultCodeTxBadAuth, nil)
+	assert.NoError(t, validate(t, tx, state, validatorSource, validatorDestination))
+}
+
+func TestValidateTransactionBalance(t *testing.T) {
+	state := validatorState()
+	source := state.accounts[validatorSource.Address()]
+	// 5 base reserves of 0.5 XLM are locked
+	source.NumSubEntries = 2
+	source.NumSponsored = 1
+	source.NumSponsoring = 2
+	source.Balance = 25000000 + 100
+	state.accounts[validatorSource.Address()] = source
+
+	tx := validatorTransaction(t, 11, txnbuild.Preconditions{})
+	assert.NoError(t, validate(t, tx, state, validatorSource))
+
+	source.SellingLiabilities = 1
+	state.accounts[validatorSource.Address()] = source
+	err := validate(t, tx, state, validatorSource)
+	assertValidationError(t, err, xdr.TransactionResultCodeTxInsufficientBalance, nil)
+}
+
+func TestValidateTransactionOperations(t *testing.T) {
+	state := validatorState()
+
+	// the payment source doesn't meet the medium threshold
+	source := state.accounts[validatorSource.Address()]
+	source.ThresholdMedium = 2
+	state.accounts[validatorSource.Address()] = source
+	tx := validatorTransaction(t, 11, txnbuild.Preconditions{})
+	err := validate(t, tx, state, validatorSource)
+	assertValidationError(t, err, xdr.TransactionResultCodeTxFailed, xdr.OperationResultCodeOpBadAuth)
+	// bumping the sequence number only requires the low threshold
+	err = validate(t, validatorTransaction(t, 11, txnbuild.Preconditions{}, &txnbuild.BumpSequence{BumpTo: 20}), state, validatorSource)
+	assert.NoError(t, err)
+
+	state = validatorState()
+	addValidatorTrustLine(state, validatorDestination, 0)
+	err = validate(t, tx, state, validatorSource)
+	assertValidationError(t, err, xdr.TransactionResultCodeTxFailed, xdr.PaymentResultCodePaymentNotAuthorized)
+
+	state.trustLines = map[string]history.TrustLine{}
+	err = validate(t, tx, state, validatorSource)
+	if assert.IsType(t, &ValidationError{}, err) {
+		assert.Equal(t, xdr.PaymentResultCodePaymentSrcNoTrust, err.(*ValidationError).OperationCode)
+		assert.Equal(t, 0, err.(*ValidationError).OperationIndex)
+	}
+
+	// the issuer doesn't need a trust line and the trust line of the
+	// destination is created by the transaction
+	tx = validatorTransaction(t, 11, txnbuild.Preconditions{},
+		&txnbuild.ChangeTrust{
+			Line:          validatorUSD.MustToChangeTrustAsset(),
+			SourceAccount: validatorDestination.Address(),
+		},
+		&txnbuild.Payment{
+			Destination:   validatorDestination.Address(),
+			Amount:        "10",
+			Asset:         validatorUSD,
+			SourceAccount: validatorIssuer.Address(),
+		},
+		&txnbuild.ManageSellOffer{
+			Selling: txnbuild.NativeAsset{},
+			Buying:  validatorUSD,
+			Amount:  "10",
+			Price:   xdr.Price{N: 1, D: 1},
+		},
+	)
+	err = validate(t, tx, state, validatorSource, validatorDestination, validatorIssuer)
+	if assert.IsType(t, &ValidationError{}, err) {
+		assert.Equal(t, xdr.ManageSellOfferResultCodeManageSellOfferBuyNoTrust, err.(*ValidationError).OperationCode)
+		assert.Equal(t, 2, err.(*ValidationError).OperationIndex)
+	}
+	addValidatorTrustLine(state, validatorSource, xdr.TrustLineFlagsAuthorizedFlag)
+	assert.NoError(t, validate(t, tx, state, validatorSource, validatorDestination, validatorIssuer))
+}
+
+func TestValidateFeeBumpTransaction(t *testing.T) {
+	state := validatorState()
+	inner, err := validatorTransaction(t, 10, txnbuild.Preconditions{}).Sign(network.TestNetworkPassphrase, validatorSource)
+	require.NoError(t, err)
+	feeBump, err := txnbuild.NewFeeBumpTransaction(txnbuild.FeeBumpTransactionParams{
+		Inner:      inner,
+		FeeAccount: validatorDestination.Address(),
+		BaseFee:    txnbuild.MinBaseFee,
+	})
+	require.NoError(t, err)
+
+	envelope := feeBump.ToXDR()
+	err = validateTransaction(envelope, network.TestNetworkPassphrase, state)
+	assertValidationError(t, err, xdr.TransactionResultCodeTxBadAuth, nil)
+
+	feeBump, err = feeBump.Sign(network.TestNetworkPassphrase, validatorDestination)
+	require.NoError(t, err)
+	envelope = feeBump.ToXDR()
+	err = validateTransaction(envelope, network.TestNetworkPassphrase, state)
+	assertValidationError(t, err, xdr.TransactionResultCodeTxFeeBumpInnerFailed, nil)
+	assert.Equal(t, xdr.TransactionResultCodeTxBadSeq, err.(*ValidationError).InnerCode)
+
+	source := state.accounts[validatorSource.Address()]
+	source.SequenceNumber = 9
+	// the fee is paid by the fee account
+	source.Balance = 0
+	state.accounts[validatorSource.Address()] = source
+	assert.NoError(t, validateTransaction(envelope, network.TestNetworkPassphrase, state))
+}
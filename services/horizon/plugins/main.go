@@ -0,0 +1,49 @@
+// Package plugins allows programs embedding Horizon to extend ingestion with
+// custom processors, which maintain their own tables without forking Horizon.
+//
+// Plugins are registered before running Horizon:
+//
+//	func main() {
+//		err := plugins.RegisterProcessorPlugin(plugins.ProcessorPlugin{
+//			Name:       "payments_by_memo",
+//			Migrations: &migrate.FileMigrationSource{Dir: "migrations"},
+//			HistoryTables: map[string]string{
+//				"payments_by_memo": "operation_id",
+//			},
+//			NewTransactionProcessor: newPaymentsByMemoProcessor,
+//		})
+//		if err != nil {
+//			log.Fatal(err)
+//		}
+//		if err = cmd.Execute(); err != nil {
+//			log.Fatal(err)
+//		}
+//	}
+//
+// The processors run in the ingestion DB transaction, during ingestion,
+// reingestion and state rebuilds. The migrations of the plugins are applied
+// by `horizon db migrate up` and `--apply-migrations`.
+package plugins
+
+import (
+	"github.com/pownieh/stellar_go/services/horizon/internal/ingest"
+	"github.com/pownieh/stellar_go/services/horizon/internal/ingest/processors"
+)
+
+// ProcessorPlugin describes the processors and tables of a plugin.
+type ProcessorPlugin = ingest.ProcessorPlugin
+
+// ChangeProcessor processes the ledger entry changes of ledgers and the
+// ledger entries of checkpoints.
+type ChangeProcessor = ingest.PluginChangeProcessor
+
+// TransactionProcessor processes the transactions of ledgers. Flush is called
+// with the session of the ingestion transaction once the transactions of the
+// ledgers have been processed.
+type TransactionProcessor = processors.LedgerTransactionProcessor
+
+// RegisterProcessorPlugin registers a processor plugin. It must be called
+// before Horizon is started.
+func RegisterProcessorPlugin(plugin ProcessorPlugin) error {
+	return ingest.RegisterProcessorPlugin(plugin)
+}
@@ -106,7 +106,8 @@ func (b *FastBatchInsertBuilder) Len() int {
 
 // Exec inserts rows in a single COPY statement. Once Exec is called no more rows
 // can be added to the FastBatchInsertBuilder unless Reset is called.
-// Exec must be called within a transaction.
+// Exec must be called within a transaction. The rows are only recorded when
+// session is a Recorder.
 func (b *FastBatchInsertBuilder) Exec(ctx context.Context, session SessionInterface, tableName string) error {
 	b.sealed = true
 	if session.GetTx() == nil {
@@ -117,6 +118,11 @@ func (b *FastBatchInsertBuilder) Exec(ctx context.Context, session SessionInterf
 		return nil
 	}
 
+	if recorder, ok := session.(Recorder); ok {
+		recorder.RecordRows(tableName, b.columns, b.rows)
+		return nil
+	}
+
 	tx := session.GetTx()
 	stmt, err := tx.PrepareContext(ctx, pq.CopyIn(tableName, b.columns...))
 	if err != nil {
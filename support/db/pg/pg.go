@@ -13,3 +13,14 @@ func IsUniqueViolation(err error) bool {
 		return false
 	}
 }
+
+// IsLockNotAvailable returns true if the statement failed because a lock
+// could not be acquired, e.g. because lock_timeout expired.
+func IsLockNotAvailable(err error) bool {
+	switch pgerr := errors.Cause(err).(type) {
+	case *pq.Error:
+		return string(pgerr.Code) == "55P03"
+	default:
+		return false
+	}
+}
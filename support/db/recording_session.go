@@ -0,0 +1,294 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/pownieh/stellar_go/support/errors"
+)
+
+// Operations of the writes recorded by RecordingSession.
+const (
+	RecordedInsert = "INSERT"
+	RecordedUpsert = "UPSERT"
+)
+
+// RecordedWrite is a write recorded by a RecordingSession instead of being
+// executed.
+type RecordedWrite struct {
+	// Label is the label of the session when the write was recorded.
+	Label string
+	// Operation is RecordedInsert or RecordedUpsert for the rows inserted by
+	// FastBatchInsertBuilder and by `WITH ... INSERT INTO ... SELECT`
+	// statements, otherwise it is the first keyword of the statement.
+	Operation string
+	// Table is the table written to, it is empty when it is unknown.
+	Table string
+	// Row is the inserted row by column, it is only set for RecordedInsert
+	// and RecordedUpsert.
+	Row map[string]interface{}
+	// Query and Args are the statement, they are only set for writes other
+	// than RecordedInsert and RecordedUpsert.
+	Query string
+	Args  []interface{}
+}
+
+// Recorder is implemented by the sessions which record the writes instead of
+// executing them. The writers which don't go through Exec, like
+// FastBatchInsertBuilder, record their writes with it.
+type Recorder interface {
+	// RecordRows records the rows inserted into table by a batch insert.
+	RecordRows(table string, columns []string, rows [][]interface{})
+	// NextID returns a negative id, different from the ids previously
+	// returned, which stands for the id of a row which was recorded instead
+	// of being inserted.
+	NextID() int64
+}
+
+// RecordingSession is a SessionInterface which records the statements writing
+// to the DB instead of executing them. Queries are run by the wrapped session
+// in a read-only transaction, so the wrapped session can be connected to a
+// read-only replica and writes which are not recorded, e.g. by
+// BatchInsertBuilder, fail instead of modifying the DB.
+//
+// The rows inserted by `WITH ... INSERT INTO ... SELECT` statements and the
+// number of rows affected by UPDATE and DELETE statements are determined with
+// SELECT queries, so recording a statement behaves like executing it on the
+// DB as of the start of the transaction.
+type RecordingSession struct {
+	SessionInterface
+	recorder *recorder
+}
+
+type recorder struct {
+	lock   sync.Mutex
+	label  string
+	writes []RecordedWrite
+	lastID int64
+}
+
+// NewRecordingSession returns a RecordingSession running the queries with
+// session.
+func NewRecordingSession(session SessionInterface) *RecordingSession {
+	return &RecordingSession{SessionInterface: session, recorder: &recorder{}}
+}
+
+// SetLabel sets the label of the writes recorded from now on.
+func (r *RecordingSession) SetLabel(label string) {
+	r.recorder.lock.Lock()
+	defer r.recorder.lock.Unlock()
+	r.recorder.label = label
+}
+
+// Writes returns the recorded writes in the order in which they were
+// recorded.
+func (r *RecordingSession) Writes() []RecordedWrite {
+	r.recorder.lock.Lock()
+	defer r.recorder.lock.Unlock()
+	return append([]RecordedWrite(nil), r.recorder.writes...)
+}
+
+// NextID implements Recorder.
+func (r *RecordingSession) NextID() int64 {
+	r.recorder.lock.Lock()
+	defer r.recorder.lock.Unlock()
+	r.recorder.lastID--
+	return r.recorder.lastID
+}
+
+func (r *RecordingSession) record(writes ...RecordedWrite) {
+	r.recorder.lock.Lock()
+	defer r.recorder.lock.Unlock()
+	for _, write := range writes {
+		write.Label = r.recorder.label
+		r.recorder.writes = append(r.recorder.writes, write)
+	}
+}
+
+// Begin starts a read-only transaction in the wrapped session.
+func (r *RecordingSession) Begin(ctx context.Context) error {
+	return r.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead})
+}
+
+// BeginTx starts a transaction, always read-only, in the wrapped session.
+func (r *RecordingSession) BeginTx(ctx context.Context, opts *sql.TxOptions) error {
+	readOnly := sql.TxOptions{ReadOnly: true}
+	if opts != nil {
+		readOnly.Isolation = opts.Isolation
+	}
+	return r.SessionInterface.BeginTx(ctx, &readOnly)
+}
+
+// Clone returns a RecordingSession wrapping a clone of the wrapped session,
+// which records its writes with the writes of r.
+func (r *RecordingSession) Clone() SessionInterface {
+	return &RecordingSession{SessionInterface: r.SessionInterface.Clone(), recorder: r.recorder}
+}
+
+// TruncateTables records the truncation of the tables.
+func (r *RecordingSession) TruncateTables(ctx context.Context, tables []string) error {
+	for _, table := range tables {
+		r.record(RecordedWrite{Operation: "TRUNCATE", Table: table, Query: "TRUNCATE " + table})
+	}
+	return nil
+}
+
+// DeleteRange records the deletion of the rows of table with an idCol in
+// [start, end).
+func (r *RecordingSession) DeleteRange(ctx context.Context, start, end int64, table string, idCol string) error {
+	del := sq.Delete(table).Where(fmt.Sprintf("%s >= ? AND %s < ?", idCol, idCol), start, end)
+	_, err := r.Exec(ctx, del)
+	return err
+}
+
+// Exec records the statement, see ExecRaw.
+func (r *RecordingSession) Exec(ctx context.Context, query sq.Sqlizer) (sql.Result, error) {
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not build query")
+	}
+	return r.ExecRaw(ctx, sql, args...)
+}
+
+var (
+	insertSelectRegexp = regexp.MustCompile(`(?is)^\s*(WITH\s.*?\))\s*INSERT\s+INTO\s+(\w+)\s*\(([^)]*)\)\s*SELECT\s+\*\s+FROM\s+(\w+)\s*(ON\s+CONFLICT.*)?$`)
+	insertValuesRegexp = regexp.MustCompile(`(?is)^\s*INSERT\s+INTO\s+(\w+)\s*\([^)]*\)\s*VALUES\s*(.*)$`)
+	updateRegexp       = regexp.MustCompile(`(?is)^\s*UPDATE\s+(\w+)\s+SET\s+(.*?)(\sWHERE\s(.*))?$`)
+	deleteRegexp       = regexp.MustCompile(`(?is)^\s*DELETE\s+FROM\s+(\w+)(\s+WHERE\s(.*))?$`)
+	keywordRegexp      = regexp.MustCompile(`^\s*(\w+)`)
+)
+
+// ExecRaw records the statement instead of executing it. The rows inserted
+// by `WITH ... INSERT INTO ... SELECT` statements, the form of the upserts of
+// the Horizon ingestion, are recorded one by one.
+func (r *RecordingSession) ExecRaw(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if match := insertSelectRegexp.FindStringSubmatch(query); match != nil {
+		operation := RecordedInsert
+		if strings.Contains(strings.ToUpper(match[5]), "DO UPDATE") {
+			operation = RecordedUpsert
+		}
+		rows, err := r.selectRows(ctx, match[1]+" SELECT * FROM "+match[4], splitColumns(match[3]), args)
+		if err != nil {
+			return nil, err
+		}
+		writes := make([]RecordedWrite, len(rows))
+		for i, row := range rows {
+			writes[i] = RecordedWrite{Operation: operation, Table: match[2], Row: row}
+		}
+		r.record(writes...)
+		return recordedResult(len(rows)), nil
+	}
+
+	write := RecordedWrite{Query: query, Args: args}
+	if match := keywordRegexp.FindStringSubmatch(query); match != nil {
+		write.Operation = strings.ToUpper(match[1])
+	}
+	var affected int64
+	switch {
+	case insertValuesRegexp.MatchString(query):
+		match := insertValuesRegexp.FindStringSubmatch(query)
+		write.Table = match[1]
+		affected = int64(strings.Count(match[2], "),(") + 1)
+	case updateRegexp.MatchString(query):
+		match := updateRegexp.FindStringSubmatch(query)
+		write.Table = match[1]
+		// the arguments of the SET clause precede those of the WHERE clause
+		setArgs := strings.Count(match[2], "?")
+		if setArgs > len(args) {
+			return nil, errors.Errorf("invalid number of arguments for %s", query)
+		}
+		count, err := r.countRows(ctx, match[1], match[4], args[setArgs:])
+		if err != nil {
+			return nil, err
+		}
+		affected = count
+	case deleteRegexp.MatchString(query):
+		match := deleteRegexp.FindStringSubmatch(query)
+		write.Table = match[1]
+		count, err := r.countRows(ctx, match[1], match[3], args)
+		if err != nil {
+			return nil, err
+		}
+		affected = count
+	}
+	r.record(write)
+	return recordedResult(affected), nil
+}
+
+// RecordRows implements Recorder.
+func (r *RecordingSession) RecordRows(table string, columns []string, rows [][]interface{}) {
+	writes := make([]RecordedWrite, len(rows))
+	for i, values := range rows {
+		row := make(map[string]interface{}, len(columns))
+		for j, column := range columns {
+			row[column] = values[j]
+		}
+		writes[i] = RecordedWrite{Operation: RecordedInsert, Table: table, Row: row}
+	}
+	r.record(writes...)
+}
+
+func (r *RecordingSession) countRows(ctx context.Context, table, where string, args []interface{}) (int64, error) {
+	query := "SELECT count(*) FROM " + table
+	if where != "" {
+		query += " WHERE " + where
+	}
+	var count int64
+	if err := r.GetRaw(ctx, &count, query, args...); err != nil {
+		return 0, errors.Wrapf(err, "could not count the rows of %s", table)
+	}
+	return count, nil
+}
+
+func (r *RecordingSession) selectRows(ctx context.Context, query string, columns []string, args []interface{}) ([]map[string]interface{}, error) {
+	rows, err := r.QueryRaw(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not select the inserted rows")
+	}
+	defer rows.Close()
+
+	var result []map[string]interface{}
+	for rows.Next() {
+		values, err := rows.SliceScan()
+		if err != nil {
+			return nil, errors.Wrap(err, "could not scan the inserted rows")
+		}
+		if len(values) != len(columns) {
+			return nil, errors.Errorf("expected %d columns but got %d", len(columns), len(values))
+		}
+		row := make(map[string]interface{}, len(columns))
+		for i, column := range columns {
+			row[column] = values[i]
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+func splitColumns(columns string) []string {
+	split := strings.Split(columns, ",")
+	for i := range split {
+		split[i] = strings.TrimSpace(split[i])
+	}
+	return split
+}
+
+// recordedResult is the sql.Result of a recorded statement.
+type recordedResult int64
+
+func (r recordedResult) LastInsertId() (int64, error) {
+	return 0, errors.New("LastInsertId is not supported by recorded statements")
+}
+
+func (r recordedResult) RowsAffected() (int64, error) {
+	return int64(r), nil
+}
+
+var _ SessionInterface = (*RecordingSession)(nil)
+var _ Recorder = (*RecordingSession)(nil)
@@ -0,0 +1,164 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/pownieh/stellar_go/support/db/dbtest"
+)
+
+func TestRecordingSessionBeginIsReadOnly(t *testing.T) {
+	ctx := context.Background()
+	session := &MockSession{}
+	defer session.AssertExpectations(t)
+	session.On("BeginTx", ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}).Return(nil).Once()
+	session.On("BeginTx", ctx, &sql.TxOptions{Isolation: sql.LevelSerializable, ReadOnly: true}).Return(nil).Once()
+	session.On("BeginTx", ctx, &sql.TxOptions{ReadOnly: true}).Return(nil).Once()
+
+	recording := NewRecordingSession(session)
+	assert.NoError(t, recording.Begin(ctx))
+	assert.NoError(t, recording.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable}))
+	assert.NoError(t, recording.BeginTx(ctx, nil))
+}
+
+func TestRecordingSessionRecordsWrites(t *testing.T) {
+	ctx := context.Background()
+	session := &MockSession{}
+	clone := &MockSession{}
+	defer mock.AssertExpectationsForObjects(t, session, clone)
+
+	session.On("Clone").Return(clone).Once()
+	session.On("GetRaw", ctx, mock.Anything, "SELECT count(*) FROM people WHERE name = ?", []interface{}{"scott"}).
+		Run(func(args mock.Arguments) {
+			*args.Get(1).(*int64) = 1
+		}).Return(nil).Once()
+	clone.On("GetRaw", ctx, mock.Anything, "SELECT count(*) FROM people WHERE hunger_level > ?", []interface{}{5}).
+		Run(func(args mock.Arguments) {
+			*args.Get(1).(*int64) = 2
+		}).Return(nil).Once()
+
+	recording := NewRecordingSession(session)
+	recording.SetLabel("first")
+	result, err := recording.ExecRaw(ctx, "INSERT INTO people (name,hunger_level) VALUES (?,?),(?,?)", "a", 1, "b", 2)
+	assert.NoError(t, err)
+	affected, err := result.RowsAffected()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), affected)
+
+	result, err = recording.Exec(ctx, sq.Update("people").Set("hunger_level", 3).Where("name = ?", "scott"))
+	assert.NoError(t, err)
+	affected, err = result.RowsAffected()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), affected)
+
+	cloned := recording.Clone().(*RecordingSession)
+	cloned.SetLabel("second")
+	result, err = cloned.Exec(ctx, sq.Delete("people").Where("hunger_level > ?", 5))
+	assert.NoError(t, err)
+	affected, err = result.RowsAffected()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), affected)
+	assert.NoError(t, cloned.TruncateTables(ctx, []string{"people"}))
+
+	assert.Equal(t, int64(-1), recording.NextID())
+	assert.Equal(t, int64(-2), cloned.NextID())
+
+	assert.Equal(t, []RecordedWrite{
+		{
+			Label:     "first",
+			Operation: "INSERT",
+			Table:     "people",
+			Query:     "INSERT INTO people (name,hunger_level) VALUES (?,?),(?,?)",
+			Args:      []interface{}{"a", 1, "b", 2},
+		},
+		{
+			Label:     "first",
+			Operation: "UPDATE",
+			Table:     "people",
+			Query:     "UPDATE people SET hunger_level = ? WHERE name = ?",
+			Args:      []interface{}{3, "scott"},
+		},
+		{
+			Label:     "second",
+			Operation: "DELETE",
+			Table:     "people",
+			Query:     "DELETE FROM people WHERE hunger_level > ?",
+			Args:      []interface{}{5},
+		},
+		{
+			Label:     "second",
+			Operation: "TRUNCATE",
+			Table:     "people",
+			Query:     "TRUNCATE people",
+		},
+	}, recording.Writes())
+}
+
+func TestRecordingSessionRecordsFastBatchInserts(t *testing.T) {
+	ctx := context.Background()
+	session := &MockSession{}
+	defer session.AssertExpectations(t)
+	session.On("GetTx").Return(&sqlx.Tx{}).Once()
+
+	recording := NewRecordingSession(session)
+	recording.SetLabel("batch")
+	insertBuilder := &FastBatchInsertBuilder{}
+	assert.NoError(t, insertBuilder.Row(map[string]interface{}{"name": "bubba", "hunger_level": 1}))
+	assert.NoError(t, insertBuilder.Exec(ctx, recording, "people"))
+
+	assert.Equal(t, []RecordedWrite{
+		{
+			Label:     "batch",
+			Operation: RecordedInsert,
+			Table:     "people",
+			Row:       map[string]interface{}{"name": "bubba", "hunger_level": 1},
+		},
+	}, recording.Writes())
+}
+
+func TestRecordingSessionRecordsInsertSelect(t *testing.T) {
+	db := dbtest.Postgres(t).Load(testSchema)
+	defer db.Close()
+	sess := &Session{DB: db.Open()}
+	defer sess.DB.Close()
+
+	ctx := context.Background()
+	recording := NewRecordingSession(sess)
+	assert.NoError(t, recording.Begin(ctx))
+	defer recording.Rollback()
+
+	result, err := recording.ExecRaw(ctx, `
+		WITH r AS
+			(SELECT unnest(?::text[]) AS name, unnest(?::integer[]) AS hunger_level)
+		INSERT INTO people (name, hunger_level)
+		SELECT * FROM r
+		ON CONFLICT (name) DO UPDATE SET hunger_level = excluded.hunger_level`,
+		"{scott,bubba}", "{1,2}",
+	)
+	assert.NoError(t, err)
+	affected, err := result.RowsAffected()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), affected)
+
+	writes := recording.Writes()
+	if assert.Len(t, writes, 2) {
+		assert.Equal(t, RecordedUpsert, writes[0].Operation)
+		assert.Equal(t, "people", writes[0].Table)
+		assert.Equal(t, "scott", writes[0].Row["name"])
+		assert.Equal(t, int64(1), writes[0].Row["hunger_level"])
+		assert.Equal(t, "bubba", writes[1].Row["name"])
+	}
+
+	// the DB is not modified and writes which are not recorded fail
+	var count int
+	assert.NoError(t, recording.GetRaw(ctx, &count, "SELECT count(*) FROM people"))
+	assert.Equal(t, 3, count)
+	_, err = sess.ExecRaw(ctx, "DELETE FROM people")
+	assert.Error(t, err)
+}
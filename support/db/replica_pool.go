@@ -0,0 +1,229 @@
+package db
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/pownieh/stellar_go/support/log"
+)
+
+// PrimaryReplicaName is the replica label of the requests routed to the
+// primary database by ReplicaPool.
+const PrimaryReplicaName = "primary"
+
+// Replica is a read replica of the primary database of a ReplicaPool.
+type Replica struct {
+	// Name identifies the replica in logs and metrics.
+	Name    string
+	Session SessionInterface
+}
+
+// ReplicaStatus is the status of a replica as of its last health check.
+type ReplicaStatus struct {
+	Name    string
+	Healthy bool
+	// Sequence is the latest sequence applied to the replica.
+	Sequence uint64
+	// Lag is the number of sequences applied to the primary database but not
+	// yet to the replica.
+	Lag       uint64
+	CheckedAt time.Time
+}
+
+type replicaState struct {
+	Replica
+	checkSession SessionInterface
+
+	lock   sync.RWMutex
+	status ReplicaStatus
+}
+
+// ReplicaPool distributes read-only queries across read replicas of a
+// primary database. Replicas are checked periodically, the health check
+// runs SequenceQuery which must return the latest sequence applied to the
+// database (e.g. the latest ledger ingested) and the replication lag is the
+// difference with the sequence of the primary database.
+type ReplicaPool struct {
+	primary  SessionInterface
+	replicas []*replicaState
+	// SequenceQuery returns a single integer, the latest sequence applied to
+	// a database.
+	SequenceQuery string
+	// CheckTimeout is the timeout of the health check of each replica.
+	CheckTimeout time.Duration
+
+	primarySession  SessionInterface
+	primarySequence uint64
+	next            uint32
+
+	healthyGauge    *prometheus.GaugeVec
+	lagGauge        *prometheus.GaugeVec
+	selectedCounter *prometheus.CounterVec
+
+	closeChan chan struct{}
+	closeOnce sync.Once
+}
+
+// NewReplicaPool returns a pool of the given replicas of the primary
+// database. The replicas are unhealthy until they are checked.
+func NewReplicaPool(primary SessionInterface, replicas []Replica, sequenceQuery string) *ReplicaPool {
+	pool := &ReplicaPool{
+		primary:       primary,
+		SequenceQuery: sequenceQuery,
+		CheckTimeout:  time.Second,
+		// sessions must be cloned because they are used concurrently
+		// by the health checks
+		primarySession: primary.Clone(),
+		closeChan:      make(chan struct{}),
+	}
+	for _, replica := range replicas {
+		pool.replicas = append(pool.replicas, &replicaState{
+			Replica:      replica,
+			checkSession: replica.Session.Clone(),
+			status:       ReplicaStatus{Name: replica.Name},
+		})
+	}
+	return pool
+}
+
+// RegisterMetrics registers the health, replication lag and usage metrics
+// of the replicas.
+func (p *ReplicaPool) RegisterMetrics(namespace string, registry *prometheus.Registry) {
+	p.healthyGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace, Subsystem: "db", Name: "replica_healthy",
+			Help: "1 if the last health check of the replica succeeded, 0 otherwise",
+		},
+		[]string{"replica"},
+	)
+	p.lagGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace, Subsystem: "db", Name: "replica_lag",
+			Help: "number of sequences applied to the primary database but not yet to the replica",
+		},
+		[]string{"replica"},
+	)
+	p.selectedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "db", Name: "replica_selected_total",
+			Help: "number of sessions handed out by the replica pool, the primary replica is the fallback",
+		},
+		[]string{"replica"},
+	)
+	registry.MustRegister(p.healthyGauge, p.lagGauge, p.selectedCounter)
+}
+
+// Start checks the replicas every interval until the pool is closed.
+func (p *ReplicaPool) Start(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		p.Check(context.Background())
+		for {
+			select {
+			case <-ticker.C:
+				p.Check(context.Background())
+			case <-p.closeChan:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+}
+
+// Close stops the health checks. It does not close the sessions.
+func (p *ReplicaPool) Close() {
+	p.closeOnce.Do(func() {
+		close(p.closeChan)
+	})
+}
+
+// Check updates the health and replication lag of the replicas. It must not
+// be called concurrently.
+func (p *ReplicaPool) Check(ctx context.Context) {
+	primarySequence, err := p.sequence(ctx, p.primarySession)
+	if err != nil {
+		log.Warnf("could not get the sequence of the primary database: %v", err)
+	} else {
+		atomic.StoreUint64(&p.primarySequence, primarySequence)
+	}
+	primarySequence = atomic.LoadUint64(&p.primarySequence)
+
+	for _, replica := range p.replicas {
+		status := ReplicaStatus{Name: replica.Name, CheckedAt: time.Now()}
+		sequence, err := p.sequence(ctx, replica.checkSession)
+		if err != nil {
+			log.WithField("replica", replica.Name).WithError(err).Warn("replica health check failed")
+		} else {
+			status.Healthy = true
+			status.Sequence = sequence
+			if primarySequence > sequence {
+				status.Lag = primarySequence - sequence
+			}
+		}
+
+		replica.lock.Lock()
+		replica.status = status
+		replica.lock.Unlock()
+
+		if p.healthyGauge != nil {
+			healthy := 0.0
+			if status.Healthy {
+				healthy = 1
+			}
+			p.healthyGauge.WithLabelValues(replica.Name).Set(healthy)
+			p.lagGauge.WithLabelValues(replica.Name).Set(float64(status.Lag))
+		}
+	}
+}
+
+func (p *ReplicaPool) sequence(ctx context.Context, session SessionInterface) (uint64, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.CheckTimeout)
+	defer cancel()
+	var sequence uint64
+	err := session.GetRaw(ctx, &sequence, p.SequenceQuery)
+	return sequence, err
+}
+
+// Status returns the status of the replicas as of their last health check.
+func (p *ReplicaPool) Status() []ReplicaStatus {
+	statuses := make([]ReplicaStatus, 0, len(p.replicas))
+	for _, replica := range p.replicas {
+		replica.lock.RLock()
+		statuses = append(statuses, replica.status)
+		replica.lock.RUnlock()
+	}
+	return statuses
+}
+
+// Session returns a new session connected to a healthy replica whose
+// sequence is at least minSequence, the replicas are used in turn. When no
+// replica has caught up to minSequence, the session is connected to the
+// primary database.
+func (p *ReplicaPool) Session(minSequence uint64) SessionInterface {
+	eligible := make([]*replicaState, 0, len(p.replicas))
+	for _, replica := range p.replicas {
+		replica.lock.RLock()
+		status := replica.status
+		replica.lock.RUnlock()
+		if status.Healthy && status.Sequence >= minSequence {
+			eligible = append(eligible, replica)
+		}
+	}
+	if len(eligible) > 0 {
+		replica := eligible[atomic.AddUint32(&p.next, 1)%uint32(len(eligible))]
+		p.selected(replica.Name)
+		return replica.Session.Clone()
+	}
+	p.selected(PrimaryReplicaName)
+	return p.primary.Clone()
+}
+
+func (p *ReplicaPool) selected(name string) {
+	if p.selectedCounter != nil {
+		p.selectedCounter.WithLabelValues(name).Inc()
+	}
+}
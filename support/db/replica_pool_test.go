@@ -0,0 +1,60 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const testSequenceQuery = "SELECT MAX(sequence) FROM history_ledgers"
+
+func mockSequenceSession(sequence uint64, err error) *MockSession {
+	session := &MockSession{}
+	session.On("Clone").Return(session)
+	session.On("GetRaw", mock.Anything, mock.Anything, testSequenceQuery, []interface{}(nil)).
+		Run(func(args mock.Arguments) {
+			*args.Get(1).(*uint64) = sequence
+		}).
+		Return(err)
+	return session
+}
+
+func TestReplicaPoolSession(t *testing.T) {
+	primary := mockSequenceSession(100, nil)
+	caughtUp := mockSequenceSession(100, nil)
+	lagging := mockSequenceSession(90, nil)
+	down := mockSequenceSession(0, errors.New("connection refused"))
+
+	pool := NewReplicaPool(primary, []Replica{
+		{Name: "caught_up", Session: caughtUp},
+		{Name: "lagging", Session: lagging},
+		{Name: "down", Session: down},
+	}, testSequenceQuery)
+
+	// replicas are not used until they are checked
+	assert.Same(t, primary, pool.Session(0))
+
+	pool.Check(context.Background())
+	statuses := pool.Status()
+	assert.Len(t, statuses, 3)
+	assert.True(t, statuses[0].Healthy)
+	assert.Equal(t, uint64(0), statuses[0].Lag)
+	assert.True(t, statuses[1].Healthy)
+	assert.Equal(t, uint64(90), statuses[1].Sequence)
+	assert.Equal(t, uint64(10), statuses[1].Lag)
+	assert.False(t, statuses[2].Healthy)
+
+	used := map[SessionInterface]int{}
+	for i := 0; i < 10; i++ {
+		used[pool.Session(90)]++
+	}
+	assert.Equal(t, map[SessionInterface]int{caughtUp: 5, lagging: 5}, used)
+
+	for i := 0; i < 10; i++ {
+		assert.Same(t, caughtUp, pool.Session(95))
+	}
+	assert.Same(t, primary, pool.Session(101))
+}
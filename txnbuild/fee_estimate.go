@@ -0,0 +1,41 @@
+package txnbuild
+
+import (
+	"github.com/pownieh/stellar_go/support/errors"
+)
+
+// BaseFeeEstimator estimates the base fee, the fee per operation, to bid for a
+// transaction to be included with the given probability within the given
+// number of ledgers. horizonclient.Client implements it using Horizon's
+// /fee_stats/estimate endpoint.
+type BaseFeeEstimator interface {
+	EstimateBaseFee(probability float64, withinLedgers uint32) (int64, error)
+}
+
+// EstimateBaseFee returns the base fee to set in TransactionParams.BaseFee for
+// the transaction to be included with the given probability within the given
+// number of ledgers. The estimate is at least MinBaseFee and, when maxBaseFee
+// isn't 0, at most maxBaseFee.
+func EstimateBaseFee(estimator BaseFeeEstimator, probability float64, withinLedgers uint32, maxBaseFee int64) (int64, error) {
+	if probability <= 0 || probability > 1 {
+		return 0, errors.New("probability must be greater than 0 and at most 1")
+	}
+	if withinLedgers == 0 {
+		return 0, errors.New("withinLedgers must be greater than 0")
+	}
+	if maxBaseFee != 0 && maxBaseFee < MinBaseFee {
+		return 0, errors.Errorf("maxBaseFee cannot be lower than network minimum of %d", MinBaseFee)
+	}
+
+	baseFee, err := estimator.EstimateBaseFee(probability, withinLedgers)
+	if err != nil {
+		return 0, errors.Wrap(err, "could not estimate base fee")
+	}
+	if baseFee < MinBaseFee {
+		baseFee = MinBaseFee
+	}
+	if maxBaseFee != 0 && baseFee > maxBaseFee {
+		baseFee = maxBaseFee
+	}
+	return baseFee, nil
+}
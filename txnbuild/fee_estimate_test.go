@@ -0,0 +1,46 @@
+package txnbuild
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type constantBaseFeeEstimator struct {
+	baseFee int64
+	err     error
+}
+
+func (e constantBaseFeeEstimator) EstimateBaseFee(probability float64, withinLedgers uint32) (int64, error) {
+	return e.baseFee, e.err
+}
+
+func TestEstimateBaseFee(t *testing.T) {
+	baseFee, err := EstimateBaseFee(constantBaseFeeEstimator{baseFee: 250}, 0.9, 1, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(250), baseFee)
+
+	baseFee, err = EstimateBaseFee(constantBaseFeeEstimator{baseFee: 10}, 0.9, 1, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(MinBaseFee), baseFee)
+
+	baseFee, err = EstimateBaseFee(constantBaseFeeEstimator{baseFee: 5000}, 0.9, 1, 1000)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1000), baseFee)
+}
+
+func TestEstimateBaseFeeErrors(t *testing.T) {
+	estimator := constantBaseFeeEstimator{baseFee: 250}
+	_, err := EstimateBaseFee(estimator, 0, 1, 0)
+	assert.EqualError(t, err, "probability must be greater than 0 and at most 1")
+	_, err = EstimateBaseFee(estimator, 1.5, 1, 0)
+	assert.EqualError(t, err, "probability must be greater than 0 and at most 1")
+	_, err = EstimateBaseFee(estimator, 0.9, 0, 0)
+	assert.EqualError(t, err, "withinLedgers must be greater than 0")
+	_, err = EstimateBaseFee(estimator, 0.9, 1, 50)
+	assert.EqualError(t, err, "maxBaseFee cannot be lower than network minimum of 100")
+
+	_, err = EstimateBaseFee(constantBaseFeeEstimator{err: errors.New("horizon is down")}, 0.9, 1, 0)
+	assert.EqualError(t, err, "could not estimate base fee: horizon is down")
+}